@@ -0,0 +1,304 @@
+// eval 补全离线评测工具：回放数据集中的对话，遮住每个用例的真实回复，
+// 用补全引擎生成建议并与真实回复比较，输出逐条结果和整体得分
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"ChatRecommend/internal/autocomplete"
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/context"
+	"ChatRecommend/internal/llm"
+	"ChatRecommend/internal/migrations"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/style"
+	"ChatRecommend/internal/summary"
+	"github.com/sirupsen/logrus"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Dataset 评测数据集
+type Dataset struct {
+	Cases []Case `json:"cases"`
+}
+
+// Case 评测用例：history 为真实回复之前的对话，reply 为被遮住的真实回复
+type Case struct {
+	Name     string           `json:"name"`
+	History  []HistoryMessage `json:"history"`
+	SenderID string           `json:"sender_id"`
+	Reply    string           `json:"reply"`
+	// 用户已输入的内容（可选），未指定时取真实回复的前 prefix_len 个字符
+	Input *string `json:"input,omitempty"`
+}
+
+// HistoryMessage 用例中的历史消息
+type HistoryMessage struct {
+	SenderID string `json:"sender_id"`
+	Content  string `json:"content"`
+}
+
+// CaseResult 单个用例的评测结果
+type CaseResult struct {
+	Name        string   `json:"name"`
+	Input       string   `json:"input"`
+	Reply       string   `json:"reply"`
+	Suggestions []string `json:"suggestions"`
+	Scores      Scores   `json:"scores"`
+	LatencyMs   int64    `json:"latency_ms"`
+	Error       string   `json:"error,omitempty"`
+}
+
+// Report 评测报告
+type Report struct {
+	Cases  []CaseResult `json:"cases"`
+	Total  int          `json:"total"`
+	Failed int          `json:"failed"`
+	// 成功用例的平均得分
+	Average      Scores `json:"average"`
+	AvgLatencyMs int64  `json:"avg_latency_ms"`
+}
+
+func main() {
+	configPath := flag.String("config", "config.yaml", "配置文件路径（使用其中的 llm、context、style、autocomplete 配置）")
+	datasetPath := flag.String("dataset", "", "评测数据集（JSON）路径")
+	llmMode := flag.String("llm", "mock", "大模型：mock（本地模拟，用于验证流程）或 real（按配置调用真实大模型）")
+	prefixLen := flag.Int("prefix-len", 2, "用例未指定 input 时，取真实回复的前几个字符作为已输入内容")
+	outPath := flag.String("out", "", "报告输出路径（JSON），为空时只打印汇总")
+	flag.Parse()
+
+	if *datasetPath == "" {
+		log.Fatal("必须指定 -dataset")
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("加载配置失败: %v", err)
+	}
+	logrus.SetLevel(logrus.WarnLevel)
+
+	dataset, err := loadDataset(*datasetPath)
+	if err != nil {
+		log.Fatalf("加载数据集失败: %v", err)
+	}
+
+	var llmService llm.Service
+	switch *llmMode {
+	case "mock":
+		llmService = mockLLM{}
+	case "real":
+		llmService = llm.NewClient(&cfg.LLM)
+	default:
+		log.Fatalf("不支持的 -llm: %s", *llmMode)
+	}
+
+	report, err := run(cfg, llmService, dataset, *prefixLen)
+	if err != nil {
+		log.Fatalf("评测失败: %v", err)
+	}
+
+	if *outPath != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Fatalf("序列化报告失败: %v", err)
+		}
+		if err := os.WriteFile(*outPath, data, 0644); err != nil {
+			log.Fatalf("写入报告失败: %v", err)
+		}
+	}
+
+	fmt.Printf("用例: %d，失败: %d\n", report.Total, report.Failed)
+	fmt.Printf("BLEU: %.4f  编辑距离相似度: %.4f  完全命中率: %.4f  平均耗时: %dms\n",
+		report.Average.BLEU, report.Average.EditSimilarity, report.Average.ExactMatch, report.AvgLatencyMs)
+}
+
+// loadDataset 读取数据集
+func loadDataset(path string) (*Dataset, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取数据集失败: %w", err)
+	}
+	var dataset Dataset
+	if err := json.Unmarshal(data, &dataset); err != nil {
+		return nil, fmt.Errorf("解析数据集失败: %w", err)
+	}
+	if len(dataset.Cases) == 0 {
+		return nil, fmt.Errorf("数据集中没有用例")
+	}
+	return &dataset, nil
+}
+
+// run 在内存数据库中逐个回放用例并计算得分
+func run(cfg *config.Config, llmService llm.Service, dataset *Dataset, prefixLen int) (*Report, error) {
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		return nil, fmt.Errorf("连接数据库失败: %w", err)
+	}
+	if err := migrations.Run(db); err != nil {
+		return nil, fmt.Errorf("数据库迁移失败: %w", err)
+	}
+
+	// 评测只关注补全本身：关闭缓存、请求去重、预取和自动摘要，最小触发长度置0
+	autocompleteCfg := cfg.Autocomplete
+	autocompleteCfg.CacheTTLSeconds = 0
+	autocompleteCfg.RequestIDTTLSeconds = 0
+	autocompleteCfg.MinTriggerLength = 0
+	autocompleteCfg.Prefetch.Enabled = false
+	summaryCfg := cfg.Summary
+	summaryCfg.AutoUpdate = false
+
+	summaryMgr := summary.NewManager(db, &summaryCfg, &cfg.Prompt, llmService, nil)
+	styleMgr := style.NewManager(db, &cfg.Style, nil)
+	contextMgr := context.NewManager(db, &cfg.Context, &cfg.Prompt, summaryMgr, styleMgr)
+	engine := autocomplete.NewEngine(db, &autocompleteCfg, contextMgr, llmService, nil)
+
+	report := &Report{Total: len(dataset.Cases)}
+	var totalLatency int64
+	for i, c := range dataset.Cases {
+		result := CaseResult{Name: c.Name, Reply: c.Reply}
+		if result.Name == "" {
+			result.Name = fmt.Sprintf("case_%d", i+1)
+		}
+		result.Input = caseInput(&c, prefixLen)
+
+		conversationID := fmt.Sprintf("eval_%d", i+1)
+		if err := loadHistory(db, styleMgr, conversationID, c.History); err != nil {
+			return nil, err
+		}
+
+		start := time.Now()
+		resp, err := engine.GetSuggestions(&models.AutocompleteRequest{
+			ConversationID: conversationID,
+			SenderID:       c.SenderID,
+			Input:          result.Input,
+		})
+		result.LatencyMs = time.Since(start).Milliseconds()
+
+		if err != nil {
+			result.Error = err.Error()
+			report.Failed++
+		} else {
+			// 建议只包含输入之后的部分，拼上输入后再与真实回复比较
+			for _, suggestion := range resp.Suggestions {
+				result.Suggestions = append(result.Suggestions, result.Input+suggestion)
+			}
+			result.Scores = best(result.Suggestions, c.Reply)
+			report.Average.BLEU += result.Scores.BLEU
+			report.Average.EditSimilarity += result.Scores.EditSimilarity
+			report.Average.ExactMatch += result.Scores.ExactMatch
+			totalLatency += result.LatencyMs
+		}
+		report.Cases = append(report.Cases, result)
+	}
+
+	if succeeded := report.Total - report.Failed; succeeded > 0 {
+		n := float64(succeeded)
+		report.Average.BLEU /= n
+		report.Average.EditSimilarity /= n
+		report.Average.ExactMatch /= n
+		report.AvgLatencyMs = totalLatency / int64(succeeded)
+	}
+	return report, nil
+}
+
+// caseInput 确定用例的已输入内容
+func caseInput(c *Case, prefixLen int) string {
+	if c.Input != nil {
+		return *c.Input
+	}
+	runes := []rune(c.Reply)
+	if prefixLen > len(runes) {
+		prefixLen = len(runes)
+	}
+	if prefixLen < 0 {
+		prefixLen = 0
+	}
+	return string(runes[:prefixLen])
+}
+
+// loadHistory 写入用例的历史消息，并为每个发送者分析风格
+func loadHistory(db *gorm.DB, styleMgr *style.Manager, conversationID string, history []HistoryMessage) error {
+	conversation := models.Conversation{
+		ConversationID: conversationID,
+		Participants:   "[]",
+		LastMessageAt:  time.Now(),
+	}
+	if err := db.Create(&conversation).Error; err != nil {
+		return fmt.Errorf("创建对话失败: %w", err)
+	}
+
+	// 历史消息按分钟间隔排列，最后一条为当前时间
+	messages := make([]models.Message, len(history))
+	base := time.Now().Add(-time.Duration(len(history)) * time.Minute)
+	for i, h := range history {
+		messages[i] = models.Message{
+			ConversationID: conversation.ID,
+			SenderID:       h.SenderID,
+			Content:        h.Content,
+			MessageType:    "text",
+			Sequence:       int64(i + 1),
+		}
+		messages[i].CreatedAt = base.Add(time.Duration(i+1) * time.Minute)
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+	if err := db.Create(&messages).Error; err != nil {
+		return fmt.Errorf("写入历史消息失败: %w", err)
+	}
+
+	senders := make(map[string]bool)
+	for _, msg := range messages {
+		if senders[msg.SenderID] {
+			continue
+		}
+		senders[msg.SenderID] = true
+		if err := styleMgr.UpdateStyle(conversation.ID, msg.SenderID, messages); err != nil {
+			logrus.WithError(err).WithField("sender_id", msg.SenderID).Warn("分析风格失败")
+		}
+	}
+	return nil
+}
+
+// mockLLM 本地模拟大模型：把上下文中最后一条历史消息作为建议返回，用于在不调用真实大模型时验证评测流程
+type mockLLM struct{}
+
+var _ llm.Service = mockLLM{}
+
+// Complete 实现 llm.Service
+func (mockLLM) Complete(ctx string, input string, opts *llm.CompleteOptions) ([]string, error) {
+	history, _, _ := strings.Cut(ctx, "=== 当前输入 ===")
+	lines := strings.Split(strings.TrimSpace(history), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if _, content, ok := strings.Cut(lines[i], "]: "); ok && strings.TrimSpace(content) != "" {
+			return []string{strings.TrimSpace(content)}, nil
+		}
+	}
+	return []string{}, nil
+}
+
+// CompleteStructured 实现 llm.Service
+func (m mockLLM) CompleteStructured(ctx string, input string, opts *llm.CompleteOptions) ([]models.Suggestion, error) {
+	suggestions, err := m.Complete(ctx, input, opts)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]models.Suggestion, len(suggestions))
+	for i, s := range suggestions {
+		result[i] = models.Suggestion{Text: s}
+	}
+	return result, nil
+}
+
+// GenerateSummary 实现 llm.Service
+func (mockLLM) GenerateSummary(messages []models.Message, existing *models.Summary, opts *llm.SummaryOptions) (string, string, error) {
+	return "", "[]", nil
+}
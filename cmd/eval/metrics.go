@@ -0,0 +1,107 @@
+package main
+
+import "math"
+
+// Scores 一条建议与真实回复的相似度指标（均为0-1，越大越好）
+type Scores struct {
+	// 字符级 BLEU-4（加一平滑，适用于中文等不分词的文本）
+	BLEU float64 `json:"bleu"`
+	// 编辑距离相似度：1 - 编辑距离 / 较长文本长度
+	EditSimilarity float64 `json:"edit_similarity"`
+	// 是否与真实回复完全一致
+	ExactMatch float64 `json:"exact_match"`
+}
+
+// maxBLEUOrder BLEU 计算的最大 n-gram 阶数
+const maxBLEUOrder = 4
+
+// score 计算一条建议相对真实回复的各项指标
+func score(candidate, reference string) Scores {
+	s := Scores{
+		BLEU:           charBLEU(candidate, reference),
+		EditSimilarity: editSimilarity(candidate, reference),
+	}
+	if candidate == reference {
+		s.ExactMatch = 1
+	}
+	return s
+}
+
+// best 取多条建议中各项指标的最大值（评测"至少有一条建议接近真实回复"）
+func best(candidates []string, reference string) Scores {
+	var result Scores
+	for _, candidate := range candidates {
+		s := score(candidate, reference)
+		result.BLEU = math.Max(result.BLEU, s.BLEU)
+		result.EditSimilarity = math.Max(result.EditSimilarity, s.EditSimilarity)
+		result.ExactMatch = math.Max(result.ExactMatch, s.ExactMatch)
+	}
+	return result
+}
+
+// charBLEU 字符级 BLEU：1-4 阶 n-gram 精确率（加一平滑）的几何平均乘以长度惩罚
+func charBLEU(candidate, reference string) float64 {
+	cand, ref := []rune(candidate), []rune(reference)
+	if len(cand) == 0 || len(ref) == 0 {
+		return 0
+	}
+
+	logSum := 0.0
+	for n := 1; n <= maxBLEUOrder; n++ {
+		refCounts := ngramCounts(ref, n)
+		matched, total := 0, 0
+		for gram, count := range ngramCounts(cand, n) {
+			total += count
+			if refCount := refCounts[gram]; refCount > 0 {
+				matched += min(count, refCount)
+			}
+		}
+		logSum += math.Log(float64(matched+1) / float64(total+1))
+	}
+
+	brevity := 1.0
+	if len(cand) < len(ref) {
+		brevity = math.Exp(1 - float64(len(ref))/float64(len(cand)))
+	}
+	return brevity * math.Exp(logSum/maxBLEUOrder)
+}
+
+// ngramCounts 统计字符 n-gram 出现次数
+func ngramCounts(runes []rune, n int) map[string]int {
+	counts := make(map[string]int)
+	for i := 0; i+n <= len(runes); i++ {
+		counts[string(runes[i:i+n])]++
+	}
+	return counts
+}
+
+// editSimilarity 基于字符编辑距离的相似度
+func editSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+// levenshtein 字符编辑距离
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// approxEqual 浮点指标按 1e-9 的误差比较
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "中文", 2},
+		{"今天天气", "今天天气", 0},
+		{"kitten", "sitting", 3},
+		// 按字符而不是字节计算
+		{"北京", "南京", 1},
+		{"我爱北京", "我爱上海", 2},
+		{"你好", "再见", 2},
+	}
+	for _, tt := range tests {
+		if got := levenshtein([]rune(tt.a), []rune(tt.b)); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d，期望 %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := levenshtein([]rune(tt.b), []rune(tt.a)); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d，期望 %d", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestEditSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"", "", 1},
+		{"", "好的", 0},
+		{"今天天气不错", "今天天气不错", 1},
+		{"你好", "再见", 0},
+		{"猫咪", "猫狗", 0.5},
+		{"今天天气", "今天天气不错", 1 - 2.0/6},
+	}
+	for _, tt := range tests {
+		if got := editSimilarity(tt.a, tt.b); !approxEqual(got, tt.want) {
+			t.Errorf("editSimilarity(%q, %q) = %v，期望 %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCharBLEU(t *testing.T) {
+	tests := []struct {
+		name                 string
+		candidate, reference string
+		want                 float64
+	}{
+		{"完全相同", "今天天气不错", "今天天气不错", 1},
+		{"单字相同", "好", "好", 1},
+		{"候选为空", "", "好的", 0},
+		{"参考为空", "好的", "", 0},
+		// 1-4 阶精确率（加一平滑）分别为 1/3、1/2、1、1
+		{"完全不同", "你好", "再见", math.Pow(1.0/6, 0.25)},
+		// 各阶全部命中，只有长度惩罚 exp(1-6/4)
+		{"前缀", "今天天气", "今天天气不错", math.Exp(-0.5)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := charBLEU(tt.candidate, tt.reference); !approxEqual(got, tt.want) {
+				t.Fatalf("charBLEU(%q, %q) = %v，期望 %v", tt.candidate, tt.reference, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScoreAndBest(t *testing.T) {
+	reference := "今天天气不错"
+
+	if got := score(reference, reference); got != (Scores{BLEU: 1, EditSimilarity: 1, ExactMatch: 1}) {
+		t.Fatalf("完全一致的建议得分为 %+v", got)
+	}
+	if got := score("你好", "再见"); got.ExactMatch != 0 || got.EditSimilarity != 0 {
+		t.Fatalf("完全不同的建议得分为 %+v", got)
+	}
+
+	// 各项指标分别取最大值，可以来自不同的建议
+	got := best([]string{"你好", "今天天气", "今天天气很不错啊"}, reference)
+	if !approxEqual(got.BLEU, charBLEU("今天天气", reference)) ||
+		!approxEqual(got.EditSimilarity, 1-2.0/8) || got.ExactMatch != 0 {
+		t.Fatalf("最佳得分为 %+v", got)
+	}
+	if got := best([]string{"再见", reference}, reference); got.ExactMatch != 1 || got.BLEU != 1 {
+		t.Fatalf("包含完全一致建议时最佳得分为 %+v", got)
+	}
+	if got := best(nil, reference); got != (Scores{}) {
+		t.Fatalf("没有建议时得分为 %+v", got)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Checkpoint 断点：已写入数据库的输入字节偏移和行数
+type Checkpoint struct {
+	Offset    int64     `json:"offset"`
+	Lines     int64     `json:"lines"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// loadCheckpoint 读取断点文件，路径为空或文件不存在时返回零值
+func loadCheckpoint(path string) (Checkpoint, error) {
+	var cp Checkpoint
+	if path == "" {
+		return cp, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cp, nil
+	}
+	if err != nil {
+		return cp, fmt.Errorf("读取断点文件失败: %w", err)
+	}
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return cp, fmt.Errorf("解析断点文件失败: %w", err)
+	}
+	if cp.Offset < 0 {
+		return cp, fmt.Errorf("断点偏移不能为负数: %d", cp.Offset)
+	}
+	return cp, nil
+}
+
+// saveCheckpoint 先写临时文件再重命名，进程中途退出时不会留下写了一半的断点
+func saveCheckpoint(path string, cp Checkpoint) error {
+	if path == "" {
+		return nil
+	}
+	cp.UpdatedAt = time.Now()
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("序列化断点失败: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("写入断点文件失败: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("替换断点文件失败: %w", err)
+	}
+	return nil
+}
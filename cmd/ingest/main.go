@@ -0,0 +1,211 @@
+// ingest 流式导入工具：从标准输入按行读取 JSON 消息（行协议见 README），批量写入数据库。
+// 写入跟不上时停止读取输入，由管道把压力传回上游；每批写入成功后记录已处理的字节偏移，重启时从断点继续
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"ChatRecommend/internal/api"
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/database"
+	"ChatRecommend/internal/migrations"
+	"github.com/sirupsen/logrus"
+)
+
+// options 导入参数
+type options struct {
+	checkpointPath string
+	batchSize      int
+	flushInterval  time.Duration
+	queueSize      int
+	maxLineBytes   int
+	maxRetries     int
+}
+
+func main() {
+	configPath := flag.String("config", "config.yaml", "配置文件路径（使用其中的 database 和 server.dedupe 配置）")
+	var opts options
+	flag.StringVar(&opts.checkpointPath, "checkpoint", "", "断点文件路径，为空时不记录断点")
+	flag.IntVar(&opts.batchSize, "batch-size", 200, "每批写入的最大消息数")
+	flag.DurationVar(&opts.flushInterval, "flush-interval", time.Second, "不足一批时最长等待多久写入")
+	flag.IntVar(&opts.queueSize, "queue", 1000, "已读取未写入的最大行数，达到后暂停读取输入")
+	flag.IntVar(&opts.maxLineBytes, "max-line-bytes", 1<<20, "单行最大字节数，超出的行被跳过")
+	flag.IntVar(&opts.maxRetries, "max-retries", 5, "一批写入失败后的最大重试次数，用尽后退出（断点停在上一批）")
+	flag.Parse()
+
+	if opts.batchSize <= 0 || opts.queueSize <= 0 || opts.flushInterval <= 0 || opts.maxLineBytes <= 0 {
+		log.Fatal("-batch-size、-queue、-flush-interval 和 -max-line-bytes 必须大于0")
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("加载配置失败: %v", err)
+	}
+	if cfg.Database.InMemory() {
+		log.Fatal("内存数据库不支持流式导入，请配置 database.db_path")
+	}
+
+	db, err := database.Open(cfg)
+	if err != nil {
+		log.Fatalf("初始化数据库失败: %v", err)
+	}
+	if err := migrations.Run(db); err != nil {
+		log.Fatalf("数据库迁移失败: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	service := api.NewIngestService(db, &cfg.Server.Dedupe)
+	if err := run(ctx, service, os.Stdin, &opts); err != nil {
+		log.Fatalf("导入失败: %v", err)
+	}
+}
+
+// run 读取输入并分批写入，直到输入结束或 ctx 取消；退出前写入已读取的最后一批
+func run(ctx context.Context, service *api.IngestService, input io.Reader, opts *options) error {
+	cp, err := loadCheckpoint(opts.checkpointPath)
+	if err != nil {
+		return err
+	}
+	if cp.Offset > 0 {
+		logrus.WithFields(logrus.Fields{"offset": cp.Offset, "lines": cp.Lines}).Info("从断点继续导入")
+	}
+
+	lines := make(chan line, opts.queueSize)
+	readErr := make(chan error, 1)
+	go func() {
+		readErr <- readLines(input, cp.Offset, opts.maxLineBytes, lines, func(end int64, err error) {
+			logrus.WithError(err).WithField("offset", end).Warn("跳过无效的行")
+		})
+	}()
+
+	w := &batchWriter{service: service, opts: opts, checkpoint: cp}
+	ticker := time.NewTicker(opts.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case l, ok := <-lines:
+			if !ok {
+				if err := w.flush(ctx); err != nil {
+					return err
+				}
+				w.logTotal()
+				return <-readErr
+			}
+			w.add(l)
+			if len(w.messages) >= opts.batchSize {
+				if err := w.flush(ctx); err != nil {
+					return err
+				}
+			}
+		case <-ticker.C:
+			if err := w.flush(ctx); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			// 收到退出信号：写入已读取的行后退出，仍在管道中的输入留给下次从断点继续
+			err := w.flush(context.Background())
+			w.logTotal()
+			return err
+		}
+	}
+}
+
+// batchWriter 累积读到的行，按批写入并推进断点
+type batchWriter struct {
+	service  *api.IngestService
+	opts     *options
+	messages []api.IngestMessage
+	// 当前批次覆盖的行数（包括被跳过的行）和最后一行结束处的偏移
+	pending    int64
+	end        int64
+	checkpoint Checkpoint
+	written    int
+	duplicates int
+	skipped    int
+}
+
+// add 把一行加入当前批次，无效的行只推进偏移
+func (w *batchWriter) add(l line) {
+	if l.msg != nil {
+		w.messages = append(w.messages, *l.msg)
+	}
+	w.pending++
+	w.end = l.end
+}
+
+// flush 写入当前批次并保存断点，失败时按指数退避重试，ctx 取消时停止重试
+func (w *batchWriter) flush(ctx context.Context) error {
+	if w.pending == 0 {
+		return nil
+	}
+
+	var result *api.IngestResult
+	var err error
+	backoff := time.Second
+	for attempt := 0; ; attempt++ {
+		if len(w.messages) == 0 {
+			result = &api.IngestResult{}
+			break
+		}
+		result, err = w.service.Write(w.messages)
+		if err == nil {
+			break
+		}
+		if attempt >= w.opts.maxRetries {
+			return err
+		}
+		logrus.WithError(err).WithField("attempt", attempt+1).Warn("写入失败，稍后重试")
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return err
+		}
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+
+	w.checkpoint.Offset = w.end
+	w.checkpoint.Lines += w.pending
+	if err := saveCheckpoint(w.opts.checkpointPath, w.checkpoint); err != nil {
+		return err
+	}
+
+	w.written += result.Written
+	w.duplicates += result.Duplicates
+	w.skipped += result.Skipped
+	if result.Skipped > 0 {
+		logrus.WithField("skipped", result.Skipped).Warn("对话只读，部分消息未写入")
+	}
+	logrus.WithFields(logrus.Fields{
+		"written":       result.Written,
+		"duplicates":    result.Duplicates,
+		"conversations": len(result.Conversations),
+		"offset":        w.checkpoint.Offset,
+	}).Debug("批次写入完成")
+
+	w.messages = w.messages[:0]
+	w.pending = 0
+	return nil
+}
+
+// logTotal 输出本次导入的汇总
+func (w *batchWriter) logTotal() {
+	logrus.WithFields(logrus.Fields{
+		"written":    w.written,
+		"duplicates": w.duplicates,
+		"skipped":    w.skipped,
+		"offset":     w.checkpoint.Offset,
+		"lines":      w.checkpoint.Lines,
+	}).Info("导入结束")
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"ChatRecommend/internal/api"
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+func testOptions(t *testing.T) *options {
+	return &options{
+		checkpointPath: filepath.Join(t.TempDir(), "checkpoint.json"),
+		batchSize:      2,
+		flushInterval:  time.Hour,
+		queueSize:      10,
+		maxLineBytes:   1 << 10,
+		maxRetries:     0,
+	}
+}
+
+// TestRunIngestsNDJSON 行协议输入经 run 写入数据库：跳过无效行，丢弃相邻重复消息，断点停在输入末尾
+func TestRunIngestsNDJSON(t *testing.T) {
+	db := testutil.NewDB(t)
+	service := api.NewIngestService(db, &config.DedupeConfig{WindowSeconds: 60, Action: "merge"})
+
+	input := strings.Join([]string{
+		`{"conversation_id":"c1","sender_id":"alice","content":"早上好","time":"2024-01-01T08:00:00Z"}`,
+		`{"conversation_id":"c1","sender_id":"alice","content":"早上好","time":"2024-01-01T08:00:10Z"}`,
+		`not json`,
+		``,
+		`{"conversation_id":"c1","sender_id":"bob","content":"早","time":"2024-01-01T08:01:00Z"}`,
+		`{"conversation_id":"c2","sender_id":"carol","content":"在吗","time":"2024-01-01T09:00:00Z"}`,
+		`{"conversation_id":"c2","sender_id":"","content":"缺少发送者"}`,
+	}, "\n") + "\n"
+
+	opts := testOptions(t)
+	if err := run(context.Background(), service, strings.NewReader(input), opts); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	var contents []string
+	if err := db.Model(&models.Message{}).
+		Joins("JOIN conversations ON conversations.id = messages.conversation_id").
+		Order("conversations.conversation_id, messages.sequence").
+		Pluck("messages.content", &contents).Error; err != nil {
+		t.Fatalf("query messages: %v", err)
+	}
+	if got, want := strings.Join(contents, ","), "早上好,早,在吗"; got != want {
+		t.Fatalf("messages = %q, want %q", got, want)
+	}
+
+	cp, err := loadCheckpoint(opts.checkpointPath)
+	if err != nil {
+		t.Fatalf("loadCheckpoint: %v", err)
+	}
+	if cp.Offset != int64(len(input)) || cp.Lines != 7 {
+		t.Fatalf("checkpoint = %+v, want offset %d lines 7", cp, len(input))
+	}
+
+	// 从断点重新运行同一份输入不会重复写入
+	if err := run(context.Background(), service, strings.NewReader(input), opts); err != nil {
+		t.Fatalf("rerun: %v", err)
+	}
+	var count int64
+	db.Model(&models.Message{}).Count(&count)
+	if count != 3 {
+		t.Fatalf("message count after rerun = %d, want 3", count)
+	}
+}
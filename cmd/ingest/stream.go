@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"ChatRecommend/internal/api"
+)
+
+// line 读到的一行：msg 为 nil 表示该行被跳过（空行或格式错误），end 为该行结束处在输入流中的字节偏移
+type line struct {
+	msg *api.IngestMessage
+	end int64
+}
+
+// parseLine 按行协议解析一行：一个 JSON 对象，字段见 api.IngestMessage；缺少 time 时使用 now。
+// 空行返回 nil, nil
+func parseLine(data []byte, now time.Time) (*api.IngestMessage, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var msg api.IngestMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("解析JSON失败: %w", err)
+	}
+	if err := msg.Validate(); err != nil {
+		return nil, err
+	}
+	if msg.Time.IsZero() {
+		msg.Time = now
+	}
+	return &msg, nil
+}
+
+// readLines 从 offset 处开始逐行读取 r（r 需从流的开头读起，offset 之前的内容被丢弃），解析后送入 out，读完后关闭 out。
+// out 有缓冲上限，写入跟不上时阻塞在发送上，不再读取输入，压力经管道传回上游进程
+func readLines(r io.Reader, offset int64, maxLineBytes int, out chan<- line, onInvalid func(end int64, err error)) error {
+	defer close(out)
+
+	if offset > 0 {
+		skipped, err := io.CopyN(io.Discard, r, offset)
+		if err == io.EOF {
+			return fmt.Errorf("输入只有 %d 字节，短于断点偏移 %d", skipped, offset)
+		}
+		if err != nil {
+			return fmt.Errorf("跳过已处理的输入失败: %w", err)
+		}
+	}
+
+	reader := bufio.NewReader(r)
+	end := offset
+	for {
+		data, err := reader.ReadBytes('\n')
+		if len(data) > 0 {
+			end += int64(len(data))
+			var msg *api.IngestMessage
+			if len(data) > maxLineBytes {
+				onInvalid(end, fmt.Errorf("行长度 %d 超过上限 %d", len(data), maxLineBytes))
+			} else if parsed, parseErr := parseLine(data, time.Now()); parseErr != nil {
+				onInvalid(end, parseErr)
+			} else {
+				msg = parsed
+			}
+			out <- line{msg: msg, end: end}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("读取输入失败: %w", err)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 
@@ -8,18 +10,23 @@ import (
 	"ChatRecommend/internal/autocomplete"
 	"ChatRecommend/internal/config"
 	"ChatRecommend/internal/context"
+	"ChatRecommend/internal/database"
 	"ChatRecommend/internal/llm"
-	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/migrations"
+	"ChatRecommend/internal/prompt"
+	"ChatRecommend/internal/rules"
 	"ChatRecommend/internal/style"
 	"ChatRecommend/internal/summary"
+	"ChatRecommend/internal/webhook"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
-	"gorm.io/driver/sqlite"
-	"gorm.io/gorm"
 )
 
 func main() {
+	rollback := flag.Bool("migrate-rollback", false, "回滚最近执行的一个数据库迁移后退出")
+	flag.Parse()
+
 	// 加载配置
 	cfg, err := config.Load("config.yaml")
 	if err != nil {
@@ -34,29 +41,70 @@ func main() {
 	logrus.Info("正在启动ChatRecommend服务...")
 
 	// 初始化数据库
-	db, err := initDatabase(cfg)
+	db, err := database.Open(cfg)
 	if err != nil {
 		log.Fatalf("初始化数据库失败: %v", err)
 	}
 
+	if *rollback {
+		id, err := migrations.New(db, migrations.All()).RollbackLast()
+		if errors.Is(err, migrations.ErrNoMigration) {
+			logrus.Info("没有可回滚的数据库迁移")
+			return
+		}
+		if err != nil {
+			log.Fatalf("回滚数据库迁移失败: %v", err)
+		}
+		logrus.WithField("migration", id).Info("数据库迁移已回滚")
+		return
+	}
+
+	if err := migrations.Run(db); err != nil {
+		log.Fatalf("数据库迁移失败: %v", err)
+	}
+
 	// 初始化大模型客户端
 	llmClient := llm.NewClient(&cfg.LLM)
 
+	// 初始化webhook分发器
+	webhookDispatcher := webhook.NewDispatcher(&cfg.Webhooks)
+
 	// 初始化摘要管理器
-	summaryLLMAdapter := summary.NewLLMAdapter(llmClient)
-	summaryMgr := summary.NewManager(db, &cfg.Summary, summaryLLMAdapter)
+	summaryMgr := summary.NewManager(db, &cfg.Summary, &cfg.Prompt, llmClient, webhookDispatcher)
+	summary.NewWorker(summaryMgr, &cfg.Summary.Worker).Start()
 
 	// 初始化风格管理器
-	styleMgr := style.NewManager(db, &cfg.Style)
+	styleMgr := style.NewManager(db, &cfg.Style, webhookDispatcher)
 
 	// 初始化上下文管理器
-	contextMgr := context.NewManager(db, &cfg.Context, summaryMgr, styleMgr)
+	contextMgr := context.NewManager(db, &cfg.Context, &cfg.Prompt, summaryMgr, styleMgr)
+	contextMgr.SetLocation(cfg.Server.Location())
+
+	// 初始化快捷补全规则
+	ruleMatcher, err := rules.NewMatcher(cfg.Autocomplete.Rules)
+	if err != nil {
+		log.Fatalf("加载补全规则失败: %v", err)
+	}
+	if err := ruleMatcher.LoadFromDB(db); err != nil {
+		logrus.WithError(err).Warn("从数据库加载补全规则失败")
+	}
 
 	// 初始化自动补全引擎
-	autocompleteEngine := autocomplete.NewEngine(db, &cfg.Autocomplete, contextMgr, llmClient)
+	autocompleteEngine := autocomplete.NewEngine(db, &cfg.Autocomplete, contextMgr, llmClient, ruleMatcher)
+	autocompleteEngine.SetDailyQuota(cfg.LLM.DailyQuota, cfg.Server.Location())
+	autocompleteEngine.StartRanking()
+
+	// 提示词模板：数据库中的激活版本覆盖配置文件，可通过管理接口在线更新
+	promptStore := prompt.NewStore(db)
+	summaryMgr.SetPromptStore(promptStore)
+	contextMgr.SetPromptStore(promptStore)
 
 	// 初始化API处理器
-	handler := api.NewHandler(db, autocompleteEngine, summaryMgr, styleMgr)
+	handler := api.NewHandler(db, autocompleteEngine, contextMgr, summaryMgr, styleMgr, cfg.Server.Location(), webhookDispatcher)
+	handler.SetReanalyzeConfig(&cfg.Server.Reanalyze)
+	handler.SetDedupeConfig(&cfg.Server.Dedupe)
+	handler.SetCompletionLogLimit(cfg.Autocomplete.CompletionLogLimit)
+	handler.SetPromptStore(promptStore)
 
 	// 设置Gin模式
 	if cfg.Log.Level == "debug" {
@@ -90,13 +138,78 @@ func main() {
 		{
 			chatGroup.POST("/complete", handler.Complete)
 			chatGroup.POST("/message", handler.SaveMessage)
+			chatGroup.PUT("/message/:id", handler.EditMessage)
+			chatGroup.GET("/message/:id/edits", handler.ListMessageEdits)
+			chatGroup.POST("/message/:id/pin", handler.PinMessage)
+			chatGroup.DELETE("/message/:id/pin", handler.UnpinMessage)
+			chatGroup.GET("/:conversation_id/pinned", handler.ListPinnedMessages)
 			chatGroup.GET("/history/:conversation_id", handler.GetHistory)
+			chatGroup.GET("/:conversation_id/export", handler.ExportConversation)
+			chatGroup.POST("/:conversation_id/read", handler.MarkRead)
+			chatGroup.GET("/:conversation_id/timeline", handler.GetTimeline)
+			chatGroup.GET("/:conversation_id/alerts", handler.ListAlerts)
+			chatGroup.GET("/:conversation_id/entities", handler.ListEntities)
+			chatGroup.GET("/:conversation_id/sentiment-trend", handler.GetSentimentTrend)
+			chatGroup.GET("/:conversation_id/completions", handler.ListCompletions)
+			chatGroup.POST("/completions/:id/feedback", handler.CompletionFeedback)
+			chatGroup.POST("/import/:platform", handler.ImportConversation)
+		}
+
+		// 摘要和风格的 dry-run 预览，不落库
+		apiGroup.POST("/summary/:conversation_id/preview", handler.PreviewSummary)
+		apiGroup.POST("/style/:conversation_id/preview", handler.PreviewStyle)
+		// 批量获取摘要预览（会话列表页）
+		apiGroup.POST("/summaries/batch", handler.BatchSummaries)
+
+		apiGroup.GET("/conversations", handler.ListConversations)
+		apiGroup.POST("/conversations/merge", handler.MergeConversations)
+		// 敏感操作按参与者角色校验（请求头 X-User-ID），对话未分配 owner/admin 时不校验，但首次分配角色只允许已有参与者
+		apiGroup.PUT("/conversation/:id/state", handler.RequireConversationRole(api.ActionUpdateState), handler.UpdateConversationState)
+		apiGroup.PUT("/conversation/:id/read-only", handler.RequireConversationRole(api.ActionSetReadOnly), handler.UpdateConversationReadOnly)
+		apiGroup.PUT("/conversation/:id/settings", handler.RequireConversationRole(api.ActionUpdateSettings), handler.UpdateConversationSettings)
+		apiGroup.PUT("/conversation/:id/participants/:user_id/role", handler.RequireConversationRole(api.ActionManageRoles), handler.UpdateParticipantRole)
+		apiGroup.PUT("/conversation/:id/tags", handler.RequireConversationRole(api.ActionUpdateTags), handler.UpdateConversationTags)
+		apiGroup.PUT("/conversation/:id/note", handler.RequireConversationRole(api.ActionUpdateNote), handler.UpdateConversationNote)
+		apiGroup.GET("/conversation/:id/metadata", handler.GetConversationMetadata)
+		apiGroup.PUT("/conversation/:id/metadata", handler.RequireConversationRole(api.ActionUpdateMetadata), handler.UpdateConversationMetadata)
+		apiGroup.PATCH("/conversation/:id/metadata", handler.RequireConversationRole(api.ActionUpdateMetadata), handler.PatchConversationMetadata)
+		apiGroup.POST("/conversation/:id/snapshot", handler.RequireConversationRole(api.ActionCreateSnapshot), handler.CreateSnapshot)
+		apiGroup.GET("/conversation/:id/snapshots", handler.ListSnapshots)
+		apiGroup.POST("/conversation/:id/restore", handler.RequireConversationRole(api.ActionRestoreSnapshot), handler.RestoreSnapshot)
+		apiGroup.GET("/conversation/:id/stats", handler.GetConversationStats)
+
+		apiGroup.PUT("/user/:sender_id/profile", handler.UpdateUserProfile)
+		apiGroup.GET("/user/:sender_id/profile", handler.GetUserProfile)
+
+		// 删除用户数据（需要管理员令牌）
+		apiGroup.DELETE("/user/:sender_id/data", api.RequireAdmin(cfg.Server.AdminToken), handler.DeleteUserData)
+
+		// 管理接口（需要管理员令牌）
+		adminGroup := apiGroup.Group("/admin", api.RequireAdmin(cfg.Server.AdminToken))
+		{
+			adminGroup.POST("/reanalyze", handler.StartReanalyze)
+			adminGroup.GET("/reanalyze/:id", handler.GetReanalyze)
+			adminGroup.DELETE("/reanalyze/:id", handler.CancelReanalyze)
+			adminGroup.GET("/prompts/:name", handler.GetPromptTemplate)
+			adminGroup.PUT("/prompts/:name", handler.UpdatePromptTemplate)
+			adminGroup.DELETE("/prompts/:name", handler.DeactivatePromptTemplate)
+		}
+
+		// 调试接口：server.debug_endpoints 开启时才注册，且需要管理员令牌
+		if cfg.Server.DebugEndpoints {
+			debugGroup := apiGroup.Group("/debug", api.RequireAdmin(cfg.Server.AdminToken))
+			{
+				debugGroup.POST("/context", handler.DebugContext)
+			}
 		}
 	}
 
 	// WebSocket路由
 	router.GET("/ws", handler.HandleWebSocket)
 
+	// 运行指标（Prometheus 文本格式）
+	router.GET("/metrics", handler.Metrics)
+
 	// 健康检查
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok"})
@@ -115,25 +228,3 @@ func main() {
 		log.Fatalf("启动HTTP服务器失败: %v", err)
 	}
 }
-
-// initDatabase 初始化数据库
-func initDatabase(cfg *config.Config) (*gorm.DB, error) {
-	db, err := gorm.Open(sqlite.Open(cfg.Database.DBPath), &gorm.Config{})
-	if err != nil {
-		return nil, fmt.Errorf("连接数据库失败: %w", err)
-	}
-
-	// 自动迁移
-	if err := db.AutoMigrate(
-		&models.Conversation{},
-		&models.Message{},
-		&models.Summary{},
-		&models.Style{},
-	); err != nil {
-		return nil, fmt.Errorf("数据库迁移失败: %w", err)
-	}
-
-	logrus.Info("数据库初始化成功")
-	return db, nil
-}
-
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/textutil"
+	"ChatRecommend/internal/webhook"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// checkAlerts 检查新消息是否命中对话的关注关键词，命中时记录提醒并通过WebSocket和webhook通知
+func (h *Handler) checkAlerts(conversation *models.Conversation, message *models.Message) {
+	settings, err := conversation.GetSettings()
+	if err != nil || len(settings.AlertKeywords) == 0 {
+		return
+	}
+
+	matched := textutil.MatchKeywords(message.Content, settings.AlertKeywords)
+	if len(matched) == 0 {
+		return
+	}
+
+	alerts := make([]models.Alert, len(matched))
+	for i, keyword := range matched {
+		alerts[i] = models.Alert{
+			ConversationID: conversation.ID,
+			MessageID:      message.ID,
+			SenderID:       message.SenderID,
+			Keyword:        keyword,
+		}
+	}
+	if err := h.db.Create(&alerts).Error; err != nil {
+		logrus.WithError(err).WithField("conversation_id", conversation.ConversationID).Error("记录提醒失败")
+		return
+	}
+
+	data := gin.H{
+		"conversation_id": conversation.ConversationID,
+		"alerts":          alerts,
+	}
+	// 提醒发给对话中所有连接（包括发送者自己的其他端）
+	h.hub.Broadcast(conversation.ConversationID, &WSMessage{Type: "alert", Data: data}, nil)
+	h.hooks.Dispatch(webhook.EventKeywordAlert, data)
+
+	logrus.WithFields(logrus.Fields{
+		"conversation_id": conversation.ConversationID,
+		"keywords":        matched,
+	}).Info("消息命中关注关键词")
+}
+
+// ListAlerts 获取对话的关键词提醒，按时间倒序
+func (h *Handler) ListAlerts(c *gin.Context) {
+	conversationID := c.Param("conversation_id")
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+
+	var conversation models.Conversation
+	if err := h.db.Where("conversation_id = ?", conversationID).First(&conversation).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "对话不存在"})
+		return
+	}
+
+	var alerts []models.Alert
+	if err := h.db.Where("conversation_id = ?", conversation.ID).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Find(&alerts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询提醒失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"conversation_id": conversationID,
+		"alerts":          alerts,
+	})
+}
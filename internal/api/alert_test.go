@@ -0,0 +1,36 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 新消息命中对话的关注关键词时记录提醒，未命中的消息不产生提醒
+func TestSaveMessageRecordsKeywordAlerts(t *testing.T) {
+	s := newTestServer(t)
+	s.saveMessage(t, "conv-alert", "alice", "最近好忙")
+	decode(t, s.do(t, http.MethodPut, "/api/conversation/conv-alert/settings", gin.H{
+		"alert_keywords": []string{"生日", "纪念日"},
+	}, UserIDHeader, "alice"), http.StatusOK, nil)
+
+	s.saveMessage(t, "conv-alert", "bob", "今天天气不错")
+	hit := s.saveMessage(t, "conv-alert", "bob", "下周三是我生日")
+
+	var resp struct {
+		Alerts []struct {
+			MessageID uint   `json:"message_id"`
+			SenderID  string `json:"sender_id"`
+			Keyword   string `json:"keyword"`
+		} `json:"alerts"`
+	}
+	decode(t, s.do(t, http.MethodGet, "/api/chat/conv-alert/alerts", nil), http.StatusOK, &resp)
+	if len(resp.Alerts) != 1 {
+		t.Fatalf("提醒为 %+v，期望 1 条", resp.Alerts)
+	}
+	alert := resp.Alerts[0]
+	if alert.MessageID != hit || alert.SenderID != "bob" || alert.Keyword != "生日" {
+		t.Errorf("提醒为 %+v，期望消息 %d 命中 生日", alert, hit)
+	}
+}
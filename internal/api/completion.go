@@ -0,0 +1,209 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"ChatRecommend/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// maxCompletionPageSize 补全历史每页最多条数
+const maxCompletionPageSize = 200
+
+// CompletionLogDTO 补全历史条目
+type CompletionLogDTO struct {
+	ID            uint       `json:"id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	SenderID      string     `json:"sender_id"`
+	Input         string     `json:"input"`
+	Mode          string     `json:"mode"`
+	Suggestions   []string   `json:"suggestions"`
+	AcceptedIndex *int       `json:"accepted_index,omitempty"`
+	AcceptedAt    *time.Time `json:"accepted_at,omitempty"`
+}
+
+// toCompletionLogDTO 转换为接口返回结构，建议无法解析时返回空列表
+func toCompletionLogDTO(log *models.CompletionLog) CompletionLogDTO {
+	suggestions, err := log.GetSuggestions()
+	if err != nil {
+		suggestions = []string{}
+	}
+	return CompletionLogDTO{
+		ID:            log.ID,
+		CreatedAt:     log.CreatedAt,
+		SenderID:      log.SenderID,
+		Input:         log.Input,
+		Mode:          log.Mode,
+		Suggestions:   suggestions,
+		AcceptedIndex: log.AcceptedIndex,
+		AcceptedAt:    log.AcceptedAt,
+	}
+}
+
+// SetCompletionLogLimit 设置每个对话保留的补全历史条数，0表示不记录（需在处理请求前调用）
+func (h *Handler) SetCompletionLogLimit(limit int) {
+	h.chat.logLimit = limit
+}
+
+// recordCompletion 记录一次返回了建议的补全，并在响应中回传记录ID；超出保留条数时删除该对话最旧的记录。
+// 记录失败只打日志，不影响补全结果
+func (s *ChatService) recordCompletion(req *models.AutocompleteRequest, resp *models.AutocompleteResponse) {
+	if s.logLimit <= 0 || s.db == nil || len(resp.Suggestions) == 0 {
+		return
+	}
+
+	entry := logrus.WithField("conversation_id", req.ConversationID)
+	var conversation models.Conversation
+	if err := s.db.Where("conversation_id = ?", req.ConversationID).First(&conversation).Error; err != nil {
+		entry.WithError(err).Warn("记录补全历史失败")
+		return
+	}
+
+	mode := req.Mode
+	if mode == "" {
+		mode = models.AutocompleteModeComplete
+	}
+	log := models.CompletionLog{
+		ConversationID: conversation.ID,
+		SenderID:       req.SenderID,
+		Input:          resp.SelectedInput,
+		Mode:           mode,
+	}
+	if log.Input == "" {
+		log.Input = req.Input
+	}
+	if err := log.SetSuggestions(resp.Suggestions); err != nil {
+		entry.WithError(err).Warn("记录补全历史失败")
+		return
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&log).Error; err != nil {
+			return err
+		}
+		keep := tx.Model(&models.CompletionLog{}).Select("id").
+			Where("conversation_id = ?", conversation.ID).
+			Order("created_at DESC, id DESC").
+			Limit(s.logLimit)
+		return tx.Where("conversation_id = ? AND id NOT IN (?)", conversation.ID, keep).Delete(&models.CompletionLog{}).Error
+	})
+	if err != nil {
+		entry.WithError(err).Warn("记录补全历史失败")
+		return
+	}
+	resp.CompletionID = log.ID
+}
+
+// ListCompletions 获取对话的补全历史，按时间倒序分页：before 为上一页返回的 next_before（RFC3339 时间），
+// 可按 sender_id 和 accepted（true 只看采纳过的，false 只看未采纳的）过滤
+func (h *Handler) ListCompletions(c *gin.Context) {
+	conversationID := c.Param("conversation_id")
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+	if limit > maxCompletionPageSize {
+		limit = maxCompletionPageSize
+	}
+
+	var conversation models.Conversation
+	if err := h.db.Where("conversation_id = ?", conversationID).First(&conversation).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "对话不存在"})
+		return
+	}
+
+	query := h.db.Where("conversation_id = ?", conversation.ID)
+	if before := c.Query("before"); before != "" {
+		t, err := time.Parse(time.RFC3339Nano, before)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "before 必须是 RFC3339 时间"})
+			return
+		}
+		query = query.Where("created_at < ?", t)
+	}
+	if senderID := c.Query("sender_id"); senderID != "" {
+		query = query.Where("sender_id = ?", senderID)
+	}
+	switch c.Query("accepted") {
+	case "":
+	case "true":
+		query = query.Where("accepted_index IS NOT NULL")
+	case "false":
+		query = query.Where("accepted_index IS NULL")
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "accepted 只支持 true 或 false"})
+		return
+	}
+
+	var logs []models.CompletionLog
+	if err := query.Order("created_at DESC, id DESC").Limit(limit).Find(&logs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询补全历史失败"})
+		return
+	}
+
+	completions := make([]CompletionLogDTO, len(logs))
+	for i := range logs {
+		completions[i] = toCompletionLogDTO(&logs[i])
+	}
+	resp := gin.H{
+		"conversation_id": conversationID,
+		"completions":     completions,
+	}
+	// 取满一页时返回下一页的游标
+	if len(logs) == limit {
+		resp["next_before"] = logs[len(logs)-1].CreatedAt.Format(time.RFC3339Nano)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// CompletionFeedback 记录用户采纳了某次补全的第几条建议，重复提交时以最后一次为准
+func (h *Handler) CompletionFeedback(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的补全记录ID"})
+		return
+	}
+
+	var req models.CompletionFeedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var log models.CompletionLog
+	if err := h.db.First(&log, id).Error; err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "补全记录不存在"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询补全记录失败"})
+		return
+	}
+
+	suggestions, err := log.GetSuggestions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if *req.AcceptedIndex < 0 || *req.AcceptedIndex >= len(suggestions) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "accepted_index 超出建议范围"})
+		return
+	}
+
+	now := time.Now()
+	if err := h.db.Model(&models.CompletionLog{}).Where("id = ?", log.ID).Updates(map[string]interface{}{
+		"accepted_index": *req.AcceptedIndex,
+		"accepted_at":    now,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "记录反馈失败"})
+		return
+	}
+	log.AcceptedIndex = req.AcceptedIndex
+	log.AcceptedAt = &now
+
+	c.JSON(http.StatusOK, toCompletionLogDTO(&log))
+}
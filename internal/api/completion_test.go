@@ -0,0 +1,109 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 补全记录请求和返回的建议，反馈后记录采纳的建议；历史按时间倒序分页，超出保留条数时删除最旧的记录
+func TestCompletionHistory(t *testing.T) {
+	s := newTestServer(t)
+	s.handler.SetCompletionLogLimit(3)
+	s.saveMessage(t, "conv-history", "bob", "周末去哪")
+
+	var ids []uint
+	for _, input := range []string{"去爬山", "去看电影", "去吃饭", "在家"} {
+		var resp struct {
+			CompletionID uint `json:"completion_id"`
+		}
+		decode(t, s.do(t, http.MethodPost, "/api/chat/complete", gin.H{
+			"conversation_id": "conv-history",
+			"sender_id":       "alice",
+			"input":           input,
+		}), http.StatusOK, &resp)
+		if resp.CompletionID == 0 {
+			t.Fatalf("补全响应缺少 completion_id")
+		}
+		ids = append(ids, resp.CompletionID)
+	}
+
+	var accepted CompletionLogDTO
+	decode(t, s.do(t, http.MethodPost, fmt.Sprintf("/api/chat/completions/%d/feedback", ids[2]), gin.H{"accepted_index": 1}),
+		http.StatusOK, &accepted)
+	if accepted.AcceptedIndex == nil || *accepted.AcceptedIndex != 1 || accepted.AcceptedAt == nil {
+		t.Errorf("反馈后的记录为 %+v", accepted)
+	}
+	decode(t, s.do(t, http.MethodPost, fmt.Sprintf("/api/chat/completions/%d/feedback", ids[2]), gin.H{"accepted_index": 5}),
+		http.StatusBadRequest, nil)
+	decode(t, s.do(t, http.MethodPost, fmt.Sprintf("/api/chat/completions/%d/feedback", ids[0]), gin.H{"accepted_index": 0}),
+		http.StatusNotFound, nil)
+
+	type page struct {
+		Completions []CompletionLogDTO `json:"completions"`
+		NextBefore  string             `json:"next_before"`
+	}
+	var first page
+	decode(t, s.do(t, http.MethodGet, "/api/chat/conv-history/completions?limit=2", nil), http.StatusOK, &first)
+	if len(first.Completions) != 2 || first.Completions[0].Input != "在家" || first.Completions[1].Input != "去吃饭" || first.NextBefore == "" {
+		t.Fatalf("第一页为 %+v", first)
+	}
+	if len(first.Completions[0].Suggestions) == 0 {
+		t.Errorf("记录缺少返回的建议: %+v", first.Completions[0])
+	}
+
+	var second page
+	decode(t, s.do(t, http.MethodGet, "/api/chat/conv-history/completions?limit=2&before="+first.NextBefore, nil), http.StatusOK, &second)
+	if len(second.Completions) != 1 || second.Completions[0].Input != "去看电影" || second.NextBefore != "" {
+		t.Errorf("第二页为 %+v，期望只剩去看电影（最旧的记录已删除）", second)
+	}
+
+	var filtered page
+	decode(t, s.do(t, http.MethodGet, "/api/chat/conv-history/completions?accepted=true", nil), http.StatusOK, &filtered)
+	if len(filtered.Completions) != 1 || filtered.Completions[0].ID != ids[2] {
+		t.Errorf("只看采纳过的记录为 %+v", filtered)
+	}
+	decode(t, s.do(t, http.MethodGet, "/api/chat/conv-history/completions?accepted=maybe", nil), http.StatusBadRequest, nil)
+}
+
+// 对话设置关闭补全后，HTTP 补全返回403 AUTOCOMPLETE_DISABLED，WebSocket 收到 autocomplete_disabled，都不调用大模型；重新开启后恢复
+func TestAutocompleteDisabledByConversationSettings(t *testing.T) {
+	s := newTestServer(t)
+	s.saveMessage(t, "conv-switch", "bob", "周末去哪")
+	decode(t, s.do(t, http.MethodPut, "/api/conversation/conv-switch/settings", gin.H{"autocomplete_enabled": false}, UserIDHeader, "bob"), http.StatusOK, nil)
+	calls := s.llm.Calls()
+
+	var body ErrorBody
+	decode(t, s.do(t, http.MethodPost, "/api/chat/complete", gin.H{
+		"conversation_id": "conv-switch",
+		"sender_id":       "alice",
+		"input":           "去爬山",
+	}), http.StatusForbidden, &body)
+	if body.Code != ErrCodeAutocompleteDisabled || body.Retriable {
+		t.Errorf("错误为 %+v，期望不可重试的 %s", body, ErrCodeAutocompleteDisabled)
+	}
+
+	conn := s.dialWS(t, "")
+	conn.send(t, map[string]interface{}{
+		"type": "autocomplete",
+		"autocomplete_request": map[string]interface{}{
+			"conversation_id": "conv-switch", "sender_id": "alice", "input": "去爬山",
+		},
+	})
+	msg := conn.next(t)
+	data, _ := msg["data"].(map[string]interface{})
+	if msg["type"] != "autocomplete_disabled" || data["conversation_id"] != "conv-switch" {
+		t.Errorf("WebSocket 收到 %v，期望 autocomplete_disabled", msg)
+	}
+	if s.llm.Calls() != calls {
+		t.Fatalf("关闭补全后不应调用大模型，多调用了 %d 次", s.llm.Calls()-calls)
+	}
+
+	decode(t, s.do(t, http.MethodPut, "/api/conversation/conv-switch/settings", gin.H{"autocomplete_enabled": true}, UserIDHeader, "bob"), http.StatusOK, nil)
+	s.complete(t, "conv-switch", "去爬山")
+	if s.llm.Calls() != calls+1 {
+		t.Error("重新开启后应调用大模型")
+	}
+}
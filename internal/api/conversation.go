@@ -0,0 +1,418 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"ChatRecommend/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// conversationItem 对话列表项
+type conversationItem struct {
+	models.Conversation
+	// 当前用户的未读数（仅在传入 user_id 时返回）
+	UnreadCount *int64 `json:"unread_count,omitempty"`
+}
+
+// ListConversations 获取对话列表
+// 查询参数：archived=false（默认，仅未归档）|true（仅归档）|all；pinned=true|false；tag（按标签筛选）；meta.<key>（按自定义字段筛选）；user_id（返回未读数）；limit；offset
+// 置顶的对话排在前面，其余按最后消息时间倒序
+func (h *Handler) ListConversations(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	query := h.db.Model(&models.Conversation{})
+
+	switch archived := c.DefaultQuery("archived", "false"); archived {
+	case "all":
+	case "true", "false":
+		query = query.Where("archived = ?", archived == "true")
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "archived 只能为 true、false 或 all"})
+		return
+	}
+
+	if pinned := c.Query("pinned"); pinned != "" {
+		value, err := strconv.ParseBool(pinned)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "pinned 只能为 true 或 false"})
+			return
+		}
+		query = query.Where("pinned = ?", value)
+	}
+
+	if tag := c.Query("tag"); tag != "" {
+		query = query.Where("tags LIKE ? ESCAPE '\\'", models.TagPattern(tag))
+	}
+
+	filters, err := models.ParseMetadataFilters(c.Request.URL.Query())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for _, filter := range filters {
+		clause, args := filter.SQL()
+		query = query.Where(clause, args...)
+	}
+
+	var conversations []models.Conversation
+	if err := query.Order("pinned DESC, last_message_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&conversations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询对话失败"})
+		return
+	}
+
+	items := make([]conversationItem, len(conversations))
+	for i, conversation := range conversations {
+		items[i] = conversationItem{Conversation: conversation}
+	}
+
+	if userID := c.Query("user_id"); userID != "" && len(conversations) > 0 {
+		ids := make([]uint, len(conversations))
+		for i, conversation := range conversations {
+			ids[i] = conversation.ID
+		}
+		counts, err := h.countUnread(ids, userID)
+		if err != nil {
+			logrus.WithError(err).Error("统计未读数失败")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "统计未读数失败"})
+			return
+		}
+		for i := range items {
+			count := counts[items[i].ID]
+			items[i].UnreadCount = &count
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"conversations": items,
+	})
+}
+
+// UpdateConversationState 更新对话的置顶/归档状态
+func (h *Handler) UpdateConversationState(c *gin.Context) {
+	var req models.UpdateConversationStateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if req.Pinned != nil {
+		updates["pinned"] = *req.Pinned
+	}
+	if req.Archived != nil {
+		updates["archived"] = *req.Archived
+		// 归档即结束对话，同时设为只读；取消归档不解除只读
+		if *req.Archived {
+			updates["read_only"] = true
+		}
+	}
+	if len(updates) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "pinned 和 archived 不能同时为空"})
+		return
+	}
+
+	var conversation models.Conversation
+	err := h.db.Where("conversation_id = ?", c.Param("id")).First(&conversation).Error
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "对话不存在"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询对话失败"})
+		return
+	}
+
+	if err := h.db.Model(&conversation).Updates(updates).Error; err != nil {
+		logrus.WithError(err).Error("更新对话状态失败")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新对话状态失败"})
+		return
+	}
+	if req.Pinned != nil {
+		conversation.Pinned = *req.Pinned
+	}
+	if req.Archived != nil {
+		conversation.Archived = *req.Archived
+		conversation.ReadOnly = conversation.ReadOnly || *req.Archived
+	}
+
+	c.JSON(http.StatusOK, conversation)
+}
+
+// UpdateConversationReadOnly 设置或解除对话只读（解除只读只能通过此接口）
+func (h *Handler) UpdateConversationReadOnly(c *gin.Context) {
+	var req models.UpdateConversationReadOnlyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var conversation models.Conversation
+	err := h.db.Where("conversation_id = ?", c.Param("id")).First(&conversation).Error
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "对话不存在"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询对话失败"})
+		return
+	}
+
+	if err := h.db.Model(&conversation).Update("read_only", *req.ReadOnly).Error; err != nil {
+		logrus.WithError(err).Error("更新对话只读状态失败")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新对话只读状态失败"})
+		return
+	}
+	conversation.ReadOnly = *req.ReadOnly
+
+	logrus.WithFields(logrus.Fields{
+		"conversation_id": conversation.ConversationID,
+		"read_only":       conversation.ReadOnly,
+	}).Info("对话只读状态已更新")
+
+	c.JSON(http.StatusOK, conversation)
+}
+
+// UpdateConversationTags 更新对话标签（整体替换，归一化去重）
+func (h *Handler) UpdateConversationTags(c *gin.Context) {
+	var req models.UpdateConversationTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var conversation models.Conversation
+	err := h.db.Where("conversation_id = ?", c.Param("id")).First(&conversation).Error
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "对话不存在"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询对话失败"})
+		return
+	}
+
+	if err := conversation.SetTags(req.Tags); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.db.Model(&conversation).Update("tags", conversation.Tags).Error; err != nil {
+		logrus.WithError(err).Error("更新对话标签失败")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新对话标签失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, conversation)
+}
+
+// UpdateConversationNote 更新对话备注
+func (h *Handler) UpdateConversationNote(c *gin.Context) {
+	var req models.UpdateConversationNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len([]rune(req.Note)) > models.MaxNoteChars {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("备注不能超过 %d 个字符", models.MaxNoteChars)})
+		return
+	}
+
+	var conversation models.Conversation
+	err := h.db.Where("conversation_id = ?", c.Param("id")).First(&conversation).Error
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "对话不存在"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询对话失败"})
+		return
+	}
+
+	if err := h.db.Model(&conversation).Update("note", req.Note).Error; err != nil {
+		logrus.WithError(err).Error("更新对话备注失败")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新对话备注失败"})
+		return
+	}
+	conversation.Note = req.Note
+
+	c.JSON(http.StatusOK, conversation)
+}
+
+// GetConversationMetadata 获取对话自定义字段
+func (h *Handler) GetConversationMetadata(c *gin.Context) {
+	var conversation models.Conversation
+	err := h.db.Where("conversation_id = ?", c.Param("id")).First(&conversation).Error
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "对话不存在"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询对话失败"})
+		return
+	}
+
+	metadata, err := conversation.GetMetadata()
+	if err != nil {
+		logrus.WithError(err).Warn("解析对话自定义字段失败")
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"conversation_id": conversation.ConversationID,
+		"metadata":        metadata,
+	})
+}
+
+// UpdateConversationMetadata 更新对话自定义字段（整体替换）
+func (h *Handler) UpdateConversationMetadata(c *gin.Context) {
+	h.saveConversationMetadata(c, false)
+}
+
+// PatchConversationMetadata 合并更新对话自定义字段，值为 null 的键被删除
+func (h *Handler) PatchConversationMetadata(c *gin.Context) {
+	h.saveConversationMetadata(c, true)
+}
+
+// saveConversationMetadata 整体替换或合并保存对话自定义字段
+func (h *Handler) saveConversationMetadata(c *gin.Context, merge bool) {
+	var req models.UpdateConversationMetadataRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var conversation models.Conversation
+	err := h.db.Where("conversation_id = ?", c.Param("id")).First(&conversation).Error
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "对话不存在"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询对话失败"})
+		return
+	}
+
+	if merge {
+		err = conversation.MergeMetadata(req.Metadata)
+	} else {
+		err = conversation.SetMetadata(req.Metadata)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.db.Model(&conversation).Update("metadata", conversation.Metadata).Error; err != nil {
+		logrus.WithError(err).Error("更新对话自定义字段失败")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新对话自定义字段失败"})
+		return
+	}
+
+	metadata, _ := conversation.GetMetadata()
+	c.JSON(http.StatusOK, gin.H{
+		"conversation_id": conversation.ConversationID,
+		"metadata":        metadata,
+	})
+}
+
+// UpdateConversationSettings 更新对话级设置（整体替换）
+func (h *Handler) UpdateConversationSettings(c *gin.Context) {
+	var settings models.ConversationSettings
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var conversation models.Conversation
+	err := h.db.Where("conversation_id = ?", c.Param("id")).First(&conversation).Error
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "对话不存在"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询对话失败"})
+		return
+	}
+
+	if err := conversation.SetSettings(&settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.db.Model(&conversation).Update("settings", conversation.Settings).Error; err != nil {
+		logrus.WithError(err).Error("更新对话设置失败")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新对话设置失败"})
+		return
+	}
+
+	h.autocomplete.InvalidateCache(conversation.ConversationID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"conversation_id": conversation.ConversationID,
+		"settings":        settings,
+	})
+}
+
+// UpdateParticipantRole 设置对话参与者的角色，用户不是参与者时加入。对话至少保留一个 owner
+func (h *Handler) UpdateParticipantRole(c *gin.Context) {
+	var req models.UpdateParticipantRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !models.ValidRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role 只能为 owner、admin 或 member"})
+		return
+	}
+
+	var conversation models.Conversation
+	err := h.db.Where("conversation_id = ?", c.Param("id")).First(&conversation).Error
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "对话不存在"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询对话失败"})
+		return
+	}
+
+	userID := c.Param("user_id")
+	if conversation.ParticipantRole(userID) == models.RoleOwner && req.Role != models.RoleOwner {
+		owners := 0
+		for _, p := range conversation.GetParticipants() {
+			if p.Role == models.RoleOwner {
+				owners++
+			}
+		}
+		if owners == 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "对话至少需要保留一个 owner"})
+			return
+		}
+	}
+
+	if err := conversation.SetParticipantRole(userID, req.Role); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.db.Model(&conversation).Update("participants", conversation.Participants).Error; err != nil {
+		logrus.WithError(err).Error("更新参与者角色失败")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新参与者角色失败"})
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"conversation_id": conversation.ConversationID,
+		"user_id":         userID,
+		"role":            req.Role,
+	}).Info("参与者角色已更新")
+
+	c.JSON(http.StatusOK, gin.H{
+		"conversation_id": conversation.ConversationID,
+		"participants":    conversation.GetParticipants(),
+	})
+}
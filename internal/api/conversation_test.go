@@ -0,0 +1,61 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listConversationIDs 查询对话列表，返回对话ID（按返回顺序）
+func (s *testServer) listConversationIDs(t *testing.T, query string) []string {
+	t.Helper()
+	var resp struct {
+		Conversations []struct {
+			ConversationID string `json:"conversation_id"`
+		} `json:"conversations"`
+	}
+	decode(t, s.do(t, http.MethodGet, "/api/conversations"+query, nil), http.StatusOK, &resp)
+	ids := make([]string, len(resp.Conversations))
+	for i, conversation := range resp.Conversations {
+		ids[i] = conversation.ConversationID
+	}
+	return ids
+}
+
+func assertIDs(t *testing.T, what string, got []string, want ...string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s 为 %v，期望 %v", what, got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("%s 为 %v，期望 %v", what, got, want)
+		}
+	}
+}
+
+// 置顶的对话排在前面，归档的对话默认不返回，可按状态过滤
+func TestListConversationsStateFilterAndOrder(t *testing.T) {
+	s := newTestServer(t)
+	// 依次产生消息，最后消息时间 conv-c 最新
+	for _, id := range []string{"conv-a", "conv-b", "conv-c"} {
+		s.saveMessage(t, id, "alice", "你好")
+	}
+
+	if w := s.do(t, http.MethodPut, "/api/conversation/conv-a/state", gin.H{"pinned": true}, UserIDHeader, "alice"); w.Code != http.StatusOK {
+		t.Fatalf("置顶对话返回 %d: %s", w.Code, w.Body.String())
+	}
+	if w := s.do(t, http.MethodPut, "/api/conversation/conv-b/state", gin.H{"archived": true}, UserIDHeader, "alice"); w.Code != http.StatusOK {
+		t.Fatalf("归档对话返回 %d: %s", w.Code, w.Body.String())
+	}
+
+	assertIDs(t, "默认列表", s.listConversationIDs(t, ""), "conv-a", "conv-c")
+	assertIDs(t, "归档列表", s.listConversationIDs(t, "?archived=true"), "conv-b")
+	assertIDs(t, "全部对话", s.listConversationIDs(t, "?archived=all"), "conv-a", "conv-c", "conv-b")
+	assertIDs(t, "未置顶对话", s.listConversationIDs(t, "?pinned=false"), "conv-c")
+
+	if w := s.do(t, http.MethodGet, "/api/conversations?archived=maybe", nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("非法 archived 参数返回 %d，期望 400", w.Code)
+	}
+}
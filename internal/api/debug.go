@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net/http"
+
+	"ChatRecommend/internal/context"
+	"ChatRecommend/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// DebugContextRequest 上下文调试请求
+type DebugContextRequest struct {
+	ConversationID    string `json:"conversation_id" binding:"required"`
+	SenderID          string `json:"sender_id" binding:"required"`
+	Input             string `json:"input"`
+	ExtraInstructions string `json:"extra_instructions,omitempty"`
+	PrivacyMode       bool   `json:"privacy_mode,omitempty"`
+	PersonaStyle      string `json:"persona_style,omitempty"`
+	Mode              string `json:"mode,omitempty"`
+}
+
+// DebugContext 构建上下文但不调用大模型，返回完整上下文及各组成部分
+func (h *Handler) DebugContext(c *gin.Context) {
+	var req DebugContextRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var conversation models.Conversation
+	err := h.db.Where("conversation_id = ?", req.ConversationID).First(&conversation).Error
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "对话不存在"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询对话失败"})
+		return
+	}
+
+	detail, err := h.contextMgr.BuildContextDetail(conversation.ID, req.SenderID, req.Input, context.BuildOptions{
+		ExtraInstructions: req.ExtraInstructions,
+		PrivacyMode:       req.PrivacyMode,
+		PersonaStyle:      req.PersonaStyle,
+		Rewrite:           req.Mode == models.AutocompleteModeRewrite,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, detail)
+}
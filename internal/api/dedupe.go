@@ -0,0 +1,93 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrDuplicateMessage 新消息与对话最近一条消息重复（server.dedupe.action 为 reject 时返回）
+var ErrDuplicateMessage = errors.New("消息与最近一条消息重复")
+
+// 重复消息的处理方式
+const (
+	// DedupeMerge 不写入新消息，返回已有的那条
+	DedupeMerge = "merge"
+	// DedupeReject 拒绝新消息
+	DedupeReject = "reject"
+)
+
+// dedupeSettings 相邻重复消息检测设置，window 为0时不检测
+type dedupeSettings struct {
+	window time.Duration
+	action string
+}
+
+// newDedupeSettings 按 server.dedupe 配置创建重复消息检测设置
+func newDedupeSettings(cfg *config.DedupeConfig) dedupeSettings {
+	return dedupeSettings{
+		window: time.Duration(cfg.WindowSeconds) * time.Second,
+		action: cfg.Action,
+	}
+}
+
+// SetDedupeConfig 设置重复消息检测的时间窗口和处理方式（需在处理请求前调用）
+func (h *Handler) SetDedupeConfig(cfg *config.DedupeConfig) {
+	h.dedupe = newDedupeSettings(cfg)
+}
+
+// isDuplicateMessage 判断 next 是否与 prev 重复：同一发送者、内容完全相同，且时间相差不超过窗口（草稿不参与）
+func isDuplicateMessage(prev, next *models.Message, window time.Duration) bool {
+	if prev == nil || window <= 0 {
+		return false
+	}
+	if prev.MessageType == models.MessageTypeDraft || next.MessageType == models.MessageTypeDraft {
+		return false
+	}
+	if prev.SenderID != next.SenderID || prev.Content != next.Content {
+		return false
+	}
+	gap := next.CreatedAt.Sub(prev.CreatedAt)
+	if gap < 0 {
+		gap = -gap
+	}
+	return gap <= window
+}
+
+// latestMessage 查询对话最近一条非草稿消息，没有消息时返回 nil
+func latestMessage(tx *gorm.DB, conversationID uint) (*models.Message, error) {
+	var messages []models.Message
+	if err := tx.Where("conversation_id = ?", conversationID).
+		Scopes(models.ExcludeDrafts).
+		Order("sequence DESC, created_at DESC").
+		Limit(1).
+		Find(&messages).Error; err != nil {
+		return nil, fmt.Errorf("查询最近消息失败: %w", err)
+	}
+	if len(messages) == 0 {
+		return nil, nil
+	}
+	return &messages[0], nil
+}
+
+// dedupeMessages 去掉与前一条消息重复的消息（第一条与 last 比较，last 为对话中已有的最后一条消息），返回保留的消息和去掉的条数
+func dedupeMessages(last *models.Message, messages []models.Message, window time.Duration) ([]models.Message, int) {
+	if window <= 0 {
+		return messages, 0
+	}
+
+	kept := make([]models.Message, 0, len(messages))
+	prev := last
+	for i := range messages {
+		if isDuplicateMessage(prev, &messages[i], window) {
+			continue
+		}
+		kept = append(kept, messages[i])
+		prev = &messages[i]
+	}
+	return kept, len(messages) - len(kept)
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/importer"
+	"ChatRecommend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// 合并模式下与最近一条消息重复的消息不再写入，返回已有消息；发送者或内容不同时正常保存
+func TestSaveMessageMergesDuplicate(t *testing.T) {
+	s := newTestServer(t)
+	s.handler.SetDedupeConfig(&config.DedupeConfig{WindowSeconds: 60, Action: DedupeMerge})
+
+	first := s.saveMessage(t, "conv-dedupe", "alice", "在吗")
+	var resp struct {
+		MessageID uint   `json:"message_id"`
+		Status    string `json:"status"`
+	}
+	decode(t, s.do(t, http.MethodPost, "/api/chat/message", gin.H{
+		"conversation_id": "conv-dedupe",
+		"sender_id":       "alice",
+		"content":         "在吗",
+	}), http.StatusOK, &resp)
+	if resp.MessageID != first || resp.Status != "duplicate" {
+		t.Errorf("重复消息的响应为 %+v，期望返回已有消息 %d", resp, first)
+	}
+	s.saveMessage(t, "conv-dedupe", "bob", "在吗")
+	s.saveMessage(t, "conv-dedupe", "alice", "在吗？")
+
+	var count int64
+	s.db.Model(&models.Message{}).Count(&count)
+	if count != 3 {
+		t.Errorf("保存了 %d 条消息，期望 3 条", count)
+	}
+}
+
+// 批量导入时丢弃与前一条（含对话中已有的最后一条）重复的消息，超出时间窗口的相同消息保留
+func TestImportMessagesDeduplicates(t *testing.T) {
+	s := newTestServer(t)
+	dedupe := newDedupeSettings(&config.DedupeConfig{WindowSeconds: 60, Action: DedupeMerge})
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	if _, _, err := importMessages(s.db, dedupe, "conv-import", []importer.Message{
+		{SenderID: "alice", Content: "到了吗", Time: base},
+	}, false); err != nil {
+		t.Fatalf("导入失败: %v", err)
+	}
+
+	_, duplicates, err := importMessages(s.db, dedupe, "conv-import", []importer.Message{
+		{SenderID: "alice", Content: "到了吗", Time: base.Add(time.Second)},
+		{SenderID: "bob", Content: "快了", Time: base.Add(2 * time.Second)},
+		{SenderID: "bob", Content: "快了", Time: base.Add(3 * time.Second)},
+		{SenderID: "bob", Content: "快了", Time: base.Add(5 * time.Minute)},
+	}, false)
+	if err != nil {
+		t.Fatalf("导入失败: %v", err)
+	}
+	if duplicates != 2 {
+		t.Errorf("丢弃了 %d 条重复消息，期望 2 条", duplicates)
+	}
+	var count int64
+	s.db.Model(&models.Message{}).Count(&count)
+	if count != 3 {
+		t.Errorf("导入后有 %d 条消息，期望 3 条", count)
+	}
+
+	// 窗口为0时不去重
+	if _, duplicates, err = importMessages(s.db, dedupeSettings{}, "conv-import", []importer.Message{
+		{SenderID: "bob", Content: "快了", Time: base.Add(6 * time.Minute)},
+	}, false); err != nil || duplicates != 0 {
+		t.Errorf("未开启去重时丢弃 %d 条 (%v)", duplicates, err)
+	}
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ChatRecommend/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// EditMessage 编辑消息内容，编辑前的内容记入编辑历史
+func (h *Handler) EditMessage(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "消息ID无效"})
+		return
+	}
+
+	var req models.EditMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var message models.Message
+	if err := h.db.First(&message, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "消息不存在"})
+		return
+	}
+	if message.SenderID != req.SenderID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只能编辑自己发送的消息"})
+		return
+	}
+	var conversation models.Conversation
+	if err := h.db.First(&conversation, message.ConversationID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "对话不存在"})
+		return
+	}
+	if conversation.ReadOnly {
+		respondError(c, ErrConversationReadOnly)
+		return
+	}
+	if message.Content == req.Content {
+		c.JSON(http.StatusOK, gin.H{"message": models.ToMessageDTOs([]models.Message{message})[0], "status": "unchanged"})
+		return
+	}
+
+	now := time.Now()
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		edit := models.MessageEdit{
+			ConversationID: message.ConversationID,
+			MessageID:      message.ID,
+			OldContent:     message.Content,
+			EditedAt:       now,
+		}
+		if err := tx.Create(&edit).Error; err != nil {
+			return fmt.Errorf("记录编辑历史失败: %w", err)
+		}
+
+		// 用 Save 而不是 Update 列，保证启用加密时新内容经过加密钩子
+		message.Content = req.Content
+		message.EditedAt = &now
+		if err := tx.Save(&message).Error; err != nil {
+			return fmt.Errorf("更新消息失败: %w", err)
+		}
+		return reindexEntities(tx, message)
+	})
+	if err != nil {
+		logrus.WithError(err).Error("编辑消息失败")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.autocomplete.InvalidateCache(conversation.ConversationID)
+	h.contextMgr.InvalidateEmotion(conversation.ID)
+	h.hub.Broadcast(conversation.ConversationID, &WSMessage{
+		Type: "message_edited",
+		Data: gin.H{
+			"conversation_id": conversation.ConversationID,
+			"message":         models.ToMessageDTOs([]models.Message{message})[0],
+		},
+	}, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": models.ToMessageDTOs([]models.Message{message})[0],
+		"status":  "success",
+	})
+}
+
+// ListMessageEdits 获取消息的编辑历史，按编辑时间正序
+func (h *Handler) ListMessageEdits(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "消息ID无效"})
+		return
+	}
+
+	var message models.Message
+	if err := h.db.First(&message, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "消息不存在"})
+		return
+	}
+
+	var edits []models.MessageEdit
+	if err := h.db.Where("message_id = ?", message.ID).
+		Order("edited_at ASC, id ASC").
+		Find(&edits).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询编辑历史失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message_id":      message.ID,
+		"current_content": message.Content,
+		"edits":           edits,
+	})
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"ChatRecommend/internal/encryption"
+	"ChatRecommend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// 启用加密时编辑后的内容和编辑历史都以密文落库，接口返回明文，历史按编辑顺序记录每次编辑前的内容
+func TestEditMessageReencryptsAndRecordsHistory(t *testing.T) {
+	cipher, err := encryption.NewContentCipher("v1", map[string]string{"v1": "test-key"})
+	if err != nil {
+		t.Fatalf("创建加密器失败: %v", err)
+	}
+	models.SetContentCipher(cipher)
+	t.Cleanup(func() { models.SetContentCipher(nil) })
+
+	s := newTestServer(t)
+	id := s.saveMessage(t, "conv_edit", "alice", "明天三点见")
+	path := fmt.Sprintf("/api/chat/message/%d", id)
+
+	for _, content := range []string{"明天四点见", "明天五点见"} {
+		var resp struct {
+			Status  string `json:"status"`
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		}
+		decode(t, s.do(t, http.MethodPut, path, gin.H{"sender_id": "alice", "content": content}), http.StatusOK, &resp)
+		if resp.Status != "success" || resp.Message.Content != content {
+			t.Fatalf("编辑返回 %+v", resp)
+		}
+	}
+
+	var stored string
+	s.db.Raw("SELECT content FROM messages WHERE id = ?", id).Scan(&stored)
+	if !encryption.IsEncrypted(stored) {
+		t.Fatalf("编辑后的内容应以密文落库: %q", stored)
+	}
+	if plaintext, err := cipher.Decrypt(stored); err != nil || plaintext != "明天五点见" {
+		t.Fatalf("密文解密为 %q, %v", plaintext, err)
+	}
+	var storedEdits []string
+	s.db.Raw("SELECT old_content FROM message_edits WHERE message_id = ? ORDER BY id", id).Scan(&storedEdits)
+	if len(storedEdits) != 2 {
+		t.Fatalf("编辑历史 %d 条，期望 2", len(storedEdits))
+	}
+	for _, old := range storedEdits {
+		if !encryption.IsEncrypted(old) {
+			t.Fatalf("编辑历史应以密文落库: %q", old)
+		}
+	}
+
+	var history struct {
+		CurrentContent string `json:"current_content"`
+		Edits          []struct {
+			OldContent string `json:"old_content"`
+		} `json:"edits"`
+	}
+	decode(t, s.do(t, http.MethodGet, path+"/edits", nil), http.StatusOK, &history)
+	if history.CurrentContent != "明天五点见" || len(history.Edits) != 2 ||
+		history.Edits[0].OldContent != "明天三点见" || history.Edits[1].OldContent != "明天四点见" {
+		t.Fatalf("编辑历史为 %+v", history)
+	}
+
+	var message models.Message
+	s.db.First(&message, id)
+	if message.EditedAt == nil {
+		t.Fatal("编辑后应记录 edited_at")
+	}
+
+	// 只能编辑自己的消息
+	w := s.do(t, http.MethodPut, path, gin.H{"sender_id": "bob", "content": "改掉"})
+	decode(t, w, http.StatusForbidden, nil)
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"ChatRecommend/internal/entity"
+	"ChatRecommend/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// indexEntities 抽取消息中的实体并写入实体索引（草稿不建索引）
+func indexEntities(tx *gorm.DB, messages []models.Message) error {
+	var entities []models.MessageEntity
+	for _, message := range messages {
+		if message.MessageType == models.MessageTypeDraft {
+			continue
+		}
+		for _, e := range entity.Extract(message.Content) {
+			entities = append(entities, models.MessageEntity{
+				ConversationID: message.ConversationID,
+				MessageID:      message.ID,
+				Type:           string(e.Type),
+				Value:          e.Value,
+				Offset:         e.Offset,
+			})
+		}
+	}
+	if len(entities) == 0 {
+		return nil
+	}
+	if err := tx.CreateInBatches(&entities, 200).Error; err != nil {
+		return fmt.Errorf("写入实体索引失败: %w", err)
+	}
+	return nil
+}
+
+// reindexEntities 消息内容变化后重建该消息的实体索引
+func reindexEntities(tx *gorm.DB, message models.Message) error {
+	if err := tx.Where("message_id = ?", message.ID).Delete(&models.MessageEntity{}).Error; err != nil {
+		return fmt.Errorf("清除实体索引失败: %w", err)
+	}
+	return indexEntities(tx, []models.Message{message})
+}
+
+// ListEntities 查询对话中抽取到的实体，按时间倒序，可按类型过滤
+func (h *Handler) ListEntities(c *gin.Context) {
+	conversationID := c.Param("conversation_id")
+
+	entityType := c.Query("type")
+	if entityType != "" && !entity.ValidType(entityType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("不支持的实体类型: %s", entityType)})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+
+	var conversation models.Conversation
+	if err := h.db.Where("conversation_id = ?", conversationID).First(&conversation).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "对话不存在"})
+		return
+	}
+
+	query := h.db.Where("conversation_id = ?", conversation.ID)
+	if entityType != "" {
+		query = query.Where("type = ?", entityType)
+	}
+	var entities []models.MessageEntity
+	if err := query.Order("created_at DESC, id DESC").
+		Limit(limit).
+		Find(&entities).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询实体失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"conversation_id": conversationID,
+		"entities":        entities,
+	})
+}
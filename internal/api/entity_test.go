@@ -0,0 +1,45 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"ChatRecommend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// 保存消息时抽取实体建立索引，可按类型查询；编辑消息后重建该消息的索引
+func TestListEntities(t *testing.T) {
+	s := newTestServer(t)
+	first := s.saveMessage(t, "conv-entity", "alice", "明天下午3点在海底捞火锅见")
+	s.saveMessage(t, "conv-entity", "bob", "好，我带上王老师，人均100元")
+
+	type entitiesResp struct {
+		Entities []models.MessageEntity `json:"entities"`
+	}
+	var all entitiesResp
+	decode(t, s.do(t, http.MethodGet, "/api/chat/conv-entity/entities", nil), http.StatusOK, &all)
+	if len(all.Entities) != 4 {
+		t.Fatalf("抽取到 %d 个实体，期望 4 个: %+v", len(all.Entities), all.Entities)
+	}
+
+	var locations entitiesResp
+	decode(t, s.do(t, http.MethodGet, "/api/chat/conv-entity/entities?type=location", nil), http.StatusOK, &locations)
+	if len(locations.Entities) != 1 || locations.Entities[0].Value != "海底捞火锅" || locations.Entities[0].MessageID != first {
+		t.Fatalf("地点实体为 %+v，期望消息 %d 中的海底捞火锅", locations.Entities, first)
+	}
+
+	decode(t, s.do(t, http.MethodPut, fmt.Sprintf("/api/chat/message/%d", first), gin.H{
+		"sender_id": "alice",
+		"content":   "明天下午3点在万达广场见",
+	}), http.StatusOK, nil)
+	locations = entitiesResp{}
+	decode(t, s.do(t, http.MethodGet, "/api/chat/conv-entity/entities?type=location", nil), http.StatusOK, &locations)
+	if len(locations.Entities) != 1 || locations.Entities[0].Value != "万达广场" {
+		t.Errorf("编辑后地点实体为 %+v，期望万达广场", locations.Entities)
+	}
+
+	decode(t, s.do(t, http.MethodGet, "/api/chat/conv-entity/entities?type=food", nil), http.StatusBadRequest, nil)
+	decode(t, s.do(t, http.MethodGet, "/api/chat/conv-missing/entities", nil), http.StatusNotFound, nil)
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"ChatRecommend/internal/autocomplete"
+	"ChatRecommend/internal/llm"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ErrorCode 错误码
+type ErrorCode string
+
+// 错误码枚举
+const (
+	ErrCodeInvalidRequest       ErrorCode = "INVALID_REQUEST"
+	ErrCodeUnknownType          ErrorCode = "UNKNOWN_TYPE"
+	ErrCodeNotFound             ErrorCode = "NOT_FOUND"
+	ErrCodeLLMTimeout           ErrorCode = "LLM_TIMEOUT"
+	ErrCodeLLMError             ErrorCode = "LLM_ERROR"
+	ErrCodeRateLimited          ErrorCode = "RATE_LIMITED"
+	ErrCodeUnauthorized         ErrorCode = "UNAUTHORIZED"
+	ErrCodeForbidden            ErrorCode = "FORBIDDEN"
+	ErrCodeDuplicate            ErrorCode = "DUPLICATE"
+	ErrCodeAutocompleteDisabled ErrorCode = "AUTOCOMPLETE_DISABLED"
+	ErrCodeInternal             ErrorCode = "INTERNAL_ERROR"
+)
+
+// ErrorBody 结构化错误
+type ErrorBody struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+	// 原样重试是否可能成功（超时、大模型故障）
+	Retriable bool `json:"retriable"`
+}
+
+// errorStatus 错误码对应的HTTP状态码
+var errorStatus = map[ErrorCode]int{
+	ErrCodeInvalidRequest:       http.StatusBadRequest,
+	ErrCodeUnknownType:          http.StatusBadRequest,
+	ErrCodeNotFound:             http.StatusNotFound,
+	ErrCodeLLMTimeout:           http.StatusGatewayTimeout,
+	ErrCodeLLMError:             http.StatusBadGateway,
+	ErrCodeRateLimited:          http.StatusTooManyRequests,
+	ErrCodeUnauthorized:         http.StatusUnauthorized,
+	ErrCodeForbidden:            http.StatusForbidden,
+	ErrCodeDuplicate:            http.StatusConflict,
+	ErrCodeAutocompleteDisabled: http.StatusForbidden,
+	ErrCodeInternal:             http.StatusInternalServerError,
+}
+
+// newErrorBody 构造结构化错误
+func newErrorBody(code ErrorCode, message string) *ErrorBody {
+	return &ErrorBody{
+		Code:      code,
+		Message:   message,
+		Retriable: code == ErrCodeLLMTimeout || code == ErrCodeLLMError,
+	}
+}
+
+// httpStatus 错误码对应的HTTP状态码，未知错误码按500处理
+func (e *ErrorBody) httpStatus() int {
+	if status, ok := errorStatus[e.Code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// respondError 以结构化错误响应，按错误类型确定错误码和HTTP状态码
+func respondError(c *gin.Context, err error) {
+	body := newErrorBody(classifyError(err), err.Error())
+	c.JSON(body.httpStatus(), body)
+}
+
+// classifyError 根据错误类型确定错误码
+func classifyError(err error) ErrorCode {
+	switch {
+	case errors.Is(err, llm.ErrTimeout), errors.Is(err, autocomplete.ErrTimeout):
+		return ErrCodeLLMTimeout
+	case errors.Is(err, llm.ErrUpstream):
+		return ErrCodeLLMError
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrCodeNotFound
+	case errors.Is(err, autocomplete.ErrInvalidRequest):
+		return ErrCodeInvalidRequest
+	case errors.Is(err, autocomplete.ErrQuotaExceeded):
+		return ErrCodeRateLimited
+	case errors.Is(err, ErrUnauthorized):
+		return ErrCodeUnauthorized
+	case errors.Is(err, ErrConversationReadOnly), errors.Is(err, ErrPermissionDenied):
+		return ErrCodeForbidden
+	case errors.Is(err, ErrDuplicateMessage):
+		return ErrCodeDuplicate
+	case errors.Is(err, ErrAutocompleteDisabled):
+		return ErrCodeAutocompleteDisabled
+	default:
+		return ErrCodeInternal
+	}
+}
+
+// ErrConversationReadOnly 对话为只读，不能写入、编辑或置顶消息
+var ErrConversationReadOnly = errors.New("对话为只读，不能写入或修改消息")
+
+// ErrAutocompleteDisabled 对话已通过设置关闭补全
+var ErrAutocompleteDisabled = errors.New("该对话已关闭补全")
+
+// isNotFound 判断是否为记录不存在错误
+func isNotFound(err error) bool {
+	return errors.Is(err, gorm.ErrRecordNotFound)
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ChatRecommend/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// 导出时每批从数据库读取的消息数（默认值和上限）
+const (
+	defaultExportBatchSize = 500
+	maxExportBatchSize     = 2000
+)
+
+// ExportConversation 流式导出对话的全部消息
+// 查询参数：batch_size（每批读取的消息数，默认500，最大2000）；include_drafts=true（包含草稿，默认不含）
+// 按 (sequence, id) 游标分批读取，每批写完立即刷新响应（chunked），内存占用只与批大小有关，与对话长度无关。
+// 响应头写出后无法再修改状态码，导出中途出错时以 complete=false 和 error 结束响应
+func (h *Handler) ExportConversation(c *gin.Context) {
+	batchSize, err := strconv.Atoi(c.DefaultQuery("batch_size", strconv.Itoa(defaultExportBatchSize)))
+	if err != nil || batchSize <= 0 {
+		batchSize = defaultExportBatchSize
+	}
+	if batchSize > maxExportBatchSize {
+		batchSize = maxExportBatchSize
+	}
+	includeDrafts := c.Query("include_drafts") == "true"
+
+	var conversation models.Conversation
+	err = h.db.Where("conversation_id = ?", c.Param("conversation_id")).First(&conversation).Error
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "对话不存在"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询对话失败"})
+		return
+	}
+
+	loc, err := h.requestLocation(c, &conversation)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": conversation.ConversationID + ".json",
+	}))
+	c.Status(http.StatusOK)
+
+	conversationID, _ := json.Marshal(conversation.ConversationID)
+	exportedAt, _ := json.Marshal(time.Now().In(loc))
+	if _, err := fmt.Fprintf(c.Writer, `{"conversation_id":%s,"exported_at":%s,"messages":[`, conversationID, exportedAt); err != nil {
+		return
+	}
+
+	count, err := h.streamMessages(c.Request.Context(), c.Writer, conversation.ID, batchSize, includeDrafts, loc)
+	if err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"conversation_id": conversation.ConversationID,
+			"exported":        count,
+		}).Error("导出对话消息中断")
+		fmt.Fprintf(c.Writer, `],"count":%d,"complete":false,"error":"导出中断"}`, count)
+		return
+	}
+	fmt.Fprintf(c.Writer, `],"count":%d,"complete":true}`, count)
+
+	logrus.WithFields(logrus.Fields{
+		"conversation_id": conversation.ConversationID,
+		"exported":        count,
+	}).Info("对话消息导出完成")
+}
+
+// streamMessages 按 (sequence, id) 游标分批读取对话消息，逐条以逗号分隔写出 JSON（时间转换到 loc），每批写完刷新一次，返回写出的消息数
+func (h *Handler) streamMessages(ctx context.Context, w gin.ResponseWriter, conversationID uint, batchSize int, includeDrafts bool, loc *time.Location) (int, error) {
+	var (
+		count    int
+		lastSeq  int64
+		lastID   uint
+		messages []models.Message
+	)
+	for {
+		if err := ctx.Err(); err != nil {
+			return count, err
+		}
+
+		query := h.db.Where("conversation_id = ?", conversationID)
+		if !includeDrafts {
+			query = query.Scopes(models.ExcludeDrafts)
+		}
+		if count > 0 {
+			query = query.Where("(sequence > ? OR (sequence = ? AND id > ?))", lastSeq, lastSeq, lastID)
+		}
+		// 复用同一个切片，避免每批重新分配
+		messages = messages[:0]
+		if err := query.Order("sequence ASC, id ASC").Limit(batchSize).Find(&messages).Error; err != nil {
+			return count, fmt.Errorf("查询消息失败: %w", err)
+		}
+
+		for _, dto := range models.ToMessageDTOsIn(messages, loc) {
+			if err := writeExportMessage(w, dto, count > 0); err != nil {
+				return count, err
+			}
+			count++
+		}
+		w.Flush()
+
+		if len(messages) < batchSize {
+			return count, nil
+		}
+		last := messages[len(messages)-1]
+		lastSeq, lastID = last.Sequence, last.ID
+	}
+}
+
+// writeExportMessage 写出一条消息，不是第一条时先写分隔的逗号
+func writeExportMessage(w io.Writer, dto models.MessageDTO, separator bool) error {
+	data, err := json.Marshal(dto)
+	if err != nil {
+		return fmt.Errorf("序列化消息失败: %w", err)
+	}
+	if separator {
+		if _, err := w.Write([]byte{','}); err != nil {
+			return fmt.Errorf("写出消息失败: %w", err)
+		}
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("写出消息失败: %w", err)
+	}
+	return nil
+}
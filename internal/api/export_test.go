@@ -0,0 +1,138 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// exportResponse 导出结果
+type exportResponse struct {
+	ConversationID string              `json:"conversation_id"`
+	Messages       []models.MessageDTO `json:"messages"`
+	Count          int                 `json:"count"`
+	Complete       bool                `json:"complete"`
+}
+
+// 按 sequence 顺序分批导出全部消息，默认不含草稿；对话不存在返回404
+func TestExportConversation(t *testing.T) {
+	s := newTestServer(t)
+	testutil.CreateConversation(t, s.db, "conv-export",
+		models.Message{SenderID: "alice", Content: "第一条", Sequence: 1},
+		models.Message{SenderID: "bob", Content: "第三条", Sequence: 3},
+		models.Message{SenderID: "alice", Content: "第二条", Sequence: 2},
+		models.Message{SenderID: "bob", Content: "第四条", Sequence: 4},
+		models.Message{SenderID: "alice", Content: "草稿", Sequence: 5, MessageType: models.MessageTypeDraft},
+	)
+
+	w := s.do(t, http.MethodGet, "/api/chat/conv-export/export?batch_size=2", nil)
+	var resp exportResponse
+	decode(t, w, http.StatusOK, &resp)
+	if !strings.Contains(w.Header().Get("Content-Disposition"), `filename=conv-export.json`) {
+		t.Errorf("Content-Disposition 为 %q", w.Header().Get("Content-Disposition"))
+	}
+	if resp.ConversationID != "conv-export" || resp.Count != 4 || !resp.Complete || len(resp.Messages) != 4 {
+		t.Fatalf("导出结果为 %+v", resp)
+	}
+	for i, want := range []string{"第一条", "第二条", "第三条", "第四条"} {
+		if resp.Messages[i].Content != want {
+			t.Errorf("第%d条消息为 %q，期望 %q", i+1, resp.Messages[i].Content, want)
+		}
+	}
+
+	decode(t, s.do(t, http.MethodGet, "/api/chat/conv-export/export?batch_size=2&include_drafts=true", nil), http.StatusOK, &resp)
+	if resp.Count != 5 || resp.Messages[4].MessageType != models.MessageTypeDraft {
+		t.Errorf("include_drafts=true 时应包含草稿: %+v", resp)
+	}
+
+	decode(t, s.do(t, http.MethodGet, "/api/chat/conv-missing/export", nil), http.StatusNotFound, nil)
+}
+
+// flushCountingWriter 丢弃写入的数据，记录总字节数、刷新次数、两次刷新之间写入的最大字节数，
+// 并在刷新时采样 GC 后的堆大小
+type flushCountingWriter struct {
+	header       http.Header
+	status       int
+	total        int
+	pending      int
+	maxPending   int
+	flushes      int
+	baseHeap     uint64
+	maxHeapDelta uint64
+}
+
+func (w *flushCountingWriter) Header() http.Header { return w.header }
+
+func (w *flushCountingWriter) WriteHeader(status int) { w.status = status }
+
+func (w *flushCountingWriter) Write(p []byte) (int, error) {
+	w.total += len(p)
+	w.pending += len(p)
+	return len(p), nil
+}
+
+func (w *flushCountingWriter) Flush() {
+	w.flushes++
+	w.maxPending = max(w.maxPending, w.pending)
+	w.pending = 0
+	if w.flushes%10 == 0 {
+		if heap := heapInUse(); heap > w.baseHeap {
+			w.maxHeapDelta = max(w.maxHeapDelta, heap-w.baseHeap)
+		}
+	}
+}
+
+// heapInUse GC 后的堆大小
+func heapInUse() uint64 {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
+
+// 大对话流式导出：逐批刷新，每批写出的数据量与批大小相当，导出过程中堆的增长远小于导出总量
+func TestExportLargeConversationMemoryBound(t *testing.T) {
+	if testing.Short() {
+		t.Skip("大对话导出测试较慢")
+	}
+	const (
+		total       = 10000
+		batchSize   = 200
+		maxHeapGrow = 3 << 20
+	)
+	s := newTestServer(t)
+	conversation := testutil.CreateConversation(t, s.db, "conv-large")
+	content := strings.Repeat("这是一条很长的历史消息", 40)
+	messages := make([]models.Message, total)
+	for i := range messages {
+		messages[i] = models.Message{ConversationID: conversation.ID, SenderID: "alice", Content: content, MessageType: "text", Sequence: int64(i + 1)}
+	}
+	if err := s.db.CreateInBatches(messages, 500).Error; err != nil {
+		t.Fatalf("保存消息失败: %v", err)
+	}
+
+	w := &flushCountingWriter{header: http.Header{}, baseHeap: heapInUse()}
+	req := httptest.NewRequest(http.MethodGet, "/api/chat/conv-large/export?batch_size=200", nil)
+	s.router.ServeHTTP(w, req)
+
+	if w.status != http.StatusOK {
+		t.Fatalf("状态码为 %d", w.status)
+	}
+	if w.flushes < total/batchSize {
+		t.Errorf("刷新了 %d 次，期望至少 %d 次", w.flushes, total/batchSize)
+	}
+	if perBatch := w.total / (total / batchSize); w.maxPending > 2*perBatch {
+		t.Errorf("两次刷新之间最多写出 %d 字节，期望不超过一批的两倍 %d 字节", w.maxPending, 2*perBatch)
+	}
+	if w.total < 4*maxHeapGrow {
+		t.Fatalf("导出总量 %d 字节太小，无法验证内存占用", w.total)
+	}
+	if w.maxHeapDelta > maxHeapGrow {
+		t.Errorf("导出过程中堆增长 %d 字节，超过阈值 %d 字节（导出总量 %d 字节）", w.maxHeapDelta, maxHeapGrow, w.total)
+	}
+}
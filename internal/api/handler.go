@@ -1,14 +1,19 @@
 package api
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
 	"ChatRecommend/internal/autocomplete"
+	"ChatRecommend/internal/context"
 	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/prompt"
 	"ChatRecommend/internal/style"
 	"ChatRecommend/internal/summary"
+	"ChatRecommend/internal/webhook"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
@@ -18,17 +23,38 @@ import (
 type Handler struct {
 	db          *gorm.DB
 	autocomplete *autocomplete.Engine
+	contextMgr  *context.Manager
 	summary     *summary.Manager
 	style       *style.Manager
+	hub         *Hub
+	// 按天分组等日期计算使用的时区
+	location    *time.Location
+	hooks       *webhook.Dispatcher
+	reanalyze   *reanalyzer
+	// 补全服务层，HTTP 和 WebSocket 共用
+	chat        *ChatService
+	// 相邻重复消息检测
+	dedupe      dedupeSettings
+	// 情绪走势缓存
+	trends      *trendCache
+	// 在线更新的提示词模板
+	prompts     *prompt.Store
 }
 
 // NewHandler 创建API处理器
-func NewHandler(db *gorm.DB, autocompleteEngine *autocomplete.Engine, summaryMgr *summary.Manager, styleMgr *style.Manager) *Handler {
+func NewHandler(db *gorm.DB, autocompleteEngine *autocomplete.Engine, contextMgr *context.Manager, summaryMgr *summary.Manager, styleMgr *style.Manager, location *time.Location, hooks *webhook.Dispatcher) *Handler {
 	return &Handler{
 		db:          db,
 		autocomplete: autocompleteEngine,
+		contextMgr:  contextMgr,
 		summary:     summaryMgr,
 		style:       styleMgr,
+		hub:         NewHub(),
+		location:    location,
+		hooks:       hooks,
+		reanalyze:   newReanalyzer(),
+		chat:        NewChatService(autocompleteEngine, db),
+		trends:      newTrendCache(),
 	}
 }
 
@@ -36,14 +62,15 @@ func NewHandler(db *gorm.DB, autocompleteEngine *autocomplete.Engine, summaryMgr
 func (h *Handler) Complete(c *gin.Context) {
 	var req models.AutocompleteRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		body := newErrorBody(ErrCodeInvalidRequest, err.Error())
+		c.JSON(body.httpStatus(), body)
 		return
 	}
 
-	resp, err := h.autocomplete.GetSuggestions(&req)
+	resp, err := h.chat.Suggest(WithTransport(c.Request.Context(), TransportHTTP), &req)
 	if err != nil {
 		logrus.WithError(err).Error("获取补全建议失败")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -57,7 +84,38 @@ func (h *Handler) SaveMessage(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !models.ValidContentFormat(req.ContentFormat) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "content_format 只能为 plain 或 markdown"})
+		return
+	}
+
+	message, duplicate, err := h.saveMessage(&req, nil)
+	if err != nil {
+		// 只读（FORBIDDEN）和重复（DUPLICATE）是预期内的拒绝，不记错误日志
+		if !errors.Is(err, ErrConversationReadOnly) && !errors.Is(err, ErrDuplicateMessage) {
+			logrus.WithError(err).Error("保存消息失败")
+		}
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message_id": message.ID,
+		"status":     saveStatus(duplicate),
+	})
+}
+
+// saveStatus 保存消息响应中的状态：重复消息被合并时为 duplicate
+func saveStatus(duplicate bool) string {
+	if duplicate {
+		return "duplicate"
+	}
+	return "success"
+}
 
+// saveMessage 保存消息并触发后续处理（HTTP和WebSocket共用），origin 为发起保存的WebSocket连接，广播时跳过。
+// 与最近一条消息重复时按 server.dedupe.action 合并（返回已有消息，duplicate 为 true）或拒绝（ErrDuplicateMessage）
+func (h *Handler) saveMessage(req *models.SaveMessageRequest, origin *Client) (*models.Message, bool, error) {
 	// 获取或创建对话
 	var conversation models.Conversation
 	err := h.db.Where("conversation_id = ?", req.ConversationID).First(&conversation).Error
@@ -68,12 +126,13 @@ func (h *Handler) SaveMessage(c *gin.Context) {
 			LastMessageAt:  time.Now(),
 		}
 		if err := h.db.Create(&conversation).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "创建对话失败"})
-			return
+			return nil, false, fmt.Errorf("创建对话失败: %w", err)
 		}
 	} else if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询对话失败"})
-		return
+		return nil, false, fmt.Errorf("查询对话失败: %w", err)
+	}
+	if conversation.ReadOnly {
+		return nil, false, ErrConversationReadOnly
 	}
 
 	// 创建消息
@@ -83,30 +142,91 @@ func (h *Handler) SaveMessage(c *gin.Context) {
 		Content:        req.Content,
 		MessageType:    req.MessageType,
 		Sequence:       req.Sequence,
+		ContentFormat:  req.ContentFormat,
 	}
 	if message.MessageType == "" {
 		message.MessageType = "text"
 	}
+	if message.ContentFormat == "" {
+		message.ContentFormat = models.ContentFormatPlain
+	}
 	if message.Sequence == 0 {
 		message.Sequence = time.Now().UnixNano()
 	}
 
-	if err := h.db.Create(&message).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存消息失败"})
-		return
+	// 保存消息并增量更新统计和实体索引（草稿不计入统计）
+	var existing *models.Message
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		// 与最近一条消息重复（导入或网络重试）时不写入
+		if h.dedupe.window > 0 && message.MessageType != models.MessageTypeDraft {
+			last, err := latestMessage(tx, conversation.ID)
+			if err != nil {
+				return err
+			}
+			message.CreatedAt = time.Now()
+			if isDuplicateMessage(last, &message, h.dedupe.window) {
+				if h.dedupe.action == DedupeReject {
+					return ErrDuplicateMessage
+				}
+				existing = last
+				return nil
+			}
+		}
+		if err := tx.Create(&message).Error; err != nil {
+			return err
+		}
+		if message.MessageType == models.MessageTypeDraft {
+			return nil
+		}
+		if err := indexEntities(tx, []models.Message{message}); err != nil {
+			return err
+		}
+		return incrementStats(tx, conversation.ID, message.SenderID, message.CreatedAt)
+	})
+	if errors.Is(err, ErrDuplicateMessage) {
+		return nil, false, err
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("保存消息失败: %w", err)
+	}
+	if existing != nil {
+		logrus.WithField("conversation_id", req.ConversationID).
+			WithField("message_id", existing.ID).
+			Debug("重复消息已合并")
+		return existing, true, nil
+	}
+
+	// 草稿不影响对话状态，不广播，也不触发摘要和风格更新
+	if message.MessageType == models.MessageTypeDraft {
+		return &message, false, nil
 	}
 
-	// 更新对话最后消息时间
+	// 更新对话最后消息时间。只写这一列：整行保存会用请求开始时读到的旧值覆盖期间被修改的只读标记、标签、设置等
 	conversation.LastMessageAt = time.Now()
-	h.db.Save(&conversation)
+	if err := h.db.Model(&conversation).UpdateColumn("last_message_at", conversation.LastMessageAt).Error; err != nil {
+		logrus.WithError(err).WithField("conversation_id", req.ConversationID).Warn("更新对话最后消息时间失败")
+	}
+
+	// 通知同一对话的其他WebSocket连接
+	h.hub.Broadcast(req.ConversationID, &WSMessage{
+		Type: "new_message",
+		Data: gin.H{
+			"conversation_id": req.ConversationID,
+			"message":         models.ToMessageDTOs([]models.Message{message})[0],
+		},
+	}, origin)
+
+	// 检查关注关键词
+	h.checkAlerts(&conversation, &message)
+
+	// 新消息追加进上下文窗口，使补全缓存失效，并为对话中其他用户预取补全
+	h.contextMgr.AppendMessage(req.ConversationID, message)
+	h.autocomplete.OnMessageSaved(req.ConversationID, req.SenderID)
 
 	// 异步更新摘要和风格
 	go h.updateSummaryAndStyle(conversation.ID, req.SenderID)
 
-	c.JSON(http.StatusOK, gin.H{
-		"message_id": message.ID,
-		"status":     "success",
-	})
+	return &message, false, nil
 }
 
 // GetHistory 获取聊天历史
@@ -138,9 +258,37 @@ func (h *Handler) GetHistory(c *gin.Context) {
 		return
 	}
 
+	// 时间按请求方时区返回（带偏移的 RFC3339）
+	loc, err := h.requestLocation(c, &conversation)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// group_by=day 时按天分组返回时间线
+	if c.Query("group_by") == "day" {
+		c.JSON(http.StatusOK, gin.H{
+			"conversation_id": conversationID,
+			"timezone":        loc.String(),
+			"days":            groupMessagesByDay(messages, loc),
+		})
+		return
+	}
+
+	// 默认返回精简结构，view=full 时返回完整消息
+	if c.Query("view") == "full" {
+		models.MessagesIn(messages, loc)
+		c.JSON(http.StatusOK, gin.H{
+			"conversation_id": conversationID,
+			"messages":       messages,
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"conversation_id": conversationID,
-		"messages":       messages,
+		"timezone":        loc.String(),
+		"messages":        models.ToMessageDTOsIn(messages, loc),
 	})
 }
 
@@ -149,17 +297,25 @@ func (h *Handler) updateSummaryAndStyle(conversationID uint, senderID string) {
 	// 获取所有消息
 	var messages []models.Message
 	if err := h.db.Where("conversation_id = ?", conversationID).
+		Scopes(models.ExcludeDrafts).
 		Order("sequence ASC, created_at ASC").
 		Find(&messages).Error; err != nil {
 		logrus.WithError(err).Error("查询消息失败")
 		return
 	}
 
-	// 更新摘要
-	summary, err := h.summary.GetOrCreateSummary(conversationID)
-	if err == nil && h.summary.ShouldUpdateSummary(summary, int64(len(messages))) {
-		if err := h.summary.UpdateSummary(conversationID, messages); err != nil {
-			logrus.WithError(err).Error("更新摘要失败")
+	// 更新摘要（归档的对话不自动更新摘要）
+	var conversation models.Conversation
+	if err := h.db.First(&conversation, conversationID).Error; err != nil {
+		logrus.WithError(err).Error("查询对话失败")
+		return
+	}
+	if !conversation.Archived {
+		summary, err := h.summary.GetOrCreateSummary(conversationID)
+		if err == nil && h.summary.ShouldUpdateSummary(summary, int64(len(messages))) {
+			if err := h.summary.UpdateSummary(conversationID, messages); err != nil {
+				logrus.WithError(err).Error("更新摘要失败")
+			}
 		}
 	}
 
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ChatRecommend/internal/autocomplete"
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/context"
+	"ChatRecommend/internal/prompt"
+	"ChatRecommend/internal/style"
+	"ChatRecommend/internal/summary"
+	"ChatRecommend/internal/testutil"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// testServer 内存数据库上的完整处理器和路由（路由与 cmd/server 一致的子集），大模型为替身
+type testServer struct {
+	db      *gorm.DB
+	llm     *testutil.MockLLM
+	handler *Handler
+	router  *gin.Engine
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	return newTestServerWithConfig(t, &config.AutocompleteConfig{SuggestionCount: 3})
+}
+
+// newTestServerWithConfig 使用指定的补全配置创建测试服务
+func newTestServerWithConfig(t *testing.T, acConfig *config.AutocompleteConfig) *testServer {
+	t.Helper()
+
+	db := testutil.NewDB(t)
+	mock := &testutil.MockLLM{Suggestions: []string{"好的，没问题", "我再想想"}, SummaryPrompt: "两人在约饭", KeyInfo: "[]"}
+	summaryMgr := summary.NewManager(db, &config.SummaryConfig{}, &config.PromptConfig{}, mock, nil)
+	styleMgr := style.NewManager(db, &config.StyleConfig{}, nil)
+	contextMgr := context.NewManager(db, &config.ContextConfig{RecentMessagesCount: 10, MaxContextTokens: 4000}, &config.PromptConfig{}, summaryMgr, styleMgr)
+	engine := autocomplete.NewEngine(db, acConfig, contextMgr, mock, nil)
+	promptStore := prompt.NewStore(db)
+	summaryMgr.SetPromptStore(promptStore)
+	contextMgr.SetPromptStore(promptStore)
+	h := NewHandler(db, engine, contextMgr, summaryMgr, styleMgr, time.UTC, nil)
+	h.SetPromptStore(promptStore)
+
+	router := gin.New()
+	router.GET("/ws", h.HandleWebSocket)
+	apiGroup := router.Group("/api")
+	chatGroup := apiGroup.Group("/chat")
+	chatGroup.POST("/complete", h.Complete)
+	chatGroup.POST("/message", h.SaveMessage)
+	chatGroup.PUT("/message/:id", h.EditMessage)
+	chatGroup.GET("/message/:id/edits", h.ListMessageEdits)
+	chatGroup.POST("/message/:id/pin", h.PinMessage)
+	chatGroup.DELETE("/message/:id/pin", h.UnpinMessage)
+	chatGroup.GET("/:conversation_id/pinned", h.ListPinnedMessages)
+	chatGroup.GET("/:conversation_id/alerts", h.ListAlerts)
+	chatGroup.GET("/:conversation_id/entities", h.ListEntities)
+	chatGroup.GET("/:conversation_id/sentiment-trend", h.GetSentimentTrend)
+	chatGroup.GET("/:conversation_id/export", h.ExportConversation)
+	chatGroup.GET("/history/:conversation_id", h.GetHistory)
+	chatGroup.POST("/:conversation_id/read", h.MarkRead)
+	chatGroup.GET("/:conversation_id/completions", h.ListCompletions)
+	chatGroup.POST("/completions/:id/feedback", h.CompletionFeedback)
+	chatGroup.POST("/import/:platform", h.ImportConversation)
+	apiGroup.POST("/summary/:conversation_id/preview", h.PreviewSummary)
+	apiGroup.POST("/style/:conversation_id/preview", h.PreviewStyle)
+	apiGroup.GET("/conversations", h.ListConversations)
+	apiGroup.POST("/summaries/batch", h.BatchSummaries)
+	apiGroup.POST("/conversations/merge", h.MergeConversations)
+	apiGroup.PUT("/conversation/:id/state", h.RequireConversationRole(ActionUpdateState), h.UpdateConversationState)
+	apiGroup.PUT("/conversation/:id/read-only", h.RequireConversationRole(ActionSetReadOnly), h.UpdateConversationReadOnly)
+	apiGroup.PUT("/conversation/:id/settings", h.RequireConversationRole(ActionUpdateSettings), h.UpdateConversationSettings)
+	apiGroup.PUT("/conversation/:id/participants/:user_id/role", h.RequireConversationRole(ActionManageRoles), h.UpdateParticipantRole)
+	apiGroup.PUT("/conversation/:id/tags", h.RequireConversationRole(ActionUpdateTags), h.UpdateConversationTags)
+	apiGroup.PUT("/conversation/:id/note", h.RequireConversationRole(ActionUpdateNote), h.UpdateConversationNote)
+	apiGroup.GET("/conversation/:id/metadata", h.GetConversationMetadata)
+	apiGroup.PUT("/conversation/:id/metadata", h.RequireConversationRole(ActionUpdateMetadata), h.UpdateConversationMetadata)
+	apiGroup.PATCH("/conversation/:id/metadata", h.RequireConversationRole(ActionUpdateMetadata), h.PatchConversationMetadata)
+	apiGroup.POST("/conversation/:id/snapshot", h.RequireConversationRole(ActionCreateSnapshot), h.CreateSnapshot)
+	apiGroup.GET("/conversation/:id/snapshots", h.ListSnapshots)
+	apiGroup.POST("/conversation/:id/restore", h.RequireConversationRole(ActionRestoreSnapshot), h.RestoreSnapshot)
+	apiGroup.GET("/conversation/:id/stats", h.GetConversationStats)
+	apiGroup.PUT("/user/:sender_id/profile", h.UpdateUserProfile)
+	apiGroup.GET("/user/:sender_id/profile", h.GetUserProfile)
+	apiGroup.DELETE("/user/:sender_id/data", h.DeleteUserData)
+	adminGroup := apiGroup.Group("/admin")
+	adminGroup.POST("/reanalyze", h.StartReanalyze)
+	adminGroup.GET("/reanalyze/:id", h.GetReanalyze)
+	adminGroup.DELETE("/reanalyze/:id", h.CancelReanalyze)
+	adminGroup.GET("/prompts/:name", h.GetPromptTemplate)
+	adminGroup.PUT("/prompts/:name", h.UpdatePromptTemplate)
+	adminGroup.DELETE("/prompts/:name", h.DeactivatePromptTemplate)
+
+	return &testServer{db: db, llm: mock, handler: h, router: router}
+}
+
+// do 发送请求，body 不为nil时编码为 JSON；headers 为成对的请求头名称和值
+func (s *testServer) do(t *testing.T, method, path string, body interface{}, headers ...string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("编码请求失败: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+// decode 解码响应，状态码不符时报告响应内容
+func decode(t *testing.T, w *httptest.ResponseRecorder, status int, out interface{}) {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("状态码为 %d，期望 %d，响应: %s", w.Code, status, w.Body.String())
+	}
+	if out != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			t.Fatalf("解码响应失败: %v，响应: %s", err, w.Body.String())
+		}
+	}
+}
+
+// saveMessage 通过接口保存消息，返回消息ID
+func (s *testServer) saveMessage(t *testing.T, conversationID, senderID, content string) uint {
+	t.Helper()
+	var resp struct {
+		MessageID uint `json:"message_id"`
+	}
+	decode(t, s.do(t, http.MethodPost, "/api/chat/message", gin.H{
+		"conversation_id": conversationID,
+		"sender_id":       senderID,
+		"content":         content,
+	}), http.StatusOK, &resp)
+	return resp.MessageID
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+)
+
+// 历史查询默认只返回精简字段，view=full 时返回完整消息记录
+func TestGetHistoryReturnsMessageDTOs(t *testing.T) {
+	s := newTestServer(t)
+	s.saveMessage(t, "conv-history", "alice", "明天一起吃饭吗")
+	s.saveMessage(t, "conv-history", "bob", "好啊")
+
+	var compact struct {
+		Messages []map[string]interface{} `json:"messages"`
+	}
+	decode(t, s.do(t, http.MethodGet, "/api/chat/history/conv-history", nil), http.StatusOK, &compact)
+	if len(compact.Messages) != 2 {
+		t.Fatalf("返回 %d 条消息，期望 2 条", len(compact.Messages))
+	}
+	first := compact.Messages[0]
+	if first["sender_id"] != "alice" || first["content"] != "明天一起吃饭吗" || first["created_at"] == nil {
+		t.Errorf("精简消息缺少展示字段: %v", first)
+	}
+	for _, field := range []string{"conversation_id", "updated_at", "deleted_at"} {
+		if _, ok := first[field]; ok {
+			t.Errorf("精简消息不应包含 %s: %v", field, first)
+		}
+	}
+
+	var full struct {
+		Messages []map[string]interface{} `json:"messages"`
+	}
+	decode(t, s.do(t, http.MethodGet, "/api/chat/history/conv-history?view=full", nil), http.StatusOK, &full)
+	if len(full.Messages) != 2 {
+		t.Fatalf("返回 %d 条消息，期望 2 条", len(full.Messages))
+	}
+	for _, field := range []string{"conversation_id", "updated_at"} {
+		if _, ok := full.Messages[0][field]; !ok {
+			t.Errorf("完整消息应包含 %s: %v", field, full.Messages[0])
+		}
+	}
+}
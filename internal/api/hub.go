@@ -0,0 +1,89 @@
+package api
+
+import (
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Hub 按对话管理WebSocket连接，用于向同一对话的其他连接广播新消息
+type Hub struct {
+	mu            sync.RWMutex
+	conversations map[string]map[*Client]bool // conversationID -> 连接集合
+}
+
+// NewHub 创建连接中心
+func NewHub() *Hub {
+	return &Hub{
+		conversations: make(map[string]map[*Client]bool),
+	}
+}
+
+// Register 登记连接关注的对话
+func (h *Hub) Register(conversationID string, client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	clients, ok := h.conversations[conversationID]
+	if !ok {
+		clients = make(map[*Client]bool)
+		h.conversations[conversationID] = clients
+	}
+	clients[client] = true
+}
+
+// Unregister 注销连接关注的所有对话
+func (h *Hub) Unregister(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for conversationID, clients := range h.conversations {
+		delete(clients, client)
+		if len(clients) == 0 {
+			delete(h.conversations, conversationID)
+		}
+	}
+}
+
+// Broadcast 向关注该对话的连接广播消息，except 为发起方连接（可为nil）
+func (h *Hub) Broadcast(conversationID string, msg *WSMessage, except *Client) {
+	if h == nil {
+		return
+	}
+
+	h.mu.RLock()
+	targets := make([]*Client, 0, len(h.conversations[conversationID]))
+	for client := range h.conversations[conversationID] {
+		if client != except {
+			targets = append(targets, client)
+		}
+	}
+	h.mu.RUnlock()
+
+	if len(targets) == 0 {
+		return
+	}
+
+	// 连接可能使用不同的编码，每种编码只序列化一次
+	msg.Version = wsProtocolVersion
+	encoded := make(map[string][]byte)
+	for _, client := range targets {
+		data, ok := encoded[client.codec.Name()]
+		if !ok {
+			var err error
+			data, err = client.codec.Marshal(msg)
+			if err != nil {
+				logrus.WithError(err).WithField("codec", client.codec.Name()).Error("序列化广播消息失败")
+				return
+			}
+			encoded[client.codec.Name()] = data
+		}
+		client.enqueue(data)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"conversation_id": conversationID,
+		"type":            msg.Type,
+		"clients":         len(targets),
+	}).Debug("已广播WebSocket消息")
+}
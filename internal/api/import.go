@@ -0,0 +1,197 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"ChatRecommend/internal/importer"
+	"ChatRecommend/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// maxImportBytes 导入文件大小上限
+const maxImportBytes = 20 << 20
+
+// ImportConversation 从平台导出文件导入聊天记录（/api/chat/import/wechat、/api/chat/import/telegram）
+// 文件通过 multipart 的 file 字段上传，也可以直接作为请求体；conversation_id 通过查询参数或表单字段指定，对话不存在时创建
+func (h *Handler) ImportConversation(c *gin.Context) {
+	platform := c.Param("platform")
+	parse, ok := importer.Parsers[platform]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "不支持的导入格式: " + platform})
+		return
+	}
+
+	conversationID := c.Query("conversation_id")
+	if conversationID == "" {
+		conversationID = c.PostForm("conversation_id")
+	}
+	if conversationID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "conversation_id不能为空"})
+		return
+	}
+
+	data, err := readImportFile(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	parsed, err := parse(data, h.location)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	conversation, duplicates, err := importMessages(h.db, h.dedupe, conversationID, parsed, true)
+	if errors.Is(err, ErrConversationReadOnly) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Error("导入聊天记录失败")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.autocomplete.InvalidateCache(conversationID)
+
+	// 异步重算摘要和风格
+	go h.recomputeSummaryAndStyle(conversation.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"conversation_id": conversationID,
+		"platform":        platform,
+		"imported":        len(parsed) - duplicates,
+		"duplicates":      duplicates,
+		"status":          "success",
+	})
+}
+
+// readImportFile 读取上传的文件，优先 multipart 的 file 字段，否则读取整个请求体
+func readImportFile(c *gin.Context) ([]byte, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes)
+
+	if file, err := c.FormFile("file"); err == nil {
+		f, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("读取上传文件失败: %w", err)
+		}
+		defer f.Close()
+		return io.ReadAll(f)
+	}
+
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取请求体失败: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, errors.New("导入内容为空")
+	}
+	return data, nil
+}
+
+// importMessages 在一个事务中（db 已在事务中时为保存点）批量写入解析出的消息，按时间生成递增的 sequence，
+// 并重建统计和参与者列表。导入接口和流式导入共用。snapshot 为 true 时写入前为对话打快照；
+// 与前一条消息重复的消息按 dedupe 丢弃，返回丢弃的条数
+func importMessages(db *gorm.DB, dedupe dedupeSettings, conversationID string, parsed []importer.Message, snapshot bool) (*models.Conversation, int, error) {
+	var conversation models.Conversation
+	var duplicates int
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("conversation_id = ?", conversationID).First(&conversation).Error
+		if err == gorm.ErrRecordNotFound {
+			conversation = models.Conversation{
+				ConversationID: conversationID,
+				Participants:   "[]",
+			}
+			if err := tx.Create(&conversation).Error; err != nil {
+				return fmt.Errorf("创建对话失败: %w", err)
+			}
+		} else if err != nil {
+			return fmt.Errorf("查询对话失败: %w", err)
+		}
+		if conversation.ReadOnly {
+			return ErrConversationReadOnly
+		}
+
+		messages := make([]models.Message, len(parsed))
+		senders := make([]string, 0)
+		seen := make(map[string]bool)
+		var lastSequence int64
+		var lastAt time.Time
+		for i, msg := range parsed {
+			// 同一时间的多条消息保持导出文件中的顺序
+			sequence := msg.Time.UnixNano()
+			if sequence <= lastSequence {
+				sequence = lastSequence + 1
+			}
+			lastSequence = sequence
+
+			messages[i] = models.Message{
+				ConversationID: conversation.ID,
+				SenderID:       msg.SenderID,
+				Content:        msg.Content,
+				MessageType:    "text",
+				Sequence:       sequence,
+			}
+			messages[i].CreatedAt = msg.Time
+			if msg.Time.After(lastAt) {
+				lastAt = msg.Time
+			}
+			if !seen[msg.SenderID] {
+				seen[msg.SenderID] = true
+				senders = append(senders, msg.SenderID)
+			}
+		}
+		last, err := latestMessage(tx, conversation.ID)
+		if err != nil {
+			return err
+		}
+		messages, duplicates = dedupeMessages(last, messages, dedupe.window)
+		if len(messages) == 0 {
+			return nil
+		}
+
+		// 导入前为对话打快照，导入内容有误时可整体回退
+		if snapshot {
+			if _, err := createSnapshot(tx, &conversation, "批量导入前自动快照"); err != nil {
+				return err
+			}
+		}
+		if err := tx.CreateInBatches(&messages, 200).Error; err != nil {
+			return fmt.Errorf("写入消息失败: %w", err)
+		}
+		if err := indexEntities(tx, messages); err != nil {
+			return err
+		}
+
+		if err := rebuildStats(tx, conversation.ID); err != nil {
+			return err
+		}
+
+		senderList, err := json.Marshal(senders)
+		if err != nil {
+			return fmt.Errorf("序列化参与者失败: %w", err)
+		}
+		updates := map[string]interface{}{
+			"participants": mergeParticipants(conversation.Participants, string(senderList)),
+		}
+		if lastAt.After(conversation.LastMessageAt) {
+			updates["last_message_at"] = lastAt
+		}
+		if err := tx.Model(&conversation).Updates(updates).Error; err != nil {
+			return fmt.Errorf("更新对话失败: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return &conversation, duplicates, nil
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/importer"
+	"gorm.io/gorm"
+)
+
+// IngestMessage 流式导入的一条消息，即 cmd/ingest 行协议中的一行
+type IngestMessage struct {
+	ConversationID string `json:"conversation_id"`
+	SenderID       string `json:"sender_id"`
+	Content        string `json:"content"`
+	// 消息时间（RFC3339），缺省时由调用方填入读到该行的时间
+	Time time.Time `json:"time"`
+}
+
+// Validate 校验必填字段
+func (m *IngestMessage) Validate() error {
+	if m.ConversationID == "" || m.SenderID == "" {
+		return errors.New("conversation_id和sender_id不能为空")
+	}
+	if strings.TrimSpace(m.Content) == "" {
+		return errors.New("content不能为空")
+	}
+	return nil
+}
+
+// IngestResult 一批消息的写入结果
+type IngestResult struct {
+	Written    int
+	Duplicates int
+	// 因对话只读而跳过的消息数
+	Skipped int
+	// 有消息写入的对话（按首次出现的顺序）
+	Conversations []string
+}
+
+// IngestService 流式导入服务：把一批消息按对话分组写入，与导入接口共用写入逻辑（importMessages：去重、统计、实体索引）。
+// 每批都写快照代价太高，流式导入不打快照；不触发摘要和风格重算，摘要由服务端的后台摘要任务补上
+type IngestService struct {
+	db     *gorm.DB
+	dedupe dedupeSettings
+}
+
+// NewIngestService 创建流式导入服务，dedupe 为相邻重复消息检测配置（可为 nil）
+func NewIngestService(db *gorm.DB, dedupe *config.DedupeConfig) *IngestService {
+	s := &IngestService{db: db}
+	if dedupe != nil {
+		s.dedupe = newDedupeSettings(dedupe)
+	}
+	return s
+}
+
+// Write 在一个事务中写入一批消息（每个对话一个保存点）。只读对话的消息被跳过；
+// 其他错误使整批回滚，调用方可以原样重试
+func (s *IngestService) Write(messages []IngestMessage) (*IngestResult, error) {
+	groups := make(map[string][]importer.Message)
+	var order []string
+	for _, msg := range messages {
+		if _, ok := groups[msg.ConversationID]; !ok {
+			order = append(order, msg.ConversationID)
+		}
+		groups[msg.ConversationID] = append(groups[msg.ConversationID], importer.Message{
+			SenderID: msg.SenderID,
+			Content:  msg.Content,
+			Time:     msg.Time,
+		})
+	}
+
+	var result *IngestResult
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result = &IngestResult{}
+		for _, conversationID := range order {
+			parsed := groups[conversationID]
+			_, duplicates, err := importMessages(tx, s.dedupe, conversationID, parsed, false)
+			if errors.Is(err, ErrConversationReadOnly) {
+				result.Skipped += len(parsed)
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("写入对话 %s 失败: %w", conversationID, err)
+			}
+			result.Written += len(parsed) - duplicates
+			result.Duplicates += duplicates
+			if len(parsed) > duplicates {
+				result.Conversations = append(result.Conversations, conversationID)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
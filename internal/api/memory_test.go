@@ -0,0 +1,42 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 内存数据库上跑通保存消息、补全和查询历史的完整流程
+func TestAPIFlowOnMemoryDatabase(t *testing.T) {
+	s := newTestServer(t)
+	s.saveMessage(t, "conv-memory", "alice", "明天一起吃饭吗")
+	s.saveMessage(t, "conv-memory", "bob", "好啊，几点")
+
+	var completion struct {
+		Suggestions []string `json:"suggestions"`
+	}
+	decode(t, s.do(t, http.MethodPost, "/api/chat/complete", gin.H{
+		"conversation_id": "conv-memory",
+		"sender_id":       "alice",
+		"input":           "晚上",
+	}), http.StatusOK, &completion)
+	if len(completion.Suggestions) == 0 {
+		t.Fatal("补全没有返回建议")
+	}
+	if s.llm.LastContext == "" {
+		t.Error("补全时应把内存库中的对话历史作为上下文")
+	}
+
+	var history struct {
+		Messages []map[string]interface{} `json:"messages"`
+	}
+	decode(t, s.do(t, http.MethodGet, "/api/chat/history/conv-memory", nil), http.StatusOK, &history)
+	if len(history.Messages) != 2 {
+		t.Fatalf("历史有 %d 条消息，期望 2 条", len(history.Messages))
+	}
+
+	// 另一个服务实例使用独立的内存库
+	other := newTestServer(t)
+	decode(t, other.do(t, http.MethodGet, "/api/chat/history/conv-memory", nil), http.StatusNotFound, nil)
+}
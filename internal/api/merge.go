@@ -0,0 +1,346 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"ChatRecommend/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// MergeConversationsRequest 合并对话请求
+type MergeConversationsRequest struct {
+	SourceConversationID string `json:"source_conversation_id" binding:"required"`
+	TargetConversationID string `json:"target_conversation_id" binding:"required"`
+}
+
+// MergeConversations 将源对话并入目标对话：迁移消息（按发送时间重排sequence）、合并参与者、删除源对话，
+// 完成后异步重算目标对话的摘要和风格
+func (h *Handler) MergeConversations(c *gin.Context) {
+	var req MergeConversationsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.SourceConversationID == req.TargetConversationID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "源对话和目标对话不能相同"})
+		return
+	}
+
+	callerID := c.GetHeader(UserIDHeader)
+	var source, target models.Conversation
+	var movedCount, totalCount int64
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("conversation_id = ?", req.SourceConversationID).First(&source).Error; err != nil {
+			return fmt.Errorf("查询源对话失败: %w", err)
+		}
+		if err := tx.Where("conversation_id = ?", req.TargetConversationID).First(&target).Error; err != nil {
+			return fmt.Errorf("查询目标对话失败: %w", err)
+		}
+		// 合并涉及两个对话，无法用路径参数中间件校验，在这里对双方分别校验角色
+		if err := authorize(tx, &source, callerID, ActionMergeConversation); err != nil {
+			return err
+		}
+		if err := authorize(tx, &target, callerID, ActionMergeConversation); err != nil {
+			return err
+		}
+
+		// 合并会删除源对话并向目标对话写入消息，任一方只读都不允许
+		if source.ReadOnly || target.ReadOnly {
+			return ErrConversationReadOnly
+		}
+
+		// 合并前为两个对话打快照，出错时可整体回退
+		if _, err := createSnapshot(tx, &source, fmt.Sprintf("合并到 %s 前自动快照", target.ConversationID)); err != nil {
+			return err
+		}
+		if _, err := createSnapshot(tx, &target, fmt.Sprintf("合并 %s 前自动快照", source.ConversationID)); err != nil {
+			return err
+		}
+
+		moved, err := moveMessages(tx, source.ID, target.ID)
+		if err != nil {
+			return err
+		}
+		movedCount = moved
+
+		// 重建目标对话的统计，删除源对话的统计
+		if err := rebuildStats(tx, target.ID); err != nil {
+			return err
+		}
+		if err := deleteStats(tx, source.ID); err != nil {
+			return err
+		}
+
+		// 合并参与者、标签、自定义字段和最后消息时间
+		target.Participants = mergeParticipants(target.Participants, source.Participants)
+		if err := mergeTags(&target, &source); err != nil {
+			return err
+		}
+		if err := mergeMetadata(&target, &source); err != nil {
+			return err
+		}
+		if source.LastMessageAt.After(target.LastMessageAt) {
+			target.LastMessageAt = source.LastMessageAt
+		}
+		if err := tx.Model(&target).Updates(map[string]interface{}{
+			"participants":    target.Participants,
+			"tags":            target.Tags,
+			"metadata":        target.Metadata,
+			"last_message_at": target.LastMessageAt,
+		}).Error; err != nil {
+			return fmt.Errorf("更新目标对话失败: %w", err)
+		}
+
+		// 提醒、编辑历史、实体索引和补全历史随消息迁移到目标对话
+		for _, model := range []interface{}{&models.Alert{}, &models.MessageEdit{}, &models.MessageEntity{}, &models.CompletionLog{}} {
+			if err := tx.Model(model).Where("conversation_id = ?", source.ID).Update("conversation_id", target.ID).Error; err != nil {
+				return fmt.Errorf("迁移对话关联数据失败: %w", err)
+			}
+		}
+
+		// 删除源对话及其摘要、风格、已读位置（物理删除，以便conversation_id可以被重新使用）
+		for _, model := range []interface{}{&models.Summary{}, &models.Style{}, &models.ReadCursor{}} {
+			if err := tx.Unscoped().Where("conversation_id = ?", source.ID).Delete(model).Error; err != nil {
+				return fmt.Errorf("删除源对话数据失败: %w", err)
+			}
+		}
+		if err := tx.Unscoped().Delete(&source).Error; err != nil {
+			return fmt.Errorf("删除源对话失败: %w", err)
+		}
+
+		return tx.Model(&models.Message{}).Where("conversation_id = ?", target.ID).Count(&totalCount).Error
+	})
+	if err != nil {
+		logrus.WithError(err).Error("合并对话失败")
+		if isNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, ErrConversationReadOnly) || errors.Is(err, ErrPermissionDenied) || errors.Is(err, ErrUnauthorized) {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.autocomplete.InvalidateCache(source.ConversationID)
+	h.autocomplete.InvalidateCache(target.ConversationID)
+
+	// 异步重算目标对话的摘要和风格
+	go h.recomputeSummaryAndStyle(target.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"conversation_id": target.ConversationID,
+		"merged_messages": movedCount,
+		"total_messages":  totalCount,
+		"status":          "success",
+	})
+}
+
+// moveMessages 把源对话的消息迁移到目标对话，再按发送时间（created_at，相同时按消息ID）重新编排目标对话全部消息的 sequence（从1开始），
+// 两个对话交错发送的消息合并后按真实时间排列；目标对话成员的已读位置换算到新的 sequence。返回迁移的消息数（不含已删除的消息）
+func moveMessages(tx *gorm.DB, sourceID, targetID uint) (int64, error) {
+	type sequenceRow struct {
+		ID       uint
+		Sequence int64
+	}
+
+	var targetRows []sequenceRow
+	if err := tx.Model(&models.Message{}).Unscoped().
+		Select("id, sequence").
+		Where("conversation_id = ?", targetID).
+		Scan(&targetRows).Error; err != nil {
+		return 0, fmt.Errorf("查询目标对话消息失败: %w", err)
+	}
+	oldSequences := make(map[uint]int64, len(targetRows))
+	for _, row := range targetRows {
+		oldSequences[row.ID] = row.Sequence
+	}
+
+	var moved int64
+	if err := tx.Model(&models.Message{}).Where("conversation_id = ?", sourceID).Count(&moved).Error; err != nil {
+		return 0, fmt.Errorf("统计源对话消息失败: %w", err)
+	}
+	// 使用UpdateColumn避免触发更新时间和保存钩子（内容无需重新处理），已删除的消息一并迁移
+	if err := tx.Model(&models.Message{}).Unscoped().
+		Where("conversation_id = ?", sourceID).
+		UpdateColumn("conversation_id", targetID).Error; err != nil {
+		return 0, fmt.Errorf("迁移消息失败: %w", err)
+	}
+
+	var rows []sequenceRow
+	if err := tx.Model(&models.Message{}).Unscoped().
+		Select("id, sequence").
+		Where("conversation_id = ?", targetID).
+		Order("created_at ASC, id ASC").
+		Scan(&rows).Error; err != nil {
+		return 0, fmt.Errorf("查询合并后的消息失败: %w", err)
+	}
+	// 目标对话原有消息的旧序号 -> 新序号，用于换算已读位置
+	remapped := make(map[int64]int64, len(targetRows))
+	for i, row := range rows {
+		seq := int64(i + 1)
+		if old, ok := oldSequences[row.ID]; ok {
+			remapped[old] = max(remapped[old], seq)
+		}
+		if row.Sequence == seq {
+			continue
+		}
+		if err := tx.Model(&models.Message{}).Unscoped().Where("id = ?", row.ID).UpdateColumn("sequence", seq).Error; err != nil {
+			return 0, fmt.Errorf("重排消息序号失败: %w", err)
+		}
+	}
+
+	if err := remapReadCursors(tx, targetID, remapped); err != nil {
+		return 0, err
+	}
+	return moved, nil
+}
+
+// remapReadCursors 把对话成员的已读位置换算为重排后的序号：取旧序号不超过已读位置的消息中最大的新序号
+func remapReadCursors(tx *gorm.DB, conversationID uint, remapped map[int64]int64) error {
+	var cursors []models.ReadCursor
+	if err := tx.Where("conversation_id = ?", conversationID).Find(&cursors).Error; err != nil {
+		return fmt.Errorf("查询已读位置失败: %w", err)
+	}
+	for _, cursor := range cursors {
+		var seq int64
+		for old, updated := range remapped {
+			if old <= cursor.LastReadSequence {
+				seq = max(seq, updated)
+			}
+		}
+		if seq == cursor.LastReadSequence {
+			continue
+		}
+		if err := tx.Model(&cursor).UpdateColumn("last_read_sequence", seq).Error; err != nil {
+			return fmt.Errorf("更新已读位置失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// mergeParticipants 合并参与者列表（JSON数组），按用户ID去重，同一用户保留目标对话中的条目（含角色）
+func mergeParticipants(target, source string) string {
+	var targetList, sourceList []interface{}
+	if target != "" {
+		json.Unmarshal([]byte(target), &targetList)
+	}
+	if source != "" {
+		json.Unmarshal([]byte(source), &sourceList)
+	}
+
+	seen := make(map[string]bool)
+	merged := make([]interface{}, 0, len(targetList)+len(sourceList))
+	for _, p := range append(targetList, sourceList...) {
+		key := models.ParticipantID(p)
+		if key == "" {
+			raw, _ := json.Marshal(p)
+			key = string(raw)
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		merged = append(merged, p)
+	}
+
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return "[]"
+	}
+	return string(data)
+}
+
+// mergeTags 把源对话的标签并入目标对话（超出数量上限的部分丢弃）
+func mergeTags(target, source *models.Conversation) error {
+	targetTags, err := target.GetTags()
+	if err != nil {
+		return err
+	}
+	sourceTags, err := source.GetTags()
+	if err != nil {
+		return err
+	}
+	merged := targetTags
+	for _, tag := range sourceTags {
+		if len(merged) >= models.MaxConversationTags {
+			break
+		}
+		if !slices.Contains(merged, tag) {
+			merged = append(merged, tag)
+		}
+	}
+	return target.SetTags(merged)
+}
+
+// mergeMetadata 把源对话的自定义字段并入目标对话，同名键保留目标对话的值（超出数量上限的部分丢弃）
+func mergeMetadata(target, source *models.Conversation) error {
+	targetMetadata, err := target.GetMetadata()
+	if err != nil {
+		return err
+	}
+	sourceMetadata, err := source.GetMetadata()
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(sourceMetadata))
+	for key := range sourceMetadata {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		if len(targetMetadata) >= models.MaxMetadataKeys {
+			break
+		}
+		if _, ok := targetMetadata[key]; !ok {
+			targetMetadata[key] = sourceMetadata[key]
+		}
+	}
+	if err := target.SetMetadata(targetMetadata); err != nil {
+		// 合并后超过大小上限时保留目标对话原有的自定义字段
+		logrus.WithError(err).Warn("合并对话自定义字段失败，保留目标对话的自定义字段")
+	}
+	return nil
+}
+
+// recomputeSummaryAndStyle 强制重算对话摘要和所有发送者的风格，出错时记录日志并继续，返回遇到的所有错误
+func (h *Handler) recomputeSummaryAndStyle(conversationID uint) error {
+	var messages []models.Message
+	if err := h.db.Where("conversation_id = ?", conversationID).
+		Scopes(models.ExcludeDrafts).
+		Order("sequence ASC, created_at ASC").
+		Find(&messages).Error; err != nil {
+		logrus.WithError(err).Error("查询消息失败")
+		return fmt.Errorf("查询消息失败: %w", err)
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+
+	var errs []error
+	if err := h.summary.UpdateSummary(conversationID, messages); err != nil {
+		logrus.WithError(err).Error("重算摘要失败")
+		errs = append(errs, fmt.Errorf("重算摘要失败: %w", err))
+	}
+
+	senders := make(map[string]bool)
+	for _, msg := range messages {
+		if senders[msg.SenderID] {
+			continue
+		}
+		senders[msg.SenderID] = true
+		if err := h.style.UpdateStyle(conversationID, msg.SenderID, messages); err != nil {
+			logrus.WithError(err).WithField("sender_id", msg.SenderID).Error("重算风格失败")
+			errs = append(errs, fmt.Errorf("重算风格失败: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
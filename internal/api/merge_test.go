@@ -0,0 +1,88 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+	"github.com/gin-gonic/gin"
+)
+
+// waitFor 轮询等待后台任务完成
+func waitFor(t *testing.T, what string, done func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待%s超时", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// 合并后全部消息按发送时间重排 sequence，总数正确，已读位置同步换算，源对话被删除
+func TestMergeConversationsResequencesByTime(t *testing.T) {
+	s := newTestServer(t)
+	base := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return base.Add(time.Duration(minutes) * time.Minute) }
+
+	target := testutil.CreateConversation(t, s.db, "conv-target",
+		models.Message{SenderID: "alice", Content: "t1", CreatedAt: at(1)},
+		models.Message{SenderID: "alice", Content: "t3", CreatedAt: at(3)},
+	)
+	testutil.CreateConversation(t, s.db, "conv-source",
+		models.Message{SenderID: "bob", Content: "s2", CreatedAt: at(2)},
+		models.Message{SenderID: "bob", Content: "s4", CreatedAt: at(4)},
+		models.Message{SenderID: "bob", Content: "s5", CreatedAt: at(5)},
+	)
+	// 合并需要调用者同时是两个对话的参与者
+	s.db.Model(&models.Conversation{}).Where("conversation_id IN ?", []string{"conv-target", "conv-source"}).
+		Update("participants", `["alice","bob"]`)
+	// alice 读到了目标对话的第2条（t3）
+	s.db.Create(&models.ReadCursor{ConversationID: target.ID, UserID: "alice", LastReadSequence: 2})
+
+	var resp struct {
+		MergedMessages int64 `json:"merged_messages"`
+		TotalMessages  int64 `json:"total_messages"`
+	}
+	decode(t, s.do(t, http.MethodPost, "/api/conversations/merge", gin.H{
+		"source_conversation_id": "conv-source",
+		"target_conversation_id": "conv-target",
+	}, UserIDHeader, "alice"), http.StatusOK, &resp)
+	if resp.MergedMessages != 3 || resp.TotalMessages != 5 {
+		t.Fatalf("合并结果为 %+v", resp)
+	}
+
+	var messages []models.Message
+	s.db.Where("conversation_id = ?", target.ID).Order("sequence ASC").Find(&messages)
+	want := []string{"t1", "s2", "t3", "s4", "s5"}
+	if len(messages) != len(want) {
+		t.Fatalf("合并后消息数为 %d", len(messages))
+	}
+	for i, msg := range messages {
+		if msg.Content != want[i] || msg.Sequence != int64(i+1) {
+			t.Fatalf("第 %d 条消息为 %q（sequence=%d），期望 %q（sequence=%d）", i, msg.Content, msg.Sequence, want[i], i+1)
+		}
+	}
+
+	var cursor models.ReadCursor
+	s.db.Where("conversation_id = ? AND user_id = ?", target.ID, "alice").First(&cursor)
+	if cursor.LastReadSequence != 3 {
+		t.Fatalf("已读位置为 %d，期望换算为 t3 的新序号 3", cursor.LastReadSequence)
+	}
+
+	var sources int64
+	s.db.Model(&models.Conversation{}).Where("conversation_id = ?", "conv-source").Count(&sources)
+	if sources != 0 {
+		t.Fatalf("源对话未删除")
+	}
+
+	// 等待异步重算摘要完成（测试配置未启用风格分析），避免测试结束后仍访问数据库
+	waitFor(t, "重算摘要", func() bool {
+		var summaries int64
+		s.db.Model(&models.Summary{}).Where("conversation_id = ? AND prompt = ?", target.ID, s.llm.SummaryPrompt).Count(&summaries)
+		return summaries == 1
+	})
+}
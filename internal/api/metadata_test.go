@@ -0,0 +1,99 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+	"github.com/gin-gonic/gin"
+)
+
+type metadataResponse struct {
+	ConversationID string                 `json:"conversation_id"`
+	Metadata       map[string]interface{} `json:"metadata"`
+}
+
+// 整体替换和合并更新自定义字段，非法字段返回400，对话不存在返回404
+func TestConversationMetadata(t *testing.T) {
+	s := newTestServer(t)
+	testutil.CreateConversation(t, s.db, "conv-meta", models.Message{SenderID: "alice", Content: "你好"})
+
+	var resp metadataResponse
+	decode(t, s.do(t, http.MethodGet, "/api/conversation/conv-meta/metadata", nil), http.StatusOK, &resp)
+	if resp.Metadata == nil || len(resp.Metadata) != 0 {
+		t.Fatalf("未设置时应返回空对象: %+v", resp)
+	}
+
+	decode(t, s.do(t, http.MethodPut, "/api/conversation/conv-meta/metadata", gin.H{
+		"metadata": gin.H{"level": "normal", "crm_id": "42"},
+	}, UserIDHeader, "alice"), http.StatusOK, nil)
+	decode(t, s.do(t, http.MethodPatch, "/api/conversation/conv-meta/metadata", gin.H{
+		"metadata": gin.H{"level": "vip", "crm_id": nil, "score": 5},
+	}, UserIDHeader, "alice"), http.StatusOK, &resp)
+	if len(resp.Metadata) != 2 || resp.Metadata["level"] != "vip" || resp.Metadata["score"] != 5.0 {
+		t.Errorf("合并后的自定义字段为 %v", resp.Metadata)
+	}
+
+	decode(t, s.do(t, http.MethodPut, "/api/conversation/conv-meta/metadata", gin.H{
+		"metadata": gin.H{"nested": gin.H{"a": "b"}},
+	}, UserIDHeader, "alice"), http.StatusBadRequest, nil)
+	decode(t, s.do(t, http.MethodGet, "/api/conversation/conv-meta/metadata", nil), http.StatusOK, &resp)
+	if resp.Metadata["level"] != "vip" {
+		t.Errorf("校验失败时不应修改已有字段: %v", resp.Metadata)
+	}
+
+	decode(t, s.do(t, http.MethodGet, "/api/conversation/conv-missing/metadata", nil), http.StatusNotFound, nil)
+	decode(t, s.do(t, http.MethodPut, "/api/conversation/conv-missing/metadata", gin.H{"metadata": gin.H{"a": "b"}}), http.StatusNotFound, nil)
+}
+
+// 按自定义字段筛选对话列表：同一键的多个取值为 OR，不同键为 AND，数字和布尔取值同时匹配文本和对应类型
+func TestListConversationsMetadataFilter(t *testing.T) {
+	s := newTestServer(t)
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	for i, conversation := range []models.Conversation{
+		{ConversationID: "conv-a", Metadata: `{"level":"vip","score":5,"active":true}`},
+		{ConversationID: "conv-b", Metadata: `{"level":"normal","score":"5"}`},
+		{ConversationID: "conv-c", Metadata: `{"level":"vip","active":"yes"}`},
+		{ConversationID: "conv-d"},
+	} {
+		conversation.LastMessageAt = base.Add(-time.Duration(i) * time.Minute)
+		if err := s.db.Create(&conversation).Error; err != nil {
+			t.Fatalf("创建对话失败: %v", err)
+		}
+	}
+
+	assertIDs(t, "level=vip", s.listConversationIDs(t, "?meta.level=vip"), "conv-a", "conv-c")
+	assertIDs(t, "level=vip 或 normal", s.listConversationIDs(t, "?meta.level=vip&meta.level=normal"), "conv-a", "conv-b", "conv-c")
+	assertIDs(t, "score=5", s.listConversationIDs(t, "?meta.score=5"), "conv-a", "conv-b")
+	assertIDs(t, "active=true", s.listConversationIDs(t, "?meta.active=true"), "conv-a")
+	assertIDs(t, "level=vip 且 score=5", s.listConversationIDs(t, "?meta.level=vip&meta.score=5"), "conv-a")
+	assertIDs(t, "未知字段", s.listConversationIDs(t, "?meta.region=华东"))
+
+	decode(t, s.do(t, http.MethodGet, "/api/conversations?meta.bad%20key=v", nil), http.StatusBadRequest, nil)
+}
+
+// 合并对话时并入源对话的自定义字段，同名键保留目标对话的值
+func TestMergeConversationsMetadata(t *testing.T) {
+	s := newTestServer(t)
+	for _, conversation := range []models.Conversation{
+		{ConversationID: "conv-target", Metadata: `{"level":"vip"}`, Participants: `["alice"]`},
+		{ConversationID: "conv-source", Metadata: `{"level":"normal","crm_id":"42"}`, Participants: `["alice"]`},
+	} {
+		if err := s.db.Create(&conversation).Error; err != nil {
+			t.Fatalf("创建对话失败: %v", err)
+		}
+	}
+
+	decode(t, s.do(t, http.MethodPost, "/api/conversations/merge", gin.H{
+		"source_conversation_id": "conv-source",
+		"target_conversation_id": "conv-target",
+	}, UserIDHeader, "alice"), http.StatusOK, nil)
+
+	var resp metadataResponse
+	decode(t, s.do(t, http.MethodGet, "/api/conversation/conv-target/metadata", nil), http.StatusOK, &resp)
+	if len(resp.Metadata) != 2 || resp.Metadata["level"] != "vip" || resp.Metadata["crm_id"] != "42" {
+		t.Errorf("合并后的自定义字段为 %v", resp.Metadata)
+	}
+}
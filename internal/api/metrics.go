@@ -0,0 +1,57 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"ChatRecommend/internal/autocomplete"
+	"github.com/gin-gonic/gin"
+)
+
+// degradeLevelValues 降级级别对应的指标值
+var degradeLevelValues = []string{autocomplete.DegradeNone, autocomplete.DegradeReduced, autocomplete.DegradeLocal}
+
+// Metrics 以 Prometheus 文本格式输出运行指标（GET /metrics）
+func (h *Handler) Metrics(c *gin.Context) {
+	var b strings.Builder
+
+	enabled := 0
+	status := h.autocomplete.DegradationStatus()
+	if status != nil {
+		enabled = 1
+	} else {
+		status = &autocomplete.DegradationStatus{Level: autocomplete.DegradeNone}
+	}
+
+	writeMetric(&b, "chatrecommend_autocomplete_degradation_enabled", "gauge", "是否启用补全自动降级", float64(enabled))
+	writeMetric(&b, "chatrecommend_autocomplete_degradation_level", "gauge", "补全降级级别：0正常，1减少建议数，2只用本地规则",
+		float64(slices.Index(degradeLevelValues, status.Level)))
+	writeMetric(&b, "chatrecommend_autocomplete_latency_p95_seconds", "gauge", "滑动窗口内大模型调用耗时P95", status.P95Ms/1000)
+	writeMetric(&b, "chatrecommend_autocomplete_latency_samples", "gauge", "滑动窗口内大模型调用次数", float64(status.Samples))
+	writeMetric(&b, "chatrecommend_autocomplete_degradation_transitions_total", "counter", "降级级别切换次数", float64(status.Transitions))
+
+	if ranking := h.autocomplete.RankingStatus(); ranking != nil {
+		writeMetric(&b, "chatrecommend_autocomplete_ranking_samples", "gauge", "参与采纳率排序聚合的已采纳补全记录数", float64(ranking.Samples))
+		writeMetric(&b, "chatrecommend_autocomplete_ranking_features", "gauge", "参与采纳率排序的特征数，为0时使用默认顺序", float64(ranking.Features))
+	}
+
+	counters, latencies := h.chat.stats.snapshot()
+	fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n", "chatrecommend_suggest_requests_total", "补全请求数，按来源和结果（ok、canceled 或错误码）统计", "chatrecommend_suggest_requests_total")
+	for _, counter := range counters {
+		fmt.Fprintf(&b, "chatrecommend_suggest_requests_total{transport=%q,result=%q} %d\n", counter.Transport, counter.Result, counter.Count)
+	}
+	fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s summary\n", "chatrecommend_suggest_duration_seconds", "补全请求耗时（含去抖等待）", "chatrecommend_suggest_duration_seconds")
+	for _, latency := range latencies {
+		fmt.Fprintf(&b, "chatrecommend_suggest_duration_seconds_sum{transport=%q} %g\n", latency.Transport, latency.Seconds)
+		fmt.Fprintf(&b, "chatrecommend_suggest_duration_seconds_count{transport=%q} %d\n", latency.Transport, latency.Count)
+	}
+
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(b.String()))
+}
+
+// writeMetric 写入一个无标签的指标
+func writeMetric(b *strings.Builder, name, typ, help string, value float64) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n%s %g\n", name, help, name, typ, name, value)
+}
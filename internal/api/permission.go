@@ -0,0 +1,121 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"ChatRecommend/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// UserIDHeader 调用者用户ID请求头，由接入层鉴权后设置
+const UserIDHeader = "X-User-ID"
+
+// 需要校验参与者角色的对话操作
+const (
+	ActionUpdateState       = "update_state"
+	ActionSetReadOnly       = "set_read_only"
+	ActionUpdateSettings    = "update_settings"
+	ActionRestoreSnapshot   = "restore_snapshot"
+	ActionMergeConversation = "merge_conversation"
+	ActionManageRoles       = "manage_roles"
+	ActionUpdateTags        = "update_tags"
+	ActionUpdateNote        = "update_note"
+	ActionUpdateMetadata    = "update_metadata"
+	ActionCreateSnapshot    = "create_snapshot"
+	// ActionSubscribe 订阅对话的 WebSocket 广播，不区分角色，只要求是已有参与者
+	ActionSubscribe = "subscribe"
+)
+
+// actionRoles 各操作允许的角色：恢复快照会覆盖全部历史、合并会删除源对话，只允许 owner
+var actionRoles = map[string][]string{
+	ActionUpdateState:       {models.RoleOwner, models.RoleAdmin},
+	ActionSetReadOnly:       {models.RoleOwner, models.RoleAdmin},
+	ActionUpdateSettings:    {models.RoleOwner, models.RoleAdmin},
+	ActionRestoreSnapshot:   {models.RoleOwner},
+	ActionMergeConversation: {models.RoleOwner},
+	ActionManageRoles:       {models.RoleOwner},
+	ActionUpdateTags:        {models.RoleOwner, models.RoleAdmin},
+	ActionUpdateNote:        {models.RoleOwner, models.RoleAdmin},
+	ActionUpdateMetadata:    {models.RoleOwner, models.RoleAdmin},
+	ActionCreateSnapshot:    {models.RoleOwner, models.RoleAdmin},
+}
+
+// ErrPermissionDenied 调用者角色无权执行该操作
+var ErrPermissionDenied = errors.New("没有权限执行该操作")
+
+// ErrUnauthorized 需要校验角色但请求未带 X-User-ID，无法识别调用者
+var ErrUnauthorized = errors.New("缺少请求头 " + UserIDHeader + "，无法识别调用者")
+
+// authorize 校验用户能否对对话执行操作。对话没有分配 owner/admin 时无法区分角色，任何操作（包括首次分配角色）
+// 都只要求是已有参与者，不会对外部调用者放开；订阅（ActionSubscribe）总是只要求是已有参与者。
+// 需要校验但 userID 为空时返回 ErrUnauthorized
+func authorize(db *gorm.DB, conversation *models.Conversation, userID, action string) error {
+	if action == ActionSubscribe || !conversation.HasRoles() {
+		return requireParticipant(db, conversation, userID, action)
+	}
+	if userID == "" {
+		return ErrUnauthorized
+	}
+	role := conversation.ParticipantRole(userID)
+	if role != "" && slices.Contains(actionRoles[action], role) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrPermissionDenied, action)
+}
+
+// requireParticipant 校验用户是对话的已有参与者
+func requireParticipant(db *gorm.DB, conversation *models.Conversation, userID, action string) error {
+	if userID == "" {
+		return ErrUnauthorized
+	}
+	isParticipant, err := existingParticipant(db, conversation, userID)
+	if err != nil {
+		return err
+	}
+	if !isParticipant {
+		return fmt.Errorf("%w: %s", ErrPermissionDenied, action)
+	}
+	return nil
+}
+
+// existingParticipant 用户是否为对话的已有参与者：在参与者列表中，或在对话中发过消息
+func existingParticipant(db *gorm.DB, conversation *models.Conversation, userID string) (bool, error) {
+	if conversation.ParticipantRole(userID) != "" {
+		return true, nil
+	}
+	var count int64
+	err := db.Model(&models.Message{}).
+		Where("conversation_id = ? AND sender_id = ?", conversation.ID, userID).
+		Limit(1).Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("查询参与者失败: %w", err)
+	}
+	return count > 0, nil
+}
+
+// RequireConversationRole 对话操作的角色校验中间件：按路径参数 id 查询对话，校验请求头 X-User-ID 对应参与者的角色。
+// 对话不存在时不校验，交由接口自行处理
+func (h *Handler) RequireConversationRole(action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var conversation models.Conversation
+		err := h.db.Where("conversation_id = ?", c.Param("id")).First(&conversation).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.Next()
+			return
+		} else if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "查询对话失败"})
+			return
+		}
+
+		if err := authorize(h.db, &conversation, c.GetHeader(UserIDHeader), action); err != nil {
+			respondError(c, err)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
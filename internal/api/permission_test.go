@@ -0,0 +1,141 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"ChatRecommend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// 已分配角色的对话：未带 X-User-ID 返回 401 UNAUTHORIZED，角色无权限返回 403 FORBIDDEN，owner 可以执行
+func TestRequireConversationRole(t *testing.T) {
+	s := newTestServer(t)
+	s.saveMessage(t, "conv-roles", "alice", "你好")
+	if err := s.db.Model(&models.Conversation{}).Where("conversation_id = ?", "conv-roles").
+		Update("participants", `[{"id":"alice","role":"owner"},"bob"]`).Error; err != nil {
+		t.Fatalf("设置参与者角色失败: %v", err)
+	}
+
+	path := "/api/conversation/conv-roles/read-only"
+	body := gin.H{"read_only": true}
+
+	var errBody ErrorBody
+	decode(t, s.do(t, http.MethodPut, path, body), http.StatusUnauthorized, &errBody)
+	if errBody.Code != ErrCodeUnauthorized {
+		t.Errorf("未带用户ID错误码为 %s，期望 %s", errBody.Code, ErrCodeUnauthorized)
+	}
+
+	errBody = ErrorBody{}
+	decode(t, s.do(t, http.MethodPut, path, body, UserIDHeader, "bob"), http.StatusForbidden, &errBody)
+	if errBody.Code != ErrCodeForbidden {
+		t.Errorf("member 错误码为 %s，期望 %s", errBody.Code, ErrCodeForbidden)
+	}
+
+	if w := s.do(t, http.MethodPut, path, body, UserIDHeader, "alice"); w.Code != http.StatusOK {
+		t.Fatalf("owner 设置只读返回 %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// 没有分配角色的对话：非参与者不能把自己设为 owner，发过消息的参与者可以完成首次分配
+func TestManageRolesBootstrapRequiresParticipant(t *testing.T) {
+	s := newTestServer(t)
+	s.saveMessage(t, "conv-bootstrap", "alice", "你好")
+
+	path := "/api/conversation/conv-bootstrap/participants/mallory/role"
+	body := gin.H{"role": models.RoleOwner}
+
+	var errBody ErrorBody
+	decode(t, s.do(t, http.MethodPut, path, body), http.StatusUnauthorized, &errBody)
+	if errBody.Code != ErrCodeUnauthorized {
+		t.Errorf("未带用户ID错误码为 %s，期望 %s", errBody.Code, ErrCodeUnauthorized)
+	}
+
+	errBody = ErrorBody{}
+	decode(t, s.do(t, http.MethodPut, path, body, UserIDHeader, "mallory"), http.StatusForbidden, &errBody)
+	if errBody.Code != ErrCodeForbidden {
+		t.Errorf("非参与者自封 owner 错误码为 %s，期望 %s", errBody.Code, ErrCodeForbidden)
+	}
+
+	var conversation models.Conversation
+	s.db.Where("conversation_id = ?", "conv-bootstrap").First(&conversation)
+	if conversation.HasRoles() {
+		t.Fatalf("被拒绝的请求不应分配角色: %s", conversation.Participants)
+	}
+
+	decode(t, s.do(t, http.MethodPut, "/api/conversation/conv-bootstrap/participants/alice/role", body, UserIDHeader, "alice"), http.StatusOK, nil)
+	s.db.Where("conversation_id = ?", "conv-bootstrap").First(&conversation)
+	if role := conversation.ParticipantRole("alice"); role != models.RoleOwner {
+		t.Fatalf("alice 的角色为 %q，期望 owner", role)
+	}
+
+	// 分配 owner 之后，member 不能再修改角色
+	decode(t, s.do(t, http.MethodPut, path, body, UserIDHeader, "mallory"), http.StatusForbidden, nil)
+}
+
+// 标签、备注、元数据和手动快照在已分配角色的对话上只允许 owner/admin
+func TestConversationWritesRequireRole(t *testing.T) {
+	s := newTestServer(t)
+	s.saveMessage(t, "conv-writes", "alice", "你好")
+	if err := s.db.Model(&models.Conversation{}).Where("conversation_id = ?", "conv-writes").
+		Update("participants", `[{"id":"alice","role":"owner"},"bob"]`).Error; err != nil {
+		t.Fatalf("设置参与者角色失败: %v", err)
+	}
+
+	cases := []struct {
+		method string
+		path   string
+		body   gin.H
+	}{
+		{http.MethodPut, "/api/conversation/conv-writes/tags", gin.H{"tags": []string{"工作"}}},
+		{http.MethodPut, "/api/conversation/conv-writes/note", gin.H{"note": "备注"}},
+		{http.MethodPut, "/api/conversation/conv-writes/metadata", gin.H{"metadata": gin.H{"crm_id": "42"}}},
+		{http.MethodPost, "/api/conversation/conv-writes/snapshot", gin.H{"label": "手动"}},
+	}
+	for _, tc := range cases {
+		if w := s.do(t, tc.method, tc.path, tc.body, UserIDHeader, "bob"); w.Code != http.StatusForbidden {
+			t.Errorf("member %s %s 返回 %d，期望 403: %s", tc.method, tc.path, w.Code, w.Body.String())
+		}
+		if w := s.do(t, tc.method, tc.path, tc.body, UserIDHeader, "alice"); w.Code != http.StatusOK {
+			t.Errorf("owner %s %s 返回 %d，期望 200: %s", tc.method, tc.path, w.Code, w.Body.String())
+		}
+	}
+}
+
+// 没有分配角色的对话不对外开放：敏感操作只允许已有参与者执行，合并要求同时是两个对话的参与者
+func TestUnassignedConversationRequiresParticipant(t *testing.T) {
+	s := newTestServer(t)
+	s.saveMessage(t, "conv-open", "alice", "你好")
+	s.saveMessage(t, "conv-other", "bob", "在吗")
+
+	cases := []struct {
+		method string
+		path   string
+		body   gin.H
+	}{
+		{http.MethodPut, "/api/conversation/conv-open/state", gin.H{"pinned": true}},
+		{http.MethodPut, "/api/conversation/conv-open/read-only", gin.H{"read_only": true}},
+		{http.MethodPut, "/api/conversation/conv-open/settings", gin.H{"alert_keywords": []string{"生日"}}},
+		{http.MethodPut, "/api/conversation/conv-open/tags", gin.H{"tags": []string{"工作"}}},
+		{http.MethodPost, "/api/conversation/conv-open/snapshot", gin.H{"label": "手动"}},
+	}
+	for _, tc := range cases {
+		if w := s.do(t, tc.method, tc.path, tc.body); w.Code != http.StatusUnauthorized {
+			t.Errorf("未带用户ID %s %s 返回 %d，期望 401", tc.method, tc.path, w.Code)
+		}
+		if w := s.do(t, tc.method, tc.path, tc.body, UserIDHeader, "mallory"); w.Code != http.StatusForbidden {
+			t.Errorf("非参与者 %s %s 返回 %d，期望 403: %s", tc.method, tc.path, w.Code, w.Body.String())
+		}
+		if w := s.do(t, tc.method, tc.path, tc.body, UserIDHeader, "alice"); w.Code != http.StatusOK {
+			t.Errorf("参与者 %s %s 返回 %d，期望 200: %s", tc.method, tc.path, w.Code, w.Body.String())
+		}
+	}
+
+	merge := gin.H{"source_conversation_id": "conv-other", "target_conversation_id": "conv-open"}
+	decode(t, s.do(t, http.MethodPost, "/api/conversations/merge", merge, UserIDHeader, "alice"), http.StatusForbidden, nil)
+	var sources int64
+	s.db.Model(&models.Conversation{}).Where("conversation_id = ?", "conv-other").Count(&sources)
+	if sources != 1 {
+		t.Fatalf("被拒绝的合并不应删除源对话")
+	}
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ChatRecommend/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// PinMessage 置顶消息，置顶消息总会注入补全上下文（即使已不在近期消息中）
+func (h *Handler) PinMessage(c *gin.Context) {
+	h.setMessagePinned(c, true)
+}
+
+// UnpinMessage 取消置顶消息
+func (h *Handler) UnpinMessage(c *gin.Context) {
+	h.setMessagePinned(c, false)
+}
+
+// setMessagePinned 设置消息的置顶状态，状态未变化时直接返回；每个对话最多置顶 MaxPinnedMessages 条，草稿不能置顶
+func (h *Handler) setMessagePinned(c *gin.Context, pinned bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "消息ID无效"})
+		return
+	}
+
+	var message models.Message
+	if err := h.db.First(&message, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "消息不存在"})
+		return
+	}
+	var conversation models.Conversation
+	if err := h.db.First(&conversation, message.ConversationID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "对话不存在"})
+		return
+	}
+	if conversation.ReadOnly {
+		respondError(c, ErrConversationReadOnly)
+		return
+	}
+	if message.Pinned == pinned {
+		c.JSON(http.StatusOK, gin.H{"message": models.ToMessageDTOs([]models.Message{message})[0], "status": "unchanged"})
+		return
+	}
+	if pinned && message.MessageType == models.MessageTypeDraft {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "草稿不能置顶"})
+		return
+	}
+
+	var pinnedAt *time.Time
+	if pinned {
+		now := time.Now()
+		pinnedAt = &now
+	}
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if pinned {
+			var count int64
+			if err := tx.Model(&models.Message{}).
+				Where("conversation_id = ? AND pinned = ?", message.ConversationID, true).
+				Count(&count).Error; err != nil {
+				return fmt.Errorf("统计置顶消息失败: %w", err)
+			}
+			if count >= models.MaxPinnedMessages {
+				return errTooManyPinned
+			}
+		}
+		// 只更新置顶列，不触发内容加密钩子
+		if err := tx.Model(&message).UpdateColumns(map[string]interface{}{
+			"pinned":    pinned,
+			"pinned_at": pinnedAt,
+		}).Error; err != nil {
+			return fmt.Errorf("更新消息置顶状态失败: %w", err)
+		}
+		return nil
+	})
+	if err == errTooManyPinned {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("每个对话最多置顶 %d 条消息", models.MaxPinnedMessages)})
+		return
+	} else if err != nil {
+		logrus.WithError(err).Error("更新消息置顶状态失败")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	message.Pinned = pinned
+	message.PinnedAt = pinnedAt
+
+	h.autocomplete.InvalidateCache(conversation.ConversationID)
+	h.hub.Broadcast(conversation.ConversationID, &WSMessage{
+		Type: "message_pinned",
+		Data: gin.H{
+			"conversation_id": conversation.ConversationID,
+			"message":         models.ToMessageDTOs([]models.Message{message})[0],
+		},
+	}, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": models.ToMessageDTOs([]models.Message{message})[0],
+		"status":  "success",
+	})
+}
+
+// errTooManyPinned 对话的置顶消息已达上限
+var errTooManyPinned = errors.New("置顶消息已达上限")
+
+// ListPinnedMessages 列出对话的置顶消息，最近置顶的在前
+func (h *Handler) ListPinnedMessages(c *gin.Context) {
+	var conversation models.Conversation
+	if err := h.db.Where("conversation_id = ?", c.Param("conversation_id")).First(&conversation).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "对话不存在"})
+		return
+	}
+
+	var messages []models.Message
+	if err := h.db.Where("conversation_id = ? AND pinned = ?", conversation.ID, true).
+		Order("pinned_at DESC, id DESC").
+		Find(&messages).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询置顶消息失败"})
+		return
+	}
+
+	loc, err := h.requestLocation(c, &conversation)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"conversation_id": conversation.ConversationID,
+		"timezone":        loc.String(),
+		"messages":        models.ToMessageDTOsIn(messages, loc),
+	})
+}
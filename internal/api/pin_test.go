@@ -0,0 +1,104 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+type pinResponse struct {
+	Message models.MessageDTO `json:"message"`
+	Status  string            `json:"status"`
+}
+
+// listPinned 查询对话的置顶消息内容（最近置顶的在前）
+func (s *testServer) listPinned(t *testing.T, conversationID string) []string {
+	t.Helper()
+	var resp struct {
+		Messages []models.MessageDTO `json:"messages"`
+	}
+	decode(t, s.do(t, http.MethodGet, "/api/chat/"+conversationID+"/pinned", nil), http.StatusOK, &resp)
+	contents := make([]string, len(resp.Messages))
+	for i, msg := range resp.Messages {
+		contents[i] = msg.Content
+	}
+	return contents
+}
+
+// 置顶和取消置顶消息，重复操作返回 unchanged；草稿不能置顶，消息不存在返回404
+func TestPinMessage(t *testing.T) {
+	s := newTestServer(t)
+	messages := []models.Message{
+		{SenderID: "alice", Content: "地址是人民路1号"},
+		{SenderID: "bob", Content: "周六下午三点"},
+		{SenderID: "alice", Content: "还没发", MessageType: models.MessageTypeDraft},
+	}
+	testutil.CreateConversation(t, s.db, "conv-pin", messages...)
+	path := func(msg models.Message) string { return fmt.Sprintf("/api/chat/message/%d/pin", msg.ID) }
+
+	var resp pinResponse
+	decode(t, s.do(t, http.MethodPost, path(messages[0]), nil), http.StatusOK, &resp)
+	if resp.Status != "success" || !resp.Message.Pinned || resp.Message.PinnedAt == nil {
+		t.Fatalf("置顶响应为 %+v", resp)
+	}
+	decode(t, s.do(t, http.MethodPost, path(messages[0]), nil), http.StatusOK, &resp)
+	if resp.Status != "unchanged" {
+		t.Errorf("重复置顶应返回 unchanged: %+v", resp)
+	}
+	decode(t, s.do(t, http.MethodPost, path(messages[1]), nil), http.StatusOK, nil)
+	assertIDs(t, "置顶消息", s.listPinned(t, "conv-pin"), "周六下午三点", "地址是人民路1号")
+
+	// pinned 和 pinned_at 未置顶时省略，重新解码前清空
+	resp = pinResponse{}
+	decode(t, s.do(t, http.MethodDelete, path(messages[1]), nil), http.StatusOK, &resp)
+	if resp.Status != "success" || resp.Message.Pinned || resp.Message.PinnedAt != nil {
+		t.Errorf("取消置顶响应为 %+v", resp)
+	}
+	assertIDs(t, "取消置顶后", s.listPinned(t, "conv-pin"), "地址是人民路1号")
+
+	decode(t, s.do(t, http.MethodPost, path(messages[2]), nil), http.StatusBadRequest, nil)
+	decode(t, s.do(t, http.MethodPost, "/api/chat/message/abc/pin", nil), http.StatusBadRequest, nil)
+	decode(t, s.do(t, http.MethodPost, "/api/chat/message/9999/pin", nil), http.StatusNotFound, nil)
+	decode(t, s.do(t, http.MethodGet, "/api/chat/conv-missing/pinned", nil), http.StatusNotFound, nil)
+}
+
+// 每个对话最多置顶 MaxPinnedMessages 条
+func TestPinMessageLimit(t *testing.T) {
+	s := newTestServer(t)
+	messages := make([]models.Message, models.MaxPinnedMessages+1)
+	for i := range messages {
+		messages[i] = models.Message{SenderID: "alice", Content: fmt.Sprintf("消息%d", i+1)}
+	}
+	testutil.CreateConversation(t, s.db, "conv-pin", messages...)
+
+	for _, msg := range messages[:models.MaxPinnedMessages] {
+		decode(t, s.do(t, http.MethodPost, fmt.Sprintf("/api/chat/message/%d/pin", msg.ID), nil), http.StatusOK, nil)
+	}
+	last := messages[models.MaxPinnedMessages]
+	decode(t, s.do(t, http.MethodPost, fmt.Sprintf("/api/chat/message/%d/pin", last.ID), nil), http.StatusBadRequest, nil)
+	if pinned := s.listPinned(t, "conv-pin"); len(pinned) != models.MaxPinnedMessages {
+		t.Errorf("置顶消息数为 %d，期望 %d", len(pinned), models.MaxPinnedMessages)
+	}
+}
+
+// 快照保存置顶状态，恢复后置顶消息仍然置顶
+func TestSnapshotRestoresPinnedMessages(t *testing.T) {
+	s := newTestServer(t)
+	messages := []models.Message{
+		{SenderID: "alice", Content: "地址是人民路1号"},
+		{SenderID: "bob", Content: "好的"},
+	}
+	testutil.CreateConversation(t, s.db, "conv-pin", messages...)
+	decode(t, s.do(t, http.MethodPost, fmt.Sprintf("/api/chat/message/%d/pin", messages[0].ID), nil), http.StatusOK, nil)
+
+	var snapshot models.ConversationSnapshot
+	decode(t, s.do(t, http.MethodPost, "/api/conversation/conv-pin/snapshot", map[string]string{"label": "置顶前"}, UserIDHeader, "alice"), http.StatusOK, &snapshot)
+	decode(t, s.do(t, http.MethodDelete, fmt.Sprintf("/api/chat/message/%d/pin", messages[0].ID), nil), http.StatusOK, nil)
+	assertIDs(t, "取消置顶后", s.listPinned(t, "conv-pin"))
+
+	decode(t, s.do(t, http.MethodPost, "/api/conversation/conv-pin/restore", map[string]uint{"snapshot_id": snapshot.ID}, UserIDHeader, "alice"), http.StatusOK, nil)
+	assertIDs(t, "恢复后", s.listPinned(t, "conv-pin"), "地址是人民路1号")
+}
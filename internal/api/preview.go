@@ -0,0 +1,66 @@
+package api
+
+import (
+	"net/http"
+
+	"ChatRecommend/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// PreviewStyleRequest 风格预览请求
+type PreviewStyleRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+}
+
+// PreviewSummary 预览按当前消息重新生成的摘要，不保存
+func (h *Handler) PreviewSummary(c *gin.Context) {
+	conversationID := c.Param("conversation_id")
+
+	var conversation models.Conversation
+	if err := h.db.Where("conversation_id = ?", conversationID).First(&conversation).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "对话不存在"})
+		return
+	}
+
+	preview, err := h.summary.PreviewSummary(conversation.ID)
+	if err != nil {
+		logrus.WithError(err).Error("预览摘要失败")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"conversation_id": conversationID,
+		"preview":         preview,
+	})
+}
+
+// PreviewStyle 预览按当前消息重新分析的用户风格，不保存
+func (h *Handler) PreviewStyle(c *gin.Context) {
+	conversationID := c.Param("conversation_id")
+
+	var req PreviewStyleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var conversation models.Conversation
+	if err := h.db.Where("conversation_id = ?", conversationID).First(&conversation).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "对话不存在"})
+		return
+	}
+
+	preview, err := h.style.PreviewStyle(conversation.ID, req.UserID)
+	if err != nil {
+		logrus.WithError(err).Error("预览风格失败")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"conversation_id": conversationID,
+		"preview":         preview,
+	})
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/style"
+	"ChatRecommend/internal/summary"
+	"ChatRecommend/internal/testutil"
+	"github.com/gin-gonic/gin"
+)
+
+// 摘要预览返回新生成的摘要和新增的关键信息，不修改已有摘要，对话没有摘要时也不创建
+func TestPreviewSummaryDoesNotWrite(t *testing.T) {
+	s := newTestServer(t)
+	s.llm.KeyInfo = `[{"type":"place","key":"地点","value":"海底捞"},{"type":"time","key":"时间","value":"周五"}]`
+	conversation := testutil.CreateConversation(t, s.db, "conv-preview",
+		models.Message{SenderID: "alice", Content: "周五去海底捞吧"},
+		models.Message{SenderID: "bob", Content: "好啊"})
+	testutil.CreateConversation(t, s.db, "conv-empty-summary", models.Message{SenderID: "alice", Content: "在吗"})
+
+	existing := models.Summary{ConversationID: conversation.ID, Prompt: "旧摘要", KeyInfo: `[{"type":"place","key":"地点","value":"海底捞"}]`, Version: 1}
+	if err := s.db.Create(&existing).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var resp struct {
+		Preview summary.Preview `json:"preview"`
+	}
+	decode(t, s.do(t, http.MethodPost, "/api/summary/conv-preview/preview", nil), http.StatusOK, &resp)
+	if resp.Preview.Prompt != s.llm.SummaryPrompt || resp.Preview.MessageCount != 2 {
+		t.Errorf("摘要预览为 %+v", resp.Preview)
+	}
+	if len(resp.Preview.AddedKeyInfo) != 1 || resp.Preview.AddedKeyInfo[0]["key"] != "时间" {
+		t.Errorf("新增关键信息为 %v，期望只有时间", resp.Preview.AddedKeyInfo)
+	}
+
+	var stored models.Summary
+	s.db.First(&stored, existing.ID)
+	if stored.Prompt != "旧摘要" || stored.KeyInfo != existing.KeyInfo || stored.Version != 1 {
+		t.Errorf("预览后摘要被修改: %+v", stored)
+	}
+
+	decode(t, s.do(t, http.MethodPost, "/api/summary/conv-empty-summary/preview", nil), http.StatusOK, nil)
+	var count int64
+	s.db.Model(&models.Summary{}).Count(&count)
+	if count != 1 {
+		t.Errorf("预览后有 %d 条摘要，期望仍为 1 条", count)
+	}
+	decode(t, s.do(t, http.MethodPost, "/api/summary/conv-missing/preview", nil), http.StatusNotFound, nil)
+}
+
+// 风格预览返回分析出的风格画像，不创建风格记录
+func TestPreviewStyleDoesNotWrite(t *testing.T) {
+	s := newTestServer(t)
+	testutil.CreateConversation(t, s.db, "conv-preview",
+		models.Message{SenderID: "alice", Content: "哈哈哈好的！"},
+		models.Message{SenderID: "bob", Content: "那就这么定了"},
+		models.Message{SenderID: "alice", Content: "周五见啦~"})
+
+	var resp struct {
+		Preview style.Preview `json:"preview"`
+	}
+	decode(t, s.do(t, http.MethodPost, "/api/style/conv-preview/preview", gin.H{"user_id": "alice"}), http.StatusOK, &resp)
+	if resp.Preview.Features == nil || resp.Preview.MessageCount != 2 || resp.Preview.Description == "" {
+		t.Errorf("风格预览为 %+v", resp.Preview)
+	}
+
+	resp.Preview = style.Preview{}
+	decode(t, s.do(t, http.MethodPost, "/api/style/conv-preview/preview", gin.H{"user_id": "carol"}), http.StatusOK, &resp)
+	if resp.Preview.Features != nil || resp.Preview.MessageCount != 0 {
+		t.Errorf("没有消息的用户预览为 %+v，期望为空", resp.Preview)
+	}
+
+	var count int64
+	s.db.Model(&models.Style{}).Count(&count)
+	if count != 0 {
+		t.Errorf("预览后有 %d 条风格记录，期望 0 条", count)
+	}
+	decode(t, s.do(t, http.MethodPost, "/api/style/conv-preview/preview", gin.H{}), http.StatusBadRequest, nil)
+}
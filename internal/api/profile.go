@@ -0,0 +1,71 @@
+package api
+
+import (
+	"net/http"
+
+	"ChatRecommend/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm/clause"
+)
+
+// UpdateUserProfile 更新用户个人档案（整体替换）
+func (h *Handler) UpdateUserProfile(c *gin.Context) {
+	senderID := c.Param("sender_id")
+
+	var fields models.ProfileFields
+	if err := c.ShouldBindJSON(&fields); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	profile := models.UserProfile{SenderID: senderID}
+	if err := profile.SetFields(&fields); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "sender_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"data", "updated_at"}),
+	}).Create(&profile).Error; err != nil {
+		logrus.WithError(err).Error("保存用户档案失败")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存用户档案失败"})
+		return
+	}
+
+	// 档案会注入用户所在对话的补全上下文，使这些对话的补全缓存失效
+	conversations, err := userConversations(h.db, senderID)
+	if err != nil {
+		logrus.WithError(err).Warn("查询用户对话失败，补全缓存未失效")
+	}
+	for _, conversation := range conversations {
+		h.autocomplete.InvalidateCache(conversation.ConversationID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sender_id": senderID,
+		"profile":   fields,
+	})
+}
+
+// GetUserProfile 获取用户个人档案，未设置时返回空档案
+func (h *Handler) GetUserProfile(c *gin.Context) {
+	senderID := c.Param("sender_id")
+
+	var profile models.UserProfile
+	if err := h.db.Where("sender_id = ?", senderID).Limit(1).Find(&profile).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询用户档案失败"})
+		return
+	}
+
+	fields, err := profile.GetFields()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sender_id": senderID,
+		"profile":   fields,
+	})
+}
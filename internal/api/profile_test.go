@@ -0,0 +1,44 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// 更新用户档案后，用户所在对话的补全缓存失效，下一次补全重新调用大模型
+func TestUpdateUserProfileInvalidatesCompletionCache(t *testing.T) {
+	s := newTestServerWithConfig(t, &config.AutocompleteConfig{SuggestionCount: 3, CacheTTLSeconds: 60})
+	s.saveMessage(t, "conv-profile", "bob", "周末去哪吃饭？")
+
+	complete := func() {
+		t.Helper()
+		w := s.do(t, http.MethodPost, "/api/chat/complete", gin.H{
+			"conversation_id": "conv-profile",
+			"sender_id":       "alice",
+			"input":           "去吃",
+		})
+		if w.Code != http.StatusOK {
+			t.Fatalf("补全返回 %d: %s", w.Code, w.Body.String())
+		}
+	}
+
+	complete()
+	complete()
+	if calls := s.llm.Calls(); calls != 1 {
+		t.Fatalf("相同请求应命中缓存，大模型调用 %d 次", calls)
+	}
+
+	if w := s.do(t, http.MethodPut, "/api/user/bob/profile", gin.H{
+		"preferences": gin.H{"口味": "不吃辣"},
+	}); w.Code != http.StatusOK {
+		t.Fatalf("更新档案返回 %d: %s", w.Code, w.Body.String())
+	}
+
+	complete()
+	if calls := s.llm.Calls(); calls != 2 {
+		t.Fatalf("更新档案后应重新调用大模型，实际调用 %d 次", calls)
+	}
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/prompt"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// maxPromptTemplateChars 提示词模板的最大字符数
+const maxPromptTemplateChars = 4000
+
+// SetPromptStore 设置提示词模板存储（在线更新模板的管理接口使用）
+func (h *Handler) SetPromptStore(store *prompt.Store) {
+	h.prompts = store
+}
+
+// promptName 读取并校验路径中的模板名称
+func promptName(c *gin.Context) (string, bool) {
+	name := c.Param("name")
+	if !models.ValidPromptName(name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "模板名称只能为 " + strings.Join(models.PromptNames, "、")})
+		return "", false
+	}
+	return name, true
+}
+
+// GetPromptTemplate 查看提示词模板的全部版本和当前生效的内容（GET /api/admin/prompts/:name）
+func (h *Handler) GetPromptTemplate(c *gin.Context) {
+	name, ok := promptName(c)
+	if !ok {
+		return
+	}
+
+	versions, err := h.prompts.Versions(name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询模板失败"})
+		return
+	}
+	var active *models.PromptTemplate
+	for i := range versions {
+		if versions[i].Active {
+			active = &versions[i]
+			break
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"name":     name,
+		"active":   active,
+		"versions": versions,
+	})
+}
+
+// UpdatePromptTemplate 新增提示词模板版本并立即激活（PUT /api/admin/prompts/:name），
+// 下一次构建上下文或生成摘要即使用新模板，同时清空补全缓存
+func (h *Handler) UpdatePromptTemplate(c *gin.Context) {
+	name, ok := promptName(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdatePromptTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if strings.TrimSpace(req.Content) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "模板内容不能为空"})
+		return
+	}
+	if len([]rune(req.Content)) > maxPromptTemplateChars {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "模板内容过长"})
+		return
+	}
+
+	template, err := h.prompts.Update(name, req.Content)
+	if err != nil {
+		logrus.WithError(err).Error("更新提示词模板失败")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.autocomplete.ClearCache()
+
+	logrus.WithFields(logrus.Fields{
+		"name":    name,
+		"version": template.Version,
+	}).Info("提示词模板已更新")
+
+	c.JSON(http.StatusOK, gin.H{
+		"template": template,
+		"status":   "success",
+	})
+}
+
+// DeactivatePromptTemplate 停用提示词模板的激活版本，回退到配置文件中的模板（DELETE /api/admin/prompts/:name）
+func (h *Handler) DeactivatePromptTemplate(c *gin.Context) {
+	name, ok := promptName(c)
+	if !ok {
+		return
+	}
+
+	deactivated, err := h.prompts.Deactivate(name)
+	if err != nil {
+		logrus.WithError(err).Error("停用提示词模板失败")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !deactivated {
+		c.JSON(http.StatusOK, gin.H{"status": "unchanged"})
+		return
+	}
+	h.autocomplete.ClearCache()
+
+	logrus.WithField("name", name).Info("提示词模板已停用，回退到配置文件")
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"ChatRecommend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// complete 请求一次补全
+func (s *testServer) complete(t *testing.T, conversationID, input string) {
+	t.Helper()
+	decode(t, s.do(t, http.MethodPost, "/api/chat/complete", gin.H{
+		"conversation_id": conversationID,
+		"sender_id":       "alice",
+		"input":           input,
+	}), http.StatusOK, nil)
+}
+
+// 在线更新系统提示词后下一次补全即使用新模板（清空补全缓存），停用后回退到配置文件
+func TestUpdatePromptTemplate(t *testing.T) {
+	s := newTestServer(t)
+	s.saveMessage(t, "conv-prompt", "bob", "晚上吃什么")
+
+	s.complete(t, "conv-prompt", "我想")
+	if strings.Contains(s.llm.LastContext, "你是客服助手") {
+		t.Fatalf("更新前不应包含新模板: %s", s.llm.LastContext)
+	}
+
+	var updated struct {
+		Template models.PromptTemplate `json:"template"`
+	}
+	decode(t, s.do(t, http.MethodPut, "/api/admin/prompts/system_prefix", gin.H{"content": "你是客服助手，当前对话 ${conversation_id}"}), http.StatusOK, &updated)
+	if updated.Template.Version != 1 || !updated.Template.Active {
+		t.Fatalf("更新结果为 %+v", updated.Template)
+	}
+
+	calls := s.llm.Calls()
+	s.complete(t, "conv-prompt", "我想")
+	if s.llm.Calls() != calls+1 {
+		t.Fatal("更新模板后应清空补全缓存")
+	}
+	if !strings.Contains(s.llm.LastContext, "你是客服助手，当前对话 conv-prompt") {
+		t.Errorf("补全应使用新模板并展开变量: %s", s.llm.LastContext)
+	}
+
+	decode(t, s.do(t, http.MethodPut, "/api/admin/prompts/system_prefix", gin.H{"content": "第二版"}), http.StatusOK, nil)
+	var listed struct {
+		Active   *models.PromptTemplate  `json:"active"`
+		Versions []models.PromptTemplate `json:"versions"`
+	}
+	decode(t, s.do(t, http.MethodGet, "/api/admin/prompts/system_prefix", nil), http.StatusOK, &listed)
+	if listed.Active == nil || listed.Active.Version != 2 || len(listed.Versions) != 2 {
+		t.Fatalf("模板版本为 %+v", listed)
+	}
+
+	var status struct {
+		Status string `json:"status"`
+	}
+	decode(t, s.do(t, http.MethodDelete, "/api/admin/prompts/system_prefix", nil), http.StatusOK, &status)
+	if status.Status != "success" {
+		t.Errorf("停用结果为 %q", status.Status)
+	}
+	s.complete(t, "conv-prompt", "我想")
+	if strings.Contains(s.llm.LastContext, "第二版") {
+		t.Errorf("停用后应回退到配置文件: %s", s.llm.LastContext)
+	}
+	decode(t, s.do(t, http.MethodDelete, "/api/admin/prompts/system_prefix", nil), http.StatusOK, &status)
+	if status.Status != "unchanged" {
+		t.Errorf("重复停用应返回 unchanged，实际 %q", status.Status)
+	}
+}
+
+// 更新摘要提示词前缀后生成摘要时使用新模板
+func TestUpdateSummaryPromptTemplate(t *testing.T) {
+	s := newTestServer(t)
+	decode(t, s.do(t, http.MethodPut, "/api/admin/prompts/summary_system_prefix", gin.H{"content": "只记录约定事项"}), http.StatusOK, nil)
+
+	conversation := models.Conversation{ConversationID: "conv-summary"}
+	if err := s.db.Create(&conversation).Error; err != nil {
+		t.Fatalf("创建对话失败: %v", err)
+	}
+	if err := s.handler.summary.UpdateSummary(conversation.ID, []models.Message{{SenderID: "alice", Content: "周五见"}}); err != nil {
+		t.Fatalf("更新摘要失败: %v", err)
+	}
+	if opts := s.llm.LastSummaryOptions; opts == nil || opts.SystemPrefix != "只记录约定事项" {
+		t.Errorf("摘要应使用新模板: %+v", opts)
+	}
+}
+
+// 模板名称不合法、内容为空或过长时返回400
+func TestUpdatePromptTemplateValidation(t *testing.T) {
+	s := newTestServer(t)
+	decode(t, s.do(t, http.MethodGet, "/api/admin/prompts/unknown", nil), http.StatusBadRequest, nil)
+	decode(t, s.do(t, http.MethodPut, "/api/admin/prompts/unknown", gin.H{"content": "x"}), http.StatusBadRequest, nil)
+	decode(t, s.do(t, http.MethodPut, "/api/admin/prompts/system_prefix", gin.H{"content": "   "}), http.StatusBadRequest, nil)
+	decode(t, s.do(t, http.MethodPut, "/api/admin/prompts/system_prefix", gin.H{"content": strings.Repeat("长", maxPromptTemplateChars+1)}), http.StatusBadRequest, nil)
+}
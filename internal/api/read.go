@@ -0,0 +1,98 @@
+package api
+
+import (
+	"net/http"
+
+	"ChatRecommend/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MarkRead 上报已读位置（已读位置只会前进不会后退）
+func (h *Handler) MarkRead(c *gin.Context) {
+	var req models.MarkReadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var conversation models.Conversation
+	err := h.db.Where("conversation_id = ?", c.Param("conversation_id")).First(&conversation).Error
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "对话不存在"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询对话失败"})
+		return
+	}
+
+	lastRead := req.LastReadSequence
+	if lastRead == 0 {
+		if err := h.db.Model(&models.Message{}).
+			Where("conversation_id = ?", conversation.ID).
+			Select("COALESCE(MAX(sequence), 0)").
+			Scan(&lastRead).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询消息失败"})
+			return
+		}
+	}
+
+	cursor := models.ReadCursor{
+		ConversationID:   conversation.ID,
+		UserID:           req.UserID,
+		LastReadSequence: lastRead,
+	}
+	if err := h.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "conversation_id"}, {Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"last_read_sequence": gorm.Expr("MAX(read_cursors.last_read_sequence, excluded.last_read_sequence)"),
+			"updated_at":         gorm.Expr("excluded.updated_at"),
+		}),
+	}).Create(&cursor).Error; err != nil {
+		logrus.WithError(err).Error("保存已读位置失败")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存已读位置失败"})
+		return
+	}
+
+	// 重新读取实际保存的已读位置（上报的位置可能比已有位置更旧）
+	if err := h.db.Where("conversation_id = ? AND user_id = ?", conversation.ID, req.UserID).
+		First(&cursor).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询已读位置失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"conversation_id":    conversation.ConversationID,
+		"user_id":            req.UserID,
+		"last_read_sequence": cursor.LastReadSequence,
+		"status":             "success",
+	})
+}
+
+// countUnread 统计用户在各对话中的未读数（序号大于已读位置且不是自己发送的消息）
+func (h *Handler) countUnread(conversationIDs []uint, userID string) (map[uint]int64, error) {
+	type unreadRow struct {
+		ConversationID uint
+		Count          int64
+	}
+
+	var rows []unreadRow
+	err := h.db.Table("messages AS m").
+		Select("m.conversation_id AS conversation_id, COUNT(*) AS count").
+		Joins("LEFT JOIN read_cursors AS r ON r.conversation_id = m.conversation_id AND r.user_id = ?", userID).
+		Where("m.conversation_id IN ? AND m.deleted_at IS NULL AND m.sender_id <> ? AND m.message_type <> ?", conversationIDs, userID, models.MessageTypeDraft).
+		Where("m.sequence > COALESCE(r.last_read_sequence, 0)").
+		Group("m.conversation_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		counts[row.ConversationID] = row.Count
+	}
+	return counts, nil
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"ChatRecommend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// unreadCount 查询对话列表中某个对话对用户的未读数
+func (s *testServer) unreadCount(t *testing.T, conversationID, userID string) int64 {
+	t.Helper()
+	var resp struct {
+		Conversations []struct {
+			ConversationID string `json:"conversation_id"`
+			UnreadCount    *int64 `json:"unread_count"`
+		} `json:"conversations"`
+	}
+	decode(t, s.do(t, http.MethodGet, "/api/conversations?user_id="+userID, nil), http.StatusOK, &resp)
+	for _, conversation := range resp.Conversations {
+		if conversation.ConversationID == conversationID {
+			if conversation.UnreadCount == nil {
+				t.Fatalf("对话 %s 缺少 unread_count", conversationID)
+			}
+			return *conversation.UnreadCount
+		}
+	}
+	t.Fatalf("对话列表中没有 %s", conversationID)
+	return 0
+}
+
+// 未读数只统计他人发送且序号大于已读位置的消息，上报已读后归零
+func TestMarkReadClearsUnreadCount(t *testing.T) {
+	s := newTestServer(t)
+	s.saveMessage(t, "conv-read", "bob", "在吗")
+	second := s.saveMessage(t, "conv-read", "bob", "周五有空吗")
+	s.saveMessage(t, "conv-read", "alice", "有")
+	s.saveMessage(t, "conv-read", "bob", "那就周五")
+
+	if got := s.unreadCount(t, "conv-read", "alice"); got != 3 {
+		t.Fatalf("已读前未读数为 %d，期望 3", got)
+	}
+
+	// 读到第2条
+	var message models.Message
+	if err := s.db.First(&message, second).Error; err != nil {
+		t.Fatalf("查询消息失败: %v", err)
+	}
+	if w := s.do(t, http.MethodPost, "/api/chat/conv-read/read", gin.H{"user_id": "alice", "last_read_sequence": message.Sequence}); w.Code != http.StatusOK {
+		t.Fatalf("上报已读返回 %d: %s", w.Code, w.Body.String())
+	}
+	if got := s.unreadCount(t, "conv-read", "alice"); got != 1 {
+		t.Fatalf("读到第2条后未读数为 %d，期望 1", got)
+	}
+
+	// 未指定序号表示全部已读；已读位置不会回退
+	if w := s.do(t, http.MethodPost, "/api/chat/conv-read/read", gin.H{"user_id": "alice"}); w.Code != http.StatusOK {
+		t.Fatalf("上报已读返回 %d: %s", w.Code, w.Body.String())
+	}
+	if w := s.do(t, http.MethodPost, "/api/chat/conv-read/read", gin.H{"user_id": "alice", "last_read_sequence": 1}); w.Code != http.StatusOK {
+		t.Fatalf("上报已读返回 %d: %s", w.Code, w.Body.String())
+	}
+	if got := s.unreadCount(t, "conv-read", "alice"); got != 0 {
+		t.Fatalf("全部已读后未读数为 %d，期望 0", got)
+	}
+	if got := s.unreadCount(t, "conv-read", "bob"); got != 1 {
+		t.Fatalf("bob 的未读数为 %d，期望 1", got)
+	}
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"ChatRecommend/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 只读对话的消息不能编辑、置顶或取消置顶，返回 403 FORBIDDEN
+func TestReadOnlyConversationRejectsMessageChanges(t *testing.T) {
+	s := newTestServer(t)
+	id := s.saveMessage(t, "conv-readonly", "alice", "原内容")
+	if err := s.db.Model(&models.Conversation{}).Where("conversation_id = ?", "conv-readonly").
+		Update("read_only", true).Error; err != nil {
+		t.Fatalf("设置只读失败: %v", err)
+	}
+
+	requests := []struct {
+		method string
+		path   string
+		body   interface{}
+	}{
+		{http.MethodPut, fmt.Sprintf("/api/chat/message/%d", id), gin.H{"sender_id": "alice", "content": "新内容"}},
+		{http.MethodPost, fmt.Sprintf("/api/chat/message/%d/pin", id), nil},
+		{http.MethodDelete, fmt.Sprintf("/api/chat/message/%d/pin", id), nil},
+	}
+	for _, r := range requests {
+		var body ErrorBody
+		decode(t, s.do(t, r.method, r.path, r.body), http.StatusForbidden, &body)
+		if body.Code != ErrCodeForbidden {
+			t.Errorf("%s %s 错误码为 %s，期望 %s", r.method, r.path, body.Code, ErrCodeForbidden)
+		}
+	}
+
+	var message models.Message
+	if err := s.db.First(&message, id).Error; err != nil {
+		t.Fatalf("查询消息失败: %v", err)
+	}
+	if message.Content != "原内容" || message.Pinned {
+		t.Fatalf("只读对话的消息被修改: content=%q pinned=%v", message.Content, message.Pinned)
+	}
+}
+
+// 保存消息期间对话被设为只读（并修改了标签），保存完成后不会把对话行写回旧值
+func TestSaveMessageKeepsConcurrentConversationUpdates(t *testing.T) {
+	s := newTestServer(t)
+	s.saveMessage(t, "conv-inflight", "alice", "第一条")
+
+	// 消息写入后、更新对话之前，模拟另一个请求设置只读和标签
+	updated := false
+	err := s.db.Callback().Create().After("gorm:create").Register("test:concurrent_read_only", func(tx *gorm.DB) {
+		if updated || tx.Statement.Table != "messages" {
+			return
+		}
+		updated = true
+		tx.Session(&gorm.Session{NewDB: true}).Model(&models.Conversation{}).
+			Where("conversation_id = ?", "conv-inflight").
+			Updates(map[string]interface{}{"read_only": true, "tags": `["工作"]`})
+	})
+	if err != nil {
+		t.Fatalf("注册回调失败: %v", err)
+	}
+
+	s.saveMessage(t, "conv-inflight", "alice", "第二条")
+	if !updated {
+		t.Fatal("模拟的并发更新没有执行")
+	}
+
+	var conversation models.Conversation
+	if err := s.db.Where("conversation_id = ?", "conv-inflight").First(&conversation).Error; err != nil {
+		t.Fatalf("查询对话失败: %v", err)
+	}
+	if !conversation.ReadOnly || conversation.Tags != `["工作"]` {
+		t.Fatalf("并发设置的只读和标签被覆盖: read_only=%v tags=%s", conversation.ReadOnly, conversation.Tags)
+	}
+
+	// 之后的保存被拒绝
+	decode(t, s.do(t, http.MethodPost, "/api/chat/message", gin.H{
+		"conversation_id": "conv-inflight", "sender_id": "alice", "content": "第三条",
+	}), http.StatusForbidden, nil)
+}
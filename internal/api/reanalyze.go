@@ -0,0 +1,254 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// 重新分析任务状态
+const (
+	ReanalyzeRunning   = "running"
+	ReanalyzeCompleted = "completed"
+	ReanalyzeCancelled = "cancelled"
+	ReanalyzeFailed    = "failed"
+)
+
+// maxReanalyzeJobs 保留的任务记录数（含已结束的任务），超出时丢弃最早的
+const maxReanalyzeJobs = 20
+
+// ReanalyzeRequest 批量重新分析请求
+type ReanalyzeRequest struct {
+	// 要重算的对话，为空表示全部对话
+	ConversationIDs []string `json:"conversation_ids"`
+}
+
+// ReanalyzeJob 批量重新分析任务（只保存在内存中，服务重启后丢失）
+type ReanalyzeJob struct {
+	ID              string   `json:"id"`
+	Status          string   `json:"status"`
+	ConversationIDs []string `json:"conversation_ids,omitempty"`
+	// 需要处理的对话数、已处理数（含失败）和失败数
+	Total      int64      `json:"total"`
+	Processed  int64      `json:"processed"`
+	Failed     int64      `json:"failed"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+
+	stop     chan struct{}
+	stopping bool
+}
+
+// reanalyzer 管理重新分析任务，同一时间只运行一个任务
+type reanalyzer struct {
+	mu        sync.Mutex
+	jobs      map[string]*ReanalyzeJob
+	order     []string
+	running   *ReanalyzeJob
+	seq       int64
+	batchSize int
+	interval  time.Duration
+}
+
+func newReanalyzer() *reanalyzer {
+	return &reanalyzer{
+		jobs:      make(map[string]*ReanalyzeJob),
+		batchSize: 20,
+		interval:  time.Second,
+	}
+}
+
+// SetReanalyzeConfig 设置批量重新分析的分批大小和限速间隔
+func (h *Handler) SetReanalyzeConfig(cfg *config.ReanalyzeConfig) {
+	h.reanalyze.mu.Lock()
+	defer h.reanalyze.mu.Unlock()
+	h.reanalyze.batchSize = cfg.BatchSize
+	h.reanalyze.interval = time.Duration(cfg.IntervalMs) * time.Millisecond
+}
+
+// snapshot 复制任务当前状态（需持有锁）
+func (job *ReanalyzeJob) snapshot() ReanalyzeJob {
+	copied := *job
+	copied.stop = nil
+	copied.stopping = false
+	return copied
+}
+
+// StartReanalyze 创建批量重新分析任务，后台分批重算对话的摘要和风格（POST /api/admin/reanalyze）
+func (h *Handler) StartReanalyze(c *gin.Context) {
+	var req ReanalyzeRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	query := h.db.Model(&models.Conversation{})
+	if len(req.ConversationIDs) > 0 {
+		query = query.Where("conversation_id IN ?", req.ConversationIDs)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "统计对话失败"})
+		return
+	}
+	if total == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "没有需要重新分析的对话"})
+		return
+	}
+
+	r := h.reanalyze
+	r.mu.Lock()
+	if r.running != nil {
+		running := r.running.snapshot()
+		r.mu.Unlock()
+		c.JSON(http.StatusConflict, gin.H{"error": "已有重新分析任务在运行", "job": running})
+		return
+	}
+	r.seq++
+	job := &ReanalyzeJob{
+		ID:              strconv.FormatInt(r.seq, 10),
+		Status:          ReanalyzeRunning,
+		ConversationIDs: req.ConversationIDs,
+		Total:           total,
+		StartedAt:       time.Now(),
+		stop:            make(chan struct{}),
+	}
+	r.jobs[job.ID] = job
+	r.order = append(r.order, job.ID)
+	if len(r.order) > maxReanalyzeJobs {
+		delete(r.jobs, r.order[0])
+		r.order = r.order[1:]
+	}
+	r.running = job
+	batchSize, interval := r.batchSize, r.interval
+	result := job.snapshot()
+	r.mu.Unlock()
+
+	go h.runReanalyze(job, batchSize, interval)
+
+	logrus.WithFields(logrus.Fields{
+		"job_id": job.ID,
+		"total":  total,
+	}).Info("开始批量重新分析")
+	c.JSON(http.StatusAccepted, result)
+}
+
+// GetReanalyze 查询重新分析任务进度（GET /api/admin/reanalyze/:id）
+func (h *Handler) GetReanalyze(c *gin.Context) {
+	h.reanalyze.mu.Lock()
+	job, ok := h.reanalyze.jobs[c.Param("id")]
+	var result ReanalyzeJob
+	if ok {
+		result = job.snapshot()
+	}
+	h.reanalyze.mu.Unlock()
+
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// CancelReanalyze 中断运行中的重新分析任务（DELETE /api/admin/reanalyze/:id），正在处理的对话完成后停止
+func (h *Handler) CancelReanalyze(c *gin.Context) {
+	r := h.reanalyze
+	r.mu.Lock()
+	job, ok := r.jobs[c.Param("id")]
+	if ok && job.Status == ReanalyzeRunning && !job.stopping {
+		job.stopping = true
+		close(job.stop)
+	}
+	var result ReanalyzeJob
+	if ok {
+		result = job.snapshot()
+	}
+	r.mu.Unlock()
+
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// runReanalyze 按对话ID顺序分批处理，每处理完一个对话等待 interval，收到中断信号后停止
+func (h *Handler) runReanalyze(job *ReanalyzeJob, batchSize int, interval time.Duration) {
+	stop := job.stop
+	status, runErr := ReanalyzeCompleted, error(nil)
+	var lastID uint
+loop:
+	for {
+		var batch []models.Conversation
+		query := h.db.Select("id", "conversation_id").Where("id > ?", lastID).Order("id ASC").Limit(batchSize)
+		if len(job.ConversationIDs) > 0 {
+			query = query.Where("conversation_id IN ?", job.ConversationIDs)
+		}
+		if err := query.Find(&batch).Error; err != nil {
+			status, runErr = ReanalyzeFailed, fmt.Errorf("查询对话失败: %w", err)
+			break
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		for _, conversation := range batch {
+			select {
+			case <-stop:
+				status = ReanalyzeCancelled
+				break loop
+			default:
+			}
+
+			err := h.recomputeSummaryAndStyle(conversation.ID)
+			h.reanalyze.mu.Lock()
+			job.Processed++
+			if err != nil {
+				job.Failed++
+			}
+			h.reanalyze.mu.Unlock()
+
+			// 限速：每个对话至少调用一次大模型并写入多张表
+			select {
+			case <-stop:
+				status = ReanalyzeCancelled
+				break loop
+			case <-time.After(interval):
+			}
+		}
+		lastID = batch[len(batch)-1].ID
+	}
+
+	now := time.Now()
+	h.reanalyze.mu.Lock()
+	job.Status = status
+	if runErr != nil {
+		job.Error = runErr.Error()
+	}
+	job.FinishedAt = &now
+	h.reanalyze.running = nil
+	result := job.snapshot()
+	h.reanalyze.mu.Unlock()
+
+	entry := logrus.WithFields(logrus.Fields{
+		"job_id":    result.ID,
+		"status":    result.Status,
+		"processed": result.Processed,
+		"failed":    result.Failed,
+	})
+	if runErr != nil {
+		entry.WithError(runErr).Error("批量重新分析失败")
+		return
+	}
+	entry.Info("批量重新分析结束")
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+	"github.com/gin-gonic/gin"
+)
+
+// waitReanalyze 轮询任务进度直到任务结束
+func (s *testServer) waitReanalyze(t *testing.T, id string) ReanalyzeJob {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var job ReanalyzeJob
+		decode(t, s.do(t, http.MethodGet, "/api/admin/reanalyze/"+id, nil), http.StatusOK, &job)
+		if job.Status != ReanalyzeRunning {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("任务 %s 超时未结束: %+v", id, job)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// 批量重新分析分批重算全部对话的摘要，进度可查询；指定对话时只处理这些对话
+func TestReanalyzeRecomputesConversations(t *testing.T) {
+	s := newTestServer(t)
+	s.handler.SetReanalyzeConfig(&config.ReanalyzeConfig{BatchSize: 2, IntervalMs: 1})
+	for _, id := range []string{"conv-a", "conv-b", "conv-c"} {
+		testutil.CreateConversation(t, s.db, id,
+			models.Message{SenderID: "alice", Content: "明天一起吃饭吗"},
+			models.Message{SenderID: "bob", Content: "好啊，几点"})
+	}
+
+	var job ReanalyzeJob
+	decode(t, s.do(t, http.MethodPost, "/api/admin/reanalyze", nil), http.StatusAccepted, &job)
+	if job.Total != 3 {
+		t.Fatalf("任务总数为 %d，期望 3", job.Total)
+	}
+	job = s.waitReanalyze(t, job.ID)
+	if job.Status != ReanalyzeCompleted || job.Processed != 3 || job.Failed != 0 || job.FinishedAt == nil {
+		t.Fatalf("任务结束状态为 %+v", job)
+	}
+	if s.llm.SummaryCalls != 3 {
+		t.Errorf("重算摘要调用大模型 %d 次，期望 3 次", s.llm.SummaryCalls)
+	}
+	var summaries int64
+	s.db.Model(&models.Summary{}).Where("prompt = ?", s.llm.SummaryPrompt).Count(&summaries)
+	if summaries != 3 {
+		t.Errorf("重算后有 %d 个对话的摘要已更新，期望 3 个", summaries)
+	}
+
+	decode(t, s.do(t, http.MethodPost, "/api/admin/reanalyze", gin.H{"conversation_ids": []string{"conv-b"}}), http.StatusAccepted, &job)
+	if job.Total != 1 {
+		t.Fatalf("指定对话的任务总数为 %d，期望 1", job.Total)
+	}
+	if job = s.waitReanalyze(t, job.ID); job.Processed != 1 {
+		t.Errorf("指定对话的任务处理了 %d 个对话，期望 1 个", job.Processed)
+	}
+
+	decode(t, s.do(t, http.MethodPost, "/api/admin/reanalyze", gin.H{"conversation_ids": []string{"conv-missing"}}), http.StatusNotFound, nil)
+	decode(t, s.do(t, http.MethodGet, "/api/admin/reanalyze/999", nil), http.StatusNotFound, nil)
+}
+
+// 运行中的任务不允许再启动新任务，中断后在当前对话处理完时停止
+func TestReanalyzeCancel(t *testing.T) {
+	s := newTestServer(t)
+	s.handler.SetReanalyzeConfig(&config.ReanalyzeConfig{BatchSize: 10, IntervalMs: int(time.Hour / time.Millisecond)})
+	for _, id := range []string{"conv-a", "conv-b", "conv-c"} {
+		testutil.CreateConversation(t, s.db, id, models.Message{SenderID: "alice", Content: "在吗"})
+	}
+
+	var job ReanalyzeJob
+	decode(t, s.do(t, http.MethodPost, "/api/admin/reanalyze", nil), http.StatusAccepted, &job)
+	decode(t, s.do(t, http.MethodPost, "/api/admin/reanalyze", nil), http.StatusConflict, nil)
+
+	decode(t, s.do(t, http.MethodDelete, "/api/admin/reanalyze/"+job.ID, nil), http.StatusOK, nil)
+	job = s.waitReanalyze(t, job.ID)
+	if job.Status != ReanalyzeCancelled || job.Processed >= job.Total {
+		t.Fatalf("中断后任务状态为 %+v，期望 cancelled 且未处理完", job)
+	}
+
+	// 中断后可以启动新任务
+	decode(t, s.do(t, http.MethodPost, "/api/admin/reanalyze", nil), http.StatusAccepted, &job)
+	decode(t, s.do(t, http.MethodDelete, "/api/admin/reanalyze/"+job.ID, nil), http.StatusOK, nil)
+	s.waitReanalyze(t, job.ID)
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// 保存消息被拒绝时返回结构化错误：只读对话为 403 FORBIDDEN，重复消息（reject）为 409 DUPLICATE
+func TestSaveMessageStructuredErrors(t *testing.T) {
+	s := newTestServer(t)
+	s.handler.SetDedupeConfig(&config.DedupeConfig{WindowSeconds: 60, Action: DedupeReject})
+
+	s.saveMessage(t, "conv-save", "alice", "在吗")
+	var body ErrorBody
+	decode(t, s.do(t, http.MethodPost, "/api/chat/message", gin.H{
+		"conversation_id": "conv-save",
+		"sender_id":       "alice",
+		"content":         "在吗",
+	}), http.StatusConflict, &body)
+	if body.Code != ErrCodeDuplicate {
+		t.Errorf("重复消息错误码为 %s，期望 %s", body.Code, ErrCodeDuplicate)
+	}
+
+	if err := s.db.Model(&models.Conversation{}).Where("conversation_id = ?", "conv-save").
+		Update("read_only", true).Error; err != nil {
+		t.Fatalf("设置只读失败: %v", err)
+	}
+	body = ErrorBody{}
+	decode(t, s.do(t, http.MethodPost, "/api/chat/message", gin.H{
+		"conversation_id": "conv-save",
+		"sender_id":       "bob",
+		"content":         "在的",
+	}), http.StatusForbidden, &body)
+	if body.Code != ErrCodeForbidden {
+		t.Errorf("只读对话错误码为 %s，期望 %s", body.Code, ErrCodeForbidden)
+	}
+}
+
+// 消息按 content_format 保存原文，未指定时为 plain；不支持的格式返回400
+func TestSaveMessageContentFormat(t *testing.T) {
+	s := newTestServer(t)
+	var resp struct {
+		MessageID uint `json:"message_id"`
+	}
+	decode(t, s.do(t, http.MethodPost, "/api/chat/message", gin.H{
+		"conversation_id": "conv-format",
+		"sender_id":       "alice",
+		"content":         "**周六**见",
+		"content_format":  models.ContentFormatMarkdown,
+	}), http.StatusOK, &resp)
+	plainID := s.saveMessage(t, "conv-format", "bob", "好的")
+
+	var markdown, plain models.Message
+	s.db.First(&markdown, resp.MessageID)
+	s.db.First(&plain, plainID)
+	if markdown.Content != "**周六**见" || markdown.ContentFormat != models.ContentFormatMarkdown {
+		t.Errorf("Markdown 消息应保存原文: %+v", markdown)
+	}
+	if plain.ContentFormat != models.ContentFormatPlain {
+		t.Errorf("未指定格式时应为 plain，实际 %q", plain.ContentFormat)
+	}
+
+	decode(t, s.do(t, http.MethodPost, "/api/chat/message", gin.H{
+		"conversation_id": "conv-format",
+		"sender_id":       "alice",
+		"content":         "<b>周六</b>",
+		"content_format":  "html",
+	}), http.StatusBadRequest, nil)
+
+	conn := s.dialWS(t, "")
+	conn.send(t, map[string]interface{}{
+		"type": "save_message",
+		"save_message_request": map[string]interface{}{
+			"conversation_id": "conv-format", "sender_id": "alice", "content": "<b>周六</b>", "content_format": "html",
+		},
+	})
+	conn.expectError(t, ErrCodeInvalidRequest, false)
+}
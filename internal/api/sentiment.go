@@ -0,0 +1,248 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/sentiment"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 情绪走势的分段方式
+const (
+	TrendSegmentDay   = "day"
+	TrendSegmentHour  = "hour"
+	TrendSegmentCount = "count"
+)
+
+// 按消息数分段时每段的默认和最大消息数
+const (
+	defaultTrendSegmentSize = 20
+	maxTrendSegmentSize     = 1000
+)
+
+// maxTrendCacheEntries 情绪走势缓存的最大条目数，超出时淘汰最早写入的
+const maxTrendCacheEntries = 256
+
+// TrendPoint 情绪走势中的一个分段
+type TrendPoint struct {
+	// 分段标签：按天为日期，按小时为"日期 时:00"，按消息数为消息序号范围（如 1-20）
+	Label string `json:"label"`
+	// 分段内第一条和最后一条消息的时间
+	Start        time.Time `json:"start"`
+	End          time.Time `json:"end"`
+	MessageCount int       `json:"message_count"`
+	sentiment.Segment
+}
+
+// SenderTrend 单个发送者的情绪走势
+type SenderTrend struct {
+	SenderID string       `json:"sender_id"`
+	Points   []TrendPoint `json:"points"`
+}
+
+// SentimentTrend 对话的情绪走势
+type SentimentTrend struct {
+	ConversationID string       `json:"conversation_id"`
+	Segment        string       `json:"segment"`
+	Points         []TrendPoint `json:"points"`
+	// 按发送者拆分的走势（by_sender=true 时返回），按发送者首次出现的顺序排列
+	Senders []SenderTrend `json:"senders,omitempty"`
+}
+
+// trendVersion 对话消息的版本：条数、最大ID和最后更新时间都不变时消息没有新增、删除或编辑
+type trendVersion struct {
+	Count     int64
+	MaxID     uint
+	UpdatedAt string
+}
+
+// trendEntry 情绪走势缓存项
+type trendEntry struct {
+	version  trendVersion
+	trend    *SentimentTrend
+	storedAt time.Time
+}
+
+// trendCache 按对话和查询参数缓存情绪走势，消息版本变化后失效
+type trendCache struct {
+	mu      sync.Mutex
+	entries map[string]trendEntry
+}
+
+func newTrendCache() *trendCache {
+	return &trendCache{entries: make(map[string]trendEntry)}
+}
+
+func (c *trendCache) get(key string, version trendVersion) (*SentimentTrend, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || entry.version != version {
+		return nil, false
+	}
+	return entry.trend, true
+}
+
+func (c *trendCache) set(key string, version trendVersion, trend *SentimentTrend) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= maxTrendCacheEntries {
+		var oldestKey string
+		var oldest time.Time
+		for k, entry := range c.entries {
+			if oldestKey == "" || entry.storedAt.Before(oldest) {
+				oldestKey, oldest = k, entry.storedAt
+			}
+		}
+		delete(c.entries, oldestKey)
+	}
+	c.entries[key] = trendEntry{version: version, trend: trend, storedAt: time.Now()}
+}
+
+// GetSentimentTrend 获取对话的情绪走势：按天、小时或消息数把历史消息（不含草稿）分段，逐段做情绪打分。
+// 查询参数 segment（day/hour/count，默认 day）、size（按消息数分段时每段条数，默认20）、by_sender（是否按发送者拆分）。
+// 结果按消息版本缓存，对话有新增、编辑或删除的消息时重新计算
+func (h *Handler) GetSentimentTrend(c *gin.Context) {
+	segment := c.DefaultQuery("segment", TrendSegmentDay)
+	switch segment {
+	case TrendSegmentDay, TrendSegmentHour, TrendSegmentCount:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "segment 只能是 day、hour 或 count"})
+		return
+	}
+	size, err := strconv.Atoi(c.DefaultQuery("size", strconv.Itoa(defaultTrendSegmentSize)))
+	if err != nil || size <= 0 || size > maxTrendSegmentSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("size 必须是1到%d之间的整数", maxTrendSegmentSize)})
+		return
+	}
+	bySender, err := strconv.ParseBool(c.DefaultQuery("by_sender", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "by_sender 必须是布尔值"})
+		return
+	}
+
+	var conversation models.Conversation
+	err = h.db.Where("conversation_id = ?", c.Param("conversation_id")).First(&conversation).Error
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "对话不存在"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询对话失败"})
+		return
+	}
+
+	trend, err := h.sentimentTrend(&conversation, segment, size, bySender)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, trend)
+}
+
+// sentimentTrend 计算情绪走势，消息版本未变时返回缓存结果
+func (h *Handler) sentimentTrend(conversation *models.Conversation, segment string, size int, bySender bool) (*SentimentTrend, error) {
+	var version trendVersion
+	row := h.db.Model(&models.Message{}).Scopes(models.ExcludeDrafts).
+		Where("conversation_id = ?", conversation.ID).
+		Select("COUNT(*), COALESCE(MAX(id), 0), COALESCE(MAX(updated_at), '')").Row()
+	if err := row.Scan(&version.Count, &version.MaxID, &version.UpdatedAt); err != nil {
+		return nil, fmt.Errorf("查询消息版本失败: %w", err)
+	}
+
+	if segment != TrendSegmentCount {
+		size = 0
+	}
+	key := fmt.Sprintf("%d:%s:%d:%t", conversation.ID, segment, size, bySender)
+	if trend, ok := h.trends.get(key, version); ok {
+		return trend, nil
+	}
+
+	var messages []models.Message
+	if err := h.db.Scopes(models.ExcludeDrafts).
+		Where("conversation_id = ?", conversation.ID).
+		Order("created_at ASC, sequence ASC, id ASC").
+		Find(&messages).Error; err != nil {
+		return nil, fmt.Errorf("查询消息失败: %w", err)
+	}
+
+	trend := &SentimentTrend{
+		ConversationID: conversation.ConversationID,
+		Segment:        segment,
+		Points:         trendPoints(messages, segment, size, h.location),
+	}
+	if bySender {
+		var order []string
+		groups := make(map[string][]models.Message)
+		for _, msg := range messages {
+			if _, ok := groups[msg.SenderID]; !ok {
+				order = append(order, msg.SenderID)
+			}
+			groups[msg.SenderID] = append(groups[msg.SenderID], msg)
+		}
+		trend.Senders = make([]SenderTrend, 0, len(order))
+		for _, senderID := range order {
+			trend.Senders = append(trend.Senders, SenderTrend{
+				SenderID: senderID,
+				Points:   trendPoints(groups[senderID], segment, size, h.location),
+			})
+		}
+	}
+
+	h.trends.set(key, version, trend)
+	return trend, nil
+}
+
+// trendPoints 把按时间排序的消息分段并逐段打分；按时间分段时只返回有消息的时段
+func trendPoints(messages []models.Message, segment string, size int, loc *time.Location) []TrendPoint {
+	if loc == nil {
+		loc = time.Local
+	}
+
+	points := make([]TrendPoint, 0)
+	var texts []string
+	flush := func(label string, segmentMessages []models.Message) {
+		texts = texts[:0]
+		for _, msg := range segmentMessages {
+			texts = append(texts, msg.Content)
+		}
+		points = append(points, TrendPoint{
+			Label:        label,
+			Start:        segmentMessages[0].CreatedAt,
+			End:          segmentMessages[len(segmentMessages)-1].CreatedAt,
+			MessageCount: len(segmentMessages),
+			Segment:      sentiment.AnalyzeSegment(texts),
+		})
+	}
+
+	if segment == TrendSegmentCount {
+		for start := 0; start < len(messages); start += size {
+			end := start + size
+			if end > len(messages) {
+				end = len(messages)
+			}
+			flush(fmt.Sprintf("%d-%d", start+1, end), messages[start:end])
+		}
+		return points
+	}
+
+	layout := dayLayout
+	if segment == TrendSegmentHour {
+		layout = "2006-01-02 15:00"
+	}
+	start := 0
+	for i := 1; i <= len(messages); i++ {
+		label := messages[start].CreatedAt.In(loc).Format(layout)
+		if i < len(messages) && messages[i].CreatedAt.In(loc).Format(layout) == label {
+			continue
+		}
+		flush(label, messages[start:i])
+		start = i
+	}
+	return points
+}
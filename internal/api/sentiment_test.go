@@ -0,0 +1,85 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/sentiment"
+	"ChatRecommend/internal/testutil"
+)
+
+// 按天、按消息数和按发送者分段计算情绪走势
+func TestGetSentimentTrend(t *testing.T) {
+	s := newTestServer(t)
+	day1 := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	day2 := day1.Add(24 * time.Hour)
+	testutil.CreateConversation(t, s.db, "conv-trend",
+		models.Message{SenderID: "alice", Content: "气死我了，凭什么", CreatedAt: day1},
+		models.Message{SenderID: "bob", Content: "别生气，我的错", CreatedAt: day1.Add(time.Minute)},
+		models.Message{SenderID: "alice", Content: "太好了，好开心", CreatedAt: day2},
+		models.Message{SenderID: "bob", Content: "哈哈，那就好", CreatedAt: day2.Add(time.Minute)},
+		models.Message{SenderID: "alice", Content: "还没写完的草稿", CreatedAt: day2.Add(2 * time.Minute), MessageType: models.MessageTypeDraft},
+	)
+
+	var trend SentimentTrend
+	decode(t, s.do(t, http.MethodGet, "/api/chat/conv-trend/sentiment-trend", nil), http.StatusOK, &trend)
+	if trend.Segment != TrendSegmentDay || len(trend.Points) != 2 || trend.Senders != nil {
+		t.Fatalf("按天分段应有两段且不拆分发送者: %+v", trend)
+	}
+	if p := trend.Points[0]; p.Label != "2024-05-01" || p.MessageCount != 2 || p.Emotion != sentiment.Angry || p.Polarity >= 0 {
+		t.Errorf("第一天应为愤怒、极性为负: %+v", p)
+	}
+	if p := trend.Points[1]; p.Label != "2024-05-02" || p.MessageCount != 2 || p.Emotion != sentiment.Happy || p.Polarity <= 0 {
+		t.Errorf("第二天应为开心、极性为正（不含草稿）: %+v", p)
+	}
+
+	decode(t, s.do(t, http.MethodGet, "/api/chat/conv-trend/sentiment-trend?segment=count&size=3&by_sender=true", nil), http.StatusOK, &trend)
+	if len(trend.Points) != 2 || trend.Points[0].Label != "1-3" || trend.Points[1].Label != "4-4" {
+		t.Fatalf("按消息数分段应为 1-3、4-4: %+v", trend.Points)
+	}
+	if len(trend.Senders) != 2 || trend.Senders[0].SenderID != "alice" || trend.Senders[1].SenderID != "bob" {
+		t.Fatalf("按发送者拆分应按首次出现的顺序排列: %+v", trend.Senders)
+	}
+	alice := trend.Senders[0].Points
+	if len(alice) != 1 || alice[0].MessageCount != 2 || alice[0].Angry == 0 || alice[0].Happy == 0 {
+		t.Errorf("alice 的走势应只统计她自己的两条消息: %+v", alice)
+	}
+
+	for _, query := range []string{"segment=week", "segment=count&size=0", "by_sender=maybe"} {
+		decode(t, s.do(t, http.MethodGet, "/api/chat/conv-trend/sentiment-trend?"+query, nil), http.StatusBadRequest, nil)
+	}
+	decode(t, s.do(t, http.MethodGet, "/api/chat/conv-missing/sentiment-trend", nil), http.StatusNotFound, nil)
+}
+
+// 消息没有变化时返回缓存结果，新增消息后重新计算
+func TestSentimentTrendCache(t *testing.T) {
+	s := newTestServer(t)
+	conversation := testutil.CreateConversation(t, s.db, "conv-trend",
+		models.Message{SenderID: "alice", Content: "好开心"},
+	)
+
+	first, err := s.handler.sentimentTrend(&conversation, TrendSegmentCount, 10, false)
+	if err != nil {
+		t.Fatalf("计算情绪走势失败: %v", err)
+	}
+	second, err := s.handler.sentimentTrend(&conversation, TrendSegmentCount, 10, false)
+	if err != nil {
+		t.Fatalf("计算情绪走势失败: %v", err)
+	}
+	if first != second {
+		t.Error("消息未变化时应返回缓存结果")
+	}
+
+	if err := s.db.Create(&models.Message{ConversationID: conversation.ID, SenderID: "bob", Content: "好难过", MessageType: "text", Sequence: 2}).Error; err != nil {
+		t.Fatalf("保存消息失败: %v", err)
+	}
+	third, err := s.handler.sentimentTrend(&conversation, TrendSegmentCount, 10, false)
+	if err != nil {
+		t.Fatalf("计算情绪走势失败: %v", err)
+	}
+	if third == first || len(third.Points) != 1 || third.Points[0].MessageCount != 2 {
+		t.Errorf("新增消息后应重新计算: %+v", third.Points)
+	}
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"ChatRecommend/internal/autocomplete"
+	"ChatRecommend/internal/models"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Transport 补全请求的来源
+type Transport string
+
+// 补全请求来源
+const (
+	TransportHTTP      Transport = "http"
+	TransportWebSocket Transport = "websocket"
+)
+
+type transportKey struct{}
+
+// WithTransport 在请求上下文中标记补全请求的来源
+func WithTransport(ctx context.Context, transport Transport) context.Context {
+	return context.WithValue(ctx, transportKey{}, transport)
+}
+
+// transportFrom 读取请求来源，未标记时按HTTP处理
+func transportFrom(ctx context.Context) Transport {
+	if transport, ok := ctx.Value(transportKey{}).(Transport); ok {
+		return transport
+	}
+	return TransportHTTP
+}
+
+// ChatService 补全服务层：HTTP 和 WebSocket 共用的补全入口。
+// 请求校验、埋点和补全历史记录在这里统一完成，限流（每日配额）、缓存和去抖由补全引擎负责；之后的鉴权、限流等也应加在这里
+type ChatService struct {
+	engine *autocomplete.Engine
+	stats  *suggestStats
+	db     *gorm.DB
+	// 每个对话保留的补全历史条数，0表示不记录
+	logLimit int
+}
+
+// NewChatService 创建补全服务
+func NewChatService(engine *autocomplete.Engine, db *gorm.DB) *ChatService {
+	return &ChatService{
+		engine: engine,
+		stats:  newSuggestStats(),
+		db:     db,
+	}
+}
+
+// Suggest 生成补全建议。WebSocket 请求经过去抖，连续输入只对最后一次生成；
+// ctx 结束（客户端断开）时立即返回 ctx 的错误，已开始的生成继续完成并写入缓存
+func (s *ChatService) Suggest(ctx context.Context, req *models.AutocompleteRequest) (*models.AutocompleteResponse, error) {
+	transport := transportFrom(ctx)
+	start := time.Now()
+
+	resp, err := s.suggest(ctx, transport, req)
+	s.stats.record(transport, err, time.Since(start))
+	if err != nil {
+		return nil, err
+	}
+	s.recordCompletion(req, resp)
+
+	logrus.WithFields(logrus.Fields{
+		"transport":         transport,
+		"conversation_id":   req.ConversationID,
+		"suggestions_count": len(resp.Suggestions),
+	}).Debug("补全完成")
+	return resp, nil
+}
+
+// suggest 校验请求并调用补全引擎
+func (s *ChatService) suggest(ctx context.Context, transport Transport, req *models.AutocompleteRequest) (*models.AutocompleteResponse, error) {
+	if req == nil {
+		return nil, fmt.Errorf("%w: 补全请求不能为空", autocomplete.ErrInvalidRequest)
+	}
+	if req.ConversationID == "" || req.SenderID == "" {
+		return nil, fmt.Errorf("%w: conversation_id和sender_id不能为空", autocomplete.ErrInvalidRequest)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	// 对话关闭了补全时不调用补全引擎（不去抖、不调用大模型）
+	if s.engine.AutocompleteDisabled(req.ConversationID) {
+		return nil, ErrAutocompleteDisabled
+	}
+
+	type result struct {
+		resp *models.AutocompleteResponse
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		var r result
+		if transport == TransportWebSocket {
+			r.resp, r.err = s.engine.GetSuggestionsWithDebounce(req)
+		} else {
+			r.resp, r.err = s.engine.GetSuggestions(req)
+		}
+		done <- r
+	}()
+
+	select {
+	case r := <-done:
+		return r.resp, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// suggestStats 补全请求埋点：按来源和结果（ok 或错误码）计数，按来源累计耗时
+type suggestStats struct {
+	mu       sync.Mutex
+	requests map[suggestStatsKey]int64
+	duration map[Transport]time.Duration
+	count    map[Transport]int64
+}
+
+type suggestStatsKey struct {
+	transport Transport
+	result    string
+}
+
+// suggestCounter 一个来源和结果下的请求数
+type suggestCounter struct {
+	Transport Transport
+	Result    string
+	Count     int64
+}
+
+// suggestLatency 一个来源下的累计耗时
+type suggestLatency struct {
+	Transport Transport
+	Seconds   float64
+	Count     int64
+}
+
+func newSuggestStats() *suggestStats {
+	return &suggestStats{
+		requests: make(map[suggestStatsKey]int64),
+		duration: make(map[Transport]time.Duration),
+		count:    make(map[Transport]int64),
+	}
+}
+
+// record 记录一次补全请求
+func (s *suggestStats) record(transport Transport, err error, elapsed time.Duration) {
+	result := "ok"
+	switch {
+	case err == nil:
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		result = "canceled"
+	default:
+		result = string(classifyError(err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests[suggestStatsKey{transport: transport, result: result}]++
+	s.duration[transport] += elapsed
+	s.count[transport]++
+}
+
+// snapshot 按来源和结果排序返回当前计数和耗时
+func (s *suggestStats) snapshot() ([]suggestCounter, []suggestLatency) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counters := make([]suggestCounter, 0, len(s.requests))
+	for key, count := range s.requests {
+		counters = append(counters, suggestCounter{Transport: key.transport, Result: key.result, Count: count})
+	}
+	sort.Slice(counters, func(i, j int) bool {
+		if counters[i].Transport != counters[j].Transport {
+			return counters[i].Transport < counters[j].Transport
+		}
+		return counters[i].Result < counters[j].Result
+	})
+
+	latencies := make([]suggestLatency, 0, len(s.count))
+	for transport, count := range s.count {
+		latencies = append(latencies, suggestLatency{Transport: transport, Seconds: s.duration[transport].Seconds(), Count: count})
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i].Transport < latencies[j].Transport })
+	return counters, latencies
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"ChatRecommend/internal/autocomplete"
+	"ChatRecommend/internal/config"
+	ctxmgr "ChatRecommend/internal/context"
+	"ChatRecommend/internal/llm"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/style"
+	"ChatRecommend/internal/summary"
+	"ChatRecommend/internal/testutil"
+	"gorm.io/gorm"
+)
+
+// blockingLLM 补全调用阻塞到 release 关闭的大模型替身
+type blockingLLM struct {
+	*testutil.MockLLM
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingLLM) Complete(ctx string, input string, opts *llm.CompleteOptions) ([]string, error) {
+	close(b.started)
+	<-b.release
+	return b.MockLLM.Complete(ctx, input, opts)
+}
+
+// newTestChatService 在内存数据库上创建使用指定大模型替身的补全服务
+func newTestChatService(t *testing.T, service llm.Service) (*ChatService, *gorm.DB) {
+	t.Helper()
+	db := testutil.NewDB(t)
+	summaryMgr := summary.NewManager(db, &config.SummaryConfig{}, &config.PromptConfig{}, service, nil)
+	styleMgr := style.NewManager(db, &config.StyleConfig{}, nil)
+	contextMgr := ctxmgr.NewManager(db, &config.ContextConfig{RecentMessagesCount: 10, MaxContextTokens: 4000}, &config.PromptConfig{}, summaryMgr, styleMgr)
+	engine := autocomplete.NewEngine(db, &config.AutocompleteConfig{SuggestionCount: 3}, contextMgr, service, nil)
+	return NewChatService(engine, db), db
+}
+
+// statsCount 某来源和结果下的请求数
+func statsCount(s *ChatService, transport Transport, result string) int64 {
+	counters, _ := s.stats.snapshot()
+	for _, counter := range counters {
+		if counter.Transport == transport && counter.Result == result {
+			return counter.Count
+		}
+	}
+	return 0
+}
+
+// 请求按上下文中标记的来源计入埋点，未标记时按 HTTP 处理
+func TestSuggestTransportTagging(t *testing.T) {
+	s := newTestServer(t)
+	s.saveMessage(t, "conv_1", "bob", "晚上吃什么")
+	chat := s.handler.chat
+	req := func(input string) *models.AutocompleteRequest {
+		return &models.AutocompleteRequest{ConversationID: "conv_1", SenderID: "alice", Input: input}
+	}
+
+	if _, err := chat.Suggest(WithTransport(context.Background(), TransportWebSocket), req("火锅")); err != nil {
+		t.Fatalf("WebSocket 补全失败: %v", err)
+	}
+	if _, err := chat.Suggest(context.Background(), req("烧烤")); err != nil {
+		t.Fatalf("补全失败: %v", err)
+	}
+	if _, err := chat.Suggest(WithTransport(context.Background(), TransportWebSocket), &models.AutocompleteRequest{}); err == nil {
+		t.Fatal("缺少 conversation_id 应返回错误")
+	}
+
+	if n := statsCount(chat, TransportWebSocket, "ok"); n != 1 {
+		t.Fatalf("websocket/ok 计数为 %d", n)
+	}
+	if n := statsCount(chat, TransportHTTP, "ok"); n != 1 {
+		t.Fatalf("未标记来源的请求应计入 http，http/ok 计数为 %d", n)
+	}
+	if n := statsCount(chat, TransportWebSocket, string(ErrCodeInvalidRequest)); n != 1 {
+		t.Fatalf("websocket/INVALID_REQUEST 计数为 %d", n)
+	}
+}
+
+// 上下文已结束时不调用大模型；生成过程中上下文结束时立即返回上下文的错误
+func TestSuggestContextCancellation(t *testing.T) {
+	mock := &blockingLLM{
+		MockLLM: &testutil.MockLLM{Suggestions: []string{"好的"}},
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	chat, db := newTestChatService(t, mock)
+	testutil.CreateConversation(t, db, "conv_1", models.Message{SenderID: "bob", Content: "在吗"})
+	req := &models.AutocompleteRequest{ConversationID: "conv_1", SenderID: "alice", Input: "在的"}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := chat.Suggest(cancelled, req); !errors.Is(err, context.Canceled) {
+		t.Fatalf("已取消的上下文返回 %v", err)
+	}
+	if mock.Calls() != 0 {
+		t.Fatal("上下文已结束时不应调用大模型")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() {
+		_, err := chat.Suggest(ctx, req)
+		result <- err
+	}()
+	<-mock.started
+	cancel()
+	select {
+	case err := <-result:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("生成中取消返回 %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("取消后应立即返回")
+	}
+	close(mock.release)
+	if n := statsCount(chat, TransportHTTP, "canceled"); n != 2 {
+		t.Fatalf("http/canceled 计数为 %d，期望 2", n)
+	}
+}
+
+// 各类错误映射到对应的错误码和是否可重试
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		err       error
+		code      ErrorCode
+		retriable bool
+	}{
+		{fmt.Errorf("%w: 今日已用完", autocomplete.ErrQuotaExceeded), ErrCodeRateLimited, false},
+		{fmt.Errorf("%w（30秒）", llm.ErrTimeout), ErrCodeLLMTimeout, true},
+		{autocomplete.ErrTimeout, ErrCodeLLMTimeout, true},
+		{fmt.Errorf("%w: 502", llm.ErrUpstream), ErrCodeLLMError, true},
+		{fmt.Errorf("查询对话失败: %w", gorm.ErrRecordNotFound), ErrCodeNotFound, false},
+		{fmt.Errorf("%w: input过长", autocomplete.ErrInvalidRequest), ErrCodeInvalidRequest, false},
+		{ErrUnauthorized, ErrCodeUnauthorized, false},
+		{ErrConversationReadOnly, ErrCodeForbidden, false},
+		{fmt.Errorf("%w: restore_snapshot", ErrPermissionDenied), ErrCodeForbidden, false},
+		{ErrDuplicateMessage, ErrCodeDuplicate, false},
+		{ErrAutocompleteDisabled, ErrCodeAutocompleteDisabled, false},
+		{errors.New("磁盘已满"), ErrCodeInternal, false},
+	}
+	for _, tt := range tests {
+		body := newErrorBody(classifyError(tt.err), tt.err.Error())
+		if body.Code != tt.code || body.Retriable != tt.retriable {
+			t.Errorf("%v 映射为 %s（retriable=%v），期望 %s（retriable=%v）", tt.err, body.Code, body.Retriable, tt.code, tt.retriable)
+		}
+	}
+}
+
+// 超出每日配额的补全返回 ErrQuotaExceeded，按 RATE_LIMITED 计数
+func TestSuggestQuotaExceeded(t *testing.T) {
+	s := newTestServer(t)
+	s.saveMessage(t, "conv_1", "bob", "晚上吃什么")
+	chat := s.handler.chat
+	chat.engine.SetDailyQuota(1, time.UTC)
+
+	if _, err := chat.Suggest(context.Background(), &models.AutocompleteRequest{ConversationID: "conv_1", SenderID: "alice", Input: "火锅"}); err != nil {
+		t.Fatalf("配额内的补全失败: %v", err)
+	}
+	_, err := chat.Suggest(context.Background(), &models.AutocompleteRequest{ConversationID: "conv_1", SenderID: "alice", Input: "烧烤"})
+	if !errors.Is(err, autocomplete.ErrQuotaExceeded) {
+		t.Fatalf("超出配额返回 %v", err)
+	}
+	if n := statsCount(chat, TransportHTTP, string(ErrCodeRateLimited)); n != 1 {
+		t.Fatalf("http/RATE_LIMITED 计数为 %d", n)
+	}
+}
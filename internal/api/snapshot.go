@@ -0,0 +1,208 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"ChatRecommend/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// CreateSnapshotRequest 创建快照请求
+type CreateSnapshotRequest struct {
+	Label string `json:"label"`
+}
+
+// RestoreSnapshotRequest 从快照恢复请求
+type RestoreSnapshotRequest struct {
+	SnapshotID uint `json:"snapshot_id" binding:"required"`
+}
+
+// CreateSnapshot 为对话打快照（保存当前消息集合、参与者和设置）
+func (h *Handler) CreateSnapshot(c *gin.Context) {
+	var req CreateSnapshotRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var conversation models.Conversation
+	err := h.db.Where("conversation_id = ?", c.Param("id")).First(&conversation).Error
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "对话不存在"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询对话失败"})
+		return
+	}
+
+	snapshot, err := createSnapshot(h.db, &conversation, req.Label)
+	if err != nil {
+		logrus.WithError(err).Error("创建快照失败")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, snapshot)
+}
+
+// ListSnapshots 列出对话的快照（新的在前）
+func (h *Handler) ListSnapshots(c *gin.Context) {
+	var snapshots []models.ConversationSnapshot
+	if err := h.db.Select("id", "created_at", "conversation_id", "label", "message_count").
+		Where("conversation_id = ?", c.Param("id")).
+		Order("id DESC").
+		Find(&snapshots).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询快照失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"conversation_id": c.Param("id"),
+		"snapshots":       snapshots,
+	})
+}
+
+// RestoreSnapshot 把对话整体回退到快照时的状态：替换全部消息、参与者和设置，对话已被删除时重新创建。
+// 完成后异步重算摘要和风格
+func (h *Handler) RestoreSnapshot(c *gin.Context) {
+	var req RestoreSnapshotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	conversationKey := c.Param("id")
+	var conversation models.Conversation
+	var restored int
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var snapshot models.ConversationSnapshot
+		if err := tx.Where("id = ? AND conversation_id = ?", req.SnapshotID, conversationKey).First(&snapshot).Error; err != nil {
+			return fmt.Errorf("查询快照失败: %w", err)
+		}
+
+		var messages []models.SnapshotMessage
+		if err := json.Unmarshal([]byte(snapshot.Data), &messages); err != nil {
+			return fmt.Errorf("解析快照数据失败: %w", err)
+		}
+
+		err := tx.Where("conversation_id = ?", conversationKey).First(&conversation).Error
+		if err == gorm.ErrRecordNotFound {
+			conversation = models.Conversation{ConversationID: conversationKey}
+			if err := tx.Create(&conversation).Error; err != nil {
+				return fmt.Errorf("重建对话失败: %w", err)
+			}
+		} else if err != nil {
+			return fmt.Errorf("查询对话失败: %w", err)
+		}
+
+		// 清除当前消息和派生数据（摘要、风格之后按恢复的消息重算；恢复的消息ID会变化，编辑历史、提醒和实体索引一并清除）
+		for _, model := range []interface{}{&models.Message{}, &models.Summary{}, &models.Style{}, &models.MessageEdit{}, &models.Alert{}, &models.MessageEntity{}} {
+			if err := tx.Unscoped().Where("conversation_id = ?", conversation.ID).Delete(model).Error; err != nil {
+				return fmt.Errorf("清除对话数据失败: %w", err)
+			}
+		}
+
+		var lastMessageAt = conversation.LastMessageAt
+		for _, msg := range messages {
+			message := models.Message{
+				ConversationID: conversation.ID,
+				SenderID:       msg.SenderID,
+				Content:        msg.Content,
+				MessageType:    msg.MessageType,
+				Sequence:       msg.Sequence,
+				Pinned:         msg.PinnedAt != nil,
+				PinnedAt:       msg.PinnedAt,
+				ContentFormat:  msg.ContentFormat,
+			}
+			message.CreatedAt = msg.CreatedAt
+			if err := tx.Create(&message).Error; err != nil {
+				return fmt.Errorf("恢复消息失败: %w", err)
+			}
+			if err := indexEntities(tx, []models.Message{message}); err != nil {
+				return err
+			}
+			if msg.CreatedAt.After(lastMessageAt) {
+				lastMessageAt = msg.CreatedAt
+			}
+		}
+		restored = len(messages)
+
+		if err := rebuildStats(tx, conversation.ID); err != nil {
+			return err
+		}
+
+		conversation.Participants = snapshot.Participants
+		conversation.Settings = snapshot.Settings
+		conversation.LastMessageAt = lastMessageAt
+		return tx.Model(&conversation).Updates(map[string]interface{}{
+			"participants":    conversation.Participants,
+			"settings":        conversation.Settings,
+			"last_message_at": conversation.LastMessageAt,
+		}).Error
+	})
+	if err != nil {
+		logrus.WithError(err).Error("从快照恢复失败")
+		if isNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.autocomplete.InvalidateCache(conversation.ConversationID)
+	go h.recomputeSummaryAndStyle(conversation.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"conversation_id":   conversation.ConversationID,
+		"snapshot_id":       req.SnapshotID,
+		"restored_messages": restored,
+		"status":            "success",
+	})
+}
+
+// createSnapshot 保存对话当前的消息集合、参与者和设置
+func createSnapshot(tx *gorm.DB, conversation *models.Conversation, label string) (*models.ConversationSnapshot, error) {
+	var messages []models.Message
+	if err := tx.Where("conversation_id = ?", conversation.ID).
+		Order("sequence ASC, created_at ASC").
+		Find(&messages).Error; err != nil {
+		return nil, fmt.Errorf("查询消息失败: %w", err)
+	}
+
+	items := make([]models.SnapshotMessage, 0, len(messages))
+	for _, msg := range messages {
+		items = append(items, models.SnapshotMessage{
+			SenderID:      msg.SenderID,
+			Content:       msg.Content,
+			MessageType:   msg.MessageType,
+			Sequence:      msg.Sequence,
+			CreatedAt:     msg.CreatedAt,
+			PinnedAt:      msg.PinnedAt,
+			ContentFormat: msg.ContentFormat,
+		})
+	}
+
+	data, err := json.Marshal(items)
+	if err != nil {
+		return nil, fmt.Errorf("序列化快照失败: %w", err)
+	}
+
+	snapshot := &models.ConversationSnapshot{
+		ConversationID: conversation.ConversationID,
+		Label:          label,
+		Participants:   conversation.Participants,
+		Settings:       conversation.Settings,
+		MessageCount:   len(items),
+		Data:           string(data),
+	}
+	if err := tx.Create(snapshot).Error; err != nil {
+		return nil, fmt.Errorf("保存快照失败: %w", err)
+	}
+
+	return snapshot, nil
+}
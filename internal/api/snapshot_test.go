@@ -0,0 +1,59 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ChatRecommend/internal/models"
+)
+
+// 批量导入前自动打快照，从快照恢复后消息和统计回到导入前的状态
+func TestImportSnapshotRestore(t *testing.T) {
+	s := newTestServer(t)
+	s.saveMessage(t, "conv-import", "alice", "导入前的消息")
+	s.saveMessage(t, "conv-import", "bob", "收到")
+
+	export := "2026-10-01 09:00:00 carol\n导入的第一条\n2026-10-01 09:01:00 alice\n导入的第二条\n"
+	req := httptest.NewRequest(http.MethodPost, "/api/chat/import/wechat?conversation_id=conv-import", strings.NewReader(export))
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	decode(t, w, http.StatusOK, nil)
+
+	var listed struct {
+		Snapshots []models.ConversationSnapshot `json:"snapshots"`
+	}
+	decode(t, s.do(t, http.MethodGet, "/api/conversation/conv-import/snapshots", nil), http.StatusOK, &listed)
+	if len(listed.Snapshots) != 1 || listed.Snapshots[0].MessageCount != 2 {
+		t.Fatalf("导入前应自动创建包含2条消息的快照，实际 %+v", listed.Snapshots)
+	}
+
+	var stats statsResponse
+	decode(t, s.do(t, http.MethodGet, "/api/conversation/conv-import/stats", nil), http.StatusOK, &stats)
+	if stats.MessageCount != 4 {
+		t.Fatalf("导入后消息数为 %d，期望 4", stats.MessageCount)
+	}
+
+	decode(t, s.do(t, http.MethodPost, "/api/conversation/conv-import/restore",
+		map[string]uint{"snapshot_id": listed.Snapshots[0].ID}, UserIDHeader, "alice"), http.StatusOK, nil)
+
+	var conversation models.Conversation
+	s.db.Where("conversation_id = ?", "conv-import").First(&conversation)
+	var messages []models.Message
+	s.db.Where("conversation_id = ?", conversation.ID).Order("sequence").Find(&messages)
+	if len(messages) != 2 || messages[0].Content != "导入前的消息" || messages[1].Content != "收到" {
+		t.Fatalf("恢复后的消息为 %+v", messages)
+	}
+
+	stats = statsResponse{}
+	decode(t, s.do(t, http.MethodGet, "/api/conversation/conv-import/stats", nil), http.StatusOK, &stats)
+	if stats.MessageCount != 2 || len(stats.Senders) != 2 {
+		t.Fatalf("恢复后统计为 %+v", stats)
+	}
+	for _, sender := range stats.Senders {
+		if sender.MessageCount != 1 {
+			t.Fatalf("恢复后发送者统计为 %+v", stats.Senders)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"ChatRecommend/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetConversationStats 获取对话统计（读取增量维护的统计表）。对话还没有统计行（如统计表引入前的老对话）时先按消息重建
+func (h *Handler) GetConversationStats(c *gin.Context) {
+	var conversation models.Conversation
+	err := h.db.Where("conversation_id = ?", c.Param("id")).First(&conversation).Error
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "对话不存在"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询对话失败"})
+		return
+	}
+
+	var stat models.ConversationStat
+	if err := h.db.Where("conversation_id = ?", conversation.ID).Limit(1).Find(&stat).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询统计失败"})
+		return
+	}
+	if stat.ID == 0 {
+		err := h.db.Transaction(func(tx *gorm.DB) error {
+			if err := rebuildStats(tx, conversation.ID); err != nil {
+				return err
+			}
+			return tx.Where("conversation_id = ?", conversation.ID).Limit(1).Find(&stat).Error
+		})
+		if err != nil {
+			logrus.WithError(err).WithField("conversation_id", conversation.ConversationID).Error("重建对话统计失败")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询统计失败"})
+			return
+		}
+	}
+
+	var senders []models.ConversationSenderStat
+	if err := h.db.Where("conversation_id = ?", conversation.ID).
+		Order("message_count DESC").
+		Find(&senders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询统计失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"conversation_id": conversation.ConversationID,
+		"message_count":   stat.MessageCount,
+		"last_active_at":  stat.LastActiveAt,
+		"senders":         senders,
+	})
+}
+
+// incrementStats 新消息保存后增量更新统计，使用 count = count + 1 保证并发更新的原子性
+func incrementStats(tx *gorm.DB, conversationID uint, senderID string, at time.Time) error {
+	err := tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "conversation_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"message_count":  gorm.Expr("message_count + 1"),
+			"last_active_at": gorm.Expr("MAX(last_active_at, excluded.last_active_at)"),
+			"updated_at":     at,
+		}),
+	}).Create(&models.ConversationStat{
+		ConversationID: conversationID,
+		MessageCount:   1,
+		LastActiveAt:   at,
+	}).Error
+	if err != nil {
+		return fmt.Errorf("更新对话统计失败: %w", err)
+	}
+
+	err = tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "conversation_id"}, {Name: "sender_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"message_count":  gorm.Expr("message_count + 1"),
+			"last_active_at": gorm.Expr("MAX(last_active_at, excluded.last_active_at)"),
+			"updated_at":     at,
+		}),
+	}).Create(&models.ConversationSenderStat{
+		ConversationID: conversationID,
+		SenderID:       senderID,
+		MessageCount:   1,
+		LastActiveAt:   at,
+	}).Error
+	if err != nil {
+		return fmt.Errorf("更新发送者统计失败: %w", err)
+	}
+	return nil
+}
+
+// rebuildStats 按当前消息重建对话统计，用于合并、恢复、删除等批量操作之后
+func rebuildStats(tx *gorm.DB, conversationID uint) error {
+	if err := deleteStats(tx, conversationID); err != nil {
+		return err
+	}
+
+	type senderRow struct {
+		SenderID     string
+		MessageCount int64
+		LastActiveAt string
+	}
+	var rows []senderRow
+	if err := tx.Model(&models.Message{}).
+		Select("sender_id, COUNT(*) AS message_count, MAX(created_at) AS last_active_at").
+		Where("conversation_id = ?", conversationID).
+		Scopes(models.ExcludeDrafts).
+		Group("sender_id").
+		Scan(&rows).Error; err != nil {
+		return fmt.Errorf("统计消息失败: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	stat := models.ConversationStat{ConversationID: conversationID}
+	senders := make([]models.ConversationSenderStat, 0, len(rows))
+	for _, row := range rows {
+		lastActive := parseSQLiteTime(row.LastActiveAt)
+		stat.MessageCount += row.MessageCount
+		if lastActive.After(stat.LastActiveAt) {
+			stat.LastActiveAt = lastActive
+		}
+		senders = append(senders, models.ConversationSenderStat{
+			ConversationID: conversationID,
+			SenderID:       row.SenderID,
+			MessageCount:   row.MessageCount,
+			LastActiveAt:   lastActive,
+		})
+	}
+
+	if err := tx.Create(&stat).Error; err != nil {
+		return fmt.Errorf("保存对话统计失败: %w", err)
+	}
+	if err := tx.Create(&senders).Error; err != nil {
+		return fmt.Errorf("保存发送者统计失败: %w", err)
+	}
+	return nil
+}
+
+// deleteStats 删除对话统计
+func deleteStats(tx *gorm.DB, conversationID uint) error {
+	for _, model := range []interface{}{&models.ConversationStat{}, &models.ConversationSenderStat{}} {
+		if err := tx.Where("conversation_id = ?", conversationID).Delete(model).Error; err != nil {
+			return fmt.Errorf("删除对话统计失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// parseSQLiteTime 解析聚合查询返回的时间字符串（SQLite 的 MAX(created_at) 不带类型信息）
+func parseSQLiteTime(value string) time.Time {
+	for _, layout := range []string{"2006-01-02 15:04:05.999999999-07:00", time.RFC3339Nano, "2006-01-02 15:04:05"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+type statsResponse struct {
+	MessageCount int64 `json:"message_count"`
+	Senders      []struct {
+		SenderID     string `json:"sender_id"`
+		MessageCount int64  `json:"message_count"`
+	} `json:"senders"`
+}
+
+func TestConversationStatsIncremental(t *testing.T) {
+	s := newTestServer(t)
+	s.saveMessage(t, "conv-stats", "alice", "你好")
+	s.saveMessage(t, "conv-stats", "bob", "你好呀")
+	s.saveMessage(t, "conv-stats", "alice", "明天有空吗")
+
+	var resp statsResponse
+	decode(t, s.do(t, http.MethodGet, "/api/conversation/conv-stats/stats", nil), http.StatusOK, &resp)
+	if resp.MessageCount != 3 || len(resp.Senders) != 2 {
+		t.Fatalf("统计为 %+v", resp)
+	}
+	if resp.Senders[0].SenderID != "alice" || resp.Senders[0].MessageCount != 2 {
+		t.Fatalf("发送者统计为 %+v", resp.Senders)
+	}
+}
+
+// 统计表引入前写入的对话没有统计行，查询时按消息重建
+func TestConversationStatsRebuildsMissingRow(t *testing.T) {
+	s := newTestServer(t)
+	testutil.CreateConversation(t, s.db, "conv-legacy",
+		models.Message{SenderID: "alice", Content: "老消息1"},
+		models.Message{SenderID: "bob", Content: "老消息2"},
+		models.Message{SenderID: "bob", Content: "草稿", MessageType: models.MessageTypeDraft},
+	)
+
+	var resp statsResponse
+	decode(t, s.do(t, http.MethodGet, "/api/conversation/conv-legacy/stats", nil), http.StatusOK, &resp)
+	if resp.MessageCount != 2 || len(resp.Senders) != 2 {
+		t.Fatalf("统计为 %+v", resp)
+	}
+
+	var count int64
+	s.db.Model(&models.ConversationStat{}).Count(&count)
+	if count != 1 {
+		t.Fatalf("重建后统计行数为 %d", count)
+	}
+}
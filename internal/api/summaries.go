@@ -0,0 +1,124 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"ChatRecommend/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// 批量获取摘要的对话数上限，以及预览长度的默认值和上限
+const (
+	maxBatchSummaries          = 200
+	defaultSummaryPreviewChars = 100
+	maxSummaryPreviewChars     = 2000
+)
+
+// BatchSummariesRequest 批量获取摘要预览请求
+type BatchSummariesRequest struct {
+	ConversationIDs []string `json:"conversation_ids" binding:"required"`
+	// 预览截断的字符数，0表示使用默认值100
+	MaxChars int `json:"max_chars"`
+}
+
+// SummaryPreview 单个对话的摘要预览
+type SummaryPreview struct {
+	ConversationID string `json:"conversation_id"`
+	// 截断后的摘要提示词，尚未生成摘要时为空
+	Prompt    string     `json:"prompt"`
+	Truncated bool       `json:"truncated"`
+	Version   int        `json:"version,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// summaryPreviewRow 批量查询的结果行，没有摘要的对话摘要字段为空
+type summaryPreviewRow struct {
+	ConversationID string
+	Prompt         *string
+	Version        *int
+	LastUpdatedAt  *time.Time
+}
+
+// BatchSummaries 批量获取多个对话的摘要预览（会话列表页使用）。一次联表查询取出所有对话及其摘要，
+// 按请求顺序返回（重复的ID只返回一次），不存在的对话放在 missing 中；只读取已有摘要，不触发生成
+func (h *Handler) BatchSummaries(c *gin.Context) {
+	var req BatchSummariesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.ConversationIDs) > maxBatchSummaries {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("conversation_ids 最多%d个", maxBatchSummaries)})
+		return
+	}
+	if req.MaxChars < 0 || req.MaxChars > maxSummaryPreviewChars {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("max_chars 必须在0到%d之间", maxSummaryPreviewChars)})
+		return
+	}
+	maxChars := req.MaxChars
+	if maxChars == 0 {
+		maxChars = defaultSummaryPreviewChars
+	}
+
+	ids := make([]string, 0, len(req.ConversationIDs))
+	seen := make(map[string]bool, len(req.ConversationIDs))
+	for _, id := range req.ConversationIDs {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	var rows []summaryPreviewRow
+	if len(ids) > 0 {
+		if err := h.db.Model(&models.Conversation{}).
+			Select("conversations.conversation_id, summaries.prompt, summaries.version, summaries.last_updated_at").
+			Joins("LEFT JOIN summaries ON summaries.conversation_id = conversations.id AND summaries.deleted_at IS NULL").
+			Where("conversations.conversation_id IN ?", ids).
+			Scan(&rows).Error; err != nil {
+			logrus.WithError(err).Error("批量查询摘要失败")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询摘要失败"})
+			return
+		}
+	}
+
+	found := make(map[string]summaryPreviewRow, len(rows))
+	for _, row := range rows {
+		found[row.ConversationID] = row
+	}
+
+	summaries := make([]SummaryPreview, 0, len(rows))
+	missing := make([]string, 0)
+	for _, id := range ids {
+		row, ok := found[id]
+		if !ok {
+			missing = append(missing, id)
+			continue
+		}
+		preview := SummaryPreview{ConversationID: id, UpdatedAt: row.LastUpdatedAt}
+		if row.Prompt != nil {
+			preview.Prompt, preview.Truncated = truncatePreview(*row.Prompt, maxChars)
+		}
+		if row.Version != nil {
+			preview.Version = *row.Version
+		}
+		summaries = append(summaries, preview)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"summaries": summaries,
+		"missing":   missing,
+	})
+}
+
+// truncatePreview 截取前 maxChars 个字符，被截断时末尾加省略号
+func truncatePreview(text string, maxChars int) (string, bool) {
+	runes := []rune(text)
+	if len(runes) <= maxChars {
+		return text, false
+	}
+	return string(runes[:maxChars]) + "…", true
+}
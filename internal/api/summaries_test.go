@@ -0,0 +1,70 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+	"github.com/gin-gonic/gin"
+)
+
+// 按请求顺序返回摘要预览：超长摘要被截断，没有摘要的对话返回空预览，不存在的对话放在 missing 中，重复的ID只返回一次
+func TestBatchSummaries(t *testing.T) {
+	s := newTestServer(t)
+	updatedAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	long := testutil.CreateConversation(t, s.db, "conv-long")
+	short := testutil.CreateConversation(t, s.db, "conv-short")
+	testutil.CreateConversation(t, s.db, "conv-empty")
+	for _, summary := range []models.Summary{
+		{ConversationID: long.ID, Prompt: strings.Repeat("约", 15), KeyInfo: "[]", Version: 3, LastUpdatedAt: updatedAt},
+		{ConversationID: short.ID, Prompt: "两人在约饭", KeyInfo: "[]", LastUpdatedAt: updatedAt},
+	} {
+		if err := s.db.Create(&summary).Error; err != nil {
+			t.Fatalf("保存摘要失败: %v", err)
+		}
+	}
+
+	var resp struct {
+		Summaries []SummaryPreview `json:"summaries"`
+		Missing   []string         `json:"missing"`
+	}
+	decode(t, s.do(t, http.MethodPost, "/api/summaries/batch", gin.H{
+		"conversation_ids": []string{"conv-short", "conv-missing", "conv-long", "conv-empty", "conv-short"},
+		"max_chars":        10,
+	}), http.StatusOK, &resp)
+
+	if len(resp.Summaries) != 3 || len(resp.Missing) != 1 || resp.Missing[0] != "conv-missing" {
+		t.Fatalf("响应为 %+v", resp)
+	}
+	if p := resp.Summaries[0]; p.ConversationID != "conv-short" || p.Prompt != "两人在约饭" || p.Truncated || p.Version != 1 {
+		t.Errorf("短摘要不应被截断: %+v", p)
+	}
+	if p := resp.Summaries[1]; p.ConversationID != "conv-long" || p.Prompt != strings.Repeat("约", 10)+"…" || !p.Truncated || p.Version != 3 ||
+		p.UpdatedAt == nil || !p.UpdatedAt.Equal(updatedAt) {
+		t.Errorf("长摘要应截断为10个字符: %+v", p)
+	}
+	if p := resp.Summaries[2]; p.ConversationID != "conv-empty" || p.Prompt != "" || p.Version != 0 || p.UpdatedAt != nil {
+		t.Errorf("没有摘要的对话应返回空预览: %+v", p)
+	}
+}
+
+// 参数校验：缺少ID列表、ID过多或截断长度越界时返回400
+func TestBatchSummariesValidation(t *testing.T) {
+	s := newTestServer(t)
+	tooMany := make([]string, maxBatchSummaries+1)
+	for i := range tooMany {
+		tooMany[i] = "conv"
+	}
+
+	for _, body := range []gin.H{
+		{},
+		{"conversation_ids": tooMany},
+		{"conversation_ids": []string{"conv"}, "max_chars": -1},
+		{"conversation_ids": []string{"conv"}, "max_chars": maxSummaryPreviewChars + 1},
+	} {
+		decode(t, s.do(t, http.MethodPost, "/api/summaries/batch", body), http.StatusBadRequest, nil)
+	}
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 标签归一化去重后保存，按标签筛选只返回打了该标签的对话；备注整体替换
+func TestConversationTagsFilterAndNote(t *testing.T) {
+	s := newTestServer(t)
+	for _, id := range []string{"conv-a", "conv-b", "conv-c"} {
+		s.saveMessage(t, id, "alice", "你好")
+	}
+
+	var tagged struct {
+		Tags string `json:"tags"`
+	}
+	decode(t, s.do(t, http.MethodPut, "/api/conversation/conv-a/tags", gin.H{
+		"tags": []string{" 客户 ", "VIP", "客户", "vip", ""},
+	}, UserIDHeader, "alice"), http.StatusOK, &tagged)
+	if tagged.Tags != `["客户","vip"]` {
+		t.Errorf("归一化后的标签为 %s", tagged.Tags)
+	}
+	decode(t, s.do(t, http.MethodPut, "/api/conversation/conv-b/tags", gin.H{
+		"tags": []string{"朋友"},
+	}, UserIDHeader, "alice"), http.StatusOK, nil)
+
+	assertIDs(t, "标签 客户", s.listConversationIDs(t, "?tag=客户"), "conv-a")
+	assertIDs(t, "标签 VIP", s.listConversationIDs(t, "?tag=VIP"), "conv-a")
+	assertIDs(t, "标签 朋友", s.listConversationIDs(t, "?tag=朋友"), "conv-b")
+	// 只匹配完整标签，不匹配标签中的片段
+	assertIDs(t, "标签 客", s.listConversationIDs(t, "?tag=客"))
+
+	var noted struct {
+		Note string `json:"note"`
+	}
+	decode(t, s.do(t, http.MethodPut, "/api/conversation/conv-c/note", gin.H{"note": "下周回访"}, UserIDHeader, "alice"), http.StatusOK, &noted)
+	if noted.Note != "下周回访" {
+		t.Errorf("备注为 %q", noted.Note)
+	}
+
+	decode(t, s.do(t, http.MethodPut, "/api/conversation/missing/tags", gin.H{"tags": []string{"客户"}}), http.StatusNotFound, nil)
+}
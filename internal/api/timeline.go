@@ -0,0 +1,47 @@
+package api
+
+import (
+	"time"
+
+	"ChatRecommend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// dayLayout 时间线分组的日期格式
+const dayLayout = "2006-01-02"
+
+// DayGroup 时间线中某一天的消息
+type DayGroup struct {
+	Date     string              `json:"date"`
+	Messages []models.MessageDTO `json:"messages"`
+}
+
+// GetTimeline 获取按天分组的消息时间线，等价于 GetHistory 的 group_by=day
+func (h *Handler) GetTimeline(c *gin.Context) {
+	q := c.Request.URL.Query()
+	q.Set("group_by", "day")
+	c.Request.URL.RawQuery = q.Encode()
+	h.GetHistory(c)
+}
+
+// groupMessagesByDay 按消息创建时间在指定时区下的日期分组，分组按首次出现的顺序排列
+// 同一天的消息即使因为 sequence 排序不连续，也会归入同一分组
+func groupMessagesByDay(messages []models.Message, loc *time.Location) []DayGroup {
+	if loc == nil {
+		loc = time.Local
+	}
+
+	groups := make([]DayGroup, 0)
+	index := make(map[string]int)
+	for _, dto := range models.ToMessageDTOsIn(messages, loc) {
+		date := dto.CreatedAt.Format(dayLayout)
+		i, ok := index[date]
+		if !ok {
+			i = len(groups)
+			index[date] = i
+			groups = append(groups, DayGroup{Date: date})
+		}
+		groups[i].Messages = append(groups[i].Messages, dto)
+	}
+	return groups
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"ChatRecommend/internal/models"
+)
+
+// 按指定时区的日期分组：UTC 同一天的消息在东八区可能跨越午夜被分到两天
+func TestGroupMessagesByDayAcrossTimezones(t *testing.T) {
+	at := func(value string) time.Time {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+	messages := []models.Message{
+		{SenderID: "alice", Content: "晚安", CreatedAt: at("2024-01-01T15:30:00Z")},
+		{SenderID: "bob", Content: "还没睡", CreatedAt: at("2024-01-01T16:30:00Z")},
+		{SenderID: "alice", Content: "早", CreatedAt: at("2024-01-02T01:00:00Z")},
+	}
+
+	utc := groupMessagesByDay(messages, time.UTC)
+	if len(utc) != 2 || utc[0].Date != "2024-01-01" || len(utc[0].Messages) != 2 || utc[1].Date != "2024-01-02" {
+		t.Fatalf("UTC 分组为 %+v", utc)
+	}
+
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skipf("缺少时区数据: %v", err)
+	}
+	groups := groupMessagesByDay(messages, shanghai)
+	if len(groups) != 2 || groups[0].Date != "2024-01-01" || len(groups[0].Messages) != 1 {
+		t.Fatalf("东八区分组为 %+v", groups)
+	}
+	if groups[1].Date != "2024-01-02" || len(groups[1].Messages) != 2 || groups[1].Messages[0].Content != "还没睡" {
+		t.Fatalf("跨午夜的消息应分到第二天: %+v", groups[1])
+	}
+	if _, offset := groups[1].Messages[0].CreatedAt.Zone(); offset != 8*3600 {
+		t.Errorf("消息时间应转换到分组时区，偏移为 %d", offset)
+	}
+}
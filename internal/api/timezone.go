@@ -0,0 +1,49 @@
+package api
+
+import (
+	"time"
+
+	"ChatRecommend/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// timezoneHeader 请求方通过该请求头（或 timezone 查询参数）声明自己的时区
+const timezoneHeader = "X-Timezone"
+
+// requestLocation 确定返回时间使用的时区，优先级：请求的 timezone 参数或 X-Timezone 请求头 >
+// 请求方（sender_id 参数）档案中的时区 > 对话设置的时区 > server.timezone。请求显式指定的时区无效时返回错误
+func (h *Handler) requestLocation(c *gin.Context, conversation *models.Conversation) (*time.Location, error) {
+	name := c.Query("timezone")
+	if name == "" {
+		name = c.GetHeader(timezoneHeader)
+	}
+	if name != "" {
+		if err := models.ValidateTimezone(name); err != nil {
+			return nil, err
+		}
+		return models.LoadTimezone(name, h.location), nil
+	}
+
+	if senderID := c.Query("sender_id"); senderID != "" {
+		var profile models.UserProfile
+		if err := h.db.Where("sender_id = ?", senderID).Limit(1).Find(&profile).Error; err != nil {
+			logrus.WithError(err).Warn("查询用户档案失败")
+		} else if profile.ID != 0 {
+			if fields, err := profile.GetFields(); err == nil && fields.Timezone != "" {
+				return models.LoadTimezone(fields.Timezone, h.location), nil
+			}
+		}
+	}
+
+	return conversationLocation(conversation, h.location), nil
+}
+
+// conversationLocation 对话设置的时区，未设置时返回 fallback
+func conversationLocation(conversation *models.Conversation, fallback *time.Location) *time.Location {
+	settings, err := conversation.GetSettings()
+	if err != nil {
+		return fallback
+	}
+	return models.LoadTimezone(settings.Timezone, fallback)
+}
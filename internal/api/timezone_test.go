@@ -0,0 +1,99 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+	"github.com/gin-gonic/gin"
+)
+
+// historyTimes 查询聊天历史，返回使用的时区和消息时间的原始文本
+func (s *testServer) historyTimes(t *testing.T, path string, status int, headers ...string) (string, []string) {
+	t.Helper()
+	var resp struct {
+		Timezone string `json:"timezone"`
+		Messages []struct {
+			CreatedAt string `json:"created_at"`
+		} `json:"messages"`
+	}
+	decode(t, s.do(t, http.MethodGet, path, nil, headers...), status, &resp)
+	times := make([]string, len(resp.Messages))
+	for i, msg := range resp.Messages {
+		times[i] = msg.CreatedAt
+	}
+	return resp.Timezone, times
+}
+
+// 消息时间按请求的时区参数或请求头 > 请求方档案 > 对话设置 > server.timezone 转换为带偏移的 RFC3339；无效时区返回400
+func TestHistoryTimezone(t *testing.T) {
+	s := newTestServer(t)
+	if _, err := time.LoadLocation("Asia/Tokyo"); err != nil {
+		t.Skipf("缺少时区数据: %v", err)
+	}
+	created := time.Date(2026, 10, 17, 2, 0, 0, 0, time.UTC)
+	testutil.CreateConversation(t, s.db, "conv-tz", models.Message{SenderID: "bob", Content: "早", CreatedAt: created})
+
+	cases := []struct {
+		name    string
+		path    string
+		headers []string
+		zone    string
+		time    string
+	}{
+		{"默认", "/api/chat/history/conv-tz", nil, "UTC", "2026-10-17T02:00:00Z"},
+		{"查询参数", "/api/chat/history/conv-tz?timezone=Asia/Tokyo", nil, "Asia/Tokyo", "2026-10-17T11:00:00+09:00"},
+		{"请求头", "/api/chat/history/conv-tz", []string{timezoneHeader, "America/New_York"}, "America/New_York", "2026-10-16T22:00:00-04:00"},
+	}
+	for _, c := range cases {
+		zone, times := s.historyTimes(t, c.path, http.StatusOK, c.headers...)
+		if zone != c.zone || len(times) != 1 || times[0] != c.time {
+			t.Errorf("%s: 时区为 %s，时间为 %v，期望 %s %s", c.name, zone, times, c.zone, c.time)
+		}
+	}
+
+	decode(t, s.do(t, http.MethodPut, "/api/conversation/conv-tz/settings", gin.H{"timezone": "Europe/London"}, UserIDHeader, "bob"), http.StatusOK, nil)
+	if zone, times := s.historyTimes(t, "/api/chat/history/conv-tz", http.StatusOK); zone != "Europe/London" || times[0] != "2026-10-17T03:00:00+01:00" {
+		t.Errorf("应使用对话设置的时区: %s %v", zone, times)
+	}
+	decode(t, s.do(t, http.MethodPut, "/api/user/alice/profile", gin.H{"timezone": "Asia/Tokyo"}), http.StatusOK, nil)
+	if zone, _ := s.historyTimes(t, "/api/chat/history/conv-tz?sender_id=alice", http.StatusOK); zone != "Asia/Tokyo" {
+		t.Errorf("请求方档案的时区应优先于对话设置，实际 %s", zone)
+	}
+	if zone, _ := s.historyTimes(t, "/api/chat/history/conv-tz?sender_id=alice&timezone=UTC", http.StatusOK); zone != "UTC" {
+		t.Errorf("请求显式指定的时区应优先，实际 %s", zone)
+	}
+
+	decode(t, s.do(t, http.MethodGet, "/api/chat/history/conv-tz?timezone=Mars/Olympus", nil), http.StatusBadRequest, nil)
+	decode(t, s.do(t, http.MethodGet, "/api/chat/conv-tz/pinned?timezone=Local", nil), http.StatusBadRequest, nil)
+	decode(t, s.do(t, http.MethodPut, "/api/conversation/conv-tz/settings", gin.H{"timezone": "Mars/Olympus"}, UserIDHeader, "bob"), http.StatusBadRequest, nil)
+	decode(t, s.do(t, http.MethodPut, "/api/user/alice/profile", gin.H{"timezone": "Mars/Olympus"}), http.StatusBadRequest, nil)
+}
+
+// 按天分组时按请求方时区计算日期
+func TestHistoryGroupByDayTimezone(t *testing.T) {
+	s := newTestServer(t)
+	if _, err := time.LoadLocation("Asia/Tokyo"); err != nil {
+		t.Skipf("缺少时区数据: %v", err)
+	}
+	testutil.CreateConversation(t, s.db, "conv-tz-day",
+		models.Message{SenderID: "alice", Content: "晚安", CreatedAt: time.Date(2026, 10, 16, 14, 0, 0, 0, time.UTC)},
+		models.Message{SenderID: "bob", Content: "还没睡", CreatedAt: time.Date(2026, 10, 16, 16, 0, 0, 0, time.UTC)},
+	)
+
+	var resp struct {
+		Timezone string     `json:"timezone"`
+		Days     []DayGroup `json:"days"`
+	}
+	decode(t, s.do(t, http.MethodGet, "/api/chat/history/conv-tz-day?group_by=day", nil), http.StatusOK, &resp)
+	if len(resp.Days) != 1 {
+		t.Fatalf("UTC 下应为同一天: %+v", resp.Days)
+	}
+	resp.Days = nil
+	decode(t, s.do(t, http.MethodGet, "/api/chat/history/conv-tz-day?group_by=day&timezone=Asia/Tokyo", nil), http.StatusOK, &resp)
+	if resp.Timezone != "Asia/Tokyo" || len(resp.Days) != 2 || resp.Days[1].Date != "2026-10-17" {
+		t.Errorf("东九区下应跨越午夜分成两天: %+v", resp)
+	}
+}
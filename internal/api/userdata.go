@@ -0,0 +1,298 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/textutil"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// DeleteUserDataResult 删除用户数据的统计
+type DeleteUserDataResult struct {
+	SenderID             string `json:"sender_id"`
+	MessagesDeleted      int64  `json:"messages_deleted"`
+	StylesDeleted        int64  `json:"styles_deleted"`
+	ReadCursorsDeleted   int64  `json:"read_cursors_deleted"`
+	SummariesReset       int64  `json:"summaries_reset"`
+	SnapshotsDeleted     int64  `json:"snapshots_deleted"`
+	ProfileDeleted       bool   `json:"profile_deleted"`
+	ConversationsUpdated int    `json:"conversations_updated"`
+	ConversationsDeleted int    `json:"conversations_deleted"`
+}
+
+// DeleteUserData 物理删除某个用户在所有对话中的数据（消息、风格画像、已读位置、补全历史、对话快照）和个人档案，并从参与者列表移除。
+// 由该用户消息生成的摘要会被删除，之后按剩余消息重新生成；对话中只剩该用户时整个对话一并删除
+func (h *Handler) DeleteUserData(c *gin.Context) {
+	senderID := c.Param("sender_id")
+	if senderID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sender_id不能为空"})
+		return
+	}
+
+	result := DeleteUserDataResult{SenderID: senderID}
+	var affected []models.Conversation
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		conversations, err := userConversations(tx, senderID)
+		if err != nil {
+			return err
+		}
+
+		for _, conversation := range conversations {
+			// 删除该用户的消息（及编辑历史、实体索引）、风格和已读位置
+			for _, model := range []interface{}{&models.MessageEdit{}, &models.MessageEntity{}} {
+				if err := tx.Where("message_id IN (?)", tx.Model(&models.Message{}).Unscoped().Select("id").
+					Where("conversation_id = ? AND sender_id = ?", conversation.ID, senderID)).
+					Delete(model).Error; err != nil {
+					return fmt.Errorf("删除消息关联数据失败: %w", err)
+				}
+			}
+			res := tx.Unscoped().Where("conversation_id = ? AND sender_id = ?", conversation.ID, senderID).Delete(&models.Message{})
+			if res.Error != nil {
+				return fmt.Errorf("删除消息失败: %w", res.Error)
+			}
+			result.MessagesDeleted += res.RowsAffected
+
+			if err := tx.Where("conversation_id = ? AND sender_id = ?", conversation.ID, senderID).Delete(&models.Alert{}).Error; err != nil {
+				return fmt.Errorf("删除提醒失败: %w", err)
+			}
+			if err := tx.Where("conversation_id = ? AND sender_id = ?", conversation.ID, senderID).Delete(&models.CompletionLog{}).Error; err != nil {
+				return fmt.Errorf("删除补全历史失败: %w", err)
+			}
+
+			res = tx.Unscoped().Where("conversation_id = ? AND user_id = ?", conversation.ID, senderID).Delete(&models.Style{})
+			if res.Error != nil {
+				return fmt.Errorf("删除风格失败: %w", res.Error)
+			}
+			result.StylesDeleted += res.RowsAffected
+
+			res = tx.Where("conversation_id = ? AND user_id = ?", conversation.ID, senderID).Delete(&models.ReadCursor{})
+			if res.Error != nil {
+				return fmt.Errorf("删除已读位置失败: %w", res.Error)
+			}
+			result.ReadCursorsDeleted += res.RowsAffected
+
+			// 摘要可能包含该用户的信息，删除后按剩余消息重新生成
+			res = tx.Unscoped().Where("conversation_id = ?", conversation.ID).Delete(&models.Summary{})
+			if res.Error != nil {
+				return fmt.Errorf("删除摘要失败: %w", res.Error)
+			}
+			result.SummariesReset += res.RowsAffected
+
+			// 快照中保存了该用户的消息，一并删除
+			res = tx.Where("conversation_id = ?", conversation.ConversationID).Delete(&models.ConversationSnapshot{})
+			if res.Error != nil {
+				return fmt.Errorf("删除快照失败: %w", res.Error)
+			}
+			result.SnapshotsDeleted += res.RowsAffected
+
+			participants := removeParticipant(conversation.Participants, senderID)
+			var remaining int64
+			if err := tx.Model(&models.Message{}).Where("conversation_id = ?", conversation.ID).Count(&remaining).Error; err != nil {
+				return fmt.Errorf("统计剩余消息失败: %w", err)
+			}
+
+			// 对话中只剩该用户：整个对话删除
+			if remaining == 0 && participants == "[]" {
+				if err := deleteConversationData(tx, conversation.ID); err != nil {
+					return err
+				}
+				result.ConversationsDeleted++
+			} else {
+				if err := rebuildStats(tx, conversation.ID); err != nil {
+					return err
+				}
+				if err := tx.Unscoped().Model(&conversation).Update("participants", participants).Error; err != nil {
+					return fmt.Errorf("更新参与者失败: %w", err)
+				}
+				result.ConversationsUpdated++
+			}
+			affected = append(affected, conversation)
+		}
+
+		// 已合并删除的对话的快照不再关联现存对话，按内容查找仍包含该用户消息或参与者信息的快照
+		deleted, err := deleteUserSnapshots(tx, senderID)
+		if err != nil {
+			return err
+		}
+		result.SnapshotsDeleted += deleted
+
+		res := tx.Where("sender_id = ?", senderID).Delete(&models.UserProfile{})
+		if res.Error != nil {
+			return fmt.Errorf("删除用户档案失败: %w", res.Error)
+		}
+		result.ProfileDeleted = res.RowsAffected > 0
+
+		res = tx.Where("user_id = ?", senderID).Delete(&models.UserStyle{})
+		if res.Error != nil {
+			return fmt.Errorf("删除用户级风格失败: %w", res.Error)
+		}
+		result.StylesDeleted += res.RowsAffected
+
+		if err := tx.Where("sender_id = ?", senderID).Delete(&models.LLMUsage{}).Error; err != nil {
+			return fmt.Errorf("删除调用计数失败: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("sender_id", senderID).Error("删除用户数据失败")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 清理内存中的缓存
+	for _, conversation := range affected {
+		h.style.InvalidateCache(conversation.ID, senderID)
+		h.autocomplete.InvalidateCache(conversation.ConversationID)
+	}
+	h.style.InvalidateUserCache(senderID)
+
+	logrus.WithFields(logrus.Fields{
+		"sender_id":             senderID,
+		"messages_deleted":      result.MessagesDeleted,
+		"conversations_deleted": result.ConversationsDeleted,
+	}).Info("用户数据已删除")
+
+	c.JSON(http.StatusOK, result)
+}
+
+// userConversations 查询用户发过消息、有风格画像或在参与者列表中的对话，包括已软删除的消息、风格和对话
+func userConversations(tx *gorm.DB, senderID string) ([]models.Conversation, error) {
+	var messageIDs, styleIDs []uint
+	if err := tx.Unscoped().Model(&models.Message{}).Where("sender_id = ?", senderID).Distinct().Pluck("conversation_id", &messageIDs).Error; err != nil {
+		return nil, fmt.Errorf("查询用户对话失败: %w", err)
+	}
+	if err := tx.Unscoped().Model(&models.Style{}).Where("user_id = ?", senderID).Distinct().Pluck("conversation_id", &styleIDs).Error; err != nil {
+		return nil, fmt.Errorf("查询用户对话失败: %w", err)
+	}
+	ids := append(messageIDs, styleIDs...)
+
+	var candidates []models.Conversation
+	query := tx.Unscoped().Where("participants LIKE ? ESCAPE '\\'", participantPattern(senderID))
+	if len(ids) > 0 {
+		query = query.Or("id IN ?", ids)
+	}
+	if err := query.Find(&candidates).Error; err != nil {
+		return nil, fmt.Errorf("查询用户对话失败: %w", err)
+	}
+
+	// LIKE 仍可能匹配到与用户ID相同的角色名（如 "owner"），按参与者列表精确过滤
+	owned := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		owned[id] = true
+	}
+	conversations := make([]models.Conversation, 0, len(candidates))
+	for _, conversation := range candidates {
+		if owned[conversation.ID] || hasParticipant(conversation.Participants, senderID) {
+			conversations = append(conversations, conversation)
+		}
+	}
+	return conversations, nil
+}
+
+// deleteUserSnapshots 删除消息集合或参与者列表中包含该用户的快照，返回删除数量。
+// 快照数据可能加密，无法在 SQL 中按内容过滤，逐批解密后检查
+func deleteUserSnapshots(tx *gorm.DB, senderID string) (int64, error) {
+	var ids []uint
+	var batch []models.ConversationSnapshot
+	err := tx.Model(&models.ConversationSnapshot{}).FindInBatches(&batch, 100, func(batchTx *gorm.DB, _ int) error {
+		for _, snapshot := range batch {
+			if snapshotReferences(&snapshot, senderID) {
+				ids = append(ids, snapshot.ID)
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return 0, fmt.Errorf("查询快照失败: %w", err)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	res := tx.Delete(&models.ConversationSnapshot{}, ids)
+	if res.Error != nil {
+		return 0, fmt.Errorf("删除快照失败: %w", res.Error)
+	}
+	return res.RowsAffected, nil
+}
+
+// snapshotReferences 快照中是否有该用户的消息，或参与者列表中有该用户。数据无法解析时视为包含，宁可多删
+func snapshotReferences(snapshot *models.ConversationSnapshot, senderID string) bool {
+	if hasParticipant(snapshot.Participants, senderID) {
+		return true
+	}
+	var items []models.SnapshotMessage
+	if err := json.Unmarshal([]byte(snapshot.Data), &items); err != nil {
+		return true
+	}
+	for _, item := range items {
+		if item.SenderID == senderID {
+			return true
+		}
+	}
+	return false
+}
+
+// deleteConversationData 物理删除对话及其关联数据
+func deleteConversationData(tx *gorm.DB, conversationID uint) error {
+	for _, model := range []interface{}{&models.Message{}, &models.Summary{}, &models.Style{}, &models.ReadCursor{}, &models.Alert{}, &models.MessageEdit{}, &models.MessageEntity{}, &models.CompletionLog{}} {
+		if err := tx.Unscoped().Where("conversation_id = ?", conversationID).Delete(model).Error; err != nil {
+			return fmt.Errorf("删除对话关联数据失败: %w", err)
+		}
+	}
+	if err := deleteStats(tx, conversationID); err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Delete(&models.Conversation{}, conversationID).Error; err != nil {
+		return fmt.Errorf("删除对话失败: %w", err)
+	}
+	return nil
+}
+
+// removeParticipant 从参与者列表中移除用户（参与者可以是用户ID字符串，或带 id/user_id/sender_id 字段的对象）
+func removeParticipant(participants, senderID string) string {
+	var list []interface{}
+	if participants != "" {
+		json.Unmarshal([]byte(participants), &list)
+	}
+
+	kept := make([]interface{}, 0, len(list))
+	for _, p := range list {
+		if models.ParticipantID(p) == senderID {
+			continue
+		}
+		kept = append(kept, p)
+	}
+
+	data, err := json.Marshal(kept)
+	if err != nil {
+		return "[]"
+	}
+	return string(data)
+}
+
+// participantPattern 在参与者 JSON 中查找用户的 LIKE 模式：按带引号的完整ID匹配（字符串元素和对象的 id 字段都是这种形式），
+// ID 中的 %、_ 按字面匹配（需配合 ESCAPE '\'）
+func participantPattern(senderID string) string {
+	quoted, _ := json.Marshal(senderID)
+	return "%" + textutil.EscapeLike(string(quoted)) + "%"
+}
+
+// hasParticipant 判断用户是否在参与者列表中
+func hasParticipant(participants, senderID string) bool {
+	var list []interface{}
+	if participants != "" {
+		json.Unmarshal([]byte(participants), &list)
+	}
+	for _, p := range list {
+		if models.ParticipantID(p) == senderID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+	"gorm.io/gorm"
+)
+
+// 按参与者查找用户对话时，用户ID中的 %、_ 按字面匹配，且不会匹配到包含该ID的其他用户
+func TestUserConversationsMatchesParticipantExactly(t *testing.T) {
+	s := newTestServer(t)
+	conversations := []models.Conversation{
+		{ConversationID: "conv-underscore", Participants: `["a_c"]`},
+		{ConversationID: "conv-abc", Participants: `["abc"]`},
+		{ConversationID: "conv-prefix", Participants: `[{"id":"a_cd","role":"owner"}]`},
+		{ConversationID: "conv-object", Participants: `[{"id":"a_c","role":"member"}]`},
+	}
+	for i := range conversations {
+		if err := s.db.Create(&conversations[i]).Error; err != nil {
+			t.Fatalf("创建对话失败: %v", err)
+		}
+	}
+
+	var candidates []models.Conversation
+	if err := s.db.Where("participants LIKE ? ESCAPE '\\'", participantPattern("a_c")).
+		Order("id").Find(&candidates).Error; err != nil {
+		t.Fatalf("查询失败: %v", err)
+	}
+	got := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		got = append(got, c.ConversationID)
+	}
+	if len(got) != 2 || got[0] != "conv-underscore" || got[1] != "conv-object" {
+		t.Fatalf("LIKE 候选为 %v，期望 [conv-underscore conv-object]", got)
+	}
+
+	result, err := userConversations(s.db, "%")
+	if err != nil {
+		t.Fatalf("查询用户对话失败: %v", err)
+	}
+	if len(result) != 0 {
+		t.Fatalf("ID 为 %% 的用户不应匹配任何对话，实际 %d 个", len(result))
+	}
+}
+
+// 删除用户数据级联删除消息（含软删除）、风格、补全历史和快照，已合并删除的对话的快照按内容清理
+func TestDeleteUserDataCascade(t *testing.T) {
+	s := newTestServer(t)
+	// 直接写库而不走保存接口：保存接口会异步创建风格记录，和下面手动创建的记录竞争
+	shared := testutil.CreateConversation(t, s.db, "conv-shared",
+		models.Message{SenderID: "alice", Content: "我住在幸福路 8 号"},
+		models.Message{SenderID: "bob", Content: "好的"})
+	// 只有已软删除消息的对话
+	soft := testutil.CreateConversation(t, s.db, "conv-soft", models.Message{SenderID: "alice", Content: "撤回的消息"})
+	if err := s.db.Where("conversation_id = ?", soft.ID).Delete(&models.Message{}).Error; err != nil {
+		t.Fatalf("软删除消息失败: %v", err)
+	}
+
+	s.db.Create(&models.Style{ConversationID: shared.ID, UserID: "alice", Features: "{}"})
+	s.db.Create(&models.Style{ConversationID: shared.ID, UserID: "bob", Features: "{}"})
+	s.db.Create(&models.UserStyle{UserID: "alice", Features: "{}"})
+	s.db.Create(&models.CompletionLog{ConversationID: shared.ID, SenderID: "alice", Suggestions: "[]"})
+	s.db.Create(&models.CompletionLog{ConversationID: shared.ID, SenderID: "bob", Suggestions: "[]"})
+	if _, err := createSnapshot(s.db, &shared, "手动快照"); err != nil {
+		t.Fatalf("创建快照失败: %v", err)
+	}
+	// 已被合并删除的对话留下的快照
+	s.db.Create(&models.ConversationSnapshot{ConversationID: "conv-merged-away", Participants: "[]",
+		Data: `[{"sender_id":"alice","content":"旧地址"}]`, MessageCount: 1})
+	s.db.Create(&models.ConversationSnapshot{ConversationID: "conv-other", Participants: "[]",
+		Data: `[{"sender_id":"carol","content":"无关"}]`, MessageCount: 1})
+
+	var result DeleteUserDataResult
+	decode(t, s.do(t, http.MethodDelete, "/api/user/alice/data", nil), http.StatusOK, &result)
+	if result.MessagesDeleted != 2 || result.SnapshotsDeleted != 2 || result.StylesDeleted != 2 {
+		t.Fatalf("删除统计为 %+v", result)
+	}
+
+	counts := []struct {
+		name  string
+		query *gorm.DB
+		want  int64
+	}{
+		{"alice 的消息（含软删除）", s.db.Unscoped().Model(&models.Message{}).Where("sender_id = ?", "alice"), 0},
+		{"bob 的消息", s.db.Model(&models.Message{}).Where("sender_id = ?", "bob"), 1},
+		{"alice 的风格", s.db.Unscoped().Model(&models.Style{}).Where("user_id = ?", "alice"), 0},
+		{"bob 的风格", s.db.Model(&models.Style{}).Where("user_id = ?", "bob"), 1},
+		{"alice 的用户级风格", s.db.Model(&models.UserStyle{}).Where("user_id = ?", "alice"), 0},
+		{"alice 的补全历史", s.db.Model(&models.CompletionLog{}).Where("sender_id = ?", "alice"), 0},
+		{"bob 的补全历史", s.db.Model(&models.CompletionLog{}).Where("sender_id = ?", "bob"), 1},
+		{"无关快照", s.db.Model(&models.ConversationSnapshot{}).Where("conversation_id = ?", "conv-other"), 1},
+		{"全部快照", s.db.Model(&models.ConversationSnapshot{}), 1},
+		{"只剩 alice 的对话", s.db.Unscoped().Model(&models.Conversation{}).Where("conversation_id = ?", "conv-soft"), 0},
+	}
+	for _, c := range counts {
+		var n int64
+		if err := c.query.Count(&n).Error; err != nil {
+			t.Fatalf("统计%s失败: %v", c.name, err)
+		}
+		if n != c.want {
+			t.Errorf("%s数量为 %d，期望 %d", c.name, n, c.want)
+		}
+	}
+}
@@ -1,8 +1,11 @@
 package api
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"ChatRecommend/internal/models"
@@ -20,11 +23,15 @@ const (
 	pingPeriod = (pongWait * 9) / 10
 	// 最大消息大小
 	maxMessageSize = 512 * 1024
+	// WebSocket协议版本，消息格式有不兼容变更时递增
+	wsProtocolVersion = "1"
 )
 
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
+	// 按顺序选择客户端声明的第一个受支持的子协议
+	Subprotocols: []string{wsSubprotocolJSON, wsSubprotocolMsgpack},
 	CheckOrigin: func(r *http.Request) bool {
 		return true // 允许所有来源，生产环境应该检查
 	},
@@ -35,6 +42,7 @@ type Client struct {
 	conn       *websocket.Conn
 	handler    *Handler
 	send       chan []byte
+	codec      wsCodec
 	conversationID string
 	senderID   string
 }
@@ -42,9 +50,14 @@ type Client struct {
 // WSMessage WebSocket消息
 type WSMessage struct {
 	Type           string                      `json:"type"`
+	Version        string                      `json:"version,omitempty"`
 	AutocompleteRequest *models.AutocompleteRequest `json:"autocomplete_request,omitempty"`
+	SaveMessageRequest  *models.SaveMessageRequest  `json:"save_message_request,omitempty"`
+	// 关注的对话和订阅者（subscribe 消息使用，sender_id 缺省时使用连接时声明的用户）
+	ConversationIDs []string                   `json:"conversation_ids,omitempty"`
+	SenderID        string                     `json:"sender_id,omitempty"`
 	Data           interface{}                 `json:"data,omitempty"`
-	Error          string                      `json:"error,omitempty"`
+	Error          *ErrorBody                  `json:"error,omitempty"`
 }
 
 // HandleWebSocket 处理WebSocket连接
@@ -59,6 +72,25 @@ func (h *Handler) HandleWebSocket(c *gin.Context) {
 		conn:    conn,
 		handler: h,
 		send:    make(chan []byte, 256),
+		codec:   codecFor(conn.Subprotocol()),
+		// 浏览器无法为 WebSocket 握手设置请求头，也可用 sender_id 参数声明用户
+		senderID: c.Query("sender_id"),
+	}
+	if client.senderID == "" {
+		client.senderID = c.GetHeader(UserIDHeader)
+	}
+
+	// 连接时可通过 conversation_id 参数（逗号分隔）声明关注的对话，无权订阅时通过连接返回错误
+	var conversationIDs []string
+	for _, conversationID := range strings.Split(c.Query("conversation_id"), ",") {
+		if conversationID = strings.TrimSpace(conversationID); conversationID != "" {
+			conversationIDs = append(conversationIDs, conversationID)
+		}
+	}
+	if len(conversationIDs) > 0 {
+		if err := client.subscribe(conversationIDs, client.senderID); err != nil {
+			client.sendError(classifyError(err), err.Error())
+		}
 	}
 
 	// 启动读写goroutine
@@ -69,6 +101,7 @@ func (h *Handler) HandleWebSocket(c *gin.Context) {
 // readPump 读取消息
 func (c *Client) readPump() {
 	defer func() {
+		c.handler.hub.Unregister(c)
 		c.conn.Close()
 	}()
 
@@ -80,7 +113,7 @@ func (c *Client) readPump() {
 	})
 
 	for {
-		_, message, err := c.conn.ReadMessage()
+		frameType, message, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				logrus.WithError(err).Error("WebSocket读取错误")
@@ -88,9 +121,15 @@ func (c *Client) readPump() {
 			break
 		}
 
+		// 文本帧总是按JSON解析，二进制帧按协商的编码解析
+		decoder := c.codec
+		if frameType == websocket.TextMessage {
+			decoder = jsonCodec{}
+		}
 		var wsMsg WSMessage
-		if err := json.Unmarshal(message, &wsMsg); err != nil {
+		if err := decoder.Unmarshal(message, &wsMsg); err != nil {
 			logrus.WithError(err).Error("解析WebSocket消息失败")
+			c.sendError(ErrCodeInvalidRequest, "消息格式错误: "+err.Error())
 			continue
 		}
 
@@ -117,6 +156,16 @@ func (c *Client) writePump() {
 
 			logrus.WithField("message_size", len(message)).Debug("writePump: 从通道接收消息")
 
+			// 二进制帧无法用换行分隔，每条消息单独一帧
+			if c.codec.FrameType() == websocket.BinaryMessage {
+				if err := c.conn.WriteMessage(websocket.BinaryMessage, message); err != nil {
+					logrus.WithError(err).Error("发送消息失败")
+					return
+				}
+				logrus.Debug("writePump: 消息已发送")
+				continue
+			}
+
 			w, err := c.conn.NextWriter(websocket.TextMessage)
 			if err != nil {
 				logrus.WithError(err).Error("创建写入器失败")
@@ -152,7 +201,11 @@ func (c *Client) handleMessage(msg *WSMessage) {
 	switch msg.Type {
 	case "autocomplete":
 		if msg.AutocompleteRequest == nil {
-			c.sendError("autocomplete_request不能为空")
+			c.sendError(ErrCodeInvalidRequest, "autocomplete_request不能为空")
+			return
+		}
+		if msg.AutocompleteRequest.ConversationID == "" || msg.AutocompleteRequest.SenderID == "" {
+			c.sendError(ErrCodeInvalidRequest, "conversation_id和sender_id不能为空")
 			return
 		}
 
@@ -161,15 +214,27 @@ func (c *Client) handleMessage(msg *WSMessage) {
 			"input":           msg.AutocompleteRequest.Input,
 		}).Debug("WebSocket 收到补全请求")
 
-		// 保存conversation_id和sender_id
+		// 保存conversation_id和sender_id，是对话参与者时关注该对话
 		c.conversationID = msg.AutocompleteRequest.ConversationID
 		c.senderID = msg.AutocompleteRequest.SenderID
-
-		// 获取补全建议
-		resp, err := c.handler.autocomplete.GetSuggestionsWithDebounce(msg.AutocompleteRequest)
+		c.follow(c.conversationID, c.senderID)
+
+		// 获取补全建议（WebSocket 请求经过去抖）
+		resp, err := c.handler.chat.Suggest(WithTransport(context.Background(), TransportWebSocket), msg.AutocompleteRequest)
+		if errors.Is(err, ErrAutocompleteDisabled) {
+			// 对话关闭了补全：单独的消息类型，客户端据此停止发送补全请求
+			c.sendMessage(&WSMessage{
+				Type: "autocomplete_disabled",
+				Data: gin.H{
+					"conversation_id": msg.AutocompleteRequest.ConversationID,
+					"message":         err.Error(),
+				},
+			})
+			return
+		}
 		if err != nil {
 			logrus.WithError(err).Error("获取补全建议失败")
-			c.sendError(err.Error())
+			c.sendError(classifyError(err), err.Error())
 			return
 		}
 
@@ -185,21 +250,99 @@ func (c *Client) handleMessage(msg *WSMessage) {
 		}
 		c.sendMessage(&response)
 
+	case "subscribe":
+		if len(msg.ConversationIDs) == 0 {
+			c.sendError(ErrCodeInvalidRequest, "conversation_ids不能为空")
+			return
+		}
+		senderID := msg.SenderID
+		if senderID == "" {
+			senderID = c.senderID
+		}
+		if err := c.subscribe(msg.ConversationIDs, senderID); err != nil {
+			c.sendError(classifyError(err), err.Error())
+			return
+		}
+		c.sendMessage(&WSMessage{
+			Type:            "subscribed",
+			ConversationIDs: msg.ConversationIDs,
+		})
+
+	case "save_message":
+		req := msg.SaveMessageRequest
+		if req == nil || req.ConversationID == "" || req.SenderID == "" || req.Content == "" {
+			c.sendError(ErrCodeInvalidRequest, "save_message_request的conversation_id、sender_id和content不能为空")
+			return
+		}
+		if !models.ValidContentFormat(req.ContentFormat) {
+			c.sendError(ErrCodeInvalidRequest, "content_format 只能为 plain 或 markdown")
+			return
+		}
+
+		message, duplicate, err := c.handler.saveMessage(req, c)
+		if err != nil {
+			logrus.WithError(err).Error("保存消息失败")
+			c.sendError(classifyError(err), err.Error())
+			return
+		}
+		// 发送消息即表示关注该对话，保存成功后发送者已是参与者
+		c.follow(req.ConversationID, req.SenderID)
+
+		c.sendMessage(&WSMessage{
+			Type: "save_message_response",
+			Data: gin.H{
+				"message_id": message.ID,
+				"status":     saveStatus(duplicate),
+			},
+		})
+
 	default:
-		c.sendError("未知的消息类型: " + msg.Type)
+		c.sendError(ErrCodeUnknownType, "未知的消息类型: "+msg.Type)
+	}
+}
+
+// subscribe 校验用户是各对话的参与者后登记关注；任一对话不存在或无权订阅时都不登记
+func (c *Client) subscribe(conversationIDs []string, userID string) error {
+	for _, conversationID := range conversationIDs {
+		var conversation models.Conversation
+		if err := c.handler.db.Where("conversation_id = ?", conversationID).First(&conversation).Error; err != nil {
+			if isNotFound(err) {
+				return fmt.Errorf("对话 %s 不存在: %w", conversationID, err)
+			}
+			return fmt.Errorf("查询对话失败: %w", err)
+		}
+		if err := authorize(c.handler.db, &conversation, userID, ActionSubscribe); err != nil {
+			return err
+		}
+	}
+	for _, conversationID := range conversationIDs {
+		c.handler.hub.Register(conversationID, c)
+	}
+	return nil
+}
+
+// follow 隐式关注对话（补全、发送消息时），与 subscribe 同样校验参与者，无权时只是不登记，不返回错误
+func (c *Client) follow(conversationID, userID string) {
+	if err := c.subscribe([]string{conversationID}, userID); err != nil {
+		logrus.WithError(err).WithField("conversation_id", conversationID).Debug("未登记关注")
 	}
 }
 
 // sendMessage 发送消息
 func (c *Client) sendMessage(msg *WSMessage) {
-	data, err := json.Marshal(msg)
+	msg.Version = wsProtocolVersion
+	data, err := c.codec.Marshal(msg)
 	if err != nil {
 		logrus.WithError(err).Error("序列化消息失败")
 		return
 	}
 
-	logrus.WithField("message", string(data)).Debug("发送 WebSocket 消息")
+	logrus.WithFields(logrus.Fields{"type": msg.Type, "codec": c.codec.Name()}).Debug("发送 WebSocket 消息")
+	c.enqueue(data)
+}
 
+// enqueue 把消息放入发送通道，通道已满时丢弃
+func (c *Client) enqueue(data []byte) {
 	select {
 	case c.send <- data:
 		logrus.Debug("消息已放入发送通道")
@@ -209,10 +352,10 @@ func (c *Client) sendMessage(msg *WSMessage) {
 }
 
 // sendError 发送错误消息
-func (c *Client) sendError(errMsg string) {
+func (c *Client) sendError(code ErrorCode, errMsg string) {
 	msg := WSMessage{
 		Type:  "error",
-		Error: errMsg,
+		Error: newErrorBody(code, errMsg),
 	}
 	c.sendMessage(&msg)
 }
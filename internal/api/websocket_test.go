@@ -0,0 +1,303 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ChatRecommend/internal/llm"
+	"ChatRecommend/internal/models"
+	"github.com/gorilla/websocket"
+)
+
+// wsConn 测试用的 WebSocket 连接，JSON 模式下一帧可能包含多条以换行分隔的消息
+type wsConn struct {
+	*websocket.Conn
+	pending []string
+}
+
+// dialWS 启动测试服务并建立 WebSocket 连接，query 为连接参数（不含 ?）
+func (s *testServer) dialWS(t *testing.T, query string) *wsConn {
+	t.Helper()
+	return s.dial(t, query, nil)
+}
+
+// dialWSProtocol 建立声明了子协议的 WebSocket 连接
+func (s *testServer) dialWSProtocol(t *testing.T, subprotocol string) *wsConn {
+	t.Helper()
+	return s.dial(t, "", &websocket.Dialer{Subprotocols: []string{subprotocol}})
+}
+
+// dial 建立 WebSocket 连接，dialer 为 nil 时使用默认配置
+func (s *testServer) dial(t *testing.T, query string, dialer *websocket.Dialer) *wsConn {
+	t.Helper()
+	if dialer == nil {
+		dialer = websocket.DefaultDialer
+	}
+
+	server := httptest.NewServer(s.router)
+	t.Cleanup(server.Close)
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+	if query != "" {
+		url += "?" + query
+	}
+	conn, _, err := dialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("建立WebSocket连接失败: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &wsConn{Conn: conn}
+}
+
+// send 以JSON文本帧发送消息
+func (c *wsConn) send(t *testing.T, msg interface{}) {
+	t.Helper()
+	if err := c.WriteJSON(msg); err != nil {
+		t.Fatalf("发送WebSocket消息失败: %v", err)
+	}
+}
+
+// next 读取下一条消息，解码为通用结构以检查线上的字段
+func (c *wsConn) next(t *testing.T) map[string]interface{} {
+	t.Helper()
+	for len(c.pending) == 0 {
+		c.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, data, err := c.ReadMessage()
+		if err != nil {
+			t.Fatalf("读取WebSocket消息失败: %v", err)
+		}
+		c.pending = strings.Split(string(data), "\n")
+	}
+	var msg map[string]interface{}
+	if err := json.Unmarshal([]byte(c.pending[0]), &msg); err != nil {
+		t.Fatalf("解码WebSocket消息失败: %v，消息: %s", err, c.pending[0])
+	}
+	c.pending = c.pending[1:]
+	return msg
+}
+
+// expectError 读取下一条消息，检查为错误消息及其错误码和是否可重试
+func (c *wsConn) expectError(t *testing.T, code ErrorCode, retriable bool) {
+	t.Helper()
+	msg := c.next(t)
+	if msg["type"] != "error" || msg["version"] != wsProtocolVersion {
+		t.Fatalf("期望版本为 %s 的错误消息，收到 %v", wsProtocolVersion, msg)
+	}
+	body, ok := msg["error"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("error 字段应为对象，收到 %v", msg["error"])
+	}
+	if body["code"] != string(code) || body["retriable"] != retriable {
+		t.Fatalf("错误为 %v，期望 code=%s retriable=%v", body, code, retriable)
+	}
+	if message, _ := body["message"].(string); message == "" {
+		t.Fatalf("错误消息不能为空: %v", body)
+	}
+}
+
+// TestWebSocketErrorCodes 各类错误以结构化的 error 返回，带错误码和是否可重试
+func TestWebSocketErrorCodes(t *testing.T) {
+	s := newTestServer(t)
+	s.saveMessage(t, "conv_ro", "alice", "你好")
+	if err := s.db.Model(&models.Conversation{}).Where("conversation_id = ?", "conv_ro").
+		Update("read_only", true).Error; err != nil {
+		t.Fatalf("设置只读失败: %v", err)
+	}
+	conn := s.dialWS(t, "")
+
+	// 格式错误的请求
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("{not json")); err != nil {
+		t.Fatalf("发送消息失败: %v", err)
+	}
+	conn.expectError(t, ErrCodeInvalidRequest, false)
+
+	// 缺少必填字段
+	conn.send(t, map[string]interface{}{"type": "autocomplete"})
+	conn.expectError(t, ErrCodeInvalidRequest, false)
+
+	// 未知消息类型
+	conn.send(t, map[string]interface{}{"type": "ping_me"})
+	conn.expectError(t, ErrCodeUnknownType, false)
+
+	// 只读对话不能保存消息
+	conn.send(t, map[string]interface{}{
+		"type": "save_message",
+		"save_message_request": map[string]interface{}{
+			"conversation_id": "conv_ro", "sender_id": "alice", "content": "还能发吗",
+		},
+	})
+	conn.expectError(t, ErrCodeForbidden, false)
+
+	// 大模型超时可重试
+	s.llm.Err = fmt.Errorf("%w（30秒）", llm.ErrTimeout)
+	conn.send(t, map[string]interface{}{
+		"type": "autocomplete",
+		"autocomplete_request": map[string]interface{}{
+			"conversation_id": "conv_ro", "sender_id": "alice", "input": "明天",
+		},
+	})
+	conn.expectError(t, ErrCodeLLMTimeout, true)
+}
+
+// TestWebSocketBroadcastNewMessage 保存消息后向关注该对话的其他连接广播，发起保存的连接不会收到
+func TestWebSocketBroadcastNewMessage(t *testing.T) {
+	s := newTestServer(t)
+	s.saveMessage(t, "conv_1", "alice", "在吗")
+	s.saveMessage(t, "conv_1", "bob", "在")
+
+	alice := s.dialWS(t, "conversation_id=conv_1&sender_id=alice")
+	bob := s.dialWS(t, "")
+	bob.send(t, map[string]interface{}{"type": "subscribe", "conversation_ids": []string{"conv_1"}, "sender_id": "bob"})
+	if msg := bob.next(t); msg["type"] != "subscribed" {
+		t.Fatalf("期望 subscribed，收到 %v", msg)
+	}
+
+	// HTTP 保存的消息广播给两个连接
+	s.saveMessage(t, "conv_1", "carol", "我也在")
+	for _, conn := range []*wsConn{alice, bob} {
+		msg := conn.next(t)
+		data, _ := msg["data"].(map[string]interface{})
+		message, _ := data["message"].(map[string]interface{})
+		if msg["type"] != "new_message" || data["conversation_id"] != "conv_1" || message["content"] != "我也在" {
+			t.Fatalf("期望 new_message 广播，收到 %v", msg)
+		}
+	}
+
+	// WebSocket 保存的消息只广播给其他连接
+	bob.send(t, map[string]interface{}{
+		"type": "save_message",
+		"save_message_request": map[string]interface{}{
+			"conversation_id": "conv_1", "sender_id": "bob", "content": "走吧",
+		},
+	})
+	if msg := bob.next(t); msg["type"] != "save_message_response" {
+		t.Fatalf("期望 save_message_response，收到 %v", msg)
+	}
+	if msg := alice.next(t); msg["type"] != "new_message" {
+		t.Fatalf("期望 new_message 广播，收到 %v", msg)
+	}
+	bob.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if _, data, err := bob.ReadMessage(); err == nil {
+		t.Fatalf("发起保存的连接不应收到自己的广播，收到 %s", data)
+	}
+}
+
+// TestWebSocketSubscribeRequiresParticipant 只有对话的已有参与者能订阅广播
+func TestWebSocketSubscribeRequiresParticipant(t *testing.T) {
+	s := newTestServer(t)
+	s.saveMessage(t, "conv_1", "alice", "在吗")
+
+	tests := []struct {
+		name     string
+		senderID string
+		ids      []string
+		code     ErrorCode
+	}{
+		{"anonymous", "", []string{"conv_1"}, ErrCodeUnauthorized},
+		{"outsider", "mallory", []string{"conv_1"}, ErrCodeForbidden},
+		{"missing conversation", "alice", []string{"conv_1", "conv_404"}, ErrCodeNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := s.dialWS(t, "")
+			conn.send(t, map[string]interface{}{"type": "subscribe", "conversation_ids": tt.ids, "sender_id": tt.senderID})
+			conn.expectError(t, tt.code, false)
+		})
+	}
+
+	// 连接参数中的订阅同样校验，无权时通过连接返回错误
+	outsider := s.dialWS(t, "conversation_id=conv_1&sender_id=mallory")
+	outsider.expectError(t, ErrCodeForbidden, false)
+
+	s.saveMessage(t, "conv_1", "alice", "有人吗")
+	s.handler.hub.mu.RLock()
+	subscribers := len(s.handler.hub.conversations["conv_1"])
+	s.handler.hub.mu.RUnlock()
+	if subscribers != 0 {
+		t.Fatalf("无权订阅的连接不应登记关注，当前 %d 个", subscribers)
+	}
+}
+
+// TestWebSocketAutocompleteFollowRequiresParticipant 补全请求隐式关注对话时同样校验参与者，非参与者收不到对话广播
+func TestWebSocketAutocompleteFollowRequiresParticipant(t *testing.T) {
+	s := newTestServer(t)
+	s.saveMessage(t, "conv_1", "alice", "在吗")
+	s.saveMessage(t, "conv_1", "bob", "在")
+
+	alice := s.dialWS(t, "")
+	mallory := s.dialWS(t, "")
+	for _, tt := range []struct {
+		conn     *wsConn
+		senderID string
+	}{{alice, "alice"}, {mallory, "mallory"}} {
+		tt.conn.send(t, map[string]interface{}{
+			"type": "autocomplete",
+			"autocomplete_request": map[string]interface{}{
+				"conversation_id": "conv_1", "sender_id": tt.senderID, "input": "好",
+			},
+		})
+		if msg := tt.conn.next(t); msg["type"] != "autocomplete_response" {
+			t.Fatalf("期望 autocomplete_response，收到 %v", msg)
+		}
+	}
+
+	// 新消息、编辑和置顶只广播给参与者
+	id := s.saveMessage(t, "conv_1", "bob", "周六见")
+	s.do(t, http.MethodPut, fmt.Sprintf("/api/chat/message/%d", id), map[string]interface{}{"sender_id": "bob", "content": "周日见"})
+	s.do(t, http.MethodPost, fmt.Sprintf("/api/chat/message/%d/pin", id), nil)
+	for _, want := range []string{"new_message", "message_edited", "message_pinned"} {
+		if msg := alice.next(t); msg["type"] != want {
+			t.Fatalf("期望 %s 广播，收到 %v", want, msg)
+		}
+	}
+	mallory.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if _, data, err := mallory.ReadMessage(); err == nil {
+		t.Fatalf("非参与者不应收到对话广播，收到 %s", data)
+	}
+}
+
+// TestWebSocketSaveMessageFollowsAfterSave 通过 WebSocket 发送消息的连接在保存成功后才关注对话
+func TestWebSocketSaveMessageFollowsAfterSave(t *testing.T) {
+	s := newTestServer(t)
+	s.saveMessage(t, "conv_ro", "alice", "你好")
+	if err := s.db.Model(&models.Conversation{}).Where("conversation_id = ?", "conv_ro").
+		Update("read_only", true).Error; err != nil {
+		t.Fatalf("设置只读失败: %v", err)
+	}
+
+	// 保存失败时不关注
+	mallory := s.dialWS(t, "")
+	mallory.send(t, map[string]interface{}{
+		"type": "save_message",
+		"save_message_request": map[string]interface{}{
+			"conversation_id": "conv_ro", "sender_id": "mallory", "content": "我也来",
+		},
+	})
+	mallory.expectError(t, ErrCodeForbidden, false)
+	s.handler.hub.mu.RLock()
+	subscribers := len(s.handler.hub.conversations["conv_ro"])
+	s.handler.hub.mu.RUnlock()
+	if subscribers != 0 {
+		t.Fatalf("保存失败的连接不应登记关注，当前 %d 个", subscribers)
+	}
+
+	// 保存成功后关注，能收到其他人的新消息
+	bob := s.dialWS(t, "")
+	bob.send(t, map[string]interface{}{
+		"type": "save_message",
+		"save_message_request": map[string]interface{}{
+			"conversation_id": "conv_1", "sender_id": "bob", "content": "有人吗",
+		},
+	})
+	if msg := bob.next(t); msg["type"] != "save_message_response" {
+		t.Fatalf("期望 save_message_response，收到 %v", msg)
+	}
+	s.saveMessage(t, "conv_1", "alice", "我在")
+	if msg := bob.next(t); msg["type"] != "new_message" {
+		t.Fatalf("期望 new_message 广播，收到 %v", msg)
+	}
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/gorilla/websocket"
+	"github.com/ugorji/go/codec"
+)
+
+// wsCodec WebSocket消息编码
+type wsCodec interface {
+	// Name 编码对应的WebSocket子协议名
+	Name() string
+	// FrameType 发送时使用的帧类型（文本帧或二进制帧）
+	FrameType() int
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// WebSocket子协议，客户端在握手时通过 Sec-WebSocket-Protocol 声明编码，未声明时使用JSON
+const (
+	wsSubprotocolJSON    = "chatrecommend.json"
+	wsSubprotocolMsgpack = "chatrecommend.msgpack"
+)
+
+// jsonCodec JSON文本帧（默认）
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string                               { return wsSubprotocolJSON }
+func (jsonCodec) FrameType() int                             { return websocket.TextMessage }
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+// msgpackCodec msgpack二进制帧，字段名与JSON一致（沿用json标签）
+type msgpackCodec struct {
+	handle *codec.MsgpackHandle
+}
+
+func newMsgpackCodec() *msgpackCodec {
+	handle := &codec.MsgpackHandle{WriteExt: true}
+	handle.RawToString = true
+	handle.MapType = reflect.TypeOf(map[string]interface{}(nil))
+	return &msgpackCodec{handle: handle}
+}
+
+func (c *msgpackCodec) Name() string   { return wsSubprotocolMsgpack }
+func (c *msgpackCodec) FrameType() int { return websocket.BinaryMessage }
+
+func (c *msgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	var data []byte
+	err := codec.NewEncoderBytes(&data, c.handle).Encode(v)
+	return data, err
+}
+
+func (c *msgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	return codec.NewDecoderBytes(data, c.handle).Decode(v)
+}
+
+// wsCodecs 支持的编码，按子协议名索引
+var wsCodecs = map[string]wsCodec{
+	wsSubprotocolJSON:    jsonCodec{},
+	wsSubprotocolMsgpack: newMsgpackCodec(),
+}
+
+// codecFor 按握手协商出的子协议选择编码，未协商时使用JSON
+func codecFor(subprotocol string) wsCodec {
+	if c, ok := wsCodecs[subprotocol]; ok {
+		return c
+	}
+	return jsonCodec{}
+}
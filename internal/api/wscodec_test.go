@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"ChatRecommend/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// wsMessageVariants 客户端发送和服务端下发的各类消息
+func wsMessageVariants() []*WSMessage {
+	return []*WSMessage{
+		{Type: "autocomplete", AutocompleteRequest: &models.AutocompleteRequest{
+			ConversationID: "conv_1", SenderID: "alice", Input: "今天天气", MaxSuggestions: 2, Stop: []string{"。"}, PrivacyMode: true,
+		}},
+		{Type: "subscribe", ConversationIDs: []string{"conv_1", "conv_2"}},
+		{Type: "save_message", SaveMessageRequest: &models.SaveMessageRequest{
+			ConversationID: "conv_1", SenderID: "alice", Content: "**你好**", Sequence: 42, ContentFormat: models.ContentFormatMarkdown,
+		}},
+		{Type: "subscribed", Version: wsProtocolVersion, ConversationIDs: []string{"conv_1"}},
+		{Type: "autocomplete_response", Version: wsProtocolVersion, Data: gin.H{
+			"suggestions": []string{"今天天气不错", "今天天气很好"}, "completion_id": 7,
+		}},
+		{Type: "autocomplete_disabled", Version: wsProtocolVersion, Data: gin.H{"conversation_id": "conv_1", "message": "该对话已关闭补全"}},
+		{Type: "save_message_response", Version: wsProtocolVersion, Data: gin.H{"message_id": 3, "status": "created"}},
+		{Type: "new_message", Version: wsProtocolVersion, Data: gin.H{
+			"conversation_id": "conv_1",
+			"message":         gin.H{"id": 3, "sender_id": "alice", "content": "你好", "sequence": 1234567890},
+		}},
+		{Type: "error", Version: wsProtocolVersion, Error: newErrorBody(ErrCodeLLMTimeout, "获取补全建议超时")},
+		{Type: "error", Version: wsProtocolVersion, Error: newErrorBody(ErrCodeForbidden, "对话为只读")},
+	}
+}
+
+// normalize 按 JSON 重新编码，消除 Data 中数字类型等编码间的差异
+func normalize(t *testing.T, msg *WSMessage) string {
+	t.Helper()
+	data, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+	return string(data)
+}
+
+// 各类消息经 msgpack 编码再解码后与原消息一致，字段名与 JSON 相同
+func TestMsgpackCodecRoundTrip(t *testing.T) {
+	codec := newMsgpackCodec()
+	for _, msg := range wsMessageVariants() {
+		t.Run(msg.Type, func(t *testing.T) {
+			data, err := codec.Marshal(msg)
+			if err != nil {
+				t.Fatalf("msgpack 编码失败: %v", err)
+			}
+			var decoded WSMessage
+			if err := codec.Unmarshal(data, &decoded); err != nil {
+				t.Fatalf("msgpack 解码失败: %v", err)
+			}
+			if got, want := normalize(t, &decoded), normalize(t, msg); got != want {
+				t.Fatalf("往返后为 %s，期望 %s", got, want)
+			}
+			if msg.Error != nil && !reflect.DeepEqual(decoded.Error, msg.Error) {
+				t.Fatalf("错误为 %+v，期望 %+v", decoded.Error, msg.Error)
+			}
+
+			// 字段名沿用 JSON 标签
+			var fields map[string]interface{}
+			if err := codec.Unmarshal(data, &fields); err != nil {
+				t.Fatalf("msgpack 解码失败: %v", err)
+			}
+			var jsonFields map[string]interface{}
+			json.Unmarshal([]byte(normalize(t, msg)), &jsonFields)
+			for key := range jsonFields {
+				if _, ok := fields[key]; !ok {
+					t.Fatalf("msgpack 消息缺少字段 %s: %v", key, fields)
+				}
+			}
+		})
+	}
+}
+
+// 协商 msgpack 子协议的连接收到二进制帧，错误按结构化字段编码
+func TestWebSocketMsgpackSubprotocol(t *testing.T) {
+	s := newTestServer(t)
+	conn := s.dialWSProtocol(t, wsSubprotocolMsgpack)
+	if conn.Subprotocol() != wsSubprotocolMsgpack {
+		t.Fatalf("协商的子协议为 %q", conn.Subprotocol())
+	}
+
+	codec := newMsgpackCodec()
+	data, err := codec.Marshal(&WSMessage{Type: "unknown"})
+	if err != nil {
+		t.Fatalf("msgpack 编码失败: %v", err)
+	}
+	if err := conn.WriteMessage(websocket.BinaryMessage, data); err != nil {
+		t.Fatalf("发送消息失败: %v", err)
+	}
+
+	frameType, reply, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("读取消息失败: %v", err)
+	}
+	if frameType != websocket.BinaryMessage {
+		t.Fatalf("帧类型为 %d，期望二进制帧", frameType)
+	}
+	var msg WSMessage
+	if err := codec.Unmarshal(reply, &msg); err != nil {
+		t.Fatalf("msgpack 解码失败: %v", err)
+	}
+	if msg.Type != "error" || msg.Error == nil || msg.Error.Code != ErrCodeUnknownType || msg.Error.Retriable {
+		t.Fatalf("收到 %+v，错误 %+v", msg, msg.Error)
+	}
+}
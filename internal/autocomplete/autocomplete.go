@@ -1,14 +1,19 @@
 package autocomplete
 
 import (
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
 	"ChatRecommend/internal/config"
 	"ChatRecommend/internal/context"
+	"ChatRecommend/internal/correction"
 	"ChatRecommend/internal/llm"
 	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/rules"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
@@ -18,67 +23,409 @@ type Engine struct {
 	db          *gorm.DB
 	config      *config.AutocompleteConfig
 	contextMgr  *context.Manager
-	llmClient   *llm.Client
+	llmClient   llm.Service
+	rules       *rules.Matcher
 	debounceMap sync.Map // 用于请求去抖
+	cache       *suggestionCache
+	requests    *idempotencyStore
+	prefetchSem chan struct{}
+	postprocess Pipeline
+	preprocess  []InputPreprocessor
+	safety      *safetyChecker
+	grammar     GrammarChecker
+	quota       *dailyQuota
+	degrade     *degrader
+	ranker      *acceptanceRanker
+
+	activeMu    sync.Mutex
+	activeUsers map[string]map[string]time.Time // conversationID -> senderID -> 最后请求时间
 }
 
+// ErrTimeout 获取补全建议超时
+var ErrTimeout = errors.New("获取补全建议超时")
+
+// ErrInvalidRequest 补全请求参数不合法
+var ErrInvalidRequest = errors.New("补全请求参数不合法")
+
+// 停止序列的数量和长度上限
+const (
+	maxStopSequences     = 4
+	maxStopSequenceChars = 32
+)
+
+// openerInput 输入为空时交给大模型的提示，用于生成开场白建议
+const openerInput = "（用户尚未输入，请根据对话背景给出几条合适的开场白或回复）"
+
 // NewEngine 创建自动补全引擎
-func NewEngine(db *gorm.DB, cfg *config.AutocompleteConfig, contextMgr *context.Manager, llmClient *llm.Client) *Engine {
-	return &Engine{
-		db:         db,
-		config:     cfg,
-		contextMgr: contextMgr,
-		llmClient:  llmClient,
+func NewEngine(db *gorm.DB, cfg *config.AutocompleteConfig, contextMgr *context.Manager, llmClient llm.Service, ruleMatcher *rules.Matcher) *Engine {
+	e := &Engine{
+		db:          db,
+		config:      cfg,
+		contextMgr:  contextMgr,
+		llmClient:   llmClient,
+		rules:       ruleMatcher,
+		activeUsers: make(map[string]map[string]time.Time),
+	}
+
+	if cfg.CacheTTLSeconds > 0 {
+		e.cache = newSuggestionCache(time.Duration(cfg.CacheTTLSeconds)*time.Second, cfg.CacheExactInput)
 	}
+
+	if cfg.RequestIDTTLSeconds > 0 {
+		e.requests = newIdempotencyStore(time.Duration(cfg.RequestIDTTLSeconds) * time.Second)
+	}
+
+	concurrency := cfg.Prefetch.MaxConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	e.prefetchSem = make(chan struct{}, concurrency)
+	e.safety = newSafetyChecker(&cfg.Safety)
+	if cfg.GrammarCheck {
+		e.grammar = RuleGrammarChecker{}
+	}
+	e.postprocess = newPipeline(e, cfg)
+	e.preprocess = newInputPreprocessors(cfg)
+	e.degrade = newDegrader(&cfg.Degradation)
+	e.ranker = newAcceptanceRanker(&cfg.Ranking)
+
+	return e
 }
 
-// GetSuggestions 获取补全建议
+// GetSuggestions 获取补全建议，携带 request_id 的重复请求直接返回首次结果；输入法组字中的请求直接返回空建议
 func (e *Engine) GetSuggestions(req *models.AutocompleteRequest) (*models.AutocompleteResponse, error) {
-	// 检查输入长度
-	if len([]rune(req.Input)) < e.config.MinTriggerLength {
+	if err := e.validateRequest(req); err != nil {
+		return nil, err
+	}
+	// 组字中的输入是未上屏的拼音，补全没有意义；不记录 request_id，组字结束后用同一ID重试仍会生成
+	if req.Composing {
+		return &models.AutocompleteResponse{Type: models.ResponseTypeCompletion, Suggestions: []string{}, InsertMode: insertMode(req)}, nil
+	}
+	original := req
+	req, ok := e.preprocessRequest(req)
+	if !ok {
+		return &models.AutocompleteResponse{Type: models.ResponseTypeCompletion, Suggestions: []string{}, InsertMode: insertMode(original)}, nil
+	}
+	resp, err := e.requests.do(req, func() (*models.AutocompleteResponse, error) {
+		if len(req.InputCandidates) > 0 {
+			return e.getSuggestionsForCandidates(req)
+		}
+		return e.getSuggestions(req)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 响应可能被缓存或被重复请求共享，复制后再填写类型和插入方式
+	result := *resp
+	if result.Type == "" {
+		result.Type = models.ResponseTypeCompletion
+	}
+	result.InsertMode = insertMode(req)
+	return &result, nil
+}
+
+// rewriteMode 请求是否为改写模式
+func rewriteMode(req *models.AutocompleteRequest) bool {
+	return req.Mode == models.AutocompleteModeRewrite
+}
+
+// insertMode 建议的插入方式：改写模式的建议替换整句输入，其余接在输入之后
+func insertMode(req *models.AutocompleteRequest) string {
+	if rewriteMode(req) {
+		return models.InsertModeReplace
+	}
+	return models.InsertModeAppend
+}
+
+// getSuggestionsForCandidates 从候选输入中选出最相关的一个作为输入生成补全，并在响应中回传选中的候选
+func (e *Engine) getSuggestionsForCandidates(req *models.AutocompleteRequest) (*models.AutocompleteResponse, error) {
+	selected, err := e.selectInput(req)
+	if err != nil {
+		return nil, err
+	}
+
+	selectedReq := *req
+	selectedReq.Input = selected
+	selectedReq.InputCandidates = nil
+	resp, err := e.getSuggestions(&selectedReq)
+	if err != nil {
+		return nil, err
+	}
+
+	// 响应可能同时被缓存，复制后再填写选中的候选
+	result := *resp
+	result.SelectedInput = selected
+	return &result, nil
+}
+
+// getSuggestions 生成补全建议
+func (e *Engine) getSuggestions(req *models.AutocompleteRequest) (*models.AutocompleteResponse, error) {
+	// 输入为空时进入开场白模式
+	opener := req.Input == ""
+
+	// 输入模糊且对话近期没有可参考的消息时，返回澄清提示而不是低质量的补全（先于最小触发长度判断，"嗯"也能得到提示）
+	if !opener {
+		resp, err := e.clarify(req)
+		if err != nil || resp != nil {
+			return resp, err
+		}
+	}
+
+	// 检查去除首尾空白后的输入长度（纯空白输入不触发补全）
+	if !opener && len([]rune(strings.TrimSpace(req.Input))) < e.config.MinTriggerLength {
 		return &models.AutocompleteResponse{
 			Suggestions: []string{},
 		}, nil
 	}
 
+	input := req.Input
+	if opener {
+		input = openerInput
+	}
+
+	// 优先尝试快捷补全规则，命中则直接返回（规则模板是续写内容，改写模式不使用；不符合锁定语言的模板不使用）。
+	// 模板可以引用用户档案和对话关键信息，与占位符的取值相同
+	if !opener && !rewriteMode(req) {
+		items := textSuggestions(e.rules.MatchWith(req.Input, func() map[string]string {
+			return e.placeholderValues(req)
+		}))
+		if len(items) > 0 {
+			items = filterLanguage(e.lockedLanguage(req), items)
+		}
+		if len(items) > 0 {
+			items = e.limitSuggestions(req, items)
+			resp := &models.AutocompleteResponse{Suggestions: suggestionTexts(items)}
+			if req.ResponseFormat == models.ResponseFormatJSON {
+				resp.Items = items
+			}
+			return resp, nil
+		}
+	}
+
+	// 预取不是用户发起的请求，不能延长用户的活跃期
+	if !req.Prefetch {
+		e.recordActiveUser(req.ConversationID, req.SenderID)
+	}
+
+	if resp, ok := e.cache.get(req); ok {
+		logrus.WithField("conversation_id", req.ConversationID).Debug("补全命中缓存")
+		return resp, nil
+	}
+
 	// 获取对话ID（通过conversation_id字符串查找）
 	var conversation models.Conversation
 	if err := e.db.Where("conversation_id = ?", req.ConversationID).First(&conversation).Error; err != nil {
 		return nil, fmt.Errorf("查询对话失败: %w", err)
 	}
 
+	// 延迟过高降级为本地回退时不调用大模型（规则已在前面匹配过），只返回空建议并标明降级
+	if !e.degrade.allowLLM() {
+		return &models.AutocompleteResponse{Suggestions: []string{}, Degraded: DegradeLocal}, nil
+	}
+
+	// 只有用户发起且真正调用大模型的请求占用每日配额（规则、缓存命中和预取不计）；
+	// 预取不计数，但用户当天配额已用完时也不再预取
+	if req.Prefetch {
+		if e.quota.exhausted(req.SenderID) {
+			return nil, ErrQuotaExceeded
+		}
+	} else if err := e.quota.acquire(req.SenderID); err != nil {
+		return nil, err
+	}
+
 	// 构建上下文
-	ctx, err := e.contextMgr.BuildContext(conversation.ID, req.SenderID, req.Input)
+	buildOpts := context.BuildOptions{
+		ExtraInstructions: req.ExtraInstructions,
+		PrivacyMode:       req.PrivacyMode,
+		PersonaStyle:      req.PersonaStyle,
+		Rewrite:           rewriteMode(req),
+	}
+	// 改写模式本身会修正输入，不需要纠错提示
+	if e.config.InputCorrection && !opener && !rewriteMode(req) {
+		buildOpts.CorrectionHints = correction.FormatHints(correction.Detect(req.Input))
+	}
+	ctx, err := e.contextMgr.BuildContext(conversation.ID, req.SenderID, input, buildOpts)
 	if err != nil {
 		return nil, fmt.Errorf("构建上下文失败: %w", err)
 	}
 
-	// 调用大模型生成补全建议
-	maxSuggestions := e.config.SuggestionCount
-	if req.MaxSuggestions > 0 {
-		maxSuggestions = req.MaxSuggestions
+	// 调用大模型生成补全建议（对话级参数覆盖全局配置）
+	opts := completeOptions(&conversation)
+	opts.SuggestionCount = e.suggestionCount(req)
+	if req.MaxTokens > 0 {
+		opts.MaxTokens = &req.MaxTokens
 	}
-
-	suggestions, err := e.llmClient.Complete(ctx, req.Input)
+	opts.Stop = req.Stop
+	opts.Priority = e.requestPriority(req)
+	// 上下文越长留给生成的 token 越少，避免超出模型窗口
+	e.applyTokenBudget(req.ConversationID, ctx, input, opts)
+	var degraded string
+	if e.degrade.applyReduced(opts) {
+		degraded = DegradeReduced
+	}
+	started := time.Now()
+	items, err := e.complete(req, ctx, input, opts)
+	e.degrade.record(time.Since(started))
 	if err != nil {
 		return nil, fmt.Errorf("生成补全建议失败: %w", err)
 	}
 
-	// 限制建议数量
-	if len(suggestions) > maxSuggestions {
-		suggestions = suggestions[:maxSuggestions]
+	// 后处理（去重叠、去重、限量、截断等，按配置顺序执行），结构化结果随建议一起改写、过滤和排序
+	items = e.postprocess.Process(req, items)
+	suggestions := suggestionTexts(items)
+
+	// 文本模式只有出现需要提示的 warn 建议时才返回 items
+	if warned := e.safety.markSafety(items); req.ResponseFormat != models.ResponseFormatJSON && !warned {
+		items = nil
+	}
+
+	// 隐私模式下摘要没有发送给大模型，不做引用标注
+	var citations []models.KeyInfoCitation
+	if e.config.CiteKeyInfo && !req.PrivacyMode {
+		citations, err = e.citeKeyInfo(conversation.ID, suggestions)
+		if err != nil {
+			logrus.WithError(err).WithField("conversation_id", req.ConversationID).Warn("标注关键信息引用失败")
+		}
 	}
 
 	logrus.WithFields(logrus.Fields{
 		"conversation_id": req.ConversationID,
 		"input_length":    len(req.Input),
+		"opener":          opener,
 		"suggestions":     len(suggestions),
 	}).Debug("生成补全建议")
 
-	return &models.AutocompleteResponse{
+	resp := &models.AutocompleteResponse{
 		Suggestions: suggestions,
+		Items:       items,
 		ContextUsed: ctx,
-	}, nil
+		Citations:   citations,
+		Degraded:    degraded,
+	}
+	// 降级生成的建议不缓存，延迟恢复后尽快得到完整结果
+	if degraded == "" {
+		e.cache.set(req, resp)
+	}
+
+	return resp, nil
+}
+
+// complete 调用大模型生成建议，JSON 模式下带有理由和语气，文本模式下只有文本
+func (e *Engine) complete(req *models.AutocompleteRequest, ctx, input string, opts *llm.CompleteOptions) ([]models.Suggestion, error) {
+	if req.ResponseFormat != models.ResponseFormatJSON {
+		suggestions, err := e.llmClient.Complete(ctx, input, opts)
+		if err != nil {
+			return nil, err
+		}
+		return textSuggestions(suggestions), nil
+	}
+	return e.llmClient.CompleteStructured(ctx, input, opts)
+}
+
+// requestPriority 补全请求在大模型并发槽位上的排队优先级，由服务端按发送者推导：high_priority_users 中的用户为 high，
+// 其余为 normal。客户端只能把请求降为 low（预取也按 low），传入的 high 不会提升优先级
+func (e *Engine) requestPriority(req *models.AutocompleteRequest) int {
+	switch {
+	case req.Priority == models.PriorityLow:
+		return llm.PriorityLow
+	case slices.Contains(e.config.HighPriorityUsers, req.SenderID):
+		return llm.PriorityHigh
+	default:
+		return llm.PriorityNormal
+	}
+}
+
+// completeOptions 根据对话设置生成大模型参数覆盖，设置非法时忽略并使用全局配置
+func completeOptions(conversation *models.Conversation) *llm.CompleteOptions {
+	settings, err := conversation.GetSettings()
+	if err == nil {
+		err = settings.Validate()
+	}
+	if err != nil {
+		logrus.WithError(err).WithField("conversation_id", conversation.ConversationID).Warn("对话设置无效，使用全局配置")
+		return &llm.CompleteOptions{}
+	}
+
+	return &llm.CompleteOptions{
+		Temperature: settings.Temperature,
+		MaxTokens:   settings.MaxTokens,
+		TopP:        settings.TopP,
+	}
+}
+
+// validateRequest 校验请求中的生成参数，防止通过超大 max_tokens 滥用大模型
+func (e *Engine) validateRequest(req *models.AutocompleteRequest) error {
+	if req.MaxTokens < 0 {
+		return fmt.Errorf("%w: max_tokens 不能为负数", ErrInvalidRequest)
+	}
+	if e.config.MaxRequestTokens > 0 && req.MaxTokens > e.config.MaxRequestTokens {
+		return fmt.Errorf("%w: max_tokens 不能超过 %d", ErrInvalidRequest, e.config.MaxRequestTokens)
+	}
+	if req.MaxSuggestions < 0 {
+		return fmt.Errorf("%w: max_suggestions 不能为负数", ErrInvalidRequest)
+	}
+	if e.config.MaxRequestSuggestions > 0 && req.MaxSuggestions > e.config.MaxRequestSuggestions {
+		return fmt.Errorf("%w: max_suggestions 不能超过 %d", ErrInvalidRequest, e.config.MaxRequestSuggestions)
+	}
+	if len(req.InputCandidates) > 0 {
+		if req.Input != "" {
+			return fmt.Errorf("%w: input 和 input_candidates 不能同时设置", ErrInvalidRequest)
+		}
+		if len(req.InputCandidates) > maxInputCandidates {
+			return fmt.Errorf("%w: input_candidates 最多 %d 个", ErrInvalidRequest, maxInputCandidates)
+		}
+		for _, candidate := range req.InputCandidates {
+			if strings.TrimSpace(candidate) == "" {
+				return fmt.Errorf("%w: input_candidates 不能包含空字符串", ErrInvalidRequest)
+			}
+		}
+	}
+	switch req.Mode {
+	case "", models.AutocompleteModeComplete:
+	case models.AutocompleteModeRewrite:
+		if strings.TrimSpace(req.Input) == "" && len(req.InputCandidates) == 0 {
+			return fmt.Errorf("%w: rewrite 模式需要 input", ErrInvalidRequest)
+		}
+	default:
+		return fmt.Errorf("%w: mode 只支持 complete 或 rewrite", ErrInvalidRequest)
+	}
+	switch req.ResponseFormat {
+	case "", models.ResponseFormatText, models.ResponseFormatJSON:
+	default:
+		return fmt.Errorf("%w: response_format 只支持 text 或 json", ErrInvalidRequest)
+	}
+	switch req.Priority {
+	case "", models.PriorityHigh, models.PriorityNormal, models.PriorityLow:
+	default:
+		return fmt.Errorf("%w: priority 只支持 high、normal 或 low", ErrInvalidRequest)
+	}
+	if len(req.Stop) > maxStopSequences {
+		return fmt.Errorf("%w: stop 最多 %d 个", ErrInvalidRequest, maxStopSequences)
+	}
+	for _, stop := range req.Stop {
+		if stop == "" || len([]rune(stop)) > maxStopSequenceChars {
+			return fmt.Errorf("%w: stop 不能为空且每个不超过 %d 个字符", ErrInvalidRequest, maxStopSequenceChars)
+		}
+	}
+	return nil
+}
+
+// suggestionCount 按请求或配置确定建议数量
+func (e *Engine) suggestionCount(req *models.AutocompleteRequest) int {
+	if req.MaxSuggestions > 0 {
+		return req.MaxSuggestions
+	}
+	return e.config.SuggestionCount
+}
+
+// limitSuggestions 按请求或配置限制建议数量
+func (e *Engine) limitSuggestions(req *models.AutocompleteRequest, suggestions []models.Suggestion) []models.Suggestion {
+	maxSuggestions := e.suggestionCount(req)
+	if len(suggestions) > maxSuggestions {
+		suggestions = suggestions[:maxSuggestions]
+	}
+	return suggestions
 }
 
 // GetSuggestionsWithDebounce 带去抖的获取补全建议
@@ -93,6 +440,11 @@ func (e *Engine) GetSuggestionsWithDebounce(req *models.AutocompleteRequest) (*m
 		}
 	}
 
+	// 输入法组字中：取消等待中的请求，等组字结束后的请求再补全
+	if req.Composing {
+		return &models.AutocompleteResponse{Type: models.ResponseTypeCompletion, Suggestions: []string{}, InsertMode: insertMode(req)}, nil
+	}
+
 	// 创建一个单次通道用于结果
 	resultChan := make(chan *models.AutocompleteResponse, 1)
 	errorChan := make(chan error, 1)
@@ -126,7 +478,7 @@ func (e *Engine) GetSuggestionsWithDebounce(req *models.AutocompleteRequest) (*m
 	case err := <-errorChan:
 		return nil, err
 	case <-time.After(30 * time.Second):
-		return nil, fmt.Errorf("获取补全建议超时（30秒）")
+		return nil, fmt.Errorf("%w（30秒）", ErrTimeout)
 	}
 }
 
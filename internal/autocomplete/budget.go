@@ -0,0 +1,45 @@
+package autocomplete
+
+import (
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/context"
+	"ChatRecommend/internal/llm"
+	"github.com/sirupsen/logrus"
+)
+
+// outputTokenBudget 从总预算中扣除上下文和输入的估算 token 数，得到生成可用的 max_tokens，
+// 限制在 [min_output_tokens, max_output_tokens] 之间，limit 大于0时（请求或对话设置指定的 max_tokens）作为上限
+func outputTokenBudget(cfg *config.TokenBudgetConfig, promptTokens, limit int) int {
+	budget := min(cfg.TotalTokens-promptTokens, cfg.MaxOutputTokens)
+	if limit > 0 {
+		budget = min(budget, limit)
+	}
+	return max(budget, cfg.MinOutputTokens)
+}
+
+// applyTokenBudget 按构建出的上下文长度动态设置 opts.MaxTokens，未启用 token 预算时不做调整
+func (e *Engine) applyTokenBudget(conversationID, ctx, input string, opts *llm.CompleteOptions) {
+	cfg := &e.config.TokenBudget
+	if cfg.TotalTokens <= 0 {
+		return
+	}
+
+	limit := 0
+	if opts.MaxTokens != nil {
+		limit = *opts.MaxTokens
+	}
+	promptTokens := context.EstimateTokens(ctx) + context.EstimateTokens(input)
+	maxTokens := outputTokenBudget(cfg, promptTokens, limit)
+	opts.MaxTokens = &maxTokens
+
+	fields := logrus.Fields{
+		"conversation_id": conversationID,
+		"prompt_tokens":   promptTokens,
+		"max_tokens":      maxTokens,
+	}
+	if promptTokens+maxTokens > cfg.TotalTokens {
+		logrus.WithFields(fields).Warn("上下文过长，生成只保留 min_output_tokens，可能超出模型窗口")
+		return
+	}
+	logrus.WithFields(fields).Debug("按上下文长度分配生成 token 预算")
+}
@@ -0,0 +1,71 @@
+package autocomplete
+
+import (
+	"strings"
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// 生成预算为总预算减去上下文长度，限制在最小和最大输出之间，请求指定的 max_tokens 作为上限
+func TestOutputTokenBudget(t *testing.T) {
+	cfg := &config.TokenBudgetConfig{TotalTokens: 1000, MinOutputTokens: 32, MaxOutputTokens: 512}
+	tests := []struct {
+		promptTokens, limit, want int
+	}{
+		{100, 0, 512},
+		{700, 0, 300},
+		{700, 64, 64},
+		{990, 0, 32},
+		{1200, 0, 32},
+		{990, 16, 32},
+	}
+	for _, tt := range tests {
+		if got := outputTokenBudget(cfg, tt.promptTokens, tt.limit); got != tt.want {
+			t.Errorf("outputTokenBudget(%d, %d) = %d，期望 %d", tt.promptTokens, tt.limit, got, tt.want)
+		}
+	}
+}
+
+// 上下文越长传给大模型的 max_tokens 越小；未启用 token 预算时不设置
+func TestGetSuggestionsTokenBudget(t *testing.T) {
+	mock := &testutil.MockLLM{Suggestions: []string{"七点见"}}
+	e, db := newTestEngine(t, &config.AutocompleteConfig{
+		TokenBudget: config.TokenBudgetConfig{TotalTokens: 1200, MinOutputTokens: 32, MaxOutputTokens: 512},
+	}, mock)
+	createTestConversation(t, db, "conv-short")
+	var long []models.Message
+	for i := 0; i < 20; i++ {
+		long = append(long, models.Message{SenderID: "bob", Content: strings.Repeat("今天的会议内容很多", 30)})
+	}
+	testutil.CreateConversation(t, db, "conv-long", long...)
+
+	maxTokens := func(conversationID string) int {
+		t.Helper()
+		if _, err := e.GetSuggestions(&models.AutocompleteRequest{ConversationID: conversationID, SenderID: "alice", Input: "晚上"}); err != nil {
+			t.Fatalf("获取补全建议失败: %v", err)
+		}
+		if mock.LastOptions == nil || mock.LastOptions.MaxTokens == nil {
+			t.Fatalf("启用 token 预算时应设置 max_tokens: %+v", mock.LastOptions)
+		}
+		return *mock.LastOptions.MaxTokens
+	}
+	if got := maxTokens("conv-short"); got != 512 {
+		t.Errorf("上下文较短时 max_tokens 为 %d，期望 512", got)
+	}
+	if got := maxTokens("conv-long"); got >= 512 || got < 32 {
+		t.Errorf("上下文较长时 max_tokens 为 %d，期望在 32 到 512 之间", got)
+	}
+
+	mock = &testutil.MockLLM{Suggestions: []string{"七点见"}}
+	e, db = newTestEngine(t, &config.AutocompleteConfig{}, mock)
+	createTestConversation(t, db, "conv-short")
+	if _, err := e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-short", SenderID: "alice", Input: "晚上"}); err != nil {
+		t.Fatalf("获取补全建议失败: %v", err)
+	}
+	if mock.LastOptions.MaxTokens != nil {
+		t.Errorf("未启用 token 预算时不应设置 max_tokens: %d", *mock.LastOptions.MaxTokens)
+	}
+}
@@ -0,0 +1,131 @@
+package autocomplete
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"ChatRecommend/internal/models"
+)
+
+// cacheEntry 缓存条目
+type cacheEntry struct {
+	resp      *models.AutocompleteResponse
+	expiresAt time.Time
+}
+
+// suggestionCache 补全结果缓存，按对话分组以便新消息到来时整体失效
+// 对话上下文（摘要、风格、近期消息）只在新消息到来时变化，因此缓存键只需包含请求参数
+type suggestionCache struct {
+	mu         sync.RWMutex
+	ttl        time.Duration
+	exactInput bool                             // 为true时输入必须完全相同才命中
+	items      map[string]map[string]cacheEntry // conversationID -> key -> entry
+}
+
+// newSuggestionCache 创建补全缓存
+func newSuggestionCache(ttl time.Duration, exactInput bool) *suggestionCache {
+	return &suggestionCache{
+		ttl:        ttl,
+		exactInput: exactInput,
+		items:      make(map[string]map[string]cacheEntry),
+	}
+}
+
+// whitespacePattern 连续空白（含全角空格）
+var whitespacePattern = regexp.MustCompile(`[\s\x{3000}]+`)
+
+// normalizeCacheInput 归一化输入用于缓存键：合并连续空白、去掉末尾空白，
+// 使只差空格的输入命中同一缓存；纯空白输入与空输入（开场白）保持区分
+func normalizeCacheInput(input string) string {
+	if input == "" {
+		return ""
+	}
+	normalized := strings.TrimRight(whitespacePattern.ReplaceAllString(input, " "), " ")
+	if normalized == "" {
+		return " "
+	}
+	return normalized
+}
+
+// key 生成缓存键（会影响结果的请求参数都需要参与）
+func (c *suggestionCache) key(req *models.AutocompleteRequest) string {
+	input := req.Input
+	if !c.exactInput {
+		input = normalizeCacheInput(input)
+	}
+	return fmt.Sprintf("%s\x00%s\x00%d\x00%s\x00%t\x00%d\x00%q\x00%s\x00%s\x00%t", req.SenderID, input, req.MaxSuggestions, req.ExtraInstructions, req.PrivacyMode, req.MaxTokens, req.Stop, req.ResponseFormat, req.PersonaStyle, rewriteMode(req))
+}
+
+// get 读取缓存，返回副本
+func (c *suggestionCache) get(req *models.AutocompleteRequest) (*models.AutocompleteResponse, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.RLock()
+	entry, ok := c.items[req.ConversationID][c.key(req)]
+	c.mu.RUnlock()
+
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+
+	resp := *entry.resp
+	resp.Suggestions = append([]string(nil), entry.resp.Suggestions...)
+	resp.Items = append([]models.Suggestion(nil), entry.resp.Items...)
+	return &resp, true
+}
+
+// set 写入缓存
+func (c *suggestionCache) set(req *models.AutocompleteRequest, resp *models.AutocompleteResponse) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries, ok := c.items[req.ConversationID]
+	if !ok {
+		entries = make(map[string]cacheEntry)
+		c.items[req.ConversationID] = entries
+	}
+
+	// 顺便清理该对话下已过期的条目
+	now := time.Now()
+	for key, entry := range entries {
+		if now.After(entry.expiresAt) {
+			delete(entries, key)
+		}
+	}
+
+	entries[c.key(req)] = cacheEntry{
+		resp:      resp,
+		expiresAt: now.Add(c.ttl),
+	}
+}
+
+// clear 清空全部对话的缓存
+func (c *suggestionCache) clear() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	c.items = make(map[string]map[string]cacheEntry)
+	c.mu.Unlock()
+}
+
+// invalidate 使对话的全部缓存失效
+func (c *suggestionCache) invalidate(conversationID string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	delete(c.items, conversationID)
+	c.mu.Unlock()
+}
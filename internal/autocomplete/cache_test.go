@@ -0,0 +1,48 @@
+package autocomplete
+
+import (
+	"testing"
+	"time"
+
+	"ChatRecommend/internal/models"
+)
+
+// 输入只差空白（多打一个空格、全角空格）时命中同一缓存；cache_exact_input 开启时需要完全相同
+func TestSuggestionCacheIgnoresWhitespace(t *testing.T) {
+	request := func(input string) *models.AutocompleteRequest {
+		return &models.AutocompleteRequest{ConversationID: "conv-cache", SenderID: "alice", Input: input}
+	}
+	for _, exact := range []bool{false, true} {
+		cache := newSuggestionCache(time.Minute, exact)
+		cache.set(request("晚上 吃饭"), &models.AutocompleteResponse{Suggestions: []string{"七点见"}})
+
+		for _, input := range []string{"晚上  吃饭", "晚上 吃饭 ", "晚上　吃饭"} {
+			resp, ok := cache.get(request(input))
+			if ok == exact {
+				t.Errorf("cache_exact_input=%t 时输入 %q 命中缓存为 %t", exact, input, ok)
+			}
+			if ok && resp.Suggestions[0] != "七点见" {
+				t.Errorf("命中的建议为 %v", resp.Suggestions)
+			}
+		}
+		if _, ok := cache.get(request("晚上 吃饭吗")); ok {
+			t.Errorf("cache_exact_input=%t 时不同的输入不应命中缓存", exact)
+		}
+	}
+}
+
+// 纯空白输入与空输入（开场白）归一化后仍然不同
+func TestNormalizeCacheInput(t *testing.T) {
+	tests := map[string]string{
+		"":         "",
+		"   ":      " ",
+		"好的  ":     "好的",
+		"a \t b\n": "a b",
+		" 开头的空格保留": " 开头的空格保留",
+	}
+	for input, want := range tests {
+		if got := normalizeCacheInput(input); got != want {
+			t.Errorf("normalizeCacheInput(%q) = %q，期望 %q", input, got, want)
+		}
+	}
+}
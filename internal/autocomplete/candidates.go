@@ -0,0 +1,64 @@
+package autocomplete
+
+import (
+	"fmt"
+
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/textutil"
+)
+
+// 候选输入的数量上限，以及打分时参考的近期消息数
+const (
+	maxInputCandidates    = 8
+	candidateContextCount = 20
+	candidateRecencyDecay = 0.85
+)
+
+// selectInput 从候选输入中选出与对话近期内容最相关的一个。
+// 打分方式见 scoreCandidate；分数相同（包括对话没有历史消息）时保留客户端给出的顺序，选第一个
+func (e *Engine) selectInput(req *models.AutocompleteRequest) (string, error) {
+	var conversation models.Conversation
+	if err := e.db.Where("conversation_id = ?", req.ConversationID).First(&conversation).Error; err != nil {
+		return "", fmt.Errorf("查询对话失败: %w", err)
+	}
+
+	var messages []models.Message
+	if err := e.db.Where("conversation_id = ?", conversation.ID).
+		Scopes(models.ExcludeDrafts).
+		Order("sequence DESC, created_at DESC").
+		Limit(candidateContextCount).
+		Find(&messages).Error; err != nil {
+		return "", fmt.Errorf("查询近期消息失败: %w", err)
+	}
+
+	// 按时间倒序，越靠前越新
+	history := make([]string, len(messages))
+	for i, msg := range messages {
+		history[i] = msg.Content
+	}
+
+	best, bestScore := 0, -1.0
+	for i, candidate := range req.InputCandidates {
+		if score := scoreCandidate(candidate, history); score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return req.InputCandidates[best], nil
+}
+
+// scoreCandidate 候选与近期消息的相关度：与每条消息的字符二元组 Dice 系数按新旧加权求和，
+// 最新一条权重为1，往前每条乘以 candidateRecencyDecay
+func scoreCandidate(candidate string, history []string) float64 {
+	grams := textutil.CharBigrams(candidate)
+	if len(grams) == 0 {
+		return 0
+	}
+
+	score := 0.0
+	weight := 1.0
+	for _, content := range history {
+		score += weight * textutil.DiceCoefficient(grams, textutil.CharBigrams(content))
+		weight *= candidateRecencyDecay
+	}
+	return score
+}
@@ -0,0 +1,48 @@
+package autocomplete
+
+import (
+	"errors"
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// 候选输入按与近期消息的相关度打分，选中的候选作为输入补全并在响应中回传
+func TestGetSuggestionsSelectsInputCandidate(t *testing.T) {
+	mock := &testutil.MockLLM{Suggestions: []string{"七点怎么样"}}
+	e, db := newTestEngine(t, &config.AutocompleteConfig{}, mock)
+	createTestConversation(t, db, "conv-candidates")
+
+	resp, err := e.GetSuggestions(&models.AutocompleteRequest{
+		ConversationID:  "conv-candidates",
+		SenderID:        "alice",
+		InputCandidates: []string{"我在开会", "明天吃饭几点"},
+	})
+	if err != nil {
+		t.Fatalf("获取补全建议失败: %v", err)
+	}
+	if resp.SelectedInput != "明天吃饭几点" || mock.LastInput != "明天吃饭几点" {
+		t.Errorf("选中的候选为 %q，交给大模型的输入为 %q", resp.SelectedInput, mock.LastInput)
+	}
+
+	// input 和 input_candidates 不能同时设置
+	_, err = e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-candidates", SenderID: "alice", Input: "明天", InputCandidates: []string{"明天"}})
+	if !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("同时设置 input 和 input_candidates 应返回 ErrInvalidRequest，得到 %v", err)
+	}
+}
+
+// 越新的消息权重越高；与历史都不相关时分数为0
+func TestScoreCandidate(t *testing.T) {
+	history := []string{"周五去看电影", "周五去吃火锅"}
+	movie := scoreCandidate("看电影", history)
+	dinner := scoreCandidate("吃火锅", history)
+	if movie <= dinner {
+		t.Errorf("与最新消息相关的候选得分 %v 应高于与较早消息相关的 %v", movie, dinner)
+	}
+	if got := scoreCandidate("天气不错", history); got != 0 {
+		t.Errorf("不相关候选的得分为 %v，期望 0", got)
+	}
+}
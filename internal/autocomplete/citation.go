@@ -0,0 +1,95 @@
+package autocomplete
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/textutil"
+	"github.com/sirupsen/logrus"
+)
+
+// 查找引用来源时向前扫描的消息数（启用加密后无法用SQL按内容检索，只能解密后逐条匹配）
+const citationScanMessages = 500
+
+// 关键信息中不参与匹配的字段
+var keyInfoMetaFields = map[string]bool{"type": true, "key": true, "confidence": true, "updated_at": true}
+
+// keyInfoTerm 关键信息用于匹配建议的文本：优先使用 value 字段，没有时取其他字段中最长的字符串。
+// 少于2个字符的文本容易误匹配，返回空
+func keyInfoTerm(item map[string]interface{}) string {
+	term, _ := item["value"].(string)
+	if term == "" {
+		for field, value := range item {
+			if s, ok := value.(string); ok && !keyInfoMetaFields[field] && len([]rune(s)) > len([]rune(term)) {
+				term = s
+			}
+		}
+	}
+	term = strings.TrimSpace(term)
+	if len([]rune(term)) < 2 {
+		return ""
+	}
+	return term
+}
+
+// citeKeyInfo 为建议生成关键信息引用标记：建议中出现某条关键信息的值（忽略大小写和全半角）即视为引用了该信息，
+// 来源消息取对话中最近一条包含该值的消息
+func (e *Engine) citeKeyInfo(conversationID uint, suggestions []string) ([]models.KeyInfoCitation, error) {
+	var summary models.Summary
+	if err := e.db.Where("conversation_id = ?", conversationID).Limit(1).Find(&summary).Error; err != nil {
+		return nil, fmt.Errorf("查询摘要失败: %w", err)
+	}
+	if summary.KeyInfo == "" || summary.KeyInfo == "[]" {
+		return nil, nil
+	}
+
+	var items []map[string]interface{}
+	if err := json.Unmarshal([]byte(summary.KeyInfo), &items); err != nil {
+		logrus.WithError(err).Warn("解析关键信息失败")
+		return nil, nil
+	}
+
+	var citations []models.KeyInfoCitation
+	var terms []string
+	for i, suggestion := range suggestions {
+		for _, item := range items {
+			term := keyInfoTerm(item)
+			if term == "" || len(textutil.MatchKeywords(suggestion, []string{term})) == 0 {
+				continue
+			}
+			citations = append(citations, models.KeyInfoCitation{Suggestion: i, KeyInfo: item})
+			terms = append(terms, term)
+		}
+	}
+	if len(citations) == 0 {
+		return nil, nil
+	}
+
+	var messages []models.Message
+	if err := e.db.Where("conversation_id = ?", conversationID).
+		Scopes(models.ExcludeDrafts).
+		Order("created_at DESC, id DESC").
+		Limit(citationScanMessages).
+		Find(&messages).Error; err != nil {
+		return nil, fmt.Errorf("查询消息失败: %w", err)
+	}
+
+	sources := make(map[string]uint, len(terms))
+	for i := range citations {
+		term := terms[i]
+		id, ok := sources[term]
+		if !ok {
+			for _, msg := range messages {
+				if len(textutil.MatchKeywords(msg.Content, []string{term})) > 0 {
+					id = msg.ID
+					break
+				}
+			}
+			sources[term] = id
+		}
+		citations[i].SourceMessageID = id
+	}
+	return citations, nil
+}
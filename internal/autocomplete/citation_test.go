@@ -0,0 +1,49 @@
+package autocomplete
+
+import (
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// 建议中出现关键信息的值时标注引用，来源为对话中最近一条包含该值的消息；隐私模式不标注
+func TestGetSuggestionsCitesKeyInfo(t *testing.T) {
+	mock := &testutil.MockLLM{Suggestions: []string{"那就老地方海底捞见", "我再看看"}}
+	e, db := newTestEngine(t, &config.AutocompleteConfig{CiteKeyInfo: true}, mock)
+	conversation := testutil.CreateConversation(t, db, "conv-cite",
+		models.Message{SenderID: "bob", Content: "上次的海底捞不错"},
+		models.Message{SenderID: "alice", Content: "周五还去海底捞吧"},
+		models.Message{SenderID: "bob", Content: "几点"},
+	)
+	if err := db.Create(&models.Summary{
+		ConversationID: conversation.ID,
+		Prompt:         "两人约周五吃饭",
+		KeyInfo:        `[{"type":"place","key":"约饭地点","value":"海底捞"},{"type":"time","key":"约饭时间","value":"周五"}]`,
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+	var source models.Message
+	db.Where("conversation_id = ? AND sequence = ?", conversation.ID, 2).First(&source)
+
+	resp, err := e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-cite", SenderID: "alice", Input: "七点吧"})
+	if err != nil {
+		t.Fatalf("获取补全建议失败: %v", err)
+	}
+	if len(resp.Citations) != 1 {
+		t.Fatalf("引用标记为 %+v，期望 1 条", resp.Citations)
+	}
+	citation := resp.Citations[0]
+	if citation.Suggestion != 0 || citation.KeyInfo["key"] != "约饭地点" || citation.SourceMessageID != source.ID {
+		t.Errorf("引用标记为 %+v，期望建议0引用约饭地点，来源消息 %d", citation, source.ID)
+	}
+
+	resp, err = e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-cite", SenderID: "alice", Input: "七点吧", PrivacyMode: true})
+	if err != nil {
+		t.Fatalf("获取补全建议失败: %v", err)
+	}
+	if len(resp.Citations) != 0 {
+		t.Errorf("隐私模式不应标注引用: %+v", resp.Citations)
+	}
+}
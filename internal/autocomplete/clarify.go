@@ -0,0 +1,79 @@
+package autocomplete
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"ChatRecommend/internal/models"
+	"gorm.io/gorm"
+)
+
+// fillerRunes 单独出现时不表达具体意思的语气词
+const fillerRunes = "嗯恩哦噢喔啊呃额唔哈呵嘿诶欸"
+
+// fillerWordPattern 英文语气词（hmm、emm、uh、oh、ok 等，字母可重复）
+var fillerWordPattern = regexp.MustCompile(`^(?:h*m+|e+m+|u+[mh]+|[oa]+h+|ok(?:ay)?|lol)$`)
+
+// clarificationQuestion 澄清提示的内容
+const clarificationQuestion = "输入的内容太少，对话近期也没有可参考的消息，暂时无法给出有意义的建议。可以多输入几个字，或说明想表达什么（如同意、拒绝、追问）。"
+
+// vagueInput 判断输入是否模糊：去掉标点、空白和 emoji 后不超过 maxChars 个字符，或只由语气词组成
+func vagueInput(input string, maxChars int) bool {
+	var core []rune
+	for _, r := range input {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			core = append(core, unicode.ToLower(r))
+		}
+	}
+	if len(core) <= maxChars {
+		return true
+	}
+	if fillerWordPattern.MatchString(string(core)) {
+		return true
+	}
+	for _, r := range core {
+		if !strings.ContainsRune(fillerRunes, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// clarify 判断是否应以澄清代替补全：开启澄清、续写模式下输入模糊，且对话在最近 context_window_minutes 内的消息少于
+// min_context_messages 条（对话不存在时视为没有消息）。需要澄清时返回澄清响应，否则返回 nil
+func (e *Engine) clarify(req *models.AutocompleteRequest) (*models.AutocompleteResponse, error) {
+	cfg := e.config.Clarification
+	if !cfg.Enabled || req.Input == "" || rewriteMode(req) || !vagueInput(req.Input, cfg.MaxVagueChars) {
+		return nil, nil
+	}
+
+	var recent int64
+	var conversation models.Conversation
+	err := e.db.Where("conversation_id = ?", req.ConversationID).First(&conversation).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, fmt.Errorf("查询对话失败: %w", err)
+	}
+	if err == nil {
+		since := time.Now().Add(-time.Duration(cfg.ContextWindowMinutes) * time.Minute)
+		if err := e.db.Model(&models.Message{}).Scopes(models.ExcludeDrafts).
+			Where("conversation_id = ? AND created_at >= ?", conversation.ID, since).
+			Count(&recent).Error; err != nil {
+			return nil, fmt.Errorf("统计近期消息失败: %w", err)
+		}
+	}
+	if recent >= int64(cfg.MinContextMessages) {
+		return nil, nil
+	}
+
+	return &models.AutocompleteResponse{
+		Type:        models.ResponseTypeClarification,
+		Suggestions: []string{},
+		Clarification: &models.Clarification{
+			Reason:   models.ClarificationVagueInput,
+			Question: clarificationQuestion,
+		},
+	}, nil
+}
@@ -0,0 +1,92 @@
+package autocomplete
+
+import (
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// 去掉标点和 emoji 后过短或只由语气词组成的输入视为模糊
+func TestVagueInput(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"嗯", true},
+		{"嗯嗯嗯。。。", true},
+		{"哦哦哦哦", true},
+		{"Hmmm", true},
+		{"ok!", true},
+		{"好的\U0001F600", true},
+		{"明天见", false},
+		{"hello", false},
+	}
+	for _, tt := range tests {
+		if got := vagueInput(tt.input, 2); got != tt.want {
+			t.Errorf("vagueInput(%q) = %v，期望 %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+// 模糊输入在对话缺少近期消息时返回澄清提示且不调用大模型；上下文充足或输入明确时照常补全
+func TestGetSuggestionsClarification(t *testing.T) {
+	mock := &testutil.MockLLM{Suggestions: []string{"好的，没问题"}}
+	e, db := newTestEngine(t, &config.AutocompleteConfig{
+		MinTriggerLength: 2,
+		Clarification:    config.ClarificationConfig{Enabled: true, MaxVagueChars: 2, ContextWindowMinutes: 30, MinContextMessages: 2},
+	}, mock)
+	testutil.CreateConversation(t, db, "conv-quiet", models.Message{SenderID: "bob", Content: "在吗"})
+	createTestConversation(t, db, "conv-active")
+
+	resp, err := e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-quiet", SenderID: "alice", Input: "嗯"})
+	if err != nil {
+		t.Fatalf("获取补全建议失败: %v", err)
+	}
+	if resp.Type != models.ResponseTypeClarification || resp.Clarification == nil || resp.Clarification.Reason != models.ClarificationVagueInput {
+		t.Fatalf("期望返回澄清提示，实际为 %+v", resp)
+	}
+	if len(resp.Suggestions) != 0 || mock.Calls() != 0 {
+		t.Errorf("澄清时不应生成建议，建议 %v，大模型调用 %d 次", resp.Suggestions, mock.Calls())
+	}
+
+	resp, err = e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-missing", SenderID: "alice", Input: "嗯嗯"})
+	if err != nil {
+		t.Fatalf("获取补全建议失败: %v", err)
+	}
+	if resp.Type != models.ResponseTypeClarification {
+		t.Errorf("对话不存在时应视为没有消息并返回澄清，实际类型 %q", resp.Type)
+	}
+
+	resp, err = e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-active", SenderID: "alice", Input: "嗯嗯"})
+	if err != nil {
+		t.Fatalf("获取补全建议失败: %v", err)
+	}
+	if resp.Type != models.ResponseTypeCompletion || resp.Clarification != nil || len(resp.Suggestions) != 1 {
+		t.Errorf("近期消息充足时应照常补全: %+v", resp)
+	}
+
+	resp, err = e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-quiet", SenderID: "alice", Input: "明天见面吧"})
+	if err != nil {
+		t.Fatalf("获取补全建议失败: %v", err)
+	}
+	if resp.Type != models.ResponseTypeCompletion || len(resp.Suggestions) != 1 {
+		t.Errorf("输入明确时应照常补全: %+v", resp)
+	}
+}
+
+// 未开启澄清时模糊输入按最小触发长度处理，类型仍为 completion
+func TestGetSuggestionsClarificationDisabled(t *testing.T) {
+	mock := &testutil.MockLLM{Suggestions: []string{"好的"}}
+	e, db := newTestEngine(t, &config.AutocompleteConfig{MinTriggerLength: 2}, mock)
+	testutil.CreateConversation(t, db, "conv-quiet", models.Message{SenderID: "bob", Content: "在吗"})
+
+	resp, err := e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-quiet", SenderID: "alice", Input: "嗯"})
+	if err != nil {
+		t.Fatalf("获取补全建议失败: %v", err)
+	}
+	if resp.Type != models.ResponseTypeCompletion || resp.Clarification != nil || len(resp.Suggestions) != 0 {
+		t.Errorf("未开启澄清时不应返回澄清提示: %+v", resp)
+	}
+}
@@ -0,0 +1,39 @@
+package autocomplete
+
+import (
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// 输入法组字中的请求返回空建议且不调用大模型，组字结束后用同一 request_id 重试仍会生成建议
+func TestGetSuggestionsSkipsComposingInput(t *testing.T) {
+	mock := &testutil.MockLLM{Suggestions: []string{"明天见", "好的"}}
+	e, db := newTestEngine(t, &config.AutocompleteConfig{RequestIDTTLSeconds: 60, DebounceMs: 10}, mock)
+	createTestConversation(t, db, "conv-composing")
+
+	req := &models.AutocompleteRequest{ConversationID: "conv-composing", SenderID: "alice", Input: "mingtian", Composing: true, RequestID: "req-1"}
+	resp, err := e.GetSuggestions(req)
+	if err != nil {
+		t.Fatalf("获取补全建议失败: %v", err)
+	}
+	if len(resp.Suggestions) != 0 || resp.Suggestions == nil {
+		t.Errorf("组字中应返回空建议列表，实际 %#v", resp.Suggestions)
+	}
+	if resp, err = e.GetSuggestionsWithDebounce(req); err != nil || len(resp.Suggestions) != 0 {
+		t.Errorf("去抖请求组字中应直接返回空建议，实际 %+v (%v)", resp, err)
+	}
+	if calls := mock.Calls(); calls != 0 {
+		t.Fatalf("组字中调用了大模型 %d 次", calls)
+	}
+
+	resp, err = e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-composing", SenderID: "alice", Input: "明天", RequestID: "req-1"})
+	if err != nil {
+		t.Fatalf("获取补全建议失败: %v", err)
+	}
+	if len(resp.Suggestions) == 0 || mock.Calls() != 1 {
+		t.Errorf("组字结束后应生成建议，实际 %v，大模型调用 %d 次", resp.Suggestions, mock.Calls())
+	}
+}
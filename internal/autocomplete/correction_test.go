@@ -0,0 +1,36 @@
+package autocomplete
+
+import (
+	"strings"
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// 含拼音串的输入在上下文中附带纠错提示，交给大模型的输入保持原样
+func TestGetSuggestionsWithPinyinInput(t *testing.T) {
+	mock := &testutil.MockLLM{Suggestions: []string{"去吃火锅"}}
+	e, db := newTestEngine(t, &config.AutocompleteConfig{InputCorrection: true}, mock)
+	createTestConversation(t, db, "conv-pinyin")
+
+	if _, err := e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-pinyin", SenderID: "alice", Input: "我们mingtian"}); err != nil {
+		t.Fatalf("获取补全建议失败: %v", err)
+	}
+	if !strings.Contains(mock.LastContext, "=== 输入纠错提示 ===") || !strings.Contains(mock.LastContext, `"mingtian" 可能是未转换成汉字的拼音（ming tian）`) {
+		t.Errorf("上下文缺少拼音纠错提示: %s", mock.LastContext)
+	}
+	if mock.LastInput != "我们mingtian" {
+		t.Errorf("输入不应被改写，得到 %q", mock.LastInput)
+	}
+
+	// 关闭纠错时不注入提示
+	e.config.InputCorrection = false
+	if _, err := e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-pinyin", SenderID: "alice", Input: "我们mingtian去"}); err != nil {
+		t.Fatalf("获取补全建议失败: %v", err)
+	}
+	if strings.Contains(mock.LastContext, "输入纠错提示") {
+		t.Errorf("关闭纠错时不应注入提示: %s", mock.LastContext)
+	}
+}
@@ -0,0 +1,207 @@
+package autocomplete
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/llm"
+	"github.com/sirupsen/logrus"
+)
+
+// 降级级别
+const (
+	// DegradeNone 正常
+	DegradeNone = "none"
+	// DegradeReduced 减少建议数并缩短 max_tokens
+	DegradeReduced = "reduced"
+	// DegradeLocal 暂停调用大模型，只使用本地快捷补全规则；每隔 probe_interval_seconds 放行一个探测请求
+	DegradeLocal = "local"
+)
+
+// degradeLevels 级别从低到高
+var degradeLevels = []string{DegradeNone, DegradeReduced, DegradeLocal}
+
+// latencySample 一次大模型调用的耗时
+type latencySample struct {
+	at       time.Time
+	duration time.Duration
+}
+
+// DegradationStatus 降级状态（用于指标）
+type DegradationStatus struct {
+	Level   string    `json:"level"`
+	P95Ms   float64   `json:"p95_ms"`
+	Samples int       `json:"samples"`
+	Since   time.Time `json:"since"`
+	// 降级切换次数
+	Transitions int64 `json:"transitions"`
+}
+
+// degrader 基于滑动窗口P95延迟的降级控制器：延迟升高时逐级降级，恢复到 recover_p95_ms 以下后升级（滞回避免来回切换）
+type degrader struct {
+	cfg *config.DegradationConfig
+
+	mu          sync.Mutex
+	samples     []latencySample
+	level       int
+	since       time.Time
+	lastProbe   time.Time
+	transitions int64
+}
+
+func newDegrader(cfg *config.DegradationConfig) *degrader {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &degrader{cfg: cfg, since: time.Now()}
+}
+
+// Level 当前降级级别
+func (d *degrader) Level() string {
+	if d == nil {
+		return DegradeNone
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return degradeLevels[d.level]
+}
+
+// allowLLM 判断本次请求能否调用大模型：只用本地回退时，每隔探测间隔放行一个请求以检测延迟是否恢复
+func (d *degrader) allowLLM() bool {
+	if d == nil {
+		return true
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if degradeLevels[d.level] != DegradeLocal {
+		return true
+	}
+	now := time.Now()
+	if now.Sub(d.lastProbe) < time.Duration(d.cfg.ProbeIntervalSeconds)*time.Second {
+		return false
+	}
+	d.lastProbe = now
+	return true
+}
+
+// record 记录一次大模型调用耗时（失败的调用同样记录，超时正是需要降级的情况）并重新评估级别
+func (d *degrader) record(duration time.Duration) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	d.samples = append(d.samples, latencySample{at: now, duration: duration})
+	d.prune(now)
+
+	// 本地回退时只有探测请求，样本不足以计算P95，按单次探测结果判断：探测够快则升一级并清空旧样本重新统计
+	if degradeLevels[d.level] == DegradeLocal {
+		if duration < time.Duration(d.cfg.RecoverP95Ms)*time.Millisecond {
+			d.samples = d.samples[:0]
+			d.setLevel(1, now, duration)
+		}
+		return
+	}
+
+	if len(d.samples) < d.cfg.MinSamples {
+		return
+	}
+	p95 := d.p95()
+	target := d.level
+	switch {
+	case p95 >= time.Duration(d.cfg.LocalP95Ms)*time.Millisecond:
+		target = 2
+	case p95 >= time.Duration(d.cfg.DegradeP95Ms)*time.Millisecond:
+		target = max(d.level, 1)
+	case p95 < time.Duration(d.cfg.RecoverP95Ms)*time.Millisecond:
+		target = 0
+	}
+	if target != d.level {
+		d.setLevel(target, now, p95)
+	}
+}
+
+// setLevel 切换级别（需持有锁）
+func (d *degrader) setLevel(level int, now time.Time, latency time.Duration) {
+	from := degradeLevels[d.level]
+	d.level = level
+	d.since = now
+	d.transitions++
+	d.lastProbe = now
+
+	entry := logrus.WithFields(logrus.Fields{
+		"from":       from,
+		"to":         degradeLevels[level],
+		"latency_ms": latency.Milliseconds(),
+	})
+	if level > 0 {
+		entry.Warn("大模型延迟过高，补全已降级")
+	} else {
+		entry.Info("大模型延迟恢复，补全已恢复正常")
+	}
+}
+
+// prune 丢弃窗口外的样本（需持有锁）
+func (d *degrader) prune(now time.Time) {
+	cutoff := now.Add(-time.Duration(d.cfg.WindowSeconds) * time.Second)
+	i := 0
+	for i < len(d.samples) && d.samples[i].at.Before(cutoff) {
+		i++
+	}
+	d.samples = d.samples[i:]
+}
+
+// p95 窗口内耗时的P95（需持有锁，样本非空）
+func (d *degrader) p95() time.Duration {
+	durations := make([]time.Duration, len(d.samples))
+	for i, s := range d.samples {
+		durations[i] = s.duration
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	idx := (len(durations)*95+99)/100 - 1
+	return durations[max(idx, 0)]
+}
+
+// status 当前降级状态
+func (d *degrader) status() DegradationStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.prune(time.Now())
+
+	status := DegradationStatus{
+		Level:       degradeLevels[d.level],
+		Samples:     len(d.samples),
+		Since:       d.since,
+		Transitions: d.transitions,
+	}
+	if len(d.samples) > 0 {
+		status.P95Ms = float64(d.p95().Microseconds()) / 1000
+	}
+	return status
+}
+
+// DegradationStatus 获取补全降级状态，未启用降级时返回 nil
+func (e *Engine) DegradationStatus() *DegradationStatus {
+	if e.degrade == nil {
+		return nil
+	}
+	status := e.degrade.status()
+	return &status
+}
+
+// applyReduced 处于 reduced 级别时减少建议数并缩短 max_tokens，返回是否做了调整
+func (d *degrader) applyReduced(opts *llm.CompleteOptions) bool {
+	if d == nil || d.Level() != DegradeReduced {
+		return false
+	}
+	opts.SuggestionCount = min(opts.SuggestionCount, d.cfg.ReducedSuggestions)
+	if opts.MaxTokens == nil || *opts.MaxTokens > d.cfg.ReducedMaxTokens {
+		maxTokens := d.cfg.ReducedMaxTokens
+		opts.MaxTokens = &maxTokens
+	}
+	return true
+}
@@ -0,0 +1,118 @@
+package autocomplete
+
+import (
+	"testing"
+	"time"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/llm"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+func testDegradationConfig() *config.DegradationConfig {
+	return &config.DegradationConfig{
+		Enabled:              true,
+		WindowSeconds:        60,
+		MinSamples:           5,
+		DegradeP95Ms:         100,
+		LocalP95Ms:           500,
+		RecoverP95Ms:         50,
+		ProbeIntervalSeconds: 60,
+		ReducedSuggestions:   1,
+		ReducedMaxTokens:     32,
+	}
+}
+
+// 窗口内P95升高时逐级降级，本地回退只按间隔放行探测请求，探测够快后逐级恢复
+func TestDegraderTransitions(t *testing.T) {
+	d := newDegrader(testDegradationConfig())
+	record := func(duration time.Duration, n int) {
+		for i := 0; i < n; i++ {
+			d.record(duration)
+		}
+	}
+
+	record(200*time.Millisecond, 4)
+	if level := d.Level(); level != DegradeNone {
+		t.Fatalf("样本不足时级别为 %s，期望 none", level)
+	}
+	record(10*time.Millisecond, 1)
+	if level := d.Level(); level != DegradeReduced {
+		t.Fatalf("P95超过降级阈值时级别为 %s，期望 reduced", level)
+	}
+	opts := &llm.CompleteOptions{SuggestionCount: 3}
+	if !d.applyReduced(opts) || opts.SuggestionCount != 1 || opts.MaxTokens == nil || *opts.MaxTokens != 32 {
+		t.Errorf("reduced 级别的补全参数为 %+v", opts)
+	}
+	// 处于恢复阈值和降级阈值之间时保持 reduced
+	d.samples = d.samples[:0]
+	record(80*time.Millisecond, 5)
+	if level := d.Level(); level != DegradeReduced {
+		t.Fatalf("P95在滞回区间时级别为 %s，期望保持 reduced", level)
+	}
+
+	record(time.Second, 1)
+	if level := d.Level(); level != DegradeLocal {
+		t.Fatalf("P95超过本地回退阈值时级别为 %s，期望 local", level)
+	}
+	if d.allowLLM() {
+		t.Error("刚切换到 local 时不应放行请求")
+	}
+	d.lastProbe = time.Now().Add(-2 * time.Minute)
+	if !d.allowLLM() || d.allowLLM() {
+		t.Error("探测间隔到期后应只放行一个探测请求")
+	}
+
+	record(600*time.Millisecond, 1)
+	if level := d.Level(); level != DegradeLocal {
+		t.Fatalf("慢探测后级别为 %s，期望保持 local", level)
+	}
+	record(20*time.Millisecond, 1)
+	if level := d.Level(); level != DegradeReduced {
+		t.Fatalf("快探测后级别为 %s，期望升为 reduced", level)
+	}
+	record(20*time.Millisecond, 5)
+	if level := d.Level(); level != DegradeNone {
+		t.Fatalf("P95恢复后级别为 %s，期望 none", level)
+	}
+
+	status := d.status()
+	if status.Level != DegradeNone || status.Samples != 5 || status.Transitions != 4 || status.P95Ms != 20 {
+		t.Errorf("降级状态为 %+v", status)
+	}
+	// 窗口外的样本不再计入
+	for i := range d.samples {
+		d.samples[i].at = time.Now().Add(-2 * time.Minute)
+	}
+	if status := d.status(); status.Samples != 0 {
+		t.Errorf("窗口外样本未丢弃: %+v", status)
+	}
+}
+
+// 降级为本地回退时补全不调用大模型并标明降级；未启用时不降级
+func TestGetSuggestionsDegradesToLocal(t *testing.T) {
+	mock := &testutil.MockLLM{Suggestions: []string{"好的", "明天见"}}
+	e, db := newTestEngine(t, &config.AutocompleteConfig{Degradation: *testDegradationConfig()}, mock)
+	createTestConversation(t, db, "conv-degrade")
+	req := &models.AutocompleteRequest{ConversationID: "conv-degrade", SenderID: "alice", Input: "那就"}
+
+	for i := 0; i < 5; i++ {
+		e.degrade.record(time.Second)
+	}
+	resp, err := e.GetSuggestions(req)
+	if err != nil {
+		t.Fatalf("获取补全建议失败: %v", err)
+	}
+	if resp.Degraded != DegradeLocal || len(resp.Suggestions) != 0 || mock.Calls() != 0 {
+		t.Errorf("本地回退时响应为 %+v，大模型调用 %d 次", resp, mock.Calls())
+	}
+	if status := e.DegradationStatus(); status == nil || status.Level != DegradeLocal {
+		t.Errorf("降级状态为 %+v，期望 local", status)
+	}
+
+	plain, _ := newTestEngine(t, &config.AutocompleteConfig{}, mock)
+	if plain.DegradationStatus() != nil {
+		t.Error("未启用降级时不应返回降级状态")
+	}
+}
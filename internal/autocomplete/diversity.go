@@ -0,0 +1,70 @@
+package autocomplete
+
+import (
+	"strings"
+
+	"ChatRecommend/internal/models"
+)
+
+// removeSimilar 剔除与已保留建议过于相似（Jaccard相似度 >= threshold）的建议，threshold<=0 时只去除完全相同的建议
+func removeSimilar(suggestions []models.Suggestion, threshold float64) []models.Suggestion {
+	kept := make([]models.Suggestion, 0, len(suggestions))
+	keptGrams := make([]map[string]bool, 0, len(suggestions))
+
+	for _, suggestion := range suggestions {
+		normalized := strings.TrimSpace(suggestion.Text)
+		if normalized == "" {
+			continue
+		}
+
+		grams := bigrams(normalized)
+		duplicate := false
+		for i, other := range keptGrams {
+			if normalized == strings.TrimSpace(kept[i].Text) {
+				duplicate = true
+				break
+			}
+			if threshold > 0 && jaccard(grams, other) >= threshold {
+				duplicate = true
+				break
+			}
+		}
+		if duplicate {
+			continue
+		}
+
+		kept = append(kept, suggestion)
+		keptGrams = append(keptGrams, grams)
+	}
+
+	return kept
+}
+
+// bigrams 按字符二元组切分文本（单字符文本返回该字符本身）
+func bigrams(text string) map[string]bool {
+	runes := []rune(strings.ToLower(text))
+	grams := make(map[string]bool, len(runes))
+	if len(runes) < 2 {
+		grams[string(runes)] = true
+		return grams
+	}
+	for i := 0; i < len(runes)-1; i++ {
+		grams[string(runes[i:i+2])] = true
+	}
+	return grams
+}
+
+// jaccard 计算两个集合的Jaccard相似度
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	intersection := 0
+	for gram := range a {
+		if b[gram] {
+			intersection++
+		}
+	}
+	union := len(a) + len(b) - intersection
+	return float64(intersection) / float64(union)
+}
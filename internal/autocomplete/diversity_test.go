@@ -0,0 +1,51 @@
+package autocomplete
+
+import (
+	"errors"
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+)
+
+func TestRemoveSimilar(t *testing.T) {
+	suggestions := textSuggestions([]string{
+		"好的，明天见",
+		"好的，明天见！",
+		"好的，明天见",
+		"",
+		"我可能去不了",
+	})
+
+	got := suggestionTexts(removeSimilar(suggestions, 0.6))
+	want := []string{"好的，明天见", "我可能去不了"}
+	if len(got) != len(want) {
+		t.Fatalf("结果为 %v，期望 %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("结果为 %v，期望 %v", got, want)
+		}
+	}
+
+	// threshold<=0 时只去除完全相同的建议
+	got = suggestionTexts(removeSimilar(textSuggestions([]string{"好的，明天见", "好的，明天见！", "好的，明天见"}), 0))
+	if len(got) != 2 {
+		t.Fatalf("结果为 %v，期望只去掉完全相同的建议", got)
+	}
+}
+
+func TestValidateRequestLimitsMaxSuggestions(t *testing.T) {
+	e := &Engine{config: &config.AutocompleteConfig{MaxRequestSuggestions: 5}}
+	for _, n := range []int{-1, 6, 1000} {
+		err := e.validateRequest(&models.AutocompleteRequest{MaxSuggestions: n})
+		if !errors.Is(err, ErrInvalidRequest) {
+			t.Errorf("max_suggestions=%d 应被拒绝，实际: %v", n, err)
+		}
+	}
+	for _, n := range []int{0, 5} {
+		if err := e.validateRequest(&models.AutocompleteRequest{MaxSuggestions: n}); err != nil {
+			t.Errorf("max_suggestions=%d 应通过，实际: %v", n, err)
+		}
+	}
+}
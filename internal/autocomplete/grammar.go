@@ -0,0 +1,192 @@
+package autocomplete
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"ChatRecommend/internal/models"
+)
+
+// GrammarChecker 建议的语法检查器，返回修正后的文本和无法自动修正的问题数。
+// 内置的 RuleGrammarChecker 只处理英文常见错误，可以通过 Engine.SetGrammarChecker 换成调用大模型等实现
+type GrammarChecker interface {
+	Check(text string) (corrected string, issues int)
+}
+
+// SetGrammarChecker 设置建议的语法检查器（需在处理请求前调用），nil 表示关闭语法检查
+func (e *Engine) SetGrammarChecker(checker GrammarChecker) {
+	e.grammar = checker
+}
+
+// checkGrammar 检查英文建议：修正能自动修正的错误，仍有问题的建议排到后面（降权），其余顺序不变；非英文建议不检查
+func checkGrammar(checker GrammarChecker, suggestions []models.Suggestion) []models.Suggestion {
+	if checker == nil {
+		return suggestions
+	}
+	issues := make([]int, len(suggestions))
+	for i, suggestion := range suggestions {
+		if !matchesLanguage(suggestion.Text, models.LanguageEn) || countScripts(suggestion.Text).latin == 0 {
+			continue
+		}
+		suggestions[i].Text, issues[i] = checker.Check(suggestion.Text)
+	}
+
+	sorted := make([]models.Suggestion, 0, len(suggestions))
+	var demoted []models.Suggestion
+	for i, suggestion := range suggestions {
+		if issues[i] > 0 {
+			demoted = append(demoted, suggestion)
+		} else {
+			sorted = append(sorted, suggestion)
+		}
+	}
+	return append(sorted, demoted...)
+}
+
+// RuleGrammarChecker 基于规则的英文语法检查：修正重复单词、a/an 误用、小写的 i、缺少撇号的缩写、
+// he/she/it don't、could of 和标点前多余的空格；连续两个冠词（如 "the a"）无法判断保留哪个，只计为问题
+type RuleGrammarChecker struct{}
+
+var (
+	wordPattern          = regexp.MustCompile(`[A-Za-z]+(?:'[A-Za-z]+)?`)
+	articlePattern       = regexp.MustCompile(`\b([Aa]n?) ([A-Za-z]+)`)
+	lowercaseIPattern    = regexp.MustCompile(`\bi('m|'ll|'ve|'d)?\b`)
+	contractionPattern   = regexp.MustCompile(`(?i)\b(dont|doesnt|didnt|cant|isnt|wasnt|werent|arent|couldnt|shouldnt|wouldnt|havent|hasnt|im|ive|youre|theyre)\b`)
+	thirdPersonDont      = regexp.MustCompile(`(?i)\b(he|she|it) don't\b`)
+	modalOfPattern       = regexp.MustCompile(`(?i)\b(could|should|would|must|might) of\b`)
+	spaceBeforePunct     = regexp.MustCompile(`(\S) +([,!?;])`)
+	spaceBeforePeriod    = regexp.MustCompile(`([A-Za-z]) +\.(\s|$)`)
+	doubleArticlePattern = regexp.MustCompile(`(?i)\b(a|an|the) (a|an|the)\b`)
+)
+
+// contractions 缺少撇号的常见缩写
+var contractions = map[string]string{
+	"dont": "don't", "doesnt": "doesn't", "didnt": "didn't", "cant": "can't", "isnt": "isn't",
+	"wasnt": "wasn't", "werent": "weren't", "arent": "aren't", "couldnt": "couldn't", "shouldnt": "shouldn't",
+	"wouldnt": "wouldn't", "havent": "haven't", "hasnt": "hasn't", "im": "I'm", "ive": "I've",
+	"youre": "you're", "theyre": "they're",
+}
+
+// 允许合法重复的单词（如 "had had"、"that that"）
+var allowedRepeats = map[string]bool{"had": true, "that": true}
+
+// 以元音字母开头但读作辅音（用 a），以及以辅音字母开头但读作元音（用 an）的常见前缀
+var (
+	consonantSoundPrefixes = []string{"uni", "use", "usu", "uti", "eu", "one", "once"}
+	vowelSoundPrefixes     = []string{"hour", "honest", "honor", "honour", "heir"}
+)
+
+// Check 实现 GrammarChecker
+func (RuleGrammarChecker) Check(text string) (string, int) {
+	text = removeRepeatedWords(text)
+	text = fixArticles(text)
+	text = replaceLowercaseI(text)
+	text = contractionPattern.ReplaceAllStringFunc(text, func(word string) string {
+		fixed := contractions[strings.ToLower(word)]
+		if fixed[0] != 'I' && unicode.IsUpper(rune(word[0])) {
+			fixed = strings.ToUpper(fixed[:1]) + fixed[1:]
+		}
+		return fixed
+	})
+	text = thirdPersonDont.ReplaceAllString(text, "$1 doesn't")
+	text = modalOfPattern.ReplaceAllString(text, "$1 have")
+	text = spaceBeforePunct.ReplaceAllString(text, "$1$2")
+	text = spaceBeforePeriod.ReplaceAllString(text, "$1.$2")
+	return text, len(doubleArticlePattern.FindAllStringIndex(text, -1))
+}
+
+// removeRepeatedWords 去掉紧接着重复的单词（中间只有空格，忽略大小写）
+func removeRepeatedWords(text string) string {
+	var b strings.Builder
+	last := 0
+	prevWord, prevEnd := "", -1
+	for _, loc := range wordPattern.FindAllStringIndex(text, -1) {
+		word := strings.ToLower(text[loc[0]:loc[1]])
+		if prevEnd >= 0 && word == prevWord && !allowedRepeats[word] && onlySpaces(text[prevEnd:loc[0]]) {
+			// 去掉这个单词及其前面的空格
+			b.WriteString(text[last:prevEnd])
+			last, prevEnd = loc[1], loc[1]
+			continue
+		}
+		prevWord, prevEnd = word, loc[1]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// onlySpaces 判断是否为非空的纯空格串
+func onlySpaces(s string) bool {
+	return s != "" && strings.Trim(s, " ") == ""
+}
+
+// fixArticles 修正 a/an。大写的 A/An 只在句首处理，句中的大写 A 多为编号或字母（如 "plan A is"）
+func fixArticles(text string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range articlePattern.FindAllStringSubmatchIndex(text, -1) {
+		article := text[loc[2]:loc[3]]
+		if unicode.IsUpper(rune(article[0])) && !sentenceStart(text[:loc[0]]) {
+			continue
+		}
+		b.WriteString(text[last:loc[0]])
+		b.WriteString(fixArticle(article, text[loc[4]:loc[5]]))
+		last = loc[1]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// sentenceStart 判断 prefix 之后是否为句首：prefix 为空白，或以句末标点加空白结尾
+func sentenceStart(prefix string) bool {
+	prefix = strings.TrimRight(prefix, " ")
+	return prefix == "" || strings.ContainsAny(prefix[len(prefix)-1:], ".!?")
+}
+
+// fixArticle 按单词的读音返回修正后的"冠词 单词"；全大写的缩写词读音无法判断，不处理
+func fixArticle(article, word string) string {
+	match := article + " " + word
+	if len(word) > 1 && strings.ToUpper(word) == word {
+		return match
+	}
+	lower := strings.ToLower(word)
+	vowelSound := strings.ContainsRune("aeiou", rune(lower[0]))
+	for _, prefix := range consonantSoundPrefixes {
+		if strings.HasPrefix(lower, prefix) {
+			vowelSound = false
+		}
+	}
+	for _, prefix := range vowelSoundPrefixes {
+		if strings.HasPrefix(lower, prefix) {
+			vowelSound = true
+		}
+	}
+
+	want := "a"
+	if vowelSound {
+		want = "an"
+	}
+	if strings.EqualFold(article, want) {
+		return match
+	}
+	if unicode.IsUpper(rune(article[0])) {
+		want = strings.ToUpper(want[:1]) + want[1:]
+	}
+	return want + " " + word
+}
+
+// replaceLowercaseI 把单独的小写 i（及 i'm、i'll 等）改为大写，i.e. 之类的缩写不处理
+func replaceLowercaseI(text string) string {
+	locs := lowercaseIPattern.FindAllStringIndex(text, -1)
+	if len(locs) == 0 {
+		return text
+	}
+	b := []byte(text)
+	for _, loc := range locs {
+		if loc[1] < len(text) && text[loc[1]] == '.' {
+			continue
+		}
+		b[loc[0]] = 'I'
+	}
+	return string(b)
+}
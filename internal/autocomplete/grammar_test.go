@@ -0,0 +1,93 @@
+package autocomplete
+
+import (
+	"strings"
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// 基于规则的语法检查修正常见英文错误，连续冠词只计为问题
+func TestRuleGrammarChecker(t *testing.T) {
+	tests := []struct {
+		text   string
+		want   string
+		issues int
+	}{
+		{"i dont know", "I don't know", 0},
+		{"see you you tomorrow", "see you tomorrow", 0},
+		{"we had had enough", "we had had enough", 0},
+		{"it is a hour away", "it is an hour away", 0},
+		{"an university and a apple", "a university and an apple", 0},
+		{"A apple a day", "An apple a day", 0},
+		{"plan A is fine", "plan A is fine", 0},
+		{"she don't care", "she doesn't care", 0},
+		{"you could of told me", "you could have told me", 0},
+		{"Sure , see you .", "Sure, see you.", 0},
+		{"Youre right", "You're right", 0},
+		{"i.e. later", "i.e. later", 0},
+		{"take the a train", "take the a train", 1},
+	}
+	for _, tt := range tests {
+		got, issues := RuleGrammarChecker{}.Check(tt.text)
+		if got != tt.want || issues != tt.issues {
+			t.Errorf("Check(%q) = %q, %d，期望 %q, %d", tt.text, got, issues, tt.want, tt.issues)
+		}
+	}
+}
+
+// 开启语法检查后修正英文建议，仍有问题的建议排到后面，中文建议不检查
+func TestGetSuggestionsGrammarCheck(t *testing.T) {
+	mock := &testutil.MockLLM{Suggestions: []string{"take the a train", "i dont know", "好的没问题", "see you you soon"}}
+	e, db := newTestEngine(t, &config.AutocompleteConfig{SuggestionCount: 4, GrammarCheck: true}, mock)
+	createTestConversation(t, db, "conv-grammar")
+
+	resp, err := e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-grammar", SenderID: "alice", Input: "ok"})
+	if err != nil {
+		t.Fatalf("获取补全建议失败: %v", err)
+	}
+	want := []string{"I don't know", "好的没问题", "see you soon", "take the a train"}
+	if strings.Join(resp.Suggestions, "|") != strings.Join(want, "|") {
+		t.Errorf("建议为 %v，期望 %v", resp.Suggestions, want)
+	}
+}
+
+// recordingChecker 记录检查过的文本，所有建议都视为有一个问题
+type recordingChecker struct {
+	checked []string
+}
+
+func (c *recordingChecker) Check(text string) (string, int) {
+	c.checked = append(c.checked, text)
+	return strings.ToUpper(text), 1
+}
+
+// 可以替换语法检查器；未开启语法检查时不处理
+func TestSetGrammarChecker(t *testing.T) {
+	mock := &testutil.MockLLM{Suggestions: []string{"i dont know", "好的"}}
+	e, db := newTestEngine(t, &config.AutocompleteConfig{}, mock)
+	createTestConversation(t, db, "conv-grammar")
+
+	resp, err := e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-grammar", SenderID: "alice", Input: "ok"})
+	if err != nil {
+		t.Fatalf("获取补全建议失败: %v", err)
+	}
+	if resp.Suggestions[0] != "i dont know" {
+		t.Errorf("未开启语法检查时不应修改建议: %v", resp.Suggestions)
+	}
+
+	checker := &recordingChecker{}
+	e.SetGrammarChecker(checker)
+	resp, err = e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-grammar", SenderID: "alice", Input: "okay"})
+	if err != nil {
+		t.Fatalf("获取补全建议失败: %v", err)
+	}
+	if len(checker.checked) != 1 || checker.checked[0] != "i dont know" {
+		t.Errorf("只应检查英文建议，实际检查了 %v", checker.checked)
+	}
+	if len(resp.Suggestions) != 2 || resp.Suggestions[0] != "好的" || resp.Suggestions[1] != "I DONT KNOW" {
+		t.Errorf("有问题的建议应修正后排到后面: %v", resp.Suggestions)
+	}
+}
@@ -0,0 +1,41 @@
+package autocomplete
+
+import (
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/context"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/rules"
+	"ChatRecommend/internal/style"
+	"ChatRecommend/internal/summary"
+	"ChatRecommend/internal/testutil"
+	"gorm.io/gorm"
+)
+
+// newTestEngine 在内存数据库上创建补全引擎，未设置的建议数和上下文参数使用测试默认值
+func newTestEngine(t *testing.T, cfg *config.AutocompleteConfig, llmClient *testutil.MockLLM) (*Engine, *gorm.DB) {
+	return newTestEngineWithRules(t, cfg, llmClient, nil)
+}
+
+// newTestEngineWithRules 同 newTestEngine，带快捷补全规则
+func newTestEngineWithRules(t *testing.T, cfg *config.AutocompleteConfig, llmClient *testutil.MockLLM, ruleMatcher *rules.Matcher) (*Engine, *gorm.DB) {
+	t.Helper()
+	if cfg.SuggestionCount == 0 {
+		cfg.SuggestionCount = 3
+	}
+	db := testutil.NewDB(t)
+	summaryMgr := summary.NewManager(db, &config.SummaryConfig{}, &config.PromptConfig{}, llmClient, nil)
+	styleMgr := style.NewManager(db, &config.StyleConfig{}, nil)
+	contextMgr := context.NewManager(db, &config.ContextConfig{RecentMessagesCount: 10, MaxContextTokens: 4000, ProfileInjection: true}, &config.PromptConfig{}, summaryMgr, styleMgr)
+	return NewEngine(db, cfg, contextMgr, llmClient, ruleMatcher), db
+}
+
+// createTestConversation 创建带两条消息的对话
+func createTestConversation(t *testing.T, db *gorm.DB, conversationID string) models.Conversation {
+	t.Helper()
+	return testutil.CreateConversation(t, db, conversationID,
+		models.Message{SenderID: "alice", Content: "明天一起吃饭吗"},
+		models.Message{SenderID: "bob", Content: "好啊，几点"},
+	)
+}
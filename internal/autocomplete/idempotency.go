@@ -0,0 +1,79 @@
+package autocomplete
+
+import (
+	"sync"
+	"time"
+
+	"ChatRecommend/internal/models"
+)
+
+// idempotentCall 同一 request_id 的一次补全调用
+type idempotentCall struct {
+	done      chan struct{}
+	resp      *models.AutocompleteResponse
+	err       error
+	expiresAt time.Time
+}
+
+// idempotencyStore 按 request_id 去重补全请求：处理中的重复请求等待首次结果，完成后的结果保留一段时间
+type idempotencyStore struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	calls map[string]*idempotentCall
+}
+
+// newIdempotencyStore 创建请求去重存储
+func newIdempotencyStore(ttl time.Duration) *idempotencyStore {
+	return &idempotencyStore{
+		ttl:   ttl,
+		calls: make(map[string]*idempotentCall),
+	}
+}
+
+// idempotencyKey request_id 只在同一对话和发送者内有效，避免不同客户端的ID冲突
+func idempotencyKey(req *models.AutocompleteRequest) string {
+	return req.ConversationID + "\x00" + req.SenderID + "\x00" + req.RequestID
+}
+
+// do 执行 fn，相同 request_id 的请求在有效期内只执行一次；失败的结果不保留，允许客户端重试
+func (s *idempotencyStore) do(req *models.AutocompleteRequest, fn func() (*models.AutocompleteResponse, error)) (*models.AutocompleteResponse, error) {
+	if s == nil || req.RequestID == "" {
+		return fn()
+	}
+
+	key := idempotencyKey(req)
+	now := time.Now()
+
+	s.mu.Lock()
+	s.cleanup(now)
+	if call, ok := s.calls[key]; ok {
+		s.mu.Unlock()
+		<-call.done
+		return call.resp, call.err
+	}
+	call := &idempotentCall{done: make(chan struct{})}
+	s.calls[key] = call
+	s.mu.Unlock()
+
+	call.resp, call.err = fn()
+
+	s.mu.Lock()
+	if call.err != nil {
+		delete(s.calls, key)
+	} else {
+		call.expiresAt = time.Now().Add(s.ttl)
+	}
+	s.mu.Unlock()
+	close(call.done)
+
+	return call.resp, call.err
+}
+
+// cleanup 清理过期的结果（调用方需持有锁）
+func (s *idempotencyStore) cleanup(now time.Time) {
+	for key, call := range s.calls {
+		if !call.expiresAt.IsZero() && now.After(call.expiresAt) {
+			delete(s.calls, key)
+		}
+	}
+}
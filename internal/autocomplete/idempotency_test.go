@@ -0,0 +1,55 @@
+package autocomplete
+
+import (
+	"errors"
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// 有效期内相同 request_id 的重试直接返回首次结果，只调用一次大模型；失败的结果不保留
+func TestGetSuggestionsDeduplicatesRequestID(t *testing.T) {
+	mock := &testutil.MockLLM{Suggestions: []string{"七点见"}}
+	e, db := newTestEngine(t, &config.AutocompleteConfig{RequestIDTTLSeconds: 60}, mock)
+	createTestConversation(t, db, "conv-idem")
+
+	first, err := e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-idem", SenderID: "alice", Input: "晚上", RequestID: "r1"})
+	if err != nil {
+		t.Fatalf("获取补全建议失败: %v", err)
+	}
+	// 重试时即使输入已变化，也返回首次结果
+	mock.Suggestions = []string{"八点见"}
+	retry, err := e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-idem", SenderID: "alice", Input: "晚上八", RequestID: "r1"})
+	if err != nil {
+		t.Fatalf("重试失败: %v", err)
+	}
+	if mock.Calls() != 1 {
+		t.Fatalf("相同 request_id 调用大模型 %d 次，期望 1 次", mock.Calls())
+	}
+	if len(retry.Suggestions) != 1 || retry.Suggestions[0] != first.Suggestions[0] {
+		t.Errorf("重试结果为 %v，期望首次结果 %v", retry.Suggestions, first.Suggestions)
+	}
+
+	// 其他发送者使用相同 request_id 不共享结果
+	if _, err := e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-idem", SenderID: "bob", Input: "晚上八", RequestID: "r1"}); err != nil {
+		t.Fatalf("获取补全建议失败: %v", err)
+	}
+	if mock.Calls() != 2 {
+		t.Fatalf("其他发送者的请求应调用大模型，共调用 %d 次", mock.Calls())
+	}
+
+	mock.Err = errors.New("服务不可用")
+	req := &models.AutocompleteRequest{ConversationID: "conv-idem", SenderID: "alice", Input: "明天", RequestID: "r2"}
+	if _, err := e.GetSuggestions(req); err == nil {
+		t.Fatal("大模型失败时应返回错误")
+	}
+	mock.Err = nil
+	if _, err := e.GetSuggestions(req); err != nil {
+		t.Fatalf("失败后重试应重新调用大模型: %v", err)
+	}
+	if mock.Calls() != 4 {
+		t.Errorf("失败的结果不应保留，共调用大模型 %d 次，期望 4 次", mock.Calls())
+	}
+}
@@ -0,0 +1,47 @@
+package autocomplete
+
+import (
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// 纯空白输入去除首尾空白后不足触发长度，直接返回空建议且不调用大模型
+func TestWhitespaceInputReturnsNoSuggestions(t *testing.T) {
+	mock := &testutil.MockLLM{Suggestions: []string{"好的"}}
+	e, db := newTestEngine(t, &config.AutocompleteConfig{MinTriggerLength: 2}, mock)
+	createTestConversation(t, db, "conv-blank")
+
+	for _, input := range []string{" ", "   ", "\t\n", " 好 "} {
+		resp, err := e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-blank", SenderID: "alice", Input: input})
+		if err != nil {
+			t.Fatalf("输入 %q 获取补全建议失败: %v", input, err)
+		}
+		if len(resp.Suggestions) != 0 {
+			t.Errorf("输入 %q 应返回空建议，实际 %v", input, resp.Suggestions)
+		}
+	}
+	if calls := mock.Calls(); calls != 0 {
+		t.Fatalf("空白输入不应调用大模型，实际调用 %d 次", calls)
+	}
+}
+
+// 输入为空时进入开场白模式，按对话背景生成开场建议
+func TestEmptyInputRequestsOpeners(t *testing.T) {
+	mock := &testutil.MockLLM{Suggestions: []string{"在吗？"}}
+	e, db := newTestEngine(t, &config.AutocompleteConfig{MinTriggerLength: 2}, mock)
+	createTestConversation(t, db, "conv-opener")
+
+	resp, err := e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-opener", SenderID: "alice"})
+	if err != nil {
+		t.Fatalf("获取开场白建议失败: %v", err)
+	}
+	if len(resp.Suggestions) != 1 || resp.Suggestions[0] != "在吗？" {
+		t.Fatalf("开场白建议为 %v", resp.Suggestions)
+	}
+	if mock.LastInput != openerInput {
+		t.Fatalf("开场白模式交给大模型的输入为 %q，期望 %q", mock.LastInput, openerInput)
+	}
+}
@@ -0,0 +1,94 @@
+package autocomplete
+
+import (
+	"unicode"
+
+	"ChatRecommend/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// scriptCounts 文本中各文字的数量：汉字、假名、谚文按字计，拉丁字母按单词计
+// （一个汉字与一个英文单词的分量相近，中文里夹几个英文单词不会被判为英文）
+type scriptCounts struct {
+	han, kana, hangul, latin int
+}
+
+func countScripts(text string) scriptCounts {
+	var counts scriptCounts
+	inWord := false
+	for _, r := range text {
+		isLatin := false
+		switch {
+		case unicode.Is(unicode.Han, r):
+			counts.han++
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			counts.kana++
+		case unicode.Is(unicode.Hangul, r):
+			counts.hangul++
+		case unicode.Is(unicode.Latin, r):
+			isLatin = true
+			if !inWord {
+				counts.latin++
+			}
+		}
+		inWord = isLatin
+	}
+	return counts
+}
+
+// matchesLanguage 判断建议是否符合锁定语言：目标语言的文字至少占一半。
+// 没有任何文字（纯数字、emoji、标点）的建议视为符合；日文、韩文还分别要求出现假名、谚文，以区分纯中文
+func matchesLanguage(text, language string) bool {
+	counts := countScripts(text)
+	total := counts.han + counts.kana + counts.hangul + counts.latin
+	if total == 0 {
+		return true
+	}
+
+	var target int
+	switch language {
+	case models.LanguageZh:
+		target = counts.han
+	case models.LanguageEn:
+		target = counts.latin
+	case models.LanguageJa:
+		if counts.kana == 0 {
+			return false
+		}
+		target = counts.kana + counts.han
+	case models.LanguageKo:
+		if counts.hangul == 0 {
+			return false
+		}
+		target = counts.hangul + counts.han
+	default:
+		return true
+	}
+	return target*2 >= total
+}
+
+// filterLanguage 去掉明显不是锁定语言的建议，未锁定时原样返回
+func filterLanguage(language string, suggestions []models.Suggestion) []models.Suggestion {
+	if language == "" {
+		return suggestions
+	}
+	kept := suggestions[:0]
+	for _, suggestion := range suggestions {
+		if matchesLanguage(suggestion.Text, language) {
+			kept = append(kept, suggestion)
+		}
+	}
+	return kept
+}
+
+// lockedLanguage 对话设置中锁定的补全语言，未锁定时返回空
+func (e *Engine) lockedLanguage(req *models.AutocompleteRequest) string {
+	var conversation models.Conversation
+	if err := e.db.Select("settings").Where("conversation_id = ?", req.ConversationID).Limit(1).Find(&conversation).Error; err != nil {
+		logrus.WithError(err).Warn("查询对话失败")
+	}
+	if settings, err := conversation.GetSettings(); err == nil && models.ValidLanguage(settings.LockedLanguage) {
+		return settings.LockedLanguage
+	}
+	return ""
+}
@@ -0,0 +1,58 @@
+package autocomplete
+
+import (
+	"strings"
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// 目标语言的文字至少占一半才算符合：汉字按字、英文按单词计，日文韩文还需出现假名或谚文
+func TestMatchesLanguage(t *testing.T) {
+	cases := []struct {
+		text, language string
+		want           bool
+	}{
+		{"See you tomorrow", models.LanguageEn, true},
+		{"明天见", models.LanguageEn, false},
+		{"OK 明天见", models.LanguageEn, false},
+		{"Let's meet at 海底捞 tomorrow", models.LanguageEn, true},
+		{"明天一起开 meeting", models.LanguageZh, true},
+		{"また明日", models.LanguageJa, true},
+		{"明日见", models.LanguageJa, false},
+		{"내일 봐요", models.LanguageKo, true},
+		{"👍 100", models.LanguageEn, true},
+	}
+	for _, c := range cases {
+		if got := matchesLanguage(c.text, c.language); got != c.want {
+			t.Errorf("matchesLanguage(%q, %s) = %v，期望 %v", c.text, c.language, got, c.want)
+		}
+	}
+}
+
+// 对话锁定英文后上下文要求使用英文，中文建议被过滤
+func TestGetSuggestionsLockedLanguage(t *testing.T) {
+	mock := &testutil.MockLLM{Suggestions: []string{"明天见", "See you tomorrow", "好的 OK"}}
+	e, db := newTestEngine(t, &config.AutocompleteConfig{}, mock)
+	conversation := createTestConversation(t, db, "conv-language")
+	if err := conversation.SetSettings(&models.ConversationSettings{LockedLanguage: models.LanguageEn}); err != nil {
+		t.Fatal(err)
+	}
+	db.Model(&conversation).Update("settings", conversation.Settings)
+
+	resp, err := e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-language", SenderID: "alice", Input: "ok"})
+	if err != nil {
+		t.Fatalf("获取补全建议失败: %v", err)
+	}
+	if len(resp.Suggestions) != 1 || resp.Suggestions[0] != "See you tomorrow" {
+		t.Errorf("锁定英文后的建议为 %v，期望只剩英文建议", resp.Suggestions)
+	}
+	if !strings.Contains(mock.LastContext, "=== 语言锁定 ===") || !strings.Contains(mock.LastContext, "English") {
+		t.Errorf("上下文缺少语言锁定指令: %s", mock.LastContext)
+	}
+	if err := conversation.SetSettings(&models.ConversationSettings{LockedLanguage: "fr"}); err == nil {
+		t.Error("不支持的锁定语言应校验失败")
+	}
+}
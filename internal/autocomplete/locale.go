@@ -0,0 +1,172 @@
+package autocomplete
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"ChatRecommend/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// localeFormat 一种区域的日期和金额写法
+type localeFormat struct {
+	// date 格式化日期，year 为0表示原文没有年份
+	date func(year, month, day int) string
+	// groupAmounts 金额整数部分是否按千位加逗号
+	groupAmounts bool
+}
+
+var monthNames = []string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"}
+
+// localeFormats 支持的区域格式。日期统一改写为带月份名称（或"月""日"）的写法，不会产生"3/5"这类有歧义的数字日期
+var localeFormats = map[string]localeFormat{
+	models.LocaleZhCN: {
+		date: func(year, month, day int) string {
+			if year == 0 {
+				return fmt.Sprintf("%d月%d日", month, day)
+			}
+			return fmt.Sprintf("%d年%d月%d日", year, month, day)
+		},
+	},
+	models.LocaleEnUS: {
+		date: func(year, month, day int) string {
+			if year == 0 {
+				return fmt.Sprintf("%s %d", monthNames[month-1], day)
+			}
+			return fmt.Sprintf("%s %d, %d", monthNames[month-1], day, year)
+		},
+		groupAmounts: true,
+	},
+	models.LocaleEnGB: {
+		date: func(year, month, day int) string {
+			if year == 0 {
+				return fmt.Sprintf("%d %s", day, monthNames[month-1])
+			}
+			return fmt.Sprintf("%d %s %d", day, monthNames[month-1], year)
+		},
+		groupAmounts: true,
+	},
+}
+
+const monthPattern = `(Jan(?:uary)?|Feb(?:ruary)?|Mar(?:ch)?|Apr(?:il)?|May|June?|July?|Aug(?:ust)?|Sep(?:t(?:ember)?)?|Oct(?:ober)?|Nov(?:ember)?|Dec(?:ember)?)\.?`
+
+// dateRule 一种能无歧义解析的日期写法，year/month/day 为对应分组序号（0表示没有该分组）
+type dateRule struct {
+	pattern          *regexp.Regexp
+	year, month, day int
+	// month 分组是英文月份名
+	monthName bool
+}
+
+// dateRules 可识别的日期写法。"3/5"这类不带年份的数字日期无法判断月日顺序，不做改写
+var dateRules = []dateRule{
+	// 2024-03-05、2024/3/5、2024.3.5
+	{pattern: regexp.MustCompile(`\b(\d{4})[-/.](\d{1,2})[-/.](\d{1,2})\b`), year: 1, month: 2, day: 3},
+	// 2024年3月5日、3月5号
+	{pattern: regexp.MustCompile(`(?:(\d{4})年)?(\d{1,2})月(\d{1,2})[日号]`), year: 1, month: 2, day: 3},
+	// March 5, 2024、Mar 5th
+	{pattern: regexp.MustCompile(`\b` + monthPattern + ` (\d{1,2})(?:st|nd|rd|th)?(?:,? (\d{4}))?\b`), year: 3, month: 1, day: 2, monthName: true},
+	// 5 March 2024、5th Mar
+	{pattern: regexp.MustCompile(`\b(\d{1,2})(?:st|nd|rd|th)? ` + monthPattern + `(?:,? (\d{4}))?\b`), year: 3, month: 2, day: 1, monthName: true},
+}
+
+// amountPatterns 金额：货币符号在前或货币单位在后，分组1为整数部分
+var amountPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`[$¥￥£€]\s?(\d{4,})(?:\.\d+)?`),
+	regexp.MustCompile(`\b(\d{4,})(?:\.\d+)?\s?(?:元|块|dollars?|pounds?|USD|GBP|RMB|CNY)`),
+}
+
+// localizeSuggestion 按区域改写建议中的日期和金额写法
+func localizeSuggestion(text string, format localeFormat) string {
+	for _, rule := range dateRules {
+		text = rule.pattern.ReplaceAllStringFunc(text, func(match string) string {
+			groups := rule.pattern.FindStringSubmatch(match)
+			year, month, day := 0, 0, 0
+			if rule.year > 0 && groups[rule.year] != "" {
+				year, _ = strconv.Atoi(groups[rule.year])
+			}
+			if rule.monthName {
+				month = parseMonthName(groups[rule.month])
+			} else {
+				month, _ = strconv.Atoi(groups[rule.month])
+			}
+			day, _ = strconv.Atoi(groups[rule.day])
+			if !validDate(year, month, day) {
+				return match
+			}
+			return format.date(year, month, day)
+		})
+	}
+
+	if format.groupAmounts {
+		for _, pattern := range amountPatterns {
+			text = replaceGroup(pattern, text, 1, groupThousands)
+		}
+	}
+	return text
+}
+
+// parseMonthName 英文月份名（或缩写）转换为月份，无法识别时返回0
+func parseMonthName(name string) int {
+	name = strings.TrimSuffix(name, ".")
+	for i, month := range monthNames {
+		if strings.HasPrefix(month, name) && len(name) >= 3 {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// validDate 粗略校验月份和日期范围（不区分大小月和闰年）
+func validDate(year, month, day int) bool {
+	if year != 0 && (year < 1900 || year > 2100) {
+		return false
+	}
+	return month >= 1 && month <= 12 && day >= 1 && day <= 31
+}
+
+// groupThousands 整数按千位加逗号
+func groupThousands(digits string) string {
+	var b strings.Builder
+	for i, r := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// replaceGroup 只替换每个匹配中指定分组的内容
+func replaceGroup(pattern *regexp.Regexp, text string, group int, replace func(string) string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range pattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end := loc[2*group], loc[2*group+1]
+		if start < 0 {
+			continue
+		}
+		b.WriteString(text[last:start])
+		b.WriteString(replace(text[start:end]))
+		last = end
+	}
+	if last == 0 {
+		return text
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// suggestionLocale 对话使用的区域：对话设置优先，否则使用全局配置，都没有时返回空（不改写）
+func (e *Engine) suggestionLocale(req *models.AutocompleteRequest) string {
+	var conversation models.Conversation
+	if err := e.db.Select("settings").Where("conversation_id = ?", req.ConversationID).Limit(1).Find(&conversation).Error; err != nil {
+		logrus.WithError(err).Warn("查询对话失败")
+	}
+	if settings, err := conversation.GetSettings(); err == nil && settings.Locale != "" {
+		return settings.Locale
+	}
+	return e.config.Locale
+}
@@ -0,0 +1,63 @@
+package autocomplete
+
+import (
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// 无歧义的日期按区域改写，金额在英文区域按千位分组，"3/5"这类有歧义的写法不改
+func TestLocalizeSuggestion(t *testing.T) {
+	cases := []struct {
+		locale, in, want string
+	}{
+		{models.LocaleZhCN, "2024-03-05 见", "2024年3月5日 见"},
+		{models.LocaleZhCN, "March 5th 见", "3月5日 见"},
+		{models.LocaleZhCN, "一共12000元", "一共12000元"},
+		{models.LocaleEnUS, "See you on 2024-03-05", "See you on March 5, 2024"},
+		{models.LocaleEnUS, "5 Mar works, total $12000.50", "March 5 works, total $12,000.50"},
+		{models.LocaleEnGB, "See you on 2024-03-05", "See you on 5 March 2024"},
+		{models.LocaleEnGB, "3月5号 it costs 1500000 pounds", "5 March it costs 1,500,000 pounds"},
+		{models.LocaleEnUS, "Meet on 3/5", "Meet on 3/5"},
+		{models.LocaleEnUS, "2024-13-40 is invalid", "2024-13-40 is invalid"},
+	}
+	for _, c := range cases {
+		if got := localizeSuggestion(c.in, localeFormats[c.locale]); got != c.want {
+			t.Errorf("%s: localizeSuggestion(%q) = %q，期望 %q", c.locale, c.in, got, c.want)
+		}
+	}
+}
+
+// 对话设置的区域覆盖全局配置，都未设置时不改写
+func TestGetSuggestionsLocalizesPerConversation(t *testing.T) {
+	mock := &testutil.MockLLM{Suggestions: []string{"那就2024-03-05见"}}
+	e, db := newTestEngine(t, &config.AutocompleteConfig{Locale: models.LocaleEnUS}, mock)
+	conversation := createTestConversation(t, db, "conv-locale")
+	plain, plainDB := newTestEngine(t, &config.AutocompleteConfig{}, mock)
+	createTestConversation(t, plainDB, "conv-locale")
+
+	suggest := func(e *Engine) string {
+		t.Helper()
+		resp, err := e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-locale", SenderID: "alice", Input: "好"})
+		if err != nil || len(resp.Suggestions) == 0 {
+			t.Fatalf("获取补全建议失败: %+v %v", resp, err)
+		}
+		return resp.Suggestions[0]
+	}
+
+	if got := suggest(e); got != "那就March 5, 2024见" {
+		t.Errorf("全局区域 en-US 的建议为 %q", got)
+	}
+	if err := conversation.SetSettings(&models.ConversationSettings{Locale: models.LocaleEnGB}); err != nil {
+		t.Fatal(err)
+	}
+	db.Model(&conversation).Update("settings", conversation.Settings)
+	if got := suggest(e); got != "那就5 March 2024见" {
+		t.Errorf("对话区域 en-GB 的建议为 %q", got)
+	}
+	if got := suggest(plain); got != "那就2024-03-05见" {
+		t.Errorf("未设置区域时建议不应改写，实际 %q", got)
+	}
+}
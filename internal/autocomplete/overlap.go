@@ -0,0 +1,76 @@
+package autocomplete
+
+import (
+	"strings"
+	"unicode"
+
+	"ChatRecommend/internal/models"
+)
+
+// minOverlapRunes 部分重叠时至少重叠的字符数，避免把碰巧相同的单个字（如"的"）误删
+const minOverlapRunes = 2
+
+// fullwidthPunct 全角标点到半角标点的映射，比较重叠时视为相同
+var fullwidthPunct = map[rune]rune{
+	'，': ',', '。': '.', '！': '!', '？': '?', '：': ':', '；': ';',
+	'（': '(', '）': ')', '“': '"', '”': '"', '‘': '\'', '’': '\'',
+}
+
+// stripInputOverlap 去除建议开头与用户已输入内容尾部重叠的部分，使建议能从光标处直接接上
+// 建议以完整输入开头时总是去除；只与输入尾部部分重叠时，重叠至少 minOverlapRunes 个字符才去除
+func stripInputOverlap(input, suggestion string) string {
+	in := []rune(strings.TrimRightFunc(input, unicode.IsSpace))
+	leading := len(suggestion) - len(strings.TrimLeftFunc(suggestion, unicode.IsSpace))
+	sug := []rune(suggestion[leading:])
+	if len(in) == 0 || len(sug) == 0 {
+		return suggestion
+	}
+
+	maxOverlap := len(in)
+	if len(sug) < maxOverlap {
+		maxOverlap = len(sug)
+	}
+
+	for k := maxOverlap; k > 0; k-- {
+		if k < len(in) && k < minOverlapRunes {
+			break
+		}
+		if runesEqualFold(in[len(in)-k:], sug[:k]) {
+			return string(sug[k:])
+		}
+	}
+	return suggestion
+}
+
+// runesEqualFold 忽略大小写和全半角标点差异比较两段文本
+func runesEqualFold(a, b []rune) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if normalizeRune(a[i]) != normalizeRune(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeRune 统一大小写和全半角标点
+func normalizeRune(r rune) rune {
+	if half, ok := fullwidthPunct[r]; ok {
+		return half
+	}
+	return unicode.ToLower(r)
+}
+
+// dropUnchanged 去掉与用户输入相同（忽略首尾空白）的改写建议，原样返回输入的改写没有意义
+func dropUnchanged(input string, suggestions []models.Suggestion) []models.Suggestion {
+	input = strings.TrimSpace(input)
+	kept := suggestions[:0]
+	for _, suggestion := range suggestions {
+		if strings.TrimSpace(suggestion.Text) != input {
+			kept = append(kept, suggestion)
+		}
+	}
+	return kept
+}
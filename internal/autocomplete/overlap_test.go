@@ -0,0 +1,27 @@
+package autocomplete
+
+import "testing"
+
+// 建议开头与输入尾部重叠时去除重叠部分，中英文、大小写和全半角标点都按相同处理
+func TestStripInputOverlap(t *testing.T) {
+	tests := []struct {
+		name, input, suggestion, want string
+	}{
+		{"完整重复输入", "明天一起", "明天一起吃饭吧", "吃饭吧"},
+		{"尾部部分重叠", "我们明天一起", "一起吃饭吧", "吃饭吧"},
+		{"英文忽略大小写", "see you To", "tomorrow", "morrow"},
+		{"英文单词重叠", "I will call", "Will call you later", " you later"},
+		{"全半角标点视为相同", "好的，明天", "好的,明天见", "见"},
+		{"建议开头的空白", "明天一起 ", "  明天一起去吧", "去吧"},
+		{"单字重叠不去除", "我喜欢你的", "的确如此", "的确如此"},
+		{"没有重叠", "明天", "几点见面", "几点见面"},
+		{"空输入", "", "好的", "好的"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stripInputOverlap(tt.input, tt.suggestion); got != tt.want {
+				t.Errorf("stripInputOverlap(%q, %q) = %q，期望 %q", tt.input, tt.suggestion, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package autocomplete
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// 请求中的 max_tokens 和 stop 透传给大模型，未指定时不覆盖配置默认值
+func TestGetSuggestionsPassesMaxTokensAndStop(t *testing.T) {
+	mock := &testutil.MockLLM{Suggestions: []string{"七点见"}}
+	e, db := newTestEngine(t, &config.AutocompleteConfig{MaxRequestTokens: 256}, mock)
+	createTestConversation(t, db, "conv-params")
+
+	_, err := e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-params", SenderID: "alice", Input: "晚上", MaxTokens: 64, Stop: []string{"\n", "。"}})
+	if err != nil {
+		t.Fatalf("获取补全建议失败: %v", err)
+	}
+	opts := mock.LastOptions
+	if opts == nil || opts.MaxTokens == nil || *opts.MaxTokens != 64 {
+		t.Fatalf("max_tokens 未透传: %+v", opts)
+	}
+	if !slices.Equal(opts.Stop, []string{"\n", "。"}) {
+		t.Errorf("stop 未透传: %q", opts.Stop)
+	}
+
+	if _, err := e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-params", SenderID: "alice", Input: "明天"}); err != nil {
+		t.Fatalf("获取补全建议失败: %v", err)
+	}
+	if mock.LastOptions.MaxTokens != nil || len(mock.LastOptions.Stop) != 0 {
+		t.Errorf("未指定时不应覆盖默认值: %+v", mock.LastOptions)
+	}
+}
+
+// 超过上限的 max_tokens 和不合法的停止序列被拒绝，不调用大模型
+func TestGetSuggestionsRejectsInvalidGenerationParams(t *testing.T) {
+	mock := &testutil.MockLLM{Suggestions: []string{"七点见"}}
+	e, db := newTestEngine(t, &config.AutocompleteConfig{MaxRequestTokens: 256}, mock)
+	createTestConversation(t, db, "conv-params")
+
+	invalid := []*models.AutocompleteRequest{
+		{MaxTokens: 257},
+		{MaxTokens: -1},
+		{Stop: []string{"a", "b", "c", "d", "e"}},
+		{Stop: []string{""}},
+		{Stop: []string{strings.Repeat("长", maxStopSequenceChars+1)}},
+	}
+	for _, req := range invalid {
+		req.ConversationID, req.SenderID, req.Input = "conv-params", "alice", "晚上"
+		if _, err := e.GetSuggestions(req); !errors.Is(err, ErrInvalidRequest) {
+			t.Errorf("max_tokens=%d stop=%q 应返回 ErrInvalidRequest，得到 %v", req.MaxTokens, req.Stop, err)
+		}
+	}
+	if mock.Calls() != 0 {
+		t.Errorf("参数不合法时不应调用大模型，调用了 %d 次", mock.Calls())
+	}
+}
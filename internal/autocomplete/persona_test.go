@@ -0,0 +1,33 @@
+package autocomplete
+
+import (
+	"strings"
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// 角色扮演指令进入发给大模型的上下文，不同角色的请求不共用缓存
+func TestGetSuggestionsPersonaStyle(t *testing.T) {
+	mock := &testutil.MockLLM{Suggestions: []string{"好的喵", "明天见喵"}}
+	e, db := newTestEngine(t, &config.AutocompleteConfig{CacheTTLSeconds: 60}, mock)
+	createTestConversation(t, db, "conv-persona")
+
+	req := &models.AutocompleteRequest{ConversationID: "conv-persona", SenderID: "alice", Input: "七点", PersonaStyle: "猫娘"}
+	if _, err := e.GetSuggestions(req); err != nil {
+		t.Fatalf("获取补全建议失败: %v", err)
+	}
+	if !strings.Contains(mock.LastContext, "=== 角色扮演 ===") || !strings.Contains(mock.LastContext, "喵") {
+		t.Fatalf("上下文缺少角色扮演指令: %s", mock.LastContext)
+	}
+
+	req = &models.AutocompleteRequest{ConversationID: "conv-persona", SenderID: "alice", Input: "七点", PersonaStyle: "文言文"}
+	if _, err := e.GetSuggestions(req); err != nil {
+		t.Fatalf("获取补全建议失败: %v", err)
+	}
+	if calls := mock.Calls(); calls != 2 {
+		t.Errorf("不同角色的请求调用大模型 %d 次，期望 2 次", calls)
+	}
+}
@@ -0,0 +1,218 @@
+package autocomplete
+
+import (
+	"fmt"
+	"sync"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// Postprocessor 补全建议后处理器，接收上一步的建议并返回处理后的建议。
+// 建议以结构化结果传递，改写文本时保留 reason、tone 等字段，过滤和排序时整条移动
+type Postprocessor interface {
+	Process(req *models.AutocompleteRequest, suggestions []models.Suggestion) []models.Suggestion
+}
+
+// PostprocessorFunc 函数形式的后处理器
+type PostprocessorFunc func(req *models.AutocompleteRequest, suggestions []models.Suggestion) []models.Suggestion
+
+// Process 实现 Postprocessor
+func (f PostprocessorFunc) Process(req *models.AutocompleteRequest, suggestions []models.Suggestion) []models.Suggestion {
+	return f(req, suggestions)
+}
+
+// Pipeline 后处理管道（责任链），按顺序依次执行各处理器
+type Pipeline []Postprocessor
+
+// Process 实现 Postprocessor，管道本身也可以作为一个处理器嵌套组合
+func (p Pipeline) Process(req *models.AutocompleteRequest, suggestions []models.Suggestion) []models.Suggestion {
+	for _, processor := range p {
+		suggestions = processor.Process(req, suggestions)
+	}
+	return suggestions
+}
+
+// 内置后处理器名称
+const (
+	PostprocessStripOverlap = "strip_overlap"
+	PostprocessDedupe       = "dedupe"
+	PostprocessLimit        = "limit"
+	PostprocessTruncate     = "truncate"
+	PostprocessPlaceholders = "fill_placeholders"
+	PostprocessLocalize     = "localize"
+	PostprocessLanguage     = "language"
+	PostprocessSafety       = "safety"
+	PostprocessGrammar      = "grammar"
+	PostprocessRank         = "rank"
+)
+
+// defaultPostprocessors 未配置时的默认后处理顺序
+var defaultPostprocessors = []string{PostprocessStripOverlap, PostprocessPlaceholders, PostprocessLocalize, PostprocessLanguage, PostprocessSafety, PostprocessGrammar, PostprocessDedupe, PostprocessRank, PostprocessLimit, PostprocessTruncate}
+
+// builtinPostprocessors 内置后处理器的构造函数，处理器可以读取引擎配置
+var builtinPostprocessors = map[string]func(e *Engine) Postprocessor{
+	// 去除建议中重复用户已输入内容的部分（开场白模式不处理）；改写模式的建议是整句替换，只去掉与原输入相同的建议
+	PostprocessStripOverlap: func(e *Engine) Postprocessor {
+		return PostprocessorFunc(func(req *models.AutocompleteRequest, suggestions []models.Suggestion) []models.Suggestion {
+			if req.Input == "" {
+				return suggestions
+			}
+			if rewriteMode(req) {
+				return dropUnchanged(req.Input, suggestions)
+			}
+			for i := range suggestions {
+				suggestions[i].Text = stripInputOverlap(req.Input, suggestions[i].Text)
+			}
+			return suggestions
+		})
+	},
+	// 剔除过于雷同的建议（同时去掉空建议）
+	PostprocessDedupe: func(e *Engine) Postprocessor {
+		return PostprocessorFunc(func(req *models.AutocompleteRequest, suggestions []models.Suggestion) []models.Suggestion {
+			return removeSimilar(suggestions, e.config.DiversityThreshold)
+		})
+	},
+	// 按历史采纳率调整建议顺序（未开启 ranking 或样本不足时不处理），放在 limit 之前，采纳率高的建议不会被截掉
+	PostprocessRank: func(e *Engine) Postprocessor {
+		return PostprocessorFunc(func(req *models.AutocompleteRequest, suggestions []models.Suggestion) []models.Suggestion {
+			return e.ranker.rank(suggestions)
+		})
+	},
+	// 限制建议数量
+	PostprocessLimit: func(e *Engine) Postprocessor {
+		return PostprocessorFunc(e.limitSuggestions)
+	},
+	// 用关键信息和用户档案填充建议中的 {名称} 占位符，填不上的保留给用户选择
+	PostprocessPlaceholders: func(e *Engine) Postprocessor {
+		return PostprocessorFunc(func(req *models.AutocompleteRequest, suggestions []models.Suggestion) []models.Suggestion {
+			if !hasPlaceholder(suggestions) {
+				return suggestions
+			}
+			values := e.placeholderValues(req)
+			for i := range suggestions {
+				suggestions[i].Text = fillPlaceholders(suggestions[i].Text, values)
+			}
+			return suggestions
+		})
+	},
+	// 按对话的区域格式改写建议中的日期和金额写法（未配置区域时不处理）
+	PostprocessLocalize: func(e *Engine) Postprocessor {
+		return PostprocessorFunc(func(req *models.AutocompleteRequest, suggestions []models.Suggestion) []models.Suggestion {
+			format, ok := localeFormats[e.suggestionLocale(req)]
+			if !ok {
+				return suggestions
+			}
+			for i := range suggestions {
+				suggestions[i].Text = localizeSuggestion(suggestions[i].Text, format)
+			}
+			return suggestions
+		})
+	},
+	// 对话锁定语言时去掉明显不是该语言的建议（未锁定时不处理）
+	PostprocessLanguage: func(e *Engine) Postprocessor {
+		return PostprocessorFunc(func(req *models.AutocompleteRequest, suggestions []models.Suggestion) []models.Suggestion {
+			return filterLanguage(e.lockedLanguage(req), suggestions)
+		})
+	},
+	// 去掉命中屏蔽词的建议（未配置安全分级时不处理），命中警示词的建议保留，生成结果时再标注等级
+	PostprocessSafety: func(e *Engine) Postprocessor {
+		return PostprocessorFunc(func(req *models.AutocompleteRequest, suggestions []models.Suggestion) []models.Suggestion {
+			return e.safety.removeBlocked(suggestions)
+		})
+	},
+	// 检查英文建议的语法：修正明显错误，仍有问题的建议排到后面（未开启 grammar_check 时不处理）
+	PostprocessGrammar: func(e *Engine) Postprocessor {
+		return PostprocessorFunc(func(req *models.AutocompleteRequest, suggestions []models.Suggestion) []models.Suggestion {
+			return checkGrammar(e.grammar, suggestions)
+		})
+	},
+	// 超长建议截断到句界
+	PostprocessTruncate: func(e *Engine) Postprocessor {
+		return PostprocessorFunc(func(req *models.AutocompleteRequest, suggestions []models.Suggestion) []models.Suggestion {
+			for i := range suggestions {
+				suggestions[i].Text = truncateSuggestion(suggestions[i].Text, e.config.MaxSuggestionChars)
+			}
+			return suggestions
+		})
+	},
+}
+
+// customPostprocessors 通过 RegisterPostprocessor 注册的命名后处理器，与内置处理器一样可以在配置中按名称启用和排序
+var (
+	customMu             sync.RWMutex
+	customPostprocessors = make(map[string]func(e *Engine) Postprocessor)
+)
+
+// RegisterPostprocessor 注册命名的自定义后处理器（需在创建引擎前调用，通常在 init 中），
+// 之后可以在 autocomplete.postprocessors 中按名称启用并与内置处理器一起排序。名称不能与内置处理器重名
+func RegisterPostprocessor(name string, build func(e *Engine) Postprocessor) error {
+	if name == "" || build == nil {
+		return fmt.Errorf("后处理器名称和构造函数不能为空")
+	}
+	if _, ok := builtinPostprocessors[name]; ok {
+		return fmt.Errorf("后处理器 %s 与内置处理器重名", name)
+	}
+
+	customMu.Lock()
+	defer customMu.Unlock()
+	if _, ok := customPostprocessors[name]; ok {
+		return fmt.Errorf("后处理器 %s 已注册", name)
+	}
+	customPostprocessors[name] = build
+	return nil
+}
+
+// lookupPostprocessor 按名称查找内置或已注册的后处理器构造函数
+func lookupPostprocessor(name string) (func(e *Engine) Postprocessor, bool) {
+	if build, ok := builtinPostprocessors[name]; ok {
+		return build, true
+	}
+	customMu.RLock()
+	defer customMu.RUnlock()
+	build, ok := customPostprocessors[name]
+	return build, ok
+}
+
+// newPipeline 按配置的名称顺序组装后处理管道，未知名称跳过并记录
+func newPipeline(e *Engine, cfg *config.AutocompleteConfig) Pipeline {
+	names := cfg.Postprocessors
+	if len(names) == 0 {
+		names = defaultPostprocessors
+	}
+
+	pipeline := make(Pipeline, 0, len(names))
+	for _, name := range names {
+		build, ok := lookupPostprocessor(name)
+		if !ok {
+			logrus.WithField("postprocessor", name).Warn("未知的补全后处理器，已跳过")
+			continue
+		}
+		pipeline = append(pipeline, build(e))
+	}
+	return pipeline
+}
+
+// Use 在管道末尾追加自定义后处理器（需在处理请求前调用）
+func (e *Engine) Use(processors ...Postprocessor) {
+	e.postprocess = append(e.postprocess, processors...)
+}
+
+// suggestionTexts 建议的文本列表
+func suggestionTexts(suggestions []models.Suggestion) []string {
+	texts := make([]string, len(suggestions))
+	for i, suggestion := range suggestions {
+		texts[i] = suggestion.Text
+	}
+	return texts
+}
+
+// textSuggestions 把文本建议包装成只有文本的结构化结果
+func textSuggestions(texts []string) []models.Suggestion {
+	suggestions := make([]models.Suggestion, len(texts))
+	for i, text := range texts {
+		suggestions[i] = models.Suggestion{Text: text}
+	}
+	return suggestions
+}
@@ -0,0 +1,118 @@
+package autocomplete
+
+import (
+	"strings"
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+)
+
+func TestPipelineComposesProcessors(t *testing.T) {
+	var calls []string
+	trim := PostprocessorFunc(func(req *models.AutocompleteRequest, suggestions []models.Suggestion) []models.Suggestion {
+		calls = append(calls, "trim")
+		for i := range suggestions {
+			suggestions[i].Text = strings.TrimSpace(suggestions[i].Text)
+		}
+		return suggestions
+	})
+	dropEmpty := PostprocessorFunc(func(req *models.AutocompleteRequest, suggestions []models.Suggestion) []models.Suggestion {
+		calls = append(calls, "drop_empty")
+		kept := suggestions[:0]
+		for _, suggestion := range suggestions {
+			if suggestion.Text != "" {
+				kept = append(kept, suggestion)
+			}
+		}
+		return kept
+	})
+	upper := PostprocessorFunc(func(req *models.AutocompleteRequest, suggestions []models.Suggestion) []models.Suggestion {
+		calls = append(calls, "upper")
+		for i := range suggestions {
+			suggestions[i].Text = strings.ToUpper(suggestions[i].Text)
+		}
+		return suggestions
+	})
+
+	// 管道本身也是处理器，可以嵌套组合
+	pipeline := Pipeline{trim, Pipeline{dropEmpty, upper}}
+	got := pipeline.Process(&models.AutocompleteRequest{}, []models.Suggestion{
+		{Text: " ok ", Tone: "casual"},
+		{Text: "  "},
+		{Text: "sure", Reason: "agree"},
+	})
+
+	if strings.Join(calls, ",") != "trim,drop_empty,upper" {
+		t.Fatalf("执行顺序为 %v", calls)
+	}
+	want := []models.Suggestion{{Text: "OK", Tone: "casual"}, {Text: "SURE", Reason: "agree"}}
+	if len(got) != len(want) {
+		t.Fatalf("结果为 %+v，期望 %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("结果为 %+v，期望 %+v", got, want)
+		}
+	}
+}
+
+func TestNewPipelineUsesConfiguredOrder(t *testing.T) {
+	e := &Engine{config: &config.AutocompleteConfig{SuggestionCount: 1}}
+	cfg := &config.AutocompleteConfig{Postprocessors: []string{PostprocessLimit, "unknown", PostprocessDedupe}}
+	pipeline := newPipeline(e, cfg)
+	if len(pipeline) != 2 {
+		t.Fatalf("管道长度为 %d，期望跳过未知处理器后为2", len(pipeline))
+	}
+
+	// 先限量再去重：只剩第一条
+	got := pipeline.Process(&models.AutocompleteRequest{}, textSuggestions([]string{"好的", "好的", "可以"}))
+	if len(got) != 1 || got[0].Text != "好的" {
+		t.Fatalf("结果为 %+v", got)
+	}
+}
+
+// 注册的自定义处理器可以在配置中按名称启用并与内置处理器一起排序；名称为空、与内置处理器重名或重复注册时返回错误
+func TestRegisterPostprocessor(t *testing.T) {
+	const name = "test_append_count"
+	t.Cleanup(func() {
+		customMu.Lock()
+		delete(customPostprocessors, name)
+		customMu.Unlock()
+	})
+	build := func(e *Engine) Postprocessor {
+		count := e.config.SuggestionCount
+		return PostprocessorFunc(func(req *models.AutocompleteRequest, suggestions []models.Suggestion) []models.Suggestion {
+			for i := range suggestions {
+				suggestions[i].Text += strings.Repeat("!", count)
+			}
+			return suggestions
+		})
+	}
+	if err := RegisterPostprocessor(name, build); err != nil {
+		t.Fatalf("注册后处理器失败: %v", err)
+	}
+	if err := RegisterPostprocessor(name, build); err == nil {
+		t.Error("重复注册应返回错误")
+	}
+	if err := RegisterPostprocessor(PostprocessDedupe, build); err == nil {
+		t.Error("与内置处理器重名应返回错误")
+	}
+	if err := RegisterPostprocessor("", build); err == nil {
+		t.Error("名称为空应返回错误")
+	}
+	if err := RegisterPostprocessor("test_nil", nil); err == nil {
+		t.Error("构造函数为空应返回错误")
+	}
+
+	// 构造函数可以读取引擎配置，自定义处理器按配置顺序在去重和限量之间执行
+	e := &Engine{config: &config.AutocompleteConfig{SuggestionCount: 2}}
+	pipeline := newPipeline(e, &config.AutocompleteConfig{Postprocessors: []string{PostprocessDedupe, name, PostprocessLimit}})
+	if len(pipeline) != 3 {
+		t.Fatalf("管道长度为 %d，期望3", len(pipeline))
+	}
+	got := pipeline.Process(&models.AutocompleteRequest{}, textSuggestions([]string{"好的", "好的", "可以", "行"}))
+	if len(got) != 2 || got[0].Text != "好的!!" || got[1].Text != "可以!!" {
+		t.Fatalf("结果为 %+v", got)
+	}
+}
@@ -0,0 +1,103 @@
+package autocomplete
+
+import (
+	"encoding/json"
+	"regexp"
+
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/textutil"
+	"github.com/sirupsen/logrus"
+)
+
+// placeholderPattern 建议中的占位符 {名称}：名称为1-16个不含空白和花括号的字符
+var placeholderPattern = regexp.MustCompile(`\{([^{}\s]{1,16})\}`)
+
+// profilePlaceholders 可由用户档案填充的占位符名称（中英文均可），偏好按偏好名称填充
+var profilePlaceholders = map[string]func(f *models.ProfileFields) string{
+	"姓名":       func(f *models.ProfileFields) string { return f.Name },
+	"名字":       func(f *models.ProfileFields) string { return f.Name },
+	"name":     func(f *models.ProfileFields) string { return f.Name },
+	"电话":       func(f *models.ProfileFields) string { return f.Phone },
+	"手机":       func(f *models.ProfileFields) string { return f.Phone },
+	"phone":    func(f *models.ProfileFields) string { return f.Phone },
+	"邮箱":       func(f *models.ProfileFields) string { return f.Email },
+	"email":    func(f *models.ProfileFields) string { return f.Email },
+	"地址":       func(f *models.ProfileFields) string { return f.Address },
+	"address":  func(f *models.ProfileFields) string { return f.Address },
+	"生日":       func(f *models.ProfileFields) string { return f.Birthday },
+	"birthday": func(f *models.ProfileFields) string { return f.Birthday },
+}
+
+// fillPlaceholders 用取值表填充建议中的占位符（名称忽略大小写和全半角），没有取值的占位符原样保留
+func fillPlaceholders(suggestion string, values map[string]string) string {
+	return placeholderPattern.ReplaceAllStringFunc(suggestion, func(ref string) string {
+		name := placeholderPattern.FindStringSubmatch(ref)[1]
+		if value, ok := values[textutil.NormalizeTag(name)]; ok {
+			return value
+		}
+		return ref
+	})
+}
+
+// hasPlaceholder 判断是否有建议包含占位符
+func hasPlaceholder(suggestions []models.Suggestion) bool {
+	for _, suggestion := range suggestions {
+		if placeholderPattern.MatchString(suggestion.Text) {
+			return true
+		}
+	}
+	return false
+}
+
+// placeholderValues 收集占位符取值（键为归一化的名称）：先取用户档案，再取对话关键信息（以 key 为名称、value 为值），
+// 关键信息与对话直接相关，同名时覆盖档案
+func (e *Engine) placeholderValues(req *models.AutocompleteRequest) map[string]string {
+	values := make(map[string]string)
+
+	var conversation models.Conversation
+	if err := e.db.Where("conversation_id = ?", req.ConversationID).Limit(1).Find(&conversation).Error; err != nil {
+		logrus.WithError(err).Warn("查询对话失败")
+		return values
+	}
+	if conversation.ID == 0 {
+		return values
+	}
+
+	if e.contextMgr != nil {
+		if fields := e.contextMgr.ProfileFields(&conversation, req.SenderID); fields != nil {
+			for name, value := range profilePlaceholders {
+				if v := value(fields); v != "" {
+					values[textutil.NormalizeTag(name)] = v
+				}
+			}
+			for name, value := range fields.Preferences {
+				if value != "" {
+					values[textutil.NormalizeTag(name)] = value
+				}
+			}
+		}
+	}
+
+	var summary models.Summary
+	if err := e.db.Where("conversation_id = ?", conversation.ID).Limit(1).Find(&summary).Error; err != nil {
+		logrus.WithError(err).Warn("查询摘要失败")
+		return values
+	}
+	if summary.KeyInfo == "" || summary.KeyInfo == "[]" {
+		return values
+	}
+	var items []map[string]interface{}
+	if err := json.Unmarshal([]byte(summary.KeyInfo), &items); err != nil {
+		logrus.WithError(err).Warn("解析关键信息失败")
+		return values
+	}
+	// 合并后的关键信息新条目在后，同名时以较新的为准
+	for _, item := range items {
+		key, _ := item["key"].(string)
+		value, _ := item["value"].(string)
+		if name := textutil.NormalizeTag(key); name != "" && value != "" {
+			values[name] = value
+		}
+	}
+	return values
+}
@@ -0,0 +1,67 @@
+package autocomplete
+
+import (
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// 占位符按归一化的名称填充，没有取值、含空白或过长的不是占位符或原样保留
+func TestFillPlaceholders(t *testing.T) {
+	values := map[string]string{"地点": "海底捞", "name": "小王"}
+	cases := []struct {
+		in, want string
+	}{
+		{"周五我们去{地点}吃{菜系}吧", "周五我们去海底捞吃{菜系}吧"},
+		{"我是{Name}", "我是小王"},
+		{"{地 点}见", "{地 点}见"},
+		{"{这是一个超过十六个字符长度的占位符名称啊}", "{这是一个超过十六个字符长度的占位符名称啊}"},
+		{"没有占位符", "没有占位符"},
+	}
+	for _, c := range cases {
+		if got := fillPlaceholders(c.in, values); got != c.want {
+			t.Errorf("fillPlaceholders(%q) = %q，期望 %q", c.in, got, c.want)
+		}
+	}
+}
+
+// 建议中的占位符用用户档案和关键信息填充，关键信息同名时覆盖档案，填不上的保留
+func TestGetSuggestionsFillsPlaceholders(t *testing.T) {
+	mock := &testutil.MockLLM{Suggestions: []string{"周五我们去{地点}吃{菜系}吧", "地址是{地址}，{时间}见"}}
+	e, db := newTestEngine(t, &config.AutocompleteConfig{}, mock)
+	conversation := createTestConversation(t, db, "conv-placeholder")
+
+	profile := models.UserProfile{SenderID: "alice"}
+	if err := profile.SetFields(&models.ProfileFields{
+		Address:     "幸福路 8 号",
+		Preferences: map[string]string{"菜系": "川菜", "地点": "家附近"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&profile).Error; err != nil {
+		t.Fatalf("创建档案失败: %v", err)
+	}
+	if err := db.Create(&models.Summary{
+		ConversationID: conversation.ID,
+		Prompt:         "两人约饭",
+		KeyInfo:        `[{"type":"place","key":"地点","value":"海底捞"}]`,
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-placeholder", SenderID: "alice", Input: "那就"})
+	if err != nil {
+		t.Fatalf("获取补全建议失败: %v", err)
+	}
+	want := []string{"周五我们去海底捞吃川菜吧", "地址是幸福路 8 号，{时间}见"}
+	if len(resp.Suggestions) != len(want) {
+		t.Fatalf("建议为 %v，期望 %v", resp.Suggestions, want)
+	}
+	for i := range want {
+		if resp.Suggestions[i] != want[i] {
+			t.Errorf("第%d条建议为 %q，期望 %q", i, resp.Suggestions[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package autocomplete
+
+import (
+	"errors"
+	"time"
+
+	"ChatRecommend/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// activeUserTTL 补全用户活跃判定时间，超过该时间未请求补全的用户不再预取
+const activeUserTTL = 24 * time.Hour
+
+// recordActiveUser 记录在对话中使用补全的用户
+func (e *Engine) recordActiveUser(conversationID, senderID string) {
+	e.activeMu.Lock()
+	defer e.activeMu.Unlock()
+
+	users, ok := e.activeUsers[conversationID]
+	if !ok {
+		users = make(map[string]time.Time)
+		e.activeUsers[conversationID] = users
+	}
+	users[senderID] = time.Now()
+}
+
+// prefetchTargets 获取需要预取的用户（对话中近期使用过补全且不是本条消息发送者的用户）
+func (e *Engine) prefetchTargets(conversationID, senderID string) []string {
+	e.activeMu.Lock()
+	defer e.activeMu.Unlock()
+
+	targets := make([]string, 0)
+	for userID, lastSeen := range e.activeUsers[conversationID] {
+		if time.Since(lastSeen) > activeUserTTL {
+			delete(e.activeUsers[conversationID], userID)
+			continue
+		}
+		if userID != senderID {
+			targets = append(targets, userID)
+		}
+	}
+	return targets
+}
+
+// InvalidateCache 使对话的补全缓存和上下文窗口失效（如对话设置变更、消息被编辑后）
+func (e *Engine) InvalidateCache(conversationID string) {
+	e.cache.invalidate(conversationID)
+	e.contextMgr.InvalidateWindow(conversationID)
+}
+
+// AutocompleteDisabled 对话是否通过设置关闭了补全（对话不存在或设置无法解析时视为启用）
+func (e *Engine) AutocompleteDisabled(conversationID string) bool {
+	var conversation models.Conversation
+	if err := e.db.Select("id", "settings").Where("conversation_id = ?", conversationID).First(&conversation).Error; err != nil {
+		return false
+	}
+	settings, err := conversation.GetSettings()
+	return err == nil && settings.AutocompleteDisabled()
+}
+
+// ClearCache 清空全部对话的补全缓存（如提示词模板更新后）
+func (e *Engine) ClearCache() {
+	e.cache.clear()
+}
+
+// OnMessageSaved 新消息保存后调用：使对话缓存失效，并为其他使用补全的用户预取常见开头的补全
+func (e *Engine) OnMessageSaved(conversationID, senderID string) {
+	e.cache.invalidate(conversationID)
+
+	// 对话关闭了补全时不预取
+	if e.AutocompleteDisabled(conversationID) {
+		return
+	}
+
+	// 未启用缓存时预取结果无处存放
+	if e.cache == nil || !e.config.Prefetch.Enabled || len(e.config.Prefetch.Prefixes) == 0 {
+		return
+	}
+
+	for _, userID := range e.prefetchTargets(conversationID, senderID) {
+		for _, prefix := range e.config.Prefetch.Prefixes {
+			req := &models.AutocompleteRequest{
+				ConversationID: conversationID,
+				SenderID:       userID,
+				Input:          prefix,
+				// 预取不是用户正在等待的请求，排在其他补全之后
+				Priority: models.PriorityLow,
+				Prefetch: true,
+			}
+			go e.prefetch(req)
+		}
+	}
+}
+
+// prefetch 预取一次补全并写入缓存，受并发上限约束，槽位已满时直接放弃。
+// 预取不占用用户的每日配额，也不刷新活跃用户（见 AutocompleteRequest.Prefetch）
+func (e *Engine) prefetch(req *models.AutocompleteRequest) {
+	select {
+	case e.prefetchSem <- struct{}{}:
+		defer func() { <-e.prefetchSem }()
+	default:
+		logrus.WithField("conversation_id", req.ConversationID).Debug("预取并发已满，跳过")
+		return
+	}
+
+	if _, err := e.GetSuggestions(req); err != nil && !errors.Is(err, ErrQuotaExceeded) {
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"conversation_id": req.ConversationID,
+			"sender_id":       req.SenderID,
+			"input":           req.Input,
+		}).Warn("预取补全失败")
+	}
+}
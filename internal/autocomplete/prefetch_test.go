@@ -0,0 +1,172 @@
+package autocomplete
+
+import (
+	"testing"
+	"time"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// 对方发消息后只为对话中其他使用补全的用户预取，预取结果在用户开始输入时命中缓存
+func TestPrefetchOnlyForOtherParticipants(t *testing.T) {
+	mock := &testutil.MockLLM{Suggestions: []string{"好的，七点见"}}
+	e, db := newTestEngine(t, &config.AutocompleteConfig{
+		CacheTTLSeconds: 60,
+		Prefetch:        config.PrefetchConfig{Enabled: true, Prefixes: []string{"好的"}, MaxConcurrency: 2},
+	}, mock)
+	createTestConversation(t, db, "conv-prefetch")
+
+	complete := func(senderID, input string) {
+		t.Helper()
+		if _, err := e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-prefetch", SenderID: senderID, Input: input}); err != nil {
+			t.Fatalf("获取补全建议失败: %v", err)
+		}
+	}
+	// alice 和 bob 都用过补全
+	complete("alice", "明天")
+	complete("bob", "明天")
+
+	e.OnMessageSaved("conv-prefetch", "bob")
+	deadline := time.Now().Add(2 * time.Second)
+	for mock.Calls() < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	// 留出时间确认没有为发送者本人预取
+	time.Sleep(50 * time.Millisecond)
+	if calls := mock.Calls(); calls != 3 {
+		t.Fatalf("应只为 alice 预取一次，大模型共调用 %d 次", calls)
+	}
+
+	complete("alice", "好的")
+	if calls := mock.Calls(); calls != 3 {
+		t.Fatalf("alice 的输入应命中预取缓存，大模型共调用 %d 次", calls)
+	}
+	complete("bob", "好的")
+	if calls := mock.Calls(); calls != 4 {
+		t.Fatalf("发送者本人不应被预取，大模型共调用 %d 次", calls)
+	}
+}
+
+// 未开启预取时收到消息不调用大模型
+func TestPrefetchDisabled(t *testing.T) {
+	mock := &testutil.MockLLM{Suggestions: []string{"好的"}}
+	e, db := newTestEngine(t, &config.AutocompleteConfig{CacheTTLSeconds: 60, Prefetch: config.PrefetchConfig{Prefixes: []string{"好的"}}}, mock)
+	createTestConversation(t, db, "conv-prefetch-off")
+
+	if _, err := e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-prefetch-off", SenderID: "alice", Input: "明天"}); err != nil {
+		t.Fatalf("获取补全建议失败: %v", err)
+	}
+	e.OnMessageSaved("conv-prefetch-off", "bob")
+	time.Sleep(50 * time.Millisecond)
+	if calls := mock.Calls(); calls != 1 {
+		t.Fatalf("未开启预取时不应调用大模型，共调用 %d 次", calls)
+	}
+}
+
+// waitCalls 等待大模型调用次数达到 n（最多2秒），再留出时间确认没有多余的调用
+func waitCalls(mock *testutil.MockLLM, n int) int {
+	deadline := time.Now().Add(2 * time.Second)
+	for mock.Calls() < n && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	return mock.Calls()
+}
+
+// 预取不占用用户的每日配额；配额用完后不再为该用户预取
+func TestPrefetchDoesNotConsumeQuota(t *testing.T) {
+	mock := &testutil.MockLLM{Suggestions: []string{"好的，七点见"}}
+	e, db := newTestEngine(t, &config.AutocompleteConfig{
+		CacheTTLSeconds: 60,
+		Prefetch:        config.PrefetchConfig{Enabled: true, Prefixes: []string{"好的"}, MaxConcurrency: 2},
+	}, mock)
+	e.SetDailyQuota(2, time.UTC)
+	createTestConversation(t, db, "conv-prefetch-quota")
+
+	usage := func() int64 {
+		var u models.LLMUsage
+		db.Where("sender_id = ?", "alice").Limit(1).Find(&u)
+		return u.Count
+	}
+
+	if _, err := e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-prefetch-quota", SenderID: "alice", Input: "明天"}); err != nil {
+		t.Fatalf("获取补全建议失败: %v", err)
+	}
+	e.OnMessageSaved("conv-prefetch-quota", "bob")
+	if calls := waitCalls(mock, 2); calls != 2 {
+		t.Fatalf("应为 alice 预取一次，大模型共调用 %d 次", calls)
+	}
+	if n := usage(); n != 1 {
+		t.Fatalf("预取后 alice 的调用计数为 %d，期望 1", n)
+	}
+
+	// alice 用完当天配额后，新消息不再触发预取
+	if _, err := e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-prefetch-quota", SenderID: "alice", Input: "后天"}); err != nil {
+		t.Fatalf("获取补全建议失败: %v", err)
+	}
+	if n := usage(); n != 2 {
+		t.Fatalf("alice 的调用计数为 %d，期望 2", n)
+	}
+	e.OnMessageSaved("conv-prefetch-quota", "bob")
+	if calls := waitCalls(mock, 4); calls != 3 {
+		t.Fatalf("配额用完后不应预取，大模型共调用 %d 次", calls)
+	}
+}
+
+// 预取不刷新用户的活跃时间：对话持续有新消息时，长时间不用补全的用户仍会过期
+func TestPrefetchDoesNotRefreshActiveUser(t *testing.T) {
+	mock := &testutil.MockLLM{Suggestions: []string{"好的，七点见"}}
+	e, db := newTestEngine(t, &config.AutocompleteConfig{
+		CacheTTLSeconds: 60,
+		Prefetch:        config.PrefetchConfig{Enabled: true, Prefixes: []string{"好的"}, MaxConcurrency: 2},
+	}, mock)
+	createTestConversation(t, db, "conv-prefetch-active")
+
+	lastSeen := time.Now().Add(-activeUserTTL + time.Hour)
+	e.activeMu.Lock()
+	e.activeUsers["conv-prefetch-active"] = map[string]time.Time{"alice": lastSeen}
+	e.activeMu.Unlock()
+
+	e.OnMessageSaved("conv-prefetch-active", "bob")
+	if calls := waitCalls(mock, 1); calls != 1 {
+		t.Fatalf("应为 alice 预取一次，大模型共调用 %d 次", calls)
+	}
+
+	e.activeMu.Lock()
+	got := e.activeUsers["conv-prefetch-active"]["alice"]
+	e.activeMu.Unlock()
+	if !got.Equal(lastSeen) {
+		t.Fatalf("预取刷新了 alice 的活跃时间: %v -> %v", lastSeen, got)
+	}
+}
+
+// 对话关闭补全后收到消息不预取；对话不存在时视为启用
+func TestPrefetchSkippedWhenAutocompleteDisabled(t *testing.T) {
+	mock := &testutil.MockLLM{Suggestions: []string{"好的"}}
+	e, db := newTestEngine(t, &config.AutocompleteConfig{
+		CacheTTLSeconds: 60,
+		Prefetch:        config.PrefetchConfig{Enabled: true, Prefixes: []string{"好的"}, MaxConcurrency: 2},
+	}, mock)
+	createTestConversation(t, db, "conv-prefetch-disabled")
+	if _, err := e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-prefetch-disabled", SenderID: "alice", Input: "明天"}); err != nil {
+		t.Fatalf("获取补全建议失败: %v", err)
+	}
+
+	if e.AutocompleteDisabled("conv-prefetch-disabled") || e.AutocompleteDisabled("conv-missing") {
+		t.Fatal("未设置时应启用补全")
+	}
+	if err := db.Model(&models.Conversation{}).Where("conversation_id = ?", "conv-prefetch-disabled").
+		Update("settings", `{"autocomplete_enabled":false}`).Error; err != nil {
+		t.Fatalf("更新设置失败: %v", err)
+	}
+	if !e.AutocompleteDisabled("conv-prefetch-disabled") {
+		t.Fatal("设置关闭后应识别为关闭补全")
+	}
+
+	e.OnMessageSaved("conv-prefetch-disabled", "bob")
+	if calls := waitCalls(mock, 2); calls != 1 {
+		t.Fatalf("关闭补全的对话不应预取，大模型共调用 %d 次", calls)
+	}
+}
@@ -0,0 +1,128 @@
+package autocomplete
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// InputPreprocessor 补全输入预处理器，返回清洗后的输入
+type InputPreprocessor func(input string) string
+
+// 内置输入预处理器名称
+const (
+	PreprocessStripInvisible = "strip_invisible"
+	PreprocessNewlines       = "normalize_newlines"
+	PreprocessWidth          = "normalize_width"
+	PreprocessWhitespace     = "collapse_whitespace"
+	// PreprocessNone 不做任何预处理（配置为 [none] 时关闭）
+	PreprocessNone = "none"
+)
+
+// defaultInputPreprocessors 未配置时的默认预处理顺序
+var defaultInputPreprocessors = []string{PreprocessStripInvisible, PreprocessNewlines, PreprocessWidth, PreprocessWhitespace}
+
+var (
+	// 连续的空格和制表符
+	inlineSpacePattern = regexp.MustCompile(`[ \t]+`)
+	// 两个以上的连续换行（中间可夹空白）
+	blankLinesPattern = regexp.MustCompile(`\n[ \t]*(\n[ \t]*)+`)
+)
+
+// builtinInputPreprocessors 内置输入预处理器
+var builtinInputPreprocessors = map[string]InputPreprocessor{
+	// 去除零宽字符、方向控制符、BOM、软连字符等不可见的格式字符和控制字符（保留换行和制表符）
+	PreprocessStripInvisible: func(input string) string {
+		return strings.Map(func(r rune) rune {
+			if r == '\n' || r == '\r' || r == '\t' {
+				return r
+			}
+			if unicode.Is(unicode.Cf, r) || unicode.IsControl(r) {
+				return -1
+			}
+			return r
+		}, input)
+	},
+	// \r\n、\r 和 Unicode 行/段分隔符统一为 \n
+	PreprocessNewlines: func(input string) string {
+		return strings.NewReplacer("\r\n", "\n", "\r", "\n", "\u2028", "\n", "\u2029", "\n").Replace(input)
+	},
+	// 全角字母、数字和全角空格转为半角；全角标点（，。！？等）是中文的正常写法，保持不变
+	PreprocessWidth: func(input string) string {
+		return strings.Map(func(r rune) rune {
+			switch {
+			case r == '\u3000':
+				return ' '
+			case r >= '０' && r <= '９', r >= 'Ａ' && r <= 'Ｚ', r >= 'ａ' && r <= 'ｚ':
+				return r - 0xFEE0
+			}
+			return r
+		}, input)
+	},
+	// 连续空格和制表符折叠为一个空格，连续空行折叠为一个换行，去掉首尾空白
+	PreprocessWhitespace: func(input string) string {
+		input = inlineSpacePattern.ReplaceAllString(input, " ")
+		input = blankLinesPattern.ReplaceAllString(input, "\n")
+		return strings.TrimSpace(input)
+	},
+}
+
+// newInputPreprocessors 按配置的名称顺序组装输入预处理器，未知名称跳过并记录
+func newInputPreprocessors(cfg *config.AutocompleteConfig) []InputPreprocessor {
+	names := cfg.InputPreprocessors
+	if len(names) == 0 {
+		names = defaultInputPreprocessors
+	}
+
+	processors := make([]InputPreprocessor, 0, len(names))
+	for _, name := range names {
+		if name == PreprocessNone {
+			continue
+		}
+		processor, ok := builtinInputPreprocessors[name]
+		if !ok {
+			logrus.WithField("preprocessor", name).Warn("未知的补全输入预处理器，已跳过")
+			continue
+		}
+		processors = append(processors, processor)
+	}
+	return processors
+}
+
+// cleanInput 依次执行输入预处理器
+func (e *Engine) cleanInput(input string) string {
+	for _, processor := range e.preprocess {
+		input = processor(input)
+	}
+	return input
+}
+
+// preprocessRequest 清洗请求中的输入和候选输入，返回清洗后的请求副本（清洗后的输入同时用于触发长度判断、规则匹配、缓存和上下文）。
+// 原本有内容、清洗后为空（如只有零宽字符或空白）的输入不触发补全，返回 false
+func (e *Engine) preprocessRequest(req *models.AutocompleteRequest) (*models.AutocompleteRequest, bool) {
+	if len(e.preprocess) == 0 {
+		return req, true
+	}
+
+	cleaned := *req
+	cleaned.Input = e.cleanInput(req.Input)
+	if req.Input != "" && cleaned.Input == "" {
+		return nil, false
+	}
+	if len(req.InputCandidates) > 0 {
+		cleaned.InputCandidates = make([]string, 0, len(req.InputCandidates))
+		for _, candidate := range req.InputCandidates {
+			if candidate = e.cleanInput(candidate); candidate != "" {
+				cleaned.InputCandidates = append(cleaned.InputCandidates, candidate)
+			}
+		}
+		if len(cleaned.InputCandidates) == 0 {
+			return nil, false
+		}
+	}
+	return &cleaned, true
+}
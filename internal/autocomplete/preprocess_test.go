@@ -0,0 +1,58 @@
+package autocomplete
+
+import (
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// 默认预处理去除不可见字符、统一换行和全半角、折叠空白；配置为 none 时不处理
+func TestCleanInput(t *testing.T) {
+	e := &Engine{preprocess: newInputPreprocessors(&config.AutocompleteConfig{})}
+	cases := []struct {
+		in, want string
+	}{
+		{"\ufeff明\u200b天\u200d见", "明天见"},
+		{"ｈｅｌｌｏ　１２３", "hello 123"},
+		{"  a \t b\r\n\r\n\r\nc ", "a b\nc"},
+		{"好的，明天见！", "好的，明天见！"},
+	}
+	for _, c := range cases {
+		if got := e.cleanInput(c.in); got != c.want {
+			t.Errorf("cleanInput(%q) = %q，期望 %q", c.in, got, c.want)
+		}
+	}
+
+	none := &Engine{preprocess: newInputPreprocessors(&config.AutocompleteConfig{InputPreprocessors: []string{PreprocessNone}})}
+	if got := none.cleanInput("明\u200b天 "); got != "明\u200b天 " {
+		t.Errorf("关闭预处理后输入被修改为 %q", got)
+	}
+}
+
+// 清洗后的输入用于触发长度判断和发给大模型，只有不可见字符的输入不触发补全
+func TestGetSuggestionsPreprocessesInput(t *testing.T) {
+	mock := &testutil.MockLLM{Suggestions: []string{"见", "吃饭"}}
+	e, db := newTestEngine(t, &config.AutocompleteConfig{MinTriggerLength: 2}, mock)
+	createTestConversation(t, db, "conv-preprocess")
+
+	resp, err := e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-preprocess", SenderID: "alice", Input: "\u200b\u200b\u200b"})
+	if err != nil || len(resp.Suggestions) != 0 {
+		t.Fatalf("只有零宽字符的输入应返回空建议，实际 %+v (%v)", resp, err)
+	}
+	resp, err = e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-preprocess", SenderID: "alice", Input: "明\u200b\u200b"})
+	if err != nil || len(resp.Suggestions) != 0 {
+		t.Fatalf("清洗后不足触发长度的输入应返回空建议，实际 %+v (%v)", resp, err)
+	}
+	if calls := mock.Calls(); calls != 0 {
+		t.Fatalf("未触发补全时调用了大模型 %d 次", calls)
+	}
+
+	if _, err := e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-preprocess", SenderID: "alice", Input: "明\u200b天  晚上"}); err != nil {
+		t.Fatalf("获取补全建议失败: %v", err)
+	}
+	if mock.LastInput != "明天 晚上" {
+		t.Errorf("发给大模型的输入为 %q，期望清洗后的 %q", mock.LastInput, "明天 晚上")
+	}
+}
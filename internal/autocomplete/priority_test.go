@@ -0,0 +1,31 @@
+package autocomplete
+
+import (
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/llm"
+	"ChatRecommend/internal/models"
+)
+
+// 排队优先级由服务端按发送者推导，客户端传入的 high 不能提升优先级
+func TestRequestPriority(t *testing.T) {
+	e := &Engine{config: &config.AutocompleteConfig{HighPriorityUsers: []string{"vip"}}}
+	tests := []struct {
+		sender   string
+		priority string
+		want     int
+	}{
+		{"vip", "", llm.PriorityHigh},
+		{"vip", models.PriorityLow, llm.PriorityLow},
+		{"free", models.PriorityHigh, llm.PriorityNormal},
+		{"free", "", llm.PriorityNormal},
+		{"free", models.PriorityLow, llm.PriorityLow},
+	}
+	for _, tt := range tests {
+		got := e.requestPriority(&models.AutocompleteRequest{SenderID: tt.sender, Priority: tt.priority})
+		if got != tt.want {
+			t.Errorf("sender=%s priority=%q 的排队优先级为 %d，期望 %d", tt.sender, tt.priority, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package autocomplete
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"ChatRecommend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrQuotaExceeded 用户当天的大模型调用次数已达配额
+var ErrQuotaExceeded = errors.New("今日大模型调用次数已达上限")
+
+// dailyQuota 按 (sender_id, 日期) 持久化计数的每日调用配额
+type dailyQuota struct {
+	db       *gorm.DB
+	limit    int64
+	location *time.Location
+}
+
+// SetDailyQuota 设置每个用户每天的大模型调用次数上限（按 location 所在时区划分日期），limit 为0时不限制
+func (e *Engine) SetDailyQuota(limit int, location *time.Location) {
+	if limit <= 0 {
+		e.quota = nil
+		return
+	}
+	if location == nil {
+		location = time.Local
+	}
+	e.quota = &dailyQuota{db: e.db, limit: int64(limit), location: location}
+}
+
+// acquire 占用一次调用额度。计数在一条 upsert 语句中完成，并发请求和重启都不会多算或丢失；
+// 已达上限时不再累加并返回 ErrQuotaExceeded
+func (q *dailyQuota) acquire(senderID string) error {
+	if q == nil {
+		return nil
+	}
+
+	usage := models.LLMUsage{
+		SenderID: senderID,
+		Day:      time.Now().In(q.location).Format("2006-01-02"),
+		Count:    1,
+	}
+	res := q.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "sender_id"}, {Name: "day"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"count":      gorm.Expr("llm_usages.count + 1"),
+			"updated_at": time.Now(),
+		}),
+		Where: clause.Where{Exprs: []clause.Expression{gorm.Expr("llm_usages.count < ?", q.limit)}},
+	}).Create(&usage)
+	if res.Error != nil {
+		return fmt.Errorf("记录大模型调用次数失败: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("%w（%d次），请明天再试", ErrQuotaExceeded, q.limit)
+	}
+	return nil
+}
+
+// exhausted 用户当天的额度是否已用完，只查询不计数（预取使用）。查询失败时视为已用完，不发起预取
+func (q *dailyQuota) exhausted(senderID string) bool {
+	if q == nil {
+		return false
+	}
+
+	var usage models.LLMUsage
+	err := q.db.Where("sender_id = ? AND day = ?", senderID, time.Now().In(q.location).Format("2006-01-02")).
+		Limit(1).Find(&usage).Error
+	return err != nil || usage.Count >= q.limit
+}
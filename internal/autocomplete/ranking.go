@@ -0,0 +1,232 @@
+package autocomplete
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// 排序特征
+const (
+	// rankPrefixRunes 前缀特征取建议开头的字符数
+	rankPrefixRunes = 2
+	// rankPositionDecay 原始位置的衰减：第 i 条建议的基础分为 1/(1+rankPositionDecay*i)，
+	// 采纳率权重明显更高的建议才会越过前面的建议，权重相近时保持大模型给出的顺序
+	rankPositionDecay = 0.3
+	// rankPriorSamples 平滑用的先验样本数：样本少的特征采纳率向整体采纳率收缩
+	rankPriorSamples = 10
+	// 单个特征权重的上下限，避免个别特征主导排序
+	minRankWeight = 0.5
+	maxRankWeight = 2.0
+)
+
+// 建议的句式类别
+const (
+	suggestionKindQuestion    = "question"
+	suggestionKindExclamation = "exclamation"
+	suggestionKindStatement   = "statement"
+)
+
+// RankingStatus 采纳率排序的聚合状态（用于指标）
+type RankingStatus struct {
+	// 参与聚合的补全记录数（含未采纳的）
+	Samples int `json:"samples"`
+	// 样本数达标、参与排序的特征数，为0时使用默认顺序
+	Features  int       `json:"features"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// acceptanceRanker 按历史采纳率调整建议顺序：定期从补全记录聚合各特征（开头、句式）的采纳率权重，
+// 没有聚合结果（冷启动）时不改变顺序
+type acceptanceRanker struct {
+	cfg *config.RankingConfig
+
+	mu      sync.RWMutex
+	weights map[string]float64
+	status  RankingStatus
+}
+
+func newAcceptanceRanker(cfg *config.RankingConfig) *acceptanceRanker {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &acceptanceRanker{cfg: cfg}
+}
+
+// suggestionFeatures 建议的排序特征：开头几个字（如"好的"）和句式（疑问、感叹、陈述）
+func suggestionFeatures(suggestion string) []string {
+	text := strings.TrimSpace(suggestion)
+	if text == "" {
+		return nil
+	}
+	runes := []rune(text)
+	prefix := runes[:min(len(runes), rankPrefixRunes)]
+	return []string{"prefix:" + strings.ToLower(string(prefix)), "kind:" + suggestionKind(text)}
+}
+
+// suggestionKind 按结尾的标点和语气词判断建议的句式
+func suggestionKind(text string) string {
+	text = strings.TrimRight(text, " \t")
+	switch {
+	case strings.HasSuffix(text, "?") || strings.HasSuffix(text, "？") ||
+		strings.HasSuffix(text, "吗") || strings.HasSuffix(text, "呢"):
+		return suggestionKindQuestion
+	case strings.HasSuffix(text, "!") || strings.HasSuffix(text, "！"):
+		return suggestionKindExclamation
+	default:
+		return suggestionKindStatement
+	}
+}
+
+// aggregateAcceptance 从补全记录聚合特征权重：每条记录的建议都计入展示次数，用户采纳的那条计入采纳次数，
+// 未采纳的记录只增加展示次数。特征的采纳率 = 含该特征的建议被采纳的次数 / 展示次数（按整体采纳率平滑），
+// 权重为其与整体采纳率之比。展示次数不足 minSamples 的特征不给权重；没有任何采纳时返回nil
+func aggregateAcceptance(logs []models.CompletionLog, minSamples int) (map[string]float64, int) {
+	shown := make(map[string]int)
+	accepted := make(map[string]int)
+	totalShown, totalAccepted, samples := 0, 0, 0
+	for i := range logs {
+		log := &logs[i]
+		suggestions, err := log.GetSuggestions()
+		if err != nil || len(suggestions) == 0 {
+			continue
+		}
+		acceptedIndex := -1
+		if log.AcceptedIndex != nil {
+			if *log.AcceptedIndex < 0 || *log.AcceptedIndex >= len(suggestions) {
+				continue
+			}
+			acceptedIndex = *log.AcceptedIndex
+			totalAccepted++
+		}
+		samples++
+		for j, suggestion := range suggestions {
+			for _, feature := range suggestionFeatures(suggestion) {
+				shown[feature]++
+				if j == acceptedIndex {
+					accepted[feature]++
+				}
+			}
+			totalShown++
+		}
+	}
+	if totalAccepted == 0 || totalShown == 0 {
+		return nil, samples
+	}
+
+	base := float64(totalAccepted) / float64(totalShown)
+	weights := make(map[string]float64)
+	for feature, n := range shown {
+		if n < minSamples {
+			continue
+		}
+		rate := (float64(accepted[feature]) + base*rankPriorSamples) / (float64(n) + rankPriorSamples)
+		weights[feature] = max(minRankWeight, min(maxRankWeight, rate/base))
+	}
+	return weights, samples
+}
+
+// weight 建议的采纳率权重：各特征权重之积，没有权重的特征按1计
+func (r *acceptanceRanker) weight(suggestion string) float64 {
+	w := 1.0
+	for _, feature := range suggestionFeatures(suggestion) {
+		if fw, ok := r.weights[feature]; ok {
+			w *= fw
+		}
+	}
+	return w
+}
+
+// rank 按"位置基础分 × 采纳率权重"重新排序建议，分数相同时保持原顺序；没有权重时不处理
+func (r *acceptanceRanker) rank(suggestions []models.Suggestion) []models.Suggestion {
+	if r == nil || len(suggestions) < 2 {
+		return suggestions
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.weights) == 0 {
+		return suggestions
+	}
+
+	scores := make([]float64, len(suggestions))
+	order := make([]int, len(suggestions))
+	for i, suggestion := range suggestions {
+		order[i] = i
+		scores[i] = r.weight(suggestion.Text) / (1 + rankPositionDecay*float64(i))
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return scores[order[a]] > scores[order[b]]
+	})
+
+	ranked := make([]models.Suggestion, len(suggestions))
+	for i, j := range order {
+		ranked[i] = suggestions[j]
+	}
+	return ranked
+}
+
+// RefreshRanking 从最近 max_logs 条补全记录（含未采纳的）重新聚合排序权重（未启用采纳率排序时不处理）
+func (e *Engine) RefreshRanking() error {
+	r := e.ranker
+	if r == nil {
+		return nil
+	}
+
+	var logs []models.CompletionLog
+	if err := e.db.Order("id DESC").
+		Limit(r.cfg.MaxLogs).
+		Find(&logs).Error; err != nil {
+		return fmt.Errorf("查询补全记录失败: %w", err)
+	}
+	weights, samples := aggregateAcceptance(logs, r.cfg.MinSamples)
+
+	r.mu.Lock()
+	r.weights = weights
+	r.status = RankingStatus{Samples: samples, Features: len(weights), UpdatedAt: time.Now()}
+	r.mu.Unlock()
+
+	logrus.WithFields(logrus.Fields{
+		"samples":  samples,
+		"features": len(weights),
+	}).Debug("补全排序权重已更新")
+	return nil
+}
+
+// StartRanking 立即聚合一次排序权重，之后按 interval_seconds 定时重新聚合（未启用采纳率排序时不启动）
+func (e *Engine) StartRanking() {
+	if e.ranker == nil {
+		return
+	}
+	if err := e.RefreshRanking(); err != nil {
+		logrus.WithError(err).Warn("聚合补全排序权重失败")
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(e.ranker.cfg.IntervalSeconds) * time.Second)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := e.RefreshRanking(); err != nil {
+				logrus.WithError(err).Warn("聚合补全排序权重失败")
+			}
+		}
+	}()
+	logrus.WithField("interval_seconds", e.ranker.cfg.IntervalSeconds).Info("补全采纳率排序已启动")
+}
+
+// RankingStatus 采纳率排序的聚合状态，未启用时返回nil
+func (e *Engine) RankingStatus() *RankingStatus {
+	if e.ranker == nil {
+		return nil
+	}
+	e.ranker.mu.RLock()
+	defer e.ranker.mu.RUnlock()
+	status := e.ranker.status
+	return &status
+}
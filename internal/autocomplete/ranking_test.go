@@ -0,0 +1,63 @@
+package autocomplete
+
+import (
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// 展示后未被采纳的记录计入展示次数：常被展示却很少被选的开头权重低于展示少但常被选的开头
+func TestRankingCountsUnacceptedLogs(t *testing.T) {
+	e, db := newTestEngine(t, &config.AutocompleteConfig{
+		Ranking: config.RankingConfig{Enabled: true, MaxLogs: 1000, MinSamples: 20},
+	}, &testutil.MockLLM{})
+	conversation := createTestConversation(t, db, "conv-ranking")
+
+	addLogs := func(n int, suggestions []string, accepted *int) {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			log := models.CompletionLog{ConversationID: conversation.ID, SenderID: "alice", AcceptedIndex: accepted}
+			if err := log.SetSuggestions(suggestions); err != nil {
+				t.Fatalf("序列化建议失败: %v", err)
+			}
+			if err := db.Create(&log).Error; err != nil {
+				t.Fatalf("保存补全记录失败: %v", err)
+			}
+		}
+	}
+	first := 0
+	// "好的"展示80次、采纳20次；"行啊"展示40次、采纳20次
+	addLogs(20, []string{"好的", "行啊"}, &first)
+	addLogs(60, []string{"好的", "嗯嗯"}, nil)
+	addLogs(20, []string{"行啊", "嗯嗯"}, &first)
+
+	if err := e.RefreshRanking(); err != nil {
+		t.Fatalf("聚合排序权重失败: %v", err)
+	}
+	if status := e.RankingStatus(); status.Samples != 100 {
+		t.Fatalf("参与聚合的记录数为 %d，期望 100（含未采纳的记录）", status.Samples)
+	}
+
+	good, fine := e.ranker.weights["prefix:好的"], e.ranker.weights["prefix:行啊"]
+	if good == 0 || fine == 0 || good >= fine {
+		t.Fatalf("权重 好的=%v 行啊=%v，期望 好的 < 行啊", good, fine)
+	}
+	ranked := e.ranker.rank([]models.Suggestion{{Text: "好的"}, {Text: "行啊"}})
+	if ranked[0].Text != "行啊" {
+		t.Fatalf("排序结果为 %+v，采纳率更高的\"行啊\"应排在前面", ranked)
+	}
+}
+
+// 没有任何采纳时不给权重，保持默认顺序
+func TestRankingWithoutAcceptance(t *testing.T) {
+	logs := make([]models.CompletionLog, 30)
+	for i := range logs {
+		logs[i].SetSuggestions([]string{"好的", "行啊"})
+	}
+	weights, samples := aggregateAcceptance(logs, 1)
+	if weights != nil || samples != 30 {
+		t.Fatalf("没有采纳时权重为 %v、样本数为 %d", weights, samples)
+	}
+}
@@ -0,0 +1,60 @@
+package autocomplete
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// 改写模式要求整句改写，返回的建议替换整句输入（不去除与输入重叠的部分），与原输入相同的建议被去掉
+func TestGetSuggestionsRewriteMode(t *testing.T) {
+	mock := &testutil.MockLLM{Suggestions: []string{"明天几点见面比较方便？", "明天几点见", "请问明天几点见呢"}}
+	e, db := newTestEngine(t, &config.AutocompleteConfig{}, mock)
+	createTestConversation(t, db, "conv-rewrite")
+
+	resp, err := e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-rewrite", SenderID: "alice", Input: "明天几点见", Mode: models.AutocompleteModeRewrite})
+	if err != nil {
+		t.Fatalf("获取改写建议失败: %v", err)
+	}
+	if resp.InsertMode != models.InsertModeReplace {
+		t.Errorf("改写模式的插入方式为 %q，期望 replace", resp.InsertMode)
+	}
+	want := []string{"明天几点见面比较方便？", "请问明天几点见呢"}
+	if strings.Join(resp.Suggestions, "|") != strings.Join(want, "|") {
+		t.Errorf("改写建议为 %v，期望 %v", resp.Suggestions, want)
+	}
+	if !strings.Contains(mock.LastContext, "=== 改写任务 ===") {
+		t.Errorf("上下文缺少改写任务说明: %s", mock.LastContext)
+	}
+
+	// 续写模式去除与输入重叠的部分，接在输入之后
+	resp, err = e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-rewrite", SenderID: "alice", Input: "明天几点见"})
+	if err != nil {
+		t.Fatalf("获取补全建议失败: %v", err)
+	}
+	if resp.InsertMode != models.InsertModeAppend || len(resp.Suggestions) == 0 || resp.Suggestions[0] != "面比较方便？" {
+		t.Errorf("续写模式的响应为 %+v", resp)
+	}
+	if strings.Contains(mock.LastContext, "=== 改写任务 ===") {
+		t.Errorf("续写模式不应包含改写任务说明: %s", mock.LastContext)
+	}
+}
+
+// 改写模式需要输入，不支持的模式被拒绝
+func TestGetSuggestionsRewriteValidation(t *testing.T) {
+	e, db := newTestEngine(t, &config.AutocompleteConfig{}, &testutil.MockLLM{Suggestions: []string{"好的"}})
+	createTestConversation(t, db, "conv-rewrite")
+
+	for _, req := range []*models.AutocompleteRequest{
+		{ConversationID: "conv-rewrite", SenderID: "alice", Input: "  ", Mode: models.AutocompleteModeRewrite},
+		{ConversationID: "conv-rewrite", SenderID: "alice", Input: "明天", Mode: "translate"},
+	} {
+		if _, err := e.GetSuggestions(req); !errors.Is(err, ErrInvalidRequest) {
+			t.Errorf("请求 %+v 应返回 ErrInvalidRequest，实际 %v", req, err)
+		}
+	}
+}
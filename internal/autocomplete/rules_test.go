@@ -0,0 +1,78 @@
+package autocomplete
+
+import (
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/rules"
+	"ChatRecommend/internal/testutil"
+)
+
+func newRuleEngine(t *testing.T, mock *testutil.MockLLM) (*Engine, models.Conversation) {
+	t.Helper()
+	matcher, err := rules.NewMatcher([]config.RuleConfig{
+		{Name: "phone", Pattern: "我的电话是$", Templates: []string{"${phone}"}, Priority: 10},
+		{Name: "restaurant", Pattern: "^去(哪|哪里)吃$", Templates: []string{"去${餐厅}吧"}},
+		{Name: "weekday", Pattern: "^今天是(?P<weekday>周.)$", Templates: []string{"今天是${weekday}，明天见！"}},
+	})
+	if err != nil {
+		t.Fatalf("创建规则失败: %v", err)
+	}
+	e, db := newTestEngineWithRules(t, &config.AutocompleteConfig{}, mock, matcher)
+	conversation := createTestConversation(t, db, "conv-rules")
+
+	profile := models.UserProfile{SenderID: "alice"}
+	if err := profile.SetFields(&models.ProfileFields{Phone: "13800000000"}); err != nil {
+		t.Fatalf("设置档案失败: %v", err)
+	}
+	if err := db.Create(&profile).Error; err != nil {
+		t.Fatalf("保存档案失败: %v", err)
+	}
+	summary := models.Summary{ConversationID: conversation.ID, KeyInfo: `[{"type":"地点","key":"餐厅","value":"海底捞"}]`}
+	if err := db.Create(&summary).Error; err != nil {
+		t.Fatalf("保存摘要失败: %v", err)
+	}
+	return e, conversation
+}
+
+// 命中规则时直接用模板生成建议，模板变量取自正则分组、用户档案和对话关键信息，不调用大模型
+func TestRulesTakePrecedenceOverLLM(t *testing.T) {
+	mock := &testutil.MockLLM{Suggestions: []string{"大模型的建议"}}
+	e, _ := newRuleEngine(t, mock)
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"我的电话是", "13800000000"},
+		{"去哪吃", "去海底捞吧"},
+		{"今天是周五", "今天是周五，明天见！"},
+	}
+	for _, tt := range tests {
+		resp, err := e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-rules", SenderID: "alice", Input: tt.input})
+		if err != nil {
+			t.Fatalf("%s: 获取补全建议失败: %v", tt.input, err)
+		}
+		if len(resp.Suggestions) != 1 || resp.Suggestions[0] != tt.want {
+			t.Errorf("%s: 建议为 %v，期望 %q", tt.input, resp.Suggestions, tt.want)
+		}
+	}
+	if mock.Calls() != 0 {
+		t.Fatalf("规则命中时不应调用大模型，实际调用 %d 次", mock.Calls())
+	}
+}
+
+// 模板引用的变量缺失（bob 没有档案）时跳过规则，交给大模型
+func TestRulesFallBackToLLMWhenVariableMissing(t *testing.T) {
+	mock := &testutil.MockLLM{Suggestions: []string{"138"}}
+	e, _ := newRuleEngine(t, mock)
+
+	resp, err := e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-rules", SenderID: "bob", Input: "我的电话是"})
+	if err != nil {
+		t.Fatalf("获取补全建议失败: %v", err)
+	}
+	if mock.Calls() != 1 || len(resp.Suggestions) != 1 || resp.Suggestions[0] != "138" {
+		t.Fatalf("建议为 %v，大模型调用 %d 次", resp.Suggestions, mock.Calls())
+	}
+}
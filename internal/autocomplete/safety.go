@@ -0,0 +1,64 @@
+package autocomplete
+
+import (
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/textutil"
+)
+
+// safetyChecker 按配置的词表给建议分级：命中屏蔽词为 blocked，命中警示词为 warn，其余为 safe
+type safetyChecker struct {
+	blockedWords []string
+	warnWords    []string
+}
+
+// newSafetyChecker 创建安全分级器，两个词表都为空时返回 nil（不分级）
+func newSafetyChecker(cfg *config.SafetyConfig) *safetyChecker {
+	if len(cfg.BlockedWords) == 0 && len(cfg.WarnWords) == 0 {
+		return nil
+	}
+	return &safetyChecker{blockedWords: cfg.BlockedWords, warnWords: cfg.WarnWords}
+}
+
+// level 返回建议的安全等级，未配置分级时返回空字符串
+func (c *safetyChecker) level(text string) string {
+	if c == nil {
+		return ""
+	}
+	if len(textutil.MatchKeywords(text, c.blockedWords)) > 0 {
+		return models.SafetyLevelBlocked
+	}
+	if len(textutil.MatchKeywords(text, c.warnWords)) > 0 {
+		return models.SafetyLevelWarn
+	}
+	return models.SafetyLevelSafe
+}
+
+// removeBlocked 去掉安全等级为 blocked 的建议，未配置分级时原样返回
+func (c *safetyChecker) removeBlocked(suggestions []models.Suggestion) []models.Suggestion {
+	if c == nil {
+		return suggestions
+	}
+	kept := suggestions[:0]
+	for _, suggestion := range suggestions {
+		if c.level(suggestion.Text) != models.SafetyLevelBlocked {
+			kept = append(kept, suggestion)
+		}
+	}
+	return kept
+}
+
+// markSafety 为建议逐条标注安全等级，返回是否有 warn 建议（文本模式下只有出现 warn 建议时才返回 items，让前端能看到需要提示的建议）
+func (c *safetyChecker) markSafety(suggestions []models.Suggestion) bool {
+	if c == nil {
+		return false
+	}
+	warned := false
+	for i := range suggestions {
+		suggestions[i].SafetyLevel = c.level(suggestions[i].Text)
+		if suggestions[i].SafetyLevel == models.SafetyLevelWarn {
+			warned = true
+		}
+	}
+	return warned
+}
@@ -0,0 +1,55 @@
+package autocomplete
+
+import (
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// 命中屏蔽词的建议被删除，命中警示词的照常返回并标记 warn；文本模式只有出现 warn 时才返回带等级的 items
+func TestGetSuggestionsSafetyLevels(t *testing.T) {
+	mock := &testutil.MockLLM{Suggestions: []string{"一起去赌场玩", "喝酒去吧", "去吃火锅吧"}}
+	e, db := newTestEngine(t, &config.AutocompleteConfig{
+		Safety: config.SafetyConfig{BlockedWords: []string{"赌场"}, WarnWords: []string{"喝酒"}},
+	}, mock)
+	createTestConversation(t, db, "conv-safety")
+
+	resp, err := e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-safety", SenderID: "alice", Input: "晚上"})
+	if err != nil {
+		t.Fatalf("获取补全建议失败: %v", err)
+	}
+	if len(resp.Suggestions) != 2 || resp.Suggestions[0] != "喝酒去吧" || resp.Suggestions[1] != "去吃火锅吧" {
+		t.Fatalf("建议为 %v，期望删除屏蔽建议", resp.Suggestions)
+	}
+	if len(resp.Items) != 2 || resp.Items[0].SafetyLevel != models.SafetyLevelWarn || resp.Items[1].SafetyLevel != models.SafetyLevelSafe {
+		t.Errorf("建议的安全等级为 %+v，期望 warn、safe", resp.Items)
+	}
+
+	mock.Suggestions = []string{"去吃火锅吧", "去看电影吧"}
+	resp, err = e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-safety", SenderID: "alice", Input: "周末"})
+	if err != nil {
+		t.Fatalf("获取补全建议失败: %v", err)
+	}
+	if len(resp.Suggestions) != 2 || resp.Items != nil {
+		t.Errorf("没有 warn 建议时文本模式不应返回 items: %+v", resp)
+	}
+
+	resp, err = e.GetSuggestions(&models.AutocompleteRequest{ConversationID: "conv-safety", SenderID: "alice", Input: "周末", ResponseFormat: models.ResponseFormatJSON})
+	if err != nil {
+		t.Fatalf("获取补全建议失败: %v", err)
+	}
+	if len(resp.Items) != 2 || resp.Items[0].SafetyLevel != models.SafetyLevelSafe {
+		t.Errorf("结构化模式应返回带安全等级的 items: %+v", resp.Items)
+	}
+}
+
+// 未配置词表时不分级
+func TestSafetyCheckerDisabled(t *testing.T) {
+	checker := newSafetyChecker(&config.SafetyConfig{})
+	suggestions := []models.Suggestion{{Text: "一起去赌场玩"}}
+	if checker != nil || len(checker.removeBlocked(suggestions)) != 1 || checker.markSafety(suggestions) || suggestions[0].SafetyLevel != "" {
+		t.Errorf("未配置词表时不应分级: %+v", suggestions)
+	}
+}
@@ -0,0 +1,28 @@
+package autocomplete
+
+import (
+	"testing"
+
+	"ChatRecommend/internal/models"
+)
+
+// TestCompleteOptionsOverrides 对话设置中的生成参数覆盖全局配置，未设置或设置非法时不覆盖
+func TestCompleteOptionsOverrides(t *testing.T) {
+	opts := completeOptions(&models.Conversation{Settings: `{"temperature":0.2,"max_tokens":64}`})
+	if opts == nil || opts.Temperature == nil || *opts.Temperature != 0.2 {
+		t.Fatalf("temperature 未覆盖: %+v", opts)
+	}
+	if opts.MaxTokens == nil || *opts.MaxTokens != 64 {
+		t.Errorf("max_tokens 未覆盖: %+v", opts)
+	}
+	if opts.TopP != nil {
+		t.Errorf("未设置的 top_p 应使用全局配置: %+v", opts)
+	}
+
+	for _, settings := range []string{"", "{}", `{"temperature":3}`, `not json`} {
+		opts := completeOptions(&models.Conversation{Settings: settings})
+		if opts != nil && (opts.Temperature != nil || opts.MaxTokens != nil || opts.TopP != nil) {
+			t.Errorf("设置 %q 不应覆盖全局参数: %+v", settings, opts)
+		}
+	}
+}
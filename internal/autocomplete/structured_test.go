@@ -0,0 +1,43 @@
+package autocomplete
+
+import (
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// 后处理改写文本、过滤和排序时，reason 和 tone 随建议一起移动
+func TestGetSuggestionsKeepsDetailsThroughPipeline(t *testing.T) {
+	mock := &testutil.MockLLM{Details: []models.Suggestion{
+		{Text: "我们2024-03-05见", Reason: "约定时间", Tone: "正式"},
+		{Text: "我们2024-03-05见", Reason: "重复", Tone: "重复"},
+		{Text: "不如改天", Reason: "婉拒", Tone: "委婉"},
+	}}
+	e, db := newTestEngine(t, &config.AutocompleteConfig{Locale: models.LocaleZhCN}, mock)
+	createTestConversation(t, db, "conv-pipeline")
+
+	resp, err := e.GetSuggestions(&models.AutocompleteRequest{
+		ConversationID: "conv-pipeline",
+		SenderID:       "alice",
+		Input:          "我们",
+		ResponseFormat: models.ResponseFormatJSON,
+	})
+	if err != nil {
+		t.Fatalf("获取补全建议失败: %v", err)
+	}
+
+	want := []models.Suggestion{
+		{Text: "2024年3月5日见", Reason: "约定时间", Tone: "正式"},
+		{Text: "不如改天", Reason: "婉拒", Tone: "委婉"},
+	}
+	if len(resp.Items) != len(want) || len(resp.Suggestions) != len(want) {
+		t.Fatalf("items 为 %+v，期望 %+v", resp.Items, want)
+	}
+	for i := range want {
+		if resp.Items[i] != want[i] || resp.Suggestions[i] != want[i].Text {
+			t.Fatalf("items 为 %+v，期望 %+v", resp.Items, want)
+		}
+	}
+}
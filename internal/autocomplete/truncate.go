@@ -0,0 +1,54 @@
+package autocomplete
+
+import (
+	"strings"
+	"unicode"
+)
+
+// 句末标点（截断后保留）
+const sentenceEnders = "。！？!?…；;"
+
+// 分句标点（截断后去掉）
+const clauseSeparators = "，,、：:"
+
+// truncateSuggestion 将超长建议截断到 maxChars 以内最近的句界，maxChars<=0 时不截断
+// 优先在句末标点处截断；没有句末标点时退到分句标点；都没有时英文退到最后一个空格，否则硬截断
+func truncateSuggestion(suggestion string, maxChars int) string {
+	runes := []rune(suggestion)
+	if maxChars <= 0 || len(runes) <= maxChars {
+		return suggestion
+	}
+
+	window := runes[:maxChars]
+	// 判断截断点后一个字符，用于识别英文句点（"end." 后接空格或结束才算句界，排除 3.14 之类）
+	next := func(i int) rune {
+		if i+1 < len(runes) {
+			return runes[i+1]
+		}
+		return ' '
+	}
+
+	lastClause := -1
+	lastSpace := -1
+	for i := len(window) - 1; i >= 0; i-- {
+		r := window[i]
+		switch {
+		case strings.ContainsRune(sentenceEnders, r):
+			return strings.TrimSpace(string(window[:i+1]))
+		case r == '.' && unicode.IsSpace(next(i)):
+			return strings.TrimSpace(string(window[:i+1]))
+		case lastClause < 0 && strings.ContainsRune(clauseSeparators, r):
+			lastClause = i
+		case lastSpace < 0 && unicode.IsSpace(r):
+			lastSpace = i
+		}
+	}
+
+	if lastClause > 0 {
+		return strings.TrimSpace(string(window[:lastClause]))
+	}
+	if lastSpace > 0 {
+		return strings.TrimSpace(string(window[:lastSpace]))
+	}
+	return string(window)
+}
@@ -0,0 +1,26 @@
+package autocomplete
+
+import "testing"
+
+func TestTruncateSuggestion(t *testing.T) {
+	cases := []struct {
+		name       string
+		suggestion string
+		maxChars   int
+		want       string
+	}{
+		{"未超长", "好的，明天见。", 20, "好的，明天见。"},
+		{"不限制", "好的，明天见。我们七点在门口集合吧", 0, "好的，明天见。我们七点在门口集合吧"},
+		{"中文句末标点", "好的，明天见。我们七点在门口集合吧", 10, "好的，明天见。"},
+		{"中文分句标点", "好的没问题，我们七点在门口集合吧", 10, "好的没问题"},
+		{"英文句点后接空格", "Sure, see you then. We can meet at seven", 25, "Sure, see you then."},
+		{"小数点不是句界", "It costs 3.14 dollars and more", 12, "It costs"},
+		{"英文单词边界", "see you tomorrow at the station", 18, "see you tomorrow"},
+		{"无句界时硬截断", "一二三四五六七八九十", 4, "一二三四"},
+	}
+	for _, tc := range cases {
+		if got := truncateSuggestion(tc.suggestion, tc.maxChars); got != tc.want {
+			t.Errorf("%s: truncateSuggestion(%q, %d) = %q，期望 %q", tc.name, tc.suggestion, tc.maxChars, got, tc.want)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
@@ -19,6 +20,16 @@ type Config struct {
 	Server       ServerConfig        `mapstructure:"server"`
 	Database     DatabaseConfig      `mapstructure:"database"`
 	Log          LogConfig           `mapstructure:"log"`
+	Webhooks     WebhookConfig       `mapstructure:"webhooks"`
+	Prompt       PromptConfig        `mapstructure:"prompt"`
+}
+
+// PromptConfig 系统提示词配置，支持 ${name} 模板变量
+type PromptConfig struct {
+	// 补全上下文最前面的系统提示词前缀（人设/规则），可用变量：conversation_id、sender_id、date
+	SystemPrefix        string `mapstructure:"system_prefix"`
+	// 摘要生成提示词前缀，可用变量：conversation_id、date
+	SummarySystemPrefix string `mapstructure:"summary_system_prefix"`
 }
 
 // LLMConfig 大模型配置
@@ -28,6 +39,12 @@ type LLMConfig struct {
 	ModelType        string    `mapstructure:"model_type"`
 	API              APIConfig `mapstructure:"api"`
 	Timeout          int       `mapstructure:"timeout"`
+	// 同时运行的Python进程上限，0表示不限制
+	MaxConcurrency   int       `mapstructure:"max_concurrency"`
+	// 排队的请求每等待该时长（毫秒）优先级提升一级，避免低优先级请求饿死，0表示使用默认值2000
+	PriorityAgingMs  int       `mapstructure:"priority_aging_ms"`
+	// 每个用户每天的补全大模型调用次数上限，0表示不限制
+	DailyQuota       int       `mapstructure:"daily_quota"`
 }
 
 // APIConfig API配置
@@ -47,6 +64,46 @@ type ContextConfig struct {
 	MaxContextTokens    int `mapstructure:"max_context_tokens"`
 	RecentMessagesCount int `mapstructure:"recent_messages_count"`
 	HistoryRetentionCount int `mapstructure:"history_retention_count"`
+	// 单条消息注入上下文的最大字符数，超过时按 long_message_mode 处理，0表示不限制
+	MaxMessageChars     int `mapstructure:"max_message_chars"`
+	// 超长消息的注入方式：placeholder（默认，只注入摘要占位）、excerpt（注入首尾节选）
+	LongMessageMode     string `mapstructure:"long_message_mode"`
+	// 近期消息时间衰减半衰期（分钟），超过一个半衰期的消息会被标注为较早的消息，0表示不标注
+	DecayHalfLifeMinutes int `mapstructure:"decay_half_life_minutes"`
+	// 是否注入用户档案中与当前输入相关的字段（可被对话设置 profile_injection 覆盖）
+	ProfileInjection     bool `mapstructure:"profile_injection"`
+	// 是否分析近期对方情绪（愤怒/悲伤/开心），并在上下文中提示补全语气
+	EmotionAware         bool `mapstructure:"emotion_aware"`
+	// 参与情绪分析的近期消息数，0表示使用默认值10
+	EmotionWindow        int  `mapstructure:"emotion_window"`
+	// 近期消息超过该条数时，较早的消息压成临时摘要注入，0表示不压缩
+	CompressThreshold    int  `mapstructure:"compress_threshold"`
+	// 压缩时保留原文的最新消息数，0表示使用默认值10
+	CompressKeepRecent   int  `mapstructure:"compress_keep_recent"`
+	// 是否对上下文中的手机号、身份证号、银行卡号、邮箱打码后再发送给大模型
+	MaskPII              bool `mapstructure:"mask_pii"`
+	// 从用户在对话中的历史回复里挑选注入的 few-shot 示例数，0表示不注入
+	FewShotCount         int  `mapstructure:"few_shot_count"`
+	// 挑选示例时扫描的最近消息数，0表示使用默认值500
+	FewShotScanMessages  int  `mapstructure:"few_shot_scan_messages"`
+	// 示例中单条消息的最大字符数，超长时只保留首尾节选，0表示使用默认值80
+	FewShotMaxChars      int  `mapstructure:"few_shot_max_chars"`
+	// 是否在上下文中注入当前时间（按对话设置、发送者档案或 server.timezone 的时区）
+	CurrentTime          bool `mapstructure:"current_time"`
+	// 近期消息滑动窗口缓存
+	Window               ContextWindowConfig `mapstructure:"window"`
+}
+
+// ContextWindowConfig 近期消息滑动窗口缓存配置
+type ContextWindowConfig struct {
+	// 是否启用：启用后每个对话在内存中维护近期消息窗口，新消息追加进窗口，补全时不再重查近期消息
+	Enabled          bool `mapstructure:"enabled"`
+	// 窗口内消息的token预算，超出时最早的消息被挤出窗口、压进临时摘要，0表示使用 max_context_tokens 的一半
+	MaxTokens        int  `mapstructure:"max_tokens"`
+	// 最多缓存窗口的对话数，超出时淘汰最久未使用的，0表示使用默认值1000
+	MaxConversations int  `mapstructure:"max_conversations"`
+	// 窗口的有效期（秒），超过后从数据库重建，0表示使用默认值300
+	TTLSeconds       int  `mapstructure:"ttl_seconds"`
 }
 
 // SummaryConfig 对话摘要配置
@@ -56,6 +113,30 @@ type SummaryConfig struct {
 	MaxSummaryTokens        int  `mapstructure:"max_summary_tokens"`
 	KeyInfoCount            int  `mapstructure:"key_info_count"`
 	AutoUpdate              bool `mapstructure:"auto_update"`
+	// 单条消息送去生成摘要的最大字符数，超长消息只取首尾节选，0表示不限制
+	MaxMessageChars         int  `mapstructure:"max_message_chars"`
+	// 关键信息合并策略：overwrite（默认，新值覆盖旧值）、higher_confidence（保留置信度高的）、none（不合并）
+	KeyInfoMergeStrategy    string `mapstructure:"key_info_merge_strategy"`
+	// 两次摘要重算的最小间隔（秒），间隔内即使达到消息阈值也不重算，0表示不限制
+	MinIntervalSeconds      int    `mapstructure:"min_interval_seconds"`
+	// 摘要格式：bullets（要点式）、narrative（叙事式，默认）、timeline（时间线式），可按对话覆盖
+	Style                   string `mapstructure:"style"`
+	// 大模型不可用或没有给出关键信息时，用规则从消息中提取日期、地点、偏好作为关键信息兜底
+	RuleKeyInfo             bool   `mapstructure:"rule_key_info"`
+	// 后台定时更新到期摘要
+	Worker                  SummaryWorkerConfig `mapstructure:"worker"`
+}
+
+// SummaryWorkerConfig 后台摘要更新配置
+type SummaryWorkerConfig struct {
+	// 执行间隔（秒），0表示不启动
+	IntervalSeconds int    `mapstructure:"interval_seconds"`
+	// 每轮最多更新的对话数，0表示不限制
+	BatchSize       int    `mapstructure:"batch_size"`
+	// 优先级策略：recent（默认，最近活跃的对话先更新）、sequential（按对话ID轮流扫描）
+	Priority        string `mapstructure:"priority"`
+	// 最后消息早于该天数的对话不再后台更新，0表示不跳过
+	InactiveDays    int    `mapstructure:"inactive_days"`
 }
 
 // StyleConfig 语言风格学习配置
@@ -64,6 +145,24 @@ type StyleConfig struct {
 	FeatureDimensions     []string `mapstructure:"feature_dimensions"`
 	UpdateThresholdMessages int    `mapstructure:"update_threshold_messages"`
 	Enabled               bool     `mapstructure:"enabled"`
+	// 停用词表路径（每行一个词），为空时使用内置中文停用词表
+	StopwordsPath         string   `mapstructure:"stopwords_path"`
+	// 自定义词典路径（每行一个词），用于补充专有词
+	UserDictPath          string   `mapstructure:"user_dict_path"`
+	// 单条消息参与风格分析的最大字符数，超长消息只取首尾采样，0表示不限制
+	MaxAnalyzeChars       int      `mapstructure:"max_analyze_chars"`
+	// 风格描述输出语言：zh（默认）、en
+	DescriptionLang       string   `mapstructure:"description_lang"`
+	// 两次风格重算的最小间隔（秒），间隔内即使达到消息阈值也不重算，0表示不限制
+	MinIntervalSeconds    int      `mapstructure:"min_interval_seconds"`
+	// 启用的风格特征提取器（按顺序执行），为空时启用全部内置提取器
+	Extractors            []string `mapstructure:"extractors"`
+	// 风格提示词优先使用的画像：conversation（默认，对话级）或 user（用户级），优先的画像为空时使用另一个
+	PromptSource          string   `mapstructure:"prompt_source"`
+	// 用户级画像使用该用户在所有对话中最近的消息数，0表示使用 learning_messages_count 的4倍
+	UserLearningMessagesCount int  `mapstructure:"user_learning_messages_count"`
+	// 已解析风格特征的缓存有效期（秒），超过后重新读库，0表示使用默认值300
+	CacheTTLSeconds       int      `mapstructure:"cache_ttl_seconds"`
 }
 
 // AutocompleteConfig 自动补全配置
@@ -71,6 +170,129 @@ type AutocompleteConfig struct {
 	MinTriggerLength int `mapstructure:"min_trigger_length"`
 	SuggestionCount  int `mapstructure:"suggestion_count"`
 	DebounceMs       int `mapstructure:"debounce_ms"`
+	// 单条建议最大字符数，超出时截断到最近的句界，0表示不限制
+	MaxSuggestionChars int `mapstructure:"max_suggestion_chars"`
+	// 建议相似度阈值（字符二元组Jaccard相似度），达到该值的雷同建议会被剔除，0表示只去除完全相同的建议
+	DiversityThreshold float64 `mapstructure:"diversity_threshold"`
+	// 补全结果缓存时间（秒），0表示不缓存
+	CacheTTLSeconds  int            `mapstructure:"cache_ttl_seconds"`
+	// 缓存键是否使用原始输入；默认归一化输入（合并连续空白、去掉末尾空白）以提高命中率
+	CacheExactInput  bool           `mapstructure:"cache_exact_input"`
+	// 相同 request_id 的请求结果保留时间（秒），0表示不做请求去重
+	RequestIDTTLSeconds int         `mapstructure:"request_id_ttl_seconds"`
+	// 补全请求可指定的 max_tokens 上限（默认1024）
+	MaxRequestTokens int            `mapstructure:"max_request_tokens"`
+	// 补全请求可指定的 max_suggestions 上限（默认10）
+	MaxRequestSuggestions int       `mapstructure:"max_request_suggestions"`
+	// 补全请求按 high 优先级排队的用户（sender_id，如付费用户），其余用户为 normal
+	HighPriorityUsers []string      `mapstructure:"high_priority_users"`
+	// 是否检测输入中的疑似拼音、首字母缩写和错别字，并作为提示注入上下文
+	InputCorrection  bool           `mapstructure:"input_correction"`
+	// 是否在补全结果中标注建议引用的关键信息及其来源消息
+	CiteKeyInfo      bool           `mapstructure:"cite_key_info"`
+	// 建议后处理器及执行顺序（strip_overlap、fill_placeholders、localize、language、safety、grammar、dedupe、rank、limit、truncate，以及通过 RegisterPostprocessor 注册的自定义处理器），为空时使用默认顺序
+	Postprocessors   []string       `mapstructure:"postprocessors"`
+	// 输入预处理器及执行顺序（strip_invisible、normalize_newlines、normalize_width、collapse_whitespace），为空时使用默认顺序，[none] 表示关闭
+	InputPreprocessors []string     `mapstructure:"input_preprocessors"`
+	// 建议中日期和金额的默认区域格式（zh-CN、en-US、en-GB），为空表示不改写，可按对话覆盖
+	Locale           string         `mapstructure:"locale"`
+	// 每个对话保留的补全历史条数（超出时删除最旧的），0表示不记录补全历史
+	CompletionLogLimit int          `mapstructure:"completion_log_limit"`
+	Prefetch         PrefetchConfig `mapstructure:"prefetch"`
+	// 是否检查英文建议的语法（修正明显错误，无法修正的降权）
+	GrammarCheck     bool           `mapstructure:"grammar_check"`
+	// 建议安全分级词表
+	Safety           SafetyConfig   `mapstructure:"safety"`
+	// 模糊输入的澄清
+	Clarification    ClarificationConfig `mapstructure:"clarification"`
+	// 按上下文长度动态分配生成的 max_tokens
+	TokenBudget      TokenBudgetConfig `mapstructure:"token_budget"`
+	// 大模型延迟过高时的自动降级
+	Degradation      DegradationConfig `mapstructure:"degradation"`
+	// 按历史采纳率调整建议排序
+	Ranking          RankingConfig  `mapstructure:"ranking"`
+	// 快捷补全规则，命中时不再调用大模型
+	Rules            []RuleConfig   `mapstructure:"rules"`
+}
+
+// SafetyConfig 补全建议安全分级配置，两个词表都为空时不分级
+type SafetyConfig struct {
+	// 命中即删除建议（blocked）
+	BlockedWords []string `mapstructure:"blocked_words"`
+	// 命中时照常返回但标记为 warn
+	WarnWords    []string `mapstructure:"warn_words"`
+}
+
+// TokenBudgetConfig 补全 token 预算配置：生成可用的 max_tokens = 总预算 - 上下文估算 token 数，
+// 并限制在 [min_output_tokens, max_output_tokens] 之间；total_tokens 为0时不启用
+type TokenBudgetConfig struct {
+	// 单次补全的总 token 预算（一般取模型上下文窗口，可略小以留出余量）
+	TotalTokens     int `mapstructure:"total_tokens"`
+	// 生成至少保留的 token 数，默认32
+	MinOutputTokens int `mapstructure:"min_output_tokens"`
+	// 生成最多使用的 token 数，默认512
+	MaxOutputTokens int `mapstructure:"max_output_tokens"`
+}
+
+// ClarificationConfig 模糊输入澄清配置：输入模糊且对话近期消息不足时返回澄清提示而不是补全
+type ClarificationConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// 去掉标点和空白后不超过该字符数的输入视为模糊（只由语气词组成的输入不论长短都视为模糊），默认2
+	MaxVagueChars        int `mapstructure:"max_vague_chars"`
+	// 统计近期消息的时间窗口（分钟），默认30
+	ContextWindowMinutes int `mapstructure:"context_window_minutes"`
+	// 窗口内消息少于该条数时视为上下文不足，默认2
+	MinContextMessages   int `mapstructure:"min_context_messages"`
+}
+
+// RuleConfig 快捷补全规则配置
+type RuleConfig struct {
+	Name      string   `mapstructure:"name"`
+	Pattern   string   `mapstructure:"pattern"`
+	Templates []string `mapstructure:"templates"`
+	Priority  int      `mapstructure:"priority"`
+}
+
+// PrefetchConfig 补全预取配置
+type PrefetchConfig struct {
+	// 是否在收到对方消息后预取补全
+	Enabled        bool     `mapstructure:"enabled"`
+	// 预取的常见开头
+	Prefixes       []string `mapstructure:"prefixes"`
+	// 预取最大并发数
+	MaxConcurrency int      `mapstructure:"max_concurrency"`
+}
+
+// DegradationConfig 补全自动降级配置：按滑动窗口内大模型调用耗时的P95逐级降级，恢复后自动升级
+type DegradationConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// 滑动窗口（秒，默认60）
+	WindowSeconds int `mapstructure:"window_seconds"`
+	// 窗口内样本数达到该值才评估（默认10）
+	MinSamples int `mapstructure:"min_samples"`
+	// P95达到该值（毫秒）时降级为 reduced（默认3000）
+	DegradeP95Ms int `mapstructure:"degrade_p95_ms"`
+	// P95达到该值（毫秒）时降级为 local，暂停调用大模型（默认8000）
+	LocalP95Ms int `mapstructure:"local_p95_ms"`
+	// P95低于该值（毫秒）时恢复正常（默认1500，应小于 degrade_p95_ms 以免来回切换）
+	RecoverP95Ms int `mapstructure:"recover_p95_ms"`
+	// local 级别下放行探测请求的间隔（秒，默认10）
+	ProbeIntervalSeconds int `mapstructure:"probe_interval_seconds"`
+	// reduced 级别的建议数上限（默认1）和 max_tokens 上限（默认64）
+	ReducedSuggestions int `mapstructure:"reduced_suggestions"`
+	ReducedMaxTokens   int `mapstructure:"reduced_max_tokens"`
+}
+
+// RankingConfig 采纳率排序配置：定期从补全记录聚合建议特征（开头、句式）的采纳率权重，
+// 后处理 rank 步骤按权重调整建议顺序，没有足够样本时保持默认顺序
+type RankingConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// 重新聚合权重的间隔（秒，默认600）
+	IntervalSeconds int `mapstructure:"interval_seconds"`
+	// 聚合最近多少条补全记录（含未采纳的，默认5000）
+	MaxLogs int `mapstructure:"max_logs"`
+	// 特征的展示次数达到该值才参与排序（默认20）
+	MinSamples int `mapstructure:"min_samples"`
 }
 
 // ServerConfig 服务器配置
@@ -78,12 +300,65 @@ type ServerConfig struct {
 	HTTPPort      int      `mapstructure:"http_port"`
 	WSPort        int      `mapstructure:"ws_port"`
 	AllowedOrigins []string `mapstructure:"allowed_origins"`
+	// 管理员令牌（请求头 X-Admin-Token），为空时管理接口不可用
+	AdminToken     string   `mapstructure:"admin_token"`
+	// 是否开放调试接口（/api/debug），默认关闭；开放后同样需要管理员令牌
+	DebugEndpoints bool     `mapstructure:"debug_endpoints"`
+	// 时区（IANA名称，如 Asia/Shanghai），用于按天分组等日期计算，默认使用服务器本地时区
+	Timezone       string   `mapstructure:"timezone"`
+	// 批量重新分析的限速配置
+	Reanalyze      ReanalyzeConfig `mapstructure:"reanalyze"`
+	// 相邻重复消息检测
+	Dedupe         DedupeConfig    `mapstructure:"dedupe"`
+}
+
+// DedupeConfig 相邻重复消息检测配置：新消息与对话最近一条消息的发送者和内容相同且时间相差不超过窗口时视为重复
+type DedupeConfig struct {
+	// 时间窗口（秒），0表示不检测
+	WindowSeconds int    `mapstructure:"window_seconds"`
+	// 处理方式：merge（默认，不写入并返回已有消息）、reject（拒绝，返回409）；批量导入总是丢弃重复消息
+	Action        string `mapstructure:"action"`
 }
 
+// ReanalyzeConfig 批量重新分析配置
+type ReanalyzeConfig struct {
+	// 每批从数据库读取的对话数（默认20）
+	BatchSize  int `mapstructure:"batch_size"`
+	// 每处理完一个对话后的等待时间（毫秒，默认1000），用于限制大模型调用和数据库写入频率
+	IntervalMs int `mapstructure:"interval_ms"`
+}
+
+// Location 返回配置的时区，未配置时为本地时区（时区名已在加载配置时校验）
+func (s *ServerConfig) Location() *time.Location {
+	if s.Timezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// MemoryDBPath 使用内存数据库的 db_path（数据不落盘，进程退出即丢失，用于测试）
+const MemoryDBPath = ":memory:"
+
 // DatabaseConfig 数据库配置
 type DatabaseConfig struct {
 	DBPath  string `mapstructure:"db_path"`
 	LogMode bool   `mapstructure:"log_mode"`
+	// 消息内容加密密钥，为空时不加密
+	EncryptionKey        string `mapstructure:"encryption_key"`
+	// 当前密钥版本（写入密文前缀，用于密钥轮换）
+	EncryptionKeyVersion string `mapstructure:"encryption_key_version"`
+	// 历史密钥（版本 -> 密钥），仅用于解密轮换前写入的数据
+	OldEncryptionKeys    map[string]string `mapstructure:"old_encryption_keys"`
+	// SQLite日志模式（默认WAL，读写可并发）
+	JournalMode string `mapstructure:"journal_mode"`
+	// 遇到锁时的等待时间（毫秒，默认5000）
+	BusyTimeoutMs int `mapstructure:"busy_timeout_ms"`
+	// 同步模式（默认NORMAL，WAL模式下兼顾安全与写入性能）
+	Synchronous string `mapstructure:"synchronous"`
 }
 
 // LogConfig 日志配置
@@ -94,6 +369,20 @@ type LogConfig struct {
 	FilePath string `mapstructure:"file_path"`
 }
 
+// WebhookConfig webhook回调配置
+type WebhookConfig struct {
+	Endpoints      []WebhookEndpoint `mapstructure:"endpoints"`
+	MaxRetries     int               `mapstructure:"max_retries"`
+	TimeoutSeconds int               `mapstructure:"timeout_seconds"`
+}
+
+// WebhookEndpoint webhook接收地址
+type WebhookEndpoint struct {
+	URL    string   `mapstructure:"url"`
+	Secret string   `mapstructure:"secret"`
+	Events []string `mapstructure:"events"`
+}
+
 var globalConfig *Config
 
 // Load 加载配置文件
@@ -112,6 +401,7 @@ func Load(configPath string) (*Config, error) {
 	if err := viper.ReadInConfig(); err != nil {
 		return nil, fmt.Errorf("读取配置文件失败: %w", err)
 	}
+	setDefaults(viper.GetViper())
 
 	config := &Config{}
 	if err := viper.Unmarshal(config); err != nil {
@@ -124,7 +414,7 @@ func Load(configPath string) (*Config, error) {
 	}
 
 	// 确保数据目录存在
-	if config.Database.DBPath != "" {
+	if config.Database.DBPath != "" && !config.Database.InMemory() {
 		dbDir := filepath.Dir(config.Database.DBPath)
 		if err := os.MkdirAll(dbDir, 0755); err != nil {
 			return nil, fmt.Errorf("创建数据目录失败: %w", err)
@@ -143,27 +433,103 @@ func Load(configPath string) (*Config, error) {
 	return config, nil
 }
 
+// setDefaults 设置配置文件中缺省字段的默认值（只在字段缺失时生效，显式写出的值包括0都保留）。
+// 所有默认值都在这里设置，validateConfig 只做校验；0表示"不启用/不限制"的字段不设默认值
+func setDefaults(v *viper.Viper) {
+	v.SetDefault("llm.python_script", "./python/llm_client.py")
+	v.SetDefault("llm.python_interpreter", "python3")
+	v.SetDefault("llm.model_type", "openai")
+	v.SetDefault("llm.timeout", 30)
+	v.SetDefault("llm.api.temperature", 0.7)
+	v.SetDefault("llm.api.max_tokens", 2000)
+	v.SetDefault("llm.api.top_p", 1.0)
+
+	v.SetDefault("context.max_context_tokens", 4000)
+	v.SetDefault("context.recent_messages_count", 50)
+	v.SetDefault("context.history_retention_count", 1000)
+	v.SetDefault("context.current_time", true)
+	v.SetDefault("context.long_message_mode", "placeholder")
+
+	v.SetDefault("summary.update_threshold_messages", 100)
+	v.SetDefault("summary.update_threshold_hours", 24)
+	v.SetDefault("summary.max_summary_tokens", 500)
+	v.SetDefault("summary.key_info_count", 10)
+	v.SetDefault("summary.auto_update", true)
+	v.SetDefault("summary.rule_key_info", true)
+	v.SetDefault("summary.key_info_merge_strategy", "overwrite")
+	v.SetDefault("summary.style", "narrative")
+	v.SetDefault("summary.worker.priority", "recent")
+
+	v.SetDefault("style.learning_messages_count", 50)
+	v.SetDefault("style.update_threshold_messages", 20)
+	v.SetDefault("style.enabled", true)
+	v.SetDefault("style.cache_ttl_seconds", 300)
+	v.SetDefault("style.prompt_source", "conversation")
+
+	v.SetDefault("autocomplete.min_trigger_length", 3)
+	v.SetDefault("autocomplete.suggestion_count", 3)
+	v.SetDefault("autocomplete.debounce_ms", 300)
+	v.SetDefault("autocomplete.max_request_tokens", 1024)
+	v.SetDefault("autocomplete.max_request_suggestions", 10)
+	v.SetDefault("autocomplete.degradation.window_seconds", 60)
+	v.SetDefault("autocomplete.degradation.min_samples", 10)
+	v.SetDefault("autocomplete.degradation.degrade_p95_ms", 3000)
+	v.SetDefault("autocomplete.degradation.local_p95_ms", 8000)
+	v.SetDefault("autocomplete.degradation.recover_p95_ms", 1500)
+	v.SetDefault("autocomplete.degradation.probe_interval_seconds", 10)
+	v.SetDefault("autocomplete.degradation.reduced_suggestions", 1)
+	v.SetDefault("autocomplete.degradation.reduced_max_tokens", 64)
+	v.SetDefault("autocomplete.ranking.interval_seconds", 600)
+	v.SetDefault("autocomplete.ranking.max_logs", 5000)
+	v.SetDefault("autocomplete.ranking.min_samples", 20)
+	v.SetDefault("autocomplete.clarification.max_vague_chars", 2)
+	v.SetDefault("autocomplete.clarification.context_window_minutes", 30)
+	v.SetDefault("autocomplete.clarification.min_context_messages", 2)
+	v.SetDefault("autocomplete.token_budget.min_output_tokens", 32)
+	v.SetDefault("autocomplete.token_budget.max_output_tokens", 512)
+
+	v.SetDefault("server.http_port", 8080)
+	v.SetDefault("server.ws_port", 8081)
+	v.SetDefault("server.debug_endpoints", false)
+	v.SetDefault("server.reanalyze.batch_size", 20)
+	v.SetDefault("server.reanalyze.interval_ms", 1000)
+	v.SetDefault("server.dedupe.action", "merge")
+
+	v.SetDefault("database.db_path", "./data/chat.db")
+	v.SetDefault("database.encryption_key_version", "v1")
+	v.SetDefault("database.journal_mode", "WAL")
+	v.SetDefault("database.busy_timeout_ms", 5000)
+	v.SetDefault("database.synchronous", "NORMAL")
+
+	v.SetDefault("log.level", "info")
+	v.SetDefault("log.format", "text")
+	v.SetDefault("log.output", "stdout")
+
+	v.SetDefault("webhooks.max_retries", 3)
+	v.SetDefault("webhooks.timeout_seconds", 5)
+}
+
 // Get 获取全局配置
 func Get() *Config {
 	return globalConfig
 }
 
-// validateConfig 验证配置
+// validateConfig 验证配置，错误信息中给出字段的完整路径；默认值由 setDefaults 设置
 func validateConfig(cfg *Config) error {
-	if cfg.LLM.PythonScript == "" {
-		return fmt.Errorf("python_script 不能为空")
-	}
-	if cfg.LLM.Timeout <= 0 {
-		return fmt.Errorf("timeout 必须大于0")
-	}
-	if cfg.Context.MaxContextTokens <= 0 {
-		return fmt.Errorf("max_context_tokens 必须大于0")
-	}
-	if cfg.Server.HTTPPort <= 0 {
-		return fmt.Errorf("http_port 必须大于0")
-	}
-	if cfg.Server.WSPort <= 0 {
-		return fmt.Errorf("ws_port 必须大于0")
+	for _, validate := range []func(*Config) error{
+		validateLLM,
+		validateContext,
+		validateSummary,
+		validateStyle,
+		validateAutocomplete,
+		validateServer,
+		validateDatabase,
+		validateLog,
+		validateWebhooks,
+	} {
+		if err := validate(cfg); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -194,3 +560,381 @@ func InitLogger(cfg *LogConfig) error {
 	return nil
 }
 
+// InMemory 是否使用内存数据库
+func (c *DatabaseConfig) InMemory() bool {
+	return c.DBPath == MemoryDBPath
+}
+
+// checkPositive 检查字段大于0
+func checkPositive(name string, value int) error {
+	if value <= 0 {
+		return fmt.Errorf("%s 必须大于0，当前为 %d", name, value)
+	}
+	return nil
+}
+
+// checkNonNegative 依次检查字段不为负数，fields 为字段名和值交替排列
+func checkNonNegative(fields ...interface{}) error {
+	for i := 0; i+1 < len(fields); i += 2 {
+		if value := fields[i+1].(int); value < 0 {
+			return fmt.Errorf("%s 不能为负数，当前为 %d", fields[i], value)
+		}
+	}
+	return nil
+}
+
+// checkRange 检查浮点字段在 [min, max] 之间
+func checkRange(name string, value, min, max float64) error {
+	if value < min || value > max {
+		return fmt.Errorf("%s 必须在 %g 到 %g 之间，当前为 %g", name, min, max, value)
+	}
+	return nil
+}
+
+// validateLLM 校验大模型配置
+func validateLLM(cfg *Config) error {
+	llm := &cfg.LLM
+	if llm.PythonScript == "" {
+		return fmt.Errorf("llm.python_script 不能为空")
+	}
+	if llm.PythonInterpreter == "" {
+		return fmt.Errorf("llm.python_interpreter 不能为空")
+	}
+	switch llm.ModelType {
+	case "openai", "anthropic":
+	default:
+		return fmt.Errorf("llm.model_type 不支持: %q（可选 openai、anthropic）", llm.ModelType)
+	}
+	if err := checkPositive("llm.timeout", llm.Timeout); err != nil {
+		return err
+	}
+	if err := checkPositive("llm.api.max_tokens", llm.API.MaxTokens); err != nil {
+		return err
+	}
+	if err := checkRange("llm.api.temperature", llm.API.Temperature, 0, 2); err != nil {
+		return err
+	}
+	if llm.API.TopP <= 0 || llm.API.TopP > 1 {
+		return fmt.Errorf("llm.api.top_p 必须大于0且不超过1，当前为 %g", llm.API.TopP)
+	}
+	if err := checkRange("llm.api.frequency_penalty", llm.API.FrequencyPenalty, -2, 2); err != nil {
+		return err
+	}
+	if err := checkRange("llm.api.presence_penalty", llm.API.PresencePenalty, -2, 2); err != nil {
+		return err
+	}
+	return checkNonNegative(
+		"llm.max_concurrency", llm.MaxConcurrency,
+		"llm.priority_aging_ms", llm.PriorityAgingMs,
+		"llm.daily_quota", llm.DailyQuota,
+	)
+}
+
+// validateContext 校验上下文配置
+func validateContext(cfg *Config) error {
+	ctx := &cfg.Context
+	if err := checkPositive("context.max_context_tokens", ctx.MaxContextTokens); err != nil {
+		return err
+	}
+	if err := checkPositive("context.recent_messages_count", ctx.RecentMessagesCount); err != nil {
+		return err
+	}
+	switch ctx.LongMessageMode {
+	case "placeholder", "excerpt":
+	default:
+		return fmt.Errorf("context.long_message_mode 不支持 %q，可选 placeholder、excerpt", ctx.LongMessageMode)
+	}
+	return checkNonNegative(
+		"context.history_retention_count", ctx.HistoryRetentionCount,
+		"context.max_message_chars", ctx.MaxMessageChars,
+		"context.decay_half_life_minutes", ctx.DecayHalfLifeMinutes,
+		"context.emotion_window", ctx.EmotionWindow,
+		"context.compress_threshold", ctx.CompressThreshold,
+		"context.compress_keep_recent", ctx.CompressKeepRecent,
+		"context.few_shot_count", ctx.FewShotCount,
+		"context.few_shot_scan_messages", ctx.FewShotScanMessages,
+		"context.few_shot_max_chars", ctx.FewShotMaxChars,
+		"context.window.max_tokens", ctx.Window.MaxTokens,
+		"context.window.max_conversations", ctx.Window.MaxConversations,
+		"context.window.ttl_seconds", ctx.Window.TTLSeconds,
+	)
+}
+
+// validateSummary 校验摘要配置
+func validateSummary(cfg *Config) error {
+	summary := &cfg.Summary
+	if err := checkPositive("summary.update_threshold_messages", summary.UpdateThresholdMessages); err != nil {
+		return err
+	}
+	if err := checkPositive("summary.update_threshold_hours", summary.UpdateThresholdHours); err != nil {
+		return err
+	}
+	if err := checkPositive("summary.max_summary_tokens", summary.MaxSummaryTokens); err != nil {
+		return err
+	}
+	if err := checkPositive("summary.key_info_count", summary.KeyInfoCount); err != nil {
+		return err
+	}
+	if err := checkNonNegative(
+		"summary.max_message_chars", summary.MaxMessageChars,
+		"summary.min_interval_seconds", summary.MinIntervalSeconds,
+		"summary.worker.interval_seconds", summary.Worker.IntervalSeconds,
+		"summary.worker.batch_size", summary.Worker.BatchSize,
+		"summary.worker.inactive_days", summary.Worker.InactiveDays,
+	); err != nil {
+		return err
+	}
+	switch summary.KeyInfoMergeStrategy {
+	case "overwrite", "higher_confidence", "none":
+	default:
+		return fmt.Errorf("summary.key_info_merge_strategy 不支持: %s", summary.KeyInfoMergeStrategy)
+	}
+	switch summary.Style {
+	case "bullets", "narrative", "timeline":
+	default:
+		return fmt.Errorf("summary.style 不支持: %s", summary.Style)
+	}
+	switch summary.Worker.Priority {
+	case "recent", "sequential":
+	default:
+		return fmt.Errorf("summary.worker.priority 不支持: %s", summary.Worker.Priority)
+	}
+	return nil
+}
+
+// validateStyle 校验语言风格学习配置
+func validateStyle(cfg *Config) error {
+	style := &cfg.Style
+	if err := checkPositive("style.learning_messages_count", style.LearningMessagesCount); err != nil {
+		return err
+	}
+	if err := checkPositive("style.update_threshold_messages", style.UpdateThresholdMessages); err != nil {
+		return err
+	}
+	switch style.DescriptionLang {
+	case "", "zh", "en":
+	default:
+		return fmt.Errorf("style.description_lang 不支持: %q（可选 zh、en）", style.DescriptionLang)
+	}
+	switch style.PromptSource {
+	case "conversation", "user":
+	default:
+		return fmt.Errorf("style.prompt_source 不支持: %s", style.PromptSource)
+	}
+	return checkNonNegative(
+		"style.max_analyze_chars", style.MaxAnalyzeChars,
+		"style.min_interval_seconds", style.MinIntervalSeconds,
+		"style.user_learning_messages_count", style.UserLearningMessagesCount,
+		"style.cache_ttl_seconds", style.CacheTTLSeconds,
+	)
+}
+
+// validateAutocomplete 校验自动补全配置及各子配置段
+func validateAutocomplete(cfg *Config) error {
+	ac := &cfg.Autocomplete
+	if err := checkPositive("autocomplete.suggestion_count", ac.SuggestionCount); err != nil {
+		return err
+	}
+	if err := checkRange("autocomplete.diversity_threshold", ac.DiversityThreshold, 0, 1); err != nil {
+		return err
+	}
+	if ac.Prefetch.Enabled {
+		if err := checkPositive("autocomplete.prefetch.max_concurrency", ac.Prefetch.MaxConcurrency); err != nil {
+			return err
+		}
+	}
+	if err := checkNonNegative(
+		"autocomplete.min_trigger_length", ac.MinTriggerLength,
+		"autocomplete.debounce_ms", ac.DebounceMs,
+		"autocomplete.max_suggestion_chars", ac.MaxSuggestionChars,
+		"autocomplete.cache_ttl_seconds", ac.CacheTTLSeconds,
+		"autocomplete.request_id_ttl_seconds", ac.RequestIDTTLSeconds,
+		"autocomplete.completion_log_limit", ac.CompletionLogLimit,
+	); err != nil {
+		return err
+	}
+	if err := checkPositive("autocomplete.max_request_tokens", ac.MaxRequestTokens); err != nil {
+		return err
+	}
+	if err := checkPositive("autocomplete.max_request_suggestions", ac.MaxRequestSuggestions); err != nil {
+		return err
+	}
+	switch ac.Locale {
+	case "", "zh-CN", "en-US", "en-GB":
+	default:
+		return fmt.Errorf("autocomplete.locale 不支持: %s", ac.Locale)
+	}
+	if err := validateDegradation(&ac.Degradation); err != nil {
+		return err
+	}
+	if err := validateRanking(&ac.Ranking); err != nil {
+		return err
+	}
+	if err := validateClarification(&ac.Clarification); err != nil {
+		return err
+	}
+	return validateTokenBudget(&ac.TokenBudget)
+}
+
+// validateServer 校验服务器配置
+func validateServer(cfg *Config) error {
+	for _, port := range []struct {
+		name  string
+		value int
+	}{
+		{"server.http_port", cfg.Server.HTTPPort},
+		{"server.ws_port", cfg.Server.WSPort},
+	} {
+		if port.value <= 0 || port.value > 65535 {
+			return fmt.Errorf("%s 必须在 1 到 65535 之间，当前为 %d", port.name, port.value)
+		}
+	}
+	server := &cfg.Server
+	if server.Timezone != "" {
+		if _, err := time.LoadLocation(server.Timezone); err != nil {
+			return fmt.Errorf("server.timezone 无效: %w", err)
+		}
+	}
+	if err := checkPositive("server.reanalyze.batch_size", server.Reanalyze.BatchSize); err != nil {
+		return err
+	}
+	if err := checkNonNegative(
+		"server.reanalyze.interval_ms", server.Reanalyze.IntervalMs,
+		"server.dedupe.window_seconds", server.Dedupe.WindowSeconds,
+	); err != nil {
+		return err
+	}
+	switch server.Dedupe.Action {
+	case "merge", "reject":
+	default:
+		return fmt.Errorf("server.dedupe.action 不支持: %s", server.Dedupe.Action)
+	}
+	return nil
+}
+
+// validateDatabase 校验数据库连接参数和加密密钥版本
+func validateDatabase(cfg *Config) error {
+	db := &cfg.Database
+	if db.EncryptionKey != "" && db.EncryptionKeyVersion == "" {
+		return fmt.Errorf("database.encryption_key_version 启用加密时不能为空")
+	}
+	if db.JournalMode == "" {
+		return fmt.Errorf("database.journal_mode 不能为空")
+	}
+	if db.Synchronous == "" {
+		return fmt.Errorf("database.synchronous 不能为空")
+	}
+	return checkPositive("database.busy_timeout_ms", db.BusyTimeoutMs)
+}
+
+// validateLog 校验日志配置
+func validateLog(cfg *Config) error {
+	if _, err := logrus.ParseLevel(cfg.Log.Level); err != nil {
+		return fmt.Errorf("log.level 不支持: %q（可选 debug、info、warn、error）", cfg.Log.Level)
+	}
+	switch cfg.Log.Format {
+	case "text", "json":
+	default:
+		return fmt.Errorf("log.format 不支持: %q（可选 text、json）", cfg.Log.Format)
+	}
+	switch cfg.Log.Output {
+	case "stdout":
+	case "file":
+		if cfg.Log.FilePath == "" {
+			return fmt.Errorf("log.output 为 file 时 log.file_path 不能为空")
+		}
+	default:
+		return fmt.Errorf("log.output 不支持: %q（可选 stdout、file）", cfg.Log.Output)
+	}
+	return nil
+}
+
+// validateWebhooks 校验 webhook 配置
+func validateWebhooks(cfg *Config) error {
+	for i, endpoint := range cfg.Webhooks.Endpoints {
+		if endpoint.URL == "" {
+			return fmt.Errorf("webhooks.endpoints[%d].url 不能为空", i)
+		}
+	}
+	if err := checkPositive("webhooks.timeout_seconds", cfg.Webhooks.TimeoutSeconds); err != nil {
+		return err
+	}
+	return checkNonNegative("webhooks.max_retries", cfg.Webhooks.MaxRetries)
+}
+
+// validateRanking 检查采纳率排序配置
+func validateRanking(r *RankingConfig) error {
+	for _, field := range []struct {
+		name  string
+		value int
+	}{
+		{"autocomplete.ranking.interval_seconds", r.IntervalSeconds},
+		{"autocomplete.ranking.max_logs", r.MaxLogs},
+		{"autocomplete.ranking.min_samples", r.MinSamples},
+	} {
+		if err := checkPositive(field.name, field.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateDegradation 检查降级配置的各项参数和阈值顺序
+func validateDegradation(d *DegradationConfig) error {
+	for _, field := range []struct {
+		name  string
+		value int
+	}{
+		{"autocomplete.degradation.window_seconds", d.WindowSeconds},
+		{"autocomplete.degradation.min_samples", d.MinSamples},
+		{"autocomplete.degradation.degrade_p95_ms", d.DegradeP95Ms},
+		{"autocomplete.degradation.local_p95_ms", d.LocalP95Ms},
+		{"autocomplete.degradation.recover_p95_ms", d.RecoverP95Ms},
+		{"autocomplete.degradation.probe_interval_seconds", d.ProbeIntervalSeconds},
+		{"autocomplete.degradation.reduced_suggestions", d.ReducedSuggestions},
+		{"autocomplete.degradation.reduced_max_tokens", d.ReducedMaxTokens},
+	} {
+		if err := checkPositive(field.name, field.value); err != nil {
+			return err
+		}
+	}
+	if !(d.RecoverP95Ms < d.DegradeP95Ms && d.DegradeP95Ms < d.LocalP95Ms) {
+		return fmt.Errorf("autocomplete.degradation 需满足 recover_p95_ms < degrade_p95_ms < local_p95_ms")
+	}
+	return nil
+}
+
+// validateClarification 启用澄清提问时检查参数
+func validateClarification(c *ClarificationConfig) error {
+	if !c.Enabled {
+		return nil
+	}
+	if err := checkNonNegative(
+		"autocomplete.clarification.max_vague_chars", c.MaxVagueChars,
+		"autocomplete.clarification.context_window_minutes", c.ContextWindowMinutes,
+		"autocomplete.clarification.min_context_messages", c.MinContextMessages,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+// validateTokenBudget 设置了总预算时检查输出预算的上下限
+func validateTokenBudget(b *TokenBudgetConfig) error {
+	if b.TotalTokens <= 0 {
+		return nil
+	}
+	if err := checkNonNegative(
+		"autocomplete.token_budget.min_output_tokens", b.MinOutputTokens,
+		"autocomplete.token_budget.max_output_tokens", b.MaxOutputTokens,
+	); err != nil {
+		return err
+	}
+	if b.MinOutputTokens > b.MaxOutputTokens {
+		return fmt.Errorf("autocomplete.token_budget.min_output_tokens 不能大于 max_output_tokens")
+	}
+	if b.MinOutputTokens >= b.TotalTokens {
+		return fmt.Errorf("autocomplete.token_budget.total_tokens 必须大于 min_output_tokens")
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// defaultConfig 只含 setDefaults 默认值的配置
+func defaultConfig(t *testing.T) *Config {
+	t.Helper()
+
+	v := viper.New()
+	setDefaults(v)
+	cfg := &Config{}
+	if err := v.Unmarshal(cfg); err != nil {
+		t.Fatalf("解析默认配置失败: %v", err)
+	}
+	return cfg
+}
+
+// TestValidateConfigErrorPaths 每个校验失败的错误信息都以字段的完整路径开头
+func TestValidateConfigErrorPaths(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		want   string
+	}{
+		{"model type", func(c *Config) { c.LLM.ModelType = "unknown" }, "llm.model_type"},
+		{"context tokens", func(c *Config) { c.Context.MaxContextTokens = 0 }, "context.max_context_tokens"},
+		{"long message mode", func(c *Config) { c.Context.LongMessageMode = "summary" }, "context.long_message_mode"},
+		{"merge strategy", func(c *Config) { c.Summary.KeyInfoMergeStrategy = "random" }, "summary.key_info_merge_strategy"},
+		{"summary style", func(c *Config) { c.Summary.Style = "poem" }, "summary.style"},
+		{"summary worker", func(c *Config) { c.Summary.Worker.BatchSize = -1 }, "summary.worker.batch_size"},
+		{"worker priority", func(c *Config) { c.Summary.Worker.Priority = "oldest" }, "summary.worker.priority"},
+		{"prompt source", func(c *Config) { c.Style.PromptSource = "global" }, "style.prompt_source"},
+		{"request tokens", func(c *Config) { c.Autocomplete.MaxRequestTokens = -1 }, "autocomplete.max_request_tokens"},
+		{"request suggestions", func(c *Config) { c.Autocomplete.MaxRequestSuggestions = -1 }, "autocomplete.max_request_suggestions"},
+		{"locale", func(c *Config) { c.Autocomplete.Locale = "fr-FR" }, "autocomplete.locale"},
+		{"ranking", func(c *Config) { c.Autocomplete.Ranking.MinSamples = -1 }, "autocomplete.ranking.min_samples"},
+		{"ranking zero", func(c *Config) { c.Autocomplete.Ranking.IntervalSeconds = 0 }, "autocomplete.ranking.interval_seconds"},
+		{"degradation window", func(c *Config) { c.Autocomplete.Degradation.WindowSeconds = 0 }, "autocomplete.degradation.window_seconds"},
+		{"clarification", func(c *Config) {
+			c.Autocomplete.Clarification.Enabled = true
+			c.Autocomplete.Clarification.MinContextMessages = -1
+		}, "autocomplete.clarification.min_context_messages"},
+		{"token budget", func(c *Config) {
+			c.Autocomplete.TokenBudget.TotalTokens = 100
+			c.Autocomplete.TokenBudget.MaxOutputTokens = -1
+		}, "autocomplete.token_budget.max_output_tokens"},
+		{"degradation", func(c *Config) { c.Autocomplete.Degradation.RecoverP95Ms = 5000 }, "autocomplete.degradation"},
+		{"port", func(c *Config) { c.Server.WSPort = 70000 }, "server.ws_port"},
+		{"timezone", func(c *Config) { c.Server.Timezone = "Mars/Olympus" }, "server.timezone"},
+		{"reanalyze interval", func(c *Config) { c.Server.Reanalyze.IntervalMs = -1 }, "server.reanalyze.interval_ms"},
+		{"reanalyze batch", func(c *Config) { c.Server.Reanalyze.BatchSize = 0 }, "server.reanalyze.batch_size"},
+		{"dedupe window", func(c *Config) { c.Server.Dedupe.WindowSeconds = -1 }, "server.dedupe.window_seconds"},
+		{"dedupe action", func(c *Config) { c.Server.Dedupe.Action = "drop" }, "server.dedupe.action"},
+		{"journal mode", func(c *Config) { c.Database.JournalMode = "" }, "database.journal_mode"},
+		{"key version", func(c *Config) {
+			c.Database.EncryptionKey = "secret"
+			c.Database.EncryptionKeyVersion = ""
+		}, "database.encryption_key_version"},
+		{"log output", func(c *Config) { c.Log.Output = "syslog" }, "log.output"},
+		{"webhook url", func(c *Config) { c.Webhooks.Endpoints = []WebhookEndpoint{{}} }, "webhooks.endpoints[0].url"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := defaultConfig(t)
+			tt.modify(cfg)
+			err := validateConfig(cfg)
+			if err == nil {
+				t.Fatalf("validateConfig 应返回错误")
+			}
+			if !strings.HasPrefix(err.Error(), tt.want+" ") {
+				t.Fatalf("错误 %q 应以 %q 开头", err.Error(), tt.want)
+			}
+		})
+	}
+}
+
+// TestValidateConfigDefaults 默认配置校验通过，各段默认值由 setDefaults 提供且校验不改动
+func TestValidateConfigDefaults(t *testing.T) {
+	cfg := defaultConfig(t)
+	cfg.Database.EncryptionKey = "secret"
+	cfg.Autocomplete.Clarification.Enabled = true
+	cfg.Autocomplete.TokenBudget.TotalTokens = 1000
+	if err := validateConfig(cfg); err != nil {
+		t.Fatalf("validateConfig: %v", err)
+	}
+
+	checks := []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"context.long_message_mode", cfg.Context.LongMessageMode, "placeholder"},
+		{"summary.key_info_merge_strategy", cfg.Summary.KeyInfoMergeStrategy, "overwrite"},
+		{"summary.style", cfg.Summary.Style, "narrative"},
+		{"summary.worker.priority", cfg.Summary.Worker.Priority, "recent"},
+		{"style.prompt_source", cfg.Style.PromptSource, "conversation"},
+		{"autocomplete.ranking.min_samples", cfg.Autocomplete.Ranking.MinSamples, 20},
+		{"autocomplete.clarification.max_vague_chars", cfg.Autocomplete.Clarification.MaxVagueChars, 2},
+		{"autocomplete.token_budget.max_output_tokens", cfg.Autocomplete.TokenBudget.MaxOutputTokens, 512},
+		{"server.reanalyze.batch_size", cfg.Server.Reanalyze.BatchSize, 20},
+		{"server.dedupe.action", cfg.Server.Dedupe.Action, "merge"},
+		{"server.debug_endpoints", cfg.Server.DebugEndpoints, false},
+		{"database.encryption_key_version", cfg.Database.EncryptionKeyVersion, "v1"},
+		{"database.journal_mode", cfg.Database.JournalMode, "WAL"},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package context
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/textutil"
+)
+
+// 临时摘要中最多保留的要点数，每条要点的最大字符数，以及作为要点的最小字符数
+const (
+	compressMinPointChars = 4
+	compressMaxPoints     = 6
+	compressPointChars    = 40
+	defaultCompressKeep   = 10
+)
+
+// splitForCompression 近期消息超过 compress_threshold 条时，把较早的一批分出来用于生成临时摘要，
+// 返回较早的消息和保留原文的最新消息；未启用或未超过阈值时较早的消息为空
+func (m *Manager) splitForCompression(messages []models.Message) ([]models.Message, []models.Message) {
+	threshold := m.config.CompressThreshold
+	if threshold <= 0 || len(messages) <= threshold {
+		return nil, messages
+	}
+
+	keep := m.config.CompressKeepRecent
+	if keep <= 0 {
+		keep = defaultCompressKeep
+	}
+	if keep >= len(messages) {
+		return nil, messages
+	}
+	return messages[:len(messages)-keep], messages[len(messages)-keep:]
+}
+
+// compressMessages 用规则把一批消息压成一段临时摘要（不调用大模型、不落库）：
+// 各发送者的消息数，加上信息量较高的几条消息节选（按时间顺序）
+func compressMessages(messages []models.Message) string {
+	if len(messages) == 0 {
+		return ""
+	}
+
+	counts := make(map[string]int)
+	var senders []string
+	for _, msg := range messages {
+		if counts[msg.SenderID] == 0 {
+			senders = append(senders, msg.SenderID)
+		}
+		counts[msg.SenderID]++
+	}
+
+	parts := make([]string, len(senders))
+	for i, sender := range senders {
+		parts[i] = fmt.Sprintf("%s %d条", sender, counts[sender])
+	}
+	header := fmt.Sprintf("更早的%d条消息（%s）", len(messages), strings.Join(parts, "，"))
+
+	// 按信息量挑选要点，再恢复时间顺序
+	indexes := make([]int, len(messages))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(a, b int) bool {
+		return pointScore(messages[indexes[a]].Content) > pointScore(messages[indexes[b]].Content)
+	})
+	if len(indexes) > compressMaxPoints {
+		indexes = indexes[:compressMaxPoints]
+	}
+	sort.Ints(indexes)
+
+	var points []string
+	for _, i := range indexes {
+		if pointScore(messages[i].Content) == 0 {
+			continue
+		}
+		content, _ := textutil.Abbreviate(strings.Join(strings.Fields(messages[i].Content), " "), compressPointChars)
+		points = append(points, fmt.Sprintf("- [%s]: %s", messages[i].SenderID, content))
+	}
+	if len(points) == 0 {
+		return header + "，没有需要保留的要点"
+	}
+	return header + "的要点：\n" + strings.Join(points, "\n")
+}
+
+// pointScore 消息的信息量评分：越长越高（封顶），含数字（时间、金额、数量等）或提问的消息加分；
+// 过短的消息（如"嗯"、"好的"）不作为要点
+func pointScore(content string) float64 {
+	length := len([]rune(strings.TrimSpace(content)))
+	if length < compressMinPointChars {
+		return 0
+	}
+	score := float64(min(length, 50))
+	if strings.IndexFunc(content, unicode.IsDigit) >= 0 {
+		score += 10
+	}
+	if strings.ContainsAny(content, "?？") {
+		score += 10
+	}
+	return score
+}
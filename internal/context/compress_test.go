@@ -0,0 +1,63 @@
+package context
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// 近期消息超过阈值时，较早的一批被压成临时摘要注入上下文，最新几条保留原文；临时摘要不落库
+func TestBuildContextCompressesOlderMessages(t *testing.T) {
+	m, db := newTestManager(t, &config.ContextConfig{RecentMessagesCount: 20, CompressThreshold: 8, CompressKeepRecent: 3})
+	messages := make([]models.Message, 10)
+	for i := range messages {
+		sender := "alice"
+		if i%2 == 1 {
+			sender = "bob"
+		}
+		messages[i] = models.Message{SenderID: sender, Content: fmt.Sprintf("第%d条：周末的安排再商量一下", i+1)}
+	}
+	messages[1].Content = "嗯"
+	conversation := testutil.CreateConversation(t, db, "conv-compress", messages...)
+
+	detail, err := m.BuildContextDetail(conversation.ID, "alice", "好", BuildOptions{})
+	if err != nil {
+		t.Fatalf("构建上下文失败: %v", err)
+	}
+	if detail.CompressedMessages != 7 || len(detail.RecentMessages) != 3 {
+		t.Fatalf("压缩了 %d 条，保留原文 %d 条，期望 7 条和 3 条", detail.CompressedMessages, len(detail.RecentMessages))
+	}
+	if !strings.HasPrefix(detail.TemporarySummary, "更早的7条消息（alice 4条，bob 3条）的要点：") {
+		t.Errorf("临时摘要为 %q", detail.TemporarySummary)
+	}
+	if strings.Contains(detail.TemporarySummary, "[bob]: 嗯") {
+		t.Errorf("过短的消息不应作为要点: %q", detail.TemporarySummary)
+	}
+	if !strings.Contains(detail.Context, "=== 较早消息摘要 ===\n"+detail.TemporarySummary) {
+		t.Errorf("上下文缺少临时摘要: %s", detail.Context)
+	}
+	history := detail.Context[strings.Index(detail.Context, "=== 近期对话历史 ==="):]
+	if strings.Contains(history, "第7条") || !strings.Contains(history, "第8条") || !strings.Contains(history, "第10条") {
+		t.Errorf("近期对话历史应只保留最新3条原文: %s", history)
+	}
+
+	var summaries int64
+	db.Model(&models.Summary{}).Where("prompt LIKE ?", "%更早的%").Count(&summaries)
+	if summaries != 0 {
+		t.Errorf("临时摘要不应落库，摘要表有 %d 条", summaries)
+	}
+
+	// 未超过阈值时不压缩
+	m.config.CompressThreshold = 20
+	detail, err = m.BuildContextDetail(conversation.ID, "alice", "好", BuildOptions{})
+	if err != nil {
+		t.Fatalf("构建上下文失败: %v", err)
+	}
+	if detail.TemporarySummary != "" || len(detail.RecentMessages) != 10 {
+		t.Errorf("未超过阈值时不应压缩: %q，近期消息 %d 条", detail.TemporarySummary, len(detail.RecentMessages))
+	}
+}
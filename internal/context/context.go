@@ -2,12 +2,16 @@ package context
 
 import (
 	"fmt"
+	"math"
 	"strings"
+	"time"
 
 	"ChatRecommend/internal/config"
 	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/prompt"
 	"ChatRecommend/internal/style"
 	"ChatRecommend/internal/summary"
+	"ChatRecommend/internal/textutil"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
@@ -16,48 +20,233 @@ import (
 type Manager struct {
 	db       *gorm.DB
 	config   *config.ContextConfig
+	prompt   *config.PromptConfig
 	summary  *summary.Manager
 	style    *style.Manager
+	emotions *emotionCache
+	windows  *windowCache
+	// 数据库中的提示词模板，激活版本覆盖配置文件中的系统提示词前缀
+	templates *prompt.Store
+	// 对话和发送者都没有设置时区时使用的默认时区
+	location *time.Location
 }
 
+// BuildOptions 构建上下文的可选参数
+type BuildOptions struct {
+	// 客户端传入的额外指令
+	ExtraInstructions string
+	// 隐私模式：不注入摘要和近期消息
+	PrivacyMode bool
+	// 输入纠错提示（疑似拼音、错别字等），只作为提示，不修改输入
+	CorrectionHints string
+	// 角色扮演：预设名称或自定义角色描述，设置后替代学到的语言风格
+	PersonaStyle string
+	// 改写模式：要求模型把当前输入整句改写成更好的表达，而不是续写
+	Rewrite bool
+}
+
+// rewriteInstruction 改写模式的任务说明
+const rewriteInstruction = "本次不是续写：请把\"当前输入\"整句改写成更通顺、得体、礼貌的表达，保持原意，语气与用户平时的语言风格一致。" +
+	"每条建议都必须是可以直接替换原输入的完整句子，不要续写新内容，不要添加解释。"
+
+// maxExtraInstructionsLength 额外指令最大长度（字符数），防止通过超长指令改写系统行为
+const maxExtraInstructionsLength = 200
+
 // NewManager 创建上下文管理器
-func NewManager(db *gorm.DB, cfg *config.ContextConfig, summaryMgr *summary.Manager, styleMgr *style.Manager) *Manager {
+func NewManager(db *gorm.DB, cfg *config.ContextConfig, promptCfg *config.PromptConfig, summaryMgr *summary.Manager, styleMgr *style.Manager) *Manager {
 	return &Manager{
-		db:      db,
-		config:  cfg,
-		summary: summaryMgr,
-		style:   styleMgr,
+		db:       db,
+		config:   cfg,
+		prompt:   promptCfg,
+		summary:  summaryMgr,
+		style:    styleMgr,
+		emotions: newEmotionCache(emotionCacheSize),
+		windows:  newWindowCache(),
+		location: time.Local,
 	}
 }
 
+// Detail 上下文构建结果及各组成部分
+type Detail struct {
+	Context           string           `json:"context"`
+	SystemPrefix      string           `json:"system_prefix,omitempty"`
+	ExtraInstructions string           `json:"extra_instructions,omitempty"`
+	PersonaPrompt     string           `json:"persona_prompt,omitempty"`
+	// 对话锁定的补全语言
+	LockedLanguage    string           `json:"locked_language,omitempty"`
+	// 注入的当前时间（对话时区）
+	CurrentTime       string           `json:"current_time,omitempty"`
+	SummaryPrompt     string           `json:"summary_prompt"`
+	StylePrompt       string           `json:"style_prompt"`
+	ProfilePrompt     string           `json:"profile_prompt,omitempty"`
+	// 置顶消息（不论新旧总会注入）
+	PinnedMessages    []models.Message `json:"pinned_messages,omitempty"`
+	// 从用户历史回复中挑选的 few-shot 示例
+	FewShotExamples   []FewShotExample `json:"few_shot_examples,omitempty"`
+	Emotion           string           `json:"emotion,omitempty"`
+	EmotionHint       string           `json:"emotion_hint,omitempty"`
+	// 较早的近期消息压缩成的临时摘要（不落库），以及被压缩的消息数
+	TemporarySummary   string          `json:"temporary_summary,omitempty"`
+	CompressedMessages int             `json:"compressed_messages,omitempty"`
+	RecentMessages    []models.Message `json:"recent_messages"`
+	DroppedMessages   int              `json:"dropped_messages"`
+	EstimatedTokens   int              `json:"estimated_tokens"`
+	Truncated         bool             `json:"truncated"`
+	// 开启 mask_pii 时各类敏感信息的打码次数
+	MaskedPII         map[string]int   `json:"masked_pii,omitempty"`
+}
+
+// EstimateTokens 粗略估算token数：1 token ≈ 3 字符
+func EstimateTokens(text string) int {
+	return (len([]rune(text)) + 2) / 3
+}
+
 // BuildContext 构建对话上下文
-func (m *Manager) BuildContext(conversationID uint, senderID string, currentInput string) (string, error) {
+func (m *Manager) BuildContext(conversationID uint, senderID string, currentInput string, opts BuildOptions) (string, error) {
+	detail, err := m.BuildContextDetail(conversationID, senderID, currentInput, opts)
+	if err != nil {
+		return "", err
+	}
+	return detail.Context, nil
+}
+
+// BuildContextDetail 构建对话上下文，并返回各组成部分（用于调试）
+func (m *Manager) BuildContextDetail(conversationID uint, senderID string, currentInput string, opts BuildOptions) (*Detail, error) {
 	var conversation models.Conversation
 	if err := m.db.First(&conversation, conversationID).Error; err != nil {
-		return "", fmt.Errorf("查询对话失败: %w", err)
+		return nil, fmt.Errorf("查询对话失败: %w", err)
 	}
 
-	// 1. 获取对话摘要提示词
-	summaryPrompt, err := m.summary.GetSummaryPrompt(conversationID)
-	if err != nil {
-		logrus.WithError(err).Warn("获取摘要失败")
+	detail := &Detail{}
+
+	// 1. 获取对话摘要提示词（隐私模式下不注入）
+	var summaryPrompt string
+	if !opts.PrivacyMode {
+		var err error
+		summaryPrompt, err = m.summary.GetSummaryPrompt(conversationID)
+		if err != nil {
+			logrus.WithError(err).Warn("获取摘要失败")
+		}
 	}
+	detail.SummaryPrompt = summaryPrompt
 
-	// 2. 获取用户语言风格提示词
-	stylePrompt, err := m.style.GetStylePrompt(conversationID, senderID)
-	if err != nil {
-		logrus.WithError(err).Warn("获取风格失败")
+	// 2. 获取用户语言风格提示词（指定了角色扮演时由角色代替学到的风格）
+	detail.PersonaPrompt = personaInstruction(conversationID, senderID, opts.PersonaStyle)
+	var stylePrompt string
+	if detail.PersonaPrompt == "" {
+		var err error
+		stylePrompt, err = m.style.GetStylePrompt(conversationID, senderID)
+		if err != nil {
+			logrus.WithError(err).Warn("获取风格失败")
+		}
 	}
+	detail.StylePrompt = stylePrompt
 
-	// 3. 获取近期消息
-	recentMessages, err := m.getRecentMessages(conversationID, m.config.RecentMessagesCount)
-	if err != nil {
-		return "", fmt.Errorf("获取近期消息失败: %w", err)
+	// 用户档案（只注入与当前输入相关的字段，隐私模式下不注入）
+	if !opts.PrivacyMode {
+		detail.ProfilePrompt = m.profilePrompt(&conversation, senderID, currentInput)
+	}
+
+	// 3. 获取近期消息（隐私模式下不注入）；启用滑动窗口时直接使用窗口内容，挤出窗口的较早消息压进临时摘要
+	var recentMessages, overflow []models.Message
+	if !opts.PrivacyMode {
+		var err error
+		if m.config.Window.Enabled {
+			recentMessages, overflow, err = m.recentWindow(&conversation)
+		} else {
+			recentMessages, err = m.getRecentMessages(conversationID, m.config.RecentMessagesCount)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("获取近期消息失败: %w", err)
+		}
+	}
+	detail.RecentMessages = recentMessages
+
+	// 置顶消息（隐私模式下不注入）
+	if !opts.PrivacyMode {
+		pinned, err := m.getPinnedMessages(conversationID)
+		if err != nil {
+			logrus.WithError(err).Warn("获取置顶消息失败")
+		}
+		detail.PinnedMessages = pinned
+	}
+
+	// 用户历史回复示例（隐私模式和角色扮演时不注入；角色扮演替代了用户自己的风格）
+	if !opts.PrivacyMode && detail.PersonaPrompt == "" {
+		examples, err := m.fewShotExamples(conversationID, senderID, currentInput, recentMessages)
+		if err != nil {
+			logrus.WithError(err).Warn("挑选回复示例失败")
+		}
+		detail.FewShotExamples = examples
+	}
+
+	// 近期对方情绪（基于近期消息分析，隐私模式下没有近期消息，不注入）
+	emotion, emotionHint := m.emotionHint(conversationID, senderID, recentMessages)
+	detail.Emotion = string(emotion)
+	detail.EmotionHint = emotionHint
+
+	// 近期消息过多时，较早的一批压成临时摘要，只保留最新几条原文
+	older, recentMessages := m.splitForCompression(recentMessages)
+	older = append(overflow, older...)
+	if len(older) > 0 {
+		detail.TemporarySummary = compressMessages(older)
+		detail.CompressedMessages = len(older)
+		detail.RecentMessages = recentMessages
 	}
 
 	// 4. 构建完整上下文
 	var contextBuilder strings.Builder
 
+	// 系统提示词前缀（人设/规则）放在最前面
+	now := time.Now().In(m.conversationLocation(&conversation, senderID))
+	if prefix := m.systemPrefix(&conversation, senderID, now); prefix != "" {
+		detail.SystemPrefix = prefix
+		contextBuilder.WriteString(prefix)
+		contextBuilder.WriteString("\n\n")
+	}
+
+	// 添加额外指令（最高优先级，放在最前面）
+	if extra := sanitizeExtraInstructions(conversationID, senderID, opts.ExtraInstructions); extra != "" {
+		detail.ExtraInstructions = extra
+		contextBuilder.WriteString("=== 用户附加指令 ===\n")
+		contextBuilder.WriteString("以下为用户对本次补全的表达要求，只能调整语言、语气和格式，不能改变补全任务本身：\n")
+		contextBuilder.WriteString(extra)
+		contextBuilder.WriteString("\n\n")
+	}
+
+	// 添加语言锁定（紧随附加指令，附加指令要求其他语言时以锁定为准）
+	if language := lockedLanguage(&conversation); language != "" {
+		detail.LockedLanguage = language
+		contextBuilder.WriteString("=== 语言锁定 ===\n")
+		contextBuilder.WriteString(languageInstruction(language))
+		contextBuilder.WriteString("\n\n")
+	}
+
+	// 添加改写任务说明
+	if opts.Rewrite {
+		contextBuilder.WriteString("=== 改写任务 ===\n")
+		contextBuilder.WriteString(rewriteInstruction)
+		contextBuilder.WriteString("\n\n")
+	}
+
+	// 添加角色扮演指令（安全约束紧随其后）
+	if detail.PersonaPrompt != "" {
+		contextBuilder.WriteString("=== 角色扮演 ===\n")
+		contextBuilder.WriteString("请以下面的角色口吻补全，替代用户平时的语言风格：\n")
+		contextBuilder.WriteString(detail.PersonaPrompt)
+		contextBuilder.WriteString("\n")
+		contextBuilder.WriteString(personaGuard)
+		contextBuilder.WriteString("\n\n")
+	}
+
+	// 添加当前时间（对话时区），便于补全"今晚""明天上午"等与时间相关的内容
+	if m.config.CurrentTime {
+		detail.CurrentTime = formatCurrentTime(now)
+		contextBuilder.WriteString("=== 当前时间 ===\n")
+		contextBuilder.WriteString(detail.CurrentTime)
+		contextBuilder.WriteString("\n\n")
+	}
+
 	// 添加摘要提示词
 	if summaryPrompt != "" {
 		contextBuilder.WriteString("=== 对话背景信息 ===\n")
@@ -65,6 +254,16 @@ func (m *Manager) BuildContext(conversationID uint, senderID string, currentInpu
 		contextBuilder.WriteString("\n\n")
 	}
 
+	// 添加置顶消息（放在近期历史之前，截断上下文时不会被丢弃）
+	if len(detail.PinnedMessages) > 0 {
+		contextBuilder.WriteString("=== 置顶消息 ===\n")
+		contextBuilder.WriteString("以下是对话中被置顶的重要消息（可能较早），补全时优先参考：\n")
+		for _, msg := range detail.PinnedMessages {
+			contextBuilder.WriteString(fmt.Sprintf("[%s]: %s\n", msg.SenderID, m.messageContent(msg)))
+		}
+		contextBuilder.WriteString("\n")
+	}
+
 	// 添加风格提示词
 	if stylePrompt != "" {
 		contextBuilder.WriteString("=== 用户语言风格 ===\n")
@@ -72,34 +271,224 @@ func (m *Manager) BuildContext(conversationID uint, senderID string, currentInpu
 		contextBuilder.WriteString("\n\n")
 	}
 
-	// 添加近期对话历史
+	// 添加回复示例
+	if len(detail.FewShotExamples) > 0 {
+		contextBuilder.WriteString("=== 回复示例 ===\n")
+		contextBuilder.WriteString("以下是用户过去在相似情境下的真实回复，仅用于参考语气和措辞，不要照抄：\n")
+		contextBuilder.WriteString(formatFewShot(senderID, detail.FewShotExamples))
+		contextBuilder.WriteString("\n")
+	}
+
+	// 添加用户档案
+	if detail.ProfilePrompt != "" {
+		contextBuilder.WriteString("=== 用户档案 ===\n")
+		contextBuilder.WriteString("以下是用户本人提供的资料，仅在补全内容需要时使用：\n")
+		contextBuilder.WriteString(detail.ProfilePrompt)
+		contextBuilder.WriteString("\n\n")
+	}
+
+	// 添加情绪提示
+	if emotionHint != "" {
+		contextBuilder.WriteString("=== 对话情绪 ===\n")
+		contextBuilder.WriteString(emotionHint)
+		contextBuilder.WriteString("\n\n")
+	}
+
+	// 当前输入（附带纠错提示）
+	inputSection := "=== 当前输入 ===\n" + fmt.Sprintf("[%s]: %s", senderID, currentInput)
+	if opts.CorrectionHints != "" {
+		inputSection = "=== 输入纠错提示 ===\n以下是对当前输入的推测，仅供理解输入，补全时不要改写用户已输入的内容：\n" +
+			opts.CorrectionHints + "\n" + inputSection
+	}
+
+	// 添加临时摘要
+	if detail.TemporarySummary != "" {
+		contextBuilder.WriteString("=== 较早消息摘要 ===\n")
+		contextBuilder.WriteString(detail.TemporarySummary)
+		contextBuilder.WriteString("\n\n")
+	}
+
+	// 添加近期对话历史（预算不足时优先丢弃较旧的消息）
 	if len(recentMessages) > 0 {
-		contextBuilder.WriteString("=== 近期对话历史 ===\n")
-		for _, msg := range recentMessages {
-			contextBuilder.WriteString(fmt.Sprintf("[%s]: %s\n", msg.SenderID, msg.Content))
+		budget := m.config.MaxContextTokens*3 - len([]rune(contextBuilder.String())) - len([]rune(inputSection))
+		lines, dropped := m.selectHistoryLines(recentMessages, budget)
+		detail.DroppedMessages = dropped
+		if len(lines) > 0 {
+			contextBuilder.WriteString("=== 近期对话历史 ===\n")
+			if dropped > 0 {
+				contextBuilder.WriteString(fmt.Sprintf("[更早的%d条消息已省略]\n", dropped))
+				detail.Truncated = true
+			}
+			for _, line := range lines {
+				contextBuilder.WriteString(line)
+			}
+			contextBuilder.WriteString("\n")
 		}
-		contextBuilder.WriteString("\n")
 	}
 
 	// 添加当前输入
-	contextBuilder.WriteString("=== 当前输入 ===\n")
-	contextBuilder.WriteString(fmt.Sprintf("[%s]: %s", senderID, currentInput))
+	contextBuilder.WriteString(inputSection)
 
 	context := contextBuilder.String()
 
 	// 5. 检查并截断上下文（简单实现，实际应该按token计算）
-	if len([]rune(context)) > m.config.MaxContextTokens*3 { // 粗略估算：1 token ≈ 3 字符
+	if EstimateTokens(context) > m.config.MaxContextTokens {
 		context = truncateContext(context, m.config.MaxContextTokens*3)
+		detail.Truncated = true
 		logrus.Warn("上下文已截断")
 	}
 
-	return context, nil
+	detail.Context = context
+	detail.EstimatedTokens = EstimateTokens(context)
+	if m.config.MaskPII {
+		m.maskDetail(conversationID, detail)
+	}
+	return detail, nil
+}
+
+// maskDetail 对上下文及各组成部分中的敏感信息打码，发送给大模型和返回给调用方（补全响应、调试接口）的都是打码后的内容
+func (m *Manager) maskDetail(conversationID uint, detail *Detail) {
+	detail.Context, detail.MaskedPII = textutil.MaskPII(detail.Context)
+	if len(detail.MaskedPII) == 0 {
+		return
+	}
+
+	for _, field := range []*string{&detail.ExtraInstructions, &detail.SummaryPrompt, &detail.ProfilePrompt, &detail.TemporarySummary} {
+		*field, _ = textutil.MaskPII(*field)
+	}
+	// 近期消息是查询结果的副本，可以直接修改
+	for i := range detail.RecentMessages {
+		detail.RecentMessages[i].Content, _ = textutil.MaskPII(detail.RecentMessages[i].Content)
+	}
+	for i := range detail.PinnedMessages {
+		detail.PinnedMessages[i].Content, _ = textutil.MaskPII(detail.PinnedMessages[i].Content)
+	}
+	for i := range detail.FewShotExamples {
+		detail.FewShotExamples[i].Prompt, _ = textutil.MaskPII(detail.FewShotExamples[i].Prompt)
+		detail.FewShotExamples[i].Reply, _ = textutil.MaskPII(detail.FewShotExamples[i].Reply)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"conversation_id": conversationID,
+		"masked":          detail.MaskedPII,
+	}).Info("上下文敏感信息已打码")
+}
+
+// SetPromptStore 设置提示词模板存储，模板在数据库中有激活版本时覆盖配置文件
+func (m *Manager) SetPromptStore(store *prompt.Store) {
+	m.templates = store
+}
+
+// systemPrefix 展开系统提示词前缀：数据库中的激活版本优先，否则使用配置文件；{date} 为对话时区的当天日期
+func (m *Manager) systemPrefix(conversation *models.Conversation, senderID string, now time.Time) string {
+	var fallback string
+	if m.prompt != nil {
+		fallback = m.prompt.SystemPrefix
+	}
+	template := m.templates.Resolve(models.PromptSystemPrefix, fallback)
+	if strings.TrimSpace(template) == "" {
+		return ""
+	}
+	return strings.TrimSpace(textutil.ExpandVars(template, map[string]string{
+		"conversation_id": conversation.ConversationID,
+		"sender_id":       senderID,
+		"date":            now.Format("2006-01-02"),
+	}))
+}
+
+// historyHeaderReserve 历史部分标题和省略提示预留的字符数
+const historyHeaderReserve = 40
+
+// selectHistoryLines 在字符预算内从最新的消息往前选择历史消息，返回按时间正序排列的行和被丢弃的消息数
+// 配置了衰减半衰期时，超过一个半衰期的消息（权重低于0.5）会被标注为较早的消息
+func (m *Manager) selectHistoryLines(messages []models.Message, budget int) ([]string, int) {
+	budget -= historyHeaderReserve
+	now := time.Now()
+	halfLife := time.Duration(m.config.DecayHalfLifeMinutes) * time.Minute
+
+	lines := make([]string, 0, len(messages))
+	used := 0
+	i := len(messages) - 1
+	for ; i >= 0; i-- {
+		msg := messages[i]
+		prefix := ""
+		if halfLife > 0 && !msg.CreatedAt.IsZero() && decayWeight(now.Sub(msg.CreatedAt), halfLife) < 0.5 {
+			prefix = "(较早) "
+		}
+		line := fmt.Sprintf("%s[%s]: %s\n", prefix, msg.SenderID, m.messageContent(msg))
+
+		length := len([]rune(line))
+		if used+length > budget {
+			break
+		}
+		used += length
+		lines = append(lines, line)
+	}
+
+	// 反转为时间正序
+	for l, r := 0, len(lines)-1; l < r; l, r = l+1, r-1 {
+		lines[l], lines[r] = lines[r], lines[l]
+	}
+
+	return lines, i + 1
+}
+
+// decayWeight 按半衰期计算消息权重：age 为 0 时权重为1，每经过一个半衰期减半
+func decayWeight(age, halfLife time.Duration) float64 {
+	if age <= 0 {
+		return 1
+	}
+	return math.Pow(0.5, float64(age)/float64(halfLife))
+}
+
+// sanitizeExtraInstructions 清理额外指令：去除首尾空白、压缩换行并限制长度
+func sanitizeExtraInstructions(conversationID uint, senderID string, extra string) string {
+	extra = strings.TrimSpace(extra)
+	if extra == "" {
+		return ""
+	}
+
+	// 换行会破坏上下文的分段结构，统一替换为空格
+	extra = strings.Join(strings.Fields(extra), " ")
+
+	runes := []rune(extra)
+	if len(runes) > maxExtraInstructionsLength {
+		logrus.WithFields(logrus.Fields{
+			"conversation_id": conversationID,
+			"sender_id":       senderID,
+			"length":          len(runes),
+		}).Warn("额外指令过长，已截断")
+		extra = string(runes[:maxExtraInstructionsLength])
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"conversation_id":    conversationID,
+		"sender_id":          senderID,
+		"extra_instructions": extra,
+	}).Info("补全请求携带额外指令")
+
+	return extra
+}
+
+// messageContent 获取注入上下文的消息内容，超长消息默认替换为摘要占位，
+// long_message_mode 为 excerpt 时保留首尾节选
+func (m *Manager) messageContent(msg models.Message) string {
+	content, abbreviated := textutil.Abbreviate(msg.Content, m.config.MaxMessageChars)
+	if !abbreviated {
+		return content
+	}
+	length := len([]rune(msg.Content))
+	if m.config.LongMessageMode == "excerpt" {
+		return fmt.Sprintf("[长消息节选，原文共%d字] %s", length, content)
+	}
+	return fmt.Sprintf("[长消息，原文共%d字，内容已省略]", length)
 }
 
 // getRecentMessages 获取近期消息
 func (m *Manager) getRecentMessages(conversationID uint, limit int) ([]models.Message, error) {
 	var messages []models.Message
 	err := m.db.Where("conversation_id = ?", conversationID).
+		Scopes(models.ExcludeDrafts).
 		Order("sequence DESC, created_at DESC").
 		Limit(limit).
 		Find(&messages).Error
@@ -113,9 +502,30 @@ func (m *Manager) getRecentMessages(conversationID uint, limit int) ([]models.Me
 		messages[i], messages[j] = messages[j], messages[i]
 	}
 
+	plainMessages(messages)
 	return messages, nil
 }
 
+// getPinnedMessages 获取对话的置顶消息，按时间正序排列
+func (m *Manager) getPinnedMessages(conversationID uint) ([]models.Message, error) {
+	var messages []models.Message
+	err := m.db.Where("conversation_id = ? AND pinned = ?", conversationID, true).
+		Scopes(models.ExcludeDrafts).
+		Order("sequence ASC, created_at ASC").
+		Limit(models.MaxPinnedMessages).
+		Find(&messages).Error
+	plainMessages(messages)
+	return messages, err
+}
+
+// plainMessages 把 Markdown 消息的内容替换为提取出的纯文本，注入上下文的消息不带 Markdown 符号
+func plainMessages(messages []models.Message) {
+	for i := range messages {
+		messages[i].Content = messages[i].PlainContent()
+		messages[i].ContentFormat = models.ContentFormatPlain
+	}
+}
+
 // truncateContext 截断上下文（保留摘要和风格，截断历史消息）
 func truncateContext(context string, maxLength int) string {
 	if len([]rune(context)) <= maxLength {
@@ -0,0 +1,45 @@
+package context
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+)
+
+// 预算不足时从最新的消息往前保留，丢弃最旧的消息；超过半衰期的消息标注为较早
+func TestSelectHistoryLinesKeepsNewest(t *testing.T) {
+	m, _ := newTestManager(t, &config.ContextConfig{DecayHalfLifeMinutes: 30})
+
+	now := time.Now()
+	messages := make([]models.Message, 6)
+	for i := range messages {
+		messages[i] = models.Message{
+			SenderID:  "alice",
+			Content:   fmt.Sprintf("第%d条消息", i+1),
+			CreatedAt: now.Add(-time.Duration(6-i) * 20 * time.Minute),
+		}
+	}
+
+	// 每行 15 到 20 个字符，预算只够保留最新的三条
+	lines, dropped := m.selectHistoryLines(messages, historyHeaderReserve+60)
+	if dropped != 3 || len(lines) != 3 {
+		t.Fatalf("保留 %d 行，丢弃 %d 条，期望保留3行丢弃3条: %q", len(lines), dropped, lines)
+	}
+	for i, want := range []string{"第4条消息", "第5条消息", "第6条消息"} {
+		if !strings.Contains(lines[i], want) {
+			t.Errorf("第%d行为 %q，期望包含 %s", i, lines[i], want)
+		}
+	}
+
+	// 40 分钟前的消息已超过一个半衰期，20 分钟前的没有
+	if !strings.HasPrefix(lines[1], "(较早) ") {
+		t.Errorf("超过半衰期的消息应标注为较早: %q", lines[1])
+	}
+	if strings.HasPrefix(lines[2], "(较早) ") {
+		t.Errorf("半衰期内的消息不应标注: %q", lines[2])
+	}
+}
@@ -0,0 +1,33 @@
+package context
+
+import (
+	"strings"
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// 草稿消息不作为历史注入上下文
+func TestBuildContextExcludesDrafts(t *testing.T) {
+	m, db := newTestManager(t, &config.ContextConfig{})
+	conversation := testutil.CreateConversation(t, db, "conv-draft",
+		models.Message{SenderID: "bob", Content: "周五有空吗"},
+		models.Message{SenderID: "alice", Content: "周五我要加班到很晚", MessageType: models.MessageTypeDraft},
+	)
+
+	detail, err := m.BuildContextDetail(conversation.ID, "alice", "周五", BuildOptions{})
+	if err != nil {
+		t.Fatalf("构建上下文失败: %v", err)
+	}
+	if !strings.Contains(detail.Context, "周五有空吗") {
+		t.Fatalf("上下文缺少已发送的消息: %s", detail.Context)
+	}
+	if strings.Contains(detail.Context, "加班") {
+		t.Errorf("草稿不应注入上下文: %s", detail.Context)
+	}
+	if len(detail.RecentMessages) != 1 {
+		t.Errorf("近期消息为 %d 条，期望 1 条", len(detail.RecentMessages))
+	}
+}
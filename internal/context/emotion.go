@@ -0,0 +1,134 @@
+package context
+
+import (
+	"container/list"
+	"sync"
+
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/sentiment"
+)
+
+// emotionHints 各情绪对应的语气提示
+var emotionHints = map[sentiment.Emotion]string{
+	sentiment.Angry: "对方情绪激动、可能在生气。补全应保持冷静克制，避免反驳、指责或火上浇油，可以先表达理解再说明情况。",
+	sentiment.Sad:   "对方情绪低落、可能在难过。补全应体贴温和，优先表达关心和安慰，避免说教或轻描淡写。",
+	sentiment.Happy: "对方心情不错。补全可以轻松愉快，适当回应对方的喜悦。",
+}
+
+// defaultEmotionWindow 未配置时参与情绪分析的近期消息数
+const defaultEmotionWindow = 10
+
+// emotionCacheSize 情绪缓存最多保留的条目数，超出时淘汰最久未使用的条目
+const emotionCacheSize = 1024
+
+// emotionKey 情绪缓存键
+type emotionKey struct {
+	conversationID uint
+	senderID       string
+}
+
+// emotionEntry 情绪缓存项，对话收到新消息（最新消息ID变化）后失效；消息被编辑时由 InvalidateEmotion 清除
+type emotionEntry struct {
+	key           emotionKey
+	lastMessageID uint
+	result        sentiment.Result
+}
+
+// emotionCache 按对话和发送者缓存情绪分析结果的 LRU 缓存
+type emotionCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // 元素为 *emotionEntry，最近使用的在前
+	entries  map[emotionKey]*list.Element
+}
+
+// newEmotionCache 创建情绪缓存
+func newEmotionCache(capacity int) *emotionCache {
+	return &emotionCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[emotionKey]*list.Element),
+	}
+}
+
+// get 读取缓存并标记为最近使用
+func (c *emotionCache) get(key emotionKey) (emotionEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return emotionEntry{}, false
+	}
+	c.order.MoveToFront(elem)
+	return *elem.Value.(*emotionEntry), true
+}
+
+// set 写入缓存，超出容量时淘汰最久未使用的条目
+func (c *emotionCache) set(entry emotionEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[entry.key]; ok {
+		*elem.Value.(*emotionEntry) = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[entry.key] = c.order.PushFront(&entry)
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*emotionEntry).key)
+	}
+}
+
+// invalidate 清除对话中所有发送者的缓存
+func (c *emotionCache) invalidate(conversationID uint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, elem := range c.entries {
+		if key.conversationID == conversationID {
+			c.order.Remove(elem)
+			delete(c.entries, key)
+		}
+	}
+}
+
+// InvalidateEmotion 清除对话的情绪分析缓存（消息被编辑等最新消息ID不变但内容变化时调用）
+func (m *Manager) InvalidateEmotion(conversationID uint) {
+	if m == nil {
+		return
+	}
+	m.emotions.invalidate(conversationID)
+}
+
+// emotionHint 分析近期消息中对方（非当前发送者）的情绪，返回情绪和对应的语气提示；中性或未启用时返回空
+func (m *Manager) emotionHint(conversationID uint, senderID string, messages []models.Message) (sentiment.Emotion, string) {
+	if !m.config.EmotionAware || len(messages) == 0 {
+		return "", ""
+	}
+
+	window := m.config.EmotionWindow
+	if window <= 0 {
+		window = defaultEmotionWindow
+	}
+	if len(messages) > window {
+		messages = messages[len(messages)-window:]
+	}
+
+	key := emotionKey{conversationID: conversationID, senderID: senderID}
+	lastMessageID := messages[len(messages)-1].ID
+
+	entry, ok := m.emotions.get(key)
+	if !ok || entry.lastMessageID != lastMessageID {
+		texts := make([]string, 0, len(messages))
+		for _, msg := range messages {
+			if msg.SenderID != senderID {
+				texts = append(texts, msg.Content)
+			}
+		}
+		entry = emotionEntry{key: key, lastMessageID: lastMessageID, result: sentiment.Analyze(texts)}
+		m.emotions.set(entry)
+	}
+
+	emotion := entry.result.Emotion
+	return emotion, emotionHints[emotion]
+}
@@ -0,0 +1,104 @@
+package context
+
+import (
+	"strings"
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/sentiment"
+	"ChatRecommend/internal/testutil"
+)
+
+// 情绪缓存超出容量时淘汰最久未使用的条目
+func TestEmotionCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newEmotionCache(2)
+	a := emotionKey{conversationID: 1, senderID: "alice"}
+	b := emotionKey{conversationID: 1, senderID: "bob"}
+	c := emotionKey{conversationID: 2, senderID: "alice"}
+
+	cache.set(emotionEntry{key: a, lastMessageID: 1})
+	cache.set(emotionEntry{key: b, lastMessageID: 1})
+	if _, ok := cache.get(a); !ok {
+		t.Fatal("a 应在缓存中")
+	}
+	cache.set(emotionEntry{key: c, lastMessageID: 1})
+
+	if _, ok := cache.get(b); ok {
+		t.Error("最久未使用的 b 应被淘汰")
+	}
+	if _, ok := cache.get(a); !ok {
+		t.Error("最近使用过的 a 不应被淘汰")
+	}
+	if _, ok := cache.get(c); !ok {
+		t.Error("新写入的 c 应在缓存中")
+	}
+}
+
+// 清除对话的情绪缓存只影响该对话
+func TestEmotionCacheInvalidateConversation(t *testing.T) {
+	m := &Manager{emotions: newEmotionCache(emotionCacheSize)}
+	a := emotionKey{conversationID: 1, senderID: "alice"}
+	b := emotionKey{conversationID: 1, senderID: "bob"}
+	other := emotionKey{conversationID: 2, senderID: "alice"}
+	for _, key := range []emotionKey{a, b, other} {
+		m.emotions.set(emotionEntry{key: key, lastMessageID: 3, result: sentiment.Result{Emotion: sentiment.Angry}})
+	}
+
+	m.InvalidateEmotion(1)
+
+	if _, ok := m.emotions.get(a); ok {
+		t.Error("对话1的缓存应被清除")
+	}
+	if _, ok := m.emotions.get(b); ok {
+		t.Error("对话1的缓存应被清除")
+	}
+	if _, ok := m.emotions.get(other); !ok {
+		t.Error("其他对话的缓存不应受影响")
+	}
+}
+
+// 对方不同的情绪在上下文中注入不同的语气提示，当前发送者自己的消息不参与分析
+func TestBuildContextEmotionHints(t *testing.T) {
+	m, db := newTestManager(t, &config.ContextConfig{EmotionAware: true})
+
+	tests := []struct {
+		name    string
+		content string
+		emotion sentiment.Emotion
+	}{
+		{"angry", "你凭什么这样，气死我了！！", sentiment.Angry},
+		{"sad", "今天好难过，想哭", sentiment.Sad},
+		{"happy", "太好了，好开心哈哈", sentiment.Happy},
+		{"neutral", "明天几点开会", ""},
+	}
+	hints := make(map[string]bool)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conversation := testutil.CreateConversation(t, db, "conv-emotion-"+tt.name,
+				models.Message{SenderID: "alice", Content: "我真开心"},
+				models.Message{SenderID: "bob", Content: tt.content},
+			)
+			detail, err := m.BuildContextDetail(conversation.ID, "alice", "嗯", BuildOptions{})
+			if err != nil {
+				t.Fatalf("构建上下文失败: %v", err)
+			}
+			if tt.emotion == "" {
+				if detail.EmotionHint != "" {
+					t.Fatalf("中性消息不应注入语气提示: %q", detail.EmotionHint)
+				}
+				return
+			}
+			if detail.Emotion != string(tt.emotion) || detail.EmotionHint != emotionHints[tt.emotion] {
+				t.Fatalf("情绪为 %q，提示为 %q，期望 %q", detail.Emotion, detail.EmotionHint, tt.emotion)
+			}
+			if !strings.Contains(detail.Context, detail.EmotionHint) {
+				t.Fatalf("上下文应包含语气提示: %s", detail.Context)
+			}
+			hints[detail.EmotionHint] = true
+		})
+	}
+	if len(hints) != 3 {
+		t.Fatalf("三种情绪应得到三种不同的语气提示，实际 %d 种", len(hints))
+	}
+}
@@ -0,0 +1,109 @@
+package context
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/textutil"
+)
+
+// few-shot 示例的默认扫描消息数和单条消息最大字符数
+const (
+	defaultFewShotScanMessages = 500
+	defaultFewShotMaxChars     = 80
+)
+
+// FewShotExample 一条 few-shot 示例：对方的消息和用户紧接着的真实回复
+type FewShotExample struct {
+	PromptSender string `json:"prompt_sender"`
+	Prompt       string `json:"prompt"`
+	Reply        string `json:"reply"`
+	// 与当前情境的相关度
+	Score float64 `json:"score"`
+}
+
+// fewShotExamples 从对话最近 few_shot_scan_messages 条消息中，挑出该用户紧接在他人消息之后的回复作为示例，
+// 按与当前情境的相关度取前 few_shot_count 条：相关度为对方最新一条消息与示例中对方消息的字符二元组 Dice 系数，
+// 加上当前输入与示例回复的 Dice 系数。已在近期消息中出现的回复不作为示例；没有可比较的内容或相关度为0时不选
+func (m *Manager) fewShotExamples(conversationID uint, senderID, currentInput string, recent []models.Message) ([]FewShotExample, error) {
+	count := m.config.FewShotCount
+	if count <= 0 {
+		return nil, nil
+	}
+
+	var lastIncoming string
+	for i := len(recent) - 1; i >= 0; i-- {
+		if recent[i].SenderID != senderID {
+			lastIncoming = recent[i].Content
+			break
+		}
+	}
+	incomingGrams := textutil.CharBigrams(lastIncoming)
+	inputGrams := textutil.CharBigrams(currentInput)
+	if len(incomingGrams) == 0 && len(inputGrams) == 0 {
+		return nil, nil
+	}
+
+	scan := m.config.FewShotScanMessages
+	if scan <= 0 {
+		scan = defaultFewShotScanMessages
+	}
+	messages, err := m.getRecentMessages(conversationID, scan)
+	if err != nil {
+		return nil, fmt.Errorf("查询示例消息失败: %w", err)
+	}
+
+	inRecent := make(map[uint]bool, len(recent))
+	for _, msg := range recent {
+		inRecent[msg.ID] = true
+	}
+
+	var examples []FewShotExample
+	// 从新到旧遍历，相关度相同时较新的示例排在前面
+	for i := len(messages) - 1; i >= 1; i-- {
+		reply, prompt := messages[i], messages[i-1]
+		if reply.SenderID != senderID || prompt.SenderID == senderID || inRecent[reply.ID] {
+			continue
+		}
+		score := textutil.DiceCoefficient(incomingGrams, textutil.CharBigrams(prompt.Content)) +
+			textutil.DiceCoefficient(inputGrams, textutil.CharBigrams(reply.Content))
+		if score <= 0 {
+			continue
+		}
+		examples = append(examples, FewShotExample{
+			PromptSender: prompt.SenderID,
+			Prompt:       prompt.Content,
+			Reply:        reply.Content,
+			Score:        score,
+		})
+	}
+
+	sort.SliceStable(examples, func(i, j int) bool { return examples[i].Score > examples[j].Score })
+	if len(examples) > count {
+		examples = examples[:count]
+	}
+
+	maxChars := m.config.FewShotMaxChars
+	if maxChars <= 0 {
+		maxChars = defaultFewShotMaxChars
+	}
+	for i := range examples {
+		examples[i].Prompt, _ = textutil.Abbreviate(examples[i].Prompt, maxChars)
+		examples[i].Reply, _ = textutil.Abbreviate(examples[i].Reply, maxChars)
+	}
+	return examples, nil
+}
+
+// formatFewShot 把示例格式化为上下文中的对话片段
+func formatFewShot(senderID string, examples []FewShotExample) string {
+	var b strings.Builder
+	for i, example := range examples {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "[%s]: %s\n[%s]: %s\n", example.PromptSender, example.Prompt, senderID, example.Reply)
+	}
+	return b.String()
+}
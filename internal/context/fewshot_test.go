@@ -0,0 +1,97 @@
+package context
+
+import (
+	"strings"
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// fewShotMessages 较早的对话中有一段与最新消息相似的问答和一段无关的问答
+func fewShotMessages() []models.Message {
+	return []models.Message{
+		{SenderID: "bob", Content: "周末去爬山吗"},
+		{SenderID: "alice", Content: "好呀，爬山我最喜欢了"},
+		{SenderID: "bob", Content: "今天加班吗"},
+		{SenderID: "alice", Content: "不加，准时下班"},
+		{SenderID: "alice", Content: "你呢"},
+		{SenderID: "bob", Content: "周末去不去爬山"},
+	}
+}
+
+// 只挑选与当前情境相关的历史回复作为示例，并以对话片段的形式注入上下文
+func TestBuildContextFewShotExamples(t *testing.T) {
+	m, db := newTestManager(t, &config.ContextConfig{RecentMessagesCount: 2, FewShotCount: 2})
+	conversation := testutil.CreateConversation(t, db, "conv-fewshot", fewShotMessages()...)
+
+	detail, err := m.BuildContextDetail(conversation.ID, "alice", "好", BuildOptions{})
+	if err != nil {
+		t.Fatalf("构建上下文失败: %v", err)
+	}
+	if len(detail.FewShotExamples) != 1 {
+		t.Fatalf("应只选出一条相关示例，实际 %+v", detail.FewShotExamples)
+	}
+	example := detail.FewShotExamples[0]
+	if example.PromptSender != "bob" || example.Prompt != "周末去爬山吗" || example.Reply != "好呀，爬山我最喜欢了" || example.Score <= 0 {
+		t.Errorf("示例为 %+v", example)
+	}
+	if !strings.Contains(detail.Context, "=== 回复示例 ===") || !strings.Contains(detail.Context, "[bob]: 周末去爬山吗\n[alice]: 好呀，爬山我最喜欢了\n") {
+		t.Errorf("上下文中应包含回复示例: %s", detail.Context)
+	}
+}
+
+// 已在近期消息中的回复不作为示例；隐私模式和角色扮演时不注入示例；未配置示例数时不注入
+func TestBuildContextFewShotSkipped(t *testing.T) {
+	m, db := newTestManager(t, &config.ContextConfig{RecentMessagesCount: 6, FewShotCount: 2})
+	conversation := testutil.CreateConversation(t, db, "conv-fewshot", fewShotMessages()...)
+	detail, err := m.BuildContextDetail(conversation.ID, "alice", "好", BuildOptions{})
+	if err != nil {
+		t.Fatalf("构建上下文失败: %v", err)
+	}
+	if len(detail.FewShotExamples) != 0 {
+		t.Errorf("近期消息中的回复不应作为示例: %+v", detail.FewShotExamples)
+	}
+
+	m, db = newTestManager(t, &config.ContextConfig{RecentMessagesCount: 2, FewShotCount: 2})
+	conversation = testutil.CreateConversation(t, db, "conv-fewshot", fewShotMessages()...)
+	for name, opts := range map[string]BuildOptions{"隐私模式": {PrivacyMode: true}, "角色扮演": {PersonaStyle: "猫娘"}} {
+		detail, err := m.BuildContextDetail(conversation.ID, "alice", "好", opts)
+		if err != nil {
+			t.Fatalf("构建上下文失败: %v", err)
+		}
+		if len(detail.FewShotExamples) != 0 || strings.Contains(detail.Context, "回复示例") {
+			t.Errorf("%s下不应注入示例: %+v", name, detail.FewShotExamples)
+		}
+	}
+
+	m, db = newTestManager(t, &config.ContextConfig{RecentMessagesCount: 2})
+	conversation = testutil.CreateConversation(t, db, "conv-fewshot", fewShotMessages()...)
+	detail, err = m.BuildContextDetail(conversation.ID, "alice", "好", BuildOptions{})
+	if err != nil {
+		t.Fatalf("构建上下文失败: %v", err)
+	}
+	if len(detail.FewShotExamples) != 0 {
+		t.Errorf("未配置示例数时不应注入: %+v", detail.FewShotExamples)
+	}
+}
+
+// 示例中的超长消息只保留节选
+func TestFewShotExamplesAbbreviated(t *testing.T) {
+	m, db := newTestManager(t, &config.ContextConfig{FewShotCount: 1, FewShotMaxChars: 10})
+	long := "好呀，爬山我最喜欢了，" + strings.Repeat("山顶的风景特别好", 5)
+	conversation := testutil.CreateConversation(t, db, "conv-fewshot",
+		models.Message{SenderID: "bob", Content: "周末去爬山吗"},
+		models.Message{SenderID: "alice", Content: long},
+	)
+
+	recent := []models.Message{{SenderID: "bob", Content: "周末去不去爬山"}}
+	examples, err := m.fewShotExamples(conversation.ID, "alice", "", recent)
+	if err != nil {
+		t.Fatalf("挑选示例失败: %v", err)
+	}
+	if len(examples) != 1 || !strings.HasPrefix(examples[0].Reply, "好呀，爬山") || !strings.Contains(examples[0].Reply, "[省略") {
+		t.Errorf("超长回复应被节选: %+v", examples)
+	}
+}
@@ -0,0 +1,43 @@
+package context
+
+import (
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/style"
+	"ChatRecommend/internal/summary"
+	"ChatRecommend/internal/testutil"
+	"gorm.io/gorm"
+)
+
+// newTestManager 在内存数据库上创建上下文管理器，未设置的近期消息数和 token 上限使用测试默认值
+func newTestManager(t *testing.T, cfg *config.ContextConfig) (*Manager, *gorm.DB) {
+	t.Helper()
+	if cfg.RecentMessagesCount == 0 {
+		cfg.RecentMessagesCount = 10
+	}
+	if cfg.MaxContextTokens == 0 {
+		cfg.MaxContextTokens = 4000
+	}
+	db := testutil.NewDB(t)
+	summaryMgr := summary.NewManager(db, &config.SummaryConfig{}, &config.PromptConfig{}, &testutil.MockLLM{}, nil)
+	styleMgr := style.NewManager(db, &config.StyleConfig{}, nil)
+	return NewManager(db, cfg, &config.PromptConfig{}, summaryMgr, styleMgr), db
+}
+
+// saveSummary 保存对话摘要
+func saveSummary(t *testing.T, db *gorm.DB, conversationID uint, prompt string) {
+	t.Helper()
+	if err := db.Create(&models.Summary{ConversationID: conversationID, Prompt: prompt, KeyInfo: "[]"}).Error; err != nil {
+		t.Fatalf("保存摘要失败: %v", err)
+	}
+}
+
+// saveStyle 保存用户在对话中的风格特征（JSON）
+func saveStyle(t *testing.T, db *gorm.DB, conversationID uint, userID, features string) {
+	t.Helper()
+	if err := db.Create(&models.Style{ConversationID: conversationID, UserID: userID, Features: features}).Error; err != nil {
+		t.Fatalf("保存风格失败: %v", err)
+	}
+}
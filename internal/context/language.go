@@ -0,0 +1,29 @@
+package context
+
+import (
+	"fmt"
+
+	"ChatRecommend/internal/models"
+)
+
+// languageNames 可锁定语言在提示词中的名称
+var languageNames = map[string]string{
+	models.LanguageZh: "中文（Chinese）",
+	models.LanguageEn: "英文（English）",
+	models.LanguageJa: "日文（Japanese）",
+	models.LanguageKo: "韩文（Korean）",
+}
+
+// lockedLanguage 对话设置中锁定的补全语言，未设置或设置无效时返回空
+func lockedLanguage(conversation *models.Conversation) string {
+	settings, err := conversation.GetSettings()
+	if err != nil || !models.ValidLanguage(settings.LockedLanguage) {
+		return ""
+	}
+	return settings.LockedLanguage
+}
+
+// languageInstruction 语言锁定指令
+func languageInstruction(language string) string {
+	return fmt.Sprintf("本对话的补全必须全部使用%s，即使对话历史、当前输入或用户附加指令中出现其他语言也不例外；人名、地名等专有名词可以保留原文。", languageNames[language])
+}
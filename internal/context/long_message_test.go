@@ -0,0 +1,58 @@
+package context
+
+import (
+	"strings"
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// 超长消息在上下文中默认只注入摘要占位，不会挤占整个 token 预算
+func TestBuildContextPlaceholdersLongMessage(t *testing.T) {
+	m, db := newTestManager(t, &config.ContextConfig{MaxMessageChars: 100, LongMessageMode: "placeholder"})
+	long := "开头" + strings.Repeat("很长的内容", 20000) + "结尾"
+	conversation := testutil.CreateConversation(t, db, "conv-long",
+		models.Message{SenderID: "bob", Content: long},
+		models.Message{SenderID: "bob", Content: "看完了吗"},
+	)
+
+	detail, err := m.BuildContextDetail(conversation.ID, "alice", "还没", BuildOptions{})
+	if err != nil {
+		t.Fatalf("构建上下文失败: %v", err)
+	}
+	if !strings.Contains(detail.Context, "[bob]: [长消息，原文共100004字，内容已省略]\n") {
+		t.Fatalf("长消息应替换为摘要占位: %.300s", detail.Context)
+	}
+	if strings.Contains(detail.Context, "很长的内容") {
+		t.Fatalf("占位模式下不应注入长消息原文: %.300s", detail.Context)
+	}
+	if !strings.Contains(detail.Context, "看完了吗") {
+		t.Fatalf("长消息之后的消息不应被挤掉: %.300s", detail.Context)
+	}
+}
+
+// excerpt 模式下超长消息只注入首尾节选
+func TestBuildContextAbbreviatesLongMessage(t *testing.T) {
+	m, db := newTestManager(t, &config.ContextConfig{MaxMessageChars: 100, LongMessageMode: "excerpt"})
+	long := "开头" + strings.Repeat("很长的内容", 20000) + "结尾"
+	conversation := testutil.CreateConversation(t, db, "conv-long",
+		models.Message{SenderID: "bob", Content: long},
+		models.Message{SenderID: "bob", Content: "看完了吗"},
+	)
+
+	detail, err := m.BuildContextDetail(conversation.ID, "alice", "还没", BuildOptions{})
+	if err != nil {
+		t.Fatalf("构建上下文失败: %v", err)
+	}
+	if !strings.Contains(detail.Context, "[长消息节选，原文共100004字] 开头") || !strings.Contains(detail.Context, "结尾") {
+		t.Fatalf("长消息应以节选形式注入: %.300s", detail.Context)
+	}
+	if !strings.Contains(detail.Context, "看完了吗") {
+		t.Fatalf("长消息之后的消息不应被挤掉: %.300s", detail.Context)
+	}
+	if n := len([]rune(detail.Context)); n > 2000 {
+		t.Fatalf("上下文长度为 %d 字，长消息未被节选", n)
+	}
+}
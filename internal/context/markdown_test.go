@@ -0,0 +1,50 @@
+package context
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// Markdown 消息以纯文本注入上下文（近期消息、置顶消息和窗口追加的消息）；纯文本消息原样注入
+func TestBuildContextStripsMarkdown(t *testing.T) {
+	m, db := newTestManager(t, &config.ContextConfig{Window: config.ContextWindowConfig{Enabled: true}})
+	pinnedAt := time.Now()
+	conversation := testutil.CreateConversation(t, db, "conv-markdown",
+		models.Message{SenderID: "bob", Content: "**地址**：[人民路1号](https://example.com)", ContentFormat: models.ContentFormatMarkdown, Pinned: true, PinnedAt: &pinnedAt},
+		models.Message{SenderID: "alice", Content: "## 清单\n- 带伞\n- 带水", ContentFormat: models.ContentFormatMarkdown},
+		models.Message{SenderID: "bob", Content: "2*3*4 **不是** 加粗", ContentFormat: models.ContentFormatPlain},
+	)
+
+	detail, err := m.BuildContextDetail(conversation.ID, "alice", "好的", BuildOptions{})
+	if err != nil {
+		t.Fatalf("构建上下文失败: %v", err)
+	}
+	for _, want := range []string{"[bob]: 地址：人民路1号", "清单\n带伞\n带水", "2*3*4 **不是** 加粗"} {
+		if !strings.Contains(detail.Context, want) {
+			t.Errorf("上下文中应包含 %q: %s", want, detail.Context)
+		}
+	}
+	for _, unwanted := range []string{"**地址**", "## 清单", "https://example.com"} {
+		if strings.Contains(detail.Context, unwanted) {
+			t.Errorf("上下文中不应包含 Markdown 语法 %q: %s", unwanted, detail.Context)
+		}
+	}
+
+	appended := models.Message{ConversationID: conversation.ID, SenderID: "bob", Content: "> 收到", ContentFormat: models.ContentFormatMarkdown, Sequence: 4}
+	if err := db.Create(&appended).Error; err != nil {
+		t.Fatalf("保存消息失败: %v", err)
+	}
+	m.AppendMessage(conversation.ConversationID, appended)
+	recent, _, err := m.recentWindow(&conversation)
+	if err != nil {
+		t.Fatalf("取窗口失败: %v", err)
+	}
+	if last := recent[len(recent)-1]; last.Content != "收到" || last.ContentFormat != models.ContentFormatPlain {
+		t.Errorf("窗口追加的消息应为纯文本: %+v", last)
+	}
+}
@@ -0,0 +1,70 @@
+package context
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxPersonaLength 自定义角色描述最大长度（字符数）
+const maxPersonaLength = 100
+
+// personaPresets 内置角色扮演预设：名称 -> 扮演指令
+var personaPresets = map[string]string{
+	"文言文":  "用文言文表达，句式简练典雅，多用之乎者也等虚词，避免现代口语词。",
+	"鲁迅风":  "模仿鲁迅杂文的笔调：冷峻、犀利、略带讽刺，善用反语和短句，但不针对具体的人进行攻击。",
+	"猫娘":   "以可爱的猫娘口吻说话，语气软萌活泼，句尾常带\"喵\"，可以用少量颜文字。",
+	"东北话":  "用东北方言表达，语气豪爽直接，适当使用\"咋整\"\"嘎哈\"\"老铁\"等口语词。",
+	"新闻播报": "以新闻主播的口吻表达，措辞正式、客观、简洁，像在播报新闻。",
+}
+
+// personaAliases 预设的英文别名
+var personaAliases = map[string]string{
+	"wenyan":    "文言文",
+	"classical": "文言文",
+	"luxun":     "鲁迅风",
+	"catgirl":   "猫娘",
+	"dongbei":   "东北话",
+	"news":      "新闻播报",
+}
+
+// personaGuard 角色扮演的安全约束，始终跟在扮演指令后面，冲突时以它为准
+const personaGuard = "角色扮演只改变措辞和语气：补全仍须是用户接下来要发送的话，不得输出违法、色情、暴力、歧视或侮辱他人的内容，与这些要求冲突时以这些要求为准。"
+
+// PersonaPresets 返回内置预设名称（按名称排序）
+func PersonaPresets() []string {
+	names := make([]string, 0, len(personaPresets))
+	for name := range personaPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// personaInstruction 解析角色扮演：预设名称（或英文别名）返回预设指令，否则把文本当作自定义角色描述，
+// 按额外指令的规则清理（压缩换行、限制长度）
+func personaInstruction(conversationID uint, senderID string, persona string) string {
+	persona = strings.TrimSpace(persona)
+	if persona == "" {
+		return ""
+	}
+	name := persona
+	if alias, ok := personaAliases[strings.ToLower(name)]; ok {
+		name = alias
+	}
+	if instruction, ok := personaPresets[name]; ok {
+		return instruction
+	}
+
+	persona = strings.Join(strings.Fields(persona), " ")
+	if runes := []rune(persona); len(runes) > maxPersonaLength {
+		logrus.WithFields(logrus.Fields{
+			"conversation_id": conversationID,
+			"sender_id":       senderID,
+			"length":          len(runes),
+		}).Warn("自定义角色描述过长，已截断")
+		persona = string(runes[:maxPersonaLength])
+	}
+	return "模仿以下角色的口吻：" + persona
+}
@@ -0,0 +1,59 @@
+package context
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// 指定角色扮演时注入扮演指令并紧跟安全约束，替代学到的语言风格；未指定时仍使用学到的风格
+func TestBuildContextPersonaStyle(t *testing.T) {
+	m, db := newTestManager(t, &config.ContextConfig{})
+	conversation := testutil.CreateConversation(t, db, "conv-persona",
+		models.Message{SenderID: "bob", Content: "晚上吃什么"},
+	)
+	saveStyle(t, db, conversation.ID, "alice", `{"vocabulary":{"好的":3},"tone":"casual"}`)
+
+	normal, err := m.BuildContextDetail(conversation.ID, "alice", "吃", BuildOptions{})
+	if err != nil {
+		t.Fatalf("构建上下文失败: %v", err)
+	}
+	if normal.PersonaPrompt != "" || !strings.Contains(normal.Context, "语气：casual") {
+		t.Fatalf("未指定角色时应使用学到的风格: %s", normal.Context)
+	}
+
+	persona, err := m.BuildContextDetail(conversation.ID, "alice", "吃", BuildOptions{PersonaStyle: " Catgirl "})
+	if err != nil {
+		t.Fatalf("构建上下文失败: %v", err)
+	}
+	if persona.PersonaPrompt != personaPresets["猫娘"] {
+		t.Errorf("英文别名解析出的扮演指令为 %q", persona.PersonaPrompt)
+	}
+	if persona.StylePrompt != "" || strings.Contains(persona.Context, "语气：casual") {
+		t.Errorf("角色扮演应替代学到的风格: %s", persona.Context)
+	}
+	if !strings.Contains(persona.Context, persona.PersonaPrompt+"\n"+personaGuard) {
+		t.Errorf("扮演指令后应紧跟安全约束: %s", persona.Context)
+	}
+}
+
+// 非预设的角色按自定义描述处理：压缩空白并限制长度
+func TestPersonaInstructionCustom(t *testing.T) {
+	if got := personaInstruction(1, "alice", "文言文"); got != personaPresets["文言文"] {
+		t.Errorf("预设扮演指令为 %q", got)
+	}
+	if got := personaInstruction(1, "alice", "  "); got != "" {
+		t.Errorf("空角色应返回空指令，实际 %q", got)
+	}
+	if got := personaInstruction(1, "alice", "热情的\n\n导游"); got != "模仿以下角色的口吻：热情的 导游" {
+		t.Errorf("自定义扮演指令为 %q", got)
+	}
+	long := personaInstruction(1, "alice", strings.Repeat("长", maxPersonaLength+20))
+	if n := utf8.RuneCountInString(strings.TrimPrefix(long, "模仿以下角色的口吻：")); n != maxPersonaLength {
+		t.Errorf("超长角色描述截断后为 %d 个字符，期望 %d", n, maxPersonaLength)
+	}
+}
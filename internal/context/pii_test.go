@@ -0,0 +1,59 @@
+package context
+
+import (
+	"strings"
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+	"ChatRecommend/internal/textutil"
+)
+
+// 开启 mask_pii 后上下文和近期消息中的手机号、银行卡号被打码，未开启时原样注入
+func TestBuildContextMasksPII(t *testing.T) {
+	messages := func() []models.Message {
+		return []models.Message{
+			{SenderID: "bob", Content: "我手机13812345678"},
+			{SenderID: "bob", Content: "钱转到4111 1111 1111 1111"},
+		}
+	}
+
+	plain, db := newTestManager(t, &config.ContextConfig{})
+	conversation := testutil.CreateConversation(t, db, "conv-pii", messages()...)
+	detail, err := plain.BuildContextDetail(conversation.ID, "alice", "收到", BuildOptions{})
+	if err != nil {
+		t.Fatalf("构建上下文失败: %v", err)
+	}
+	if !strings.Contains(detail.Context, "13812345678") || detail.MaskedPII != nil {
+		t.Fatalf("未开启脱敏时上下文应保留原文: %s", detail.Context)
+	}
+
+	masked, db := newTestManager(t, &config.ContextConfig{MaskPII: true})
+	conversation = testutil.CreateConversation(t, db, "conv-pii", messages()...)
+	saveSummary(t, db, conversation.ID, "bob 的手机号是13812345678")
+	detail, err = masked.BuildContextDetail(conversation.ID, "alice", "收到", BuildOptions{})
+	if err != nil {
+		t.Fatalf("构建上下文失败: %v", err)
+	}
+	for _, raw := range []string{"13812345678", "4111 1111 1111 1111"} {
+		if strings.Contains(detail.Context, raw) {
+			t.Errorf("上下文未打码 %s: %s", raw, detail.Context)
+		}
+	}
+	if !strings.Contains(detail.Context, "138****5678") || !strings.Contains(detail.Context, "411* **** **** 1111") {
+		t.Errorf("上下文缺少打码后的内容: %s", detail.Context)
+	}
+	if detail.MaskedPII[textutil.PIIPhone] != 2 || detail.MaskedPII[textutil.PIIBankCard] != 1 {
+		t.Errorf("打码统计为 %v", detail.MaskedPII)
+	}
+	if detail.SummaryPrompt != "bob 的手机号是138****5678" || detail.RecentMessages[0].Content != "我手机138****5678" {
+		t.Errorf("摘要和近期消息也应打码: %q %q", detail.SummaryPrompt, detail.RecentMessages[0].Content)
+	}
+
+	var stored models.Message
+	db.Where("conversation_id = ?", conversation.ID).Order("sequence").First(&stored)
+	if stored.Content != "我手机13812345678" {
+		t.Errorf("打码不应修改数据库中的消息: %q", stored.Content)
+	}
+}
@@ -0,0 +1,53 @@
+package context
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// 置顶消息即使早已滑出近期消息窗口也会注入上下文，放在近期历史之前；隐私模式下不注入
+func TestBuildContextPinnedMessages(t *testing.T) {
+	m, db := newTestManager(t, &config.ContextConfig{RecentMessagesCount: 3})
+	pinnedAt := time.Now()
+	messages := []models.Message{
+		{SenderID: "bob", Content: "地址是人民路1号", Pinned: true, PinnedAt: &pinnedAt},
+	}
+	for i := 0; i < 5; i++ {
+		messages = append(messages, models.Message{SenderID: "alice", Content: fmt.Sprintf("闲聊%d", i+1)})
+	}
+	conversation := testutil.CreateConversation(t, db, "conv-pin", messages...)
+
+	detail, err := m.BuildContextDetail(conversation.ID, "alice", "我到", BuildOptions{})
+	if err != nil {
+		t.Fatalf("构建上下文失败: %v", err)
+	}
+	if len(detail.PinnedMessages) != 1 || detail.PinnedMessages[0].Content != "地址是人民路1号" {
+		t.Fatalf("置顶消息为 %+v", detail.PinnedMessages)
+	}
+	for _, msg := range detail.RecentMessages {
+		if msg.Pinned {
+			t.Fatalf("置顶消息不应在近期消息窗口中，测试数据有误")
+		}
+	}
+	pinnedIdx := strings.Index(detail.Context, "=== 置顶消息 ===\n")
+	if pinnedIdx < 0 || !strings.Contains(detail.Context, "[bob]: 地址是人民路1号") {
+		t.Fatalf("上下文中应包含置顶消息: %s", detail.Context)
+	}
+	if recentIdx := strings.Index(detail.Context, "闲聊5"); recentIdx < pinnedIdx {
+		t.Errorf("置顶消息应放在近期历史之前: %s", detail.Context)
+	}
+
+	private, err := m.BuildContextDetail(conversation.ID, "alice", "我到", BuildOptions{PrivacyMode: true})
+	if err != nil {
+		t.Fatalf("构建上下文失败: %v", err)
+	}
+	if len(private.PinnedMessages) != 0 || strings.Contains(private.Context, "人民路") {
+		t.Errorf("隐私模式下不应注入置顶消息: %s", private.Context)
+	}
+}
@@ -0,0 +1,29 @@
+package context
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// 配置的系统提示词前缀展开模板变量后放在上下文最前面
+func TestBuildContextSystemPrefix(t *testing.T) {
+	m, db := newTestManager(t, &config.ContextConfig{})
+	m.prompt = &config.PromptConfig{SystemPrefix: "你是${sender_id}的贴心助手（对话 ${conversation_id}，${date}），模仿用户语气"}
+	conversation := testutil.CreateConversation(t, db, "conv-prefix",
+		models.Message{SenderID: "bob", Content: "周五有空吗"},
+	)
+
+	detail, err := m.BuildContextDetail(conversation.ID, "alice", "有", BuildOptions{})
+	if err != nil {
+		t.Fatalf("构建上下文失败: %v", err)
+	}
+	want := "你是alice的贴心助手（对话 conv-prefix，" + time.Now().Format("2006-01-02") + "），模仿用户语气"
+	if detail.SystemPrefix != want || !strings.HasPrefix(detail.Context, want+"\n\n") {
+		t.Errorf("系统提示词前缀为 %q，上下文: %s", detail.SystemPrefix, detail.Context)
+	}
+}
@@ -0,0 +1,43 @@
+package context
+
+import (
+	"strings"
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// 隐私模式只注入风格画像和当前输入，不注入摘要和近期历史
+func TestBuildContextPrivacyMode(t *testing.T) {
+	m, db := newTestManager(t, &config.ContextConfig{})
+	conversation := testutil.CreateConversation(t, db, "conv-privacy",
+		models.Message{SenderID: "bob", Content: "我的银行卡密码是123456"},
+		models.Message{SenderID: "alice", Content: "别发这个"},
+	)
+	saveSummary(t, db, conversation.ID, "两人在讨论银行卡")
+	saveStyle(t, db, conversation.ID, "alice", `{"vocabulary":{"好的":3},"tone":"casual"}`)
+
+	normal, err := m.BuildContextDetail(conversation.ID, "alice", "知道了", BuildOptions{})
+	if err != nil {
+		t.Fatalf("构建上下文失败: %v", err)
+	}
+	if !strings.Contains(normal.Context, "两人在讨论银行卡") || !strings.Contains(normal.Context, "123456") {
+		t.Fatalf("普通模式应注入摘要和近期消息: %s", normal.Context)
+	}
+
+	private, err := m.BuildContextDetail(conversation.ID, "alice", "知道了", BuildOptions{PrivacyMode: true})
+	if err != nil {
+		t.Fatalf("构建上下文失败: %v", err)
+	}
+	if strings.Contains(private.Context, "两人在讨论银行卡") || strings.Contains(private.Context, "123456") {
+		t.Fatalf("隐私模式不应注入摘要和近期消息: %s", private.Context)
+	}
+	if private.SummaryPrompt != "" || len(private.RecentMessages) != 0 {
+		t.Fatalf("隐私模式的摘要为 %q，近期消息 %d 条", private.SummaryPrompt, len(private.RecentMessages))
+	}
+	if !strings.Contains(private.Context, "语气：casual") || !strings.Contains(private.Context, "[alice]: 知道了") {
+		t.Fatalf("隐私模式仍应注入风格画像和当前输入: %s", private.Context)
+	}
+}
@@ -0,0 +1,106 @@
+package context
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"ChatRecommend/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// profileTriggers 输入中出现这些词时才注入对应的档案字段，避免无关的个人信息进入上下文
+var profileTriggers = []struct {
+	label    string
+	keywords []string
+	value    func(f *models.ProfileFields) string
+}{
+	{"姓名", []string{"名字", "叫我", "我叫", "姓名", "称呼"}, func(f *models.ProfileFields) string { return f.Name }},
+	{"电话", []string{"电话", "手机", "号码", "联系方式"}, func(f *models.ProfileFields) string { return f.Phone }},
+	{"邮箱", []string{"邮箱", "邮件", "email", "Email"}, func(f *models.ProfileFields) string { return f.Email }},
+	{"地址", []string{"地址", "住在", "我家", "住址", "寄到"}, func(f *models.ProfileFields) string { return f.Address }},
+	{"生日", []string{"生日", "出生"}, func(f *models.ProfileFields) string { return f.Birthday }},
+}
+
+// preferenceTriggers 输入中出现这些词时注入全部偏好；否则只注入名称出现在输入中的偏好
+var preferenceTriggers = []string{"喜欢", "偏好", "口味", "习惯", "不吃", "爱吃"}
+
+// profileEnabled 判断对话是否注入用户档案：对话设置优先，未设置时使用全局配置
+func (m *Manager) profileEnabled(conversation *models.Conversation) bool {
+	settings, err := conversation.GetSettings()
+	if err == nil && settings.ProfileInjection != nil {
+		return *settings.ProfileInjection
+	}
+	return m.config.ProfileInjection
+}
+
+// profilePrompt 生成与当前输入相关的档案片段，没有相关字段时返回空
+func (m *Manager) profilePrompt(conversation *models.Conversation, senderID, currentInput string) string {
+	if strings.TrimSpace(currentInput) == "" {
+		return ""
+	}
+
+	fields := m.ProfileFields(conversation, senderID)
+	if fields == nil {
+		return ""
+	}
+	return formatRelevantProfile(fields, currentInput)
+}
+
+// ProfileFields 获取对话中允许使用的用户档案字段，对话未启用档案注入、没有档案或读取失败时返回nil
+func (m *Manager) ProfileFields(conversation *models.Conversation, senderID string) *models.ProfileFields {
+	if !m.profileEnabled(conversation) {
+		return nil
+	}
+
+	var profile models.UserProfile
+	if err := m.db.Where("sender_id = ?", senderID).Limit(1).Find(&profile).Error; err != nil {
+		logrus.WithError(err).Warn("查询用户档案失败")
+		return nil
+	}
+	if profile.ID == 0 {
+		return nil
+	}
+	fields, err := profile.GetFields()
+	if err != nil {
+		logrus.WithError(err).Warn("解析用户档案失败")
+		return nil
+	}
+	return fields
+}
+
+// formatRelevantProfile 只挑出当前输入涉及的档案字段
+func formatRelevantProfile(fields *models.ProfileFields, input string) string {
+	var lines []string
+	for _, trigger := range profileTriggers {
+		value := trigger.value(fields)
+		if value == "" || !containsAny(input, trigger.keywords) {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", trigger.label, value))
+	}
+
+	allPreferences := containsAny(input, preferenceTriggers)
+	keys := make([]string, 0, len(fields.Preferences))
+	for key := range fields.Preferences {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if allPreferences || strings.Contains(input, key) {
+			lines = append(lines, fmt.Sprintf("%s: %s", key, fields.Preferences[key]))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// containsAny 判断文本是否包含任一关键词
+func containsAny(text string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if strings.Contains(text, keyword) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package context
+
+import (
+	"strings"
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// 启用档案注入时，只有与当前输入相关的档案字段出现在构建的上下文中
+func TestBuildContextInjectsRelevantProfile(t *testing.T) {
+	m, db := newTestManager(t, &config.ContextConfig{ProfileInjection: true})
+	conversation := testutil.CreateConversation(t, db, "conv-profile",
+		models.Message{SenderID: "bob", Content: "周末去哪吃饭？"},
+	)
+	profile := models.UserProfile{SenderID: "alice"}
+	if err := profile.SetFields(&models.ProfileFields{
+		Name:        "王小丽",
+		Phone:       "13800000000",
+		Preferences: map[string]string{"口味": "不吃辣"},
+	}); err != nil {
+		t.Fatalf("设置档案失败: %v", err)
+	}
+	if err := db.Create(&profile).Error; err != nil {
+		t.Fatalf("保存档案失败: %v", err)
+	}
+
+	detail, err := m.BuildContextDetail(conversation.ID, "alice", "我不吃辣的，", BuildOptions{})
+	if err != nil {
+		t.Fatalf("构建上下文失败: %v", err)
+	}
+	if !strings.Contains(detail.Context, "口味: 不吃辣") {
+		t.Fatalf("上下文应包含相关的档案偏好: %s", detail.Context)
+	}
+	if strings.Contains(detail.Context, "13800000000") || strings.Contains(detail.Context, "王小丽") {
+		t.Fatalf("上下文不应包含与输入无关的档案字段: %s", detail.Context)
+	}
+
+	// 对话设置关闭档案注入时不注入
+	disabled := false
+	if err := conversation.SetSettings(&models.ConversationSettings{ProfileInjection: &disabled}); err != nil {
+		t.Fatalf("设置对话失败: %v", err)
+	}
+	if err := db.Save(&conversation).Error; err != nil {
+		t.Fatalf("保存对话失败: %v", err)
+	}
+	detail, err = m.BuildContextDetail(conversation.ID, "alice", "我不吃辣的，", BuildOptions{})
+	if err != nil {
+		t.Fatalf("构建上下文失败: %v", err)
+	}
+	if strings.Contains(detail.Context, "口味: 不吃辣") || detail.ProfilePrompt != "" {
+		t.Fatalf("关闭档案注入后不应注入档案: %q", detail.ProfilePrompt)
+	}
+}
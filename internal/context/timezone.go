@@ -0,0 +1,47 @@
+package context
+
+import (
+	"fmt"
+	"time"
+
+	"ChatRecommend/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// weekdayNames 星期的中文名称
+var weekdayNames = [...]string{"星期日", "星期一", "星期二", "星期三", "星期四", "星期五", "星期六"}
+
+// SetLocation 设置默认时区（一般为 server.timezone），对话和发送者都没有设置时区时使用
+func (m *Manager) SetLocation(loc *time.Location) {
+	if loc != nil {
+		m.location = loc
+	}
+}
+
+// conversationLocation 对话使用的时区，优先级：对话设置的时区 > 发送者档案中的时区 > 默认时区
+func (m *Manager) conversationLocation(conversation *models.Conversation, senderID string) *time.Location {
+	if settings, err := conversation.GetSettings(); err == nil && settings.Timezone != "" {
+		return models.LoadTimezone(settings.Timezone, m.location)
+	}
+
+	var profile models.UserProfile
+	if err := m.db.Where("sender_id = ?", senderID).Limit(1).Find(&profile).Error; err != nil {
+		logrus.WithError(err).Warn("查询用户档案失败")
+		return m.location
+	}
+	if profile.ID == 0 {
+		return m.location
+	}
+	fields, err := profile.GetFields()
+	if err != nil {
+		return m.location
+	}
+	return models.LoadTimezone(fields.Timezone, m.location)
+}
+
+// formatCurrentTime 当前时间的提示文本，如"2026年10月17日 星期六 14:30（Asia/Shanghai，UTC+08:00）"
+func formatCurrentTime(now time.Time) string {
+	return fmt.Sprintf("%s %s %s（%s，UTC%s）",
+		now.Format("2006年1月2日"), weekdayNames[now.Weekday()], now.Format("15:04"),
+		now.Location().String(), now.Format("-07:00"))
+}
@@ -0,0 +1,78 @@
+package context
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// 当前时间的提示文本带星期、时区名和 UTC 偏移
+func TestFormatCurrentTime(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("缺少时区数据: %v", err)
+	}
+	now := time.Date(2026, 10, 17, 14, 30, 0, 0, newYork)
+	if got, want := formatCurrentTime(now), "2026年10月17日 星期六 14:30（America/New_York，UTC-04:00）"; got != want {
+		t.Errorf("formatCurrentTime = %q，期望 %q", got, want)
+	}
+}
+
+// 当前时间按对话设置的时区 > 发送者档案的时区 > 默认时区注入，系统提示词的 ${date} 使用同一时区；关闭后不注入
+func TestBuildContextCurrentTime(t *testing.T) {
+	m, db := newTestManager(t, &config.ContextConfig{CurrentTime: true})
+	m.SetLocation(time.UTC)
+	m.prompt = &config.PromptConfig{SystemPrefix: "今天是 ${date}"}
+	conversation := testutil.CreateConversation(t, db, "conv-time", models.Message{SenderID: "bob", Content: "今晚有空吗"})
+
+	build := func() *Detail {
+		t.Helper()
+		detail, err := m.BuildContextDetail(conversation.ID, "alice", "我", BuildOptions{})
+		if err != nil {
+			t.Fatalf("构建上下文失败: %v", err)
+		}
+		return detail
+	}
+
+	if detail := build(); !strings.Contains(detail.CurrentTime, "（UTC，UTC+00:00）") || !strings.Contains(detail.Context, "=== 当前时间 ===\n") {
+		t.Errorf("未设置时区时应使用默认时区: %s", detail.Context)
+	}
+
+	profile := models.UserProfile{SenderID: "alice"}
+	if err := profile.SetFields(&models.ProfileFields{Timezone: "Asia/Tokyo"}); err != nil {
+		t.Fatalf("设置档案失败: %v", err)
+	}
+	if err := db.Create(&profile).Error; err != nil {
+		t.Fatalf("保存档案失败: %v", err)
+	}
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("缺少时区数据: %v", err)
+	}
+	detail := build()
+	if !strings.Contains(detail.CurrentTime, "Asia/Tokyo，UTC+09:00") {
+		t.Errorf("应使用发送者档案的时区: %s", detail.CurrentTime)
+	}
+	if want := "今天是 " + time.Now().In(tokyo).Format("2006-01-02"); detail.SystemPrefix != want {
+		t.Errorf("系统提示词为 %q，期望 %q", detail.SystemPrefix, want)
+	}
+
+	if err := conversation.SetSettings(&models.ConversationSettings{Timezone: "America/New_York"}); err != nil {
+		t.Fatalf("设置对话时区失败: %v", err)
+	}
+	if err := db.Model(&conversation).Update("settings", conversation.Settings).Error; err != nil {
+		t.Fatalf("保存设置失败: %v", err)
+	}
+	if detail := build(); !strings.Contains(detail.CurrentTime, "America/New_York") {
+		t.Errorf("对话设置的时区应优先: %s", detail.CurrentTime)
+	}
+
+	m.config.CurrentTime = false
+	if detail := build(); detail.CurrentTime != "" || strings.Contains(detail.Context, "=== 当前时间 ===") {
+		t.Errorf("关闭后不应注入当前时间: %s", detail.Context)
+	}
+}
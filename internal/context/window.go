@@ -0,0 +1,257 @@
+package context
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"ChatRecommend/internal/models"
+)
+
+// 被挤出窗口的较早消息最多保留的条数（用于生成临时摘要），默认最多缓存窗口的对话数，以及窗口默认的有效期
+const (
+	windowOverflowLimit        = 50
+	defaultWindowConversations = 1000
+	defaultWindowTTL           = 5 * time.Minute
+)
+
+// messageWindow 对话的近期消息滑动窗口：messages 为窗口内的消息，overflow 为被挤出窗口的较早消息，均按时间正序。
+// maxID 为窗口已包含的最新消息ID（不含草稿），复用窗口前与数据库比较，其他进程（如 cmd/ingest）写入的消息也能追加进来
+type messageWindow struct {
+	messages []models.Message
+	overflow []models.Message
+	tokens   int
+	maxID    uint
+	loadedAt time.Time
+	usedAt   time.Time
+}
+
+// windowCache 按对话缓存近期消息窗口。version 在任何对话的窗口追加或失效时递增，
+// 从数据库加载窗口期间有变化时不保存加载结果，避免丢掉加载期间保存的消息
+type windowCache struct {
+	mu      sync.Mutex
+	entries map[string]*messageWindow
+	version uint64
+}
+
+func newWindowCache() *windowCache {
+	return &windowCache{entries: make(map[string]*messageWindow)}
+}
+
+// recentWindow 从滑动窗口取近期消息和被挤出窗口的较早消息（均为副本）。复用窗口前查询对话的最新消息ID：
+// 有更新的消息（其他进程写入）时只追加这些消息，最新ID变小（消息被删除）或窗口超过有效期时重建；
+// 对话还没有窗口时查询一次数据库建立窗口
+func (m *Manager) recentWindow(conversation *models.Conversation) ([]models.Message, []models.Message, error) {
+	key := conversation.ConversationID
+
+	latest, err := m.latestMessageID(conversation.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	m.windows.mu.Lock()
+	w, ok := m.windows.entries[key]
+	if ok && (time.Since(w.loadedAt) > m.windowTTL() || latest < w.maxID) {
+		delete(m.windows.entries, key)
+		ok = false
+	}
+	if ok && latest == w.maxID {
+		w.usedAt = time.Now()
+		recent, overflow := slices.Clone(w.messages), slices.Clone(w.overflow)
+		m.windows.mu.Unlock()
+		return recent, overflow, nil
+	}
+	var since uint
+	if ok {
+		since = w.maxID
+	}
+	version := m.windows.version
+	m.windows.mu.Unlock()
+
+	if ok {
+		if recent, overflow, caught, err := m.catchUpWindow(key, w, conversation.ID, since, latest); err != nil || caught {
+			return recent, overflow, err
+		}
+	}
+
+	messages, err := m.getRecentMessages(conversation.ID, m.config.RecentMessagesCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	w = &messageWindow{maxID: latest, loadedAt: time.Now(), usedAt: time.Now()}
+	for _, msg := range messages {
+		m.pushWindow(w, msg)
+	}
+	recent, overflow := slices.Clone(w.messages), slices.Clone(w.overflow)
+
+	m.windows.mu.Lock()
+	defer m.windows.mu.Unlock()
+	if m.windows.version != version {
+		return recent, overflow, nil
+	}
+	if _, ok := m.windows.entries[key]; !ok {
+		m.evictWindowLocked()
+		m.windows.entries[key] = w
+	}
+	return recent, overflow, nil
+}
+
+// catchUpWindow 把窗口建立后数据库中新增的消息（ID 大于 since）追加进窗口。新增消息超过 recent_messages_count 条时
+// 不如直接重建，返回 caught=false 由调用方重建
+func (m *Manager) catchUpWindow(key string, w *messageWindow, conversationID, since, latest uint) ([]models.Message, []models.Message, bool, error) {
+	var messages []models.Message
+	if err := m.db.Where("conversation_id = ? AND id > ?", conversationID, since).
+		Scopes(models.ExcludeDrafts).
+		Order("sequence ASC, created_at ASC").
+		Limit(m.config.RecentMessagesCount + 1).
+		Find(&messages).Error; err != nil {
+		return nil, nil, false, fmt.Errorf("查询新增消息失败: %w", err)
+	}
+	if len(messages) > m.config.RecentMessagesCount {
+		return nil, nil, false, nil
+	}
+	plainMessages(messages)
+
+	m.windows.mu.Lock()
+	defer m.windows.mu.Unlock()
+	if m.windows.entries[key] != w {
+		// 期间窗口被失效或替换，交给调用方重建
+		return nil, nil, false, nil
+	}
+	m.windows.version++
+	for _, msg := range messages {
+		if !w.contains(msg.ID) {
+			m.pushWindow(w, msg)
+		}
+	}
+	w.maxID = max(w.maxID, latest)
+	w.usedAt = time.Now()
+	return slices.Clone(w.messages), slices.Clone(w.overflow), true, nil
+}
+
+// latestMessageID 对话最新的非草稿消息ID，没有消息时为0
+func (m *Manager) latestMessageID(conversationID uint) (uint, error) {
+	var latest uint
+	if err := m.db.Model(&models.Message{}).
+		Where("conversation_id = ?", conversationID).
+		Scopes(models.ExcludeDrafts).
+		Select("COALESCE(MAX(id), 0)").
+		Scan(&latest).Error; err != nil {
+		return 0, fmt.Errorf("查询最新消息失败: %w", err)
+	}
+	return latest, nil
+}
+
+// contains 窗口（含被挤出的较早消息）中是否已有该消息
+func (w *messageWindow) contains(id uint) bool {
+	hasID := func(msg models.Message) bool { return msg.ID == id }
+	return slices.ContainsFunc(w.messages, hasID) || slices.ContainsFunc(w.overflow, hasID)
+}
+
+// windowTTL 窗口的有效期，超过后下次补全时从数据库重建（兜底其他进程对消息的编辑和删除）
+func (m *Manager) windowTTL() time.Duration {
+	if m.config.Window.TTLSeconds > 0 {
+		return time.Duration(m.config.Window.TTLSeconds) * time.Second
+	}
+	return defaultWindowTTL
+}
+
+// AppendMessage 新消息保存后追加进对话的窗口（草稿不进窗口）；对话还没有窗口时不做处理，下次补全时再建立
+func (m *Manager) AppendMessage(conversationID string, message models.Message) {
+	if m == nil || !m.config.Window.Enabled || message.MessageType == models.MessageTypeDraft {
+		return
+	}
+
+	m.windows.mu.Lock()
+	defer m.windows.mu.Unlock()
+	m.windows.version++
+	w, ok := m.windows.entries[conversationID]
+	if !ok {
+		return
+	}
+	if w.contains(message.ID) {
+		return
+	}
+	message.Content = message.PlainContent()
+	message.ContentFormat = models.ContentFormatPlain
+	m.pushWindow(w, message)
+	w.maxID = max(w.maxID, message.ID)
+}
+
+// InvalidateWindow 丢弃对话的窗口（消息被编辑、删除、合并、导入或恢复快照后），下次补全时从数据库重建
+func (m *Manager) InvalidateWindow(conversationID string) {
+	if m == nil {
+		return
+	}
+
+	m.windows.mu.Lock()
+	defer m.windows.mu.Unlock()
+	m.windows.version++
+	delete(m.windows.entries, conversationID)
+}
+
+// pushWindow 按时间顺序把消息放进窗口，窗口超出 token 预算或 recent_messages_count 时把最早的消息挤进 overflow（窗口内至少保留一条）
+func (m *Manager) pushWindow(w *messageWindow, message models.Message) {
+	w.messages = insertMessage(w.messages, message)
+	w.tokens += m.windowTokens(message)
+
+	budget := m.windowBudget()
+	for len(w.messages) > 1 && (w.tokens > budget || len(w.messages) > m.config.RecentMessagesCount) {
+		oldest := w.messages[0]
+		w.messages = w.messages[1:]
+		w.tokens -= m.windowTokens(oldest)
+		w.overflow = insertMessage(w.overflow, oldest)
+	}
+	if len(w.overflow) > windowOverflowLimit {
+		w.overflow = w.overflow[len(w.overflow)-windowOverflowLimit:]
+	}
+}
+
+// windowTokens 消息注入上下文后占用的token数
+func (m *Manager) windowTokens(message models.Message) int {
+	return EstimateTokens(fmt.Sprintf("[%s]: %s\n", message.SenderID, m.messageContent(message)))
+}
+
+// windowBudget 窗口的token预算
+func (m *Manager) windowBudget() int {
+	if m.config.Window.MaxTokens > 0 {
+		return m.config.Window.MaxTokens
+	}
+	return m.config.MaxContextTokens / 2
+}
+
+// evictWindowLocked 缓存的对话数达到上限时淘汰最久未使用的窗口，调用方需持有锁
+func (m *Manager) evictWindowLocked() {
+	limit := m.config.Window.MaxConversations
+	if limit <= 0 {
+		limit = defaultWindowConversations
+	}
+	for len(m.windows.entries) >= limit {
+		var oldestKey string
+		var oldest time.Time
+		for key, w := range m.windows.entries {
+			if oldestKey == "" || w.usedAt.Before(oldest) {
+				oldestKey, oldest = key, w.usedAt
+			}
+		}
+		delete(m.windows.entries, oldestKey)
+	}
+}
+
+// insertMessage 按 (sequence, created_at) 顺序插入消息，新消息通常在末尾
+func insertMessage(messages []models.Message, message models.Message) []models.Message {
+	i := len(messages)
+	for i > 0 && messageAfter(messages[i-1], message) {
+		i--
+	}
+	return slices.Insert(messages, i, message)
+}
+
+// messageAfter 判断消息 a 是否排在 b 之后
+func messageAfter(a, b models.Message) bool {
+	if a.Sequence != b.Sequence {
+		return a.Sequence > b.Sequence
+	}
+	return a.CreatedAt.After(b.CreatedAt)
+}
@@ -0,0 +1,118 @@
+package context
+
+import (
+	"testing"
+	"time"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+	"gorm.io/gorm"
+)
+
+func newWindowManager(t *testing.T) (*Manager, *gorm.DB) {
+	t.Helper()
+	return newTestManager(t, &config.ContextConfig{
+		RecentMessagesCount: 5,
+		Window:              config.ContextWindowConfig{Enabled: true},
+	})
+}
+
+func windowContents(messages []models.Message) []string {
+	contents := make([]string, len(messages))
+	for i, msg := range messages {
+		contents[i] = msg.Content
+	}
+	return contents
+}
+
+func assertContents(t *testing.T, got []models.Message, want ...string) {
+	t.Helper()
+	contents := windowContents(got)
+	if len(contents) != len(want) {
+		t.Fatalf("窗口消息为 %v，期望 %v", contents, want)
+	}
+	for i := range want {
+		if contents[i] != want[i] {
+			t.Fatalf("窗口消息为 %v，期望 %v", contents, want)
+		}
+	}
+}
+
+// 窗口建立后，本进程保存的消息通过 AppendMessage 追加，其他进程直接写库的消息在下次取窗口时补上
+func TestRecentWindowCatchesUpExternalMessages(t *testing.T) {
+	m, db := newWindowManager(t)
+	conversation := testutil.CreateConversation(t, db, "conv-window",
+		models.Message{SenderID: "u1", Content: "第一条"},
+		models.Message{SenderID: "u2", Content: "第二条"},
+	)
+
+	recent, _, err := m.recentWindow(&conversation)
+	if err != nil {
+		t.Fatalf("建立窗口失败: %v", err)
+	}
+	assertContents(t, recent, "第一条", "第二条")
+
+	appended := models.Message{ConversationID: conversation.ID, SenderID: "u1", Content: "第三条", Sequence: 3}
+	if err := db.Create(&appended).Error; err != nil {
+		t.Fatalf("保存消息失败: %v", err)
+	}
+	m.AppendMessage(conversation.ConversationID, appended)
+
+	// 模拟 cmd/ingest：直接写库，不经过 AppendMessage
+	ingested := models.Message{ConversationID: conversation.ID, SenderID: "u2", Content: "第四条", Sequence: 4}
+	if err := db.Create(&ingested).Error; err != nil {
+		t.Fatalf("保存消息失败: %v", err)
+	}
+
+	recent, _, err = m.recentWindow(&conversation)
+	if err != nil {
+		t.Fatalf("取窗口失败: %v", err)
+	}
+	assertContents(t, recent, "第一条", "第二条", "第三条", "第四条")
+
+	// 再取一次不应重复追加
+	recent, _, err = m.recentWindow(&conversation)
+	if err != nil {
+		t.Fatalf("取窗口失败: %v", err)
+	}
+	assertContents(t, recent, "第一条", "第二条", "第三条", "第四条")
+}
+
+// 消息被其他进程删除（最新ID变小）或窗口过期时重建窗口
+func TestRecentWindowRebuildsAfterDeleteAndTTL(t *testing.T) {
+	m, db := newWindowManager(t)
+	conversation := testutil.CreateConversation(t, db, "conv-window-rebuild",
+		models.Message{SenderID: "u1", Content: "第一条"},
+		models.Message{SenderID: "u2", Content: "第二条"},
+	)
+	if _, _, err := m.recentWindow(&conversation); err != nil {
+		t.Fatalf("建立窗口失败: %v", err)
+	}
+
+	if err := db.Where("conversation_id = ? AND sequence = ?", conversation.ID, 2).Delete(&models.Message{}).Error; err != nil {
+		t.Fatalf("删除消息失败: %v", err)
+	}
+	recent, _, err := m.recentWindow(&conversation)
+	if err != nil {
+		t.Fatalf("取窗口失败: %v", err)
+	}
+	assertContents(t, recent, "第一条")
+
+	// 其他进程编辑了消息内容：有效期内沿用窗口，过期后重建
+	if err := db.Model(&models.Message{}).Where("conversation_id = ?", conversation.ID).
+		Update("content", "已编辑").Error; err != nil {
+		t.Fatalf("编辑消息失败: %v", err)
+	}
+	recent, _, _ = m.recentWindow(&conversation)
+	assertContents(t, recent, "第一条")
+
+	m.windows.mu.Lock()
+	m.windows.entries[conversation.ConversationID].loadedAt = time.Now().Add(-2 * defaultWindowTTL)
+	m.windows.mu.Unlock()
+	recent, _, err = m.recentWindow(&conversation)
+	if err != nil {
+		t.Fatalf("取窗口失败: %v", err)
+	}
+	assertContents(t, recent, "已编辑")
+}
@@ -0,0 +1,169 @@
+// Package correction 对补全输入做轻量纠错检测：识别未转换的拼音串、拼音首字母缩写和常见错别字。
+// 检测结果只作为提示注入上下文，不修改用户输入。
+package correction
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Hint 纠错提示
+type Hint struct {
+	// 原文片段
+	Original string `json:"original"`
+	// 类型：pinyin（拼音串）、initials（拼音首字母）、typo（错别字）
+	Kind string `json:"kind"`
+	// 建议（拼音为切分后的音节，错别字为正确写法）
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// 提示类型
+const (
+	KindPinyin   = "pinyin"
+	KindInitials = "initials"
+	KindTypo     = "typo"
+)
+
+// commonTypos 常见错别字混淆对（错误写法 -> 正确写法）
+var commonTypos = map[string]string{
+	"以经":   "已经",
+	"即然":   "既然",
+	"因该":   "应该",
+	"在见":   "再见",
+	"重来没有": "从来没有",
+	"一但":   "一旦",
+	"不防试试": "不妨试试",
+	"迫不急待": "迫不及待",
+	"按装":   "安装",
+	"再接再励": "再接再厉",
+	"默守成规": "墨守成规",
+	"甘败下风": "甘拜下风",
+	"走头无路": "走投无路",
+	"谈笑风声": "谈笑风生",
+	"出奇不意": "出其不意",
+	"哭笑不的": "哭笑不得",
+	"莫明其妙": "莫名其妙",
+}
+
+// typoKeys 排序后的错别字列表，保证提示顺序稳定
+var typoKeys = func() []string {
+	keys := make([]string, 0, len(commonTypos))
+	for wrong := range commonTypos {
+		keys = append(keys, wrong)
+	}
+	sort.Strings(keys)
+	return keys
+}()
+
+// 拼音声母（用于首字母缩写检测）
+const initialLetters = "bpmfdtnlgkhjqxrzcsyw"
+
+// Detect 检测输入中的疑似拼音串、首字母缩写和常见错别字
+func Detect(input string) []Hint {
+	hints := make([]Hint, 0)
+
+	for _, run := range letterRuns(input) {
+		lower := strings.ToLower(run)
+		if syllables, ok := splitPinyin(lower); ok && len(syllables) >= 2 {
+			hints = append(hints, Hint{Original: run, Kind: KindPinyin, Suggestion: strings.Join(syllables, " ")})
+			continue
+		}
+		if isInitials(lower) {
+			hints = append(hints, Hint{Original: run, Kind: KindInitials})
+		}
+	}
+
+	for _, wrong := range typoKeys {
+		if strings.Contains(input, wrong) {
+			hints = append(hints, Hint{Original: wrong, Kind: KindTypo, Suggestion: commonTypos[wrong]})
+		}
+	}
+
+	return hints
+}
+
+// FormatHints 把纠错提示格式化为上下文中的说明文字，没有提示时返回空
+func FormatHints(hints []Hint) string {
+	if len(hints) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	for _, hint := range hints {
+		switch hint.Kind {
+		case KindPinyin:
+			b.WriteString(fmt.Sprintf("- \"%s\" 可能是未转换成汉字的拼音（%s），请按对应汉字理解\n", hint.Original, hint.Suggestion))
+		case KindInitials:
+			b.WriteString(fmt.Sprintf("- \"%s\" 可能是拼音首字母缩写，请结合上下文理解\n", hint.Original))
+		case KindTypo:
+			b.WriteString(fmt.Sprintf("- \"%s\" 可能是 \"%s\" 的错别字\n", hint.Original, hint.Suggestion))
+		}
+	}
+	return b.String()
+}
+
+// letterRuns 提取输入中连续的ASCII字母串（至少2个字母）
+// 出现在英文句子中（前后有空格分隔的多个英文单词）的字母串不视为拼音，减少误判
+func letterRuns(input string) []string {
+	runs := make([]string, 0)
+	var current []rune
+	flush := func() {
+		if len(current) >= 2 {
+			runs = append(runs, string(current))
+		}
+		current = current[:0]
+	}
+	for _, r := range input {
+		if r < unicode.MaxASCII && unicode.IsLetter(r) {
+			current = append(current, r)
+			continue
+		}
+		flush()
+	}
+	flush()
+
+	if len(strings.Fields(input)) >= 3 && len(runs) >= 3 {
+		return nil
+	}
+	return runs
+}
+
+// isInitials 判断是否为拼音首字母缩写（2-4个字母，全部为声母）
+func isInitials(s string) bool {
+	if len(s) < 2 || len(s) > 4 {
+		return false
+	}
+	for _, r := range s {
+		if !strings.ContainsRune(initialLetters, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// splitPinyin 把字母串切分为拼音音节（动态规划，优先较少音节），无法完整切分时返回false
+func splitPinyin(s string) ([]string, bool) {
+	n := len(s)
+	// best[i] 表示 s[:i] 的最少音节切分
+	best := make([][]string, n+1)
+	best[0] = []string{}
+	for i := 1; i <= n; i++ {
+		for l := 1; l <= maxSyllableLength && l <= i; l++ {
+			prev := best[i-l]
+			if prev == nil || !syllables[s[i-l:i]] {
+				continue
+			}
+			if best[i] == nil || len(prev)+1 < len(best[i]) {
+				candidate := make([]string, len(prev), len(prev)+1)
+				copy(candidate, prev)
+				best[i] = append(candidate, s[i-l:i])
+			}
+		}
+	}
+	if best[n] == nil {
+		return nil, false
+	}
+	return best[n], true
+}
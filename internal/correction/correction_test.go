@@ -0,0 +1,31 @@
+package correction
+
+import "testing"
+
+// 识别拼音串、首字母缩写和常见错别字，英文句子中的单词不视为拼音
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []Hint
+	}{
+		{"我们mingtian去吃饭", []Hint{{Original: "mingtian", Kind: KindPinyin, Suggestion: "ming tian"}}},
+		{"zhidao了", []Hint{{Original: "zhidao", Kind: KindPinyin, Suggestion: "zhi dao"}}},
+		{"好的xswl", []Hint{{Original: "xswl", Kind: KindInitials}}},
+		{"我以经到了", []Hint{{Original: "以经", Kind: KindTypo, Suggestion: "已经"}}},
+		{"see you tomorrow", nil},
+		{"明天见", nil},
+	}
+	for _, tt := range tests {
+		got := Detect(tt.input)
+		if len(got) != len(tt.want) {
+			t.Errorf("Detect(%q) = %+v，期望 %+v", tt.input, got, tt.want)
+			continue
+		}
+		for i := range tt.want {
+			if got[i] != tt.want[i] {
+				t.Errorf("Detect(%q) = %+v，期望 %+v", tt.input, got, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Package database 按配置打开 SQLite 数据库，服务端和命令行工具共用
+package database
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/encryption"
+	"ChatRecommend/internal/models"
+	"github.com/sirupsen/logrus"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// memoryDBSeq 内存数据库序号，保证每次初始化得到独立的内存库
+var memoryDBSeq atomic.Int64
+
+// sqliteDSN 构建SQLite连接串，通过DSN参数设置PRAGMA，保证连接池中的每个连接都生效
+func sqliteDSN(cfg *config.DatabaseConfig) string {
+	// 内存库使用命名的共享缓存：连接池中的多个连接看到同一个库，不同实例之间互不干扰。
+	// 内存库不支持WAL，日志模式和同步模式不需要设置
+	if cfg.InMemory() {
+		return fmt.Sprintf("file:chatrecommend-%d?mode=memory&cache=shared&_busy_timeout=%d",
+			memoryDBSeq.Add(1), cfg.BusyTimeoutMs)
+	}
+
+	separator := "?"
+	if strings.Contains(cfg.DBPath, "?") {
+		separator = "&"
+	}
+	return fmt.Sprintf("%s%s_journal_mode=%s&_busy_timeout=%d&_synchronous=%s",
+		cfg.DBPath, separator, cfg.JournalMode, cfg.BusyTimeoutMs, cfg.Synchronous)
+}
+
+// Open 配置消息内容加密并连接数据库（不执行迁移）
+func Open(cfg *config.Config) (*gorm.DB, error) {
+	// 配置消息内容加密
+	if cfg.Database.EncryptionKey != "" {
+		keys := make(map[string]string, len(cfg.Database.OldEncryptionKeys)+1)
+		for version, key := range cfg.Database.OldEncryptionKeys {
+			keys[version] = key
+		}
+		keys[cfg.Database.EncryptionKeyVersion] = cfg.Database.EncryptionKey
+
+		contentCipher, err := encryption.NewContentCipher(cfg.Database.EncryptionKeyVersion, keys)
+		if err != nil {
+			return nil, fmt.Errorf("初始化消息加密失败: %w", err)
+		}
+		models.SetContentCipher(contentCipher)
+		logrus.WithField("key_version", cfg.Database.EncryptionKeyVersion).Info("已启用消息内容加密")
+	}
+
+	db, err := gorm.Open(sqlite.Open(sqliteDSN(&cfg.Database)), &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("连接数据库失败: %w", err)
+	}
+
+	// 内存库在最后一个连接关闭时销毁，连接不能因过期被回收
+	if cfg.Database.InMemory() {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, fmt.Errorf("获取数据库连接池失败: %w", err)
+		}
+		sqlDB.SetConnMaxLifetime(0)
+		sqlDB.SetConnMaxIdleTime(0)
+		logrus.Warn("使用内存数据库，数据不会持久化")
+	}
+
+	var journalMode string
+	if err := db.Raw("PRAGMA journal_mode").Scan(&journalMode).Error; err != nil {
+		logrus.WithError(err).Warn("查询SQLite日志模式失败")
+	} else {
+		logrus.WithField("journal_mode", journalMode).Info("SQLite日志模式")
+	}
+
+	logrus.Info("数据库初始化成功")
+	return db, nil
+}
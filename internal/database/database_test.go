@@ -0,0 +1,126 @@
+package database
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestOpenWALConcurrentWrites WAL模式下多个连接并发写入不会出现 database is locked
+func TestOpenWALConcurrentWrites(t *testing.T) {
+	logrus.SetLevel(logrus.ErrorLevel)
+	db, err := Open(&config.Config{Database: config.DatabaseConfig{
+		DBPath:        filepath.Join(t.TempDir(), "chat.db"),
+		JournalMode:   "WAL",
+		BusyTimeoutMs: 5000,
+		Synchronous:   "NORMAL",
+	}})
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	db.Logger = logger.Discard
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	sqlDB.SetMaxOpenConns(8)
+
+	var journalMode string
+	if err := db.Raw("PRAGMA journal_mode").Scan(&journalMode).Error; err != nil || journalMode != "wal" {
+		t.Fatalf("日志模式为 %q (%v)，期望 wal", journalMode, err)
+	}
+	if err := db.AutoMigrate(&models.Conversation{}); err != nil {
+		t.Fatalf("建表失败: %v", err)
+	}
+
+	const workers, writes = 8, 25
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*writes)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < writes; i++ {
+				conversation := models.Conversation{ConversationID: fmt.Sprintf("conv-%d-%d", w, i)}
+				if err := db.Create(&conversation).Error; err != nil {
+					errs <- err
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("并发写入失败: %v", err)
+	}
+
+	var count int64
+	db.Model(&models.Conversation{}).Count(&count)
+	if count != workers*writes {
+		t.Errorf("写入 %d 条，期望 %d 条", count, workers*writes)
+	}
+}
+
+// TestOpenMemoryDatabase 内存库在连接池内共享数据，不同实例之间互不干扰
+func TestOpenMemoryDatabase(t *testing.T) {
+	logrus.SetLevel(logrus.ErrorLevel)
+	open := func() *gorm.DB {
+		t.Helper()
+		db, err := Open(&config.Config{Database: config.DatabaseConfig{
+			DBPath:        config.MemoryDBPath,
+			BusyTimeoutMs: 5000,
+		}})
+		if err != nil {
+			t.Fatalf("打开内存数据库失败: %v", err)
+		}
+		db.Logger = logger.Discard
+		sqlDB, err := db.DB()
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { sqlDB.Close() })
+		sqlDB.SetMaxOpenConns(4)
+		return db
+	}
+
+	first := open()
+	if err := first.AutoMigrate(&models.Conversation{}); err != nil {
+		t.Fatalf("建表失败: %v", err)
+	}
+
+	// 同时占用多个连接写入，所有连接都应看到同一张表
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := first.Create(&models.Conversation{ConversationID: fmt.Sprintf("conv-%d", i)}).Error; err != nil {
+				errs <- err
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("内存库写入失败: %v", err)
+	}
+	var count int64
+	first.Model(&models.Conversation{}).Count(&count)
+	if count != 8 {
+		t.Errorf("内存库中有 %d 个对话，期望 8 个", count)
+	}
+
+	second := open()
+	if second.Migrator().HasTable(&models.Conversation{}) {
+		t.Error("新的内存库不应看到其它实例的表")
+	}
+}
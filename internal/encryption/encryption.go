@@ -0,0 +1,112 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// encryptedPrefix 密文前缀，完整格式为 enc:<版本>:<base64(nonce+密文)>
+const encryptedPrefix = "enc:"
+
+// ContentCipher 消息内容加解密器（AES-GCM），支持按版本前缀进行密钥轮换
+type ContentCipher struct {
+	currentVersion string
+	aeads          map[string]cipher.AEAD
+}
+
+// NewContentCipher 创建加解密器
+// currentVersion 为加密时使用的密钥版本，keys 为所有可用于解密的 版本->密钥
+func NewContentCipher(currentVersion string, keys map[string]string) (*ContentCipher, error) {
+	if currentVersion == "" {
+		return nil, fmt.Errorf("密钥版本不能为空")
+	}
+	if _, ok := keys[currentVersion]; !ok {
+		return nil, fmt.Errorf("缺少当前版本 %s 的密钥", currentVersion)
+	}
+
+	c := &ContentCipher{
+		currentVersion: currentVersion,
+		aeads:          make(map[string]cipher.AEAD, len(keys)),
+	}
+	for version, key := range keys {
+		if version == "" || strings.Contains(version, ":") {
+			return nil, fmt.Errorf("非法的密钥版本: %q", version)
+		}
+		if key == "" {
+			return nil, fmt.Errorf("密钥版本 %s 的密钥为空", version)
+		}
+
+		// 使用SHA-256将任意长度的密钥派生为AES-256密钥
+		sum := sha256.Sum256([]byte(key))
+		block, err := aes.NewCipher(sum[:])
+		if err != nil {
+			return nil, fmt.Errorf("创建AES加密器失败: %w", err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("创建GCM失败: %w", err)
+		}
+		c.aeads[version] = aead
+	}
+
+	return c, nil
+}
+
+// IsEncrypted 判断内容是否为密文
+func IsEncrypted(content string) bool {
+	return strings.HasPrefix(content, encryptedPrefix)
+}
+
+// Encrypt 使用当前版本密钥加密
+func (c *ContentCipher) Encrypt(plaintext string) (string, error) {
+	aead := c.aeads[c.currentVersion]
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("生成随机数失败: %w", err)
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedPrefix + c.currentVersion + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 根据密文中的版本前缀选择密钥解密，非密文原样返回（兼容未加密的历史数据）
+func (c *ContentCipher) Decrypt(content string) (string, error) {
+	if !IsEncrypted(content) {
+		return content, nil
+	}
+
+	rest := strings.TrimPrefix(content, encryptedPrefix)
+	idx := strings.Index(rest, ":")
+	if idx <= 0 {
+		return "", fmt.Errorf("密文格式错误")
+	}
+	version, payload := rest[:idx], rest[idx+1:]
+
+	aead, ok := c.aeads[version]
+	if !ok {
+		return "", fmt.Errorf("未找到密钥版本: %s", version)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", fmt.Errorf("解码密文失败: %w", err)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("密文长度错误")
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("解密失败: %w", err)
+	}
+
+	return string(plaintext), nil
+}
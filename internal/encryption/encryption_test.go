@@ -0,0 +1,82 @@
+package encryption
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEncryptDecryptRoundTrip(t *testing.T) {
+	c, err := NewContentCipher("v1", map[string]string{"v1": "secret"})
+	if err != nil {
+		t.Fatalf("创建加解密器失败: %v", err)
+	}
+
+	encrypted, err := c.Encrypt("明天七点见")
+	if err != nil {
+		t.Fatalf("加密失败: %v", err)
+	}
+	if !strings.HasPrefix(encrypted, "enc:v1:") || strings.Contains(encrypted, "明天") {
+		t.Fatalf("密文格式不符: %s", encrypted)
+	}
+	again, _ := c.Encrypt("明天七点见")
+	if again == encrypted {
+		t.Error("相同明文两次加密应得到不同密文")
+	}
+
+	plaintext, err := c.Decrypt(encrypted)
+	if err != nil || plaintext != "明天七点见" {
+		t.Fatalf("解密得到 %q, %v", plaintext, err)
+	}
+	// 未加密的历史数据原样返回
+	if plaintext, err := c.Decrypt("历史明文"); err != nil || plaintext != "历史明文" {
+		t.Fatalf("明文解密得到 %q, %v", plaintext, err)
+	}
+}
+
+// 轮换密钥后新数据用新版本加密，旧版本密文仍可解密；缺少旧密钥时报错
+func TestKeyRotation(t *testing.T) {
+	old, err := NewContentCipher("v1", map[string]string{"v1": "old-key"})
+	if err != nil {
+		t.Fatalf("创建加解密器失败: %v", err)
+	}
+	legacy, _ := old.Encrypt("旧消息")
+
+	rotated, err := NewContentCipher("v2", map[string]string{"v1": "old-key", "v2": "new-key"})
+	if err != nil {
+		t.Fatalf("创建加解密器失败: %v", err)
+	}
+	if plaintext, err := rotated.Decrypt(legacy); err != nil || plaintext != "旧消息" {
+		t.Fatalf("旧版本密文解密得到 %q, %v", plaintext, err)
+	}
+	fresh, _ := rotated.Encrypt("新消息")
+	if !strings.HasPrefix(fresh, "enc:v2:") {
+		t.Fatalf("新数据应使用当前版本加密: %s", fresh)
+	}
+
+	onlyNew, _ := NewContentCipher("v2", map[string]string{"v2": "new-key"})
+	if _, err := onlyNew.Decrypt(legacy); err == nil {
+		t.Fatal("缺少旧版本密钥时应报错")
+	}
+	wrongKey, _ := NewContentCipher("v1", map[string]string{"v1": "other-key"})
+	if _, err := wrongKey.Decrypt(legacy); err == nil {
+		t.Fatal("密钥不匹配时应报错")
+	}
+}
+
+func TestNewContentCipherValidatesKeys(t *testing.T) {
+	cases := []struct {
+		name    string
+		current string
+		keys    map[string]string
+	}{
+		{"空版本", "", map[string]string{"v1": "k"}},
+		{"缺少当前版本", "v2", map[string]string{"v1": "k"}},
+		{"版本含冒号", "v:1", map[string]string{"v:1": "k"}},
+		{"空密钥", "v1", map[string]string{"v1": ""}},
+	}
+	for _, tc := range cases {
+		if _, err := NewContentCipher(tc.current, tc.keys); err == nil {
+			t.Errorf("%s: 应返回错误", tc.name)
+		}
+	}
+}
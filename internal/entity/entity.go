@@ -0,0 +1,160 @@
+package entity
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Type 实体类型
+type Type string
+
+// 支持的实体类型
+const (
+	Location Type = "location"
+	Time     Type = "time"
+	Person   Type = "person"
+	Money    Type = "money"
+)
+
+// Types 全部实体类型
+var Types = []Type{Location, Time, Person, Money}
+
+// ValidType 判断实体类型是否合法
+func ValidType(t string) bool {
+	for _, typ := range Types {
+		if string(typ) == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Entity 从文本中抽取的实体
+type Entity struct {
+	Type  Type   `json:"type"`
+	Value string `json:"value"`
+	// 在文本中的起始位置（按字符计）
+	Offset int `json:"offset"`
+}
+
+// Extractor 实体抽取器。内置规则抽取器之后可以串接其他实现（如调用大模型补充规则覆盖不到的实体）
+type Extractor interface {
+	Extract(text string) []Entity
+}
+
+// rule 一条抽取规则：group 为实体值所在的分组（0表示整个匹配）
+type rule struct {
+	typ     Type
+	pattern *regexp.Regexp
+	group   int
+}
+
+const (
+	weekday  = `[一二三四五六日天]`
+	cnNumber = `[零一二两三四五六七八九十百千万]`
+	// 常见姓氏，用于识别"王老师""小李"这类称呼
+	surnames = `王李张刘陈杨黄赵吴周徐孙马朱胡郭何高林罗郑梁谢宋唐许韩冯邓曹彭曾肖田董袁潘于蒋蔡余杜叶程苏魏吕丁任沈姚卢姜崔钟谭陆汪范金石廖贾夏韦付方白邹孟熊秦邱江尹薛闫段雷侯龙史陶黎贺顾毛郝龚邵万钱严覃武戴莫孔向汤`
+	// 地点后缀
+	placeSuffix = `餐厅|饭店|酒店|酒吧|咖啡馆|咖啡厅|咖啡|茶餐厅|火锅店|火锅|烧烤|食堂|商场|广场|公园|医院|学校|大学|图书馆|体育馆|电影院|地铁站|车站|火车站|机场|大厦|中心|公司|小区|路|街|店`
+)
+
+// rules 内置抽取规则，同一位置多条规则命中时保留最长的匹配
+var rules = []rule{
+	// 金额：¥100、100元、3.5万元、五十块
+	{Money, regexp.MustCompile(`[¥￥$]\s?\d+(?:\.\d+)?[万千]?`), 0},
+	{Money, regexp.MustCompile(`\d+(?:\.\d+)?\s?(?:万元|万块|元|块钱|块|美元|刀|RMB|rmb)`), 0},
+	{Money, regexp.MustCompile(cnNumber + `+(?:万元|元|块钱|块)`), 0},
+
+	// 时间：2024-05-01、5月1日、明天下午3点半、下周五晚上、19:30
+	{Time, regexp.MustCompile(`\d{4}[-/年]\d{1,2}[-/月]\d{1,2}[日号]?`), 0},
+	{Time, regexp.MustCompile(`\d{1,2}月\d{1,2}[日号]`), 0},
+	{Time, regexp.MustCompile(`(?:今天|明天|后天|昨天|前天|今晚|明晚|大后天|(?:这|下|上)?(?:周|星期|礼拜)` + weekday + `)` +
+		`(?:早上|上午|中午|下午|傍晚|晚上)?(?:\d{1,2}(?:[:：]\d{2}|点(?:半|\d{1,2}分?)?)|` + cnNumber + `{1,3}点半?)?`), 0},
+	{Time, regexp.MustCompile(`(?:早上|上午|中午|下午|傍晚|晚上)?\d{1,2}(?:[:：]\d{2}|点(?:半|\d{1,2}分?)?)`), 0},
+
+	// 人名：王老师、李总、小张、@小明
+	{Person, regexp.MustCompile(`[` + surnames + `](?:先生|女士|小姐|老师|经理|总|医生|律师|师傅|阿姨|叔叔|姐|哥)`), 0},
+	{Person, regexp.MustCompile(`[小老][` + surnames + `]`), 0},
+	{Person, regexp.MustCompile(`@([\p{Han}\w]{1,16})`), 1},
+
+	// 地点：需要"在/去/到"等引导词，避免把整句话当作地名；取引导词之后到地点后缀的最短片段
+	{Location, regexp.MustCompile(`(?:在|去|到|来|回|约在|从|定在)([\p{Han}A-Za-z0-9]{0,10}?(?:` + placeSuffix + `))`), 1},
+}
+
+// RuleExtractor 基于正则和词典的规则抽取器
+type RuleExtractor struct{}
+
+// Extract 实现 Extractor
+func (RuleExtractor) Extract(text string) []Entity {
+	return Extract(text)
+}
+
+// span 命中的片段（字节位置）
+type span struct {
+	typ        Type
+	start, end int
+}
+
+// Extract 用内置规则抽取实体：按出现位置排序，同一起点保留最长的命中并跳过与之重叠的命中，同类型同值只保留第一次出现
+func Extract(text string) []Entity {
+	var spans []span
+	for _, r := range rules {
+		for _, loc := range r.pattern.FindAllStringSubmatchIndex(text, -1) {
+			start, end := loc[2*r.group], loc[2*r.group+1]
+			if start < 0 || start == end {
+				continue
+			}
+			spans = append(spans, span{typ: r.typ, start: start, end: end})
+		}
+	}
+
+	sort.Slice(spans, func(i, j int) bool {
+		if spans[i].start != spans[j].start {
+			return spans[i].start < spans[j].start
+		}
+		return spans[i].end-spans[i].start > spans[j].end-spans[j].start
+	})
+
+	var entities []Entity
+	seen := make(map[string]bool)
+	lastEnd := 0
+	for _, s := range spans {
+		if s.start < lastEnd {
+			continue
+		}
+		value := strings.TrimSpace(text[s.start:s.end])
+		key := string(s.typ) + "|" + value
+		lastEnd = s.end
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		entities = append(entities, Entity{Type: s.typ, Value: value, Offset: utf8.RuneCountInString(text[:s.start])})
+	}
+	return entities
+}
+
+// Chain 依次执行多个抽取器并合并结果，同类型同值的实体只保留第一个
+func Chain(extractors ...Extractor) Extractor {
+	return chain(extractors)
+}
+
+type chain []Extractor
+
+func (c chain) Extract(text string) []Entity {
+	var result []Entity
+	seen := make(map[string]bool)
+	for _, extractor := range c {
+		for _, e := range extractor.Extract(text) {
+			key := string(e.Type) + "|" + e.Value
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			result = append(result, e)
+		}
+	}
+	return result
+}
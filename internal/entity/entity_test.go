@@ -0,0 +1,54 @@
+package entity
+
+import (
+	"reflect"
+	"testing"
+)
+
+// 规则抽取地点、时间、人名和金额，按出现位置排序，偏移按字符计，重叠时保留最长的匹配
+func TestExtract(t *testing.T) {
+	cases := []struct {
+		text string
+		want []Entity
+	}{
+		{"明天下午3点半在海底捞火锅见，王老师也来", []Entity{
+			{Time, "明天下午3点半", 0}, {Location, "海底捞火锅", 8}, {Person, "王老师", 15},
+		}},
+		{"这顿饭一共256元，我转你128块", []Entity{{Money, "256元", 5}, {Money, "128块", 13}}},
+		{"@小明 下周五晚上去万达广场", []Entity{{Person, "小明", 1}, {Time, "下周五晚上", 4}, {Location, "万达广场", 10}}},
+		{"2024-05-01 我们到人民公园", []Entity{{Time, "2024-05-01", 0}, {Location, "人民公园", 14}}},
+		{"小李说小李也去", []Entity{{Person, "小李", 0}}},
+		{"好的没问题", nil},
+	}
+	for _, c := range cases {
+		if got := Extract(c.text); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("Extract(%q) = %+v，期望 %+v", c.text, got, c.want)
+		}
+	}
+}
+
+type fixedExtractor []Entity
+
+func (f fixedExtractor) Extract(string) []Entity { return f }
+
+// 串接的抽取器按顺序合并结果，同类型同值的实体只保留第一个
+func TestChain(t *testing.T) {
+	extractor := Chain(RuleExtractor{}, fixedExtractor{{Location, "海底捞火锅", 0}, {Location, "老地方", 3}})
+	got := extractor.Extract("去海底捞火锅")
+	want := []Entity{{Location, "海底捞火锅", 1}, {Location, "老地方", 3}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("串接抽取结果为 %+v，期望 %+v", got, want)
+	}
+}
+
+// 只接受定义过的实体类型
+func TestValidType(t *testing.T) {
+	for _, typ := range Types {
+		if !ValidType(string(typ)) {
+			t.Errorf("%s 应为合法类型", typ)
+		}
+	}
+	if ValidType("food") || ValidType("") {
+		t.Error("未定义的类型不应合法")
+	}
+}
@@ -0,0 +1,26 @@
+// Package importer 解析常见聊天平台导出的聊天记录，转换为统一的消息结构
+package importer
+
+import (
+	"errors"
+	"time"
+)
+
+// Message 从导出文件中解析出的消息
+type Message struct {
+	SenderID string
+	Content  string
+	Time     time.Time
+}
+
+// ErrNoMessages 导出文件中没有解析出任何消息
+var ErrNoMessages = errors.New("未解析出任何消息")
+
+// Parser 聊天记录解析器，loc 为导出文件中不带时区的时间所使用的时区
+type Parser func(data []byte, loc *time.Location) ([]Message, error)
+
+// Parsers 按平台名称注册的解析器
+var Parsers = map[string]Parser{
+	"wechat":   ParseWeChat,
+	"telegram": ParseTelegram,
+}
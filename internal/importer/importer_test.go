@@ -0,0 +1,78 @@
+package importer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// readSample 读取 testdata 中的样本导出文件
+func readSample(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("读取样本文件失败: %v", err)
+	}
+	return data
+}
+
+// checkMessages 比较解析结果的发送者、内容和时间
+func checkMessages(t *testing.T, got, want []Message) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("解析出 %d 条消息，期望 %d 条: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i].SenderID != want[i].SenderID || got[i].Content != want[i].Content || !got[i].Time.Equal(want[i].Time) {
+			t.Errorf("第%d条消息为 %+v，期望 %+v", i, got[i], want[i])
+		}
+	}
+}
+
+// 微信 txt 导出支持两种头部顺序、多行内容和 BOM，空内容的消息被跳过；html 导出先转为纯文本再解析
+func TestParseWeChat(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	at := func(hour, min, sec int) time.Time { return time.Date(2024, 1, 2, hour, min, sec, 0, loc) }
+
+	messages, err := ParseWeChat(readSample(t, "wechat.txt"), loc)
+	if err != nil {
+		t.Fatalf("解析微信 txt 失败: %v", err)
+	}
+	checkMessages(t, messages, []Message{
+		{SenderID: "张三", Content: "周末一起去爬山吗", Time: at(20, 15, 3)},
+		{SenderID: "李四", Content: "好啊\n几点出发？", Time: at(20, 16, 0)},
+	})
+
+	messages, err = ParseWeChat(readSample(t, "wechat.html"), loc)
+	if err != nil {
+		t.Fatalf("解析微信 html 失败: %v", err)
+	}
+	checkMessages(t, messages, []Message{
+		{SenderID: "张三", Content: "周末一起去爬山吗", Time: at(20, 15, 3)},
+		{SenderID: "李四", Content: "好啊\n几点出发？", Time: at(20, 16, 0)},
+		{SenderID: "张三", Content: "早上&八点", Time: at(20, 17, 0)},
+	})
+
+	if _, err := ParseWeChat([]byte("没有消息头部的文本"), loc); !errors.Is(err, ErrNoMessages) {
+		t.Errorf("无法识别的内容应返回 ErrNoMessages，得到 %v", err)
+	}
+}
+
+// Telegram 导出跳过服务消息和纯媒体消息，拼接带格式的文本片段，发送者优先使用 from_id
+func TestParseTelegram(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	messages, err := ParseTelegram(readSample(t, "telegram.json"), loc)
+	if err != nil {
+		t.Fatalf("解析 Telegram 导出失败: %v", err)
+	}
+	checkMessages(t, messages, []Message{
+		{SenderID: "user1001", Content: "Hiking this weekend?", Time: time.Unix(1704197703, 0)},
+		{SenderID: "Bob", Content: "Sure, see https://example.com for the trail", Time: time.Date(2024, 1, 2, 20, 16, 0, 0, loc)},
+	})
+
+	if _, err := ParseTelegram([]byte("not json"), loc); err == nil {
+		t.Error("非 JSON 内容应解析失败")
+	}
+}
@@ -0,0 +1,106 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// telegramExport Telegram Desktop 导出的单个聊天（result.json）
+type telegramExport struct {
+	Messages []telegramMessage `json:"messages"`
+}
+
+// telegramMessage 导出文件中的消息，text 可能是字符串或由字符串和带格式片段组成的数组
+type telegramMessage struct {
+	Type   string          `json:"type"`
+	Date   string          `json:"date"`
+	Unix   string          `json:"date_unixtime"`
+	From   string          `json:"from"`
+	FromID string          `json:"from_id"`
+	Text   json.RawMessage `json:"text"`
+}
+
+// telegramTimeLayouts date 字段的时间格式（导出时的本地时间，不带时区）
+var telegramTimeLayouts = []string{"2006-01-02T15:04:05"}
+
+// ParseTelegram 解析 Telegram Desktop 导出的 JSON 聊天记录，跳过服务消息和纯媒体消息
+// 发送者优先使用 from_id（稳定的用户ID），缺失时使用显示名 from
+func ParseTelegram(data []byte, loc *time.Location) ([]Message, error) {
+	var export telegramExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("解析Telegram导出文件失败: %w", err)
+	}
+
+	var messages []Message
+	for _, msg := range export.Messages {
+		if msg.Type != "message" {
+			continue
+		}
+		content := strings.TrimSpace(telegramText(msg.Text))
+		if content == "" {
+			continue
+		}
+
+		sender := msg.FromID
+		if sender == "" {
+			sender = msg.From
+		}
+
+		at, err := telegramTime(msg, loc)
+		if err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, Message{SenderID: sender, Content: content, Time: at})
+	}
+
+	if len(messages) == 0 {
+		return nil, ErrNoMessages
+	}
+	return messages, nil
+}
+
+// telegramTime 优先使用 date_unixtime，没有时按本地时间解析 date
+func telegramTime(msg telegramMessage, loc *time.Location) (time.Time, error) {
+	if msg.Unix != "" {
+		if sec, err := strconv.ParseInt(msg.Unix, 10, 64); err == nil {
+			return time.Unix(sec, 0), nil
+		}
+	}
+	return parseLocalTime(msg.Date, telegramTimeLayouts, loc)
+}
+
+// telegramText 拼接 text 字段：字符串直接返回，数组中的字符串和片段的 text 依次拼接
+func telegramText(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+
+	var parts []json.RawMessage
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return ""
+	}
+	var b strings.Builder
+	for _, part := range parts {
+		var s string
+		if err := json.Unmarshal(part, &s); err == nil {
+			b.WriteString(s)
+			continue
+		}
+		var entity struct {
+			Text string `json:"text"`
+		}
+		if err := json.Unmarshal(part, &entity); err == nil {
+			b.WriteString(entity.Text)
+		}
+	}
+	return b.String()
+}
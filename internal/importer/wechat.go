@@ -0,0 +1,123 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// 微信导出文本中每条消息以一行"头部"开始，常见两种顺序：
+//
+//	2024-01-02 15:04:05 张三
+//	张三 2024-01-02 15:04:05
+//
+// 头部之后直到下一个头部之间的行是消息内容
+var (
+	wechatTimeFirst   = regexp.MustCompile(`^(\d{4}-\d{1,2}-\d{1,2} \d{1,2}:\d{2}(?::\d{2})?)\s+(.+)$`)
+	wechatSenderFirst = regexp.MustCompile(`^(.+?)\s+(\d{4}-\d{1,2}-\d{1,2} \d{1,2}:\d{2}(?::\d{2})?)$`)
+)
+
+// wechatTimeLayouts 头部时间支持的格式
+var wechatTimeLayouts = []string{"2006-1-2 15:04:05", "2006-1-2 15:04"}
+
+// HTML 导出的标签处理：换行类标签转为换行，其余标签去掉
+var (
+	htmlBreakTag = regexp.MustCompile(`(?i)<br\s*/?>|</(?:p|div|li|tr|h\d)>`)
+	htmlTag      = regexp.MustCompile(`(?s)<[^>]*>`)
+	htmlStyleTag = regexp.MustCompile(`(?is)<(script|style)[^>]*>.*?</(?:script|style)>`)
+)
+
+// ParseWeChat 解析微信导出的聊天记录（txt 或 html），html 会先转为纯文本再按 txt 格式解析
+func ParseWeChat(data []byte, loc *time.Location) ([]Message, error) {
+	text := string(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	if looksLikeHTML(text) {
+		text = htmlToText(text)
+	}
+
+	var messages []Message
+	var current *Message
+	var body []string
+	flush := func() {
+		if current == nil {
+			return
+		}
+		current.Content = strings.TrimSpace(strings.Join(body, "\n"))
+		if current.Content != "" {
+			messages = append(messages, *current)
+		}
+		current, body = nil, nil
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		line = strings.TrimRight(line, " \t")
+		if sender, at, ok := parseWeChatHeader(strings.TrimSpace(line), loc); ok {
+			flush()
+			current = &Message{SenderID: sender, Time: at}
+			continue
+		}
+		if current != nil {
+			body = append(body, line)
+		}
+	}
+	flush()
+
+	if len(messages) == 0 {
+		return nil, ErrNoMessages
+	}
+	return messages, nil
+}
+
+// parseWeChatHeader 识别消息头部行，返回发送者和时间
+func parseWeChatHeader(line string, loc *time.Location) (string, time.Time, bool) {
+	var sender, timestamp string
+	if m := wechatTimeFirst.FindStringSubmatch(line); m != nil {
+		timestamp, sender = m[1], m[2]
+	} else if m := wechatSenderFirst.FindStringSubmatch(line); m != nil {
+		sender, timestamp = m[1], m[2]
+	} else {
+		return "", time.Time{}, false
+	}
+
+	at, err := parseLocalTime(timestamp, wechatTimeLayouts, loc)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return strings.TrimSpace(sender), at, true
+}
+
+// parseLocalTime 按给定格式依次尝试解析不带时区的时间
+func parseLocalTime(value string, layouts []string, loc *time.Location) (time.Time, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("无法解析时间: %s", value)
+}
+
+// looksLikeHTML 判断内容是否为 html
+func looksLikeHTML(text string) bool {
+	head := strings.ToLower(strings.TrimSpace(text))
+	if len(head) > 512 {
+		head = head[:512]
+	}
+	return strings.HasPrefix(head, "<!doctype html") || strings.Contains(head, "<html")
+}
+
+// htmlToText 把 html 转为按行排列的纯文本
+func htmlToText(text string) string {
+	text = htmlStyleTag.ReplaceAllString(text, "")
+	text = htmlBreakTag.ReplaceAllString(text, "\n")
+	text = htmlTag.ReplaceAllString(text, "")
+	lines := strings.Split(html.UnescapeString(text), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.Join(lines, "\n")
+}
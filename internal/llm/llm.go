@@ -2,7 +2,9 @@ package llm
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os/exec"
 	"time"
@@ -12,9 +14,72 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-// Client 大模型客户端
+// Service 大模型服务接口，所有需要调用大模型的模块都依赖该接口
+type Service interface {
+	// Complete 生成补全建议，opts 为空时使用全局配置
+	Complete(context string, input string, opts *CompleteOptions) ([]string, error)
+	// CompleteStructured 以 JSON 模式生成带理由和调性标签的补全建议，解析失败时回退到按行切分文本
+	CompleteStructured(context string, input string, opts *CompleteOptions) ([]models.Suggestion, error)
+	// GenerateSummary 生成对话摘要，返回摘要提示词和关键信息JSON
+	GenerateSummary(messages []models.Message, existingSummary *models.Summary, opts *SummaryOptions) (string, string, error)
+}
+
+// SummaryOptions 摘要生成参数
+type SummaryOptions struct {
+	// 摘要提示词前缀，拼在摘要提示词最前面
+	SystemPrefix string
+	// 摘要格式（bullets/narrative/timeline）及对应的格式指令
+	Style       string
+	Instruction string
+}
+
+// CompleteOptions 补全生成参数，非空字段覆盖全局 APIConfig
+type CompleteOptions struct {
+	Temperature *float64
+	MaxTokens   *int
+	TopP        *float64
+	// 期望的建议数量，大于1时要求模型给出风格各异的多条建议
+	SuggestionCount int
+	// 停止序列
+	Stop []string
+	// 排队优先级（PriorityLow/PriorityNormal/PriorityHigh），并发槽位占满时高优先级先执行
+	Priority int
+}
+
+// Client 大模型客户端（通过Python脚本调用），实现 Service 接口
 type Client struct {
 	config *config.LLMConfig
+	slots  *slotQueue // 带优先级的并发控制，为nil时不限制
+}
+
+var _ Service = (*Client)(nil)
+
+// ErrTimeout 调用大模型超时
+var ErrTimeout = errors.New("调用大模型超时")
+
+// ErrUpstream 大模型或Python脚本调用失败（服务商返回错误、脚本异常退出、响应无法解析等）
+var ErrUpstream = errors.New("大模型调用失败")
+
+// ProtocolVersion Go与Python脚本之间的JSON协议版本，协议有不兼容变更时递增
+const ProtocolVersion = "1"
+
+// envelope 发送给Python脚本的请求信封
+type envelope struct {
+	Version string         `json:"version"`
+	Action  string         `json:"action"`
+	Request interface{}    `json:"request"`
+	Config  envelopeConfig `json:"config"`
+}
+
+// envelopeConfig 随请求传递的大模型配置
+type envelopeConfig struct {
+	ModelType string           `json:"model_type"`
+	API       config.APIConfig `json:"api"`
+}
+
+// validator 响应校验接口
+type validator interface {
+	validate() error
 }
 
 // Request 大模型请求
@@ -31,6 +96,17 @@ type Response struct {
 	Error     string   `json:"error,omitempty"`
 }
 
+// validate 校验补全响应：出错时必须有error，否则必须有suggestions或text
+func (r *Response) validate() error {
+	if r.Error != "" {
+		return nil
+	}
+	if len(r.Suggestions) == 0 && r.Text == "" {
+		return fmt.Errorf("complete 响应缺少 suggestions 或 text")
+	}
+	return nil
+}
+
 // SummaryRequest 摘要生成请求
 type SummaryRequest struct {
 	Messages        []models.Message `json:"messages"`
@@ -45,15 +121,70 @@ type SummaryResponse struct {
 	Error   string                   `json:"error,omitempty"`
 }
 
+// validate 校验摘要响应：出错时必须有error，否则必须有prompt
+func (r *SummaryResponse) validate() error {
+	if r.Error != "" {
+		return nil
+	}
+	if r.Prompt == "" {
+		return fmt.Errorf("generate_summary 响应缺少 prompt")
+	}
+	return nil
+}
+
 // NewClient 创建大模型客户端
 func NewClient(cfg *config.LLMConfig) *Client {
-	return &Client{
+	c := &Client{
 		config: cfg,
 	}
+	if cfg.MaxConcurrency > 0 {
+		c.slots = newSlotQueue(cfg.MaxConcurrency, time.Duration(cfg.PriorityAgingMs)*time.Millisecond)
+	}
+	return c
 }
 
 // Complete 生成补全建议
-func (c *Client) Complete(context string, input string) ([]string, error) {
+func (c *Client) Complete(context string, input string, opts *CompleteOptions) ([]string, error) {
+	resp, err := c.complete(context, input, opts, models.ResponseFormatText)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Suggestions) > 0 {
+		return resp.Suggestions, nil
+	}
+
+	// 如果没有建议，从文本中提取
+	if resp.Text != "" {
+		return []string{resp.Text}, nil
+	}
+
+	return []string{}, nil
+}
+
+// CompleteStructured 以 JSON 模式生成结构化补全建议
+func (c *Client) CompleteStructured(context string, input string, opts *CompleteOptions) ([]models.Suggestion, error) {
+	resp, err := c.complete(context, input, opts, models.ResponseFormatJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions, err := ParseSuggestions(resp.Text)
+	if err != nil {
+		logrus.WithError(err).Warn("解析JSON格式的补全建议失败，回退到文本切分")
+		suggestions = splitSuggestions(resp.Text)
+	}
+	// 兼容未按约定返回 JSON、只给出 suggestions 的脚本
+	if len(suggestions) == 0 {
+		for _, text := range resp.Suggestions {
+			suggestions = append(suggestions, models.Suggestion{Text: text})
+		}
+	}
+	return suggestions, nil
+}
+
+// complete 调用Python脚本生成补全，format 为输出格式
+func (c *Client) complete(context string, input string, opts *CompleteOptions, format string) (*Response, error) {
 	req := Request{
 		Context: context,
 		Input:   input,
@@ -66,30 +197,46 @@ func (c *Client) Complete(context string, input string) ([]string, error) {
 			"presence_penalty":  c.config.API.PresencePenalty,
 		},
 	}
-
-	resp, err := c.callPython("complete", req)
-	if err != nil {
-		return nil, err
+	if opts != nil {
+		if opts.Temperature != nil {
+			req.Parameters["temperature"] = *opts.Temperature
+		}
+		if opts.MaxTokens != nil {
+			req.Parameters["max_tokens"] = *opts.MaxTokens
+		}
+		if opts.TopP != nil {
+			req.Parameters["top_p"] = *opts.TopP
+		}
+		if len(opts.Stop) > 0 {
+			req.Parameters["stop"] = opts.Stop
+		}
+		if opts.SuggestionCount > 0 {
+			req.Parameters["suggestion_count"] = opts.SuggestionCount
+		}
+	}
+	if format == models.ResponseFormatJSON {
+		req.Parameters["response_format"] = format
 	}
 
-	if resp.Error != "" {
-		return nil, fmt.Errorf("大模型返回错误: %s", resp.Error)
+	priority := PriorityNormal
+	if opts != nil {
+		priority = opts.Priority
 	}
 
-	if len(resp.Suggestions) > 0 {
-		return resp.Suggestions, nil
+	var resp Response
+	if err := c.callPython("complete", req, &resp, priority); err != nil {
+		return nil, err
 	}
 
-	// 如果没有建议，从文本中提取
-	if resp.Text != "" {
-		return []string{resp.Text}, nil
+	if resp.Error != "" {
+		return nil, fmt.Errorf("%w: 大模型返回错误: %s", ErrUpstream, resp.Error)
 	}
 
-	return []string{}, nil
+	return &resp, nil
 }
 
 // GenerateSummary 生成对话摘要
-func (c *Client) GenerateSummary(messages []models.Message, existingSummary *models.Summary) (string, string, error) {
+func (c *Client) GenerateSummary(messages []models.Message, existingSummary *models.Summary, opts *SummaryOptions) (string, string, error) {
 	req := SummaryRequest{
 		Messages:        messages,
 		ExistingSummary: existingSummary,
@@ -98,14 +245,22 @@ func (c *Client) GenerateSummary(messages []models.Message, existingSummary *mod
 			"key_info_count":     10,
 		},
 	}
+	if opts != nil && opts.SystemPrefix != "" {
+		req.Config["system_prefix"] = opts.SystemPrefix
+	}
+	if opts != nil && opts.Instruction != "" {
+		req.Config["style"] = opts.Style
+		req.Config["format_instruction"] = opts.Instruction
+	}
 
-	resp, err := c.callPythonForSummary(req)
-	if err != nil {
+	var resp SummaryResponse
+	// 摘要在后台生成，排在补全之后
+	if err := c.callPython("generate_summary", req, &resp, PriorityLow); err != nil {
 		return "", "", err
 	}
 
 	if resp.Error != "" {
-		return "", "", fmt.Errorf("大模型返回错误: %s", resp.Error)
+		return "", "", fmt.Errorf("%w: 大模型返回错误: %s", ErrUpstream, resp.Error)
 	}
 
 	// 序列化关键信息
@@ -122,77 +277,41 @@ func (c *Client) GenerateSummary(messages []models.Message, existingSummary *mod
 	return resp.Prompt, keyInfoJSON, nil
 }
 
-// callPython 调用Python脚本
-func (c *Client) callPython(action string, req interface{}) (*Response, error) {
-	reqJSON, err := json.Marshal(map[string]interface{}{
-		"action": action,
-		"request": req,
-		"config": map[string]interface{}{
-			"model_type": c.config.ModelType,
-			"api":        c.config.API,
+// callPython 调用Python脚本，所有动作共用同一套进程管理（并发控制、超时、日志），priority 为排队优先级
+func (c *Client) callPython(action string, req interface{}, resp interface{}, priority int) error {
+	reqJSON, err := json.Marshal(envelope{
+		Version: ProtocolVersion,
+		Action:  action,
+		Request: req,
+		Config: envelopeConfig{
+			ModelType: c.config.ModelType,
+			API:       c.config.API,
 		},
 	})
 	if err != nil {
-		return nil, fmt.Errorf("序列化请求失败: %w", err)
+		return fmt.Errorf("序列化请求失败: %w", err)
 	}
 
-	logrus.WithField("request_json", string(reqJSON)).Debug("传递给 Python 的配置")
-
-	// 执行Python脚本
-	cmd := exec.Command(c.config.PythonInterpreter, c.config.PythonScript)
-	cmd.Stdin = bytes.NewReader(reqJSON)
-	
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	logrus.WithFields(logrus.Fields{
+		"action":       action,
+		"request_json": string(reqJSON),
+	}).Debug("传递给 Python 的配置")
 
-	// 设置超时
-	done := make(chan error, 1)
-	go func() {
-		done <- cmd.Run()
-	}()
-
-	select {
-	case err := <-done:
-		// 总是输出 stderr（包含调试信息）
-		if stderrStr := stderr.String(); stderrStr != "" {
-			logrus.WithField("python_stderr", stderrStr).Debug("Python 脚本输出")
-		}
+	// 并发控制：排队时间同样以 timeout 为上限，满载时请求不会无限期等待
+	if c.slots != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(c.config.Timeout)*time.Second)
+		err := c.slots.acquire(ctx, priority)
+		cancel()
 		if err != nil {
-			return nil, fmt.Errorf("执行Python脚本失败: %w, stderr: %s", err, stderr.String())
+			return fmt.Errorf("%w：排队等待超过 %d 秒", ErrTimeout, c.config.Timeout)
 		}
-	case <-time.After(time.Duration(c.config.Timeout) * time.Second):
-		cmd.Process.Kill()
-		return nil, fmt.Errorf("调用大模型超时（%d秒）", c.config.Timeout)
-	}
-
-	// 解析响应
-	var resp Response
-	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
-		return nil, fmt.Errorf("解析响应失败: %w, stdout: %s", err, stdout.String())
-	}
-
-	return &resp, nil
-}
-
-// callPythonForSummary 调用Python脚本生成摘要
-func (c *Client) callPythonForSummary(req SummaryRequest) (*SummaryResponse, error) {
-	reqJSON, err := json.Marshal(map[string]interface{}{
-		"action": "generate_summary",
-		"request": req,
-		"config": map[string]interface{}{
-			"model_type": c.config.ModelType,
-			"api":        c.config.API,
-		},
-	})
-	if err != nil {
-		return nil, fmt.Errorf("序列化请求失败: %w", err)
+		defer c.slots.release()
 	}
 
 	// 执行Python脚本
 	cmd := exec.Command(c.config.PythonInterpreter, c.config.PythonScript)
 	cmd.Stdin = bytes.NewReader(reqJSON)
-	
+
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
@@ -210,19 +329,24 @@ func (c *Client) callPythonForSummary(req SummaryRequest) (*SummaryResponse, err
 			logrus.WithField("python_stderr", stderrStr).Debug("Python 脚本输出")
 		}
 		if err != nil {
-			return nil, fmt.Errorf("执行Python脚本失败: %w, stderr: %s", err, stderr.String())
+			return fmt.Errorf("%w: 执行Python脚本失败: %w, stderr: %s", ErrUpstream, err, stderr.String())
 		}
 	case <-time.After(time.Duration(c.config.Timeout) * time.Second):
 		cmd.Process.Kill()
-		return nil, fmt.Errorf("调用大模型超时（%d秒）", c.config.Timeout)
+		return fmt.Errorf("%w（%d秒）", ErrTimeout, c.config.Timeout)
 	}
 
 	// 解析响应
-	var resp SummaryResponse
-	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
-		return nil, fmt.Errorf("解析响应失败: %w, stdout: %s", err, stdout.String())
+	if err := json.Unmarshal(stdout.Bytes(), resp); err != nil {
+		return fmt.Errorf("%w: 解析响应失败: %w, stdout: %s", ErrUpstream, err, stdout.String())
 	}
 
-	return &resp, nil
-}
+	// 校验协议必填字段
+	if v, ok := resp.(validator); ok {
+		if err := v.validate(); err != nil {
+			return fmt.Errorf("%w: 响应不符合协议（版本%s）: %w, stdout: %s", ErrUpstream, ProtocolVersion, err, stdout.String())
+		}
+	}
 
+	return nil
+}
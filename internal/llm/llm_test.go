@@ -0,0 +1,136 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ChatRecommend/internal/config"
+)
+
+func TestResponseValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		resp    Response
+		wantErr bool
+	}{
+		{"只有 suggestions", Response{Suggestions: []string{"好的"}}, false},
+		{"只有 text", Response{Text: "好的"}, false},
+		{"出错时不要求内容", Response{Error: "rate limited"}, false},
+		{"缺少内容", Response{}, true},
+		{"空 suggestions", Response{Suggestions: []string{}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.resp.validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("validate 返回 %v，期望出错 %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSummaryResponseValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		resp    SummaryResponse
+		wantErr bool
+	}{
+		{"有 prompt", SummaryResponse{Prompt: "两人约了周六吃饭"}, false},
+		{"出错时不要求 prompt", SummaryResponse{Error: "timeout"}, false},
+		{"只有 key_info", SummaryResponse{KeyInfo: []map[string]interface{}{{"type": "time"}}}, true},
+		{"缺少 prompt", SummaryResponse{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.resp.validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("validate 返回 %v，期望出错 %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// stubClient 用 shell 脚本代替 Python 脚本：把收到的请求写入 request.json，输出固定的响应
+func stubClient(t *testing.T, response string) (*Client, string) {
+	t.Helper()
+	dir := t.TempDir()
+	requestPath := filepath.Join(dir, "request.json")
+	responsePath := filepath.Join(dir, "response.json")
+	if err := os.WriteFile(responsePath, []byte(response), 0o644); err != nil {
+		t.Fatalf("写入响应失败: %v", err)
+	}
+	script := filepath.Join(dir, "stub.sh")
+	body := "cat > '" + requestPath + "'\ncat '" + responsePath + "'\n"
+	if err := os.WriteFile(script, []byte(body), 0o644); err != nil {
+		t.Fatalf("写入脚本失败: %v", err)
+	}
+	return NewClient(&config.LLMConfig{PythonInterpreter: "sh", PythonScript: script, ModelType: "openai", Timeout: 5}), requestPath
+}
+
+// 请求信封带协议版本和动作
+func TestCallPythonEnvelope(t *testing.T) {
+	client, requestPath := stubClient(t, `{"suggestions": ["好的", "没问题"]}`)
+	suggestions, err := client.Complete("上下文", "好", nil)
+	if err != nil {
+		t.Fatalf("补全失败: %v", err)
+	}
+	if len(suggestions) != 2 || suggestions[0] != "好的" {
+		t.Fatalf("建议为 %v", suggestions)
+	}
+
+	data, err := os.ReadFile(requestPath)
+	if err != nil {
+		t.Fatalf("读取请求失败: %v", err)
+	}
+	var env struct {
+		Version string `json:"version"`
+		Action  string `json:"action"`
+		Request struct {
+			Input string `json:"input"`
+		} `json:"request"`
+		Config struct {
+			ModelType string `json:"model_type"`
+		} `json:"config"`
+	}
+	if err := json.Unmarshal(data, &env); err != nil {
+		t.Fatalf("请求不是合法的 JSON: %v，请求: %s", err, data)
+	}
+	if env.Version != ProtocolVersion || env.Action != "complete" || env.Request.Input != "好" || env.Config.ModelType != "openai" {
+		t.Fatalf("请求信封为 %s", data)
+	}
+}
+
+// 缺少必填字段或脚本报告协议版本不兼容时返回 ErrUpstream，错误信息说明原因
+func TestCallPythonRejectsInvalidResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		summary  bool
+		want     string
+	}{
+		{"补全缺少 suggestions 和 text", `{}`, false, "complete 响应缺少 suggestions 或 text"},
+		{"摘要缺少 prompt", `{"key_info": []}`, true, "generate_summary 响应缺少 prompt"},
+		{"无法解析", `not json`, false, "解析响应失败"},
+		{"协议版本不兼容", `{"error": "不支持的协议版本: 1，脚本支持的版本: 2"}`, false, "不支持的协议版本: 1"},
+		{"摘要协议版本不兼容", `{"error": "不支持的协议版本: 1，脚本支持的版本: 2"}`, true, "不支持的协议版本: 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := stubClient(t, tt.response)
+			var err error
+			if tt.summary {
+				_, _, err = client.GenerateSummary(nil, nil, nil)
+			} else {
+				_, err = client.Complete("上下文", "好", nil)
+			}
+			if !errors.Is(err, ErrUpstream) {
+				t.Fatalf("返回 %v，期望 ErrUpstream", err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("错误 %q 应包含 %q", err.Error(), tt.want)
+			}
+		})
+	}
+}
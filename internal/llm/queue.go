@@ -0,0 +1,114 @@
+package llm
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// 调用大模型的优先级，槽位被占满时优先级高的请求先拿到空出的槽位，同优先级按到达顺序
+const (
+	PriorityLow    = -1
+	PriorityNormal = 0
+	PriorityHigh   = 1
+)
+
+// defaultPriorityAging 未配置时排队请求的优先级每等待多久提升一级
+const defaultPriorityAging = 2 * time.Second
+
+// slotQueue 带优先级等待队列的并发槽位：最多 capacity 个调用同时运行，其余按优先级排队。
+// 排队的请求每等待 aging 优先级提升一级（老化），持续满载时低优先级请求（摘要、预取）也不会一直等待
+type slotQueue struct {
+	mu       sync.Mutex
+	capacity int
+	aging    time.Duration
+	inUse    int
+	seq      uint64
+	waiters  []*waiter
+	// now 当前时间，测试中可替换
+	now func() time.Time
+}
+
+// waiter 排队中的请求，拿到槽位时 ready 被关闭
+type waiter struct {
+	priority int
+	seq      uint64
+	queuedAt time.Time
+	ready    chan struct{}
+}
+
+// newSlotQueue 创建并发槽位，capacity 必须大于0，aging<=0 时使用默认值
+func newSlotQueue(capacity int, aging time.Duration) *slotQueue {
+	if aging <= 0 {
+		aging = defaultPriorityAging
+	}
+	return &slotQueue{capacity: capacity, aging: aging, now: time.Now}
+}
+
+// acquire 获取一个槽位，没有空闲槽位时按优先级排队等待；ctx 结束时退出队列并返回 ctx.Err()
+func (q *slotQueue) acquire(ctx context.Context, priority int) error {
+	q.mu.Lock()
+	if q.inUse < q.capacity && len(q.waiters) == 0 {
+		q.inUse++
+		q.mu.Unlock()
+		return nil
+	}
+	w := &waiter{priority: priority, seq: q.seq, queuedAt: q.now(), ready: make(chan struct{})}
+	q.seq++
+	q.waiters = append(q.waiters, w)
+	q.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	q.mu.Lock()
+	for i, queued := range q.waiters {
+		if queued == w {
+			q.waiters = append(q.waiters[:i], q.waiters[i+1:]...)
+			q.mu.Unlock()
+			return ctx.Err()
+		}
+	}
+	q.mu.Unlock()
+	// 取消的同时已被分到槽位：转交给下一个排队的请求
+	q.release()
+	return ctx.Err()
+}
+
+// release 释放槽位：有请求在排队时直接交给当前优先级（含老化提升）最高的请求，否则归还
+func (q *slotQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.waiters) == 0 {
+		q.inUse--
+		return
+	}
+
+	now := q.now()
+	best := 0
+	for i := 1; i < len(q.waiters); i++ {
+		if q.before(q.waiters[i], q.waiters[best], now) {
+			best = i
+		}
+	}
+	w := q.waiters[best]
+	q.waiters = append(q.waiters[:best], q.waiters[best+1:]...)
+	close(w.ready)
+}
+
+// effectivePriority 排队请求的当前优先级：原优先级加上已等待的老化级数
+func (q *slotQueue) effectivePriority(w *waiter, now time.Time) int {
+	return w.priority + int(now.Sub(w.queuedAt)/q.aging)
+}
+
+// before 请求 a 是否应先于 b 拿到槽位：当前优先级高的在前，相同时先到的在前
+func (q *slotQueue) before(a, b *waiter, now time.Time) bool {
+	pa, pb := q.effectivePriority(a, now), q.effectivePriority(b, now)
+	if pa != pb {
+		return pa > pb
+	}
+	return a.seq < b.seq
+}
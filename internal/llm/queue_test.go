@@ -0,0 +1,180 @@
+package llm
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock 可手动推进的时钟
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// enqueue 依次让各优先级的请求进入等待队列，返回按拿到槽位先后记录优先级的通道
+func enqueue(t *testing.T, q *slotQueue, priorities ...int) <-chan int {
+	t.Helper()
+	order := make(chan int, len(priorities))
+	for i, priority := range priorities {
+		go func(priority int) {
+			q.acquire(context.Background(), priority)
+			order <- priority
+		}(priority)
+		waitQueued(t, q, i+1)
+	}
+	return order
+}
+
+func waitQueued(t *testing.T, q *slotQueue, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		q.mu.Lock()
+		queued := len(q.waiters)
+		q.mu.Unlock()
+		if queued >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("等待 %d 个请求进入队列超时", n)
+}
+
+// drain 逐个释放槽位，返回各请求拿到槽位的顺序
+func drain(t *testing.T, q *slotQueue, order <-chan int, n int) []int {
+	t.Helper()
+	got := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		q.release()
+		select {
+		case priority := <-order:
+			got = append(got, priority)
+		case <-time.After(time.Second):
+			t.Fatalf("等待请求拿到槽位超时")
+		}
+	}
+	return got
+}
+
+func assertOrder(t *testing.T, got []int, want ...int) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("拿到槽位的顺序为 %v，期望 %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("拿到槽位的顺序为 %v，期望 %v", got, want)
+		}
+	}
+}
+
+func TestSlotQueueHighPriorityFirst(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	q := newSlotQueue(1, time.Second)
+	q.now = clock.Now
+	q.acquire(context.Background(), PriorityNormal)
+
+	order := enqueue(t, q, PriorityLow, PriorityNormal, PriorityHigh, PriorityNormal)
+	got := drain(t, q, order, 4)
+	assertOrder(t, got, PriorityHigh, PriorityNormal, PriorityNormal, PriorityLow)
+}
+
+// 等待足够久的低优先级请求会老化提升，排在新到的高优先级请求之前
+func TestSlotQueueAgingPreventsStarvation(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	q := newSlotQueue(1, time.Second)
+	q.now = clock.Now
+	q.acquire(context.Background(), PriorityNormal)
+
+	order := enqueue(t, q, PriorityLow)
+	clock.Advance(2 * time.Second)
+	order2 := enqueue(t, q, PriorityHigh)
+
+	q.release()
+	select {
+	case priority := <-order:
+		if priority != PriorityLow {
+			t.Fatalf("拿到槽位的优先级为 %d", priority)
+		}
+	case <-order2:
+		t.Fatalf("等待了两个老化间隔的低优先级请求应先于新到的高优先级请求")
+	case <-time.After(time.Second):
+		t.Fatalf("等待请求拿到槽位超时")
+	}
+	drain(t, q, order2, 1)
+}
+
+// 取消的请求退出队列，之后的槽位交给其余排队的请求
+func TestSlotQueueAcquireCancel(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	q := newSlotQueue(1, time.Second)
+	q.now = clock.Now
+	q.acquire(context.Background(), PriorityNormal)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := make(chan error, 1)
+	go func() {
+		cancelled <- q.acquire(ctx, PriorityHigh)
+	}()
+	waitQueued(t, q, 1)
+	order := enqueue(t, q, PriorityLow)
+
+	cancel()
+	select {
+	case err := <-cancelled:
+		if err != context.Canceled {
+			t.Fatalf("取消后 acquire 返回 %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("取消后 acquire 未返回")
+	}
+	waitQueued(t, q, 1)
+	q.mu.Lock()
+	queued := len(q.waiters)
+	q.mu.Unlock()
+	if queued != 1 {
+		t.Fatalf("取消后队列中还有 %d 个请求，期望 1", queued)
+	}
+
+	got := drain(t, q, order, 1)
+	assertOrder(t, got, PriorityLow)
+	q.release()
+	if q.inUse != 0 {
+		t.Fatalf("全部释放后占用 %d 个槽位", q.inUse)
+	}
+}
+
+// 老化后按当前优先级排序，当前优先级相同时按到达顺序
+func TestSlotQueueAgingOrder(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	q := newSlotQueue(1, time.Second)
+	q.now = clock.Now
+	q.acquire(context.Background(), PriorityNormal)
+
+	order := make(chan int, 3)
+	for i, priority := range []int{PriorityLow, PriorityNormal, PriorityHigh} {
+		go func(priority int) {
+			q.acquire(context.Background(), priority)
+			order <- priority
+		}(priority)
+		waitQueued(t, q, i+1)
+		clock.Advance(time.Second)
+	}
+	// 此时 Low 等待3秒（当前优先级2），Normal 2秒（2），High 1秒（2），当前优先级相同按到达顺序
+	got := drain(t, q, order, 3)
+	assertOrder(t, got, PriorityLow, PriorityNormal, PriorityHigh)
+}
@@ -0,0 +1,78 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"ChatRecommend/internal/models"
+)
+
+// listPrefixPattern 行首的列表符号和编号（如 "-"、"1."、"(1)"）
+var listPrefixPattern = regexp.MustCompile(`^(?:[-*•]\s*)?(?:[(（]?\d+[.、)）:：]\s*)?`)
+
+// structuredResponse JSON 模式下约定的返回结构
+type structuredResponse struct {
+	Suggestions []models.Suggestion `json:"suggestions"`
+}
+
+// ParseSuggestions 解析 JSON 模式的返回文本，支持 {"suggestions": [...]} 和直接返回数组两种形式，
+// 兼容模型用 ```json 代码块包裹输出的情况
+func ParseSuggestions(text string) ([]models.Suggestion, error) {
+	text = stripCodeFence(text)
+	if text == "" {
+		return nil, fmt.Errorf("返回内容为空")
+	}
+
+	var suggestions []models.Suggestion
+	if strings.HasPrefix(text, "[") {
+		if err := json.Unmarshal([]byte(text), &suggestions); err != nil {
+			return nil, fmt.Errorf("解析建议数组失败: %w", err)
+		}
+	} else {
+		var resp structuredResponse
+		if err := json.Unmarshal([]byte(text), &resp); err != nil {
+			return nil, fmt.Errorf("解析建议对象失败: %w", err)
+		}
+		suggestions = resp.Suggestions
+	}
+
+	result := make([]models.Suggestion, 0, len(suggestions))
+	for _, s := range suggestions {
+		s.Text = strings.TrimSpace(s.Text)
+		if s.Text == "" {
+			continue
+		}
+		result = append(result, s)
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("没有有效的建议")
+	}
+	return result, nil
+}
+
+// stripCodeFence 去掉首尾空白和 markdown 代码块标记
+func stripCodeFence(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") {
+		return text
+	}
+	text = strings.TrimPrefix(text, "```")
+	if i := strings.Index(text, "\n"); i >= 0 {
+		text = text[i+1:]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "```"))
+}
+
+// splitSuggestions 把无法解析为 JSON 的文本按行切分为建议（去掉编号和空行）
+func splitSuggestions(text string) []models.Suggestion {
+	var suggestions []models.Suggestion
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(listPrefixPattern.ReplaceAllString(strings.TrimSpace(line), ""))
+		if line != "" {
+			suggestions = append(suggestions, models.Suggestion{Text: line})
+		}
+	}
+	return suggestions
+}
@@ -0,0 +1,69 @@
+package llm
+
+import (
+	"testing"
+
+	"ChatRecommend/internal/models"
+)
+
+func TestParseSuggestions(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []models.Suggestion
+	}{
+		{
+			name: "对象形式",
+			text: `{"suggestions": [{"text": "好的", "reason": "同意", "tone": "友好"}, {"text": " 再说吧 "}]}`,
+			want: []models.Suggestion{{Text: "好的", Reason: "同意", Tone: "友好"}, {Text: "再说吧"}},
+		},
+		{
+			name: "数组形式",
+			text: `[{"text": "没问题", "tone": "轻松"}]`,
+			want: []models.Suggestion{{Text: "没问题", Tone: "轻松"}},
+		},
+		{
+			name: "代码块包裹并跳过空建议",
+			text: "```json\n{\"suggestions\": [{\"text\": \"\"}, {\"text\": \"收到\"}]}\n```",
+			want: []models.Suggestion{{Text: "收到"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSuggestions(tt.text)
+			if err != nil {
+				t.Fatalf("解析失败: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("结果为 %+v，期望 %+v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Fatalf("结果为 %+v，期望 %+v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseSuggestionsInvalid(t *testing.T) {
+	for _, text := range []string{"", "好的\n再说吧", `{"suggestions": []}`, `[{"text": "  "}]`} {
+		if _, err := ParseSuggestions(text); err == nil {
+			t.Errorf("%q 应解析失败", text)
+		}
+	}
+}
+
+// 解析失败时回退到按行切分，去掉编号和空行
+func TestSplitSuggestionsFallback(t *testing.T) {
+	got := splitSuggestions("1. 好的\n\n- 没问题\n（3）再说吧")
+	want := []string{"好的", "没问题", "再说吧"}
+	if len(got) != len(want) {
+		t.Fatalf("结果为 %+v，期望 %v", got, want)
+	}
+	for i := range want {
+		if got[i].Text != want[i] {
+			t.Fatalf("结果为 %+v，期望 %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Migration 一次数据库结构变更。ID 一经发布不可修改，按在列表中的顺序执行
+type Migration struct {
+	ID       string
+	Migrate  func(tx *gorm.DB) error
+	Rollback func(tx *gorm.DB) error
+}
+
+// SchemaMigration 已执行的迁移记录
+type SchemaMigration struct {
+	ID        string    `gorm:"primarykey"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// TableName 迁移记录表名
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// ErrNoMigration 没有可回滚的迁移
+var ErrNoMigration = errors.New("没有已执行的迁移")
+
+// Migrator 版本化迁移执行器
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// New 创建迁移执行器
+func New(db *gorm.DB, migrations []Migration) *Migrator {
+	return &Migrator{db: db, migrations: migrations}
+}
+
+// Run 按顺序执行全部未执行的迁移
+func Run(db *gorm.DB) error {
+	return New(db, All()).Migrate()
+}
+
+// Migrate 按顺序执行未执行的迁移，每个迁移及其记录在同一个事务中提交，失败时停止并保留已成功的迁移
+func (m *Migrator) Migrate() error {
+	applied, err := m.applied()
+	if err != nil {
+		return err
+	}
+
+	known := make(map[string]bool, len(m.migrations))
+	for _, migration := range m.migrations {
+		if known[migration.ID] {
+			return fmt.Errorf("迁移ID重复: %s", migration.ID)
+		}
+		known[migration.ID] = true
+	}
+	for id := range applied {
+		if !known[id] {
+			logrus.WithField("migration", id).Warn("数据库中存在未知的迁移记录，可能由更新版本的程序执行")
+		}
+	}
+
+	for _, migration := range m.migrations {
+		if applied[migration.ID] {
+			continue
+		}
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Migrate(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{ID: migration.ID, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("执行迁移 %s 失败: %w", migration.ID, err)
+		}
+		logrus.WithField("migration", migration.ID).Info("已执行数据库迁移")
+	}
+	return nil
+}
+
+// RollbackLast 回滚最近执行的一个迁移，返回被回滚的迁移ID
+func (m *Migrator) RollbackLast() (string, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return "", err
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if !applied[migration.ID] {
+			continue
+		}
+		if migration.Rollback == nil {
+			return "", fmt.Errorf("迁移 %s 不支持回滚", migration.ID)
+		}
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Rollback(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaMigration{ID: migration.ID}).Error
+		})
+		if err != nil {
+			return "", fmt.Errorf("回滚迁移 %s 失败: %w", migration.ID, err)
+		}
+		logrus.WithField("migration", migration.ID).Info("已回滚数据库迁移")
+		return migration.ID, nil
+	}
+	return "", ErrNoMigration
+}
+
+// applied 查询已执行的迁移，迁移记录表不存在时先创建
+func (m *Migrator) applied() (map[string]bool, error) {
+	if err := m.db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return nil, fmt.Errorf("创建迁移记录表失败: %w", err)
+	}
+
+	var ids []string
+	if err := m.db.Model(&SchemaMigration{}).Pluck("id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("查询迁移记录失败: %w", err)
+	}
+	applied := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		applied[id] = true
+	}
+	return applied, nil
+}
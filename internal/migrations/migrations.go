@@ -0,0 +1,204 @@
+package migrations
+
+import (
+	"fmt"
+	"time"
+
+	"ChatRecommend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// All 全部迁移，按执行顺序排列。修改表结构时在末尾追加新的迁移，不要修改已发布的迁移。
+// 迁移只使用 schema.go 中冻结的表结构，不引用 models 中的现行模型
+func All() []Migration {
+	return []Migration{
+		{
+			// 初始表结构。引入版本化迁移之前由 AutoMigrate 创建的老库执行时会补齐缺少的列和索引
+			ID: "20261017_initial",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(initialTables()...)
+			},
+			Rollback: func(tx *gorm.DB) error {
+				tables := initialTables()
+				for i := len(tables) - 1; i >= 0; i-- {
+					if err := tx.Migrator().DropTable(tables[i]); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			// 每日大模型调用计数
+			ID: "20261017_llm_usage",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.Migrator().CreateTable(&llmUsageV1{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&llmUsageV1{})
+			},
+		},
+		{
+			// 用户级风格画像
+			ID: "20261017_user_styles",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.Migrator().CreateTable(&userStyleV1{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&userStyleV1{})
+			},
+		},
+		{
+			// 对话只读标记
+			ID: "20261017_conversation_read_only",
+			Migrate: func(tx *gorm.DB) error {
+				return addColumns(tx, &conversationReadOnly{}, "ReadOnly")
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return dropColumns(tx, "conversations", "read_only")
+			},
+		},
+		{
+			// 消息实体索引
+			ID: "20261017_message_entities",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.Migrator().CreateTable(&messageEntityV1{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&messageEntityV1{})
+			},
+		},
+		{
+			// 摘要格式
+			ID: "20261017_summary_style",
+			Migrate: func(tx *gorm.DB) error {
+				return addColumns(tx, &summaryStyle{}, "Style")
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return dropColumns(tx, "summaries", "style")
+			},
+		},
+		{
+			// 补全历史
+			ID: "20261017_completion_logs",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.Migrator().CreateTable(&completionLogV1{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&completionLogV1{})
+			},
+		},
+		{
+			// 对话自定义字段
+			ID: "20261017_conversation_metadata",
+			Migrate: func(tx *gorm.DB) error {
+				return addColumns(tx, &conversationMetadata{}, "Metadata")
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return dropColumns(tx, "conversations", "metadata")
+			},
+		},
+		{
+			// 消息置顶
+			ID: "20261017_message_pinned",
+			Migrate: func(tx *gorm.DB) error {
+				if err := addColumns(tx, &messagePinned{}, "Pinned", "PinnedAt"); err != nil {
+					return err
+				}
+				if tx.Migrator().HasIndex(&messagePinned{}, "Pinned") {
+					return nil
+				}
+				return tx.Migrator().CreateIndex(&messagePinned{}, "Pinned")
+			},
+			Rollback: func(tx *gorm.DB) error {
+				if err := tx.Migrator().DropIndex(&messagePinned{}, "Pinned"); err != nil {
+					return err
+				}
+				return dropColumns(tx, "messages", "pinned_at", "pinned")
+			},
+		},
+		{
+			// 在线更新的提示词模板
+			ID: "20261017_prompt_templates",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.Migrator().CreateTable(&promptTemplateV1{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&promptTemplateV1{})
+			},
+		},
+		{
+			// 消息内容格式
+			ID: "20261017_message_content_format",
+			Migrate: func(tx *gorm.DB) error {
+				return addColumns(tx, &messageContentFormat{}, "ContentFormat")
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return dropColumns(tx, "messages", "content_format")
+			},
+		},
+		{
+			// 按现有消息重建对话统计：统计表引入前保存的消息没有计入
+			ID: "20261017_backfill_conversation_stats",
+			Migrate: func(tx *gorm.DB) error {
+				return backfillStats(tx)
+			},
+			Rollback: func(tx *gorm.DB) error {
+				// 重建的统计与增量维护的结果一致，回滚不需要处理
+				return nil
+			},
+		},
+	}
+}
+
+// addColumns 为表添加列。早期版本的初始迁移按现行模型建表，可能已经创建了这些列，已存在的列跳过
+func addColumns(tx *gorm.DB, model interface{}, fields ...string) error {
+	for _, field := range fields {
+		if tx.Migrator().HasColumn(model, field) {
+			continue
+		}
+		if err := tx.Migrator().AddColumn(model, field); err != nil {
+			return fmt.Errorf("添加列 %s 失败: %w", field, err)
+		}
+	}
+	return nil
+}
+
+// dropColumns 删除表中的列，不存在的列跳过。直接执行 ALTER TABLE DROP COLUMN：
+// gorm 的 sqlite Migrator().DropColumn 通过重建表实现，会丢失表上的其他索引
+func dropColumns(tx *gorm.DB, table string, columns ...string) error {
+	for _, column := range columns {
+		if !tx.Migrator().HasColumn(table, column) {
+			continue
+		}
+		if err := tx.Exec("ALTER TABLE ? DROP COLUMN ?", clause.Table{Name: table}, clause.Column{Name: column}).Error; err != nil {
+			return fmt.Errorf("删除列 %s.%s 失败: %w", table, column, err)
+		}
+	}
+	return nil
+}
+
+// backfillStats 清空并按现有消息（不含草稿和已删除的消息）重建全部对话统计和发送者统计
+func backfillStats(tx *gorm.DB) error {
+	for _, model := range []interface{}{&models.ConversationSenderStat{}, &models.ConversationStat{}} {
+		if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(model).Error; err != nil {
+			return fmt.Errorf("清空对话统计失败: %w", err)
+		}
+	}
+
+	now := time.Now()
+	if err := tx.Exec(`INSERT INTO conversation_stats (conversation_id, message_count, last_active_at, updated_at)
+		SELECT conversation_id, COUNT(*), MAX(created_at), ? FROM messages
+		WHERE deleted_at IS NULL AND message_type <> ?
+		GROUP BY conversation_id`, now, models.MessageTypeDraft).Error; err != nil {
+		return fmt.Errorf("重建对话统计失败: %w", err)
+	}
+	if err := tx.Exec(`INSERT INTO conversation_sender_stats (conversation_id, sender_id, message_count, last_active_at, updated_at)
+		SELECT conversation_id, sender_id, COUNT(*), MAX(created_at), ? FROM messages
+		WHERE deleted_at IS NULL AND message_type <> ?
+		GROUP BY conversation_id, sender_id`, now, models.MessageTypeDraft).Error; err != nil {
+		return fmt.Errorf("重建发送者统计失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"ChatRecommend/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var testDBSeq atomic.Int64
+
+// openTestDB 打开独立的空内存数据库
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:migrations_test_%d?mode=memory&cache=shared", testDBSeq.Add(1))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("打开内存数据库失败: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// schemaOf 数据库表结构摘要：每个表和索引一行（表附带排序后的列名），不含迁移记录表
+func schemaOf(t *testing.T, db *gorm.DB) []string {
+	t.Helper()
+	var objects []struct {
+		Type    string
+		Name    string
+		TblName string
+	}
+	if err := db.Raw(`SELECT type, name, tbl_name FROM sqlite_master
+		WHERE type IN ('table', 'index') AND name NOT LIKE 'sqlite_%' AND tbl_name <> 'schema_migrations'`).Scan(&objects).Error; err != nil {
+		t.Fatalf("查询表结构失败: %v", err)
+	}
+
+	var schema []string
+	for _, object := range objects {
+		if object.Type == "index" {
+			schema = append(schema, "index "+object.TblName+"."+object.Name)
+			continue
+		}
+		columns, err := db.Migrator().ColumnTypes(object.Name)
+		if err != nil {
+			t.Fatalf("查询 %s 的列失败: %v", object.Name, err)
+		}
+		names := make([]string, len(columns))
+		for i, column := range columns {
+			names[i] = column.Name()
+		}
+		slices.Sort(names)
+		schema = append(schema, "table "+object.Name+"("+strings.Join(names, ",")+")")
+	}
+	slices.Sort(schema)
+	return schema
+}
+
+// 从空库执行全部迁移得到的表结构与现行模型一致：新增字段必须配套新的迁移
+func TestMigrateMatchesModels(t *testing.T) {
+	migrated := openTestDB(t)
+	if err := Run(migrated); err != nil {
+		t.Fatalf("执行迁移失败: %v", err)
+	}
+	expected := openTestDB(t)
+	if err := expected.AutoMigrate(models.All()...); err != nil {
+		t.Fatalf("建表失败: %v", err)
+	}
+
+	if got, want := schemaOf(t, migrated), schemaOf(t, expected); !slices.Equal(got, want) {
+		t.Fatalf("迁移后的表结构与模型不一致\n迁移: %v\n模型: %v", got, want)
+	}
+}
+
+// 初始迁移使用冻结的表结构，不会提前创建后续迁移负责的表和列
+func TestInitialMigrationIsFrozen(t *testing.T) {
+	db := openTestDB(t)
+	if err := New(db, All()[:1]).Migrate(); err != nil {
+		t.Fatalf("执行初始迁移失败: %v", err)
+	}
+
+	for _, table := range []string{"llm_usages", "user_styles", "message_entities", "completion_logs", "prompt_templates"} {
+		if db.Migrator().HasTable(table) {
+			t.Errorf("初始迁移不应创建表 %s", table)
+		}
+	}
+	for _, column := range []struct{ table, name string }{
+		{"conversations", "read_only"},
+		{"conversations", "metadata"},
+		{"summaries", "style"},
+		{"messages", "pinned"},
+		{"messages", "content_format"},
+	} {
+		if db.Migrator().HasColumn(column.table, column.name) {
+			t.Errorf("初始迁移不应创建列 %s.%s", column.table, column.name)
+		}
+	}
+}
+
+// 逐个回滚迁移：每次回滚后的表结构与只执行之前迁移的结果一致，全部回滚后可以重新执行
+func TestRollbackEachMigration(t *testing.T) {
+	all := All()
+	db := openTestDB(t)
+	migrator := New(db, all)
+	if err := migrator.Migrate(); err != nil {
+		t.Fatalf("执行迁移失败: %v", err)
+	}
+
+	for i := len(all) - 1; i >= 0; i-- {
+		id, err := migrator.RollbackLast()
+		if err != nil {
+			t.Fatalf("回滚失败: %v", err)
+		}
+		if id != all[i].ID {
+			t.Fatalf("回滚了 %s，期望 %s", id, all[i].ID)
+		}
+
+		expected := openTestDB(t)
+		if err := New(expected, all[:i]).Migrate(); err != nil {
+			t.Fatalf("执行前 %d 个迁移失败: %v", i, err)
+		}
+		if got, want := schemaOf(t, db), schemaOf(t, expected); !slices.Equal(got, want) {
+			t.Fatalf("回滚 %s 后表结构不一致\n回滚: %v\n期望: %v", id, got, want)
+		}
+	}
+
+	if _, err := migrator.RollbackLast(); !errors.Is(err, ErrNoMigration) {
+		t.Fatalf("全部回滚后再回滚返回 %v，期望 ErrNoMigration", err)
+	}
+	if err := migrator.Migrate(); err != nil {
+		t.Fatalf("全部回滚后重新执行迁移失败: %v", err)
+	}
+}
+
+// 由早期版本（初始迁移按现行模型建表）执行过初始迁移的库，后续迁移跳过已存在的列
+func TestMigrateSkipsExistingColumns(t *testing.T) {
+	db := openTestDB(t)
+	if err := db.AutoMigrate(models.All()...); err != nil {
+		t.Fatalf("建表失败: %v", err)
+	}
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		t.Fatalf("创建迁移记录表失败: %v", err)
+	}
+	if err := db.Create(&SchemaMigration{ID: "20261017_initial"}).Error; err != nil {
+		t.Fatalf("写入迁移记录失败: %v", err)
+	}
+	for _, table := range []string{"llm_usages", "user_styles", "message_entities", "completion_logs", "prompt_templates"} {
+		if err := db.Migrator().DropTable(table); err != nil {
+			t.Fatalf("删除表 %s 失败: %v", table, err)
+		}
+	}
+
+	if err := Run(db); err != nil {
+		t.Fatalf("执行迁移失败: %v", err)
+	}
+}
+
+// 统计回填迁移按现有消息重建统计，不计草稿和已删除的消息
+func TestBackfillStats(t *testing.T) {
+	db := openTestDB(t)
+	if err := db.AutoMigrate(models.All()...); err != nil {
+		t.Fatalf("建表失败: %v", err)
+	}
+
+	conversation := models.Conversation{ConversationID: "conv-backfill"}
+	db.Create(&conversation)
+	messages := []models.Message{
+		{ConversationID: conversation.ID, SenderID: "alice", Content: "1", Sequence: 1},
+		{ConversationID: conversation.ID, SenderID: "bob", Content: "2", Sequence: 2},
+		{ConversationID: conversation.ID, SenderID: "alice", Content: "3", Sequence: 3},
+		{ConversationID: conversation.ID, SenderID: "alice", Content: "草稿", Sequence: 4, MessageType: models.MessageTypeDraft},
+		{ConversationID: conversation.ID, SenderID: "bob", Content: "已删除", Sequence: 5},
+	}
+	if err := db.Create(&messages).Error; err != nil {
+		t.Fatalf("保存消息失败: %v", err)
+	}
+	db.Delete(&messages[4])
+	// 统计表引入后只增量累加过一条
+	db.Create(&models.ConversationStat{ConversationID: conversation.ID, MessageCount: 1})
+
+	if err := backfillStats(db); err != nil {
+		t.Fatalf("回填统计失败: %v", err)
+	}
+
+	var stat models.ConversationStat
+	if err := db.Where("conversation_id = ?", conversation.ID).First(&stat).Error; err != nil {
+		t.Fatalf("查询统计失败: %v", err)
+	}
+	if stat.MessageCount != 3 || stat.LastActiveAt.IsZero() {
+		t.Fatalf("对话统计为 %+v", stat)
+	}
+	var senders []models.ConversationSenderStat
+	db.Where("conversation_id = ?", conversation.ID).Order("sender_id").Find(&senders)
+	if len(senders) != 2 || senders[0].MessageCount != 2 || senders[1].MessageCount != 1 {
+		t.Fatalf("发送者统计为 %+v", senders)
+	}
+}
@@ -0,0 +1,296 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 迁移使用的表结构快照。迁移只能依赖这里冻结的结构，不能引用 models 中的现行模型：
+// 现行模型会随后续需求增加字段，直接使用会让早期迁移提前创建后续迁移负责的列和表
+
+// 初始表结构（引入版本化迁移时的模型）
+
+type conversationV1 struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+
+	ConversationID string `gorm:"uniqueIndex;not null"`
+	Participants   string `gorm:"type:text"`
+	LastMessageAt  time.Time
+	Pinned         bool   `gorm:"default:false;index"`
+	Archived       bool   `gorm:"default:false;index"`
+	Settings       string `gorm:"type:text"`
+	Tags           string `gorm:"type:text"`
+	Note           string `gorm:"type:text"`
+
+	Messages []messageV1 `gorm:"foreignKey:ConversationID;references:ID"`
+	Summary  *summaryV1  `gorm:"foreignKey:ConversationID;references:ID"`
+	Styles   []styleV1   `gorm:"foreignKey:ConversationID;references:ID"`
+}
+
+func (conversationV1) TableName() string { return "conversations" }
+
+type messageV1 struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+
+	ConversationID uint   `gorm:"index;not null"`
+	SenderID       string `gorm:"index;not null"`
+	Content        string `gorm:"type:text;not null"`
+	MessageType    string `gorm:"default:text"`
+	Sequence       int64  `gorm:"index"`
+	EditedAt       *time.Time
+}
+
+func (messageV1) TableName() string { return "messages" }
+
+type summaryV1 struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+
+	ConversationID   uint   `gorm:"uniqueIndex;not null"`
+	Prompt           string `gorm:"type:text;not null"`
+	KeyInfo          string `gorm:"type:text"`
+	LastMessageCount int64
+	LastUpdatedAt    time.Time
+	Version          int `gorm:"default:1"`
+}
+
+func (summaryV1) TableName() string { return "summaries" }
+
+type styleV1 struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+
+	ConversationID   uint   `gorm:"index;not null"`
+	UserID           string `gorm:"index;not null"`
+	Features         string `gorm:"type:text;not null"`
+	Description      string `gorm:"type:text"`
+	LastMessageCount int64
+	LastUpdatedAt    time.Time
+}
+
+func (styleV1) TableName() string { return "styles" }
+
+type readCursorV1 struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	ConversationID   uint   `gorm:"uniqueIndex:idx_read_cursor;not null"`
+	UserID           string `gorm:"uniqueIndex:idx_read_cursor;not null"`
+	LastReadSequence int64
+}
+
+func (readCursorV1) TableName() string { return "read_cursors" }
+
+type completionRuleV1 struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+
+	Name      string `gorm:"not null"`
+	Pattern   string `gorm:"not null"`
+	Templates string `gorm:"type:text;not null"`
+	Priority  int
+	Enabled   bool `gorm:"default:true"`
+}
+
+func (completionRuleV1) TableName() string { return "completion_rules" }
+
+type conversationSnapshotV1 struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+
+	ConversationID string `gorm:"index;not null"`
+	Label          string
+	Participants   string `gorm:"type:text"`
+	Settings       string `gorm:"type:text"`
+	MessageCount   int
+	Data           string `gorm:"type:text;not null"`
+}
+
+func (conversationSnapshotV1) TableName() string { return "conversation_snapshots" }
+
+type conversationStatV1 struct {
+	ID        uint `gorm:"primarykey"`
+	UpdatedAt time.Time
+
+	ConversationID uint  `gorm:"uniqueIndex;not null"`
+	MessageCount   int64 `gorm:"not null;default:0"`
+	LastActiveAt   time.Time
+}
+
+func (conversationStatV1) TableName() string { return "conversation_stats" }
+
+type conversationSenderStatV1 struct {
+	ID        uint `gorm:"primarykey"`
+	UpdatedAt time.Time
+
+	ConversationID uint   `gorm:"uniqueIndex:idx_sender_stat;not null"`
+	SenderID       string `gorm:"uniqueIndex:idx_sender_stat;not null"`
+	MessageCount   int64  `gorm:"not null;default:0"`
+	LastActiveAt   time.Time
+}
+
+func (conversationSenderStatV1) TableName() string { return "conversation_sender_stats" }
+
+type alertV1 struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+
+	ConversationID uint   `gorm:"index;not null"`
+	MessageID      uint   `gorm:"index;not null"`
+	SenderID       string `gorm:"index"`
+	Keyword        string
+}
+
+func (alertV1) TableName() string { return "alerts" }
+
+type messageEditV1 struct {
+	ID uint `gorm:"primarykey"`
+
+	ConversationID uint   `gorm:"index;not null"`
+	MessageID      uint   `gorm:"index;not null"`
+	OldContent     string `gorm:"type:text;not null"`
+	EditedAt       time.Time
+}
+
+func (messageEditV1) TableName() string { return "message_edits" }
+
+type userProfileV1 struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	SenderID string `gorm:"uniqueIndex;not null"`
+	Data     string `gorm:"type:text"`
+}
+
+func (userProfileV1) TableName() string { return "user_profiles" }
+
+// initialTables 初始表结构，按依赖顺序排列（被外键引用的表在前）
+func initialTables() []interface{} {
+	return []interface{}{
+		&conversationV1{},
+		&messageV1{},
+		&summaryV1{},
+		&styleV1{},
+		&readCursorV1{},
+		&completionRuleV1{},
+		&conversationSnapshotV1{},
+		&conversationStatV1{},
+		&conversationSenderStatV1{},
+		&alertV1{},
+		&messageEditV1{},
+		&userProfileV1{},
+	}
+}
+
+// 后续迁移新增的表和列
+
+type llmUsageV1 struct {
+	ID        uint `gorm:"primarykey"`
+	UpdatedAt time.Time
+
+	SenderID string `gorm:"uniqueIndex:idx_llm_usage;not null"`
+	Day      string `gorm:"uniqueIndex:idx_llm_usage;not null"`
+	Count    int64  `gorm:"not null;default:0"`
+}
+
+func (llmUsageV1) TableName() string { return "llm_usages" }
+
+type userStyleV1 struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	UserID        string `gorm:"uniqueIndex;not null"`
+	Features      string `gorm:"type:text;not null"`
+	Description   string `gorm:"type:text"`
+	MessageCount  int64
+	LastUpdatedAt time.Time
+}
+
+func (userStyleV1) TableName() string { return "user_styles" }
+
+type conversationReadOnly struct {
+	ReadOnly bool `gorm:"default:false"`
+}
+
+func (conversationReadOnly) TableName() string { return "conversations" }
+
+type messageEntityV1 struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+
+	ConversationID uint   `gorm:"index:idx_entity_conversation_type,priority:1;not null"`
+	MessageID      uint   `gorm:"index;not null"`
+	Type           string `gorm:"size:20;index:idx_entity_conversation_type,priority:2;not null"`
+	Value          string `gorm:"type:text;not null"`
+	Offset         int
+}
+
+func (messageEntityV1) TableName() string { return "message_entities" }
+
+type summaryStyle struct {
+	Style string `gorm:"size:20"`
+}
+
+func (summaryStyle) TableName() string { return "summaries" }
+
+type completionLogV1 struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"index:idx_completion_conversation_time,priority:2"`
+
+	ConversationID uint   `gorm:"index:idx_completion_conversation_time,priority:1;not null"`
+	SenderID       string `gorm:"index;not null"`
+	Input          string `gorm:"type:text"`
+	Mode           string `gorm:"size:20"`
+	Suggestions    string `gorm:"type:text;not null"`
+	AcceptedIndex  *int
+	AcceptedAt     *time.Time
+}
+
+func (completionLogV1) TableName() string { return "completion_logs" }
+
+type conversationMetadata struct {
+	Metadata string `gorm:"type:text"`
+}
+
+func (conversationMetadata) TableName() string { return "conversations" }
+
+type messagePinned struct {
+	Pinned   bool `gorm:"default:false;index"`
+	PinnedAt *time.Time
+}
+
+func (messagePinned) TableName() string { return "messages" }
+
+type promptTemplateV1 struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+
+	Name    string `gorm:"size:64;not null;uniqueIndex:idx_prompt_name_version,priority:1"`
+	Version int    `gorm:"not null;uniqueIndex:idx_prompt_name_version,priority:2"`
+	Content string `gorm:"type:text;not null"`
+	Active  bool   `gorm:"default:false;index"`
+}
+
+func (promptTemplateV1) TableName() string { return "prompt_templates" }
+
+type messageContentFormat struct {
+	ContentFormat string `gorm:"size:16;default:plain"`
+}
+
+func (messageContentFormat) TableName() string { return "messages" }
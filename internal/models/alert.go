@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Alert 关注关键词命中提醒（对话设置 alert_keywords 中的关键词出现在新消息中时记录）
+type Alert struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// 所属对话ID
+	ConversationID uint `gorm:"index;not null" json:"-"`
+	// 命中的消息ID
+	MessageID uint `gorm:"index;not null" json:"message_id"`
+	// 消息发送者
+	SenderID string `gorm:"index" json:"sender_id"`
+	// 命中的关键词
+	Keyword string `json:"keyword"`
+}
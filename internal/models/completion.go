@@ -0,0 +1,93 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"ChatRecommend/internal/encryption"
+	"gorm.io/gorm"
+)
+
+// CompletionLog 一次补全请求及返回的建议（以及用户是否采纳），启用加密时输入和建议同样加密存储
+type CompletionLog struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"index:idx_completion_conversation_time,priority:2" json:"created_at"`
+
+	// 所属对话ID，与创建时间组成联合索引，便于按时间分页
+	ConversationID uint `gorm:"index:idx_completion_conversation_time,priority:1;not null" json:"-"`
+	// 请求补全的用户
+	SenderID string `gorm:"index;not null" json:"sender_id"`
+	// 补全时的输入
+	Input string `gorm:"type:text" json:"input"`
+	// 补全模式：complete 或 rewrite
+	Mode string `gorm:"size:20" json:"mode"`
+	// 返回的建议（JSON数组）
+	Suggestions string `gorm:"type:text;not null" json:"-"`
+	// 采纳的建议下标，未采纳时为空
+	AcceptedIndex *int `json:"accepted_index,omitempty"`
+	// 采纳时间
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+}
+
+// GetSuggestions 解析返回的建议
+func (l *CompletionLog) GetSuggestions() ([]string, error) {
+	var suggestions []string
+	if err := json.Unmarshal([]byte(l.Suggestions), &suggestions); err != nil {
+		return nil, fmt.Errorf("解析补全建议失败: %w", err)
+	}
+	return suggestions, nil
+}
+
+// SetSuggestions 设置返回的建议
+func (l *CompletionLog) SetSuggestions(suggestions []string) error {
+	data, err := json.Marshal(suggestions)
+	if err != nil {
+		return fmt.Errorf("序列化补全建议失败: %w", err)
+	}
+	l.Suggestions = string(data)
+	return nil
+}
+
+// BeforeSave 保存前加密输入和建议
+func (l *CompletionLog) BeforeSave(tx *gorm.DB) error {
+	if contentCipher == nil {
+		return nil
+	}
+
+	for _, field := range []*string{&l.Input, &l.Suggestions} {
+		if *field == "" {
+			continue
+		}
+		encrypted, err := contentCipher.Encrypt(*field)
+		if err != nil {
+			return fmt.Errorf("加密补全记录失败: %w", err)
+		}
+		*field = encrypted
+	}
+	return nil
+}
+
+// AfterSave 保存后还原明文，保证调用方继续使用（或再次保存）的是明文
+func (l *CompletionLog) AfterSave(tx *gorm.DB) error {
+	return l.AfterFind(tx)
+}
+
+// AfterFind 查询后解密输入和建议
+func (l *CompletionLog) AfterFind(tx *gorm.DB) error {
+	if contentCipher == nil {
+		return nil
+	}
+
+	for _, field := range []*string{&l.Input, &l.Suggestions} {
+		if !encryption.IsEncrypted(*field) {
+			continue
+		}
+		plaintext, err := contentCipher.Decrypt(*field)
+		if err != nil {
+			return fmt.Errorf("解密补全记录失败: %w", err)
+		}
+		*field = plaintext
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"ChatRecommend/internal/encryption"
+	"gorm.io/gorm"
+)
+
+// MessageEdit 消息编辑历史：每次编辑前的旧内容（启用加密时与消息内容一样加密存储）
+type MessageEdit struct {
+	ID uint `gorm:"primarykey" json:"id"`
+
+	// 所属对话ID（便于随对话一起删除和迁移）
+	ConversationID uint `gorm:"index;not null" json:"-"`
+	// 被编辑的消息ID
+	MessageID uint `gorm:"index;not null" json:"message_id"`
+	// 编辑前的内容
+	OldContent string `gorm:"type:text;not null" json:"old_content"`
+	// 编辑时间
+	EditedAt time.Time `json:"edited_at"`
+}
+
+// EditMessageRequest 编辑消息请求
+type EditMessageRequest struct {
+	// 编辑者，必须是消息的发送者
+	SenderID string `json:"sender_id" binding:"required"`
+	Content  string `json:"content" binding:"required"`
+}
+
+// BeforeSave 保存前加密旧内容
+func (e *MessageEdit) BeforeSave(tx *gorm.DB) error {
+	if contentCipher == nil {
+		return nil
+	}
+
+	encrypted, err := contentCipher.Encrypt(e.OldContent)
+	if err != nil {
+		return fmt.Errorf("加密编辑历史失败: %w", err)
+	}
+	e.OldContent = encrypted
+	return nil
+}
+
+// AfterSave 保存后还原明文，保证调用方继续使用（或再次保存）的是明文
+func (e *MessageEdit) AfterSave(tx *gorm.DB) error {
+	return e.AfterFind(tx)
+}
+
+// AfterFind 查询后解密旧内容
+func (e *MessageEdit) AfterFind(tx *gorm.DB) error {
+	if contentCipher == nil || !encryption.IsEncrypted(e.OldContent) {
+		return nil
+	}
+
+	plaintext, err := contentCipher.Decrypt(e.OldContent)
+	if err != nil {
+		return fmt.Errorf("解密编辑历史失败: %w", err)
+	}
+	e.OldContent = plaintext
+	return nil
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"fmt"
+
+	"ChatRecommend/internal/encryption"
+	"gorm.io/gorm"
+)
+
+// contentCipher 消息内容加解密器，为nil时不加密
+//
+// 启用加密后数据库中的 Message.Content 为密文，无法再通过SQL（LIKE等）按内容检索，
+// 需要按内容查找时应先取出消息再在内存中匹配。
+var contentCipher *encryption.ContentCipher
+
+// SetContentCipher 设置消息内容加解密器
+func SetContentCipher(c *encryption.ContentCipher) {
+	contentCipher = c
+}
+
+// BeforeSave 保存前加密消息内容。总是加密，不根据内容是否像密文来判断：
+// 用户消息可能恰好以密文前缀开头，跳过加密会以明文落库且之后无法解密。
+// 保存后 AfterSave 会还原明文，同一结构体多次保存也不会重复加密
+func (m *Message) BeforeSave(tx *gorm.DB) error {
+	if contentCipher == nil {
+		return nil
+	}
+
+	encrypted, err := contentCipher.Encrypt(m.Content)
+	if err != nil {
+		return fmt.Errorf("加密消息内容失败: %w", err)
+	}
+	m.Content = encrypted
+	return nil
+}
+
+// AfterSave 保存后还原明文，保证调用方继续使用的是明文内容
+func (m *Message) AfterSave(tx *gorm.DB) error {
+	return m.decryptContent()
+}
+
+// AfterFind 查询后解密消息内容
+func (m *Message) AfterFind(tx *gorm.DB) error {
+	return m.decryptContent()
+}
+
+// decryptContent 解密消息内容
+func (m *Message) decryptContent() error {
+	if contentCipher == nil || !encryption.IsEncrypted(m.Content) {
+		return nil
+	}
+
+	plaintext, err := contentCipher.Decrypt(m.Content)
+	if err != nil {
+		return fmt.Errorf("解密消息内容失败: %w", err)
+	}
+	m.Content = plaintext
+	return nil
+}
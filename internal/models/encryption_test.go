@@ -0,0 +1,69 @@
+package models
+
+import (
+	"testing"
+
+	"ChatRecommend/internal/encryption"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestMessageEncryptionWithCipherPrefix(t *testing.T) {
+	cipher, err := encryption.NewContentCipher("v1", map[string]string{"v1": "test-key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetContentCipher(cipher)
+	defer SetContentCipher(nil)
+
+	db, err := gorm.Open(sqlite.Open("file:models_encryption?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&Message{}, &MessageEdit{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 用户消息恰好以密文前缀开头时也要加密，并能原样读回
+	content := "enc:v1:看起来像密文的消息"
+	message := Message{ConversationID: 1, SenderID: "u1", Content: content}
+	if err := db.Create(&message).Error; err != nil {
+		t.Fatal(err)
+	}
+	if message.Content != content {
+		t.Fatalf("保存后应还原明文，得到 %q", message.Content)
+	}
+
+	var raw string
+	if err := db.Table("messages").Select("content").Where("id = ?", message.ID).Row().Scan(&raw); err != nil {
+		t.Fatal(err)
+	}
+	if raw == content {
+		t.Fatal("以密文前缀开头的消息以明文落库")
+	}
+
+	var loaded Message
+	if err := db.First(&loaded, message.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Content != content {
+		t.Fatalf("读回内容 = %q, want %q", loaded.Content, content)
+	}
+
+	// 同一结构体再次保存不会重复加密
+	edit := MessageEdit{ConversationID: 1, MessageID: message.ID, OldContent: "旧内容"}
+	if err := db.Create(&edit).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Save(&edit).Error; err != nil {
+		t.Fatal(err)
+	}
+	var loadedEdit MessageEdit
+	if err := db.First(&loadedEdit, edit.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if loadedEdit.OldContent != "旧内容" {
+		t.Fatalf("编辑历史读回 = %q", loadedEdit.OldContent)
+	}
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"ChatRecommend/internal/encryption"
+	"gorm.io/gorm"
+)
+
+// MessageEntity 从消息内容中抽取的结构化实体（地点、时间、人名、金额），启用加密时实体值同样加密存储
+type MessageEntity struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// 所属对话ID，与实体类型组成联合索引，便于按类型查询
+	ConversationID uint `gorm:"index:idx_entity_conversation_type,priority:1;not null" json:"conversation_id"`
+	// 实体所在的消息ID
+	MessageID uint `gorm:"index;not null" json:"message_id"`
+	// 实体类型：location/time/person/money
+	Type string `gorm:"size:20;index:idx_entity_conversation_type,priority:2;not null" json:"type"`
+	// 实体值（原文片段）
+	Value string `gorm:"type:text;not null" json:"value"`
+	// 在消息内容中的起始位置（按字符计）
+	Offset int `json:"offset"`
+}
+
+// BeforeSave 保存前加密实体值
+func (e *MessageEntity) BeforeSave(tx *gorm.DB) error {
+	if contentCipher == nil {
+		return nil
+	}
+
+	encrypted, err := contentCipher.Encrypt(e.Value)
+	if err != nil {
+		return fmt.Errorf("加密实体失败: %w", err)
+	}
+	e.Value = encrypted
+	return nil
+}
+
+// AfterSave 保存后还原明文，保证调用方继续使用（或再次保存）的是明文
+func (e *MessageEntity) AfterSave(tx *gorm.DB) error {
+	return e.AfterFind(tx)
+}
+
+// AfterFind 查询后解密实体值
+func (e *MessageEntity) AfterFind(tx *gorm.DB) error {
+	if contentCipher == nil || !encryption.IsEncrypted(e.Value) {
+		return nil
+	}
+
+	plaintext, err := contentCipher.Decrypt(e.Value)
+	if err != nil {
+		return fmt.Errorf("解密实体失败: %w", err)
+	}
+	e.Value = plaintext
+	return nil
+}
@@ -0,0 +1,140 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 自定义字段的数量、键长度、字符串值长度和序列化后大小上限
+const (
+	MaxMetadataKeys       = 32
+	maxMetadataValueChars = 256
+	maxMetadataBytes      = 8192
+)
+
+// metadataKeyPattern 自定义字段键：1-64 个字母、数字、下划线或短横线
+var metadataKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// ValidMetadataKey 判断自定义字段键是否合法
+func ValidMetadataKey(key string) bool {
+	return metadataKeyPattern.MatchString(key)
+}
+
+// ValidateMetadata 校验自定义字段：扁平对象，值只能为字符串、数字或布尔值
+func ValidateMetadata(metadata map[string]interface{}) error {
+	if len(metadata) > MaxMetadataKeys {
+		return fmt.Errorf("自定义字段最多 %d 个", MaxMetadataKeys)
+	}
+	for key, value := range metadata {
+		if !ValidMetadataKey(key) {
+			return fmt.Errorf("自定义字段键 %q 不合法，只能包含字母、数字、下划线和短横线且不超过64个字符", key)
+		}
+		switch v := value.(type) {
+		case string:
+			if len([]rune(v)) > maxMetadataValueChars {
+				return fmt.Errorf("自定义字段 %s 的值不能超过 %d 个字符", key, maxMetadataValueChars)
+			}
+		case float64, bool:
+		default:
+			return fmt.Errorf("自定义字段 %s 的值只能为字符串、数字或布尔值", key)
+		}
+	}
+	return nil
+}
+
+// GetMetadata 解析对话自定义字段，未设置时返回空对象
+func (c *Conversation) GetMetadata() (map[string]interface{}, error) {
+	metadata := map[string]interface{}{}
+	if c.Metadata == "" {
+		return metadata, nil
+	}
+	if err := json.Unmarshal([]byte(c.Metadata), &metadata); err != nil {
+		return map[string]interface{}{}, fmt.Errorf("解析对话自定义字段失败: %w", err)
+	}
+	return metadata, nil
+}
+
+// SetMetadata 校验后保存对话自定义字段（整体替换）
+func (c *Conversation) SetMetadata(metadata map[string]interface{}) error {
+	if err := ValidateMetadata(metadata); err != nil {
+		return err
+	}
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("序列化对话自定义字段失败: %w", err)
+	}
+	if len(data) > maxMetadataBytes {
+		return fmt.Errorf("自定义字段序列化后不能超过 %d 字节", maxMetadataBytes)
+	}
+	c.Metadata = string(data)
+	return nil
+}
+
+// MergeMetadata 把 patch 合并进对话自定义字段，值为 null 的键被删除
+func (c *Conversation) MergeMetadata(patch map[string]interface{}) error {
+	metadata, err := c.GetMetadata()
+	if err != nil {
+		return err
+	}
+	for key, value := range patch {
+		if value == nil {
+			delete(metadata, key)
+			continue
+		}
+		metadata[key] = value
+	}
+	return c.SetMetadata(metadata)
+}
+
+// MetadataFilter 按自定义字段筛选对话的条件：同一个键的多个取值之间为 OR
+type MetadataFilter struct {
+	Key    string
+	Values []string
+}
+
+// ParseMetadataFilters 从查询参数中解析 meta.<key>=<value> 形式的筛选条件，按键排序返回
+func ParseMetadataFilters(query map[string][]string) ([]MetadataFilter, error) {
+	var filters []MetadataFilter
+	for param, values := range query {
+		key, ok := strings.CutPrefix(param, "meta.")
+		if !ok {
+			continue
+		}
+		if !ValidMetadataKey(key) {
+			return nil, fmt.Errorf("自定义字段筛选 %q 的键不合法", param)
+		}
+		filters = append(filters, MetadataFilter{Key: key, Values: values})
+	}
+	sort.Slice(filters, func(i, j int) bool { return filters[i].Key < filters[j].Key })
+	return filters, nil
+}
+
+// SQL 生成筛选条件：字符串值按文本精确匹配；取值可解析为数字时也匹配相等的数字值；取值为 true/false 时也匹配布尔值
+func (f MetadataFilter) SQL() (string, []interface{}) {
+	path := `$."` + f.Key + `"`
+	// 未设置自定义字段的旧对话为空字符串，NULLIF 避免 json_extract 报错
+	const doc = "NULLIF(metadata, '')"
+	var clauses []string
+	var args []interface{}
+	for _, value := range f.Values {
+		clauses = append(clauses, "(json_type("+doc+", ?) = 'text' AND json_extract("+doc+", ?) = ?)")
+		args = append(args, path, path, value)
+		if number, err := strconv.ParseFloat(value, 64); err == nil && !math.IsInf(number, 0) && !math.IsNaN(number) {
+			clauses = append(clauses, "(json_type("+doc+", ?) IN ('integer', 'real') AND json_extract("+doc+", ?) = ?)")
+			args = append(args, path, path, number)
+		}
+		if value == "true" || value == "false" {
+			clauses = append(clauses, "json_type("+doc+", ?) = ?")
+			args = append(args, path, value)
+		}
+	}
+	return "(" + strings.Join(clauses, " OR ") + ")", args
+}
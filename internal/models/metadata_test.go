@@ -0,0 +1,74 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestConversationMetadataValidate 非法的键、值类型、数量和长度被拒绝，合法字段可原样读回
+func TestConversationMetadataValidate(t *testing.T) {
+	tooMany := map[string]interface{}{}
+	for i := 0; i <= MaxMetadataKeys; i++ {
+		tooMany["key"+strings.Repeat("x", i)] = "v"
+	}
+	invalid := []map[string]interface{}{
+		{"bad key": "v"},
+		{strings.Repeat("k", 65): "v"},
+		{"nested": map[string]interface{}{"a": "b"}},
+		{"list": []interface{}{"a"}},
+		{"long": strings.Repeat("长", maxMetadataValueChars+1)},
+		tooMany,
+	}
+	for _, metadata := range invalid {
+		var conversation Conversation
+		if err := conversation.SetMetadata(metadata); err == nil {
+			t.Errorf("自定义字段 %v 应校验失败", metadata)
+		}
+		if conversation.Metadata != "" {
+			t.Errorf("校验失败时不应写入自定义字段: %s", conversation.Metadata)
+		}
+	}
+
+	var conversation Conversation
+	if err := conversation.SetMetadata(map[string]interface{}{"level": "vip", "score": 5.0, "active": true}); err != nil {
+		t.Fatalf("合法自定义字段校验失败: %v", err)
+	}
+	metadata, err := conversation.GetMetadata()
+	if err != nil || metadata["level"] != "vip" || metadata["score"] != 5.0 || metadata["active"] != true {
+		t.Errorf("读回的自定义字段为 %v, %v", metadata, err)
+	}
+}
+
+// TestConversationMetadataMerge 合并时覆盖同名键，值为 null 的键被删除
+func TestConversationMetadataMerge(t *testing.T) {
+	var conversation Conversation
+	if err := conversation.SetMetadata(map[string]interface{}{"level": "normal", "crm_id": "42"}); err != nil {
+		t.Fatalf("保存自定义字段失败: %v", err)
+	}
+	if err := conversation.MergeMetadata(map[string]interface{}{"level": "vip", "crm_id": nil, "region": "华东"}); err != nil {
+		t.Fatalf("合并自定义字段失败: %v", err)
+	}
+	metadata, _ := conversation.GetMetadata()
+	if len(metadata) != 2 || metadata["level"] != "vip" || metadata["region"] != "华东" {
+		t.Errorf("合并后的自定义字段为 %v", metadata)
+	}
+}
+
+// TestParseMetadataFilters 只解析 meta. 前缀的参数并按键排序，键不合法时报错
+func TestParseMetadataFilters(t *testing.T) {
+	filters, err := ParseMetadataFilters(map[string][]string{
+		"meta.tier":  {"1", "2"},
+		"meta.level": {"vip"},
+		"limit":      {"10"},
+	})
+	if err != nil {
+		t.Fatalf("解析筛选条件失败: %v", err)
+	}
+	if len(filters) != 2 || filters[0].Key != "level" || filters[1].Key != "tier" || len(filters[1].Values) != 2 {
+		t.Errorf("筛选条件为 %+v", filters)
+	}
+
+	if _, err := ParseMetadataFilters(map[string][]string{"meta.bad key": {"v"}}); err == nil {
+		t.Error("键不合法时应报错")
+	}
+}
@@ -3,6 +3,7 @@ package models
 import (
 	"time"
 
+	"ChatRecommend/internal/textutil"
 	"gorm.io/gorm"
 )
 
@@ -19,6 +20,20 @@ type Conversation struct {
 	Participants   string `gorm:"type:text" json:"participants"`
 	// 最后一条消息时间
 	LastMessageAt  time.Time `json:"last_message_at"`
+	// 是否置顶
+	Pinned         bool      `gorm:"default:false;index" json:"pinned"`
+	// 是否归档（归档的对话不自动更新摘要）
+	Archived       bool      `gorm:"default:false;index" json:"archived"`
+	// 是否只读（只读对话不再接收新消息；归档时自动设为只读，取消归档不会解除，需调用只读接口）
+	ReadOnly       bool      `gorm:"default:false" json:"read_only"`
+	// 对话级设置（JSON格式存储，见 ConversationSettings）
+	Settings       string    `gorm:"type:text" json:"settings"`
+	// 标签（JSON数组，已归一化去重，见 SetTags）
+	Tags           string    `gorm:"type:text" json:"tags"`
+	// 备注
+	Note           string    `gorm:"type:text" json:"note"`
+	// 接入方自定义字段（扁平JSON对象，见 SetMetadata）
+	Metadata       string    `gorm:"type:text" json:"metadata"`
 
 	// 关联关系
 	Messages []Message `gorm:"foreignKey:ConversationID;references:ID" json:"messages,omitempty"`
@@ -43,6 +58,43 @@ type Message struct {
 	MessageType    string `gorm:"default:text" json:"message_type"`
 	// 消息序号（用于排序）
 	Sequence       int64  `gorm:"index" json:"sequence"`
+	// 最后编辑时间，未编辑过为空
+	EditedAt       *time.Time `json:"edited_at,omitempty"`
+	// 是否置顶（置顶消息总会注入补全上下文）及置顶时间
+	Pinned         bool       `gorm:"default:false;index" json:"pinned"`
+	PinnedAt       *time.Time `json:"pinned_at,omitempty"`
+	// 内容格式（plain, markdown），markdown 消息在风格分析和上下文注入前提取纯文本
+	ContentFormat  string     `gorm:"size:16;default:plain" json:"content_format"`
+}
+
+// 消息内容格式
+const (
+	ContentFormatPlain    = "plain"
+	ContentFormatMarkdown = "markdown"
+)
+
+// ValidContentFormat 判断消息内容格式是否合法（空表示 plain）
+func ValidContentFormat(format string) bool {
+	return format == "" || format == ContentFormatPlain || format == ContentFormatMarkdown
+}
+
+// PlainContent 消息的纯文本内容：markdown 消息去掉 Markdown 语法，其他格式原样返回
+func (m *Message) PlainContent() string {
+	if m.ContentFormat == ContentFormatMarkdown {
+		return textutil.StripMarkdown(m.Content)
+	}
+	return m.Content
+}
+
+// MaxPinnedMessages 每个对话最多置顶的消息数
+const MaxPinnedMessages = 10
+
+// MessageTypeDraft 草稿消息类型：客户端保存的未发送草稿，不参与上下文构建、摘要和风格分析
+const MessageTypeDraft = "draft"
+
+// ExcludeDrafts 查询条件：排除草稿消息
+func ExcludeDrafts(db *gorm.DB) *gorm.DB {
+	return db.Where("message_type <> ?", MessageTypeDraft)
 }
 
 // Summary 对话摘要模型
@@ -64,6 +116,8 @@ type Summary struct {
 	LastUpdatedAt    time.Time `json:"last_updated_at"`
 	// 版本号（用于追踪更新）
 	Version          int       `gorm:"default:1" json:"version"`
+	// 生成时使用的摘要格式（bullets/narrative/timeline）
+	Style            string    `gorm:"size:20" json:"style"`
 }
 
 // Style 语言风格模型
@@ -87,18 +141,183 @@ type Style struct {
 	LastUpdatedAt    time.Time `json:"last_updated_at"`
 }
 
+// UserStyle 用户级语言风格画像，聚合该用户在所有对话中的近期消息
+type UserStyle struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// 用户ID
+	UserID        string    `gorm:"uniqueIndex;not null" json:"user_id"`
+	// 风格特征（JSON格式存储，结构与对话级画像相同）
+	Features      string    `gorm:"type:text;not null" json:"features"`
+	// 风格描述（文本描述）
+	Description   string    `gorm:"type:text" json:"description"`
+	// 参与分析的消息数
+	MessageCount  int64     `json:"message_count"`
+	// 最后更新时间
+	LastUpdatedAt time.Time `json:"last_updated_at"`
+}
+
+// ReadCursor 已读位置模型
+type ReadCursor struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// 所属对话ID
+	ConversationID   uint   `gorm:"uniqueIndex:idx_read_cursor;not null" json:"conversation_id"`
+	// 用户ID
+	UserID           string `gorm:"uniqueIndex:idx_read_cursor;not null" json:"user_id"`
+	// 已读到的消息序号
+	LastReadSequence int64  `json:"last_read_sequence"`
+}
+
+// CompletionRule 快捷补全规则模型
+type CompletionRule struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// 规则名称
+	Name      string `gorm:"not null" json:"name"`
+	// 触发正则
+	Pattern   string `gorm:"not null" json:"pattern"`
+	// 补全模板（JSON数组），支持 ${name} 变量
+	Templates string `gorm:"type:text;not null" json:"templates"`
+	// 优先级（越大越先匹配）
+	Priority  int    `json:"priority"`
+	// 是否启用
+	Enabled   bool   `gorm:"default:true" json:"enabled"`
+}
+
 // AutocompleteRequest 自动补全请求
 type AutocompleteRequest struct {
 	ConversationID string `json:"conversation_id" binding:"required"`
 	SenderID       string `json:"sender_id" binding:"required"`
-	Input          string `json:"input" binding:"required"`
+	// 用户当前输入，为空时返回开场白建议
+	Input          string `json:"input"`
 	MaxSuggestions int    `json:"max_suggestions,omitempty"`
+	// 额外指令（如"用英文回复"、"正式一点"），作为最高优先级指令拼入上下文顶部
+	ExtraInstructions string `json:"extra_instructions,omitempty"`
+	// 隐私模式：只注入风格画像和当前输入，不把摘要和历史消息发送给大模型
+	PrivacyMode    bool   `json:"privacy_mode,omitempty"`
+	// 角色扮演（可选）：预设名称（文言文、鲁迅风、猫娘等）或自定义角色描述，设置后替代学到的语言风格
+	PersonaStyle   string `json:"persona_style,omitempty"`
+	// 请求ID（可选），客户端重试时携带相同的ID，短时间内只会生成一次
+	RequestID      string `json:"request_id,omitempty"`
+	// 最大生成token数（可选），不能超过 autocomplete.max_request_tokens
+	MaxTokens      int      `json:"max_tokens,omitempty"`
+	// 停止序列（可选），最多4个
+	Stop           []string `json:"stop,omitempty"`
+	// 输出格式（可选）：text（默认）或 json，json 时额外返回带理由和调性标签的结构化建议
+	ResponseFormat string   `json:"response_format,omitempty"`
+	// 候选输入（可选），与 input 互斥：服务端选出与对话近期内容最相关的一个作为输入进行补全
+	InputCandidates []string `json:"input_candidates,omitempty"`
+	// 输入法正在组字（拼音尚未上屏），为 true 时不补全，直接返回空建议；前端应在 compositionend 后再请求
+	Composing       bool     `json:"composing,omitempty"`
+	// 补全模式（可选）：complete（默认，续写当前输入）或 rewrite（把当前输入整句改写成更好的表达）
+	Mode            string   `json:"mode,omitempty"`
+	// 排队优先级（可选）：high、normal（默认）或 low，由接入层按鉴权结果（如付费等级）填写
+	Priority        string   `json:"priority,omitempty"`
+	// 服务端在保存消息后发起的预取请求：不占用每日调用配额、不刷新补全活跃用户。不参与序列化，客户端无法设置
+	Prefetch        bool     `json:"-"`
+}
+
+// 补全请求的排队优先级
+const (
+	PriorityHigh   = "high"
+	PriorityNormal = "normal"
+	PriorityLow    = "low"
+)
+
+// 补全模式
+const (
+	AutocompleteModeComplete = "complete"
+	AutocompleteModeRewrite  = "rewrite"
+)
+
+// 建议的插入方式
+const (
+	// InsertModeAppend 建议接在用户已输入内容之后
+	InsertModeAppend = "append"
+	// InsertModeReplace 建议替换用户已输入的整句内容
+	InsertModeReplace = "replace"
+)
+
+// 补全输出格式
+const (
+	ResponseFormatText = "text"
+	ResponseFormatJSON = "json"
+)
+
+// Suggestion 结构化补全建议
+type Suggestion struct {
+	Text   string `json:"text"`
+	Reason string `json:"reason,omitempty"`
+	Tone   string `json:"tone,omitempty"`
+	// 安全等级（safe/warn/blocked），未配置安全分级时省略；warn 的建议照常返回，由前端提示
+	SafetyLevel string `json:"safety_level,omitempty"`
+}
+
+// 补全建议的安全等级
+const (
+	SafetyLevelSafe    = "safe"
+	SafetyLevelWarn    = "warn"
+	SafetyLevelBlocked = "blocked"
+)
+
+// KeyInfoCitation 补全建议对关键信息的引用标记
+type KeyInfoCitation struct {
+	// 引用该信息的建议在 suggestions 中的下标
+	Suggestion      int                    `json:"suggestion"`
+	// 被引用的关键信息（摘要中的原始条目）
+	KeyInfo         map[string]interface{} `json:"key_info"`
+	// 来源消息ID（对话中最近一条包含该信息的消息），找不到时省略
+	SourceMessageID uint                   `json:"source_message_id,omitempty"`
 }
 
 // AutocompleteResponse 自动补全响应
 type AutocompleteResponse struct {
+	// 响应类型：completion（补全建议）或 clarification（输入太模糊，返回澄清提示，suggestions 为空）
+	Type        string   `json:"type"`
 	Suggestions []string `json:"suggestions"`
+	// 结构化建议（response_format 为 json 时返回），与 suggestions 一一对应
+	Items       []Suggestion `json:"items,omitempty"`
 	ContextUsed string   `json:"context_used,omitempty"`
+	// 从 input_candidates 中选中的输入（仅在请求携带候选时返回）
+	SelectedInput string `json:"selected_input,omitempty"`
+	// 建议引用的关键信息（autocomplete.cite_key_info 开启时返回），一条建议可引用多条信息
+	Citations     []KeyInfoCitation `json:"citations,omitempty"`
+	// 降级级别（大模型延迟过高时）：reduced（减少建议数、缩短长度）或 local（只用本地规则），正常时省略
+	Degraded      string `json:"degraded,omitempty"`
+	// 建议的插入方式：append（接在输入之后）或 replace（替换整句输入，改写模式）
+	InsertMode    string `json:"insert_mode"`
+	// 补全历史记录ID（记录补全历史时返回），采纳建议后用它提交反馈
+	CompletionID  uint   `json:"completion_id,omitempty"`
+	// 澄清提示（type 为 clarification 时返回）
+	Clarification *Clarification `json:"clarification,omitempty"`
+}
+
+// 补全响应类型
+const (
+	ResponseTypeCompletion    = "completion"
+	ResponseTypeClarification = "clarification"
+)
+
+// ClarificationVagueInput 澄清原因：输入太模糊且缺少可参考的上下文
+const ClarificationVagueInput = "vague_input"
+
+// Clarification 澄清提示：信息不足以给出有意义的建议时代替补全返回，提示用户补充
+type Clarification struct {
+	Reason   string `json:"reason"`
+	Question string `json:"question"`
+}
+
+// CompletionFeedbackRequest 补全反馈请求：用户采纳了第几条建议
+type CompletionFeedbackRequest struct {
+	AcceptedIndex *int `json:"accepted_index" binding:"required"`
 }
 
 // SaveMessageRequest 保存消息请求
@@ -108,5 +327,101 @@ type SaveMessageRequest struct {
 	Content        string `json:"content" binding:"required"`
 	MessageType    string `json:"message_type,omitempty"`
 	Sequence       int64  `json:"sequence,omitempty"`
+	// 内容格式：plain（默认）或 markdown
+	ContentFormat  string `json:"content_format,omitempty"`
+}
+
+// UpdateConversationStateRequest 更新对话状态请求（字段为空表示不修改）
+type UpdateConversationStateRequest struct {
+	Pinned   *bool `json:"pinned,omitempty"`
+	Archived *bool `json:"archived,omitempty"`
 }
 
+// UpdateParticipantRoleRequest 设置参与者角色请求
+type UpdateParticipantRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// UpdateConversationReadOnlyRequest 设置/解除对话只读请求
+type UpdateConversationReadOnlyRequest struct {
+	ReadOnly *bool `json:"read_only" binding:"required"`
+}
+
+// UpdateConversationTagsRequest 更新对话标签请求（整体替换）
+type UpdateConversationTagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+// UpdateConversationMetadataRequest 更新对话自定义字段请求
+type UpdateConversationMetadataRequest struct {
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+// UpdateConversationNoteRequest 更新对话备注请求
+type UpdateConversationNoteRequest struct {
+	Note string `json:"note"`
+}
+
+// MarkReadRequest 上报已读位置请求
+type MarkReadRequest struct {
+	UserID           string `json:"user_id" binding:"required"`
+	// 已读到的消息序号，为0时表示全部已读
+	LastReadSequence int64  `json:"last_read_sequence,omitempty"`
+}
+
+// MessageDTO 精简的消息结构（历史查询默认返回，只保留前端展示所需字段）
+type MessageDTO struct {
+	ID          uint      `json:"id"`
+	SenderID    string    `json:"sender_id"`
+	Content     string    `json:"content"`
+	MessageType string    `json:"message_type"`
+	Sequence    int64     `json:"sequence"`
+	CreatedAt   time.Time `json:"created_at"`
+	EditedAt    *time.Time `json:"edited_at,omitempty"`
+	Pinned      bool      `json:"pinned,omitempty"`
+	PinnedAt    *time.Time `json:"pinned_at,omitempty"`
+	ContentFormat string  `json:"content_format,omitempty"`
+}
+
+// ToMessageDTOs 将消息列表转换为精简结构
+func ToMessageDTOs(messages []Message) []MessageDTO {
+	dtos := make([]MessageDTO, 0, len(messages))
+	for _, msg := range messages {
+		dtos = append(dtos, MessageDTO{
+			ID:          msg.ID,
+			SenderID:    msg.SenderID,
+			Content:     msg.Content,
+			MessageType: msg.MessageType,
+			Sequence:    msg.Sequence,
+			CreatedAt:   msg.CreatedAt,
+			EditedAt:    msg.EditedAt,
+			Pinned:      msg.Pinned,
+			PinnedAt:    msg.PinnedAt,
+			ContentFormat: msg.ContentFormat,
+		})
+	}
+	return dtos
+}
+
+// All 返回需要自动迁移的全部模型，新增表时在这里登记
+func All() []interface{} {
+	return []interface{}{
+		&Conversation{},
+		&Message{},
+		&Summary{},
+		&Style{},
+		&ReadCursor{},
+		&CompletionRule{},
+		&ConversationSnapshot{},
+		&ConversationStat{},
+		&ConversationSenderStat{},
+		&Alert{},
+		&MessageEdit{},
+		&UserProfile{},
+		&LLMUsage{},
+		&UserStyle{},
+		&MessageEntity{},
+		&CompletionLog{},
+		&PromptTemplate{},
+	}
+}
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// 参与者角色
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+)
+
+// Participant 带角色的对话参与者。参与者列表（Conversation.Participants）的元素可以是用户ID字符串，
+// 也可以是带 id（或 user_id、sender_id）字段的对象；字符串元素和没有 role 字段的对象视为 member
+type Participant struct {
+	ID   string `json:"id"`
+	Role string `json:"role"`
+}
+
+// ValidRole 判断角色是否合法
+func ValidRole(role string) bool {
+	return role == RoleOwner || role == RoleAdmin || role == RoleMember
+}
+
+// ParticipantID 获取参与者列表元素的用户ID
+func ParticipantID(p interface{}) string {
+	switch v := p.(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		for _, key := range []string{"id", "user_id", "sender_id"} {
+			if id, ok := v[key].(string); ok {
+				return id
+			}
+		}
+	}
+	return ""
+}
+
+// participantRole 获取参与者列表元素的角色
+func participantRole(p interface{}) string {
+	if v, ok := p.(map[string]interface{}); ok {
+		if role, ok := v["role"].(string); ok && ValidRole(role) {
+			return role
+		}
+	}
+	return RoleMember
+}
+
+// participantList 解析参与者列表，格式错误时返回空列表
+func (c *Conversation) participantList() []interface{} {
+	var list []interface{}
+	if c.Participants != "" {
+		json.Unmarshal([]byte(c.Participants), &list)
+	}
+	return list
+}
+
+// GetParticipants 获取带角色的参与者列表
+func (c *Conversation) GetParticipants() []Participant {
+	list := c.participantList()
+	participants := make([]Participant, 0, len(list))
+	for _, p := range list {
+		if id := ParticipantID(p); id != "" {
+			participants = append(participants, Participant{ID: id, Role: participantRole(p)})
+		}
+	}
+	return participants
+}
+
+// ParticipantRole 获取用户在对话中的角色，不是参与者时返回空
+func (c *Conversation) ParticipantRole(userID string) string {
+	for _, p := range c.GetParticipants() {
+		if p.ID == userID {
+			return p.Role
+		}
+	}
+	return ""
+}
+
+// HasRoles 对话是否分配了 owner 或 admin。未分配时无法区分角色，敏感操作只要求调用者是已有参与者
+func (c *Conversation) HasRoles() bool {
+	for _, p := range c.GetParticipants() {
+		if p.Role != RoleMember {
+			return true
+		}
+	}
+	return false
+}
+
+// SetParticipantRole 设置参与者角色：字符串元素转为对象，对象元素保留其他字段，不是参与者时追加
+func (c *Conversation) SetParticipantRole(userID, role string) error {
+	if !ValidRole(role) {
+		return fmt.Errorf("角色不合法: %s", role)
+	}
+
+	list := c.participantList()
+	found := false
+	for i, p := range list {
+		if ParticipantID(p) != userID {
+			continue
+		}
+		found = true
+		if v, ok := p.(map[string]interface{}); ok {
+			v["role"] = role
+		} else {
+			list[i] = Participant{ID: userID, Role: role}
+		}
+	}
+	if !found {
+		list = append(list, Participant{ID: userID, Role: role})
+	}
+
+	data, err := json.Marshal(list)
+	if err != nil {
+		return fmt.Errorf("序列化参与者失败: %w", err)
+	}
+	c.Participants = string(data)
+	return nil
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"ChatRecommend/internal/encryption"
+	"gorm.io/gorm"
+)
+
+// UserProfile 用户个人档案（启用加密时档案数据加密存储）
+type UserProfile struct {
+	ID        uint      `gorm:"primarykey" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// 用户ID（与消息的 sender_id 一致）
+	SenderID string `gorm:"uniqueIndex;not null" json:"sender_id"`
+	// 档案字段（ProfileFields 的JSON）
+	Data string `gorm:"type:text" json:"-"`
+}
+
+// ProfileFields 档案字段，均为可选
+type ProfileFields struct {
+	Name     string `json:"name,omitempty"`
+	Phone    string `json:"phone,omitempty"`
+	Email    string `json:"email,omitempty"`
+	Address  string `json:"address,omitempty"`
+	Birthday string `json:"birthday,omitempty"`
+	// 用户所在时区（IANA 时区名，如 Asia/Shanghai）
+	Timezone string `json:"timezone,omitempty"`
+	// 偏好（如 口味: 不吃辣）
+	Preferences map[string]string `json:"preferences,omitempty"`
+}
+
+// GetFields 解析档案字段
+func (p *UserProfile) GetFields() (*ProfileFields, error) {
+	fields := &ProfileFields{}
+	if p.Data == "" {
+		return fields, nil
+	}
+	if err := json.Unmarshal([]byte(p.Data), fields); err != nil {
+		return fields, fmt.Errorf("解析用户档案失败: %w", err)
+	}
+	return fields, nil
+}
+
+// SetFields 校验并保存档案字段
+func (p *UserProfile) SetFields(fields *ProfileFields) error {
+	if err := ValidateTimezone(fields.Timezone); err != nil {
+		return err
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return fmt.Errorf("序列化用户档案失败: %w", err)
+	}
+	p.Data = string(data)
+	return nil
+}
+
+// BeforeSave 保存前加密档案数据
+func (p *UserProfile) BeforeSave(tx *gorm.DB) error {
+	if contentCipher == nil {
+		return nil
+	}
+
+	encrypted, err := contentCipher.Encrypt(p.Data)
+	if err != nil {
+		return fmt.Errorf("加密用户档案失败: %w", err)
+	}
+	p.Data = encrypted
+	return nil
+}
+
+// AfterSave 保存后还原明文
+func (p *UserProfile) AfterSave(tx *gorm.DB) error {
+	return p.AfterFind(tx)
+}
+
+// AfterFind 查询后解密档案数据
+func (p *UserProfile) AfterFind(tx *gorm.DB) error {
+	if contentCipher == nil || !encryption.IsEncrypted(p.Data) {
+		return nil
+	}
+
+	plaintext, err := contentCipher.Decrypt(p.Data)
+	if err != nil {
+		return fmt.Errorf("解密用户档案失败: %w", err)
+	}
+	p.Data = plaintext
+	return nil
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// 支持在线更新的提示词模板名称，对应 prompt 配置中的同名项
+const (
+	PromptSystemPrefix        = "system_prefix"
+	PromptSummarySystemPrefix = "summary_system_prefix"
+)
+
+// PromptNames 全部提示词模板名称
+var PromptNames = []string{PromptSystemPrefix, PromptSummarySystemPrefix}
+
+// ValidPromptName 判断提示词模板名称是否合法
+func ValidPromptName(name string) bool {
+	for _, n := range PromptNames {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// PromptTemplate 提示词模板的一个版本。每次更新新增一个版本并激活，同一名称最多一个激活版本；
+// 没有激活版本时使用配置文件中的模板
+type PromptTemplate struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	Name    string `gorm:"size:64;not null;uniqueIndex:idx_prompt_name_version,priority:1" json:"name"`
+	Version int    `gorm:"not null;uniqueIndex:idx_prompt_name_version,priority:2" json:"version"`
+	Content string `gorm:"type:text;not null" json:"content"`
+	Active  bool   `gorm:"default:false;index" json:"active"`
+}
+
+// UpdatePromptTemplateRequest 更新提示词模板请求
+type UpdatePromptTemplateRequest struct {
+	Content string `json:"content" binding:"required"`
+}
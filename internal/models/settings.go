@@ -0,0 +1,154 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ConversationSettings 对话级设置，未设置的字段使用全局配置
+type ConversationSettings struct {
+	// 大模型生成参数覆盖
+	Temperature *float64 `json:"temperature,omitempty"`
+	MaxTokens   *int     `json:"max_tokens,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	// 关注关键词，新消息命中时记录提醒并通知
+	AlertKeywords []string `json:"alert_keywords,omitempty"`
+	// 是否注入用户档案，未设置时使用全局配置 context.profile_injection
+	ProfileInjection *bool `json:"profile_injection,omitempty"`
+	// 摘要格式，为空时使用全局配置 summary.style
+	SummaryStyle string `json:"summary_style,omitempty"`
+	// 补全建议中日期和金额的区域格式，为空时使用全局配置 autocomplete.locale
+	Locale string `json:"locale,omitempty"`
+	// 锁定的补全语言，设置后要求模型只用该语言补全，并过滤明显不是该语言的建议
+	LockedLanguage string `json:"locked_language,omitempty"`
+	// 是否启用补全，未设置时启用；关闭后 HTTP 和 WebSocket 的补全请求都被拒绝，也不再预取
+	AutocompleteEnabled *bool `json:"autocomplete_enabled,omitempty"`
+	// 对话时区（IANA 时区名），用于上下文中的当前时间和返回消息时间，为空时使用发送者档案或 server.timezone
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// AutocompleteDisabled 对话是否通过设置关闭了补全
+func (s *ConversationSettings) AutocompleteDisabled() bool {
+	return s.AutocompleteEnabled != nil && !*s.AutocompleteEnabled
+}
+
+// 可锁定的补全语言
+const (
+	LanguageZh = "zh"
+	LanguageEn = "en"
+	LanguageJa = "ja"
+	LanguageKo = "ko"
+)
+
+// ValidLanguage 判断语言是否可锁定
+func ValidLanguage(language string) bool {
+	switch language {
+	case LanguageZh, LanguageEn, LanguageJa, LanguageKo:
+		return true
+	}
+	return false
+}
+
+// 支持的区域格式
+const (
+	LocaleZhCN = "zh-CN"
+	LocaleEnUS = "en-US"
+	LocaleEnGB = "en-GB"
+)
+
+// ValidLocale 判断区域格式是否受支持
+func ValidLocale(locale string) bool {
+	switch locale {
+	case LocaleZhCN, LocaleEnUS, LocaleEnGB:
+		return true
+	}
+	return false
+}
+
+// 摘要格式
+const (
+	// SummaryStyleBullets 要点式：每行一个要点
+	SummaryStyleBullets = "bullets"
+	// SummaryStyleNarrative 叙事式：一段连贯的概括
+	SummaryStyleNarrative = "narrative"
+	// SummaryStyleTimeline 时间线式：按时间先后列出事件
+	SummaryStyleTimeline = "timeline"
+)
+
+// ValidSummaryStyle 判断摘要格式是否合法
+func ValidSummaryStyle(style string) bool {
+	switch style {
+	case SummaryStyleBullets, SummaryStyleNarrative, SummaryStyleTimeline:
+		return true
+	}
+	return false
+}
+
+// maxSettingsMaxTokens 对话级 max_tokens 上限
+const maxSettingsMaxTokens = 8192
+
+// 关注关键词的数量和长度上限
+const (
+	maxAlertKeywords     = 20
+	maxAlertKeywordChars = 32
+)
+
+// Validate 校验设置取值范围
+func (s *ConversationSettings) Validate() error {
+	if s.Temperature != nil && (*s.Temperature < 0 || *s.Temperature > 2) {
+		return fmt.Errorf("temperature 必须在 0 到 2 之间")
+	}
+	if s.MaxTokens != nil && (*s.MaxTokens <= 0 || *s.MaxTokens > maxSettingsMaxTokens) {
+		return fmt.Errorf("max_tokens 必须在 1 到 %d 之间", maxSettingsMaxTokens)
+	}
+	if s.TopP != nil && (*s.TopP <= 0 || *s.TopP > 1) {
+		return fmt.Errorf("top_p 必须在 0 到 1 之间（不含0）")
+	}
+	if len(s.AlertKeywords) > maxAlertKeywords {
+		return fmt.Errorf("alert_keywords 最多 %d 个", maxAlertKeywords)
+	}
+	for _, keyword := range s.AlertKeywords {
+		if strings.TrimSpace(keyword) == "" || len([]rune(keyword)) > maxAlertKeywordChars {
+			return fmt.Errorf("alert_keywords 不能为空且每个不超过 %d 个字符", maxAlertKeywordChars)
+		}
+	}
+	if s.SummaryStyle != "" && !ValidSummaryStyle(s.SummaryStyle) {
+		return fmt.Errorf("summary_style 不支持: %s", s.SummaryStyle)
+	}
+	if s.Locale != "" && !ValidLocale(s.Locale) {
+		return fmt.Errorf("locale 不支持: %s（可选 zh-CN、en-US、en-GB）", s.Locale)
+	}
+	if s.LockedLanguage != "" && !ValidLanguage(s.LockedLanguage) {
+		return fmt.Errorf("locked_language 不支持: %s（可选 zh、en、ja、ko）", s.LockedLanguage)
+	}
+	if err := ValidateTimezone(s.Timezone); err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetSettings 解析对话级设置，未设置时返回空设置
+func (c *Conversation) GetSettings() (*ConversationSettings, error) {
+	settings := &ConversationSettings{}
+	if c.Settings == "" || c.Settings == "{}" {
+		return settings, nil
+	}
+	if err := json.Unmarshal([]byte(c.Settings), settings); err != nil {
+		return settings, fmt.Errorf("解析对话设置失败: %w", err)
+	}
+	return settings, nil
+}
+
+// SetSettings 校验并保存对话级设置
+func (c *Conversation) SetSettings(settings *ConversationSettings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return fmt.Errorf("序列化对话设置失败: %w", err)
+	}
+	c.Settings = string(data)
+	return nil
+}
@@ -0,0 +1,57 @@
+package models
+
+import "testing"
+
+// TestConversationSettingsValidateRanges 生成参数超出范围时拒绝保存，合法值序列化后可原样读回
+func TestConversationSettingsValidateRanges(t *testing.T) {
+	float := func(v float64) *float64 { return &v }
+	integer := func(v int) *int { return &v }
+
+	invalid := []ConversationSettings{
+		{Temperature: float(-0.1)},
+		{Temperature: float(2.1)},
+		{MaxTokens: integer(0)},
+		{MaxTokens: integer(maxSettingsMaxTokens + 1)},
+		{TopP: float(0)},
+		{TopP: float(1.5)},
+	}
+	for _, settings := range invalid {
+		var conversation Conversation
+		if err := conversation.SetSettings(&settings); err == nil {
+			t.Errorf("设置 %+v 应校验失败", settings)
+		}
+		if conversation.Settings != "" {
+			t.Errorf("校验失败时不应写入设置: %s", conversation.Settings)
+		}
+	}
+
+	var conversation Conversation
+	if err := conversation.SetSettings(&ConversationSettings{Temperature: float(0), MaxTokens: integer(256), TopP: float(1)}); err != nil {
+		t.Fatalf("合法设置校验失败: %v", err)
+	}
+	settings, err := conversation.GetSettings()
+	if err != nil {
+		t.Fatalf("读取设置失败: %v", err)
+	}
+	if settings.Temperature == nil || *settings.Temperature != 0 || *settings.MaxTokens != 256 || *settings.TopP != 1 {
+		t.Errorf("读回的设置为 %+v", settings)
+	}
+}
+
+// TestConversationSettingsAutocompleteDisabled 未设置或设为 true 时启用补全，只有显式设为 false 才关闭
+func TestConversationSettingsAutocompleteDisabled(t *testing.T) {
+	enabled, disabled := true, false
+	cases := []struct {
+		settings ConversationSettings
+		want     bool
+	}{
+		{ConversationSettings{}, false},
+		{ConversationSettings{AutocompleteEnabled: &enabled}, false},
+		{ConversationSettings{AutocompleteEnabled: &disabled}, true},
+	}
+	for _, c := range cases {
+		if got := c.settings.AutocompleteDisabled(); got != c.want {
+			t.Errorf("%+v 的 AutocompleteDisabled() = %v，期望 %v", c.settings, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"ChatRecommend/internal/encryption"
+	"gorm.io/gorm"
+)
+
+// ConversationSnapshot 对话快照模型，用于批量操作（导入、合并）出错后整体回退
+//
+// 快照按对话标识（字符串 conversation_id）关联而不是数据库ID，
+// 这样对话被合并删除后仍可以从快照重新创建。
+type ConversationSnapshot struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// 对话标识
+	ConversationID string `gorm:"index;not null" json:"conversation_id"`
+	// 快照说明（如"合并前自动快照"）
+	Label string `json:"label"`
+	// 快照时的参与者列表和对话设置
+	Participants string `gorm:"type:text" json:"-"`
+	Settings     string `gorm:"type:text" json:"-"`
+	// 快照中的消息数量
+	MessageCount int `json:"message_count"`
+	// 消息集合（JSON格式存储，见 SnapshotMessage），启用加密时整体加密
+	Data string `gorm:"type:text;not null" json:"-"`
+}
+
+// SnapshotMessage 快照中保存的消息
+type SnapshotMessage struct {
+	SenderID      string     `json:"sender_id"`
+	Content       string     `json:"content"`
+	MessageType   string     `json:"message_type"`
+	Sequence      int64      `json:"sequence"`
+	CreatedAt     time.Time  `json:"created_at"`
+	PinnedAt      *time.Time `json:"pinned_at,omitempty"`
+	ContentFormat string     `json:"content_format,omitempty"`
+}
+
+// BeforeSave 保存前加密快照数据
+func (s *ConversationSnapshot) BeforeSave(tx *gorm.DB) error {
+	if contentCipher == nil {
+		return nil
+	}
+
+	encrypted, err := contentCipher.Encrypt(s.Data)
+	if err != nil {
+		return fmt.Errorf("加密快照数据失败: %w", err)
+	}
+	s.Data = encrypted
+	return nil
+}
+
+// AfterSave 保存后还原明文，保证调用方继续使用（或再次保存）的是明文
+func (s *ConversationSnapshot) AfterSave(tx *gorm.DB) error {
+	return s.AfterFind(tx)
+}
+
+// AfterFind 查询后解密快照数据
+func (s *ConversationSnapshot) AfterFind(tx *gorm.DB) error {
+	if contentCipher == nil || !encryption.IsEncrypted(s.Data) {
+		return nil
+	}
+
+	plaintext, err := contentCipher.Decrypt(s.Data)
+	if err != nil {
+		return fmt.Errorf("解密快照数据失败: %w", err)
+	}
+	s.Data = plaintext
+	return nil
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// ConversationStat 对话统计（增量维护，统计接口直接读取，避免全表扫描）
+type ConversationStat struct {
+	ID        uint      `gorm:"primarykey" json:"-"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// 所属对话ID
+	ConversationID uint `gorm:"uniqueIndex;not null" json:"-"`
+	// 消息总数（不含草稿）
+	MessageCount int64 `gorm:"not null;default:0" json:"message_count"`
+	// 最后活跃时间
+	LastActiveAt time.Time `json:"last_active_at"`
+}
+
+// ConversationSenderStat 对话中每个发送者的统计
+type ConversationSenderStat struct {
+	ID        uint      `gorm:"primarykey" json:"-"`
+	UpdatedAt time.Time `json:"-"`
+
+	// 所属对话ID
+	ConversationID uint `gorm:"uniqueIndex:idx_sender_stat;not null" json:"-"`
+	// 发送者ID
+	SenderID string `gorm:"uniqueIndex:idx_sender_stat;not null" json:"sender_id"`
+	// 该发送者的消息数
+	MessageCount int64 `gorm:"not null;default:0" json:"message_count"`
+	// 该发送者最后发言时间
+	LastActiveAt time.Time `json:"last_active_at"`
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"ChatRecommend/internal/textutil"
+)
+
+// 标签数量、标签长度和备注长度上限
+const (
+	MaxConversationTags = 20
+	maxTagChars         = 32
+	MaxNoteChars        = 2000
+)
+
+// NormalizeTags 归一化标签（去除首尾空白、统一大小写和全半角）并按首次出现顺序去重，空标签忽略
+func NormalizeTags(tags []string) ([]string, error) {
+	result := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = textutil.NormalizeTag(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len([]rune(tag)) > maxTagChars {
+			return nil, fmt.Errorf("标签不能超过 %d 个字符", maxTagChars)
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	if len(result) > MaxConversationTags {
+		return nil, fmt.Errorf("标签最多 %d 个", MaxConversationTags)
+	}
+	return result, nil
+}
+
+// GetTags 解析对话标签，未设置时返回空列表
+func (c *Conversation) GetTags() ([]string, error) {
+	tags := []string{}
+	if c.Tags == "" {
+		return tags, nil
+	}
+	if err := json.Unmarshal([]byte(c.Tags), &tags); err != nil {
+		return []string{}, fmt.Errorf("解析对话标签失败: %w", err)
+	}
+	return tags, nil
+}
+
+// SetTags 归一化去重后保存对话标签
+func (c *Conversation) SetTags(tags []string) error {
+	normalized, err := NormalizeTags(tags)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(normalized)
+	if err != nil {
+		return fmt.Errorf("序列化对话标签失败: %w", err)
+	}
+	c.Tags = string(data)
+	return nil
+}
+
+// TagPattern 按标签筛选对话时使用的 LIKE 模式（匹配 JSON 数组中的完整元素，需配合 ESCAPE '\'）
+func TagPattern(tag string) string {
+	data, _ := json.Marshal(textutil.NormalizeTag(tag))
+	return "%" + textutil.EscapeLike(string(data)) + "%"
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ValidateTimezone 校验 IANA 时区名（如 Asia/Shanghai、America/New_York），空字符串表示未设置
+func ValidateTimezone(name string) error {
+	if name == "" {
+		return nil
+	}
+	// time.LoadLocation 会把 "Local" 解析为服务器时区，不接受
+	if strings.EqualFold(name, "Local") {
+		return fmt.Errorf("timezone 不支持: %s", name)
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return fmt.Errorf("timezone 不支持: %s", name)
+	}
+	return nil
+}
+
+// LoadTimezone 解析时区名，未设置或无效时返回 fallback
+func LoadTimezone(name string, fallback *time.Location) *time.Location {
+	if name == "" || ValidateTimezone(name) != nil {
+		return fallback
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return fallback
+	}
+	return loc
+}
+
+// ToMessageDTOsIn 将消息列表转换为精简结构，时间字段转换到指定时区（JSON 中为带该时区偏移的 RFC3339）
+func ToMessageDTOsIn(messages []Message, loc *time.Location) []MessageDTO {
+	dtos := ToMessageDTOs(messages)
+	if loc == nil {
+		return dtos
+	}
+	for i := range dtos {
+		dtos[i].CreatedAt = dtos[i].CreatedAt.In(loc)
+		dtos[i].EditedAt = timeIn(dtos[i].EditedAt, loc)
+		dtos[i].PinnedAt = timeIn(dtos[i].PinnedAt, loc)
+	}
+	return dtos
+}
+
+// MessagesIn 把消息的时间字段就地转换到指定时区
+func MessagesIn(messages []Message, loc *time.Location) {
+	if loc == nil {
+		return
+	}
+	for i := range messages {
+		messages[i].CreatedAt = messages[i].CreatedAt.In(loc)
+		messages[i].UpdatedAt = messages[i].UpdatedAt.In(loc)
+		messages[i].EditedAt = timeIn(messages[i].EditedAt, loc)
+		messages[i].PinnedAt = timeIn(messages[i].PinnedAt, loc)
+	}
+}
+
+// timeIn 把可选时间转换到指定时区（返回新的指针，不修改原值）
+func timeIn(t *time.Time, loc *time.Location) *time.Time {
+	if t == nil {
+		return nil
+	}
+	converted := t.In(loc)
+	return &converted
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+// TestValidateTimezone 接受 IANA 时区名和空字符串，拒绝未知名称和服务器本地时区 Local
+func TestValidateTimezone(t *testing.T) {
+	for _, name := range []string{"", "UTC", "Asia/Shanghai", "America/New_York"} {
+		if err := ValidateTimezone(name); err != nil {
+			t.Errorf("ValidateTimezone(%q) 返回错误: %v", name, err)
+		}
+	}
+	for _, name := range []string{"Local", "local", "Mars/Olympus", "+08:00"} {
+		if err := ValidateTimezone(name); err == nil {
+			t.Errorf("ValidateTimezone(%q) 应返回错误", name)
+		}
+	}
+
+	if loc := LoadTimezone("Asia/Tokyo", time.UTC); loc.String() != "Asia/Tokyo" {
+		t.Errorf("LoadTimezone 返回 %s", loc)
+	}
+	if loc := LoadTimezone("Mars/Olympus", time.UTC); loc != time.UTC {
+		t.Errorf("无效时区应回退，返回 %s", loc)
+	}
+	if loc := LoadTimezone("", time.UTC); loc != time.UTC {
+		t.Errorf("未设置时应回退，返回 %s", loc)
+	}
+}
+
+// TestTimezoneValidatedOnSave 对话设置和用户档案中的时区无效时拒绝保存
+func TestTimezoneValidatedOnSave(t *testing.T) {
+	var conversation Conversation
+	if err := conversation.SetSettings(&ConversationSettings{Timezone: "Mars/Olympus"}); err == nil {
+		t.Error("对话设置的无效时区应校验失败")
+	}
+	if err := conversation.SetSettings(&ConversationSettings{Timezone: "America/New_York"}); err != nil {
+		t.Errorf("合法时区校验失败: %v", err)
+	}
+
+	var profile UserProfile
+	if err := profile.SetFields(&ProfileFields{Timezone: "Local"}); err == nil {
+		t.Error("档案的无效时区应校验失败")
+	}
+}
+
+// TestToMessageDTOsIn 时间字段转换到指定时区，时刻不变；不修改原消息
+func TestToMessageDTOsIn(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("缺少时区数据: %v", err)
+	}
+	created := time.Date(2026, 10, 17, 2, 0, 0, 0, time.UTC)
+	pinned := created.Add(time.Hour)
+	messages := []Message{{Content: "早", CreatedAt: created, PinnedAt: &pinned}}
+
+	dtos := ToMessageDTOsIn(messages, tokyo)
+	if got := dtos[0].CreatedAt.Format(time.RFC3339); got != "2026-10-17T11:00:00+09:00" {
+		t.Errorf("created_at 为 %s", got)
+	}
+	if dtos[0].PinnedAt == nil || !dtos[0].PinnedAt.Equal(pinned) || dtos[0].PinnedAt.Location() != tokyo {
+		t.Errorf("pinned_at 为 %v", dtos[0].PinnedAt)
+	}
+	if messages[0].PinnedAt.Location() != time.UTC {
+		t.Error("不应修改原消息的时间")
+	}
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// LLMUsage 每个用户每天的大模型调用次数，用于每日配额
+type LLMUsage struct {
+	ID        uint      `gorm:"primarykey" json:"-"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// 发送者ID
+	SenderID string `gorm:"uniqueIndex:idx_llm_usage;not null" json:"sender_id"`
+	// 日期（YYYY-MM-DD，按 server.timezone 计算）
+	Day string `gorm:"uniqueIndex:idx_llm_usage;not null" json:"day"`
+	// 当天已调用次数
+	Count int64 `gorm:"not null;default:0" json:"count"`
+}
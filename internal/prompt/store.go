@@ -0,0 +1,129 @@
+package prompt
+
+import (
+	"fmt"
+	"sync"
+
+	"ChatRecommend/internal/models"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Store 提示词模板存储：从数据库加载各模板的激活版本并缓存在内存中，更新或停用模板时使缓存失效，
+// 下一次构建上下文或生成摘要即使用新模板。缓存只在单个服务实例内有效
+type Store struct {
+	db *gorm.DB
+
+	mu    sync.RWMutex
+	cache map[string]cachedTemplate
+	// 每次失效时递增，加载期间模板被更新时不缓存加载结果，避免缓存旧版本
+	generation uint64
+}
+
+// cachedTemplate 缓存的激活版本，found 为 false 表示数据库中没有激活版本（同样缓存，避免每次查询）
+type cachedTemplate struct {
+	content string
+	found   bool
+}
+
+// NewStore 创建提示词模板存储
+func NewStore(db *gorm.DB) *Store {
+	return &Store{db: db, cache: make(map[string]cachedTemplate)}
+}
+
+// Resolve 返回模板的激活版本内容，数据库中没有激活版本（或未配置存储）时返回 fallback（配置文件中的模板）
+func (s *Store) Resolve(name, fallback string) string {
+	if s == nil {
+		return fallback
+	}
+
+	s.mu.RLock()
+	entry, ok := s.cache[name]
+	generation := s.generation
+	s.mu.RUnlock()
+	if !ok {
+		var err error
+		entry, err = s.load(name, generation)
+		if err != nil {
+			// 查询失败时不缓存，下次重试
+			logrus.WithError(err).WithField("name", name).Warn("加载提示词模板失败，使用配置文件中的模板")
+			return fallback
+		}
+	}
+	if !entry.found {
+		return fallback
+	}
+	return entry.content
+}
+
+// load 从数据库加载模板的激活版本，加载期间没有失效时写入缓存
+func (s *Store) load(name string, generation uint64) (cachedTemplate, error) {
+	var templates []models.PromptTemplate
+	if err := s.db.Where("name = ? AND active = ?", name, true).Limit(1).Find(&templates).Error; err != nil {
+		return cachedTemplate{}, fmt.Errorf("查询提示词模板失败: %w", err)
+	}
+	entry := cachedTemplate{}
+	if len(templates) > 0 {
+		entry = cachedTemplate{content: templates[0].Content, found: true}
+	}
+
+	s.mu.Lock()
+	if s.generation == generation {
+		s.cache[name] = entry
+	}
+	s.mu.Unlock()
+	return entry, nil
+}
+
+// Update 新增模板的一个版本（版本号递增）并激活，之前的版本全部停用
+func (s *Store) Update(name, content string) (*models.PromptTemplate, error) {
+	template := &models.PromptTemplate{Name: name, Content: content, Active: true}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var latest int
+		if err := tx.Model(&models.PromptTemplate{}).Where("name = ?", name).
+			Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+			return fmt.Errorf("查询模板版本失败: %w", err)
+		}
+		if err := tx.Model(&models.PromptTemplate{}).Where("name = ? AND active = ?", name, true).
+			Update("active", false).Error; err != nil {
+			return fmt.Errorf("停用旧版本失败: %w", err)
+		}
+		template.Version = latest + 1
+		if err := tx.Create(template).Error; err != nil {
+			return fmt.Errorf("保存模板失败: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.Invalidate(name)
+	return template, nil
+}
+
+// Deactivate 停用模板的激活版本，之后回退到配置文件中的模板；返回是否有版本被停用
+func (s *Store) Deactivate(name string) (bool, error) {
+	res := s.db.Model(&models.PromptTemplate{}).Where("name = ? AND active = ?", name, true).Update("active", false)
+	if res.Error != nil {
+		return false, fmt.Errorf("停用模板失败: %w", res.Error)
+	}
+	s.Invalidate(name)
+	return res.RowsAffected > 0, nil
+}
+
+// Versions 列出模板的全部版本，新版本在前
+func (s *Store) Versions(name string) ([]models.PromptTemplate, error) {
+	var templates []models.PromptTemplate
+	if err := s.db.Where("name = ?", name).Order("version DESC").Find(&templates).Error; err != nil {
+		return nil, fmt.Errorf("查询模板版本失败: %w", err)
+	}
+	return templates, nil
+}
+
+// Invalidate 使模板的缓存失效，下次使用时重新从数据库加载
+func (s *Store) Invalidate(name string) {
+	s.mu.Lock()
+	s.generation++
+	delete(s.cache, name)
+	s.mu.Unlock()
+}
@@ -0,0 +1,75 @@
+package prompt
+
+import (
+	"testing"
+
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// 没有激活版本时使用配置文件中的模板；更新后立即生效、版本号递增，停用后回退到配置文件
+func TestStoreUpdateAndDeactivate(t *testing.T) {
+	store := NewStore(testutil.NewDB(t))
+	const fallback = "配置文件中的模板"
+
+	if got := store.Resolve(models.PromptSystemPrefix, fallback); got != fallback {
+		t.Fatalf("没有激活版本时应使用配置文件，实际 %q", got)
+	}
+
+	first, err := store.Update(models.PromptSystemPrefix, "版本一")
+	if err != nil {
+		t.Fatalf("更新模板失败: %v", err)
+	}
+	second, err := store.Update(models.PromptSystemPrefix, "版本二")
+	if err != nil {
+		t.Fatalf("更新模板失败: %v", err)
+	}
+	if first.Version != 1 || second.Version != 2 || !second.Active {
+		t.Errorf("版本号为 %d、%d，期望 1、2", first.Version, second.Version)
+	}
+	if got := store.Resolve(models.PromptSystemPrefix, fallback); got != "版本二" {
+		t.Errorf("更新后应使用最新版本，实际 %q", got)
+	}
+	if got := store.Resolve(models.PromptSummarySystemPrefix, fallback); got != fallback {
+		t.Errorf("其他模板不受影响，实际 %q", got)
+	}
+
+	versions, err := store.Versions(models.PromptSystemPrefix)
+	if err != nil || len(versions) != 2 || versions[0].Version != 2 || !versions[0].Active || versions[1].Active {
+		t.Fatalf("版本列表为 %+v, %v", versions, err)
+	}
+
+	deactivated, err := store.Deactivate(models.PromptSystemPrefix)
+	if err != nil || !deactivated {
+		t.Fatalf("停用模板失败: %v, %v", deactivated, err)
+	}
+	if got := store.Resolve(models.PromptSystemPrefix, fallback); got != fallback {
+		t.Errorf("停用后应回退到配置文件，实际 %q", got)
+	}
+	if deactivated, _ := store.Deactivate(models.PromptSystemPrefix); deactivated {
+		t.Error("没有激活版本时不应停用任何版本")
+	}
+}
+
+// 其他实例直接写入数据库的版本在缓存失效后才生效；未配置存储时使用配置文件
+func TestStoreCache(t *testing.T) {
+	db := testutil.NewDB(t)
+	store := NewStore(db)
+	store.Resolve(models.PromptSystemPrefix, "fallback")
+
+	if err := db.Create(&models.PromptTemplate{Name: models.PromptSystemPrefix, Version: 1, Content: "外部写入", Active: true}).Error; err != nil {
+		t.Fatalf("保存模板失败: %v", err)
+	}
+	if got := store.Resolve(models.PromptSystemPrefix, "fallback"); got != "fallback" {
+		t.Errorf("缓存未失效时应返回缓存结果，实际 %q", got)
+	}
+	store.Invalidate(models.PromptSystemPrefix)
+	if got := store.Resolve(models.PromptSystemPrefix, "fallback"); got != "外部写入" {
+		t.Errorf("缓存失效后应重新加载，实际 %q", got)
+	}
+
+	var nilStore *Store
+	if got := nilStore.Resolve(models.PromptSystemPrefix, "fallback"); got != "fallback" {
+		t.Errorf("未配置存储时应使用配置文件，实际 %q", got)
+	}
+}
@@ -0,0 +1,167 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// variablePattern 模板变量语法：${name}，name 可以是正则的命名分组、分组序号或外部提供的变量
+var variablePattern = regexp.MustCompile(`\$\{([\p{L}\p{N}_]+)\}`)
+
+// Rule 快捷补全规则
+type Rule struct {
+	Name      string
+	Pattern   *regexp.Regexp
+	Templates []string
+	Priority  int
+}
+
+// Matcher 规则匹配器（并发安全）
+type Matcher struct {
+	mu    sync.RWMutex
+	rules []Rule
+}
+
+// NewMatcher 根据配置创建规则匹配器
+func NewMatcher(cfgRules []config.RuleConfig) (*Matcher, error) {
+	m := &Matcher{}
+	for _, rc := range cfgRules {
+		rule, err := compile(rc.Name, rc.Pattern, rc.Templates, rc.Priority)
+		if err != nil {
+			return nil, err
+		}
+		m.rules = append(m.rules, rule)
+	}
+	m.sort()
+	return m, nil
+}
+
+// LoadFromDB 从数据库加载启用的规则并与配置中的规则合并，非法规则跳过并记录
+func (m *Matcher) LoadFromDB(db *gorm.DB) error {
+	var records []models.CompletionRule
+	if err := db.Where("enabled = ?", true).Find(&records).Error; err != nil {
+		return fmt.Errorf("查询补全规则失败: %w", err)
+	}
+
+	loaded := make([]Rule, 0, len(records))
+	for _, record := range records {
+		var templates []string
+		if err := json.Unmarshal([]byte(record.Templates), &templates); err != nil {
+			logrus.WithError(err).WithField("rule", record.Name).Warn("解析规则模板失败，已跳过")
+			continue
+		}
+		rule, err := compile(record.Name, record.Pattern, templates, record.Priority)
+		if err != nil {
+			logrus.WithError(err).WithField("rule", record.Name).Warn("编译规则失败，已跳过")
+			continue
+		}
+		loaded = append(loaded, rule)
+	}
+
+	m.mu.Lock()
+	m.rules = append(m.rules, loaded...)
+	m.sort()
+	m.mu.Unlock()
+
+	logrus.WithField("count", len(loaded)).Info("已从数据库加载补全规则")
+	return nil
+}
+
+// Match 按优先级依次匹配规则，返回第一条命中规则生成的建议
+// vars 为外部提供的模板变量；引用了缺失变量的模板会被跳过，全部模板都被跳过时继续尝试下一条规则
+func (m *Matcher) Match(input string, vars map[string]string) []string {
+	return m.MatchWith(input, func() map[string]string { return vars })
+}
+
+// MatchWith 同 Match，外部变量在有规则命中时才通过 loadVars 获取（只调用一次），没有规则命中时不产生查询开销
+func (m *Matcher) MatchWith(input string, loadVars func() map[string]string) []string {
+	if m == nil {
+		return nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var vars map[string]string
+	loaded := false
+	for _, rule := range m.rules {
+		match := rule.Pattern.FindStringSubmatch(input)
+		if match == nil {
+			continue
+		}
+		if !loaded {
+			vars, loaded = loadVars(), true
+		}
+
+		values := make(map[string]string, len(vars)+len(match))
+		for k, v := range vars {
+			values[k] = v
+		}
+		values["input"] = input
+		for i, name := range rule.Pattern.SubexpNames() {
+			values[fmt.Sprint(i)] = match[i]
+			if name != "" {
+				values[name] = match[i]
+			}
+		}
+
+		suggestions := make([]string, 0, len(rule.Templates))
+		for _, tpl := range rule.Templates {
+			if text, ok := expand(tpl, values); ok {
+				suggestions = append(suggestions, text)
+			}
+		}
+		if len(suggestions) > 0 {
+			logrus.WithField("rule", rule.Name).Debug("命中快捷补全规则")
+			return suggestions
+		}
+	}
+
+	return nil
+}
+
+// compile 编译规则
+func compile(name, pattern string, templates []string, priority int) (Rule, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return Rule{}, fmt.Errorf("规则 %s 的正则无效: %w", name, err)
+	}
+	if len(templates) == 0 {
+		return Rule{}, fmt.Errorf("规则 %s 缺少补全模板", name)
+	}
+	return Rule{
+		Name:      name,
+		Pattern:   re,
+		Templates: templates,
+		Priority:  priority,
+	}, nil
+}
+
+// sort 按优先级从高到低排序（调用方需持有写锁或处于初始化阶段）
+func (m *Matcher) sort() {
+	sort.SliceStable(m.rules, func(i, j int) bool {
+		return m.rules[i].Priority > m.rules[j].Priority
+	})
+}
+
+// expand 展开模板变量，存在缺失或为空的变量时返回false
+func expand(tpl string, values map[string]string) (string, bool) {
+	ok := true
+	text := variablePattern.ReplaceAllStringFunc(tpl, func(ref string) string {
+		name := variablePattern.FindStringSubmatch(ref)[1]
+		value, exists := values[name]
+		if !exists || value == "" {
+			ok = false
+		}
+		return value
+	})
+	return text, ok
+}
@@ -0,0 +1,164 @@
+package sentiment
+
+import (
+	"strings"
+)
+
+// Emotion 情绪类别
+type Emotion string
+
+// 支持的情绪
+const (
+	Neutral Emotion = "neutral"
+	Angry   Emotion = "angry"
+	Sad     Emotion = "sad"
+	Happy   Emotion = "happy"
+)
+
+// Result 情绪分析结果
+type Result struct {
+	Emotion Emotion `json:"emotion"`
+	// 该情绪的加权得分，neutral 时为各情绪中的最高分
+	Score float64 `json:"score"`
+}
+
+// lexicon 情绪词表（词 -> 权重），按子串匹配
+var lexicon = map[Emotion]map[string]float64{
+	Angry: {
+		"生气": 1, "气死": 1.5, "烦死": 1.5, "滚": 1.5, "闭嘴": 1.5, "受够": 1.5, "凭什么": 1, "有病": 1.5,
+		"讨厌": 1, "过分": 1, "别烦": 1, "无语": 0.5, "火大": 1.5, "混蛋": 1.5, "什么意思": 0.5,
+		"😡": 1.5, "😠": 1.5, "🤬": 1.5, "💢": 1,
+	},
+	Sad: {
+		"难过": 1.5, "伤心": 1.5, "哭": 1, "想哭": 1.5, "失望": 1, "好累": 1, "心累": 1.5, "崩溃": 1.5,
+		"不开心": 1.5, "孤独": 1, "委屈": 1.5, "分手": 1, "去世": 1.5, "失眠": 0.5, "唉": 0.5, "郁闷": 1,
+		"😢": 1.5, "😭": 1.5, "😞": 1, "😔": 1, "💔": 1.5,
+	},
+	Happy: {
+		"开心": 1.5, "高兴": 1.5, "太好了": 1.5, "哈哈": 1, "棒": 1, "耶": 1, "幸福": 1.5, "喜欢": 0.5,
+		"谢谢": 0.5, "期待": 1, "激动": 1, "恭喜": 1.5, "好耶": 1.5, "爱你": 1,
+		"😄": 1, "😂": 1, "😊": 1, "🎉": 1.5, "❤": 0.5, "😁": 1,
+	},
+}
+
+// 否定词出现在情绪词前两个字符内时该次命中不计分（如"不难过"）
+var negations = []string{"不", "没", "别", "无"}
+
+// 判定为非中性的最低得分
+const minScore = 1.0
+
+// 越早的消息权重越低：最新一条权重为1，往前每条乘以 recencyDecay
+const recencyDecay = 0.7
+
+// Analyze 分析一组按时间正序排列的消息文本的整体情绪，越新的消息权重越高。
+// 最高得分不足 minScore 时判定为中性；得分相同时按愤怒、悲伤、开心的顺序优先
+func Analyze(texts []string) Result {
+	scores := make(map[Emotion]float64)
+	weight := 1.0
+	for i := len(texts) - 1; i >= 0; i-- {
+		for emotion, score := range scoreText(texts[i]) {
+			scores[emotion] += weight * score
+		}
+		weight *= recencyDecay
+	}
+
+	best := Result{Emotion: Neutral}
+	for _, emotion := range []Emotion{Angry, Sad, Happy} {
+		if scores[emotion] > best.Score {
+			best = Result{Emotion: emotion, Score: scores[emotion]}
+		}
+	}
+	if best.Score < minScore {
+		best.Emotion = Neutral
+	}
+	return best
+}
+
+// scoreText 单条文本在各情绪上的得分，连续感叹号加强愤怒
+func scoreText(text string) map[Emotion]float64 {
+	scores := make(map[Emotion]float64)
+	for emotion, words := range lexicon {
+		for word, weight := range words {
+			for offset := 0; ; {
+				idx := strings.Index(text[offset:], word)
+				if idx < 0 {
+					break
+				}
+				pos := offset + idx
+				if !negated(text[:pos]) {
+					scores[emotion] += weight
+				}
+				offset = pos + len(word)
+			}
+		}
+	}
+	if scores[Angry] > 0 && (strings.Contains(text, "！！") || strings.Contains(text, "!!")) {
+		scores[Angry] += 0.5
+	}
+	return scores
+}
+
+// negated 判断情绪词前面两个字符内是否有否定词
+func negated(prefix string) bool {
+	runes := []rune(prefix)
+	if len(runes) > 2 {
+		runes = runes[len(runes)-2:]
+	}
+	tail := string(runes)
+	for _, neg := range negations {
+		if strings.Contains(tail, neg) {
+			return true
+		}
+	}
+	return false
+}
+
+// Segment 一段消息的情绪统计，用于情绪走势
+type Segment struct {
+	// 各情绪按消息数平均的得分（不做时间衰减）
+	Angry float64 `json:"angry"`
+	Sad   float64 `json:"sad"`
+	Happy float64 `json:"happy"`
+	// 情绪极性：开心得分减去愤怒和悲伤得分，正数偏积极，负数偏消极
+	Polarity float64 `json:"polarity"`
+	// 主导情绪：平均得分最高的情绪，不足 minSegmentScore 时为中性
+	Emotion Emotion `json:"emotion"`
+}
+
+// 判定一段消息有主导情绪的最低平均得分（大约每三条消息出现一次明显的情绪词）
+const minSegmentScore = 0.3
+
+// AnalyzeSegment 统计一段消息的情绪，每条消息权重相同；得分相同时按愤怒、悲伤、开心的顺序优先
+func AnalyzeSegment(texts []string) Segment {
+	var segment Segment
+	if len(texts) == 0 {
+		segment.Emotion = Neutral
+		return segment
+	}
+	for _, text := range texts {
+		scores := scoreText(text)
+		segment.Angry += scores[Angry]
+		segment.Sad += scores[Sad]
+		segment.Happy += scores[Happy]
+	}
+	n := float64(len(texts))
+	segment.Angry /= n
+	segment.Sad /= n
+	segment.Happy /= n
+	segment.Polarity = segment.Happy - segment.Angry - segment.Sad
+
+	segment.Emotion = Neutral
+	best := 0.0
+	for _, candidate := range []struct {
+		emotion Emotion
+		score   float64
+	}{{Angry, segment.Angry}, {Sad, segment.Sad}, {Happy, segment.Happy}} {
+		if candidate.score > best {
+			segment.Emotion, best = candidate.emotion, candidate.score
+		}
+	}
+	if best < minSegmentScore {
+		segment.Emotion = Neutral
+	}
+	return segment
+}
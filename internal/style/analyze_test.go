@@ -0,0 +1,24 @@
+package style
+
+import (
+	"strings"
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+)
+
+// 超长消息只取首尾采样参与分析，统计结果不被单条长文主导
+func TestAnalyzeStyleSamplesLongMessage(t *testing.T) {
+	m := NewManager(nil, &config.StyleConfig{MaxAnalyzeChars: 200}, nil)
+	long := strings.Repeat("好的好的好的好的好的好的好的好的好的好的好的好的。", 50000)
+
+	features := m.analyzeStyle([]models.Message{{Content: long}, {Content: "嗯嗯。"}})
+	if features.SampleCount != 2 {
+		t.Fatalf("样本数为 %d，期望 2", features.SampleCount)
+	}
+	// 采样后长消息最多贡献 200 字，句号数量远小于原文
+	if count := features.Punctuation["。"]; count == 0 || count > 20 {
+		t.Fatalf("句号计数为 %d，长消息未被采样", count)
+	}
+}
@@ -0,0 +1,75 @@
+package style
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultCacheTTL 未配置时风格特征缓存的有效期
+const defaultCacheTTL = 5 * time.Minute
+
+// featuresKey 风格特征缓存键
+type featuresKey struct {
+	conversationID uint
+	userID         string
+}
+
+// cachedFeatures 缓存的风格特征及其加载时间
+type cachedFeatures struct {
+	features *StyleFeatures
+	loadedAt time.Time
+}
+
+// featuresCache 已解析风格特征的并发安全缓存。条目超过 ttl 后视为未命中（兜底其他进程对风格的更新）；
+// version 在任何条目失效时递增，从数据库读取期间有失效发生时不写入读取结果，避免把失效前读到的旧特征写回缓存
+type featuresCache struct {
+	mu      sync.RWMutex
+	ttl     time.Duration
+	items   map[featuresKey]cachedFeatures
+	version uint64
+}
+
+// newFeaturesCache 创建风格特征缓存，ttl<=0 时使用默认值
+func newFeaturesCache(ttl time.Duration) *featuresCache {
+	if ttl <= 0 {
+		ttl = defaultCacheTTL
+	}
+	return &featuresCache{
+		ttl:   ttl,
+		items: make(map[featuresKey]cachedFeatures),
+	}
+}
+
+// get 读取缓存（缓存中的特征只读，不应被修改）。未命中时返回当前版本，从数据库读取后用该版本调用 set
+func (c *featuresCache) get(conversationID uint, userID string) (*StyleFeatures, uint64, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	item, ok := c.items[featuresKey{conversationID, userID}]
+	if !ok || time.Since(item.loadedAt) > c.ttl {
+		return nil, c.version, false
+	}
+	return item.features, c.version, true
+}
+
+// set 写入缓存，version 与读取前 get 返回的版本不一致（期间有失效）时不写入
+func (c *featuresCache) set(conversationID uint, userID string, features *StyleFeatures, version uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.version != version {
+		return
+	}
+	c.items[featuresKey{conversationID, userID}] = cachedFeatures{features: features, loadedAt: time.Now()}
+}
+
+// invalidate 使缓存失效
+func (c *featuresCache) invalidate(conversationID uint, userID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.version++
+	delete(c.items, featuresKey{conversationID, userID})
+}
+
+// InvalidateCache 使某个用户在对话中的风格特征缓存失效
+func (m *Manager) InvalidateCache(conversationID uint, userID string) {
+	m.cache.invalidate(conversationID, userID)
+}
@@ -0,0 +1,100 @@
+package style
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+	"gorm.io/gorm"
+)
+
+func TestFeaturesCacheDropsStaleSetAfterInvalidate(t *testing.T) {
+	cache := newFeaturesCache(time.Minute)
+
+	_, version, ok := cache.get(1, "alice")
+	if ok {
+		t.Fatal("空缓存不应命中")
+	}
+	// 读库期间风格被更新
+	cache.invalidate(1, "alice")
+	cache.set(1, "alice", &StyleFeatures{SentenceLength: 1}, version)
+	if _, _, ok := cache.get(1, "alice"); ok {
+		t.Fatal("失效前读到的特征不应写回缓存")
+	}
+
+	_, version, _ = cache.get(1, "alice")
+	cache.set(1, "alice", &StyleFeatures{SentenceLength: 2}, version)
+	features, _, ok := cache.get(1, "alice")
+	if !ok || features.SentenceLength != 2 {
+		t.Fatalf("期望命中新特征，实际 %+v, %v", features, ok)
+	}
+}
+
+func TestFeaturesCacheExpires(t *testing.T) {
+	cache := newFeaturesCache(time.Minute)
+	_, version, _ := cache.get(1, "alice")
+	cache.set(1, "alice", &StyleFeatures{}, version)
+
+	key := featuresKey{1, "alice"}
+	item := cache.items[key]
+	item.loadedAt = time.Now().Add(-2 * time.Minute)
+	cache.items[key] = item
+
+	if _, _, ok := cache.get(1, "alice"); ok {
+		t.Fatal("过期条目不应命中")
+	}
+}
+
+// 重复读取同一用户的风格特征命中缓存不再查库，UpdateStyle 后重新读取新特征
+func TestGetStyleFeaturesCacheHit(t *testing.T) {
+	db := testutil.NewDB(t)
+	var queries atomic.Int64
+	if err := db.Callback().Query().After("gorm:query").Register("test:count_style_queries", func(tx *gorm.DB) {
+		if tx.Statement.Table == "styles" {
+			queries.Add(1)
+		}
+	}); err != nil {
+		t.Fatalf("注册查询回调失败: %v", err)
+	}
+	m := NewManager(db, &config.StyleConfig{Enabled: true}, nil)
+	conversation := testutil.CreateConversation(t, db, "conv-style-cache")
+	messages := []models.Message{{SenderID: "alice", Content: "好的好的。"}, {SenderID: "alice", Content: "没问题。"}}
+	if err := m.UpdateStyle(conversation.ID, "alice", messages); err != nil {
+		t.Fatalf("更新风格失败: %v", err)
+	}
+
+	first, err := m.GetStyleFeatures(conversation.ID, "alice")
+	if err != nil {
+		t.Fatalf("读取风格失败: %v", err)
+	}
+	loaded := queries.Load()
+	if loaded == 0 {
+		t.Fatal("首次读取应查询数据库")
+	}
+	for i := 0; i < 3; i++ {
+		features, err := m.GetStyleFeatures(conversation.ID, "alice")
+		if err != nil {
+			t.Fatalf("读取风格失败: %v", err)
+		}
+		if features != first {
+			t.Fatal("重复读取应返回缓存的特征")
+		}
+	}
+	if n := queries.Load(); n != loaded {
+		t.Fatalf("命中缓存时不应查询数据库，多查询了 %d 次", n-loaded)
+	}
+
+	if err := m.UpdateStyle(conversation.ID, "alice", append(messages, models.Message{SenderID: "alice", Content: "哈哈哈哈哈哈哈哈哈哈哈哈"})); err != nil {
+		t.Fatalf("更新风格失败: %v", err)
+	}
+	updated, err := m.GetStyleFeatures(conversation.ID, "alice")
+	if err != nil {
+		t.Fatalf("读取风格失败: %v", err)
+	}
+	if updated == first || updated.SampleCount != 3 {
+		t.Fatalf("更新后应读取新特征，样本数为 %d", updated.SampleCount)
+	}
+}
@@ -0,0 +1,46 @@
+package style
+
+// 风格维度
+const (
+	DimensionTone           = "tone"
+	DimensionSentenceLength = "sentence_length"
+	DimensionCommonPhrases  = "common_phrases"
+	DimensionRhythm         = "rhythm"
+)
+
+// 各维度置信度达到0.5所需的样本数：语气、句长较稳定，常用短语需要更多消息，节奏按连续消息对计
+var halfConfidenceSamples = map[string]int{
+	DimensionTone:           10,
+	DimensionSentenceLength: 10,
+	DimensionCommonPhrases:  20,
+	DimensionRhythm:         5,
+}
+
+const (
+	// lowConfidence 低于该置信度的维度不注入提示词
+	lowConfidence = 0.3
+	// highConfidence 低于该置信度时提示词使用"初步观察"的措辞
+	highConfidence = 0.6
+)
+
+// sampleConfidence 按样本量计算置信度：n/(n+k)，样本为0时为0，随样本增加趋近于1
+func sampleConfidence(dimension string, samples int) float64 {
+	if samples <= 0 {
+		return 0
+	}
+	k := halfConfidenceSamples[dimension]
+	return float64(samples) / float64(samples+k)
+}
+
+// confidence 获取维度置信度，旧版本保存的特征没有置信度信息时视为可信
+func (f *StyleFeatures) confidence(dimension string) float64 {
+	if f.Confidence == nil {
+		return 1
+	}
+	return f.Confidence[dimension]
+}
+
+// tentative 样本不足时整体措辞需要弱化
+func (f *StyleFeatures) tentative() bool {
+	return f.Confidence != nil && f.confidence(DimensionTone) < highConfidence
+}
@@ -0,0 +1,61 @@
+package style
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+)
+
+// 置信度随样本量单调增加并趋近于1，达到 halfConfidenceSamples 时为0.5
+func TestSampleConfidenceGrowsWithSamples(t *testing.T) {
+	if got := sampleConfidence(DimensionTone, 0); got != 0 {
+		t.Errorf("无样本时置信度为 %v，期望 0", got)
+	}
+	if got := sampleConfidence(DimensionTone, halfConfidenceSamples[DimensionTone]); got != 0.5 {
+		t.Errorf("样本数为 k 时置信度为 %v，期望 0.5", got)
+	}
+	prev := 0.0
+	for _, n := range []int{1, 5, 20, 100, 1000} {
+		got := sampleConfidence(DimensionCommonPhrases, n)
+		if got <= prev || got >= 1 {
+			t.Fatalf("样本数 %d 的置信度为 %v，应大于 %v 且小于1", n, got, prev)
+		}
+		prev = got
+	}
+}
+
+// 样本很少时提示词使用初步观察的措辞并省略低置信维度，样本充足时正常注入
+func TestStylePromptHedgesLowConfidence(t *testing.T) {
+	m := NewManager(nil, &config.StyleConfig{DescriptionLang: "zh"}, nil)
+	messages := func(n int) []models.Message {
+		list := make([]models.Message, n)
+		for i := range list {
+			list[i] = models.Message{Content: fmt.Sprintf("周末 一起 打球 吧 第%d次", i)}
+		}
+		return list
+	}
+
+	few := m.analyzeStyle(messages(5))
+	if few.SampleCount != 5 || few.Confidence[DimensionTone] >= highConfidence {
+		t.Fatalf("5条消息的样本数为 %d，语气置信度为 %v", few.SampleCount, few.Confidence[DimensionTone])
+	}
+	// analyzeStyle 不提取常用短语，这里补上以检查低置信维度被省略
+	few.CommonPhrases = []string{"一起打球"}
+	prompt := m.promptFromFeatures(few)
+	if !strings.HasPrefix(prompt, "初步观察到的用户语言风格") || !strings.Contains(prompt, "语气：") {
+		t.Errorf("样本不足时应弱化措辞: %q", prompt)
+	}
+	if strings.Contains(prompt, "常用短语") {
+		t.Errorf("低置信的常用短语维度不应注入: %q", prompt)
+	}
+
+	many := m.analyzeStyle(messages(60))
+	many.CommonPhrases = []string{"一起打球"}
+	prompt = m.promptFromFeatures(many)
+	if !strings.HasPrefix(prompt, "用户的语言风格特征：") || !strings.Contains(prompt, "常用短语：一起打球") {
+		t.Errorf("样本充足时应正常注入各维度: %q", prompt)
+	}
+}
@@ -0,0 +1,77 @@
+package style
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// defaultStopwords 内置的小型中文停用词表（单字词在分词时已被过滤，这里只列多字词）
+var defaultStopwords = []string{
+	"我们", "你们", "他们", "她们", "它们", "咱们", "自己",
+	"这个", "那个", "这些", "那些", "这样", "那样", "这里", "那里",
+	"什么", "怎么", "为什么", "怎么样", "哪里", "哪个",
+	"没有", "可以", "就是", "还是", "或者", "而且", "然后",
+	"因为", "所以", "但是", "不过", "如果", "虽然", "已经",
+	"一个", "一下", "一些", "不是", "只是", "还有", "时候",
+}
+
+// loadWordList 从文件加载词表，每行一个词，忽略空行和以#开头的注释行
+func loadWordList(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开词表文件失败: %w", err)
+	}
+	defer file.Close()
+
+	words := make([]string, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		word := strings.TrimSpace(scanner.Text())
+		if word == "" || strings.HasPrefix(word, "#") {
+			continue
+		}
+		words = append(words, word)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取词表文件失败: %w", err)
+	}
+
+	return words, nil
+}
+
+// loadStopwords 加载停用词表，未配置或加载失败时回退到内置停用词表
+func loadStopwords(path string) map[string]bool {
+	words := defaultStopwords
+	if path != "" {
+		loaded, err := loadWordList(path)
+		if err != nil {
+			logrus.WithError(err).WithField("path", path).Warn("加载停用词表失败，使用内置停用词表")
+		} else {
+			words = loaded
+		}
+	}
+
+	stopwords := make(map[string]bool, len(words))
+	for _, word := range words {
+		stopwords[word] = true
+	}
+	return stopwords
+}
+
+// loadUserDict 加载自定义词典（专有名词等），未配置或加载失败时返回空
+func loadUserDict(path string) []string {
+	if path == "" {
+		return nil
+	}
+
+	words, err := loadWordList(path)
+	if err != nil {
+		logrus.WithError(err).WithField("path", path).Warn("加载自定义词典失败，忽略")
+		return nil
+	}
+	return words
+}
@@ -0,0 +1,65 @@
+package style
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+)
+
+// vocabulary 提取常用词汇（测试辅助）
+func vocabulary(t *testing.T, cfg *config.StyleConfig, contents ...string) map[string]int {
+	t.Helper()
+	m := NewManager(nil, cfg, nil)
+	extractor := &vocabularyExtractor{stopwords: m.stopwords, userDict: m.userDict}
+	messages := make([]models.Message, len(contents))
+	for i, content := range contents {
+		messages[i] = models.Message{Content: content}
+	}
+	return extractor.Extract(messages)[featureVocabulary].(map[string]int)
+}
+
+func writeWordList(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "words.txt")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("写入词表失败: %v", err)
+	}
+	return path
+}
+
+func TestVocabularyFiltersStopwords(t *testing.T) {
+	// 未配置时使用内置停用词表
+	words := vocabulary(t, &config.StyleConfig{}, "我们 火锅 然后 火锅")
+	if words["我们"] != 0 || words["然后"] != 0 {
+		t.Errorf("内置停用词应被过滤: %v", words)
+	}
+	if words["火锅"] != 2 {
+		t.Errorf("火锅 词频为 %d，期望 2", words["火锅"])
+	}
+
+	// 配置的停用词表替换内置表
+	path := writeWordList(t, "# 注释\n火锅\n\n")
+	words = vocabulary(t, &config.StyleConfig{StopwordsPath: path}, "我们 火锅 烧烤")
+	if words["火锅"] != 0 {
+		t.Errorf("自定义停用词应被过滤: %v", words)
+	}
+	if words["我们"] != 1 || words["烧烤"] != 1 {
+		t.Errorf("不在自定义停用词表中的词应保留: %v", words)
+	}
+}
+
+// 停用词表加载失败时回退到内置表；自定义词典中的专有词按子串统计
+func TestVocabularyFallbackAndUserDict(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.txt")
+	dict := writeWordList(t, "海底捞\n")
+	words := vocabulary(t, &config.StyleConfig{StopwordsPath: missing, UserDictPath: dict}, "我们去海底捞吧", "然后 海底捞")
+	if words["然后"] != 0 {
+		t.Errorf("加载失败时应回退到内置停用词表: %v", words)
+	}
+	if words["海底捞"] != 2 {
+		t.Errorf("专有词 海底捞 词频为 %d，期望 2", words["海底捞"])
+	}
+}
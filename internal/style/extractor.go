@@ -0,0 +1,216 @@
+package style
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"ChatRecommend/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// FeatureExtractor 风格特征提取器，从一组消息中提取若干特征。
+// 返回值的键为特征名：内置特征名（vocabulary、sentence_length、emoji_usage、punctuation）
+// 写入 StyleFeatures 对应字段，其他键写入 StyleFeatures.Extra
+type FeatureExtractor interface {
+	Name() string
+	Extract(messages []models.Message) map[string]any
+}
+
+// ExtractorFactory 根据风格管理器创建提取器（可读取停用词、词典等配置）
+type ExtractorFactory func(m *Manager) FeatureExtractor
+
+// 内置提取器名称
+const (
+	ExtractorVocabulary     = "vocabulary"
+	ExtractorSentenceLength = "sentence_length"
+	ExtractorEmoji          = "emoji"
+	ExtractorPunctuation    = "punctuation"
+)
+
+// 内置特征名
+const (
+	featureVocabulary     = "vocabulary"
+	featureSentenceLength = "sentence_length"
+	featureEmojiUsage     = "emoji_usage"
+	featurePunctuation    = "punctuation"
+)
+
+// defaultExtractors 未配置时启用的提取器（按顺序执行）
+var defaultExtractors = []string{ExtractorVocabulary, ExtractorSentenceLength, ExtractorEmoji, ExtractorPunctuation}
+
+var (
+	extractorsMu sync.RWMutex
+	extractors   = map[string]ExtractorFactory{
+		ExtractorVocabulary: func(m *Manager) FeatureExtractor {
+			return &vocabularyExtractor{stopwords: m.stopwords, userDict: m.userDict}
+		},
+		ExtractorSentenceLength: func(m *Manager) FeatureExtractor { return sentenceLengthExtractor{} },
+		ExtractorEmoji:          func(m *Manager) FeatureExtractor { return emojiExtractor{} },
+		ExtractorPunctuation:    func(m *Manager) FeatureExtractor { return punctuationExtractor{} },
+	}
+)
+
+// RegisterExtractor 注册自定义提取器，需在创建风格管理器之前调用，之后可在配置 style.extractors 中按名称启用。
+// 名称与内置或已注册的提取器重复时返回错误
+func RegisterExtractor(name string, factory ExtractorFactory) error {
+	if name == "" || factory == nil {
+		return fmt.Errorf("提取器名称和构造函数不能为空")
+	}
+
+	extractorsMu.Lock()
+	defer extractorsMu.Unlock()
+	if _, ok := extractors[name]; ok {
+		return fmt.Errorf("提取器 %s 已注册", name)
+	}
+	extractors[name] = factory
+	return nil
+}
+
+// newExtractors 按配置创建提取器，未知名称记录警告后跳过
+func newExtractors(m *Manager, names []string) []FeatureExtractor {
+	if len(names) == 0 {
+		names = defaultExtractors
+	}
+
+	extractorsMu.RLock()
+	defer extractorsMu.RUnlock()
+
+	result := make([]FeatureExtractor, 0, len(names))
+	for _, name := range names {
+		factory, ok := extractors[name]
+		if !ok {
+			logrus.WithField("extractor", name).Warn("未知的风格特征提取器，已忽略")
+			continue
+		}
+		result = append(result, factory(m))
+	}
+	return result
+}
+
+// merge 合并提取器结果到风格特征，内置特征类型不符时忽略
+func (f *StyleFeatures) merge(values map[string]any) {
+	for key, value := range values {
+		switch key {
+		case featureVocabulary:
+			if v, ok := value.(map[string]int); ok {
+				f.Vocabulary = v
+			}
+		case featureSentenceLength:
+			if v, ok := value.(float64); ok {
+				f.SentenceLength = v
+			}
+		case featureEmojiUsage:
+			if v, ok := value.(float64); ok {
+				f.EmojiUsage = v
+			}
+		case featurePunctuation:
+			if v, ok := value.(map[string]int); ok {
+				f.Punctuation = v
+			}
+		default:
+			if f.Extra == nil {
+				f.Extra = make(map[string]any)
+			}
+			f.Extra[key] = value
+		}
+	}
+}
+
+// vocabularyExtractor 常用词汇（按空格切词并补充自定义词典中的专有词，取频率最高的10个）
+type vocabularyExtractor struct {
+	stopwords map[string]bool
+	userDict  []string
+}
+
+func (e *vocabularyExtractor) Name() string { return ExtractorVocabulary }
+
+func (e *vocabularyExtractor) Extract(messages []models.Message) map[string]any {
+	wordFreq := make(map[string]int)
+	for _, msg := range messages {
+		// 简单分词（可以改进为更专业的分词）
+		for _, word := range strings.Fields(msg.Content) {
+			// 自定义词典中的词在下面按子串统计，这里跳过以免重复计数
+			if len([]rune(word)) >= 2 && !e.stopwords[word] && !slices.Contains(e.userDict, word) {
+				wordFreq[word]++
+			}
+		}
+
+		// 补充自定义词典中的专有词（中文没有空格分隔，按子串统计）
+		for _, word := range e.userDict {
+			if e.stopwords[word] {
+				continue
+			}
+			if count := strings.Count(msg.Content, word); count > 0 {
+				wordFreq[word] += count
+			}
+		}
+	}
+	return map[string]any{featureVocabulary: getTopN(wordFreq, 10)}
+}
+
+// sentenceLengthExtractor 平均句子长度（按语言断句，汉字按字、英文按单词计，见 splitSentences、sentenceLength）
+type sentenceLengthExtractor struct{}
+
+func (sentenceLengthExtractor) Name() string { return ExtractorSentenceLength }
+
+func (sentenceLengthExtractor) Extract(messages []models.Message) map[string]any {
+	totalLength := 0
+	sentenceCount := 0
+	for _, msg := range messages {
+		for _, s := range splitSentences(msg.Content) {
+			if n := sentenceLength(s); n > 0 {
+				totalLength += n
+				sentenceCount++
+			}
+		}
+	}
+
+	var avg float64
+	if sentenceCount > 0 {
+		avg = float64(totalLength) / float64(sentenceCount)
+	}
+	return map[string]any{featureSentenceLength: avg}
+}
+
+// emojiExtractor emoji使用频率（每100个字符中的emoji数，简单按码点范围判断）
+type emojiExtractor struct{}
+
+func (emojiExtractor) Name() string { return ExtractorEmoji }
+
+func (emojiExtractor) Extract(messages []models.Message) map[string]any {
+	emojiCount := 0
+	totalChars := 0
+	for _, msg := range messages {
+		for _, r := range msg.Content {
+			totalChars++
+			if r >= 0x1F300 && r <= 0x1F9FF {
+				emojiCount++
+			}
+		}
+	}
+
+	var usage float64
+	if totalChars > 0 {
+		usage = float64(emojiCount) / float64(totalChars) * 100
+	}
+	return map[string]any{featureEmojiUsage: usage}
+}
+
+// punctuationExtractor 中文标点使用次数
+type punctuationExtractor struct{}
+
+func (punctuationExtractor) Name() string { return ExtractorPunctuation }
+
+func (punctuationExtractor) Extract(messages []models.Message) map[string]any {
+	counts := make(map[string]int)
+	for _, msg := range messages {
+		for _, r := range msg.Content {
+			if strings.ContainsRune("，。！？、；：", r) {
+				counts[string(r)]++
+			}
+		}
+	}
+	return map[string]any{featurePunctuation: counts}
+}
@@ -0,0 +1,77 @@
+package style
+
+import (
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+)
+
+// 自定义提取器不能与内置或已注册的提取器重名
+func TestRegisterExtractorRejectsDuplicates(t *testing.T) {
+	factory := func(m *Manager) FeatureExtractor { return emojiExtractor{} }
+
+	if err := RegisterExtractor(ExtractorEmoji, factory); err == nil {
+		t.Fatal("与内置提取器重名应返回错误")
+	}
+	if err := RegisterExtractor("test_custom", factory); err != nil {
+		t.Fatalf("注册自定义提取器失败: %v", err)
+	}
+	t.Cleanup(func() {
+		extractorsMu.Lock()
+		delete(extractors, "test_custom")
+		extractorsMu.Unlock()
+	})
+	if err := RegisterExtractor("test_custom", factory); err == nil {
+		t.Fatal("重复注册应返回错误")
+	}
+	if err := RegisterExtractor("", factory); err == nil {
+		t.Fatal("空名称应返回错误")
+	}
+}
+
+// countingExtractor 记录调用次数的自定义提取器，输出消息条数和一个覆盖内置特征的值
+type countingExtractor struct {
+	calls *int
+}
+
+func (countingExtractor) Name() string { return "test_counting" }
+
+func (e countingExtractor) Extract(messages []models.Message) map[string]any {
+	*e.calls++
+	return map[string]any{
+		"message_total":   len(messages),
+		featureEmojiUsage: 0.5,
+	}
+}
+
+// 按配置启用的自定义提取器被调用，输出写入 Extra，内置特征名覆盖对应字段
+func TestCustomExtractorOutputUsed(t *testing.T) {
+	calls := 0
+	if err := RegisterExtractor("test_counting", func(m *Manager) FeatureExtractor {
+		return countingExtractor{calls: &calls}
+	}); err != nil {
+		t.Fatalf("注册自定义提取器失败: %v", err)
+	}
+	t.Cleanup(func() {
+		extractorsMu.Lock()
+		delete(extractors, "test_counting")
+		extractorsMu.Unlock()
+	})
+
+	m := NewManager(nil, &config.StyleConfig{Extractors: []string{ExtractorVocabulary, "test_counting"}}, nil)
+	features := m.analyzeStyle([]models.Message{{Content: "好的"}, {Content: "没问题"}})
+
+	if calls != 1 {
+		t.Fatalf("自定义提取器调用 %d 次，期望 1", calls)
+	}
+	if total, ok := features.Extra["message_total"].(int); !ok || total != 2 {
+		t.Fatalf("Extra 中的自定义特征为 %v", features.Extra["message_total"])
+	}
+	if features.EmojiUsage != 0.5 {
+		t.Fatalf("emoji_usage 为 %g，应使用自定义提取器的输出", features.EmojiUsage)
+	}
+	if _, ok := features.Extra[featureEmojiUsage]; ok {
+		t.Fatal("内置特征名不应写入 Extra")
+	}
+}
@@ -0,0 +1,81 @@
+package style
+
+import "strings"
+
+// descriptionTemplate 风格描述模板（按输出语言区分）
+type descriptionTemplate struct {
+	// 风格提示词（GetStylePrompt）
+	promptHeader          string
+	promptHeaderTentative string // 样本不足时使用
+	promptTone            string
+	promptSentenceLength  string
+	promptCommonPhrases   string
+	promptRhythm          string
+
+	// 风格描述（generateDescription）
+	descTone           string
+	descSentenceLength string
+	descEmoji          string
+	descCommonPhrases  string
+	descSeparator      string
+
+	// 说话节奏
+	rhythmBurst    string
+	rhythmComplete string
+
+	phraseSeparator string
+}
+
+// defaultDescriptionLang 默认描述语言
+const defaultDescriptionLang = "zh"
+
+// descriptionTemplates 各语言的描述模板
+var descriptionTemplates = map[string]descriptionTemplate{
+	"zh": {
+		promptHeader:          "用户的语言风格特征：\n",
+		promptHeaderTentative: "初步观察到的用户语言风格（样本较少，仅供参考）：\n",
+		promptTone:            "- 语气：%s\n",
+		promptSentenceLength:  "- 平均句子长度：%.1f字\n",
+		promptCommonPhrases:   "- 常用短语：%s\n",
+		promptRhythm:          "- 说话节奏：%s\n",
+
+		descTone:           "语气：%s，",
+		descSentenceLength: "平均句子长度：%.1f字，",
+		descEmoji:          "经常使用表情符号，",
+		descCommonPhrases:  "常用短语：%s",
+		descSeparator:      "，",
+
+		rhythmBurst:    "习惯连发多条短消息",
+		rhythmComplete: "习惯把话说完整再一次发出",
+
+		phraseSeparator: "、",
+	},
+	"en": {
+		promptHeader:          "The user's writing style:\n",
+		promptHeaderTentative: "Early observations of the user's writing style (few samples, treat as tentative):\n",
+		promptTone:            "- Tone: %s\n",
+		promptSentenceLength:  "- Average sentence length: %.1f words\n",
+		promptCommonPhrases:   "- Common phrases: %s\n",
+		promptRhythm:          "- Messaging rhythm: %s\n",
+
+		descTone:           "Tone: %s, ",
+		descSentenceLength: "average sentence length: %.1f words, ",
+		descEmoji:          "uses emoji frequently, ",
+		descCommonPhrases:  "common phrases: %s",
+		descSeparator:      ", ",
+
+		rhythmBurst:    "tends to send several short messages in a row",
+		rhythmComplete: "tends to finish a thought before sending it in one message",
+
+		phraseSeparator: ", ",
+	},
+}
+
+// template 获取配置语言对应的描述模板，未知语言回退到中文
+func (m *Manager) template() descriptionTemplate {
+	lang := strings.ToLower(m.config.DescriptionLang)
+	if tpl, ok := descriptionTemplates[lang]; ok {
+		return tpl
+	}
+	return descriptionTemplates[defaultDescriptionLang]
+}
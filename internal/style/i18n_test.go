@@ -0,0 +1,50 @@
+package style
+
+import (
+	"strings"
+	"testing"
+
+	"ChatRecommend/internal/config"
+)
+
+func testFeatures() *StyleFeatures {
+	return &StyleFeatures{
+		Vocabulary:     map[string]int{"hello": 3},
+		Tone:           "casual",
+		SentenceLength: 8,
+		CommonPhrases:  []string{"hello"},
+	}
+}
+
+func TestDescriptionInChinese(t *testing.T) {
+	m := NewManager(nil, &config.StyleConfig{DescriptionLang: "zh"}, nil)
+	features := testFeatures()
+
+	desc := m.generateDescription(features)
+	if !strings.HasPrefix(desc, "语气：casual") || !strings.Contains(desc, "平均句子长度：8.0字") {
+		t.Errorf("中文描述为 %q", desc)
+	}
+	prompt := m.promptFromFeatures(features)
+	if !strings.HasPrefix(prompt, "用户的语言风格特征：") || !strings.Contains(prompt, "常用短语：hello") {
+		t.Errorf("中文提示词为 %q", prompt)
+	}
+}
+
+func TestDescriptionInEnglish(t *testing.T) {
+	m := NewManager(nil, &config.StyleConfig{DescriptionLang: "EN"}, nil)
+	features := testFeatures()
+
+	desc := m.generateDescription(features)
+	if !strings.HasPrefix(desc, "Tone: casual") || !strings.Contains(desc, "average sentence length: 8.0 words") {
+		t.Errorf("英文描述为 %q", desc)
+	}
+	prompt := m.promptFromFeatures(features)
+	if !strings.HasPrefix(prompt, "The user's writing style:") || !strings.Contains(prompt, "Common phrases: hello") {
+		t.Errorf("英文提示词为 %q", prompt)
+	}
+	for _, r := range desc + prompt {
+		if r >= 0x4e00 && r <= 0x9fff {
+			t.Fatalf("英文输出中包含汉字: %q %q", desc, prompt)
+		}
+	}
+}
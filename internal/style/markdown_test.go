@@ -0,0 +1,30 @@
+package style
+
+import (
+	"reflect"
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+)
+
+// Markdown 消息去掉语法后再分析，结果与直接分析对应的纯文本一致；纯文本消息中的符号原样参与分析
+func TestAnalyzeStyleStripsMarkdown(t *testing.T) {
+	m := NewManager(nil, &config.StyleConfig{}, nil)
+	markdown := m.analyzeStyle([]models.Message{{
+		Content:       "## 周末安排\n- **火锅**真好吃\n- 去[人民公园](https://example.com)散步",
+		ContentFormat: models.ContentFormatMarkdown,
+	}})
+	plain := m.analyzeStyle([]models.Message{{Content: "周末安排\n火锅真好吃\n去人民公园散步"}})
+	if markdown.SentenceLength != plain.SentenceLength || !reflect.DeepEqual(markdown.Vocabulary, plain.Vocabulary) {
+		t.Errorf("Markdown 消息的分析结果为 %+v，期望与纯文本一致 %+v", markdown, plain)
+	}
+
+	raw := m.analyzeStyle([]models.Message{{
+		Content:       "## 周末安排\n- **火锅**真好吃\n- 去[人民公园](https://example.com)散步",
+		ContentFormat: models.ContentFormatPlain,
+	}})
+	if raw.SentenceLength == plain.SentenceLength {
+		t.Error("纯文本格式的消息不应去掉 Markdown 符号")
+	}
+}
@@ -0,0 +1,69 @@
+package style
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+)
+
+// rhythmMessages 按给定顺序构造消息，sequence 依次递增；offsets 为相对起始时间的秒数，负数表示没有时间戳
+func rhythmMessages(senders []string, offsets []int) []models.Message {
+	start := time.Date(2026, 10, 1, 20, 0, 0, 0, time.UTC)
+	messages := make([]models.Message, len(senders))
+	for i, sender := range senders {
+		messages[i] = models.Message{SenderID: sender, Content: "好的", Sequence: int64(i + 1)}
+		if offsets[i] >= 0 {
+			messages[i].CreatedAt = start.Add(time.Duration(offsets[i]) * time.Second)
+		}
+	}
+	return messages
+}
+
+// 连发比例、平均间隔、节奏置信度和描述
+func TestAnalyzeRhythm(t *testing.T) {
+	shuffled := rhythmMessages([]string{"alice", "alice", "alice", "alice"}, []int{0, 10, 20, 30})
+	shuffled[0], shuffled[3] = shuffled[3], shuffled[0]
+
+	tests := []struct {
+		name     string
+		messages []models.Message
+		burst    float64
+		avgGap   float64
+		gaps     int
+		desc     string
+	}{
+		{"连发", rhythmMessages([]string{"alice", "alice", "alice", "alice"}, []int{0, 10, 20, 30}), 0.75, 10, 3, "习惯连发多条短消息"},
+		{"正常间隔", rhythmMessages([]string{"alice", "alice", "alice"}, []int{0, 300, 900}), 0, 450, 2, "习惯把话说完整再一次发出"},
+		{"部分连发", rhythmMessages([]string{"alice", "alice", "alice", "alice"}, []int{0, 30, 330, 630}), 0.25, 210, 3, ""},
+		{"没有时间戳", rhythmMessages([]string{"alice", "alice", "alice"}, []int{-1, -1, -1}), 0, 0, 0, ""},
+		{"时间戳相同", rhythmMessages([]string{"alice", "alice", "alice"}, []int{60, 60, 60}), 0, 0, 0, ""},
+		{"与他人交替", rhythmMessages([]string{"alice", "bob", "alice", "bob", "alice"}, []int{0, 5, 10, 15, 20}), 0, 0, 0, ""},
+		{"他人插话", rhythmMessages([]string{"alice", "alice", "bob", "alice", "alice"}, []int{0, 5, 10, 15, 20}), 0.5, 5, 2, "习惯连发多条短消息"},
+		// 按 sequence 而不是传入顺序判断先后
+		{"乱序传入", shuffled, 0.75, 10, 3, "习惯连发多条短消息"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManager(nil, &config.StyleConfig{DescriptionLang: "zh"}, nil)
+			features, desc, _ := m.buildStyle(tt.messages, "alice")
+
+			if features.BurstRatio != tt.burst {
+				t.Errorf("BurstRatio 为 %v，期望 %v", features.BurstRatio, tt.burst)
+			}
+			if features.AvgGapSeconds != tt.avgGap {
+				t.Errorf("AvgGapSeconds 为 %v，期望 %v", features.AvgGapSeconds, tt.avgGap)
+			}
+			if got, want := features.Confidence[DimensionRhythm], sampleConfidence(DimensionRhythm, tt.gaps); got != want {
+				t.Errorf("节奏置信度为 %v，期望 %v（%d 个间隔）", got, want, tt.gaps)
+			}
+			for _, rhythm := range []string{"习惯连发多条短消息", "习惯把话说完整再一次发出"} {
+				if strings.Contains(desc, rhythm) != (rhythm == tt.desc) {
+					t.Errorf("描述 %q 中节奏部分不符，期望 %q", desc, tt.desc)
+				}
+			}
+		})
+	}
+}
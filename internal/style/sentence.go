@@ -0,0 +1,67 @@
+package style
+
+import (
+	"strings"
+	"unicode"
+)
+
+// splitSentences 按语言切分句子：中文在。！？处断句，英文在 .!? 后接空白或文本结尾处断句
+// （避免把 3.14、e.g.x 之类切开），中英混排时两种规则同时生效。连续的结束标点归入同一句，空白句子被丢弃
+func splitSentences(text string) []string {
+	runes := []rune(text)
+	var sentences []string
+	start := 0
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		end := false
+		switch {
+		case strings.ContainsRune("。！？", r):
+			end = true
+		case strings.ContainsRune(".!?", r):
+			end = i+1 == len(runes) || unicode.IsSpace(runes[i+1]) || strings.ContainsRune(".!?。！？", runes[i+1])
+		}
+		if !end {
+			continue
+		}
+		// 吞掉紧随的结束标点（如"？！"、"..."）
+		for i+1 < len(runes) && strings.ContainsRune(".!?。！？", runes[i+1]) {
+			i++
+		}
+		sentences = appendSentence(sentences, runes[start:i+1])
+		start = i + 1
+	}
+	return appendSentence(sentences, runes[start:])
+}
+
+// appendSentence 追加去掉首尾空白后的非空句子
+func appendSentence(sentences []string, runes []rune) []string {
+	s := strings.TrimSpace(string(runes))
+	if s == "" {
+		return sentences
+	}
+	return append(sentences, s)
+}
+
+// sentenceLength 句子长度：汉字（及其他 CJK 字符）按字计，英文等按单词计，标点和空白不计。
+// 一个汉字与一个英文单词承载的信息量相近，这样中英文的句长可以放在一起平均。数字中的小数点不拆分单词
+func sentenceLength(sentence string) int {
+	runes := []rune(sentence)
+	length := 0
+	inWord := false
+	for i, r := range runes {
+		switch {
+		case r == '.' && inWord && i > 0 && unicode.IsDigit(runes[i-1]) && i+1 < len(runes) && unicode.IsDigit(runes[i+1]):
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			length++
+			inWord = false
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '\'' && inWord:
+			if !inWord {
+				length++
+				inWord = true
+			}
+		default:
+			inWord = false
+		}
+	}
+	return length
+}
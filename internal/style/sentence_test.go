@@ -0,0 +1,50 @@
+package style
+
+import (
+	"reflect"
+	"testing"
+
+	"ChatRecommend/internal/models"
+)
+
+// 中文在。！？处断句，英文在 .!? 后接空白处断句，小数点和连续标点不会切出空句
+func TestSplitSentences(t *testing.T) {
+	cases := []struct {
+		text string
+		want []string
+	}{
+		{"今天好累。明天见！你呢？", []string{"今天好累。", "明天见！", "你呢？"}},
+		{"I'm tired. See you tomorrow! You?", []string{"I'm tired.", "See you tomorrow!", "You?"}},
+		{"It costs 3.14 dollars... Really?!", []string{"It costs 3.14 dollars...", "Really?!"}},
+		{"好的OK. 明天meeting见。Sure", []string{"好的OK.", "明天meeting见。", "Sure"}},
+		{"  ", nil},
+	}
+	for _, c := range cases {
+		if got := splitSentences(c.text); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("splitSentences(%q) = %q，期望 %q", c.text, got, c.want)
+		}
+	}
+}
+
+// 纯中文按字、纯英文按单词、混排时分别计数后相加求平均句长
+func TestSentenceLengthExtractor(t *testing.T) {
+	cases := []struct {
+		name    string
+		content []string
+		want    float64
+	}{
+		{"纯中文", []string{"今天好累。明天见！"}, 3.5},
+		{"纯英文", []string{"I am so tired today. See you tomorrow."}, 4},
+		{"混排", []string{"明天meeting见。", "It costs 3.14 dollars."}, 4},
+	}
+	for _, c := range cases {
+		messages := make([]models.Message, len(c.content))
+		for i, content := range c.content {
+			messages[i] = models.Message{SenderID: "alice", Content: content}
+		}
+		got := sentenceLengthExtractor{}.Extract(messages)[featureSentenceLength]
+		if got != c.want {
+			t.Errorf("%s样本的平均句长为 %v，期望 %v", c.name, got, c.want)
+		}
+	}
+}
@@ -1,13 +1,17 @@
 package style
 
 import (
+	"cmp"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
 	"ChatRecommend/internal/config"
 	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/textutil"
+	"ChatRecommend/internal/webhook"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
@@ -16,6 +20,13 @@ import (
 type Manager struct {
 	db     *gorm.DB
 	config *config.StyleConfig
+	hooks  *webhook.Dispatcher
+
+	stopwords map[string]bool // 停用词
+	userDict  []string        // 自定义词典
+	cache     *featuresCache  // 已解析的风格特征缓存
+
+	extractors []FeatureExtractor // 启用的特征提取器
 }
 
 // StyleFeatures 风格特征
@@ -26,14 +37,28 @@ type StyleFeatures struct {
 	Tone            string         `json:"tone"`             // 语气（formal, casual, friendly等）
 	Punctuation     map[string]int `json:"punctuation"`      // 标点符号使用
 	CommonPhrases   []string       `json:"common_phrases"`   // 常用短语
+	BurstRatio      float64        `json:"burst_ratio"`      // 连发比例（紧跟自己上一条消息发送的比例）
+	AvgGapSeconds   float64        `json:"avg_gap_seconds"`  // 连续消息平均间隔（秒），0表示缺少时间数据
+	SampleCount     int            `json:"sample_count"`     // 参与分析的消息数
+	Confidence      map[string]float64 `json:"confidence,omitempty"` // 各维度置信度（0-1），随样本量增加
+	Extra           map[string]any `json:"extra,omitempty"`  // 自定义提取器输出的特征
 }
 
+// burstGap 连发判定间隔：与自己上一条消息间隔不超过该值视为连发
+const burstGap = 60 * time.Second
+
 // NewManager 创建风格管理器
-func NewManager(db *gorm.DB, cfg *config.StyleConfig) *Manager {
-	return &Manager{
-		db:     db,
-		config: cfg,
-	}
+func NewManager(db *gorm.DB, cfg *config.StyleConfig, hooks *webhook.Dispatcher) *Manager {
+	m := &Manager{
+		db:        db,
+		config:    cfg,
+		hooks:     hooks,
+		stopwords: loadStopwords(cfg.StopwordsPath),
+		userDict:  loadUserDict(cfg.UserDictPath),
+		cache:     newFeaturesCache(time.Duration(cfg.CacheTTLSeconds) * time.Second),
+	}
+	m.extractors = newExtractors(m, cfg.Extractors)
+	return m
 }
 
 // GetOrCreateStyle 获取或创建用户风格
@@ -69,6 +94,12 @@ func (m *Manager) ShouldUpdateStyle(style *models.Style, currentMessageCount int
 		return false
 	}
 
+	// 距上次重算不足最小间隔时先不重算，新消息攒到下次（从未分析过的风格不受限制）
+	if m.config.MinIntervalSeconds > 0 && style.LastMessageCount > 0 &&
+		time.Since(style.LastUpdatedAt) < time.Duration(m.config.MinIntervalSeconds)*time.Second {
+		return false
+	}
+
 	// 检查消息数量阈值
 	if currentMessageCount-style.LastMessageCount >= int64(m.config.UpdateThresholdMessages) {
 		return true
@@ -83,22 +114,11 @@ func (m *Manager) UpdateStyle(conversationID uint, userID string, messages []mod
 		return nil
 	}
 
-	// 过滤出该用户的消息
-	userMessages := make([]models.Message, 0)
-	for _, msg := range messages {
-		if msg.SenderID == userID {
-			userMessages = append(userMessages, msg)
-		}
-	}
-
-	if len(userMessages) == 0 {
+	features, description, userMessages := m.buildStyle(messages, userID)
+	if userMessages == 0 {
 		return nil
 	}
 
-	// 分析风格特征
-	features := m.analyzeStyle(userMessages)
-	description := m.generateDescription(features)
-
 	// 序列化特征
 	featuresJSON, err := json.Marshal(features)
 	if err != nil {
@@ -113,70 +133,177 @@ func (m *Manager) UpdateStyle(conversationID uint, userID string, messages []mod
 
 	style.Features = string(featuresJSON)
 	style.Description = description
-	style.LastMessageCount = int64(len(userMessages))
+	style.LastMessageCount = int64(userMessages)
 	style.LastUpdatedAt = time.Now()
 
 	if err := m.db.Save(style).Error; err != nil {
 		return fmt.Errorf("保存风格失败: %w", err)
 	}
+	m.cache.invalidate(conversationID, userID)
 
 	logrus.WithFields(logrus.Fields{
 		"conversation_id": conversationID,
 		"user_id":         userID,
 	}).Info("用户语言风格已更新")
 
+	// 对话级画像变化后同步重算用户级画像
+	if err := m.UpdateUserStyle(userID); err != nil {
+		logrus.WithError(err).WithField("user_id", userID).Warn("更新用户级风格失败")
+	}
+
+	m.hooks.Dispatch(webhook.EventStyleUpdated, map[string]interface{}{
+		"conversation_id": conversationID,
+		"user_id":         userID,
+		"description":     description,
+	})
+
 	return nil
 }
 
-// GetStyleFeatures 获取用户风格特征
+// buildStyle 从对话消息中分析指定用户的风格特征和描述，返回参与分析的该用户消息数（为0时不分析）
+func (m *Manager) buildStyle(messages []models.Message, userID string) (*StyleFeatures, string, int) {
+	// 过滤出该用户的消息
+	userMessages := make([]models.Message, 0)
+	for _, msg := range messages {
+		if msg.SenderID == userID {
+			userMessages = append(userMessages, msg)
+		}
+	}
+
+	if len(userMessages) == 0 {
+		return nil, "", 0
+	}
+
+	// 分析风格特征
+	features := m.analyzeStyle(userMessages)
+	m.analyzeRhythm(messages, userID, features)
+	return features, m.generateDescription(features), len(userMessages)
+}
+
+// Preview 风格预览：将要生成的风格画像，不落库
+type Preview struct {
+	ConversationID uint           `json:"conversation_id"`
+	UserID         string         `json:"user_id"`
+	Features       *StyleFeatures `json:"features"`
+	Description    string         `json:"description"`
+	// 补全时注入的风格提示词
+	Prompt       string `json:"prompt"`
+	MessageCount int64  `json:"message_count"`
+}
+
+// PreviewStyle 用对话当前的全部消息分析用户风格，不修改数据库和缓存；该用户没有消息时 Features 为空
+func (m *Manager) PreviewStyle(conversationID uint, userID string) (*Preview, error) {
+	var messages []models.Message
+	if err := m.db.Where("conversation_id = ?", conversationID).
+		Scopes(models.ExcludeDrafts).
+		Order("sequence ASC, created_at ASC").
+		Find(&messages).Error; err != nil {
+		return nil, fmt.Errorf("查询消息失败: %w", err)
+	}
+
+	preview := &Preview{ConversationID: conversationID, UserID: userID}
+	features, description, count := m.buildStyle(messages, userID)
+	if count == 0 {
+		return preview, nil
+	}
+	preview.Features = features
+	preview.Description = description
+	preview.Prompt = m.promptFromFeatures(features)
+	preview.MessageCount = int64(count)
+	return preview, nil
+}
+
+// GetStyleFeatures 获取用户风格特征（优先读缓存，返回的特征为只读）
 func (m *Manager) GetStyleFeatures(conversationID uint, userID string) (*StyleFeatures, error) {
+	features, version, ok := m.cache.get(conversationID, userID)
+	if ok {
+		return features, nil
+	}
+
 	style, err := m.GetOrCreateStyle(conversationID, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	var features StyleFeatures
+	features = &StyleFeatures{}
 	if style.Features != "" && style.Features != "{}" {
-		if err := json.Unmarshal([]byte(style.Features), &features); err != nil {
+		if err := json.Unmarshal([]byte(style.Features), features); err != nil {
 			logrus.WithError(err).Warn("解析风格特征失败")
 			return &StyleFeatures{}, nil
 		}
 	}
 
-	return &features, nil
+	m.cache.set(conversationID, userID, features, version)
+	return features, nil
 }
 
-// GetStylePrompt 获取风格提示词（用于大模型）
+// GetStylePrompt 获取风格提示词（用于大模型）。按 prompt_source 优先使用对话级或用户级画像，优先的画像为空时使用另一个
 func (m *Manager) GetStylePrompt(conversationID uint, userID string) (string, error) {
-	features, err := m.GetStyleFeatures(conversationID, userID)
-	if err != nil {
-		return "", err
+	sources := []func() (*StyleFeatures, error){
+		func() (*StyleFeatures, error) { return m.GetStyleFeatures(conversationID, userID) },
+		func() (*StyleFeatures, error) { return m.GetUserStyleFeatures(userID) },
+	}
+	if m.config.PromptSource == PromptSourceUser {
+		sources[0], sources[1] = sources[1], sources[0]
+	}
+
+	for _, source := range sources {
+		features, err := source()
+		if err != nil {
+			return "", err
+		}
+		if prompt := m.promptFromFeatures(features); prompt != "" {
+			return prompt, nil
+		}
 	}
+	return "", nil
+}
 
+// promptFromFeatures 根据风格特征生成提示词，特征为空或所有维度置信度都过低时返回空
+func (m *Manager) promptFromFeatures(features *StyleFeatures) string {
 	if features == nil || len(features.Vocabulary) == 0 {
-		return "", nil
+		return ""
 	}
 
 	// 构建风格提示词
+	tpl := m.template()
 	var prompt strings.Builder
-	prompt.WriteString("用户的语言风格特征：\n")
-	
-	if features.Tone != "" {
-		prompt.WriteString(fmt.Sprintf("- 语气：%s\n", features.Tone))
+	if features.tentative() {
+		prompt.WriteString(tpl.promptHeaderTentative)
+	} else {
+		prompt.WriteString(tpl.promptHeader)
 	}
-	
-	if features.SentenceLength > 0 {
-		prompt.WriteString(fmt.Sprintf("- 平均句子长度：%.1f字\n", features.SentenceLength))
+
+	// 置信度过低的维度不注入
+	dimensions := 0
+	if features.Tone != "" && features.confidence(DimensionTone) >= lowConfidence {
+		prompt.WriteString(fmt.Sprintf(tpl.promptTone, features.Tone))
+		dimensions++
 	}
-	
-	if len(features.CommonPhrases) > 0 {
-		prompt.WriteString(fmt.Sprintf("- 常用短语：%s\n", strings.Join(features.CommonPhrases[:min(5, len(features.CommonPhrases))], "、")))
+
+	if features.SentenceLength > 0 && features.confidence(DimensionSentenceLength) >= lowConfidence {
+		prompt.WriteString(fmt.Sprintf(tpl.promptSentenceLength, features.SentenceLength))
+		dimensions++
+	}
+
+	if len(features.CommonPhrases) > 0 && features.confidence(DimensionCommonPhrases) >= lowConfidence {
+		prompt.WriteString(fmt.Sprintf(tpl.promptCommonPhrases, strings.Join(features.CommonPhrases[:min(5, len(features.CommonPhrases))], tpl.phraseSeparator)))
+		dimensions++
+	}
+
+	if rhythm := describeRhythm(features, tpl); rhythm != "" && features.confidence(DimensionRhythm) >= lowConfidence {
+		prompt.WriteString(fmt.Sprintf(tpl.promptRhythm, rhythm))
+		dimensions++
+	}
+
+	if dimensions == 0 {
+		return ""
 	}
 
-	return prompt.String(), nil
+	return prompt.String()
 }
 
-// analyzeStyle 分析消息风格特征
+// analyzeStyle 分析消息风格特征：依次执行启用的提取器并合并结果，再据此判断语气
 func (m *Manager) analyzeStyle(messages []models.Message) *StyleFeatures {
 	features := &StyleFeatures{
 		Vocabulary:    make(map[string]int),
@@ -184,93 +311,126 @@ func (m *Manager) analyzeStyle(messages []models.Message) *StyleFeatures {
 		CommonPhrases: make([]string, 0),
 	}
 
-	totalLength := 0
-	emojiCount := 0
-	totalChars := 0
+	// Markdown 消息先提取纯文本，超长消息只取首尾采样，避免遍历整篇长文
+	sampled := make([]models.Message, len(messages))
+	for i, msg := range messages {
+		sampled[i] = msg
+		sampled[i].Content = textutil.SampleHeadTail(msg.PlainContent(), m.config.MaxAnalyzeChars)
+	}
 
-	// 常用词汇（简单实现，可以改进）
-	wordFreq := make(map[string]int)
+	for _, extractor := range m.extractors {
+		features.merge(extractor.Extract(sampled))
+	}
 
-	for _, msg := range messages {
-		content := msg.Content
-		totalChars += len([]rune(content))
-		
-		// 统计句子长度
-		sentences := strings.Split(content, "。")
-		for _, s := range sentences {
-			if len(s) > 0 {
-				totalLength += len([]rune(s))
-			}
+	// 样本量与各维度置信度（节奏维度在 analyzeRhythm 中计算）
+	features.SampleCount = len(messages)
+	features.Confidence = map[string]float64{
+		DimensionTone:           sampleConfidence(DimensionTone, len(messages)),
+		DimensionSentenceLength: sampleConfidence(DimensionSentenceLength, len(messages)),
+		DimensionCommonPhrases:  sampleConfidence(DimensionCommonPhrases, len(messages)),
+	}
+
+	// 判断语气（简单实现）
+	if features.SentenceLength < 10 && features.EmojiUsage > 2 {
+		features.Tone = "casual"
+	} else if features.SentenceLength > 30 {
+		features.Tone = "formal"
+	} else {
+		features.Tone = "friendly"
+	}
+
+	return features
+}
+
+// analyzeRhythm 分析说话节奏（连发比例与连续消息间隔）
+// messages 为整个对话的消息，按 sequence（相同时按 created_at）排序后判断用户的消息是否紧跟在自己上一条之后。
+// 间隔只取自 created_at：sequence 可由客户端指定，只表示先后顺序、没有时间单位，不能换算成间隔；
+// 导入的历史消息可能缺少时间戳（created_at 为空或与上一条相同），这类消息对不参与间隔统计。
+func (m *Manager) analyzeRhythm(messages []models.Message, userID string, features *StyleFeatures) {
+	messages = slices.Clone(messages)
+	slices.SortStableFunc(messages, func(a, b models.Message) int {
+		if a.Sequence != b.Sequence {
+			return cmp.Compare(a.Sequence, b.Sequence)
 		}
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
 
-		// 统计emoji（简单判断，可以改进）
-		for _, r := range content {
-			if r >= 0x1F300 && r <= 0x1F9FF {
-				emojiCount++
-			}
+	userCount := 0
+	burstCount := 0
+	gapCount := 0
+	var totalGap time.Duration
+
+	for i, msg := range messages {
+		if msg.SenderID != userID {
+			continue
 		}
+		userCount++
 
-		// 统计标点符号
-		for _, r := range content {
-			if strings.ContainsRune("，。！？、；：", r) {
-				features.Punctuation[string(r)]++
-			}
+		if i == 0 || messages[i-1].SenderID != userID {
+			continue
 		}
 
-		// 简单分词（可以改进为更专业的分词）
-		words := strings.Fields(content)
-		for _, word := range words {
-			if len([]rune(word)) >= 2 {
-				wordFreq[word]++
-			}
+		prev := messages[i-1]
+		if prev.CreatedAt.IsZero() || msg.CreatedAt.IsZero() {
+			continue
+		}
+		gap := msg.CreatedAt.Sub(prev.CreatedAt)
+		if gap <= 0 {
+			// 批量导入时时间戳相同，无法判断真实间隔
+			continue
 		}
-	}
 
-	// 计算平均句子长度
-	sentenceCount := 0
-	for _, msg := range messages {
-		sentenceCount += len(strings.Split(msg.Content, "。"))
-	}
-	if sentenceCount > 0 {
-		features.SentenceLength = float64(totalLength) / float64(sentenceCount)
+		gapCount++
+		totalGap += gap
+		if gap <= burstGap {
+			burstCount++
+		}
 	}
 
-	// 计算emoji使用频率
-	if totalChars > 0 {
-		features.EmojiUsage = float64(emojiCount) / float64(totalChars) * 100
+	if features.Confidence != nil {
+		features.Confidence[DimensionRhythm] = sampleConfidence(DimensionRhythm, gapCount)
 	}
 
-	// 获取最常用的词汇
-	topWords := getTopN(wordFreq, 10)
-	for word, count := range topWords {
-		features.Vocabulary[word] = count
+	if userCount == 0 || gapCount == 0 {
+		return
 	}
 
-	// 判断语气（简单实现）
-	if features.SentenceLength < 10 && features.EmojiUsage > 2 {
-		features.Tone = "casual"
-	} else if features.SentenceLength > 30 {
-		features.Tone = "formal"
-	} else {
-		features.Tone = "friendly"
-	}
+	features.BurstRatio = float64(burstCount) / float64(userCount)
+	features.AvgGapSeconds = totalGap.Seconds() / float64(gapCount)
+}
 
-	return features
+// describeRhythm 描述说话节奏，缺少时间数据时返回空
+func describeRhythm(features *StyleFeatures, tpl descriptionTemplate) string {
+	if features.AvgGapSeconds <= 0 {
+		return ""
+	}
+	if features.BurstRatio >= 0.5 {
+		return tpl.rhythmBurst
+	}
+	if features.BurstRatio <= 0.1 {
+		return tpl.rhythmComplete
+	}
+	return ""
 }
 
 // generateDescription 生成风格描述
 func (m *Manager) generateDescription(features *StyleFeatures) string {
+	tpl := m.template()
 	var desc strings.Builder
-	
-	desc.WriteString(fmt.Sprintf("语气：%s，", features.Tone))
-	desc.WriteString(fmt.Sprintf("平均句子长度：%.1f字，", features.SentenceLength))
-	
+
+	desc.WriteString(fmt.Sprintf(tpl.descTone, features.Tone))
+	desc.WriteString(fmt.Sprintf(tpl.descSentenceLength, features.SentenceLength))
+
 	if features.EmojiUsage > 2 {
-		desc.WriteString("经常使用表情符号，")
+		desc.WriteString(tpl.descEmoji)
 	}
-	
+
+	if rhythm := describeRhythm(features, tpl); rhythm != "" {
+		desc.WriteString(rhythm + tpl.descSeparator)
+	}
+
 	if len(features.CommonPhrases) > 0 {
-		desc.WriteString(fmt.Sprintf("常用短语：%s", strings.Join(features.CommonPhrases[:min(3, len(features.CommonPhrases))], "、")))
+		desc.WriteString(fmt.Sprintf(tpl.descCommonPhrases, strings.Join(features.CommonPhrases[:min(3, len(features.CommonPhrases))], tpl.phraseSeparator)))
 	}
 
 	return desc.String()
@@ -292,7 +452,7 @@ func getTopN(wordFreq map[string]int, n int) map[string]int {
 	}
 	
 	// 简单排序
-	for i := 0; i < len(words)-1 && i < n; i++ {
+	for i := 0; i < len(words) && i < n; i++ {
 		maxIdx := i
 		for j := i + 1; j < len(words); j++ {
 			if words[j].count > words[maxIdx].count {
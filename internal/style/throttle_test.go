@@ -0,0 +1,28 @@
+package style
+
+import (
+	"testing"
+	"time"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+)
+
+// 频繁消息下风格重算被最小间隔节流，从未分析过的风格不受限制
+func TestShouldUpdateStyleThrottled(t *testing.T) {
+	m := NewManager(nil, &config.StyleConfig{Enabled: true, UpdateThresholdMessages: 5, MinIntervalSeconds: 60}, nil)
+
+	recent := &models.Style{LastMessageCount: 10, LastUpdatedAt: time.Now().Add(-10 * time.Second)}
+	if m.ShouldUpdateStyle(recent, 30) {
+		t.Error("距上次重算不足最小间隔时不应重算")
+	}
+
+	stale := &models.Style{LastMessageCount: 10, LastUpdatedAt: time.Now().Add(-2 * time.Minute)}
+	if !m.ShouldUpdateStyle(stale, 30) {
+		t.Error("超过最小间隔且达到消息数阈值时应重算")
+	}
+
+	if !m.ShouldUpdateStyle(&models.Style{LastUpdatedAt: time.Now()}, 5) {
+		t.Error("从未分析过的风格不应受最小间隔限制")
+	}
+}
@@ -0,0 +1,109 @@
+package style
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"ChatRecommend/internal/models"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 风格提示词的画像来源
+const (
+	PromptSourceConversation = "conversation"
+	PromptSourceUser         = "user"
+)
+
+// userCacheConversationID 用户级画像在特征缓存中使用的对话ID（对话ID从1开始，不会冲突）
+const userCacheConversationID = 0
+
+// userLearningMessages 用户级画像参与分析的消息数
+func (m *Manager) userLearningMessages() int {
+	if m.config.UserLearningMessagesCount > 0 {
+		return m.config.UserLearningMessagesCount
+	}
+	if m.config.LearningMessagesCount > 0 {
+		return m.config.LearningMessagesCount * 4
+	}
+	return 200
+}
+
+// UpdateUserStyle 按用户在所有对话中最近的消息重算用户级画像。
+// 用户级画像不分析说话节奏（连发需要结合所在对话的上下文判断），该维度只由对话级画像提供
+func (m *Manager) UpdateUserStyle(userID string) error {
+	if !m.config.Enabled {
+		return nil
+	}
+
+	var messages []models.Message
+	if err := m.db.Where("sender_id = ?", userID).
+		Scopes(models.ExcludeDrafts).
+		Order("created_at DESC, id DESC").
+		Limit(m.userLearningMessages()).
+		Find(&messages).Error; err != nil {
+		return fmt.Errorf("查询用户消息失败: %w", err)
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+
+	features := m.analyzeStyle(messages)
+	featuresJSON, err := json.Marshal(features)
+	if err != nil {
+		return fmt.Errorf("序列化风格特征失败: %w", err)
+	}
+
+	style := models.UserStyle{
+		UserID:        userID,
+		Features:      string(featuresJSON),
+		Description:   m.generateDescription(features),
+		MessageCount:  int64(len(messages)),
+		LastUpdatedAt: time.Now(),
+	}
+	if err := m.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"features", "description", "message_count", "last_updated_at", "updated_at"}),
+	}).Create(&style).Error; err != nil {
+		return fmt.Errorf("保存用户级风格失败: %w", err)
+	}
+	m.cache.invalidate(userCacheConversationID, userID)
+
+	logrus.WithFields(logrus.Fields{
+		"user_id":  userID,
+		"messages": len(messages),
+	}).Debug("用户级语言风格已更新")
+	return nil
+}
+
+// GetUserStyleFeatures 获取用户级风格特征（优先读缓存，返回的特征为只读），尚未生成时返回空特征
+func (m *Manager) GetUserStyleFeatures(userID string) (*StyleFeatures, error) {
+	features, version, ok := m.cache.get(userCacheConversationID, userID)
+	if ok {
+		return features, nil
+	}
+
+	var style models.UserStyle
+	err := m.db.Where("user_id = ?", userID).First(&style).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, fmt.Errorf("查询用户级风格失败: %w", err)
+	}
+
+	features = &StyleFeatures{}
+	if style.Features != "" && style.Features != "{}" {
+		if err := json.Unmarshal([]byte(style.Features), features); err != nil {
+			logrus.WithError(err).Warn("解析用户级风格特征失败")
+			return &StyleFeatures{}, nil
+		}
+	}
+
+	m.cache.set(userCacheConversationID, userID, features, version)
+	return features, nil
+}
+
+// InvalidateUserCache 使用户级风格特征缓存失效
+func (m *Manager) InvalidateUserCache(userID string) {
+	m.cache.invalidate(userCacheConversationID, userID)
+}
@@ -0,0 +1,78 @@
+package style
+
+import (
+	"strings"
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// 更新对话级画像时同步重算用户级画像，用户级画像聚合该用户在全部对话中的消息
+func TestUpdateStyleSyncsUserStyle(t *testing.T) {
+	db := testutil.NewDB(t)
+	m := NewManager(db, &config.StyleConfig{Enabled: true, PromptSource: PromptSourceConversation}, nil)
+	work := testutil.CreateConversation(t, db, "conv-work",
+		models.Message{SenderID: "alice", Content: "收到 马上 处理"},
+		models.Message{SenderID: "bob", Content: "辛苦了"},
+	)
+	friends := testutil.CreateConversation(t, db, "conv-friends",
+		models.Message{SenderID: "alice", Content: "哈哈 周末 打球"},
+		models.Message{SenderID: "alice", Content: "周末 打球 走起"},
+		models.Message{SenderID: "alice", Content: "晚上 吃啥"},
+		models.Message{SenderID: "alice", Content: "火锅 可以"},
+	)
+
+	var messages []models.Message
+	db.Where("conversation_id = ?", work.ID).Order("sequence").Find(&messages)
+	if err := m.UpdateStyle(work.ID, "alice", messages); err != nil {
+		t.Fatalf("更新风格失败: %v", err)
+	}
+
+	var userStyle models.UserStyle
+	if err := db.Where("user_id = ?", "alice").First(&userStyle).Error; err != nil {
+		t.Fatalf("未生成用户级画像: %v", err)
+	}
+	if userStyle.MessageCount != 5 {
+		t.Errorf("用户级画像分析了 %d 条消息，期望聚合两个对话的 5 条", userStyle.MessageCount)
+	}
+	features, err := m.GetUserStyleFeatures("alice")
+	if err != nil {
+		t.Fatalf("读取用户级风格失败: %v", err)
+	}
+	if features.Vocabulary["打球"] != 2 || features.Vocabulary["处理"] != 1 {
+		t.Errorf("用户级词汇应来自全部对话: %v", features.Vocabulary)
+	}
+
+	// 没有对话级画像的对话回退到用户级画像
+	prompt, err := m.GetStylePrompt(friends.ID, "alice")
+	if err != nil || !strings.Contains(prompt, "语言风格") {
+		t.Errorf("没有对话级画像时应使用用户级画像，得到 %q, %v", prompt, err)
+	}
+}
+
+// prompt_source=user 时优先使用用户级画像
+func TestGetStylePromptPrefersUserSource(t *testing.T) {
+	db := testutil.NewDB(t)
+	m := NewManager(db, &config.StyleConfig{Enabled: true, PromptSource: PromptSourceUser, DescriptionLang: "zh"}, nil)
+	conversation := testutil.CreateConversation(t, db, "conv-source")
+	if err := db.Create(&models.Style{ConversationID: conversation.ID, UserID: "alice", Features: `{"vocabulary":{"好的":3},"tone":"formal"}`}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.UserStyle{UserID: "alice", Features: `{"vocabulary":{"好的":3},"tone":"casual"}`}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	prompt, err := m.GetStylePrompt(conversation.ID, "alice")
+	if err != nil || !strings.Contains(prompt, "语气：casual") {
+		t.Errorf("应优先使用用户级画像，得到 %q, %v", prompt, err)
+	}
+
+	m.config.PromptSource = PromptSourceConversation
+	m.InvalidateUserCache("alice")
+	prompt, err = m.GetStylePrompt(conversation.ID, "alice")
+	if err != nil || !strings.Contains(prompt, "语气：formal") {
+		t.Errorf("prompt_source=conversation 时应使用对话级画像，得到 %q, %v", prompt, err)
+	}
+}
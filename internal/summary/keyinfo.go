@@ -0,0 +1,102 @@
+package summary
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 关键信息合并策略
+const (
+	// MergeOverwrite 同一 type+key 的关键信息以新值覆盖旧值（默认）
+	MergeOverwrite = "overwrite"
+	// MergeHigherConfidence 同一 type+key 保留 confidence 较高的一条，相同时取新值
+	MergeHigherConfidence = "higher_confidence"
+	// MergeNone 不合并，直接使用大模型返回的关键信息
+	MergeNone = "none"
+)
+
+// keyInfoIdentity 关键信息的去重标识：有 type 或 key 字段时按 type+key，否则按完整内容
+func keyInfoIdentity(item map[string]interface{}) string {
+	typ, hasType := item["type"]
+	key, hasKey := item["key"]
+	if hasType || hasKey {
+		return fmt.Sprintf("%v|%v", typ, key)
+	}
+	raw, _ := json.Marshal(item)
+	return string(raw)
+}
+
+// parseKeyInfo 解析关键信息JSON，解析失败时返回空列表
+func parseKeyInfo(raw string) []map[string]interface{} {
+	var items []map[string]interface{}
+	if raw == "" {
+		return items
+	}
+	if err := json.Unmarshal([]byte(raw), &items); err != nil {
+		logrus.WithError(err).Warn("解析关键信息失败")
+		return nil
+	}
+	return items
+}
+
+// confidenceOf 读取关键信息的置信度，缺失时视为0
+func confidenceOf(item map[string]interface{}) float64 {
+	if v, ok := item["confidence"].(float64); ok {
+		return v
+	}
+	return 0
+}
+
+// mergeKeyInfo 按 type+key 合并新旧关键信息，保持旧条目的顺序，新条目追加在后面
+// 被新值更新的条目会刷新 updated_at
+func mergeKeyInfo(oldJSON, newJSON, strategy string) string {
+	if strategy == MergeNone {
+		return newJSON
+	}
+
+	oldItems := parseKeyInfo(oldJSON)
+	newItems := parseKeyInfo(newJSON)
+	if newItems == nil {
+		return newJSON
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	merged := make([]map[string]interface{}, 0, len(oldItems)+len(newItems))
+	index := make(map[string]int, len(oldItems)+len(newItems))
+
+	for _, item := range oldItems {
+		id := keyInfoIdentity(item)
+		if i, ok := index[id]; ok {
+			merged[i] = item
+			continue
+		}
+		index[id] = len(merged)
+		merged = append(merged, item)
+	}
+
+	for _, item := range newItems {
+		id := keyInfoIdentity(item)
+		i, ok := index[id]
+		if !ok {
+			item["updated_at"] = now
+			index[id] = len(merged)
+			merged = append(merged, item)
+			continue
+		}
+		if strategy == MergeHigherConfidence && confidenceOf(item) < confidenceOf(merged[i]) {
+			continue
+		}
+		item["updated_at"] = now
+		merged[i] = item
+	}
+
+	raw, err := json.Marshal(merged)
+	if err != nil {
+		logrus.WithError(err).Warn("序列化合并后的关键信息失败")
+		return newJSON
+	}
+	return string(raw)
+}
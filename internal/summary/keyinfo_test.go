@@ -0,0 +1,61 @@
+package summary
+
+import (
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// 多次增量摘要提取到同一 type+key 的关键信息时合并为一条，新值覆盖旧值
+func TestUpdateSummaryMergesDuplicateKeyInfo(t *testing.T) {
+	db := testutil.NewDB(t)
+	mock := &testutil.MockLLM{SummaryPrompt: "两人在聊吃饭", KeyInfo: `[{"type":"preference","key":"口味","value":"喜欢吃辣"},{"type":"event","key":"约饭","value":"周五"}]`}
+	m := NewManager(db, &config.SummaryConfig{}, &config.PromptConfig{}, mock, nil)
+	conversation := testutil.CreateConversation(t, db, "conv-keyinfo",
+		models.Message{SenderID: "alice", Content: "我喜欢吃辣"},
+	)
+	var messages []models.Message
+	db.Where("conversation_id = ?", conversation.ID).Find(&messages)
+
+	if err := m.UpdateSummary(conversation.ID, messages); err != nil {
+		t.Fatalf("更新摘要失败: %v", err)
+	}
+	mock.KeyInfo = `[{"type":"preference","key":"口味","value":"特别能吃辣","confidence":0.9},{"type":"person","key":"同事","value":"小王"}]`
+	if err := m.UpdateSummary(conversation.ID, messages); err != nil {
+		t.Fatalf("更新摘要失败: %v", err)
+	}
+
+	keyInfo, err := m.GetKeyInfo(conversation.ID)
+	if err != nil {
+		t.Fatalf("读取关键信息失败: %v", err)
+	}
+	if len(keyInfo) != 3 {
+		t.Fatalf("关键信息为 %v，期望合并为3条", keyInfo)
+	}
+	if keyInfo[0]["value"] != "特别能吃辣" || keyInfo[0]["confidence"] != 0.9 || keyInfo[0]["updated_at"] == nil {
+		t.Errorf("重复的关键信息应被新值覆盖并刷新时间: %v", keyInfo[0])
+	}
+	if keyInfo[1]["value"] != "周五" || keyInfo[2]["value"] != "小王" {
+		t.Errorf("旧条目应保持顺序，新条目追加在后: %v", keyInfo)
+	}
+}
+
+// higher_confidence 策略保留置信度较高的一条，none 策略直接使用新结果
+func TestMergeKeyInfoStrategies(t *testing.T) {
+	old := `[{"type":"preference","key":"口味","value":"喜欢吃辣","confidence":0.8}]`
+	lower := `[{"type":"preference","key":"口味","value":"不吃辣","confidence":0.3}]`
+
+	merged := parseKeyInfo(mergeKeyInfo(old, lower, MergeHigherConfidence))
+	if len(merged) != 1 || merged[0]["value"] != "喜欢吃辣" {
+		t.Errorf("higher_confidence 应保留置信度较高的旧值: %v", merged)
+	}
+	merged = parseKeyInfo(mergeKeyInfo(old, lower, MergeOverwrite))
+	if len(merged) != 1 || merged[0]["value"] != "不吃辣" {
+		t.Errorf("overwrite 应使用新值: %v", merged)
+	}
+	if got := mergeKeyInfo(old, lower, MergeNone); got != lower {
+		t.Errorf("none 应直接使用新结果，得到 %s", got)
+	}
+}
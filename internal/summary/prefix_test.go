@@ -0,0 +1,31 @@
+package summary
+
+import (
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// 摘要使用独立的系统提示词前缀，模板变量按对话展开
+func TestUpdateSummarySystemPrefix(t *testing.T) {
+	db := testutil.NewDB(t)
+	mock := &testutil.MockLLM{SummaryPrompt: "两人在约饭", KeyInfo: "[]"}
+	m := NewManager(db, &config.SummaryConfig{}, &config.PromptConfig{
+		SystemPrefix:        "你是补全助手",
+		SummarySystemPrefix: "你是对话 ${conversation_id} 的记录员",
+	}, mock, nil)
+	conversation := testutil.CreateConversation(t, db, "conv-prefix",
+		models.Message{SenderID: "alice", Content: "周五吃饭吧"},
+	)
+	var messages []models.Message
+	db.Where("conversation_id = ?", conversation.ID).Find(&messages)
+
+	if err := m.UpdateSummary(conversation.ID, messages); err != nil {
+		t.Fatalf("更新摘要失败: %v", err)
+	}
+	if mock.LastSummaryOptions == nil || mock.LastSummaryOptions.SystemPrefix != "你是对话 conv-prefix 的记录员" {
+		t.Errorf("摘要系统提示词前缀为 %+v", mock.LastSummaryOptions)
+	}
+}
@@ -0,0 +1,204 @@
+package summary
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"time"
+
+	"ChatRecommend/internal/entity"
+	"ChatRecommend/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// KeyInfoSourceRule 规则兜底提取的关键信息来源标记
+const KeyInfoSourceRule = "rule"
+
+// 规则提取的关键信息置信度（低于大模型给出的关键信息，higher_confidence 策略下不会覆盖大模型的结果）
+const ruleKeyInfoConfidence = 0.5
+
+// ruleScanMessages 规则提取时扫描的最近消息数
+const ruleScanMessages = 200
+
+// maxPreferenceItems 每个发送者每种偏好最多保留的表述数
+const maxPreferenceItems = 5
+
+// absoluteDatePattern 带月日的绝对日期（相对时间如"明天"不作为纪念日候选）
+var absoluteDatePattern = regexp.MustCompile(`^(?:\d{4}[-/年])?\d{1,2}[-/月]\d{1,2}[日号]?$`)
+
+// anniversaryKeywords 日期附近出现这些词时标注为对应的纪念日，按顺序匹配
+var anniversaryKeywords = []struct {
+	words []string
+	key   string
+}{
+	{[]string{"生日"}, "生日"},
+	{[]string{"纪念日", "周年", "结婚", "领证", "在一起", "恋爱"}, "纪念日"},
+}
+
+// preferencePattern 第一人称的偏好表述："我喜欢…""我最讨厌…""我不吃…"
+var preferencePattern = regexp.MustCompile(`我(?:也|还|一直|最|很|特别|超|非常|比较|真的|就|都)*` +
+	`(不喜欢|不爱吃|不爱喝|不能吃|不吃|受不了|讨厌|喜欢|爱吃|爱喝|爱看|爱玩)` +
+	`([^，。！？!?,.；;~～\s]{1,12})`)
+
+// preferenceTrailing 偏好对象末尾的语气词
+const preferenceTrailing = "了的啊呀哦吧啦呢嘛"
+
+// ruleKeyInfo 用正则和词典从最近的消息中提取关键信息（大模型不可用或没有给出关键信息时兜底）：
+// 绝对日期（纪念日候选）、约定地点、第一人称的偏好表述。结果标记 source=rule，最多 limit 条（0表示不限制）
+func ruleKeyInfo(messages []models.Message, limit int) []map[string]interface{} {
+	if len(messages) > ruleScanMessages {
+		messages = messages[len(messages)-ruleScanMessages:]
+	}
+
+	var items []map[string]interface{}
+	seen := make(map[string]bool)
+	add := func(item map[string]interface{}) {
+		id := keyInfoIdentity(item)
+		if seen[id] {
+			return
+		}
+		seen[id] = true
+		item["source"] = KeyInfoSourceRule
+		item["confidence"] = ruleKeyInfoConfidence
+		items = append(items, item)
+	}
+
+	// 偏好按发送者和倾向汇总，最近的表述在前
+	type preferenceKey struct{ sender, polarity string }
+	preferences := make(map[preferenceKey][]string)
+	var preferenceOrder []preferenceKey
+
+	// 从最新的消息往前扫描，同一 type+key 保留最近的一条
+	for i := len(messages) - 1; i >= 0; i-- {
+		msg := messages[i]
+		if msg.MessageType == models.MessageTypeDraft {
+			continue
+		}
+
+		for _, e := range entity.Extract(msg.Content) {
+			switch {
+			case e.Type == entity.Time && absoluteDatePattern.MatchString(e.Value):
+				add(map[string]interface{}{
+					"type":       "日期",
+					"key":        anniversaryKey(msg.Content),
+					"value":      e.Value,
+					"message_id": msg.ID,
+				})
+			case e.Type == entity.Location:
+				add(map[string]interface{}{
+					"type":       "地点",
+					"key":        "地点",
+					"value":      e.Value,
+					"message_id": msg.ID,
+				})
+			}
+		}
+
+		for _, match := range preferencePattern.FindAllStringSubmatch(msg.Content, -1) {
+			object := strings.TrimRight(match[2], preferenceTrailing)
+			if object == "" || strings.ContainsAny(object, "吗什么") {
+				continue
+			}
+			key := preferenceKey{sender: msg.SenderID, polarity: preferencePolarity(match[1])}
+			if _, ok := preferences[key]; !ok {
+				preferenceOrder = append(preferenceOrder, key)
+			}
+			if len(preferences[key]) < maxPreferenceItems && !containsString(preferences[key], match[1]+object) {
+				preferences[key] = append(preferences[key], match[1]+object)
+			}
+		}
+	}
+
+	for _, key := range preferenceOrder {
+		add(map[string]interface{}{
+			"type":      "偏好",
+			"key":       key.sender + key.polarity,
+			"value":     strings.Join(preferences[key], "、"),
+			"sender_id": key.sender,
+		})
+	}
+
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+	return items
+}
+
+// anniversaryKey 日期类关键信息的 key：消息中有纪念日相关的词时标注为对应纪念日，否则为纪念日候选
+func anniversaryKey(content string) string {
+	for _, group := range anniversaryKeywords {
+		for _, word := range group.words {
+			if strings.Contains(content, word) {
+				return group.key
+			}
+		}
+	}
+	return "纪念日候选"
+}
+
+// preferencePolarity 偏好倾向：喜欢或不喜欢
+func preferencePolarity(verb string) string {
+	switch verb {
+	case "不喜欢", "不爱吃", "不爱喝", "不能吃", "不吃", "受不了", "讨厌":
+		return "不喜欢"
+	default:
+		return "喜欢"
+	}
+}
+
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
+
+// fillKeyInfo 用规则提取的关键信息补充已有关键信息：只补充已有关键信息中没有的 type+key，
+// 已有的规则条目用新结果刷新，大模型给出的条目保持不变。返回合并后的JSON和新增的条目数
+func fillKeyInfo(existingJSON string, ruleItems []map[string]interface{}) (string, int) {
+	if len(ruleItems) == 0 {
+		return existingJSON, 0
+	}
+	existing := parseKeyInfo(existingJSON)
+	if existing == nil && existingJSON != "" {
+		// 已有关键信息无法解析时不覆盖
+		return existingJSON, 0
+	}
+
+	index := make(map[string]int, len(existing))
+	for i, item := range existing {
+		index[keyInfoIdentity(item)] = i
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	added, changed := 0, false
+	for _, item := range ruleItems {
+		i, ok := index[keyInfoIdentity(item)]
+		if !ok {
+			item["updated_at"] = now
+			index[keyInfoIdentity(item)] = len(existing)
+			existing = append(existing, item)
+			added++
+			changed = true
+			continue
+		}
+		// 值没变的规则条目保持原样，避免每次都改写 updated_at
+		if existing[i]["source"] == KeyInfoSourceRule && existing[i]["value"] != item["value"] {
+			item["updated_at"] = now
+			existing[i] = item
+			changed = true
+		}
+	}
+	if !changed {
+		return existingJSON, 0
+	}
+
+	raw, err := json.Marshal(existing)
+	if err != nil {
+		logrus.WithError(err).Warn("序列化规则关键信息失败")
+		return existingJSON, 0
+	}
+	return string(raw), added
+}
@@ -0,0 +1,126 @@
+package summary
+
+import (
+	"errors"
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// ruleKeyInfoMessages 含纪念日、约定地点和偏好表述的对话，草稿不参与提取
+func ruleKeyInfoMessages() []models.Message {
+	return []models.Message{
+		{SenderID: "alice", Content: "2024-05-01 是我们在一起的日子"},
+		{SenderID: "bob", Content: "明天到人民公园见"},
+		{SenderID: "alice", Content: "我最喜欢吃火锅了，我不吃香菜"},
+		{SenderID: "alice", Content: "我也喜欢看电影"},
+		{SenderID: "bob", Content: "我讨厌下雨", MessageType: models.MessageTypeDraft},
+	}
+}
+
+// findKeyInfo 按 type+key 查找关键信息
+func findKeyInfo(items []map[string]interface{}, typ, key string) map[string]interface{} {
+	for _, item := range items {
+		if item["type"] == typ && item["key"] == key {
+			return item
+		}
+	}
+	return nil
+}
+
+// 规则提取绝对日期、地点和第一人称偏好，标记来源和置信度
+func TestRuleKeyInfo(t *testing.T) {
+	items := ruleKeyInfo(ruleKeyInfoMessages(), 0)
+
+	if item := findKeyInfo(items, "日期", "纪念日"); item == nil || item["value"] != "2024-05-01" {
+		t.Errorf("应提取纪念日: %v", items)
+	}
+	if item := findKeyInfo(items, "地点", "地点"); item == nil || item["value"] != "人民公园" {
+		t.Errorf("应提取约定地点: %v", items)
+	}
+	if item := findKeyInfo(items, "偏好", "alice喜欢"); item == nil || item["value"] != "喜欢看电影、喜欢吃火锅" {
+		t.Errorf("应按发送者汇总喜欢的表述，最近的在前: %v", items)
+	}
+	if item := findKeyInfo(items, "偏好", "alice不喜欢"); item == nil || item["value"] != "不吃香菜" {
+		t.Errorf("应提取不喜欢的表述: %v", items)
+	}
+	if item := findKeyInfo(items, "偏好", "bob不喜欢"); item != nil {
+		t.Errorf("草稿不应参与提取: %v", item)
+	}
+	for _, item := range items {
+		if item["source"] != KeyInfoSourceRule || item["confidence"] != ruleKeyInfoConfidence {
+			t.Errorf("规则条目应标记来源和置信度: %v", item)
+		}
+	}
+
+	if limited := ruleKeyInfo(ruleKeyInfoMessages(), 2); len(limited) != 2 {
+		t.Errorf("限制条数后有 %d 条", len(limited))
+	}
+}
+
+// 补充时不覆盖大模型给出的同名条目，已有规则条目的值变化时刷新
+func TestFillKeyInfo(t *testing.T) {
+	existing := `[{"type":"地点","key":"地点","value":"海底捞"},{"type":"偏好","key":"alice喜欢","value":"喜欢吃辣","source":"rule"}]`
+	rule := []map[string]interface{}{
+		{"type": "地点", "key": "地点", "value": "人民公园", "source": KeyInfoSourceRule},
+		{"type": "偏好", "key": "alice喜欢", "value": "喜欢吃火锅", "source": KeyInfoSourceRule},
+		{"type": "日期", "key": "生日", "value": "3月5日", "source": KeyInfoSourceRule},
+	}
+
+	merged, added := fillKeyInfo(existing, rule)
+	items := parseKeyInfo(merged)
+	if added != 1 || len(items) != 3 {
+		t.Fatalf("应新增1条，合并结果为 %s", merged)
+	}
+	if item := findKeyInfo(items, "地点", "地点"); item["value"] != "海底捞" {
+		t.Errorf("大模型给出的条目不应被覆盖: %v", item)
+	}
+	if item := findKeyInfo(items, "偏好", "alice喜欢"); item["value"] != "喜欢吃火锅" {
+		t.Errorf("规则条目应刷新: %v", item)
+	}
+
+	if unchanged, added := fillKeyInfo("not json", rule); unchanged != "not json" || added != 0 {
+		t.Errorf("无法解析的关键信息不应被覆盖: %s", unchanged)
+	}
+}
+
+// 大模型没有给出关键信息时用规则结果补充；生成失败时只保存规则关键信息，摘要提示词和版本不变
+func TestUpdateSummaryRuleKeyInfoFallback(t *testing.T) {
+	db := testutil.NewDB(t)
+	mock := &testutil.MockLLM{SummaryPrompt: "两人在约会", KeyInfo: "[]"}
+	m := NewManager(db, &config.SummaryConfig{RuleKeyInfo: true}, &config.PromptConfig{}, mock, nil)
+	messages := ruleKeyInfoMessages()
+	conversation := testutil.CreateConversation(t, db, "conv-rule", messages...)
+
+	if err := m.UpdateSummary(conversation.ID, messages); err != nil {
+		t.Fatalf("更新摘要失败: %v", err)
+	}
+	keyInfo, err := m.GetKeyInfo(conversation.ID)
+	if err != nil || findKeyInfo(keyInfo, "地点", "地点") == nil {
+		t.Fatalf("大模型没有给出关键信息时应用规则补充: %v, %v", keyInfo, err)
+	}
+
+	failing := testutil.CreateConversation(t, db, "conv-failing", ruleKeyInfoMessages()...)
+	mock.Err = errors.New("服务不可用")
+	if err := m.UpdateSummary(failing.ID, messages); err == nil {
+		t.Fatal("大模型失败时应返回错误")
+	}
+	var summary models.Summary
+	if err := db.Where("conversation_id = ?", failing.ID).First(&summary).Error; err != nil {
+		t.Fatalf("查询摘要失败: %v", err)
+	}
+	if summary.Prompt != "" || summary.LastMessageCount != 0 || findKeyInfo(parseKeyInfo(summary.KeyInfo), "日期", "纪念日") == nil {
+		t.Errorf("生成失败时应只保存规则关键信息: %+v", summary)
+	}
+
+	disabled := NewManager(db, &config.SummaryConfig{}, &config.PromptConfig{}, &testutil.MockLLM{SummaryPrompt: "两人在约会", KeyInfo: "[]"}, nil)
+	plain := testutil.CreateConversation(t, db, "conv-plain", ruleKeyInfoMessages()...)
+	if err := disabled.UpdateSummary(plain.ID, messages); err != nil {
+		t.Fatalf("更新摘要失败: %v", err)
+	}
+	if keyInfo, _ := disabled.GetKeyInfo(plain.ID); len(keyInfo) != 0 {
+		t.Errorf("未开启规则兜底时不应补充关键信息: %v", keyInfo)
+	}
+}
@@ -0,0 +1,78 @@
+package summary
+
+import (
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// 三种摘要格式分别把对应的格式指令传给大模型并记录在摘要上，未配置时使用叙事式
+func TestUpdateSummaryStyleInstruction(t *testing.T) {
+	for _, style := range []string{"", models.SummaryStyleBullets, models.SummaryStyleNarrative, models.SummaryStyleTimeline} {
+		db := testutil.NewDB(t)
+		mock := &testutil.MockLLM{SummaryPrompt: "两人在约饭", KeyInfo: "[]"}
+		m := NewManager(db, &config.SummaryConfig{Style: style}, &config.PromptConfig{}, mock, nil)
+		conversation := testutil.CreateConversation(t, db, "conv-style", models.Message{SenderID: "alice", Content: "周五吃饭吧"})
+		var messages []models.Message
+		db.Where("conversation_id = ?", conversation.ID).Find(&messages)
+
+		if err := m.UpdateSummary(conversation.ID, messages); err != nil {
+			t.Fatalf("更新摘要失败: %v", err)
+		}
+		want := style
+		if want == "" {
+			want = models.SummaryStyleNarrative
+		}
+		opts := mock.LastSummaryOptions
+		if opts == nil || opts.Style != want || opts.Instruction != styleInstructions[want] || opts.Instruction == "" {
+			t.Errorf("格式 %q 的摘要参数为 %+v，期望 %s 及其指令", style, opts, want)
+		}
+		var summary models.Summary
+		db.Where("conversation_id = ?", conversation.ID).First(&summary)
+		if summary.Style != want {
+			t.Errorf("格式 %q 生成的摘要记录格式为 %q，期望 %q", style, summary.Style, want)
+		}
+	}
+}
+
+// 对话设置的摘要格式覆盖全局配置，格式切换后摘要需要重新生成
+func TestSummaryStyleConversationOverride(t *testing.T) {
+	db := testutil.NewDB(t)
+	mock := &testutil.MockLLM{SummaryPrompt: "- 周五吃饭", KeyInfo: "[]"}
+	m := NewManager(db, &config.SummaryConfig{Style: models.SummaryStyleNarrative, AutoUpdate: true,
+		UpdateThresholdMessages: 10, UpdateThresholdHours: 24}, &config.PromptConfig{}, mock, nil)
+	conversation := testutil.CreateConversation(t, db, "conv-style", models.Message{SenderID: "alice", Content: "周五吃饭吧"})
+	if err := conversation.SetSettings(&models.ConversationSettings{SummaryStyle: models.SummaryStyleBullets}); err != nil {
+		t.Fatal(err)
+	}
+	db.Model(&conversation).Update("settings", conversation.Settings)
+	var messages []models.Message
+	db.Where("conversation_id = ?", conversation.ID).Find(&messages)
+
+	if err := m.UpdateSummary(conversation.ID, messages); err != nil {
+		t.Fatalf("更新摘要失败: %v", err)
+	}
+	if opts := mock.LastSummaryOptions; opts == nil || opts.Style != models.SummaryStyleBullets {
+		t.Fatalf("对话覆盖后的摘要参数为 %+v，期望 bullets", opts)
+	}
+
+	summary, err := m.GetOrCreateSummary(conversation.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.ShouldUpdateSummary(summary, 1) {
+		t.Error("格式未变且未达到阈值时不应更新摘要")
+	}
+	if err := conversation.SetSettings(&models.ConversationSettings{SummaryStyle: models.SummaryStyleTimeline}); err != nil {
+		t.Fatal(err)
+	}
+	db.Model(&conversation).Update("settings", conversation.Settings)
+	if !m.ShouldUpdateSummary(summary, 1) {
+		t.Error("摘要格式切换后应重新生成摘要")
+	}
+	if err := conversation.SetSettings(&models.ConversationSettings{SummaryStyle: "table"}); err == nil {
+		t.Error("不支持的摘要格式应校验失败")
+	}
+}
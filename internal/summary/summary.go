@@ -3,10 +3,15 @@ package summary
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/llm"
 	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/prompt"
+	"ChatRecommend/internal/textutil"
+	"ChatRecommend/internal/webhook"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
@@ -15,23 +20,29 @@ import (
 type Manager struct {
 	db     *gorm.DB
 	config *config.SummaryConfig
-	llm    LLMInterface
-}
-
-// LLMInterface 大模型接口（用于生成摘要）
-type LLMInterface interface {
-	GenerateSummary(messages []models.Message, existingSummary *models.Summary) (string, string, error)
+	prompt *config.PromptConfig
+	llm    llm.Service
+	hooks  *webhook.Dispatcher
+	// 数据库中的提示词模板，激活版本覆盖配置文件中的摘要提示词前缀
+	templates *prompt.Store
 }
 
 // NewManager 创建摘要管理器
-func NewManager(db *gorm.DB, cfg *config.SummaryConfig, llm LLMInterface) *Manager {
+func NewManager(db *gorm.DB, cfg *config.SummaryConfig, promptCfg *config.PromptConfig, llmService llm.Service, hooks *webhook.Dispatcher) *Manager {
 	return &Manager{
 		db:     db,
 		config: cfg,
-		llm:    llm,
+		prompt: promptCfg,
+		llm:    llmService,
+		hooks:  hooks,
 	}
 }
 
+// SetPromptStore 设置提示词模板存储，模板在数据库中有激活版本时覆盖配置文件
+func (m *Manager) SetPromptStore(store *prompt.Store) {
+	m.templates = store
+}
+
 // GetOrCreateSummary 获取或创建对话摘要
 func (m *Manager) GetOrCreateSummary(conversationID uint) (*models.Summary, error) {
 	var summary models.Summary
@@ -65,6 +76,17 @@ func (m *Manager) ShouldUpdateSummary(summary *models.Summary, currentMessageCou
 		return false
 	}
 
+	// 距上次重算不足最小间隔时先不重算，新消息攒到下次（从未生成过的摘要不受限制）
+	if m.config.MinIntervalSeconds > 0 && summary.Prompt != "" &&
+		time.Since(summary.LastUpdatedAt) < time.Duration(m.config.MinIntervalSeconds)*time.Second {
+		return false
+	}
+
+	// 摘要格式切换后重新生成，使摘要提示词符合新格式（加格式字段之前生成的摘要按阈值正常更新）
+	if summary.Prompt != "" && summary.Style != "" && summary.Style != m.currentStyle(summary.ConversationID) {
+		return true
+	}
+
 	// 检查消息数量阈值
 	if currentMessageCount-summary.LastMessageCount >= int64(m.config.UpdateThresholdMessages) {
 		return true
@@ -78,25 +100,87 @@ func (m *Manager) ShouldUpdateSummary(summary *models.Summary, currentMessageCou
 	return false
 }
 
-// UpdateSummary 更新对话摘要
-func (m *Manager) UpdateSummary(conversationID uint, messages []models.Message) error {
-	summary, err := m.GetOrCreateSummary(conversationID)
-	if err != nil {
-		return err
+// Preview 摘要预览：将要生成的摘要，不落库
+type Preview struct {
+	ConversationID uint   `json:"conversation_id"`
+	Prompt         string `json:"prompt"`
+	// 合并后的关键信息
+	KeyInfo []map[string]interface{} `json:"key_info"`
+	// 相对当前摘要新增的关键信息
+	AddedKeyInfo []map[string]interface{} `json:"added_key_info"`
+	// 摘要格式
+	Style string `json:"style"`
+	// 保存后的版本号
+	Version      int   `json:"version"`
+	MessageCount int64 `json:"message_count"`
+}
+
+// PreviewSummary 用对话当前的全部消息生成摘要预览，不修改数据库（对话还没有摘要时也不会创建）
+func (m *Manager) PreviewSummary(conversationID uint) (*Preview, error) {
+	var messages []models.Message
+	if err := m.db.Where("conversation_id = ?", conversationID).
+		Scopes(models.ExcludeDrafts).
+		Order("sequence ASC, created_at ASC").
+		Find(&messages).Error; err != nil {
+		return nil, fmt.Errorf("查询消息失败: %w", err)
 	}
 
-	// 调用大模型生成摘要
-	prompt, keyInfo, err := m.llm.GenerateSummary(messages, summary)
+	var summary models.Summary
+	err := m.db.Where("conversation_id = ?", conversationID).First(&summary).Error
+	if err == gorm.ErrRecordNotFound {
+		summary = models.Summary{ConversationID: conversationID, KeyInfo: "[]", Version: 1}
+	} else if err != nil {
+		return nil, fmt.Errorf("查询摘要失败: %w", err)
+	}
+
+	oldKeyInfo := summary.KeyInfo
+	if err := m.generate(&summary, messages); err != nil {
+		return nil, err
+	}
+	return &Preview{
+		ConversationID: conversationID,
+		Prompt:         summary.Prompt,
+		KeyInfo:        parseKeyInfo(summary.KeyInfo),
+		AddedKeyInfo:   diffKeyInfo(oldKeyInfo, summary.KeyInfo),
+		Style:          summary.Style,
+		Version:        summary.Version,
+		MessageCount:   summary.LastMessageCount,
+	}, nil
+}
+
+// generate 调用大模型生成新摘要并合并关键信息，只修改传入的摘要对象，由调用方决定是否保存
+func (m *Manager) generate(summary *models.Summary, messages []models.Message) error {
+	opts := m.summaryOptions(summary.ConversationID)
+	prompt, keyInfo, err := m.llm.GenerateSummary(m.abbreviateMessages(messages), summary, opts)
 	if err != nil {
 		return fmt.Errorf("生成摘要失败: %w", err)
 	}
 
-	// 更新摘要
 	summary.Prompt = prompt
-	summary.KeyInfo = keyInfo
+	summary.Style = opts.Style
+	summary.KeyInfo = mergeKeyInfo(summary.KeyInfo, keyInfo, m.config.KeyInfoMergeStrategy)
+	if len(parseKeyInfo(keyInfo)) == 0 && m.config.RuleKeyInfo {
+		// 大模型没有给出关键信息时用规则提取的结果补充
+		summary.KeyInfo, _ = fillKeyInfo(summary.KeyInfo, ruleKeyInfo(messages, m.config.KeyInfoCount))
+	}
 	summary.LastMessageCount = int64(len(messages))
 	summary.LastUpdatedAt = time.Now()
 	summary.Version++
+	return nil
+}
+
+// UpdateSummary 更新对话摘要
+func (m *Manager) UpdateSummary(conversationID uint, messages []models.Message) error {
+	summary, err := m.GetOrCreateSummary(conversationID)
+	if err != nil {
+		return err
+	}
+
+	oldKeyInfo := summary.KeyInfo
+	if err := m.generate(summary, messages); err != nil {
+		m.saveRuleKeyInfo(summary, messages)
+		return err
+	}
 
 	if err := m.db.Save(summary).Error; err != nil {
 		return fmt.Errorf("保存摘要失败: %w", err)
@@ -107,9 +191,136 @@ func (m *Manager) UpdateSummary(conversationID uint, messages []models.Message)
 		"version":         summary.Version,
 	}).Info("对话摘要已更新")
 
+	m.hooks.Dispatch(webhook.EventSummaryUpdated, map[string]interface{}{
+		"conversation_id": conversationID,
+		"version":         summary.Version,
+		"prompt":          summary.Prompt,
+	})
+	if added := diffKeyInfo(oldKeyInfo, summary.KeyInfo); len(added) > 0 {
+		m.hooks.Dispatch(webhook.EventKeyInfoAdded, map[string]interface{}{
+			"conversation_id": conversationID,
+			"key_info":        added,
+		})
+	}
+
 	return nil
 }
 
+// saveRuleKeyInfo 大模型生成摘要失败时用规则提取关键信息兜底：只保存关键信息，摘要提示词、版本和消息计数不变，
+// 下次检查时仍会重新调用大模型
+func (m *Manager) saveRuleKeyInfo(summary *models.Summary, messages []models.Message) {
+	if !m.config.RuleKeyInfo {
+		return
+	}
+	oldKeyInfo := summary.KeyInfo
+	keyInfo, added := fillKeyInfo(oldKeyInfo, ruleKeyInfo(messages, m.config.KeyInfoCount))
+	if keyInfo == oldKeyInfo {
+		return
+	}
+	if err := m.db.Model(summary).Update("key_info", keyInfo).Error; err != nil {
+		logrus.WithError(err).Warn("保存规则关键信息失败")
+		return
+	}
+	summary.KeyInfo = keyInfo
+
+	logrus.WithFields(logrus.Fields{
+		"conversation_id": summary.ConversationID,
+		"added":           added,
+	}).Info("摘要生成失败，已用规则提取关键信息兜底")
+
+	if items := diffKeyInfo(oldKeyInfo, keyInfo); len(items) > 0 {
+		m.hooks.Dispatch(webhook.EventKeyInfoAdded, map[string]interface{}{
+			"conversation_id": summary.ConversationID,
+			"key_info":        items,
+		})
+	}
+}
+
+// styleInstructions 各摘要格式对应的生成指令，随请求传给大模型，生成的摘要提示词即为对应格式
+var styleInstructions = map[string]string{
+	models.SummaryStyleBullets:   "摘要提示词使用要点式：每行一个要点，以\"- \"开头，每条不超过30字，不要写成段落。",
+	models.SummaryStyleNarrative: "摘要提示词使用叙事式：用一段连贯的自然语言概括对话的来龙去脉和当前进展，不要分点。",
+	models.SummaryStyleTimeline:  "摘要提示词使用时间线式：按时间先后每行一个事件，格式为\"[时间] 事件\"，时间不明确时写\"[早先]\"。",
+}
+
+// summaryStyle 对话使用的摘要格式：对话设置优先，否则使用全局配置
+func (m *Manager) summaryStyle(conversation *models.Conversation) string {
+	if settings, err := conversation.GetSettings(); err == nil && settings.SummaryStyle != "" {
+		return settings.SummaryStyle
+	}
+	if m.config.Style != "" {
+		return m.config.Style
+	}
+	return models.SummaryStyleNarrative
+}
+
+// currentStyle 对话当前应使用的摘要格式
+func (m *Manager) currentStyle(conversationID uint) string {
+	var conversation models.Conversation
+	if err := m.db.Select("settings").First(&conversation, conversationID).Error; err != nil {
+		logrus.WithError(err).Warn("查询对话失败")
+	}
+	return m.summaryStyle(&conversation)
+}
+
+// summaryOptions 确定摘要格式并展开配置的摘要提示词前缀
+func (m *Manager) summaryOptions(conversationID uint) *llm.SummaryOptions {
+	var conversation models.Conversation
+	if err := m.db.Select("conversation_id", "settings").First(&conversation, conversationID).Error; err != nil {
+		logrus.WithError(err).Warn("查询对话失败")
+	}
+
+	style := m.summaryStyle(&conversation)
+	opts := &llm.SummaryOptions{
+		Style:       style,
+		Instruction: styleInstructions[style],
+	}
+	var fallback string
+	if m.prompt != nil {
+		fallback = m.prompt.SummarySystemPrefix
+	}
+	if template := m.templates.Resolve(models.PromptSummarySystemPrefix, fallback); strings.TrimSpace(template) != "" {
+		opts.SystemPrefix = strings.TrimSpace(textutil.ExpandVars(template, map[string]string{
+			"conversation_id": conversation.ConversationID,
+			"date":            time.Now().Format("2006-01-02"),
+		}))
+	}
+	return opts
+}
+
+// diffKeyInfo 找出新关键信息中旧关键信息没有的条目（按 type+key 判断，值被更新的条目不算新增）
+func diffKeyInfo(oldJSON, newJSON string) []map[string]interface{} {
+	oldItems := parseKeyInfo(oldJSON)
+	newItems := parseKeyInfo(newJSON)
+
+	existing := make(map[string]bool, len(oldItems))
+	for _, item := range oldItems {
+		existing[keyInfoIdentity(item)] = true
+	}
+
+	added := make([]map[string]interface{}, 0)
+	for _, item := range newItems {
+		if !existing[keyInfoIdentity(item)] {
+			added = append(added, item)
+		}
+	}
+	return added
+}
+
+// abbreviateMessages 缩减超长消息，避免摘要请求超出token限制（返回副本，不修改调用方的消息）
+func (m *Manager) abbreviateMessages(messages []models.Message) []models.Message {
+	if m.config.MaxMessageChars <= 0 {
+		return messages
+	}
+
+	result := make([]models.Message, len(messages))
+	for i, msg := range messages {
+		msg.Content, _ = textutil.Abbreviate(msg.Content, m.config.MaxMessageChars)
+		result[i] = msg
+	}
+	return result
+}
+
 // GetSummaryPrompt 获取摘要提示词
 func (m *Manager) GetSummaryPrompt(conversationID uint) (string, error) {
 	summary, err := m.GetOrCreateSummary(conversationID)
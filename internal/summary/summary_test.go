@@ -0,0 +1,48 @@
+package summary
+
+import (
+	"errors"
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+)
+
+// 摘要管理器通过 llm.Service 生成摘要，可以用替身验证请求和落库结果
+func TestUpdateSummaryUsesLLMService(t *testing.T) {
+	db := testutil.NewDB(t)
+	mock := &testutil.MockLLM{SummaryPrompt: "两人约了周五吃火锅", KeyInfo: `[{"type":"event","key":"约饭","value":"周五火锅"}]`}
+	m := NewManager(db, &config.SummaryConfig{}, &config.PromptConfig{}, mock, nil)
+	conversation := testutil.CreateConversation(t, db, "conv-summary",
+		models.Message{SenderID: "alice", Content: "周五吃火锅吧"},
+		models.Message{SenderID: "bob", Content: "好"},
+	)
+	var messages []models.Message
+	db.Where("conversation_id = ?", conversation.ID).Order("sequence").Find(&messages)
+
+	if err := m.UpdateSummary(conversation.ID, messages); err != nil {
+		t.Fatalf("更新摘要失败: %v", err)
+	}
+	if mock.SummaryCalls != 1 || len(mock.LastMessages) != 2 {
+		t.Fatalf("大模型调用 %d 次，传入 %d 条消息", mock.SummaryCalls, len(mock.LastMessages))
+	}
+
+	prompt, err := m.GetSummaryPrompt(conversation.ID)
+	if err != nil || prompt != mock.SummaryPrompt {
+		t.Fatalf("摘要提示词为 %q, %v", prompt, err)
+	}
+	keyInfo, err := m.GetKeyInfo(conversation.ID)
+	if err != nil || len(keyInfo) != 1 || keyInfo[0]["value"] != "周五火锅" {
+		t.Fatalf("关键信息为 %v, %v", keyInfo, err)
+	}
+
+	// 大模型失败时返回错误，已有摘要保持不变
+	mock.Err = errors.New("服务不可用")
+	if err := m.UpdateSummary(conversation.ID, messages); err == nil {
+		t.Fatal("大模型失败时应返回错误")
+	}
+	if prompt, _ := m.GetSummaryPrompt(conversation.ID); prompt != mock.SummaryPrompt {
+		t.Fatalf("失败后摘要提示词变为 %q", prompt)
+	}
+}
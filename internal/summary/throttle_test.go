@@ -0,0 +1,28 @@
+package summary
+
+import (
+	"testing"
+	"time"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+)
+
+// 消息数达到阈值但距上次重算不足最小间隔时不重算，间隔过后再重算；从未生成过的摘要不受限制
+func TestShouldUpdateSummaryThrottled(t *testing.T) {
+	m := NewManager(nil, &config.SummaryConfig{AutoUpdate: true, UpdateThresholdMessages: 5, UpdateThresholdHours: 24, MinIntervalSeconds: 60}, &config.PromptConfig{}, nil, nil)
+
+	recent := &models.Summary{Prompt: "两人在约饭", LastMessageCount: 10, LastUpdatedAt: time.Now().Add(-10 * time.Second)}
+	if m.ShouldUpdateSummary(recent, 30) {
+		t.Error("距上次重算不足最小间隔时不应重算")
+	}
+
+	stale := &models.Summary{Prompt: "两人在约饭", LastMessageCount: 10, LastUpdatedAt: time.Now().Add(-2 * time.Minute)}
+	if !m.ShouldUpdateSummary(stale, 30) {
+		t.Error("超过最小间隔且达到消息数阈值时应重算")
+	}
+
+	if !m.ShouldUpdateSummary(&models.Summary{LastUpdatedAt: time.Now()}, 5) {
+		t.Error("从未生成过的摘要不应受最小间隔限制")
+	}
+}
@@ -0,0 +1,160 @@
+package summary
+
+import (
+	"fmt"
+	"time"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// 后台摘要更新的优先级策略
+const (
+	// WorkerPriorityRecent 按最后消息时间降序，最近活跃的对话先更新
+	WorkerPriorityRecent = "recent"
+	// WorkerPrioritySequential 按对话ID顺序轮流扫描，每轮从上一轮停下的位置继续
+	WorkerPrioritySequential = "sequential"
+)
+
+// Worker 后台定时检查并更新到期的对话摘要（消息数或时间达到阈值，见 ShouldUpdateSummary）。
+// 保存消息时已会触发更新，Worker 用于补上长时间没有新消息、只达到时间阈值的对话
+type Worker struct {
+	manager *Manager
+	config  *config.SummaryWorkerConfig
+	// sequential 策略下上一轮处理到的对话ID
+	cursor uint
+	stop   chan struct{}
+}
+
+// NewWorker 创建后台摘要更新任务
+func NewWorker(manager *Manager, cfg *config.SummaryWorkerConfig) *Worker {
+	return &Worker{
+		manager: manager,
+		config:  cfg,
+		stop:    make(chan struct{}),
+	}
+}
+
+// Start 按 interval_seconds 定时执行，interval_seconds 为0时不启动
+func (w *Worker) Start() {
+	if w.config.IntervalSeconds <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(w.config.IntervalSeconds) * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := w.RunOnce(); err != nil {
+					logrus.WithError(err).Error("后台更新摘要失败")
+				}
+			case <-w.stop:
+				return
+			}
+		}
+	}()
+	logrus.WithFields(logrus.Fields{
+		"interval_seconds": w.config.IntervalSeconds,
+		"priority":         w.config.Priority,
+	}).Info("后台摘要更新已启动")
+}
+
+// Stop 停止定时执行
+func (w *Worker) Stop() {
+	close(w.stop)
+}
+
+// RunOnce 执行一轮：按优先级策略依次检查对话，更新到期的摘要，最多更新 batch_size 个，返回更新的对话ID（按更新顺序）。
+// 已归档的对话不更新，最后消息早于 inactive_days 天的对话跳过
+func (w *Worker) RunOnce() ([]uint, error) {
+	if !w.manager.config.AutoUpdate {
+		return nil, nil
+	}
+
+	candidates, err := w.candidates()
+	if err != nil {
+		return nil, err
+	}
+
+	var updated []uint
+	for _, conversation := range candidates {
+		if w.config.BatchSize > 0 && len(updated) >= w.config.BatchSize {
+			break
+		}
+		w.cursor = conversation.ID
+
+		ok, err := w.manager.updateIfDue(conversation.ID)
+		if err != nil {
+			logrus.WithError(err).WithField("conversation_id", conversation.ConversationID).Warn("后台更新摘要失败")
+			continue
+		}
+		if ok {
+			updated = append(updated, conversation.ID)
+		}
+	}
+
+	// sequential 策略扫完全部对话后从头开始
+	if w.config.Priority == WorkerPrioritySequential && (w.config.BatchSize <= 0 || len(updated) < w.config.BatchSize) {
+		w.cursor = 0
+	}
+	if len(updated) > 0 {
+		logrus.WithField("count", len(updated)).Info("后台摘要更新完成")
+	}
+	return updated, nil
+}
+
+// candidates 按优先级策略排序的待检查对话
+func (w *Worker) candidates() ([]models.Conversation, error) {
+	query := w.manager.db.Where("archived = ?", false)
+	if w.config.InactiveDays > 0 {
+		query = query.Where("last_message_at >= ?", time.Now().AddDate(0, 0, -w.config.InactiveDays))
+	}
+	if w.config.Priority == WorkerPrioritySequential {
+		query = query.Where("id > ?", w.cursor).Order("id ASC")
+	} else {
+		query = query.Order("last_message_at DESC, id DESC")
+	}
+
+	var conversations []models.Conversation
+	if err := query.Find(&conversations).Error; err != nil {
+		return nil, fmt.Errorf("查询对话失败: %w", err)
+	}
+	return conversations, nil
+}
+
+// updateIfDue 对话摘要到期时用全部消息重新生成，返回是否更新
+func (m *Manager) updateIfDue(conversationID uint) (bool, error) {
+	var count int64
+	if err := m.db.Model(&models.Message{}).
+		Where("conversation_id = ?", conversationID).
+		Scopes(models.ExcludeDrafts).
+		Count(&count).Error; err != nil {
+		return false, fmt.Errorf("统计消息失败: %w", err)
+	}
+	if count == 0 {
+		return false, nil
+	}
+
+	summary, err := m.GetOrCreateSummary(conversationID)
+	if err != nil {
+		return false, err
+	}
+	if !m.ShouldUpdateSummary(summary, count) {
+		return false, nil
+	}
+
+	var messages []models.Message
+	if err := m.db.Where("conversation_id = ?", conversationID).
+		Scopes(models.ExcludeDrafts).
+		Order("sequence ASC, created_at ASC").
+		Find(&messages).Error; err != nil {
+		return false, fmt.Errorf("查询消息失败: %w", err)
+	}
+	if err := m.UpdateSummary(conversationID, messages); err != nil {
+		return false, err
+	}
+	return true, nil
+}
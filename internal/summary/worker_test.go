@@ -0,0 +1,76 @@
+package summary
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/models"
+	"ChatRecommend/internal/testutil"
+	"gorm.io/gorm"
+)
+
+// newWorkerTestConversations 创建最后消息时间不同的对话，返回按创建顺序的对话ID：
+// 两天前、一小时前、十分钟前、四十天前（长期不活跃）、十分钟前但已归档
+func newWorkerTestConversations(t *testing.T, db *gorm.DB) []uint {
+	t.Helper()
+	now := time.Now()
+	lastMessageAt := []time.Duration{48 * time.Hour, time.Hour, 10 * time.Minute, 40 * 24 * time.Hour, 10 * time.Minute}
+	ids := make([]uint, len(lastMessageAt))
+	for i, ago := range lastMessageAt {
+		conversation := testutil.CreateConversation(t, db, "conv-worker-"+string(rune('a'+i)),
+			models.Message{SenderID: "alice", Content: "周末有空吗"})
+		updates := map[string]interface{}{"last_message_at": now.Add(-ago), "archived": i == 4}
+		if err := db.Model(&conversation).Updates(updates).Error; err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = conversation.ID
+	}
+	return ids
+}
+
+func newWorkerTestManager(t *testing.T) (*Manager, *gorm.DB) {
+	t.Helper()
+	db := testutil.NewDB(t)
+	mock := &testutil.MockLLM{SummaryPrompt: "两人在约周末", KeyInfo: "[]"}
+	return NewManager(db, &config.SummaryConfig{AutoUpdate: true, UpdateThresholdMessages: 1, UpdateThresholdHours: 24},
+		&config.PromptConfig{}, mock, nil), db
+}
+
+// recent 策略按最后消息时间降序更新，跳过归档和长期不活跃的对话，已更新的对话下一轮不再更新
+func TestWorkerRunOnceRecentFirst(t *testing.T) {
+	m, db := newWorkerTestManager(t)
+	ids := newWorkerTestConversations(t, db)
+	w := NewWorker(m, &config.SummaryWorkerConfig{BatchSize: 2, Priority: WorkerPriorityRecent, InactiveDays: 30})
+
+	for _, want := range [][]uint{{ids[2], ids[1]}, {ids[0]}, nil} {
+		updated, err := w.RunOnce()
+		if err != nil {
+			t.Fatalf("后台更新失败: %v", err)
+		}
+		if !reflect.DeepEqual(updated, want) {
+			t.Errorf("本轮更新的对话为 %v，期望 %v", updated, want)
+		}
+	}
+}
+
+// sequential 策略按对话ID分批轮流扫描，扫完后从头开始
+func TestWorkerRunOnceSequential(t *testing.T) {
+	m, db := newWorkerTestManager(t)
+	ids := newWorkerTestConversations(t, db)
+	w := NewWorker(m, &config.SummaryWorkerConfig{BatchSize: 2, Priority: WorkerPrioritySequential})
+
+	for _, want := range [][]uint{{ids[0], ids[1]}, {ids[2], ids[3]}, nil} {
+		updated, err := w.RunOnce()
+		if err != nil {
+			t.Fatalf("后台更新失败: %v", err)
+		}
+		if !reflect.DeepEqual(updated, want) {
+			t.Errorf("本轮更新的对话为 %v，期望 %v", updated, want)
+		}
+	}
+	if w.cursor != 0 {
+		t.Errorf("扫完全部对话后游标为 %d，期望从头开始", w.cursor)
+	}
+}
@@ -0,0 +1,140 @@
+// Package testutil 测试共用的内存数据库和大模型替身，只在 _test.go 中使用
+package testutil
+
+import (
+	"sync"
+	"testing"
+
+	"ChatRecommend/internal/config"
+	"ChatRecommend/internal/database"
+	"ChatRecommend/internal/llm"
+	"ChatRecommend/internal/migrations"
+	"ChatRecommend/internal/models"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func init() {
+	logrus.SetLevel(logrus.ErrorLevel)
+}
+
+// NewDB 创建执行过全部迁移的内存数据库，每次调用得到独立的库，测试结束时关闭
+func NewDB(t testing.TB) *gorm.DB {
+	t.Helper()
+
+	db, err := database.Open(&config.Config{Database: config.DatabaseConfig{
+		DBPath:        config.MemoryDBPath,
+		BusyTimeoutMs: 5000,
+	}})
+	if err != nil {
+		t.Fatalf("打开内存数据库失败: %v", err)
+	}
+	db = db.Session(&gorm.Session{Logger: logger.Discard})
+	if err := migrations.Run(db); err != nil {
+		t.Fatalf("执行迁移失败: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// CreateConversation 创建对话并按顺序保存消息（sequence 从1递增），返回对话
+func CreateConversation(t testing.TB, db *gorm.DB, conversationID string, messages ...models.Message) models.Conversation {
+	t.Helper()
+
+	conversation := models.Conversation{ConversationID: conversationID}
+	if err := db.Create(&conversation).Error; err != nil {
+		t.Fatalf("创建对话失败: %v", err)
+	}
+	for i := range messages {
+		messages[i].ConversationID = conversation.ID
+		if messages[i].Sequence == 0 {
+			messages[i].Sequence = int64(i + 1)
+		}
+		if messages[i].MessageType == "" {
+			messages[i].MessageType = "text"
+		}
+		if err := db.Create(&messages[i]).Error; err != nil {
+			t.Fatalf("保存消息失败: %v", err)
+		}
+	}
+	return conversation
+}
+
+// MockLLM 记录调用参数并返回预设结果的大模型替身，实现 llm.Service
+type MockLLM struct {
+	mu sync.Mutex
+
+	// 预设的补全建议、结构化建议、摘要结果和错误
+	Suggestions   []string
+	Details       []models.Suggestion
+	SummaryPrompt string
+	KeyInfo       string
+	Err           error
+
+	// 调用记录
+	CompleteCalls      int
+	SummaryCalls       int
+	LastContext        string
+	LastInput          string
+	LastOptions        *llm.CompleteOptions
+	LastSummaryOptions *llm.SummaryOptions
+	LastMessages       []models.Message
+}
+
+var _ llm.Service = (*MockLLM)(nil)
+
+// Complete 实现 llm.Service
+func (m *MockLLM) Complete(ctx string, input string, opts *llm.CompleteOptions) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.CompleteCalls++
+	m.LastContext, m.LastInput, m.LastOptions = ctx, input, opts
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	return append([]string(nil), m.Suggestions...), nil
+}
+
+// CompleteStructured 实现 llm.Service，未预设结构化建议时由 Suggestions 生成
+func (m *MockLLM) CompleteStructured(ctx string, input string, opts *llm.CompleteOptions) ([]models.Suggestion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.CompleteCalls++
+	m.LastContext, m.LastInput, m.LastOptions = ctx, input, opts
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	if m.Details != nil {
+		return append([]models.Suggestion(nil), m.Details...), nil
+	}
+	details := make([]models.Suggestion, len(m.Suggestions))
+	for i, suggestion := range m.Suggestions {
+		details[i] = models.Suggestion{Text: suggestion}
+	}
+	return details, nil
+}
+
+// GenerateSummary 实现 llm.Service
+func (m *MockLLM) GenerateSummary(messages []models.Message, existingSummary *models.Summary, opts *llm.SummaryOptions) (string, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.SummaryCalls++
+	m.LastMessages, m.LastSummaryOptions = messages, opts
+	if m.Err != nil {
+		return "", "", m.Err
+	}
+	return m.SummaryPrompt, m.KeyInfo, nil
+}
+
+// Calls 补全调用次数（并发安全）
+func (m *MockLLM) Calls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.CompleteCalls
+}
@@ -0,0 +1,39 @@
+package textutil
+
+import (
+	"strings"
+	"unicode"
+)
+
+// normalizeForMatch 统一大小写并把全角字母数字转为半角，便于关键词匹配
+func normalizeForMatch(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '！' && r <= '～' {
+			r -= 0xFEE0
+		}
+		return unicode.ToLower(r)
+	}, s)
+}
+
+// MatchKeywords 返回在文本中出现的关键词（忽略大小写和全半角差异），按关键词顺序去重
+func MatchKeywords(text string, keywords []string) []string {
+	normalized := normalizeForMatch(text)
+	var matched []string
+	seen := make(map[string]bool, len(keywords))
+	for _, keyword := range keywords {
+		key := normalizeForMatch(strings.TrimSpace(keyword))
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		if strings.Contains(normalized, key) {
+			matched = append(matched, keyword)
+		}
+	}
+	return matched
+}
+
+// NormalizeTag 归一化标签：去除首尾空白，统一大小写和全半角
+func NormalizeTag(tag string) string {
+	return normalizeForMatch(strings.TrimSpace(tag))
+}
@@ -0,0 +1,94 @@
+package textutil
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Markdown 语法的匹配规则，按 StripMarkdown 中的顺序依次替换
+var (
+	mdFence        = regexp.MustCompile("(?m)^[ \t]*(```|~~~).*$\n?")
+	mdImage        = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLink         = regexp.MustCompile(`\[([^\]]+)\]\([^)]*\)`)
+	mdRefLink      = regexp.MustCompile(`\[([^\]]+)\]\[[^\]]*\]`)
+	mdLinkDef      = regexp.MustCompile(`(?m)^[ \t]{0,3}\[[^\]]+\]:[ \t]*\S+.*$\n?`)
+	mdAutolink     = regexp.MustCompile(`<((?:https?|ftp|mailto):[^>\s]+)>`)
+	mdHTMLTag      = regexp.MustCompile(`</?[A-Za-z][A-Za-z0-9]*(?:\s[^<>]*)?/?>`)
+	mdInlineCode   = regexp.MustCompile("`+([^`\n]+)`+")
+	mdHeading      = regexp.MustCompile(`(?m)^[ \t]{0,3}#{1,6}[ \t]+(.*?)[ \t]*#*[ \t]*$`)
+	mdSetextLine   = regexp.MustCompile(`(?m)^[ \t]{0,3}(=+|-+)[ \t]*$\n?`)
+	mdBlockquote   = regexp.MustCompile(`(?m)^[ \t]{0,3}(?:>[ \t]?)+`)
+	mdListItem     = regexp.MustCompile(`(?m)^([ \t]*)(?:[-*+]|\d+[.)])[ \t]+(?:\[[ xX]\][ \t]+)?`)
+	mdRule         = regexp.MustCompile(`(?m)^[ \t]{0,3}(?:[*_][ \t]*){3,}$\n?`)
+	mdTableDivider = regexp.MustCompile(`(?m)^[ \t]*\|?[ \t]*:?-+:?[ \t]*(?:\|[ \t]*:?-+:?[ \t]*)+\|?[ \t]*$\n?`)
+	mdBoldStar     = regexp.MustCompile(`\*\*([^*\n]+)\*\*`)
+	mdBoldUnder    = regexp.MustCompile(`(^|[^\w])__([^_\n]+)__([^\w]|$)`)
+	// 单个星号或下划线包住的斜体，两侧是字母数字时（如 2*3*4、snake_case）不算
+	mdItalicStar  = regexp.MustCompile(`(^|[^\w*])\*([^*\s][^*\n]*)\*([^\w*]|$)`)
+	mdItalicUnder = regexp.MustCompile(`(^|[^\w])_([^_\s][^_\n]*)_([^\w]|$)`)
+	mdStrike      = regexp.MustCompile(`~~([^~\n]+)~~`)
+	mdEscape      = regexp.MustCompile("\\\\([\\\\`*_{}\\[\\]()#+\\-.!|~>])")
+	mdBlankLines  = regexp.MustCompile(`\n{3,}`)
+)
+
+// mdEscapeBase 转义字符在处理期间替换成的私用区字符起点，处理完再换回原字符，避免被当作语法
+const mdEscapeBase = 0xE000
+
+// StripMarkdown 去掉 Markdown 语法只保留正文，用于风格分析和上下文注入：
+// 标题、引用、列表、分隔线的标记被去掉；加粗、斜体、删除线、行内代码保留文字；
+// 链接和图片只保留文字（自动链接保留地址）；代码块去掉围栏保留代码；简单的 HTML 标签被去掉；表格的竖线换成空格
+func StripMarkdown(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = mdEscape.ReplaceAllStringFunc(text, func(escaped string) string {
+		return string(rune(mdEscapeBase + int(escaped[1])))
+	})
+
+	// 行级语法
+	text = mdFence.ReplaceAllString(text, "")
+	text = mdLinkDef.ReplaceAllString(text, "")
+	text = mdRule.ReplaceAllString(text, "")
+	text = mdTableDivider.ReplaceAllString(text, "")
+	text = mdSetextLine.ReplaceAllString(text, "")
+	text = mdHeading.ReplaceAllString(text, "$1")
+	text = mdBlockquote.ReplaceAllString(text, "")
+	text = mdListItem.ReplaceAllString(text, "$1")
+
+	// 行内语法：图片要在链接之前处理
+	text = mdImage.ReplaceAllString(text, "$1")
+	text = mdLink.ReplaceAllString(text, "$1")
+	text = mdRefLink.ReplaceAllString(text, "$1")
+	text = mdAutolink.ReplaceAllString(text, "$1")
+	text = mdInlineCode.ReplaceAllString(text, "$1")
+	text = mdHTMLTag.ReplaceAllString(text, "")
+	text = mdBoldStar.ReplaceAllString(text, "$1")
+	text = mdBoldUnder.ReplaceAllString(text, "$1$2$3")
+	text = mdItalicStar.ReplaceAllString(text, "$1$2$3")
+	text = mdItalicUnder.ReplaceAllString(text, "$1$2$3")
+	text = mdStrike.ReplaceAllString(text, "$1")
+
+	// 表格行的竖线
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "|") && strings.HasSuffix(trimmed, "|") && len(trimmed) > 1 {
+			cells := strings.Split(strings.Trim(trimmed, "|"), "|")
+			for j := range cells {
+				cells[j] = strings.TrimSpace(cells[j])
+			}
+			lines[i] = strings.Join(cells, " ")
+		} else {
+			lines[i] = strings.TrimRight(line, " \t")
+		}
+	}
+	text = strings.Join(lines, "\n")
+
+	// 换回转义的字符
+	text = strings.Map(func(r rune) rune {
+		if r >= mdEscapeBase && r < mdEscapeBase+0x80 {
+			return r - mdEscapeBase
+		}
+		return r
+	}, text)
+
+	return strings.TrimSpace(mdBlankLines.ReplaceAllString(text, "\n\n"))
+}
@@ -0,0 +1,34 @@
+package textutil
+
+import "testing"
+
+// 去掉 Markdown 语法只保留正文，算式、下划线变量名和转义字符原样保留
+func TestStripMarkdown(t *testing.T) {
+	cases := []struct {
+		in, want string
+	}{
+		{"# 周末安排", "周末安排"},
+		{"## 标题 ##", "标题"},
+		{"- 买菜\n- 做饭\n1. 洗碗", "买菜\n做饭\n洗碗"},
+		{"- [x] 订票", "订票"},
+		{"> 你说的对\n> 就这么办", "你说的对\n就这么办"},
+		{"**一定**要*准时*，~~别~~迟到", "一定要准时，别迟到"},
+		{"__重要__的事", "重要的事"},
+		{"2*3*4 等于 24", "2*3*4 等于 24"},
+		{"变量叫 snake_case_name", "变量叫 snake_case_name"},
+		{"看[这个链接](https://example.com)和![图片](a.png)", "看这个链接和图片"},
+		{"地址 <https://example.com>", "地址 https://example.com"},
+		{"用 `go test` 跑一下", "用 go test 跑一下"},
+		{"```go\nfmt.Println(1)\n```", "fmt.Println(1)"},
+		{"| 时间 | 地点 |\n| --- | :---: |\n| 三点 | 公园 |", "时间 地点\n三点 公园"},
+		{"上面\n\n---\n\n下面", "上面\n\n下面"},
+		{"<b>加粗</b>文字", "加粗文字"},
+		{`\*不是斜体\* 和 \# 号`, "*不是斜体* 和 # 号"},
+		{"明天三点见", "明天三点见"},
+	}
+	for _, c := range cases {
+		if got := StripMarkdown(c.in); got != c.want {
+			t.Errorf("StripMarkdown(%q) = %q，期望 %q", c.in, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package textutil
+
+import (
+	"regexp"
+	"strings"
+)
+
+// PII 类型
+const (
+	PIIIDCard   = "id_card"
+	PIIBankCard = "bank_card"
+	PIIPhone    = "phone"
+	PIIEmail    = "email"
+)
+
+// piiRule 一种敏感信息的识别规则：group 为需要打码的分组（0表示整个匹配），valid 为空表示匹配即命中
+type piiRule struct {
+	kind    string
+	pattern *regexp.Regexp
+	group   int
+	valid   func(match string) bool
+}
+
+// piiRules 按顺序匹配：身份证和银行卡较长，先于手机号处理，避免长号码中的片段被当作手机号
+var piiRules = []piiRule{
+	// 18位身份证：地区码 + 出生日期 + 顺序码 + 校验位
+	{PIIIDCard, regexp.MustCompile(`\b[1-9]\d{5}(?:18|19|20)\d{2}(?:0[1-9]|1[0-2])(?:0[1-9]|[12]\d|3[01])\d{3}[\dXx]\b`), 0, nil},
+	// 银行卡：16-19位数字，允许每4位以空格或短横线分隔，需通过 Luhn 校验
+	{PIIBankCard, regexp.MustCompile(`\b\d{4}(?:[ -]?\d{4}){3}(?:[ -]?\d{1,3})?\b`), 0, luhnValid},
+	// 大陆手机号，可带 +86/86 前缀（前缀不打码）
+	{PIIPhone, regexp.MustCompile(`(?:\+86[ -]?|\b86[ -]?|\b)(1[3-9]\d{9})\b`), 1, nil},
+	{PIIEmail, regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`), 0, nil},
+}
+
+// MaskPII 识别文本中的身份证号、银行卡号、手机号和邮箱并打码，返回打码后的文本和各类型的命中次数。
+// 数字类只保留前3位和后4位，邮箱只保留首字符和域名；打码不改变文本长度
+func MaskPII(text string) (string, map[string]int) {
+	var counts map[string]int
+	for _, rule := range piiRules {
+		var b strings.Builder
+		last := 0
+		for _, loc := range rule.pattern.FindAllStringSubmatchIndex(text, -1) {
+			start, end := loc[2*rule.group], loc[2*rule.group+1]
+			match := text[start:end]
+			if rule.valid != nil && !rule.valid(match) {
+				continue
+			}
+			if counts == nil {
+				counts = make(map[string]int)
+			}
+			counts[rule.kind]++
+
+			b.WriteString(text[last:start])
+			if rule.kind == PIIEmail {
+				b.WriteString(maskEmail(match))
+			} else {
+				b.WriteString(maskDigits(match, 3, 4))
+			}
+			last = end
+		}
+		if last > 0 {
+			b.WriteString(text[last:])
+			text = b.String()
+		}
+	}
+	return text, counts
+}
+
+// maskDigits 保留前 keepHead 位和后 keepTail 位数字，其余数字（含身份证校验位X）替换为*，分隔符保持不变
+func maskDigits(s string, keepHead, keepTail int) string {
+	total := 0
+	for _, r := range s {
+		if isDigitOrX(r) {
+			total++
+		}
+	}
+
+	var b strings.Builder
+	seen := 0
+	for _, r := range s {
+		if !isDigitOrX(r) {
+			b.WriteRune(r)
+			continue
+		}
+		if seen < keepHead || seen >= total-keepTail {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('*')
+		}
+		seen++
+	}
+	return b.String()
+}
+
+func isDigitOrX(r rune) bool {
+	return (r >= '0' && r <= '9') || r == 'X' || r == 'x'
+}
+
+// maskEmail 邮箱只保留用户名首字符和域名
+func maskEmail(s string) string {
+	at := strings.IndexByte(s, '@')
+	if at <= 1 {
+		return s
+	}
+	return s[:1] + strings.Repeat("*", at-1) + s[at:]
+}
+
+// luhnValid Luhn 校验（忽略分隔符）
+func luhnValid(s string) bool {
+	sum := 0
+	double := false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
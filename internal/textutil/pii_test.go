@@ -0,0 +1,28 @@
+package textutil
+
+import (
+	"reflect"
+	"testing"
+)
+
+// 身份证、银行卡（需通过 Luhn 校验）、手机号和邮箱按位打码，文本长度和分隔符不变
+func TestMaskPII(t *testing.T) {
+	cases := []struct {
+		in, want string
+		counts   map[string]int
+	}{
+		{"我手机13812345678，有事打我", "我手机138****5678，有事打我", map[string]int{PIIPhone: 1}},
+		{"号码 +86 13812345678", "号码 +86 138****5678", map[string]int{PIIPhone: 1}},
+		{"卡号4111 1111 1111 1111", "卡号411* **** **** 1111", map[string]int{PIIBankCard: 1}},
+		{"订单号1234567812345678", "订单号1234567812345678", nil},
+		{"身份证11010119900307123X", "身份证110***********123X", map[string]int{PIIIDCard: 1}},
+		{"发到alice@example.com", "发到a****@example.com", map[string]int{PIIEmail: 1}},
+		{"明天三点见", "明天三点见", nil},
+	}
+	for _, c := range cases {
+		got, counts := MaskPII(c.in)
+		if got != c.want || !reflect.DeepEqual(counts, c.counts) {
+			t.Errorf("MaskPII(%q) = %q %v，期望 %q %v", c.in, got, counts, c.want, c.counts)
+		}
+	}
+}
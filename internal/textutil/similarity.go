@@ -0,0 +1,46 @@
+package textutil
+
+import (
+	"strings"
+	"unicode"
+)
+
+// CharBigrams 统计字符二元组（忽略大小写和空白、标点），不足两个字符时退化为单字
+func CharBigrams(text string) map[string]int {
+	runes := make([]rune, 0, len(text))
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			runes = append(runes, r)
+		}
+	}
+
+	grams := make(map[string]int)
+	if len(runes) == 1 {
+		grams[string(runes)]++
+		return grams
+	}
+	for i := 0; i+1 < len(runes); i++ {
+		grams[string(runes[i:i+2])]++
+	}
+	return grams
+}
+
+// DiceCoefficient 两个二元组多重集合的 Dice 系数（0-1）
+func DiceCoefficient(a, b map[string]int) float64 {
+	total := 0
+	for _, n := range a {
+		total += n
+	}
+	for _, n := range b {
+		total += n
+	}
+	if total == 0 {
+		return 0
+	}
+
+	common := 0
+	for gram, n := range a {
+		common += min(n, b[gram])
+	}
+	return 2 * float64(common) / float64(total)
+}
@@ -0,0 +1,17 @@
+package textutil
+
+import "regexp"
+
+// templateVarPattern 模板变量 ${name}
+var templateVarPattern = regexp.MustCompile(`\$\{(\w+)\}`)
+
+// ExpandVars 展开模板中的 ${name} 变量，未提供的变量保持原样，便于发现配置错误
+func ExpandVars(tpl string, vars map[string]string) string {
+	return templateVarPattern.ReplaceAllStringFunc(tpl, func(ref string) string {
+		name := templateVarPattern.FindStringSubmatch(ref)[1]
+		if value, ok := vars[name]; ok {
+			return value
+		}
+		return ref
+	})
+}
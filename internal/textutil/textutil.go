@@ -0,0 +1,50 @@
+package textutil
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Abbreviate 将超过 maxChars 个字符的文本缩减为"开头…[省略N字]…结尾"的形式，
+// 开头和结尾各保留 maxChars/2 个字符。maxChars<=0 时不处理。返回值表示是否被缩减
+func Abbreviate(text string, maxChars int) (string, bool) {
+	if maxChars <= 0 {
+		return text, false
+	}
+
+	runes := []rune(text)
+	if len(runes) <= maxChars {
+		return text, false
+	}
+
+	half := maxChars / 2
+	head := string(runes[:half])
+	tail := string(runes[len(runes)-(maxChars-half):])
+	omitted := len(runes) - maxChars
+
+	return fmt.Sprintf("%s…[省略%d字]…%s", head, omitted, tail), true
+}
+
+// SampleHeadTail 对超过 maxChars 个字符的文本取首尾采样（中间以换行连接，不插入额外标记），
+// 用于统计分析。maxChars<=0 时不处理
+func SampleHeadTail(text string, maxChars int) string {
+	if maxChars <= 0 {
+		return text
+	}
+
+	runes := []rune(text)
+	if len(runes) <= maxChars {
+		return text
+	}
+
+	half := maxChars / 2
+	return string(runes[:half]) + "\n" + string(runes[len(runes)-(maxChars-half):])
+}
+
+// likeEscaper 转义 LIKE 模式中的通配符和转义字符
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// EscapeLike 转义 LIKE 模式中的 %、_ 和 \，使其按字面匹配（需配合 ESCAPE '\'）
+func EscapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
@@ -0,0 +1,173 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ChatRecommend/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// 事件类型
+const (
+	EventSummaryUpdated = "summary_updated"
+	EventKeyInfoAdded   = "key_info_added"
+	EventStyleUpdated   = "style_updated"
+	EventKeywordAlert   = "keyword_alert"
+)
+
+// 请求头
+const (
+	HeaderEvent     = "X-ChatRecommend-Event"
+	HeaderTimestamp = "X-ChatRecommend-Timestamp"
+	HeaderSignature = "X-ChatRecommend-Signature"
+)
+
+// Event webhook事件
+type Event struct {
+	Event     string      `json:"event"`
+	Timestamp int64       `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// Dispatcher webhook事件分发器
+type Dispatcher struct {
+	config *config.WebhookConfig
+	client *http.Client
+}
+
+// NewDispatcher 创建webhook分发器，未配置任何endpoint时返回nil
+func NewDispatcher(cfg *config.WebhookConfig) *Dispatcher {
+	if cfg == nil || len(cfg.Endpoints) == 0 {
+		return nil
+	}
+
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+
+	return &Dispatcher{
+		config: cfg,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Dispatch 异步投递事件到所有订阅了该事件的endpoint，不阻塞调用方
+func (d *Dispatcher) Dispatch(event string, data interface{}) {
+	if d == nil {
+		return
+	}
+
+	body, err := json.Marshal(Event{
+		Event:     event,
+		Timestamp: time.Now().Unix(),
+		Data:      data,
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("event", event).Error("序列化webhook事件失败")
+		return
+	}
+
+	for _, endpoint := range d.config.Endpoints {
+		if !subscribed(endpoint, event) {
+			continue
+		}
+		go d.deliver(endpoint, event, body)
+	}
+}
+
+// deliver 投递事件，失败时按指数退避重试
+func (d *Dispatcher) deliver(endpoint config.WebhookEndpoint, event string, body []byte) {
+	backoff := time.Second
+	attempts := d.config.MaxRetries + 1
+
+	for attempt := 1; attempt <= attempts; attempt++ {
+		err := d.post(endpoint, event, body)
+		if err == nil {
+			logrus.WithFields(logrus.Fields{
+				"url":   endpoint.URL,
+				"event": event,
+			}).Debug("webhook投递成功")
+			return
+		}
+
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"url":     endpoint.URL,
+			"event":   event,
+			"attempt": attempt,
+		}).Warn("webhook投递失败")
+
+		if attempt < attempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"url":   endpoint.URL,
+		"event": event,
+	}).Error("webhook投递失败，已放弃")
+}
+
+// post 发送一次带签名的请求
+func (d *Dispatcher) post(endpoint config.WebhookEndpoint, event string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, event)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	if endpoint.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(endpoint.Secret, timestamp, body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("发送请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("响应状态码: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign 计算签名：sha256=hex(HMAC-SHA256(secret, timestamp + "." + body))
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature 校验签名，供接收方使用
+func VerifySignature(secret string, timestamp string, body []byte, signature string) bool {
+	expected := Sign(secret, timestamp, body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// subscribed 判断endpoint是否订阅了事件，未配置events时订阅全部事件
+func subscribed(endpoint config.WebhookEndpoint, event string) bool {
+	if len(endpoint.Events) == 0 {
+		return true
+	}
+	for _, e := range endpoint.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
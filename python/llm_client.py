@@ -6,6 +6,7 @@
 """
 
 import json
+import re
 import sys
 import os
 import io
@@ -31,12 +32,40 @@ except ImportError:
     Anthropic = None
 
 
+# Go与本脚本之间的JSON协议版本，需与 internal/llm/llm.go 中的 ProtocolVersion 保持一致
+PROTOCOL_VERSION = "1"
+
+
 def load_config() -> Dict[str, Any]:
     """从环境变量或配置文件加载配置"""
     # 这里可以从环境变量读取，或者从Go传递的配置中获取
     return {}
 
 
+def diversity_instruction(count: int) -> str:
+    """要求模型一次给出多条风格各异的建议"""
+    return (f"\n\n请给出{count}条措辞和风格明显不同的补全建议（例如简洁、热情、正式等不同说法），"
+            "每行一条，不要编号，不要添加任何解释。")
+
+
+def json_instruction(count: int) -> str:
+    """JSON 模式下要求模型只返回合法 JSON"""
+    return (f"\n\n请给出{max(count, 1)}条补全建议，只返回一个合法的 JSON 对象，不要包含任何其他文字，格式为："
+            '{"suggestions": [{"text": "建议内容", "reason": "推荐理由", "tone": "调性标签，如简洁/热情/正式"}]}')
+
+
+def split_suggestions(text: str, count: int) -> List[str]:
+    """按行切分模型返回的多条建议，去除编号和空行"""
+    suggestions = []
+    for line in text.split("\n"):
+        line = line.strip().lstrip("-*•").strip()
+        # 去掉 "1." "1、" "(1)" 之类的编号
+        line = re.sub(r"^[(（]?\d+[.、)）:：]\s*", "", line).strip()
+        if line:
+            suggestions.append(line)
+    return suggestions[:count] if suggestions else [text]
+
+
 def call_openai_api(request: Dict[str, Any], config: Dict[str, Any]) -> Dict[str, Any]:
     """调用OpenAI API"""
     if OpenAI is None:
@@ -51,6 +80,9 @@ def call_openai_api(request: Dict[str, Any], config: Dict[str, Any]) -> Dict[str
         base_url=api_config.get("base_url", "https://api.openai.com/v1")
     )
 
+    # 请求中的生成参数（如对话级覆盖）优先于全局配置
+    params = {**api_config, **request.get("parameters", {})}
+
     context = request.get("context", "")
     input_text = request.get("input", "")
 
@@ -60,22 +92,37 @@ def call_openai_api(request: Dict[str, Any], config: Dict[str, Any]) -> Dict[str
     if input_text:
         input_text = input_text.encode('utf-8', errors='replace').decode('utf-8', errors='replace')
 
+    count = int(params.get("suggestion_count", 1) or 1)
+    json_mode = params.get("response_format") == "json"
+    if json_mode:
+        context = (context or "") + json_instruction(count)
+    elif count > 1:
+        context = (context or "") + diversity_instruction(count)
+
     # 构建消息
     messages = []
     if context:
         messages.append({"role": "system", "content": context})
     messages.append({"role": "user", "content": input_text})
 
+    extra = {}
+    if params.get("stop"):
+        extra["stop"] = params["stop"]
+    if json_mode:
+        # 启用模型的 JSON mode
+        extra["response_format"] = {"type": "json_object"}
+
     # 调用API
     try:
         response = client.chat.completions.create(
-            model=api_config.get("model", "gpt-4"),
+            model=params.get("model", "gpt-4"),
             messages=messages,
-            temperature=api_config.get("temperature", 0.7),
-            max_tokens=api_config.get("max_tokens", 2000),
-            top_p=api_config.get("top_p", 1.0),
-            frequency_penalty=api_config.get("frequency_penalty", 0.0),
-            presence_penalty=api_config.get("presence_penalty", 0.0),
+            temperature=params.get("temperature", 0.7),
+            max_tokens=params.get("max_tokens", 2000),
+            top_p=params.get("top_p", 1.0),
+            frequency_penalty=params.get("frequency_penalty", 0.0),
+            presence_penalty=params.get("presence_penalty", 0.0),
+            **extra,
         )
 
         text = response.choices[0].message.content
@@ -84,15 +131,15 @@ def call_openai_api(request: Dict[str, Any], config: Dict[str, Any]) -> Dict[str
         if text:
             text = text.encode('utf-8', errors='replace').decode('utf-8', errors='replace')
 
-        # 生成多个建议（简单实现：基于不同temperature）
-        suggestions = [text]
-        if len(suggestions) < 3:
-            # 可以生成更多变体
-            suggestions.append(text)
+        # JSON 模式下原样返回文本，由调用方解析
+        if json_mode:
+            return {"text": text}
+
+        suggestions = split_suggestions(text, count) if count > 1 and text else [text]
 
         return {
             "text": text,
-            "suggestions": suggestions[:3]
+            "suggestions": suggestions
         }
     except Exception as e:
         return {"error": f"OpenAI API调用失败: {str(e)}"}
@@ -108,26 +155,46 @@ def call_anthropic_api(request: Dict[str, Any], config: Dict[str, Any]) -> Dict[
         api_key=api_config.get("api_key", os.getenv("ANTHROPIC_API_KEY", ""))
     )
 
+    # 请求中的生成参数（如对话级覆盖）优先于全局配置
+    params = {**api_config, **request.get("parameters", {})}
+
     context = request.get("context", "")
     input_text = request.get("input", "")
     
+    count = int(params.get("suggestion_count", 1) or 1)
+    json_mode = params.get("response_format") == "json"
+    if json_mode:
+        # Anthropic 没有 JSON mode，通过提示词约定返回合法 JSON
+        context = (context or "") + json_instruction(count)
+    elif count > 1:
+        context = (context or "") + diversity_instruction(count)
+
     # 构建消息
     message = f"{context}\n\n{input_text}" if context else input_text
 
+    extra = {}
+    if params.get("stop"):
+        extra["stop_sequences"] = params["stop"]
+
     try:
         response = client.messages.create(
-            model=api_config.get("model", "claude-3-opus-20240229"),
-            max_tokens=api_config.get("max_tokens", 2000),
-            temperature=api_config.get("temperature", 0.7),
-            messages=[{"role": "user", "content": message}]
+            model=params.get("model", "claude-3-opus-20240229"),
+            max_tokens=params.get("max_tokens", 2000),
+            temperature=params.get("temperature", 0.7),
+            top_p=params.get("top_p", 1.0),
+            messages=[{"role": "user", "content": message}],
+            **extra,
         )
 
         text = response.content[0].text
-        suggestions = [text]
+        if json_mode:
+            return {"text": text}
+
+        suggestions = split_suggestions(text, count) if count > 1 and text else [text]
 
         return {
             "text": text,
-            "suggestions": suggestions[:3]
+            "suggestions": suggestions
         }
     except Exception as e:
         return {"error": f"Anthropic API调用失败: {str(e)}"}
@@ -139,8 +206,11 @@ def generate_summary(request: Dict[str, Any], config: Dict[str, Any]) -> Dict[st
     existing_summary = request.get("existing_summary")
     summary_config = request.get("config", {})
 
-    # 构建摘要提示词
-    prompt = "请分析以下对话，生成一个简洁的摘要，包含关键信息和对话主题。\n\n"
+    # 构建摘要提示词（配置了前缀时放在最前面）
+    prompt = ""
+    if summary_config.get("system_prefix"):
+        prompt += summary_config["system_prefix"] + "\n\n"
+    prompt += "请分析以下对话，生成一个简洁的摘要，包含关键信息和对话主题。\n\n"
     
     if existing_summary:
         prompt += f"已有摘要：{existing_summary.get('prompt', '')}\n\n"
@@ -152,6 +222,9 @@ def generate_summary(request: Dict[str, Any], config: Dict[str, Any]) -> Dict[st
         prompt += f"[{msg.get('sender_id', 'unknown')}]: {msg.get('content', '')}\n"
 
     prompt += "\n请生成：\n1. 一个简洁的摘要提示词（用于后续对话上下文）\n2. 关键信息列表（JSON格式）"
+    # 摘要格式指令（要点式/叙事式/时间线式）
+    if summary_config.get("format_instruction"):
+        prompt += "\n\n" + summary_config["format_instruction"]
 
     # 调用大模型生成摘要
     api_config = config.get("api", {})
@@ -224,11 +297,14 @@ def main():
         input_data = sys.stdin.read()
         request_data = json.loads(input_data)
         
+        version = request_data.get("version")
         action = request_data.get("action")
         request = request_data.get("request", {})
         config = request_data.get("config", {})
         
-        if action == "complete":
+        if version != PROTOCOL_VERSION:
+            result = {"error": f"不支持的协议版本: {version}，脚本支持的版本: {PROTOCOL_VERSION}"}
+        elif action == "complete":
             result = handle_complete(request, config)
         elif action == "generate_summary":
             result = generate_summary(request, config)